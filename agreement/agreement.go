@@ -45,10 +45,13 @@ const (
 	EL_AG_UNABLE_WRITE_NODE_EXCH_PATTERN_TO_DB   = "Unable to save the new node exchange pattern %v to the local database. Error: %v"
 	EL_AG_TERM_UNABLE_SYNC_CONTAINERS            = "anax terminating, unable to sync up containers."
 	EL_AG_TERM_UNABLE_SYNC_AGS                   = "anax terminating, unable to complete agreement sync up. %v"
+	EL_AG_MSG_KEY_ROTATED                        = "Node messaging key rotated, the new key fingerprint is %v."
+	EL_AG_UNABLE_ROTATE_MSG_KEY                  = "Unable to rotate the node messaging key, error: %v"
 )
 
 // name for the subworker
 const NODE_POLICY_WATCHER = "NodePolicyWatcher"
+const MESSAGE_KEY_ROTATION = "NodeMessageKeyRotation"
 
 // This is does nothing useful at run time.
 // This code is only used at compile time to make the eventlog messages get into the catalog so that
@@ -75,6 +78,8 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_AG_UNABLE_WRITE_NODE_EXCH_PATTERN_TO_DB)
 	msgPrinter.Sprintf(EL_AG_TERM_UNABLE_SYNC_CONTAINERS)
 	msgPrinter.Sprintf(EL_AG_TERM_UNABLE_SYNC_AGS)
+	msgPrinter.Sprintf(EL_AG_MSG_KEY_ROTATED)
+	msgPrinter.Sprintf(EL_AG_UNABLE_ROTATE_MSG_KEY)
 }
 
 // must be safely-constructed!!
@@ -249,6 +254,25 @@ func (w *AgreementWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *AgreementWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.PolicyCreatedMessage{},
+		&events.BlockchainClientInitializedMessage{},
+		&events.BlockchainClientStoppingMessage{},
+		&events.AccountFundedMessage{},
+		&events.ExchangeDeviceMessage{},
+		&events.DeviceContainersSyncedMessage{},
+		&events.EdgeConfigCompleteMessage{},
+		&events.NodeShutdownMessage{},
+		&events.NodeShutdownCompleteMessage{},
+		&events.NodePolicyMessage{},
+		&events.ExchangeChangeMessage{},
+		&events.NodeHeartbeatStateChangeMessage{},
+	}
+}
+
 // Initialize the agreement worker before it begins processing commands.
 func (w *AgreementWorker) Initialize() bool {
 
@@ -281,6 +305,13 @@ func (w *AgreementWorker) Initialize() bool {
 			glog.Errorf(logString(fmt.Sprintf("failed to get the messaging keys. %v", err)))
 		}
 
+		// If a key rotation was interrupted by a restart, the new key might not be in the exchange yet.
+		if err := exchange.PublishRecoveredKey("", func(publicKey []byte) error {
+			return exchange.PatchNodeMessageKey(w, publicKey)
+		}); err != nil {
+			glog.Errorf(logString(fmt.Sprintf("failed to publish the messaging key. %v", err)))
+		}
+
 		// Establish agreement protocol handlers
 		for _, protocolName := range policy.AllAgreementProtocols() {
 			pph := producer.CreateProducerPH(protocolName, w.BaseWorker.Manager.Config, w.db, w.pm, w)
@@ -316,6 +347,11 @@ func (w *AgreementWorker) Initialize() bool {
 	// this subworker will catch if the node policy built-in properties have changed without the agent restarting
 	w.DispatchSubworker(NODE_POLICY_WATCHER, w.reconcileNodePolicy, 60, false)
 
+	// this subworker replaces the node's messaging key on a schedule, if configured
+	if w.Config.Edge.MessageKeyRotationIntervalH > 0 {
+		w.DispatchSubworker(MESSAGE_KEY_ROTATION, w.rotateMessageKey, 3600, false)
+	}
+
 	glog.Info(logString(fmt.Sprintf("waiting for commands.")))
 
 	return true
@@ -359,6 +395,29 @@ func (w *AgreementWorker) reconcileNodePolicy() int {
 	return 60
 }
 
+// Replace the node's messaging key when it is older than the configured rotation interval. The key is only replaced
+// while the node is registered, because the new public key has to be published in the exchange.
+func (w *AgreementWorker) rotateMessageKey() int {
+	if w.hznOffline || w.GetExchangeToken() == "" || !exchange.KeyRotationDue("", w.Config.Edge.MessageKeyRotationIntervalH) {
+		return 0
+	}
+
+	glog.V(3).Infof(logString(fmt.Sprintf("rotating the node messaging key")))
+	if err := exchange.RotateNodeMessageKey(w, w.Config.Edge.GetMessageKeyGracePeriodS()); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to rotate the node messaging key, error: %v", err)))
+		eventlog.LogNodeEvent(w.db, persistence.SEVERITY_ERROR,
+			persistence.NewMessageMeta(EL_AG_UNABLE_ROTATE_MSG_KEY, err.Error()),
+			persistence.EC_ERROR_NODE_MESSAGE_KEY_ROTATION,
+			exchange.GetId(w.GetExchangeId()), exchange.GetOrg(w.GetExchangeId()), w.devicePattern, "")
+	} else if info, err := exchange.GetMessageKeyInfo(""); err == nil {
+		eventlog.LogNodeEvent(w.db, persistence.SEVERITY_INFO,
+			persistence.NewMessageMeta(EL_AG_MSG_KEY_ROTATED, info.Fingerprint),
+			persistence.EC_NODE_MESSAGE_KEY_ROTATED,
+			exchange.GetId(w.GetExchangeId()), exchange.GetOrg(w.GetExchangeId()), w.devicePattern, "")
+	}
+	return 0
+}
+
 // Enter the command processing loop. Initialization is complete so wait for commands to
 // perform. Commands are created as the result of events that are triggered elsewhere
 // in the system. This function returns ture if the command was handled, false if not.
@@ -1018,7 +1077,9 @@ func (w *AgreementWorker) recordAgreementState(agreementId string, pol *policy.P
 	resp = new(exchange.PostDeviceResponse)
 	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/nodes/" + exchange.GetId(w.GetExchangeId()) + "/agreements/" + agreementId + "?" + exchange.NOHEARTBEAT_PARAM
 	for {
-		if err, tpErr := exchange.InvokeExchange(w.GetHTTPFactory().NewHTTPClient(nil), "PUT", targetURL, w.GetExchangeId(), w.GetExchangeToken(), as, &resp); err != nil {
+		if exchange.QueueExchangeWrite("PUT", targetURL, "", as) {
+			return nil
+		} else if err, tpErr := exchange.InvokeExchange(w.GetHTTPFactory().NewHTTPClient(nil), "PUT", targetURL, w.GetExchangeId(), w.GetExchangeToken(), as, &resp); err != nil {
 			glog.Errorf(err.Error())
 			return err
 		} else if tpErr != nil {
@@ -1041,7 +1102,9 @@ func deleteProducerAgreement(httpClient *http.Client, url string, deviceId strin
 	resp = new(exchange.PostDeviceResponse)
 	targetURL := url + "orgs/" + exchange.GetOrg(deviceId) + "/nodes/" + exchange.GetId(deviceId) + "/agreements/" + agreementId
 	for {
-		if err, tpErr := exchange.InvokeExchange(httpClient, "DELETE", targetURL, deviceId, token, nil, &resp); err != nil {
+		if exchange.QueueExchangeWrite("DELETE", targetURL, "", nil) {
+			return nil
+		} else if err, tpErr := exchange.InvokeExchange(httpClient, "DELETE", targetURL, deviceId, token, nil, &resp); err != nil {
 			glog.Errorf(logString(fmt.Sprintf("%s", err.Error())))
 			return err
 		} else if tpErr != nil {
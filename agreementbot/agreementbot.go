@@ -2,6 +2,7 @@ package agreementbot
 
 import (
 	"bytes"
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"github.com/golang/glog"
@@ -18,6 +19,7 @@ import (
 	"github.com/open-horizon/anax/worker"
 	"net/http"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +29,7 @@ import (
 const DATABASE_HEARTBEAT = "AgbotDatabaseHeartBeat"
 const GOVERN_AGREEMENTS = "AgBotGovernAgreements"
 const GOVERN_ARCHIVED_AGREEMENTS = "AgBotGovernArchivedAgreements"
+const GOVERN_EVENT_LOGS = "AgBotGovernEventLogs"
 const SECRETS_PROVIDER = "AgbotSecretsProvider"
 const SECRETS_UPDATE = "AgbotSecretsUpdate"
 const AGENT_FILE_VERSION_UPDATE = "AgbotUpdateAgentFileVersion"
@@ -36,6 +39,7 @@ const NMP_HA_GROUP_STATUS = "NMPHAGroupMonitor"
 const POLICY_WATCHER = "AgBotPolicyWatcher"
 const STALE_PARTITIONS = "AgbotStaleDatabasePartition"
 const MESSAGE_KEY_CHECK = "AgbotMessageKeyCheck"
+const MESSAGE_KEY_ROTATION = "AgbotMessageKeyRotation"
 
 // Agreement governance timing state. Used in the GovernAgreements subworker.
 type DVState struct {
@@ -63,6 +67,7 @@ type AgreementBotWorker struct {
 	nodeSearch           *NodeSearch // The object that controls node searches and the state of search sessions.
 	secretProvider       secrets.AgbotSecrets
 	secretUpdateManager  *SecretUpdateManager
+	exchanges            *ExchangeFederation // The exchanges that hold the orgs served by this agbot.
 }
 
 func NewAgreementBotWorker(name string, cfg *config.HorizonConfig, db persistence.AgbotDatabase, s secrets.AgbotSecrets) *AgreementBotWorker {
@@ -79,11 +84,12 @@ func NewAgreementBotWorker(name string, cfg *config.HorizonConfig, db persistenc
 		NHManager:            NewNodeHealthManager(),
 		GovTiming:            DVState{},
 		shutdownStarted:      false,
-		noworkDispatch:       time.Now().Unix(),
+		noworkDispatch:       worker.GetClock().Now().Unix(),
 		newMessagesToProcess: false,
 		nodeSearch:           NewNodeSearch(),
 		secretProvider:       s,
 		secretUpdateManager:  NewSecretUpdateManager(cfg.AgreementBot.SecretsUpdateCheckInterval, cfg.AgreementBot.SecretsUpdateCheckInterval, cfg.AgreementBot.SecretsUpdateCheckMaxInterval, cfg.AgreementBot.SecretsUpdateCheckIncrement),
+		exchanges:            NewExchangeFederation(cfg),
 	}
 
 	patternManager = NewPatternManager()
@@ -103,7 +109,7 @@ func (w *AgreementBotWorker) Messages() chan events.Message {
 
 func (w *AgreementBotWorker) NewEvent(incoming events.Message) {
 
-	if w.Config.AgreementBot == (config.AGConfig{}) {
+	if reflect.DeepEqual(w.Config.AgreementBot, config.AGConfig{}) {
 		return
 	}
 
@@ -299,6 +305,30 @@ func (w *AgreementBotWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *AgreementBotWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.AccountFundedMessage{},
+		&events.BlockchainClientInitializedMessage{},
+		&events.BlockchainClientStoppingMessage{},
+		&events.EthBlockchainEventMessage{},
+		&events.ABApiAgreementCancelationMessage{},
+		&events.PolicyChangedMessage{},
+		&events.PolicyDeletedMessage{},
+		&events.ABApiWorkloadUpgradeMessage{},
+		&events.NodeShutdownCompleteMessage{},
+		&events.NodeShutdownMessage{},
+		&events.CacheServicePolicyMessage{},
+		&events.ServicePolicyChangedMessage{},
+		&events.ServicePolicyDeletedMessage{},
+		&events.NodePolicyChangedMessage{},
+		&events.MMSObjectPolicyMessage{},
+		&events.MMSObjectPoliciesMessage{},
+		&events.ExchangeChangeMessage{},
+		&events.SecretUpdatesMessage{},
+	}
+}
+
 // This function is used by Initialize to send the Agbot terminate message in the cases where Initialize fails such that the
 // entire agbot process should also terminate.
 func (w *AgreementBotWorker) fail() bool {
@@ -311,7 +341,7 @@ func (w *AgreementBotWorker) Initialize() bool {
 	glog.Info("AgreementBot worker initializing")
 
 	// If there is no Agbot config, we will terminate. This is a normal condition when running on a node.
-	if w.Config.AgreementBot == (config.AGConfig{}) {
+	if reflect.DeepEqual(w.Config.AgreementBot, config.AGConfig{}) {
 		glog.Warningf("AgreementBotWorker terminating, no AgreementBot config.")
 		return false
 	} else if w.db == nil {
@@ -319,10 +349,15 @@ func (w *AgreementBotWorker) Initialize() bool {
 		return false
 	}
 
-	// Log an error if the current exchange version does not meet the requirement.
-	if err := version.VerifyExchangeVersion(w.Config.Collaborators.HTTPClientFactory, w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken(), false); err != nil {
-		glog.Errorf(AWlogString(fmt.Sprintf("Error verifiying exchange version. error: %v", err)))
-		return w.fail()
+	// Log an error if the current version of any of the exchanges does not meet the requirement.
+	for _, ec := range w.exchanges.Exchanges() {
+		if err := version.VerifyExchangeVersion(w.Config.Collaborators.HTTPClientFactory, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken(), false); err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("Error verifiying the version of the %v exchange. error: %v", ec.DisplayName(), err)))
+			return w.fail()
+		}
+	}
+	if w.exchanges.IsFederated() {
+		glog.Infof(AWlogString(fmt.Sprintf("serving orgs in multiple exchanges, %v", w.exchanges)))
 	}
 
 	// Make sure the policy directory is in place so that we have a place to put the generated policy files.
@@ -385,20 +420,22 @@ func (w *AgreementBotWorker) Initialize() bool {
 			}
 		}
 		glog.V(3).Infof("AgreementBotWorker waiting for policies to appear")
-		time.Sleep(time.Duration(w.BaseWorker.Manager.Config.AgreementBot.CheckUpdatedPolicyS) * time.Second)
+		worker.GetClock().Sleep(time.Duration(w.BaseWorker.Manager.Config.AgreementBot.CheckUpdatedPolicyS) * time.Second)
 
 	}
 
 	glog.Info("AgreementBot worker started")
 
 	// Tell the node search component to initialize itself.
-	w.nodeSearch.Init(w.db, w.pm, w.consumerPH, w.Messages(), w, w.Config)
+	w.nodeSearch.Init(w.db, w.pm, w.consumerPH, w.Messages(), w.exchanges, w.Config)
 
-	// Make sure that our public key is registered in the exchange so that other parties
+	// Make sure that our public key is registered in each exchange so that other parties
 	// can send us messages.
-	if err := w.registerPublicKey(); err != nil {
-		glog.Errorf("AgreementBotWorker unable to register public key, error: %v", err)
-		return w.fail()
+	for _, ec := range w.exchanges.Exchanges() {
+		if err := w.registerPublicKey(ec); err != nil {
+			glog.Errorf("AgreementBotWorker unable to register public key in the %v exchange, error: %v", ec.DisplayName(), err)
+			return w.fail()
+		}
 	}
 
 	// For each agreement protocol in the current list of configured policies, startup a processor
@@ -438,8 +475,14 @@ func (w *AgreementBotWorker) Initialize() bool {
 	// Start the governance routines using the subworker APIs.
 	w.DispatchSubworker(GOVERN_AGREEMENTS, w.GovernAgreements, int(w.BaseWorker.Manager.Config.AgreementBot.ProcessGovernanceIntervalS), false)
 	w.DispatchSubworker(GOVERN_ARCHIVED_AGREEMENTS, w.GovernArchivedAgreements, 1800, false)
+	if w.Config.AgreementBot.GetEventLogMaxAgeH() > 0 {
+		w.DispatchSubworker(GOVERN_EVENT_LOGS, w.GovernEventLogs, 3600, false)
+	}
 	//w.DispatchSubworker(GOVERN_BC_NEEDS, w.GovernBlockchainNeeds, 60, false)
 	w.DispatchSubworker(MESSAGE_KEY_CHECK, w.messageKeyCheck, w.BaseWorker.Manager.Config.AgreementBot.MessageKeyCheck, false)
+	if w.BaseWorker.Manager.Config.AgreementBot.MessageKeyRotationIntervalH > 0 {
+		w.DispatchSubworker(MESSAGE_KEY_ROTATION, w.rotateMessageKey, 3600, false)
+	}
 	w.DispatchSubworker(SECRETS_UPDATE, w.secretsUpdate, w.BaseWorker.Manager.Config.GetSecretsUpdateCheck(), false)
 
 	if w.Config.AgreementBot.CheckUpdatedPolicyS != 0 {
@@ -454,13 +497,14 @@ func (w *AgreementBotWorker) Initialize() bool {
 // Sets the timeouts for the given agreement in the db and returns the agreementTimeout then the protocolTimeout
 func (w *AgreementBotWorker) SetAgreementTimeouts(ag persistence.Agreement, protocol string) (uint64, uint64) {
 	maxHb := 0
-	dev, _ := GetDevice(w.BaseWorker.EC.HTTPFactory.NewHTTPClient(nil), ag.DeviceId, w.BaseWorker.EC.URL, w.BaseWorker.EC.Id, w.BaseWorker.EC.Token)
+	ec := w.exchanges.ForId(ag.DeviceId)
+	dev, _ := GetDevice(w.BaseWorker.EC.HTTPFactory.NewHTTPClient(nil), ag.DeviceId, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
 	if dev != nil {
 		maxHb = dev.HeartbeatIntv.MaxInterval
 	}
 
 	if maxHb == 0 {
-		exchOrg, _ := exchange.GetOrganization(w.BaseWorker.EC.HTTPFactory, exchange.GetOrg(ag.DeviceId), w.BaseWorker.EC.URL, w.BaseWorker.EC.Id, w.BaseWorker.EC.Token)
+		exchOrg, _ := exchange.GetOrganization(w.BaseWorker.EC.HTTPFactory, exchange.GetOrg(ag.DeviceId), ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
 		if exchOrg != nil {
 			maxHb = exchOrg.HeartbeatIntv.MaxInterval
 		}
@@ -694,7 +738,7 @@ func (w *AgreementBotWorker) CommandHandler(command worker.Command) bool {
 	// Therefore, if there is a steady flow of commands coming into the command handler, the noworkhandler
 	// might never get control. Given that, the noworkhandler will be explicitly invoked by the command handler
 	// if it hasn't run in a while.
-	if time.Since(time.Unix(w.noworkDispatch, 0)).Seconds() >= float64(w.Config.AgreementBot.NewContractIntervalS*2) {
+	if worker.GetClock().Since(time.Unix(w.noworkDispatch, 0)).Seconds() >= float64(w.Config.AgreementBot.NewContractIntervalS*2) {
 		glog.V(5).Infof(AWlogString(fmt.Sprintf("early NoWorkhandler dispatch")))
 		w.NoWorkHandler()
 	}
@@ -729,27 +773,35 @@ func (w *AgreementBotWorker) calculateMessageLimit() int {
 	return calcLimit
 }
 
-// Pull messages from the exchange, decrypt them and put them on the high priority protocol specific work queue.
+// Pull messages from the exchanges, decrypt them and put them on the high priority protocol specific work queue. The
+// limit applies to the messages from all the exchanges.
 func (w *AgreementBotWorker) processProtocolMessage(limit int) {
 	glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker retrieving messages from the exchange, limit: %v", limit))
 
-	if limit == 0 {
-		return
+	for _, ec := range w.exchanges.Exchanges() {
+		if limit <= 0 {
+			break
+		}
+		limit -= w.processExchangeMessages(ec, limit)
 	}
+	glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker done processing messages"))
+}
+
+// Process the messages in the agbot's message queue in one exchange. Returns the number of messages read.
+func (w *AgreementBotWorker) processExchangeMessages(ec *FederatedExchange, limit int) int {
 
-	if msgs, err := w.getMessages(limit); err != nil {
-		glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to retrieve exchange messages, error: %v", err))
+	msgs, err := w.getMessages(ec, limit)
+	if err != nil {
+		glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to retrieve messages from the %v exchange, error: %v", ec.DisplayName(), err))
 	} else {
 		// Loop through all the returned messages and process them.
 		for _, msg := range msgs {
 
 			glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker reading message %v from the exchange", msg.MsgId))
-			// First get my own keys
-			_, myPrivKey, _ := exchange.GetKeys(w.Config.AgreementBot.MessageKeyPath)
 
 			// Deconstruct and decrypt the message. If there is a problem with the message, it will be deleted.
 			deleteMessage := true
-			if protocolMessage, receivedPubKey, err := exchange.DeconstructExchangeMessage(msg.Message, myPrivKey); err != nil {
+			if protocolMessage, receivedPubKey, err := w.deconstructMessage(msg.Message, msg.MsgId); err != nil {
 				glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to deconstruct exchange message %v, error %v", msg, err))
 			} else if serializedPubKey, err := exchange.MarshalPublicKey(receivedPubKey); err != nil {
 				glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to marshal the key from the encrypted message %v, error %v", receivedPubKey, err))
@@ -760,18 +812,21 @@ func (w *AgreementBotWorker) processProtocolMessage(limit int) {
 			} else if !w.consumerPH.Has(msgProtocol) {
 				glog.Infof(fmt.Sprintf("AgreementBotWorker unable to direct exchange message %v to a protocol handler, deleting it.", protocolMessage))
 				deleteMessage = false
-				DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
+				DeleteMessage(msg.MsgId, ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL(), w.httpClient)
 			} else {
 				// The message seems to be good, so don't delete it yet, the protocol worker that handles the message will delete it.
 				deleteMessage = false
 
+				// If the node has rotated its messaging key, drop the cached copy of the node so that replies use the new key.
+				exchange.DeleteCacheNodeIfKeyChanged(exchange.GetOrg(msg.DeviceId), exchange.GetId(msg.DeviceId), msg.DevicePubKey)
+
 				// Send the message to a protocol worker.
 				cmd := NewNewProtocolMessageCommand(protocolMessage, msg.MsgId, msg.DeviceId, msg.DevicePubKey)
 				if !w.consumerPH.Get(msgProtocol).AcceptCommand(cmd) {
 					glog.Infof(fmt.Sprintf("AgreementBotWorker protocol handler for %v not accepting exchange messages, deleting msg.", msgProtocol))
-					DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
+					DeleteMessage(msg.MsgId, ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL(), w.httpClient)
 				} else if err := w.consumerPH.Get(msgProtocol).DispatchProtocolMessage(cmd, w.consumerPH.Get(msgProtocol)); err != nil {
-					DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
+					DeleteMessage(msg.MsgId, ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL(), w.httpClient)
 				}
 
 			}
@@ -779,17 +834,37 @@ func (w *AgreementBotWorker) processProtocolMessage(limit int) {
 			// If anything went wrong trying to decrypt the message or verify its origin, etc, just delete it. These errors aren't
 			// expected to be retryable.
 			if deleteMessage {
-				DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
+				DeleteMessage(msg.MsgId, ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL(), w.httpClient)
 			}
 
 		}
 	}
-	glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker done processing messages"))
+	return len(msgs)
+}
+
+// Decrypt a message with any of the agbot's messaging keys. If none of them work, another agbot instance that shares
+// the keys might have rotated them, so the keys are reloaded and the message is tried once more. Messages that are
+// too old, that have been received before or that are not valid are rejected without reloading the keys.
+func (w *AgreementBotWorker) deconstructMessage(message []byte, msgId int) ([]byte, *rsa.PublicKey, error) {
+	keyPath := w.Config.AgreementBot.MessageKeyPath
+	if myPrivKeys, err := exchange.GetDecryptionKeys(keyPath); err != nil {
+		return nil, nil, err
+	} else if protocolMessage, receivedPubKey, err := exchange.ReceiveExchangeMessage(message, myPrivKeys, msgId); err == nil {
+		return protocolMessage, receivedPubKey, nil
+	} else if !exchange.IsKeyMismatchError(err) {
+		return nil, nil, err
+	} else if _, _, rerr := exchange.ReloadKeys(keyPath); rerr != nil {
+		return nil, nil, err
+	} else if myPrivKeys, rerr := exchange.GetDecryptionKeys(keyPath); rerr != nil {
+		return nil, nil, err
+	} else {
+		return exchange.ReceiveExchangeMessage(message, myPrivKeys, msgId)
+	}
 }
 
 func (w *AgreementBotWorker) NoWorkHandler() {
 
-	w.noworkDispatch = time.Now().Unix()
+	w.noworkDispatch = worker.GetClock().Now().Unix()
 
 	glog.V(3).Infof("AgreementBotWorker queueing deferred commands")
 	for _, cph := range w.consumerPH.GetAll() {
@@ -919,7 +994,7 @@ func (w *AgreementBotWorker) policyWatcher(name string, quit chan bool) {
 			glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker %v exiting the subworker", name))
 			return
 
-		case <-time.After(time.Duration(w.Config.AgreementBot.CheckUpdatedPolicyS) * time.Second):
+		case <-worker.GetClock().After(time.Duration(w.Config.AgreementBot.CheckUpdatedPolicyS) * time.Second):
 			contents, _ = policy.PolicyFileChangeWatcher(w.Config.AgreementBot.PolicyPath, contents, w.Config.ArchSynonyms, w.changedPolicy, w.deletedPolicy, w.errorPolicy, w.serviceResolver, 0)
 		}
 	}
@@ -949,12 +1024,12 @@ func (w *AgreementBotWorker) errorPolicy(org string, fileName string, err error)
 	glog.Errorf(fmt.Sprintf("AgreementBotWorker tried to read policy file %v/%v, encountered error: %v", org, fileName, err))
 }
 
-func (w *AgreementBotWorker) getMessages(limit int) ([]exchange.AgbotMessage, error) {
+func (w *AgreementBotWorker) getMessages(ec *FederatedExchange, limit int) ([]exchange.AgbotMessage, error) {
 	var resp interface{}
 	resp = new(exchange.GetAgbotMessageResponse)
-	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/agbots/" + exchange.GetId(w.GetExchangeId()) + "/msgs?maxmsgs=" + strconv.Itoa(limit)
+	targetURL := ec.GetExchangeURL() + "orgs/" + exchange.GetOrg(ec.GetExchangeId()) + "/agbots/" + exchange.GetId(ec.GetExchangeId()) + "/msgs?maxmsgs=" + strconv.Itoa(limit)
 	for {
-		if err, tpErr := exchange.InvokeExchange(w.httpClient, "GET", targetURL, w.GetExchangeId(), w.GetExchangeToken(), nil, &resp); err != nil {
+		if err, tpErr := exchange.InvokeExchange(w.httpClient, "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(err.Error())
 			return nil, err
 		} else if tpErr != nil {
@@ -1074,7 +1149,8 @@ func (w *AgreementBotWorker) syncOnInit() error {
 					} else if existingPol := w.pm.GetPolicy(ag.Org, pol.Header.Name); existingPol == nil {
 						glog.Errorf(AWlogString(fmt.Sprintf("agreement %v has a policy %v that doesn't exist anymore", ag.CurrentAgreementId, pol.Header.Name)))
 						// Update state in exchange
-						if err := DeleteConsumerAgreement(w.Config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken(), ag.CurrentAgreementId); err != nil {
+						ec := w.exchanges.ForId(ag.DeviceId)
+						if err := DeleteConsumerAgreement(w.Config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken(), ag.CurrentAgreementId); err != nil {
 							glog.Errorf(AWlogString(fmt.Sprintf("error deleting agreement %v in exchange: %v", ag.CurrentAgreementId, err)))
 						}
 						// Remove any workload usage records so that a new agreement will be made starting from the highest priority workload
@@ -1095,7 +1171,7 @@ func (w *AgreementBotWorker) syncOnInit() error {
 						// There is a small window where an agreement might not have been recorded in the exchange. Let's just make sure.
 					} else {
 
-						if exchangeAgreement, err := w.getConsumerAgreementState(ag.CurrentAgreementId); err != nil {
+						if exchangeAgreement, err := w.getConsumerAgreementState(ag.CurrentAgreementId, ag.Org); err != nil {
 							glog.Errorf(AWlogString(fmt.Sprintf("encountered error getting agbot agreement %v from exchange, error %v", ag.CurrentAgreementId, err)))
 							continue
 						} else {
@@ -1126,7 +1202,7 @@ func (w *AgreementBotWorker) syncOnInit() error {
 					// After checking the policy, add it in to a map. In Each for loop which iterate the agreements, checking if current policy inside agreement has been handled or not
 					if pol != nil && !bPolicyCheckingMap[pol.Header.Name] {
 						glog.V(3).Infof(AWlogString(fmt.Sprintf("checking policy against exchange for agreement %v.", ag.CurrentAgreementId)))
-						if exchPols, err := exchange.GetBusinessPolicies(w, exchange.GetOrg(pol.Header.Name), exchange.GetId(pol.Header.Name)); err != nil {
+						if exchPols, err := exchange.GetBusinessPolicies(w.exchanges.ForId(pol.Header.Name), exchange.GetOrg(pol.Header.Name), exchange.GetId(pol.Header.Name)); err != nil {
 							glog.Errorf(AWlogString(fmt.Sprintf("error getting business policies from exchange for org: %v, policy name: %v error: %v", ag.Org, pol.Header.Name, err)))
 						} else if len(exchPols) == 0 {
 							glog.V(3).Infof(AWlogString(fmt.Sprintf("business policy %v from agreement %v is not found from exchange.", pol.Header.Name, ag.CurrentAgreementId)))
@@ -1174,7 +1250,7 @@ func (w *AgreementBotWorker) syncOnInit() error {
 			for org, typeMap := range neededBCInstances {
 				for typeName, instMap := range typeMap {
 					for instName, _ := range instMap {
-						w.Messages() <- events.NewNewBCContainerMessage(events.NEW_BC_CLIENT, typeName, instName, org, w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken())
+						w.Messages() <- events.NewNewBCContainerMessage(events.NEW_BC_CLIENT, typeName, instName, org, w.exchanges.ForOrg(org).GetExchangeURL(), w.exchanges.ForOrg(org).GetExchangeId(), w.exchanges.ForOrg(org).GetExchangeToken())
 					}
 				}
 			}
@@ -1226,9 +1302,10 @@ func (w *AgreementBotWorker) recordConsumerAgreementState(agreementId string, po
 
 	var resp interface{}
 	resp = new(exchange.AllAgbotAgreementsResponse)
-	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/agbots/" + exchange.GetId(w.GetExchangeId()) + "/agreements/" + agreementId
+	ec := w.exchanges.ForOrg(org)
+	targetURL := ec.GetExchangeURL() + "orgs/" + exchange.GetOrg(ec.GetExchangeId()) + "/agbots/" + exchange.GetId(ec.GetExchangeId()) + "/agreements/" + agreementId
 	for {
-		if err, tpErr := exchange.InvokeExchange(w.httpClient, "PUT", targetURL, w.GetExchangeId(), w.GetExchangeToken(), &as, &resp); err != nil {
+		if err, tpErr := exchange.InvokeExchange(w.httpClient, "PUT", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), &as, &resp); err != nil {
 			glog.Errorf(err.Error())
 			return err
 		} else if tpErr != nil {
@@ -1243,15 +1320,17 @@ func (w *AgreementBotWorker) recordConsumerAgreementState(agreementId string, po
 
 }
 
-func (w *AgreementBotWorker) getConsumerAgreementState(agreementId string) (map[string]exchange.AgbotAgreement, error) {
+// Get the agreement from the exchange that holds the agreement's org.
+func (w *AgreementBotWorker) getConsumerAgreementState(agreementId string, org string) (map[string]exchange.AgbotAgreement, error) {
 
 	glog.V(5).Infof(AWlogString(fmt.Sprintf("getting agbot agreement %v", agreementId)))
 
 	var resp interface{}
 	resp = new(exchange.AllAgbotAgreementsResponse)
-	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/agbots/" + exchange.GetId(w.GetExchangeId()) + "/agreements/" + agreementId
+	ec := w.exchanges.ForOrg(org)
+	targetURL := ec.GetExchangeURL() + "orgs/" + exchange.GetOrg(ec.GetExchangeId()) + "/agbots/" + exchange.GetId(ec.GetExchangeId()) + "/agreements/" + agreementId
 	for {
-		if err, tpErr := exchange.InvokeExchange(w.httpClient, "GET", targetURL, w.GetExchangeId(), w.GetExchangeToken(), nil, &resp); err != nil {
+		if err, tpErr := exchange.InvokeExchange(w.httpClient, "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(err.Error())
 			return nil, err
 		} else if tpErr != nil {
@@ -1276,15 +1355,15 @@ func listContains(list string, target string) bool {
 	return false
 }
 
-func (w *AgreementBotWorker) registerPublicKey() error {
-	glog.V(5).Infof(AWlogString(fmt.Sprintf("registering agbot public key")))
+func (w *AgreementBotWorker) registerPublicKey(ec *FederatedExchange) error {
+	glog.V(5).Infof(AWlogString(fmt.Sprintf("registering agbot public key in the %v exchange", ec.DisplayName())))
 
 	as := exchange.CreateAgbotPublicKeyPatch(w.Config.AgreementBot.MessageKeyPath)
 	var resp interface{}
 	resp = new(exchange.PostDeviceResponse)
-	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/agbots/" + exchange.GetId(w.GetExchangeId())
+	targetURL := ec.GetExchangeURL() + "orgs/" + exchange.GetOrg(ec.GetExchangeId()) + "/agbots/" + exchange.GetId(ec.GetExchangeId())
 	for {
-		if err, tpErr := exchange.InvokeExchange(w.httpClient, "PATCH", targetURL, w.GetExchangeId(), w.GetExchangeToken(), &as, &resp); err != nil {
+		if err, tpErr := exchange.InvokeExchange(w.httpClient, "PATCH", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), &as, &resp); err != nil {
 			glog.Errorf(err.Error())
 			return err
 		} else if tpErr != nil {
@@ -1300,40 +1379,62 @@ func (w *AgreementBotWorker) registerPublicKey() error {
 
 func (w *AgreementBotWorker) serviceResolver(wURL string, wOrg string, wVersion string, wArch string) (*policy.APISpecList, error) {
 
-	asl, _, _, err := exchange.GetHTTPServiceResolverHandler(w)(wURL, wOrg, wVersion, wArch)
+	asl, _, _, err := exchange.GetHTTPServiceResolverHandler(w.exchanges.ForOrg(wOrg))(wURL, wOrg, wVersion, wArch)
 	if err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("unable to resolve %v %v, error %v", wURL, wOrg, err)))
 	}
 	return asl, err
 }
 
-// Get the configured org/pattern/nodeorg triplet for this agbot.
+// Get the configured org/pattern/nodeorg triplet for this agbot, from each of the exchanges it serves. An exchange
+// only provides the patterns of the orgs that it holds.
 func (w *AgreementBotWorker) saveAgbotServedPatterns() {
-	servedPatterns, err := exchange.GetHTTPAgbotServedPattern(w)()
-	if err != nil {
-		glog.Errorf(AWlogString(fmt.Sprintf("unable to retrieve agbot served patterns, error %v", err)))
+	servedPatterns := make(map[string]exchange.ServedPattern)
+	for _, ec := range w.exchanges.Exchanges() {
+		served, err := exchange.GetHTTPAgbotServedPattern(ec)()
+		if err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to retrieve agbot served patterns from the %v exchange, error %v", ec.DisplayName(), err)))
+		}
+		for key, sp := range served {
+			if w.exchanges.ForOrg(sp.PatternOrg) != ec {
+				glog.Warningf(AWlogString(fmt.Sprintf("ignoring served pattern %v in the %v exchange, org %v is held by another exchange", sp, ec.DisplayName(), sp.PatternOrg)))
+				continue
+			}
+			servedPatterns[key] = sp
+		}
 	}
 
 	// Consume the configured org/pattern pairs into the PatternManager
-	if err = patternManager.SetCurrentPatterns(servedPatterns, w.Config.AgreementBot.PolicyPath); err != nil {
+	if err := patternManager.SetCurrentPatterns(servedPatterns, w.Config.AgreementBot.PolicyPath); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("unable to process agbot served patterns %v, error %v", servedPatterns, err)))
 	}
 }
 
-// Get the configured (policy org, business policy, node org) triplets for this agbot.
+// Get the configured (policy org, business policy, node org) triplets for this agbot, from each of the exchanges it
+// serves.
 func (w *AgreementBotWorker) saveAgbotServedPolicies() {
-	servedPolicies, err := exchange.GetHTTPAgbotServedDeploymentPolicy(w)()
-	if err != nil {
-		glog.Errorf(AWlogString(fmt.Sprintf("unable to retrieve agbot served deployment policies, error %v", err)))
+	servedPolicies := make(map[string]exchange.ServedBusinessPolicy)
+	for _, ec := range w.exchanges.Exchanges() {
+		served, err := exchange.GetHTTPAgbotServedDeploymentPolicy(ec)()
+		if err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to retrieve agbot served deployment policies from the %v exchange, error %v", ec.DisplayName(), err)))
+		}
+		for key, sp := range served {
+			if w.exchanges.ForOrg(sp.BusinessPolOrg) != ec {
+				glog.Warningf(AWlogString(fmt.Sprintf("ignoring served deployment policy %v in the %v exchange, org %v is held by another exchange", sp, ec.DisplayName(), sp.BusinessPolOrg)))
+				continue
+			}
+			servedPolicies[key] = sp
+		}
 	}
 
 	// Consume the configured (policy org, business policy, node org) triplets into the BusinessPolicyManager
-	if err = businessPolManager.SetCurrentBusinessPolicies(servedPolicies, w.pm); err != nil {
+	if err := businessPolManager.SetCurrentBusinessPolicies(servedPolicies, w.pm); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("unable to process agbot served deployment policies %v, error %v", servedPolicies, err)))
 	}
 
 	// Consume the configured (policy org, business policy, node org) triplets into the ObjectPolicyManager
-	if err = w.MMSObjectPM.SetCurrentPolicyOrgs(servedPolicies); err != nil {
+	if err := w.MMSObjectPM.SetCurrentPolicyOrgs(servedPolicies); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("unable to process agbot served deployment policies for MMS %v, error %v", servedPolicies, err)))
 	}
 
@@ -1355,13 +1456,14 @@ func (w *AgreementBotWorker) generatePolicyFromPatterns(msg *events.ExchangeChan
 		var err error
 
 		// check if the org exists on the exchange or not
-		if _, err = exchange.GetOrganization(w.Config.Collaborators.HTTPClientFactory, org, w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken()); err != nil {
+		ec := w.exchanges.ForOrg(org)
+		if _, err = exchange.GetOrganization(w.Config.Collaborators.HTTPClientFactory, org, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken()); err != nil {
 			// org does not exist is returned as an error
 			glog.V(5).Infof(AWlogString(fmt.Sprintf("unable to get organization %v: %v", org, err)))
 			exchangePatternMetadata = make(map[string]exchange.Pattern)
 		} else {
 			// Query exchange for all patterns in the org
-			if exchangePatternMetadata, err = exchange.GetPatterns(w.Config.Collaborators.HTTPClientFactory, org, "", w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken()); err != nil {
+			if exchangePatternMetadata, err = exchange.GetPatterns(w.Config.Collaborators.HTTPClientFactory, org, "", ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken()); err != nil {
 				return errors.New(fmt.Sprintf("unable to get patterns for org %v, error %v", org, err))
 			}
 		}
@@ -1399,14 +1501,15 @@ func (w *AgreementBotWorker) generatePolicyFromBusinessPols(msg *events.Exchange
 		var err error
 
 		// check if the org exists on the exchange or not
-		getOrganization := exchange.GetHTTPExchangeOrgHandler(w)
+		ec := w.exchanges.ForOrg(org)
+		getOrganization := exchange.GetHTTPExchangeOrgHandler(ec)
 		if _, err = getOrganization(org); err != nil {
 			// org does not exist is returned as an error
 			glog.V(5).Infof(AWlogString(fmt.Sprintf("unable to get organization %v: %v", org, err)))
 			exchPolsMetadata = make(map[string]exchange.ExchangeBusinessPolicy)
 		} else {
 			// Query exchange for all business policies in the org
-			getBusinessPolicies := exchange.GetHTTPBusinessPoliciesHandler(w)
+			getBusinessPolicies := exchange.GetHTTPBusinessPoliciesHandler(ec)
 			if exchPolsMetadata, err = getBusinessPolicies(org, ""); err != nil {
 				return errors.New(fmt.Sprintf("unable to get business polices for org %v, error %v", org, err))
 			}
@@ -1445,7 +1548,7 @@ func (w *AgreementBotWorker) handleObjectPoliciesChange(msg *events.MMSObjectPol
 	org := objPolChanges[0].OrgID
 
 	// Check for policy metadata changes and update policies accordingly. Publish any status change events.
-	if events, err := w.MMSObjectPM.UpdatePolicies(org, &objPolChanges, exchange.GetHTTPObjectQueryHandler(w)); err != nil {
+	if events, err := w.MMSObjectPM.UpdatePolicies(org, &objPolChanges, exchange.GetHTTPObjectQueryHandler(w.exchanges.ForOrg(org))); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("unable to update object policies for org %v, error %v", org, err)))
 	} else {
 		for _, ev := range events {
@@ -1505,7 +1608,7 @@ func (w *AgreementBotWorker) updateServicePolicies(msg *events.ExchangeChangeMes
 // Get service policy
 func (w *AgreementBotWorker) getServicePolicy(svcId string) (*externalpolicy.ExternalPolicy, error) {
 
-	servicePolicyHandler := exchange.GetHTTPServicePolicyWithIdHandler(w)
+	servicePolicyHandler := exchange.GetHTTPServicePolicyWithIdHandler(w.exchanges.ForId(svcId))
 	servicePolicy, err := servicePolicyHandler(svcId)
 	if err != nil {
 		return nil, fmt.Errorf("error trying to query service policy for %v: %v", svcId, err)
@@ -1546,6 +1649,8 @@ func (w *AgreementBotWorker) stalePartitions() int {
 		if claimed, err := w.db.MovePartition(w.Config.GetPartitionStale()); err != nil {
 			glog.Errorf(AWlogString(fmt.Sprintf("Error claiming an unowned partition, error: %v", err)))
 		} else if claimed {
+			partitionClaims.Inc()
+
 			// Perform the same sanity checks on existing agreements when we pick up a new set of agreements
 			// in the new partition.
 			if err := w.syncOnInit(); err != nil {
@@ -1556,22 +1661,57 @@ func (w *AgreementBotWorker) stalePartitions() int {
 	return 0
 }
 
+// Load the messaging keys from the filesystem again and return the public key.
+func (w *AgreementBotWorker) reloadMessageKey() []byte {
+	if pubKey, _, err := exchange.ReloadKeys(w.Config.AgreementBot.MessageKeyPath); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to reload message key, error: %v", err)))
+		return nil
+	} else if b, err := exchange.MarshalPublicKey(pubKey); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to marshal message key, error: %v", err)))
+		return nil
+	} else {
+		return b
+	}
+}
+
+// Replace the agbot's messaging key when it is older than the configured rotation interval.
+func (w *AgreementBotWorker) rotateMessageKey() int {
+	keyPath := w.Config.AgreementBot.MessageKeyPath
+	if !exchange.KeyRotationDue(keyPath, w.Config.AgreementBot.MessageKeyRotationIntervalH) {
+		return 0
+	}
+
+	glog.V(3).Infof(AWlogString(fmt.Sprintf("rotating agbot message key")))
+	if err := w.exchanges.RotateMessageKey(keyPath, w.Config.AgreementBot.GetMessageKeyGracePeriodS()); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to rotate message key, error: %v", err)))
+	}
+	return 0
+}
+
 // Ensure that the agbot's message key is still in its object in the exchange. If the agbot itself is missing,
 // we will panic (that should not happen). If the key is missing (i.e. the current key is a zero length byte array)
 // we will add our key back. If there is a key but it is just wrong, we will panic. This latter case could occur if
 // multiple agbots are setup without sharing the same messaging key.
 func (w *AgreementBotWorker) messageKeyCheck() int {
+	for _, ec := range w.exchanges.Exchanges() {
+		w.checkMessageKey(ec)
+	}
+	return 0
+}
 
-	glog.V(5).Infof(AWlogString(fmt.Sprintf("checking agbot message key")))
+// Make sure that the agbot's message key is the one in an exchange.
+func (w *AgreementBotWorker) checkMessageKey(ec *FederatedExchange) {
+
+	glog.V(5).Infof(AWlogString(fmt.Sprintf("checking agbot message key in the %v exchange", ec.DisplayName())))
 
 	key := exchange.CreateAgbotPublicKeyPatch(w.Config.AgreementBot.MessageKeyPath).PublicKey
 	var resp interface{}
 	resp = new(exchange.GetAgbotsResponse)
-	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/agbots/" + exchange.GetId(w.GetExchangeId())
+	targetURL := ec.GetExchangeURL() + "orgs/" + exchange.GetOrg(ec.GetExchangeId()) + "/agbots/" + exchange.GetId(ec.GetExchangeId())
 	for {
-		if err, tpErr := exchange.InvokeExchange(w.httpClient, "GET", targetURL, w.GetExchangeId(), w.GetExchangeToken(), nil, &resp); err != nil {
+		if err, tpErr := exchange.InvokeExchange(w.httpClient, "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(err.Error())
-			return 0
+			return
 		} else if tpErr != nil {
 			glog.Warningf(tpErr.Error())
 			time.Sleep(10 * time.Second)
@@ -1580,8 +1720,8 @@ func (w *AgreementBotWorker) messageKeyCheck() int {
 
 			// Got a response from the exchange. Make sure this agbot is in the response.
 			ags := resp.(*exchange.GetAgbotsResponse).Agbots
-			if agbot, there := ags[w.GetExchangeId()]; !there {
-				msg := AWlogString(fmt.Sprintf("agbot %v not in GET response %v as expected", w.GetExchangeId(), ags))
+			if agbot, there := ags[ec.GetExchangeId()]; !there {
+				msg := AWlogString(fmt.Sprintf("agbot %v not in GET response %v as expected", ec.GetExchangeId(), ags))
 				glog.Errorf(msg)
 				panic(msg)
 
@@ -1589,15 +1729,16 @@ func (w *AgreementBotWorker) messageKeyCheck() int {
 
 				// There is no message key in the exchange, this should not happen but we can fix it, so we will add it back in if we can.
 				glog.Errorf(AWlogString(fmt.Sprintf("agbot message key is empty, adding it back in %v", key)))
-				if err := w.registerPublicKey(); err != nil {
+				if err := w.registerPublicKey(ec); err != nil {
 					msg := AWlogString(fmt.Sprintf("unable to register public key, error: %v", err))
 					glog.Errorf(msg)
 					panic(msg)
 				}
 
-			} else if !bytes.Equal(key, agbot.PublicKey) {
+			} else if !bytes.Equal(key, agbot.PublicKey) && !bytes.Equal(w.reloadMessageKey(), agbot.PublicKey) {
 
-				// Make sure the message key in the exchange is our key. If not, exit quickly.
+				// Make sure the message key in the exchange is our key, after picking up a key that might have been rotated by
+				// another agbot instance. If not, exit quickly.
 				msg := AWlogString(fmt.Sprintf("agbot message key has changed from %v to %v", key, agbot.PublicKey))
 				glog.Errorf(msg)
 				panic(msg)
@@ -1605,7 +1746,7 @@ func (w *AgreementBotWorker) messageKeyCheck() int {
 			} else {
 				glog.V(5).Infof(AWlogString(fmt.Sprintf("agbot message key is present")))
 			}
-			return 0
+			return
 
 		}
 	}
@@ -1642,6 +1783,7 @@ func (w *AgreementBotWorker) secretsUpdate() int {
 
 		// Send out an event with the changed secrets and affected policies in it.
 		if secretUpdates != nil && secretUpdates.Length() != 0 {
+			secretUpdatesDetected.Add(float64(secretUpdates.Length()))
 			w.Messages() <- events.NewSecretUpdatesMessage(events.UPDATED_SECRETS, secretUpdates)
 			nextRunWait = w.secretUpdateManager.AdjustSecretsPollingInterval(secretUpdates.Length())
 		} else {
@@ -1660,12 +1802,13 @@ func (w *AgreementBotWorker) monitorHAGroupNMPUpdates() int {
 
 	for _, node := range upgradingNodes {
 		remove := false
-		if exNode, err := exchange.GetExchangeDevice(w.GetHTTPFactory(), fmt.Sprintf("%v/%v", node.OrgId, node.NodeId), w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL()); err != nil {
+		ec := w.exchanges.ForOrg(node.OrgId)
+		if exNode, err := exchange.GetExchangeDevice(w.GetHTTPFactory(), fmt.Sprintf("%v/%v", node.OrgId, node.NodeId), ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL()); err != nil {
 			glog.Errorf(AWlogString(fmt.Sprintf("error getting node %v/%v: %v", node.OrgId, node.NodeId, err)))
 			continue
 		} else if exNode == nil || exNode.HAGroup != node.GroupName {
 			remove = true
-		} else if nmpStatus, err := exchange.GetNodeManagementPolicyStatus(w, node.OrgId, node.NodeId, node.NMPName); err != nil {
+		} else if nmpStatus, err := exchange.GetNodeManagementPolicyStatus(ec, node.OrgId, node.NodeId, node.NMPName); err != nil {
 			glog.Errorf(AWlogString(fmt.Sprintf("error getting nmp status %v/%v/%v: %v", node.OrgId, node.NodeId, node.NMPName, err)))
 			continue
 		} else if !exchangecommon.IsActiveStatus(nmpStatus.Status()) {
@@ -1734,7 +1877,8 @@ func (w *AgreementBotWorker) updateWorkloadUsageForHAGroup(org string, groupName
 	glog.V(3).Info(AWlogString(fmt.Sprintf("AgreementBot start to update workload usages after HA group change for %v/%v", org, groupName)))
 
 	// get hs group node IDs
-	haGroup, err := GetHAGroup(org, groupName, w.GetHTTPFactory().NewHTTPClient(nil), w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken())
+	ec := w.exchanges.ForOrg(org)
+	haGroup, err := GetHAGroup(org, groupName, w.GetHTTPFactory().NewHTTPClient(nil), ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
 	if err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("Failed to get HA group %v/%v from the exchange. %v", org, groupName, err)))
 		return
@@ -1790,7 +1934,8 @@ func (w *AgreementBotWorker) removeInvalidEntriesFrom_HA_WLU(haWorkloads []persi
 	for _, ha_wlu := range haWorkloads {
 		bDelete := false
 
-		exHAGroup, err := GetHAGroup(ha_wlu.OrgId, ha_wlu.GroupName, w.GetHTTPFactory().NewHTTPClient(nil), w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken())
+		ec := w.exchanges.ForOrg(ha_wlu.OrgId)
+		exHAGroup, err := GetHAGroup(ha_wlu.OrgId, ha_wlu.GroupName, w.GetHTTPFactory().NewHTTPClient(nil), ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
 		if err != nil {
 			return errors.New(fmt.Sprintf("unable to get HA group %v/%v from exchange , error %v", ha_wlu.OrgId, ha_wlu.GroupName, err))
 		}
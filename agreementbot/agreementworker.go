@@ -24,6 +24,7 @@ import (
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"golang.org/x/text/message"
 )
@@ -169,6 +170,7 @@ type CancelAgreement struct {
 	Protocol    string
 	Reason      uint
 	MessageId   int
+	SenderId    string // exchange Id of the sender of the cancel message, if there is one
 }
 
 func (c CancelAgreement) Type() string {
@@ -189,6 +191,18 @@ func NewCancelAgreement(agId string, protocol string, reason uint, messageId int
 	}
 }
 
+// A cancel that was received in a message from the node.
+func NewCancelAgreementFromMessage(agId string, protocol string, reason uint, messageId int, senderId string) AgreementWork {
+	return CancelAgreement{
+		workType:    CANCEL,
+		AgreementId: agId,
+		Protocol:    protocol,
+		Reason:      reason,
+		MessageId:   messageId,
+		SenderId:    senderId,
+	}
+}
+
 type HandleWorkloadUpgrade struct {
 	workType    string
 	AgreementId string
@@ -287,11 +301,17 @@ type BaseAgreementWorker struct {
 	mmsObjMgr  *MMSObjectPolicyManager
 	secretsMgr secrets.AgbotSecrets
 	nodeSearch *NodeSearch
+	active     *tracing.ActiveSpan // The agreement being worked on, if it is traced
+	exchanges  *ExchangeFederation // The exchanges holding the nodes that agreements are made with
+	current    *FederatedExchange  // The exchange holding the node of the work item being handled, if it is set
 }
 
 // A local implementation of the ExchangeContext interface because Agbot agreement workers are not full featured workers.
+// While a work item is being handled, the exchange is the one that holds the work item's node.
 func (b *BaseAgreementWorker) GetExchangeId() string {
-	if b.ec != nil {
+	if b.current != nil {
+		return b.current.GetExchangeId()
+	} else if b.ec != nil {
 		return b.ec.Id
 	} else {
 		return ""
@@ -299,7 +319,9 @@ func (b *BaseAgreementWorker) GetExchangeId() string {
 }
 
 func (b *BaseAgreementWorker) GetExchangeToken() string {
-	if b.ec != nil {
+	if b.current != nil {
+		return b.current.GetExchangeToken()
+	} else if b.ec != nil {
 		return b.ec.Token
 	} else {
 		return ""
@@ -307,7 +329,9 @@ func (b *BaseAgreementWorker) GetExchangeToken() string {
 }
 
 func (b *BaseAgreementWorker) GetExchangeURL() string {
-	if b.ec != nil {
+	if b.current != nil {
+		return b.current.GetExchangeURL()
+	} else if b.ec != nil {
 		return b.ec.URL
 	} else {
 		return ""
@@ -315,7 +339,9 @@ func (b *BaseAgreementWorker) GetExchangeURL() string {
 }
 
 func (b *BaseAgreementWorker) GetCSSURL() string {
-	if b.ec != nil {
+	if b.current != nil {
+		return b.current.GetCSSURL()
+	} else if b.ec != nil {
 		return b.ec.CSSURL
 	} else {
 		return ""
@@ -332,12 +358,18 @@ func (b *BaseAgreementWorker) GetAgbotURL() string {
 
 func (b *BaseAgreementWorker) GetHTTPFactory() *config.HTTPClientFactory {
 	if b.ec != nil {
-		return b.ec.HTTPFactory
+		return tracing.TracedFactory(b.ec.HTTPFactory, b.active.Context)
 	} else {
-		return b.config.Collaborators.HTTPClientFactory
+		return tracing.TracedFactory(b.config.Collaborators.HTTPClientFactory, b.active.Context)
 	}
 }
 
+// Use the exchange that holds the input node until the returned function is called.
+func (b *BaseAgreementWorker) useExchangeOf(deviceId string) func() {
+	b.current = b.exchanges.ForId(deviceId)
+	return func() { b.current = nil }
+}
+
 func (b *BaseAgreementWorker) AgreementLockManager() *AgreementLockManager {
 	return b.alm
 }
@@ -364,6 +396,8 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 
 	msgPrinter := i18n.GetMessagePrinter()
 
+	defer b.useExchangeOf(wi.Device.Id)()
+
 	// get node policy
 	nodePolicyHandler := exchange.GetHTTPNodePolicyHandler(b)
 	_, nodePolicy, err := compcheck.GetNodePolicy(nodePolicyHandler, wi.Device.Id, msgPrinter)
@@ -415,6 +449,19 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		glog.Infof(BAWlogstring(workerId, fmt.Sprintf("using AgreementId %v", agreementIdString)))
 	}
 
+	// The agreement's trace is rooted here, where the agbot starts to make the agreement. The node's work on the
+	// agreement joins the same trace because the trace id is derived from the agreement id.
+	rootSpan := tracing.StartSpanWithContext("agreement", tracing.AgreementTraceContext(agreementIdString), tracing.SpanContext{})
+	rootSpan.SetAttribute("agreement.id", agreementIdString)
+	rootSpan.SetAttribute("agreement.protocol", cph.Name())
+	rootSpan.SetAttribute("node.id", wi.Device.Id)
+	rootSpan.SetAttribute("policy", wi.ConsumerPolicy.Header.Name)
+	b.active.Set(rootSpan)
+	defer func() {
+		b.active.Set(nil)
+		rootSpan.End()
+	}()
+
 	bcType, bcName, bcOrg := (&wi.ProducerPolicy).RequiresKnownBC(cph.Name())
 
 	// Use the blockchain name to choose the handler
@@ -433,7 +480,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 	// workload in the current consumer policy. If that's the case, query the exchange to get all the device
 	// policies so we can merge them.
 	var exchangeDev *exchange.Device
-	if theDev, err := GetDevice(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), wi.Device.Id, b.config.AgreementBot.ExchangeURL, cph.GetExchangeId(), cph.GetExchangeToken()); err != nil {
+	if theDev, err := GetDevice(b.GetHTTPFactory().NewHTTPClient(nil), wi.Device.Id, b.GetExchangeURL(), b.GetExchangeId(), b.GetExchangeToken()); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error getting device %v policies, error: %v", wi.Device.Id, err)))
 		return
 	} else {
@@ -534,7 +581,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		if workload.Arch == "" || workload.Arch == "*" {
 			//workload.Arch = exchangeDev.Arch
 			// Need to find if there is a service exists which arch matches the device arch
-			servicesMap, err := exchange.GetHTTPSelectedServicesHandler(cph)(workload.WorkloadURL, workload.Org, workload.Version, workloadArch)
+			servicesMap, err := exchange.GetHTTPSelectedServicesHandler(b)(workload.WorkloadURL, workload.Org, workload.Version, workloadArch)
 			findService := false
 			if err != nil {
 				glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error searching for service details with workload arch set to empty, error: %v", err)))
@@ -561,7 +608,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 			workloadArch = workload.Arch
 		}
 
-		asl, workloadDetails, sIds, err := exchange.GetHTTPServiceResolverHandler(cph)(workload.WorkloadURL, workload.Org, workload.Version, workload.Arch)
+		asl, workloadDetails, sIds, err := exchange.GetHTTPServiceResolverHandler(b)(workload.WorkloadURL, workload.Org, workload.Version, workload.Arch)
 		if err != nil {
 			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error searching for service details with workload arch:%v, %v, error: %v", workloadArch, workload, err)))
 			return
@@ -577,6 +624,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		t_comp, t_reason := compcheck.CheckTypeCompatibility(nodeType, &topSvcDef, msgPrinter)
 		if !t_comp {
 			glog.Warningf(BAWlogstring(workerId, fmt.Sprintf("cannot make agreement with node %v for service %v/%v %v. %v", wi.Device.Id, workload.Org, workload.WorkloadURL, workload.Version, t_reason)))
+			b.logProposalNotSent(wi, agreementIdString, workload, t_reason)
 			return
 		}
 
@@ -586,6 +634,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 			t_comp, consumerNamespace, t_reason = compcheck.CheckClusterNamespaceCompatibility(nodeType, exchangeDev.ClusterNamespace, exchangeDev.IsNamespaceScoped, wi.ConsumerPolicy.ClusterNamespace, topSvcDef.GetClusterDeployment(), wi.ConsumerPolicy.PatternId, false, msgPrinter)
 			if !t_comp {
 				glog.Warningf(BAWlogstring(workerId, fmt.Sprintf("cannot make agreement with node %v for service %v/%v %v. %v", wi.Device.Id, workload.Org, workload.WorkloadURL, workload.Version, t_reason)))
+				b.logProposalNotSent(wi, agreementIdString, workload, t_reason)
 				return
 			} else {
 				wi.ConsumerPolicy.ClusterNamespace = consumerNamespace
@@ -619,7 +668,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		}
 
 		// get dependent service definitions for later use
-		_, depServices, _, _, err := exchange.GetHTTPServiceDefResolverHandler(cph)(workload.WorkloadURL, workload.Org, workload.Version, workload.Arch)
+		_, depServices, _, _, err := exchange.GetHTTPServiceDefResolverHandler(b)(workload.WorkloadURL, workload.Org, workload.Version, workload.Arch)
 		if err != nil {
 			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error searching for dependent service details for %v, error: %v", workload, err)))
 			return
@@ -719,6 +768,13 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 
 		// All the error cases have been checked, now decide whether to propose this workload or try another version
 		if !policy_match || !userInput_match || !secrets_match {
+			if !policy_match {
+				b.logProposalNotSent(wi, agreementIdString, workload, "the node is not compatible with the policy of the service")
+			} else if !userInput_match {
+				b.logProposalNotSent(wi, agreementIdString, workload, "the user input does not meet the requirements of the service")
+			} else {
+				b.logProposalNotSent(wi, agreementIdString, workload, "the secrets of the service could not be processed")
+			}
 			if !workload.HasEmptyPriority() {
 				// If this is not the first time through the loop, update the workload usage record, otherwise create it.
 				if lastWorkload != nil {
@@ -795,7 +851,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 
 	// if the node max heartbeat interval is not set on the node, then get if from the org
 	if nodeMaxHBInterval == 0 {
-		exchOrg, err := exchange.GetOrganization(b.config.Collaborators.HTTPClientFactory, exchange.GetOrg(wi.Device.Id), b.config.AgreementBot.ExchangeURL, cph.GetExchangeId(), cph.GetExchangeToken())
+		exchOrg, err := exchange.GetOrganization(b.GetHTTPFactory(), exchange.GetOrg(wi.Device.Id), b.GetExchangeURL(), b.GetExchangeId(), b.GetExchangeToken())
 		if err != nil {
 			glog.Errorf(BAWlogstring(workerId, fmt.Errorf("Unable to get org %v from exchange: %v", exchange.GetOrg(wi.Device.Id), err)))
 		}
@@ -814,9 +870,12 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 	// Create pending agreement in database
 	if err := b.db.AgreementAttempt(agreementIdString, wi.Org, wi.Device.Id, nodeType, wi.ConsumerPolicy.Header.Name, bcType, bcName, bcOrg, cph.Name(), wi.ConsumerPolicy.PatternId, svcIds, wi.ConsumerPolicy.NodeH, b.config.AgreementBot.GetProtocolTimeout(nodeMaxHBInterval), b.config.AgreementBot.GetAgreementTimeout(nodeMaxHBInterval)); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error persisting agreement attempt: %v", err)))
+		return
+	}
+	recordAgreementTransition(cph.Name(), AG_STATE_ATTEMPTED)
 
-		// Decoding device publicKey to []byte
-	} else if publicKeyBytes, err := base64.StdEncoding.DecodeString(wi.Device.PublicKey); err != nil {
+	// Decoding device publicKey to []byte
+	if publicKeyBytes, err := base64.StdEncoding.DecodeString(wi.Device.PublicKey); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error decoding device publicKey for node: %s, %v", wi.Device.Id, err)))
 
 		// Create message target for protocol message
@@ -824,8 +883,9 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error creating message target: %v", err)))
 
 		// Initiate the protocol
-	} else if proposal, err := protocolHandler.InitiateAgreement(agreementIdString, &wi.ProducerPolicy, &wi.ConsumerPolicy, wi.Org, cph.GetExchangeId(), mt, workload, b.config.AgreementBot.DefaultWorkloadPW, b.config.AgreementBot.NoDataIntervalS, cph.GetSendMessage()); err != nil {
+	} else if proposal, err := b.initiateAgreement(protocolHandler, agreementIdString, wi, cph, mt, workload); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error initiating agreement: %v", err)))
+		rootSpan.SetError(err)
 
 		// Remove pending agreement from database
 		if err := b.db.DeleteAgreement(agreementIdString, cph.Name()); err != nil {
@@ -834,13 +894,33 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 
 		// TODO: Publish error on the message bus
 
+	} else {
+		// The proposal has been sent to the node.
+		recordProposal(wi.Org, wi.ConsumerPolicy.Header.Name, PROPOSAL_SENT)
+		saveEventLog(b.db, persistence.NewAgbotEventLog(persistence.SEVERITY_INFO, persistence.EC_AB_PROPOSAL_SENT,
+			fmt.Sprintf("Sent agreement proposal for service %v/%v %v to node %v.", workload.Org, workload.WorkloadURL, workload.Version, wi.Device.Id),
+			agreementIdString, wi.Device.Id, wi.Org, wi.ConsumerPolicy.Header.Name, ""))
+		recordAgreementTransition(cph.Name(), AG_STATE_PROPOSED)
+
 		// Update the agreement in the DB with the proposal and policy
-	} else if err := cph.PersistAgreement(wi, proposal, workerId); err != nil {
-		glog.Errorf(err.Error())
+		if err := cph.PersistAgreement(wi, proposal, workerId); err != nil {
+			glog.Errorf(err.Error())
+		}
 	}
 
 }
 
+// Send the agreement proposal to the node, recording the time it takes in its own span.
+func (b *BaseAgreementWorker) initiateAgreement(protocolHandler abstractprotocol.ProtocolHandler, agreementId string, wi *InitiateAgreement, cph ConsumerProtocolHandler, mt interface{}, workload *policy.Workload) (abstractprotocol.Proposal, error) {
+	span := tracing.StartSpan("propose", tracing.AgreementTraceContext(agreementId))
+	span.SetAttribute("node.id", wi.Device.Id)
+	defer span.End()
+
+	proposal, err := protocolHandler.InitiateAgreement(agreementId, &wi.ProducerPolicy, &wi.ConsumerPolicy, wi.Org, b.GetExchangeId(), mt, workload, b.config.AgreementBot.DefaultWorkloadPW, b.config.AgreementBot.NoDataIntervalS, cph.GetSendMessage())
+	span.SetError(err)
+	return proposal, err
+}
+
 // get the merged producer policy. asl is the spec list for the dependent services for a top level service.
 func (b *BaseAgreementWorker) GetMergedProducerPolicyForPattern(deviceId string, dev *exchange.Device, asl policy.APISpecList) (*policy.Policy, error) {
 	var mergedProducer *policy.Policy
@@ -1003,15 +1083,34 @@ func (b *BaseAgreementWorker) ValidateAndExtractSecrets(consumerPolicy *policy.P
 
 }
 
+// Save the reason that a proposal was not sent to a node in the event log.
+func (b *BaseAgreementWorker) logProposalNotSent(wi *InitiateAgreement, agreementId string, workload *policy.Workload, reason string) {
+	saveEventLog(b.db, persistence.NewAgbotEventLog(persistence.SEVERITY_WARN, persistence.EC_AB_PROPOSAL_NOT_SENT,
+		fmt.Sprintf("Did not send an agreement proposal for service %v/%v %v to node %v: %v.", workload.Org, workload.WorkloadURL, workload.Version, wi.Device.Id, reason),
+		agreementId, wi.Device.Id, wi.Org, wi.ConsumerPolicy.Header.Name, reason))
+}
+
 func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler, wi *HandleReply, workerId string) bool {
 
 	reply := wi.Reply
 	protocolHandler := cph.AgreementProtocolHandler("", "", "") // Use the generic protocol handler
 
+	defer b.useExchangeOf(wi.SenderId)()
+
 	// The reply message is usually deleted before recording on the blockchain. For now assume it will be deleted at the end. Early exit from
 	// this function is NOT allowed.
 	deletedMessage := false
 
+	// The reply is handled in the agreement's trace.
+	span := tracing.StartSpan("reply", tracing.AgreementTraceContext(reply.AgreementId()))
+	span.SetAttribute("node.id", wi.SenderId)
+	span.SetAttribute("proposal.accepted", reply.ProposalAccepted())
+	b.active.Set(span)
+	defer func() {
+		b.active.Set(nil)
+		span.End()
+	}()
+
 	// Get the agreement id lock to prevent any other thread from processing this same agreement.
 	lock := b.alm.getAgreementLock(wi.Reply.AgreementId())
 	lock.Lock()
@@ -1062,6 +1161,10 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 		} else {
 			// Done handling the response successfully
 			ackReplyAsValid = true
+			recordProposal(agreement.Org, agreement.PolicyName, PROPOSAL_ACCEPTED)
+			logAgreementEvent(b.db, persistence.SEVERITY_INFO, persistence.EC_AB_PROPOSAL_ACCEPTED, agreement, "",
+				fmt.Sprintf("Node %v accepted the agreement proposal.", agreement.DeviceId))
+			recordAgreementTransition(cph.Name(), AG_STATE_REPLIED)
 
 			// If we dont have a workload usage record for this device, then we need to create one. If there is already a
 			// workload usage record and workload rollback retry counting is enabled, then check to see if the workload priority
@@ -1078,7 +1181,7 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 					// Need a new workload usage record but not the same as the highest priority. That can't be right.
 					ackReplyAsValid = false
 				} else {
-					if theDev, err := GetDevice(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), wi.SenderId, b.config.AgreementBot.ExchangeURL, cph.GetExchangeId(), cph.GetExchangeToken()); err != nil {
+					if theDev, err := GetDevice(b.GetHTTPFactory().NewHTTPClient(nil), wi.SenderId, b.GetExchangeURL(), b.GetExchangeId(), b.GetExchangeToken()); err != nil {
 						glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error getting device %v policies, error: %v", wi.SenderId, err)))
 					} else if !pol.Workloads[0].HasEmptyPriority() || theDev.HAGroup != "" {
 						// workload usage is used to track the priorities as well as the service upgrades for HA groups
@@ -1131,7 +1234,7 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 
 				// Delete the original reply message
 				if wi.MessageId != 0 {
-					if err := cph.DeleteMessage(wi.MessageId); err != nil {
+					if err := cph.DeleteMessage(wi.MessageId, wi.SenderId); err != nil {
 						glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error deleting message %v from exchange for agbot %v", wi.MessageId, cph.GetExchangeId())))
 					}
 				}
@@ -1161,6 +1264,14 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 	} else {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("received rejection from producer %v", reply)))
 
+		if agreement, err := b.db.FindSingleAgreementByAgreementId(reply.AgreementId(), cph.Name(), []persistence.AFilter{}); err != nil {
+			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error querying rejected agreement %v, error: %v", reply.AgreementId(), err)))
+		} else if agreement != nil {
+			recordProposal(agreement.Org, agreement.PolicyName, PROPOSAL_REJECTED)
+			logAgreementEvent(b.db, persistence.SEVERITY_WARN, persistence.EC_AB_PROPOSAL_REJECTED, agreement, TERM_REASON_NEGATIVE_REPLY,
+				fmt.Sprintf("Node %v rejected the agreement proposal.", agreement.DeviceId))
+		}
+
 		b.AddRetry(cph, reply.AgreementId(), workerId)
 
 		// Returns true if the protocol msg can be deleted.
@@ -1175,11 +1286,12 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 
 	// Get rid of the exchange message if there is one
 	if wi.MessageId != 0 && !deletedMessage {
-		if err := cph.DeleteMessage(wi.MessageId); err != nil {
+		if err := cph.DeleteMessage(wi.MessageId, wi.SenderId); err != nil {
 			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error deleting message %v from exchange for agbot %v", wi.MessageId, cph.GetExchangeId())))
 		}
 	}
 
+	span.SetAttribute("reply.valid", ackReplyAsValid)
 	return ackReplyAsValid
 
 }
@@ -1222,7 +1334,7 @@ func (b *BaseAgreementWorker) HandleDataReceivedAck(cph ConsumerProtocolHandler,
 
 	// Get rid of the exchange message if there is one
 	if wi.MessageId != 0 && deleteMessage {
-		if err := cph.DeleteMessage(wi.MessageId); err != nil {
+		if err := cph.DeleteMessage(wi.MessageId, wi.SenderId); err != nil {
 			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error deleting message %v from exchange for agbot %v", wi.MessageId, cph.GetExchangeId())))
 		}
 	}
@@ -1323,8 +1435,9 @@ func (b *BaseAgreementWorker) CancelAgreement(cph ConsumerProtocolHandler, agree
 		return false
 	}
 
-	// Update state in exchange
-	if err := DeleteConsumerAgreement(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), b.config.AgreementBot.ExchangeURL, cph.GetExchangeId(), cph.GetExchangeToken(), agreementId); err != nil {
+	// Update state in the exchange that holds the node.
+	ec := b.exchanges.ForId(ag.DeviceId)
+	if err := DeleteConsumerAgreement(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken(), agreementId); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error deleting agreement %v in exchange: %v", agreementId, err)))
 	}
 
@@ -1375,6 +1488,11 @@ func (b *BaseAgreementWorker) CancelAgreement(cph ConsumerProtocolHandler, agree
 	// Archive the record
 	if _, err := b.db.ArchiveAgreement(ag.CurrentAgreementId, cph.Name(), reason, cph.GetTerminationReason(reason)); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error archiving terminated agreement: %v, error: %v", ag.CurrentAgreementId, err)))
+	} else {
+		recordAgreementTransition(cph.Name(), AG_STATE_ARCHIVED)
+		agreementCancelCounter.Inc(cph.Name(), cph.GetTerminationReason(reason))
+		logAgreementEvent(b.db, persistence.SEVERITY_INFO, persistence.EC_AB_AGREEMENT_CANCELLED, ag, cph.GetTerminationReason(reason),
+			fmt.Sprintf("Cancelled the agreement with node %v, reason: %v.", ag.DeviceId, cph.GetTerminationReason(reason)))
 	}
 
 	return true
@@ -19,6 +19,7 @@ import (
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/metrics"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/worker"
 )
@@ -31,6 +32,7 @@ type API struct {
 	bcState        map[string]map[string]apicommon.BlockchainState
 	bcStateLock    sync.Mutex
 	EC             *worker.BaseExchangeContext
+	exchanges      *ExchangeFederation
 	em             *events.EventStateManager
 	shutdownError  string
 	configFile     string
@@ -49,6 +51,7 @@ func NewAPIListener(name string, config *config.HorizonConfig, db persistence.Ag
 		name:           name,
 		db:             db,
 		EC:             worker.NewExchangeContext(config.AgreementBot.ExchangeId, config.AgreementBot.ExchangeToken, config.AgreementBot.ExchangeURL, config.GetAgbotCSSURL(), "", config.Collaborators.HTTPClientFactory),
+		exchanges:      NewExchangeFederation(config),
 		em:             events.NewEventStateManager(),
 		configFile:     configFile,
 		secretProvider: s,
@@ -106,6 +109,15 @@ func (a *API) NewEvent(ev events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (a *API) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.BlockchainClientInitializedMessage{},
+		&events.BlockchainClientStoppingMessage{},
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 func (a *API) saveShutdownError(msg events.Message) {
 	switch msg.(type) {
 	case *events.NodeShutdownCompleteMessage:
@@ -357,11 +369,15 @@ func (a *API) listen(apiListen string) {
 		router.HandleFunc("/policy/{org}/{name}", a.policy).Methods("GET", "OPTIONS")
 		router.HandleFunc("/policy/{name}/upgrade", a.policy).Methods("POST", "OPTIONS")
 		router.HandleFunc("/workloadusage", a.workloadusage).Methods("GET", "OPTIONS")
+		router.HandleFunc("/eventlog", a.eventlog).Methods("GET", "OPTIONS")
 		router.HandleFunc("/status", a.status).Methods("GET", "OPTIONS")
 		router.HandleFunc("/health", a.health).Methods("GET", "OPTIONS")
 		router.HandleFunc("/status/workers", a.workerstatus).Methods("GET", "OPTIONS")
+		router.HandleFunc("/debug/eventtap", a.eventTap).Methods("GET", "OPTIONS")
+		router.HandleFunc("/metrics", metrics.Handler(func() { refreshPartitionMetrics(a.db) })).Methods("GET", "OPTIONS")
 		router.HandleFunc("/node", a.node).Methods("GET", "DELETE", "OPTIONS")
 		router.HandleFunc("/config", a.config).Methods("GET", "OPTIONS")
+		router.HandleFunc("/messagekey", a.messagekey).Methods("GET", "PUT", "OPTIONS")
 		router.HandleFunc("/cache/servedorg", a.ListServedOrgs).Methods("GET", "OPTIONS")
 		router.HandleFunc("/cache/pattern", a.ListPatterns).Methods("GET", "OPTIONS")
 		router.HandleFunc("/cache/pattern/{org}", a.ListPatterns).Methods("GET", "OPTIONS")
@@ -483,7 +499,7 @@ func (a *API) agreement(w http.ResponseWriter, r *http.Request) {
 func (a *API) policy(w http.ResponseWriter, r *http.Request) {
 
 	serviceResolver := func(wURL string, wOrg string, wVersion string, wArch string) (*policy.APISpecList, error) {
-		asl, _, _, err := exchange.GetHTTPServiceResolverHandler(a)(wURL, wOrg, wVersion, wArch)
+		asl, _, _, err := exchange.GetHTTPServiceResolverHandler(a.exchanges.ForOrg(wOrg))(wURL, wOrg, wVersion, wArch)
 		if err != nil {
 			glog.Errorf(APIlogString(fmt.Sprintf("unable to resolve %v %v, error %v", wURL, wOrg, err)))
 		}
@@ -652,6 +668,49 @@ func (a *API) workloadusage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (a *API) eventlog(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		// The query parameters are the time window, page and order of the event logs, the rest are selectors.
+		if err := r.ParseForm(); err != nil {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "query parameters", Error: err.Error()})
+			return
+		}
+		selections := make(map[string][]string)
+		for k, v := range r.Form {
+			selections[k] = v
+		}
+
+		query, err := persistence.GetEventLogQuery(selections, time.Now())
+		if err != nil {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "query parameters", Error: err.Error()})
+			return
+		}
+		selectors, err := persistence.ConvertToSelectors(selections)
+		if err != nil {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "selectors", Error: err.Error()})
+			return
+		}
+
+		if evlogs, next, err := a.db.FindEventLogs(selectors, *query); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding event logs, error: %v", err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else {
+			if next != "" {
+				w.Header().Set(EVENTLOG_NEXT_CURSOR_HEADER, next)
+			}
+			writeResponse(w, evlogs, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (a *API) status(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
@@ -713,6 +772,52 @@ func (a *API) workerstatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Stream the events passed between the workers, as JSON lines. The event tap is for debugging, it is only served when
+// it is turned on in the configuration and the request has the event tap's token.
+func (a *API) eventTap(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		if !worker.EventTapEnabled() {
+			http.Error(w, "The event tap is not enabled. Set EventTap.TokenFile in the anax configuration file to enable it.", http.StatusNotFound)
+			return
+		} else if !worker.ValidEventTapAuthorization(r.Header.Get("Authorization")) {
+			glog.Warningf(APIlogString("event tap request without the event tap token"))
+			http.Error(w, "The request does not have the event tap token.", http.StatusUnauthorized)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "query parameters", Error: err.Error()})
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			glog.Error(APIlogString("error streaming the event tap, streaming is not supported"))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		glog.V(3).Infof(APIlogString(fmt.Sprintf("Streaming the event tap with selection %v", r.Form)))
+
+		sub := worker.SubscribeEventTap(worker.DEFAULT_EVENT_TAP_BUFFER, worker.GetEventTapFilter(r.Form))
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		if err := worker.StreamTappedEvents(w, flusher.Flush, sub, r.Context().Done()); err != nil {
+			glog.V(3).Infof(APIlogString(fmt.Sprintf("Stopped streaming the event tap, error %v", err)))
+		}
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (a *API) node(w http.ResponseWriter, r *http.Request) {
 
 	resource := "node"
@@ -781,6 +886,46 @@ func (a *API) node(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Get information about the agbot's messaging key, or replace it. The old key can still be used to decrypt messages
+// for the configured grace period.
+func (a *API) messagekey(w http.ResponseWriter, r *http.Request) {
+
+	resource := "messagekey"
+	keyPath := a.Config.AgreementBot.MessageKeyPath
+
+	switch r.Method {
+	case "GET":
+		glog.V(5).Infof(APIlogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if info, err := exchange.GetMessageKeyInfo(keyPath); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error getting message key information, error: %v", err)))
+			w.WriteHeader(http.StatusInternalServerError)
+		} else {
+			writeResponse(w, info, http.StatusOK)
+		}
+
+	case "PUT":
+		glog.V(5).Infof(APIlogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if err := a.exchanges.RotateMessageKey(keyPath, a.Config.AgreementBot.GetMessageKeyGracePeriodS()); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error rotating message key, error: %v", err)))
+			w.WriteHeader(http.StatusInternalServerError)
+		} else if info, err := exchange.GetMessageKeyInfo(keyPath); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error getting message key information, error: %v", err)))
+			w.WriteHeader(http.StatusInternalServerError)
+		} else {
+			glog.V(3).Infof(APIlogString(fmt.Sprintf("rotated message key, new key fingerprint is %v", info.Fingerprint)))
+			writeResponse(w, info, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, PUT, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // Get Agbot config info
 func (a *API) config(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -11,6 +11,7 @@ import (
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	uuid "github.com/satori/go.uuid"
 )
@@ -36,6 +37,8 @@ func NewBasicAgreementWorker(c *BasicProtocolHandler, cfg *config.HorizonConfig,
 			mmsObjMgr:  mmsObjMgr,
 			secretsMgr: secretsMgr,
 			nodeSearch: nodeSearch,
+			active:     &tracing.ActiveSpan{},
+			exchanges:  NewExchangeFederation(cfg),
 		},
 		protocolHandler: c,
 	}
@@ -249,11 +252,15 @@ func (a *BasicAgreementWorker) start(work *PrioritizedWorkQueue) {
 				if ag, err := a.db.AgreementFinalized(wi.Reply.AgreementId(), a.protocolHandler.Name()); err != nil {
 					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error persisting agreement %v finalized: %v", wi.Reply.AgreementId(), err)))
 
+				} else {
+					recordAgreementTransition(a.protocolHandler.Name(), AG_STATE_FINALIZED)
+
 					// Update state in exchange
-				} else if pol, err := policy.DemarshalPolicy(ag.Policy); err != nil {
-					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error demarshalling policy from agreement %v, error: %v", wi.Reply.AgreementId(), err)))
-				} else if err := a.protocolHandler.RecordConsumerAgreementState(wi.Reply.AgreementId(), pol, ag.Org, "Finalized Agreement", a.workerID); err != nil {
-					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error setting agreement %v finalized state in exchange: %v", wi.Reply.AgreementId(), err)))
+					if pol, err := policy.DemarshalPolicy(ag.Policy); err != nil {
+						glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error demarshalling policy from agreement %v, error: %v", wi.Reply.AgreementId(), err)))
+					} else if err := a.protocolHandler.RecordConsumerAgreementState(wi.Reply.AgreementId(), pol, ag.Org, "Finalized Agreement", a.workerID); err != nil {
+						glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error setting agreement %v finalized state in exchange: %v", wi.Reply.AgreementId(), err)))
+					}
 				}
 				lock.Unlock()
 			}
@@ -268,7 +275,7 @@ func (a *BasicAgreementWorker) start(work *PrioritizedWorkQueue) {
 
 			// Get rid of the original agreement cancellation message if the agreement is owned by this agbot.
 			if wi.MessageId != 0 && deleteMessage {
-				if err := a.protocolHandler.DeleteMessage(wi.MessageId); err != nil {
+				if err := a.protocolHandler.DeleteMessage(wi.MessageId, wi.SenderId); err != nil {
 					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error deleting message %v from exchange", wi.MessageId)))
 				}
 			}
@@ -317,7 +324,7 @@ func (a *BasicAgreementWorker) start(work *PrioritizedWorkQueue) {
 
 			// Get rid of the original agreement validation request message.
 			if wi.MessageId != 0 && deleteMessage {
-				if err := a.protocolHandler.DeleteMessage(wi.MessageId); err != nil {
+				if err := a.protocolHandler.DeleteMessage(wi.MessageId, wi.SenderId); err != nil {
 					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error deleting message %v from exchange", wi.MessageId)))
 				}
 			}
@@ -346,7 +353,7 @@ func (a *BasicAgreementWorker) start(work *PrioritizedWorkQueue) {
 
 			// Get rid of the original message if the agreement is owned by this agbot.
 			if wi.MessageId != 0 && deleteMessage {
-				if err := a.protocolHandler.DeleteMessage(wi.MessageId); err != nil {
+				if err := a.protocolHandler.DeleteMessage(wi.MessageId, wi.SenderId); err != nil {
 					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error deleting message %v from exchange", wi.MessageId)))
 				}
 			}
@@ -381,7 +388,7 @@ func (a *BasicAgreementWorker) start(work *PrioritizedWorkQueue) {
 
 			// Get rid of the original agreement update message.
 			if wi.MessageId != 0 && deleteMessage {
-				if err := a.protocolHandler.DeleteMessage(wi.MessageId); err != nil {
+				if err := a.protocolHandler.DeleteMessage(wi.MessageId, wi.SenderId); err != nil {
 					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error deleting message %v from exchange", wi.MessageId)))
 				}
 			}
@@ -471,7 +478,7 @@ func (a *BasicAgreementWorker) start(work *PrioritizedWorkQueue) {
 
 			// Get rid of the original agreement update reply message.
 			if wi.MessageId != 0 && deleteMessage {
-				if err := a.protocolHandler.DeleteMessage(wi.MessageId); err != nil {
+				if err := a.protocolHandler.DeleteMessage(wi.MessageId, wi.SenderId); err != nil {
 					glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error deleting message %v from exchange", wi.MessageId)))
 				}
 			}
@@ -39,6 +39,7 @@ func NewBasicProtocolHandler(name string, cfg *config.HorizonConfig, db persiste
 				mmsObjMgr:        mmsObjMgr,
 				secretsMgr:       secretsMgr,
 				nodeSearch:       nodeSearch,
+				exchanges:        NewExchangeFederation(cfg),
 			},
 			agreementPH: basicprotocol.NewProtocolHandler(cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil), pm),
 			// Allow the main agbot thread to distribute protocol msgs and agreement handling to the worker pool.
@@ -23,6 +23,19 @@ type ChangesWorker struct {
 func NewChangesWorker(name string, cfg *config.HorizonConfig) *ChangesWorker {
 
 	ec := worker.NewExchangeContext(cfg.AgreementBot.ExchangeId, cfg.AgreementBot.ExchangeToken, cfg.AgreementBot.ExchangeURL, cfg.AgreementBot.CSSURL, "", cfg.Collaborators.HTTPClientFactory)
+	return newChangesWorker(name, cfg, ec)
+}
+
+// Each exchange has its own change ids, so an agbot that serves additional exchanges has a changes worker for each
+// of them, using the agbot's identity in that exchange.
+func NewFederatedChangesWorker(name string, cfg *config.HorizonConfig, ex config.AgbotExchange) *ChangesWorker {
+
+	ec := worker.NewExchangeContext(ex.ExchangeId, ex.ExchangeToken, ex.ExchangeURL, ex.CSSURL, "", cfg.Collaborators.HTTPClientFactory)
+	return newChangesWorker(name, cfg, ec)
+}
+
+func newChangesWorker(name string, cfg *config.HorizonConfig, ec *worker.BaseExchangeContext) *ChangesWorker {
+
 	worker := &ChangesWorker{
 		BaseWorker:     worker.NewBaseWorker(name, cfg, ec),
 		changeID:       0,
@@ -30,7 +43,7 @@ func NewChangesWorker(name string, cfg *config.HorizonConfig) *ChangesWorker {
 		noworkDispatch: time.Now().Unix(),
 	}
 
-	glog.Info(chglog(fmt.Sprintf("Starting ExchangeChanges worker")))
+	glog.Info(chglog(fmt.Sprintf("Starting ExchangeChanges worker for %v", ec.URL)))
 
 	worker.Start(worker, int(cfg.AgreementBot.ExchangeHeartbeat))
 	return worker
@@ -76,6 +89,13 @@ func (w *ChangesWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *ChangesWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 // Handle commands that are placed on the command queue.
 func (w *ChangesWorker) CommandHandler(command worker.Command) bool {
 
@@ -56,7 +56,7 @@ type ConsumerProtocolHandler interface {
 	IsTerminationReasonNodeShutdown(code uint) bool
 	GetSendMessage() func(mt interface{}, pay []byte) error
 	RecordConsumerAgreementState(agreementId string, pol *policy.Policy, org string, state string, workerID string) error
-	DeleteMessage(msgId int) error
+	DeleteMessage(msgId int, senderId string) error
 	CreateMeteringNotification(mp policy.Meter, agreement *persistence.Agreement) (*metering.MeteringNotification, error)
 	TerminateAgreement(agreement *persistence.Agreement, reason uint, workerId string)
 	VerifyAgreement(ag *persistence.Agreement, cph ConsumerProtocolHandler)
@@ -99,6 +99,7 @@ type BaseConsumerProtocolHandler struct {
 	mmsObjMgr        *MMSObjectPolicyManager
 	secretsMgr       secrets.AgbotSecrets
 	nodeSearch       *NodeSearch
+	exchanges        *ExchangeFederation // The exchanges holding the nodes that agreements are made with.
 }
 
 func (b *BaseConsumerProtocolHandler) GetSendMessage() func(mt interface{}, pay []byte) error {
@@ -187,13 +188,15 @@ func (w *BaseConsumerProtocolHandler) sendMessage(mt interface{}, pay []byte) er
 		}
 	}
 
-	exchDev, err := exchange.GetExchangeDevice(w.GetHTTPFactory(), messageTarget.ReceiverExchangeId, w.agbotId, w.token, w.config.AgreementBot.ExchangeURL)
+	// The message is sent with the agbot's identity in the exchange that holds the node.
+	ec := w.exchanges.ForId(messageTarget.ReceiverExchangeId)
+	exchDev, err := exchange.GetExchangeDevice(w.GetHTTPFactory(), messageTarget.ReceiverExchangeId, ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL())
 	if err != nil {
 		return fmt.Errorf("Unable to get device from exchange: %v", err)
 	}
 	maxHb := exchDev.HeartbeatIntv.MaxInterval
 	if maxHb == 0 {
-		exchOrg, err := exchange.GetOrganization(w.GetHTTPFactory(), exchange.GetOrg(messageTarget.ReceiverExchangeId), w.config.AgreementBot.ExchangeURL, w.agbotId, w.token)
+		exchOrg, err := exchange.GetOrganization(w.GetHTTPFactory(), exchange.GetOrg(messageTarget.ReceiverExchangeId), ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
 		if err != nil {
 			return fmt.Errorf("Unable to get org from exchange: %v", err)
 		}
@@ -212,9 +215,9 @@ func (w *BaseConsumerProtocolHandler) sendMessage(mt interface{}, pay []byte) er
 		pm := exchange.CreatePostMessage(msgBody, exchangeMessageTTL)
 		var resp interface{}
 		resp = new(exchange.PostDeviceResponse)
-		targetURL := w.config.AgreementBot.ExchangeURL + "orgs/" + exchange.GetOrg(messageTarget.ReceiverExchangeId) + "/nodes/" + exchange.GetId(messageTarget.ReceiverExchangeId) + "/msgs"
+		targetURL := ec.GetExchangeURL() + "orgs/" + exchange.GetOrg(messageTarget.ReceiverExchangeId) + "/nodes/" + exchange.GetId(messageTarget.ReceiverExchangeId) + "/msgs"
 		for {
-			if err, tpErr := exchange.InvokeExchange(w.httpClient, "POST", targetURL, w.agbotId, w.token, pm, &resp); err != nil {
+			if err, tpErr := exchange.InvokeExchange(w.httpClient, "POST", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), pm, &resp); err != nil {
 				return err
 			} else if tpErr != nil {
 				glog.Warningf(tpErr.Error())
@@ -259,7 +262,7 @@ func (b *BaseConsumerProtocolHandler) DispatchProtocolMessage(cmd *NewProtocolMe
 		} else if ag.DeviceId != cmd.From {
 			glog.Warningf(BCPHlogstring(b.Name(), fmt.Sprintf("cancel ignored, cancel message for %v came from id %v but agreement is with %v", can.AgreementId(), cmd.From, ag.DeviceId)))
 		} else {
-			agreementWork := NewCancelAgreement(can.AgreementId(), can.Protocol(), can.Reason(), cmd.MessageId)
+			agreementWork := NewCancelAgreementFromMessage(can.AgreementId(), can.Protocol(), can.Reason(), cmd.MessageId, cmd.From)
 			cph.WorkQueue().InboundHigh() <- &agreementWork
 			if glog.V(5) {
 				glog.Infof(BCPHlogstring(b.Name(), fmt.Sprintf("queued cancel message")))
@@ -299,6 +302,8 @@ func (b *BaseConsumerProtocolHandler) HandlePolicyChanged(cmd *PolicyChangedComm
 	if eventPol, err := policy.DemarshalPolicy(cmd.Msg.PolicyString()); err != nil {
 		glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("error demarshalling change policy event %v, error: %v", cmd.Msg.PolicyString(), err)))
 	} else {
+		logPolicyEvent(b.db, persistence.EC_AB_POLICY_CHANGED, cmd.Msg.Org(), eventPol.Header.Name,
+			fmt.Sprintf("Policy %v changed, the agreements that use it will be updated or cancelled.", eventPol.Header.Name))
 
 		// Cancel related agreements
 		InProgress := func() persistence.AFilter {
@@ -394,12 +399,15 @@ func (b *BaseConsumerProtocolHandler) HandlePolicyChangeForAgreement(ag persiste
 
 	msgPrinter := i18n.GetMessagePrinter()
 
+	// The node, its policy and the services are read from the exchange that holds the node.
+	ec := b.exchanges.ForId(ag.DeviceId)
+
 	svcAllPol := externalpolicy.ExternalPolicy{}
-	svcPolicyHandler := exchange.GetHTTPServicePolicyHandler(b)
-	svcResolveHandler := exchange.GetHTTPServiceDefResolverHandler(b)
+	svcPolicyHandler := exchange.GetHTTPServicePolicyHandler(ec)
+	svcResolveHandler := exchange.GetHTTPServiceDefResolverHandler(ec)
 
 	for _, svcId := range ag.ServiceId {
-		if svcDef, err := exchange.GetServiceWithId(b, svcId); err != nil {
+		if svcDef, err := exchange.GetServiceWithId(ec, svcId); err != nil {
 			glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("failed to get service %v, error: %v", svcId, err)))
 			return false, false, false
 		} else if svcDef != nil {
@@ -416,21 +424,21 @@ func (b *BaseConsumerProtocolHandler) HandlePolicyChangeForAgreement(ag persiste
 		glog.Infof(BCPHlogstring(b.Name(), fmt.Sprintf("For agreement %v merged svc policy is %v", ag.CurrentAgreementId, svcAllPol)))
 	}
 
-	busPolHandler := exchange.GetHTTPBusinessPoliciesHandler(b)
+	busPolHandler := exchange.GetHTTPBusinessPoliciesHandler(ec)
 	_, busPol, err := compcheck.GetBusinessPolicy(busPolHandler, ag.PolicyName, true, msgPrinter)
 	if err != nil {
 		glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("failed to get business policy %v/%v from the exchange: %v", ag.Org, ag.PolicyName, err)))
 		return false, false, false
 	}
 
-	nodePolHandler := exchange.GetHTTPNodePolicyHandler(b)
+	nodePolHandler := exchange.GetHTTPNodePolicyHandler(ec)
 	_, nodePol, err := compcheck.GetNodePolicy(nodePolHandler, ag.DeviceId, msgPrinter)
 	if err != nil {
 		glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("failed to get node policy for %v from the exchange.", ag.DeviceId)))
 		return false, false, false
 	}
 
-	dev, err := exchange.GetExchangeDevice(b.GetHTTPFactory(), ag.DeviceId, b.GetExchangeId(), b.GetExchangeToken(), b.GetExchangeURL())
+	dev, err := exchange.GetExchangeDevice(ec.GetHTTPFactory(), ag.DeviceId, ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL())
 	if err != nil {
 		glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("failed to get node %v from the exchange.", ag.DeviceId)))
 		return false, false, false
@@ -530,7 +538,7 @@ func (b *BaseConsumerProtocolHandler) HandlePolicyChangeForAgreement(ag persiste
 	}
 
 	// populate the workload with the deployment string
-	if svcDef, _, err := exchange.GetHTTPServiceHandler(b)(wl.WorkloadURL, wl.Org, wl.Version, wl.Arch); err != nil {
+	if svcDef, _, err := exchange.GetHTTPServiceHandler(ec)(wl.WorkloadURL, wl.Org, wl.Version, wl.Arch); err != nil {
 		glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("error getting service '%v' from the exchange, error: %v", wl, err)))
 		return false, false, false
 	} else if svcDef == nil {
@@ -577,6 +585,9 @@ func (b *BaseConsumerProtocolHandler) HandlePolicyDeleted(cmd *PolicyDeletedComm
 	if eventPol, err := policy.DemarshalPolicy(cmd.Msg.PolicyString()); err != nil {
 		glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("error demarshalling change policy event %v, error: %v", cmd.Msg.PolicyString(), err)))
 	} else {
+		logPolicyEvent(b.db, persistence.EC_AB_POLICY_DELETED, cmd.Msg.Org(), eventPol.Header.Name,
+			fmt.Sprintf("Policy %v was deleted, the agreements that use it will be cancelled.", eventPol.Header.Name))
+
 		if wlu_array, err := b.db.FindWorkloadUsages([]persistence.WUFilter{persistence.PNoAWUFilter(eventPol.Header.Name)}); err != nil {
 			glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("Failed to get the workload usages with policy name: %v, %v", eventPol.Header.Name, err)))
 		} else {
@@ -726,9 +737,10 @@ func (b *BaseConsumerProtocolHandler) HandlePolicyChangeForMMSObject(agreement p
 	}
 
 	if agreement.GetDeviceType() == persistence.DEVICE_TYPE_DEVICE {
-		if b.GetCSSURL() != "" && agreement.Pattern == "" {
-			AgreementHandleMMSObjectPolicy(b, b.mmsObjMgr, agreement, b.Name(), BCPHlogstring)
-		} else if b.GetCSSURL() == "" {
+		ec := b.exchanges.ForId(agreement.DeviceId)
+		if ec.GetCSSURL() != "" && agreement.Pattern == "" {
+			AgreementHandleMMSObjectPolicy(ec, b.mmsObjMgr, agreement, b.Name(), BCPHlogstring)
+		} else if ec.GetCSSURL() == "" {
 			glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("unable to re-evaluate object placement because there is no CSS URL configured in this agbot")))
 		}
 	}
@@ -747,7 +759,8 @@ func (b *BaseConsumerProtocolHandler) CancelAgreement(ag persistence.Agreement,
 	if wlUsage, err := b.db.FindSingleWorkloadUsageByDeviceAndPolicyName(ag.DeviceId, ag.PolicyName); err != nil {
 		glog.Warningf(BCPHlogstring(b.Name(), fmt.Sprintf("error retreiving workload usage for %v using policy %v, error: %v", ag.DeviceId, ag.PolicyName, err)))
 	} else if wlUsage != nil && policyMatches {
-		theDev, err := GetDevice(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), ag.DeviceId, b.config.AgreementBot.ExchangeURL, cph.GetExchangeId(), cph.GetExchangeToken())
+		ec := b.exchanges.ForId(ag.DeviceId)
+		theDev, err := GetDevice(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), ag.DeviceId, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
 		if err != nil {
 			glog.Errorf(BCPHlogstring(b.Name(), fmt.Sprintf("error getting device %v, error: %v", ag.DeviceId, err)))
 			return
@@ -894,9 +907,11 @@ func (b *BaseConsumerProtocolHandler) RecordConsumerAgreementState(agreementId s
 
 	var resp interface{}
 	resp = new(exchange.PostDeviceResponse)
-	targetURL := b.config.AgreementBot.ExchangeURL + "orgs/" + exchange.GetOrg(b.agbotId) + "/agbots/" + exchange.GetId(b.agbotId) + "/agreements/" + agreementId
+	// The agreement is recorded in the exchange that holds the org.
+	ec := b.exchanges.ForOrg(org)
+	targetURL := ec.GetExchangeURL() + "orgs/" + exchange.GetOrg(ec.GetExchangeId()) + "/agbots/" + exchange.GetId(ec.GetExchangeId()) + "/agreements/" + agreementId
 	for {
-		if err, tpErr := exchange.InvokeExchange(b.httpClient, "PUT", targetURL, b.agbotId, b.token, &as, &resp); err != nil {
+		if err, tpErr := exchange.InvokeExchange(b.httpClient, "PUT", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), &as, &resp); err != nil {
 			glog.Errorf(err.Error())
 			return err
 		} else if tpErr != nil {
@@ -913,9 +928,11 @@ func (b *BaseConsumerProtocolHandler) RecordConsumerAgreementState(agreementId s
 
 }
 
-func (b *BaseConsumerProtocolHandler) DeleteMessage(msgId int) error {
+// Delete a message from the agbot's message queue in the exchange that holds the sender.
+func (b *BaseConsumerProtocolHandler) DeleteMessage(msgId int, senderId string) error {
 
-	return DeleteMessage(msgId, b.agbotId, b.token, b.config.AgreementBot.ExchangeURL, b.httpClient)
+	ec := b.exchanges.ForId(senderId)
+	return DeleteMessage(msgId, ec.GetExchangeId(), ec.GetExchangeToken(), ec.GetExchangeURL(), b.httpClient)
 
 }
 
@@ -987,9 +1004,10 @@ func (b *BaseConsumerProtocolHandler) getDevice(deviceId string, workerId string
 
 	var resp interface{}
 	resp = new(exchange.GetDevicesResponse)
-	targetURL := b.config.AgreementBot.ExchangeURL + "orgs/" + exchange.GetOrg(deviceId) + "/nodes/" + exchange.GetId(deviceId)
+	ec := b.exchanges.ForId(deviceId)
+	targetURL := ec.GetExchangeURL() + "orgs/" + exchange.GetOrg(deviceId) + "/nodes/" + exchange.GetId(deviceId)
 	for {
-		if err, tpErr := exchange.InvokeExchange(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), "GET", targetURL, b.agbotId, b.token, nil, &resp); err != nil {
+		if err, tpErr := exchange.InvokeExchange(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(BCPHlogstring2(workerId, fmt.Sprintf("%s", err.Error())))
 			return nil, err
 		} else if tpErr != nil {
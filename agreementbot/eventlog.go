@@ -0,0 +1,30 @@
+package agreementbot
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// The response header of the /eventlog API that holds the cursor of the next page.
+const EVENTLOG_NEXT_CURSOR_HEADER = "X-Eventlog-Next-Cursor"
+
+// Save an event about an agreement in the agbot's event log. The event log is there to help the users, so a failure to
+// save an event is logged and otherwise ignored.
+func logAgreementEvent(db persistence.AgbotDatabase, severity string, eventCode string, ag *persistence.Agreement, reason string, message string) {
+	saveEventLog(db, persistence.NewAgbotEventLog(severity, eventCode, message, ag.CurrentAgreementId, ag.DeviceId, ag.Org, ag.PolicyName, reason))
+}
+
+// Save an event about a deployment policy or pattern in the agbot's event log.
+func logPolicyEvent(db persistence.AgbotDatabase, eventCode string, org string, policyName string, message string) {
+	saveEventLog(db, persistence.NewAgbotEventLog(persistence.SEVERITY_INFO, eventCode, message, "", "", org, policyName, ""))
+}
+
+func saveEventLog(db persistence.AgbotDatabase, el *persistence.AgbotEventLog) {
+	if db == nil {
+		return
+	} else if err := db.SaveEventLog(el); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to save event log %v, error: %v", el, err)))
+	}
+}
@@ -0,0 +1,44 @@
+//go:build unit
+// +build unit
+
+package agreementbot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetEventLogQuery(t *testing.T) {
+	now := time.Unix(10000, 0)
+
+	selections := map[string][]string{"since": {"1h"}, "limit": {"10"}, "order": {"DESC"}, "node_id": {"myorg/node1"}}
+	query, err := persistence.GetEventLogQuery(selections, now)
+	assert.Nil(t, err)
+	assert.Equal(t, persistence.EventLogQuery{Since: 6400, Limit: 10, Order: persistence.EVENTLOG_ORDER_DESC}, *query)
+	assert.Equal(t, map[string][]string{"node_id": {"myorg/node1"}}, selections, "Only the selectors should be left.")
+
+	_, err = persistence.GetEventLogQuery(map[string][]string{"cursor": {"xyz"}}, now)
+	assert.NotNil(t, err, "The cursor is not a record id.")
+	_, err = persistence.GetEventLogQuery(map[string][]string{"order": {"up"}}, now)
+	assert.NotNil(t, err, "The order is not valid.")
+	_, err = persistence.GetEventLogQuery(map[string][]string{"limit": {"-1"}}, now)
+	assert.NotNil(t, err, "The limit is not valid.")
+}
+
+func Test_AgbotEventLog_Matches(t *testing.T) {
+	el := persistence.NewAgbotEventLog(persistence.SEVERITY_INFO, persistence.EC_AB_AGREEMENT_CANCELLED, "Cancelled the agreement.", "ag1", "myorg/node1", "myorg", "myorg/pol1", "PolicyChanged")
+
+	match := func(selections map[string][]string) bool {
+		s, err := persistence.ConvertToSelectors(selections)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return el.Matches(s)
+	}
+	assert.True(t, match(map[string][]string{"reason": {"PolicyChanged"}, "policy": {"~pol"}}))
+	assert.False(t, match(map[string][]string{"node_id": {"myorg/node2"}}))
+	assert.False(t, match(map[string][]string{"source_type": {"agreement"}}), "The attribute is not in the agbot event log.")
+}
@@ -0,0 +1,156 @@
+package agreementbot
+
+import (
+	"fmt"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/exchange"
+)
+
+// An agbot can serve orgs that live in different exchanges, for example separate regional management hubs. The
+// exchange at AgreementBot.ExchangeURL is the primary exchange, and it holds every org that is not listed in one of
+// the additional exchanges in AgreementBot.Exchanges. Since each org lives in only one exchange, everything the agbot
+// does for a node, a pattern, a deployment policy or an agreement is sent to the exchange that holds its org, using
+// the agbot's identity in that exchange.
+
+// The name of the primary exchange. State that belongs to the primary exchange is not qualified by the exchange name,
+// so that it is the same as it was before the agbot served other exchanges.
+const PRIMARY_EXCHANGE = ""
+
+// One of the exchanges served by the agbot.
+type FederatedExchange struct {
+	*exchange.CustomExchangeContext
+	Name string   // The name of the exchange, PRIMARY_EXCHANGE for the primary exchange.
+	Orgs []string // The orgs that live in this exchange. Empty for the primary exchange, which holds all the other orgs.
+}
+
+func (f *FederatedExchange) String() string {
+	return fmt.Sprintf("Name: %v, URL: %v, Id: %v, Orgs: %v", f.DisplayName(), f.GetExchangeURL(), f.GetExchangeId(), f.Orgs)
+}
+
+// The name of the exchange, for log messages.
+func (f *FederatedExchange) DisplayName() string {
+	if f.Name == PRIMARY_EXCHANGE {
+		return "primary"
+	}
+	return f.Name
+}
+
+type ExchangeFederation struct {
+	exchanges []*FederatedExchange          // The exchanges served by the agbot, the primary exchange first.
+	orgs      map[string]*FederatedExchange // The additional exchanges, keyed by the orgs they hold.
+}
+
+func NewExchangeFederation(cfg *config.HorizonConfig) *ExchangeFederation {
+	primary := &FederatedExchange{
+		CustomExchangeContext: exchange.NewCustomExchangeContext(cfg.AgreementBot.ExchangeId, cfg.AgreementBot.ExchangeToken, cfg.AgreementBot.ExchangeURL, cfg.GetAgbotCSSURL(), cfg.Collaborators.HTTPClientFactory),
+		Name:                  PRIMARY_EXCHANGE,
+		Orgs:                  []string{},
+	}
+
+	f := &ExchangeFederation{
+		exchanges: []*FederatedExchange{primary},
+		orgs:      make(map[string]*FederatedExchange),
+	}
+	for _, ex := range cfg.AgreementBot.Exchanges {
+		fe := &FederatedExchange{
+			CustomExchangeContext: exchange.NewCustomExchangeContext(ex.ExchangeId, ex.ExchangeToken, ex.ExchangeURL, ex.CSSURL, cfg.Collaborators.HTTPClientFactory),
+			Name:                  ex.Name,
+			Orgs:                  ex.Orgs,
+		}
+		f.exchanges = append(f.exchanges, fe)
+		for _, org := range ex.Orgs {
+			f.orgs[org] = fe
+		}
+	}
+	return f
+}
+
+func (f *ExchangeFederation) String() string {
+	return fmt.Sprintf("Exchanges: %v", f.exchanges)
+}
+
+// All the exchanges served by the agbot, the primary exchange first.
+func (f *ExchangeFederation) Exchanges() []*FederatedExchange {
+	return f.exchanges
+}
+
+func (f *ExchangeFederation) Primary() *FederatedExchange {
+	return f.exchanges[0]
+}
+
+// Returns true when the agbot serves more than the primary exchange.
+func (f *ExchangeFederation) IsFederated() bool {
+	return len(f.exchanges) > 1
+}
+
+// Returns the exchange that holds an org.
+func (f *ExchangeFederation) ForOrg(org string) *FederatedExchange {
+	if fe, ok := f.orgs[org]; ok {
+		return fe
+	}
+	return f.Primary()
+}
+
+// Returns the exchange that holds an org qualified id, such as a node id, a pattern id or a deployment policy name.
+func (f *ExchangeFederation) ForId(id string) *FederatedExchange {
+	return f.ForOrg(exchange.GetOrg(id))
+}
+
+// Returns the key of the search session for an org qualified deployment policy name. A search session is held by the
+// exchange that the search is made in, so the sessions of the additional exchanges are qualified by the exchange name.
+// If an org is moved to another exchange, the searches of its policies start new sessions there.
+func (f *ExchangeFederation) SearchSessionKey(policyName string) string {
+	if fe := f.ForId(policyName); fe.Name != PRIMARY_EXCHANGE {
+		return fe.Name + "/" + policyName
+	}
+	return policyName
+}
+
+// Returns the orgs in the input list that live in the input exchange.
+func (f *ExchangeFederation) OrgsIn(fe *FederatedExchange, orgs []string) []string {
+	res := make([]string, 0, len(orgs))
+	for _, org := range orgs {
+		if f.ForOrg(org) == fe {
+			res = append(res, org)
+		}
+	}
+	return res
+}
+
+// Replace the agbot's messaging key. The agbot has one messaging key, so the new key is published to each of the
+// exchanges. If any of them cannot be updated the current key is kept, and the current public key is published again
+// to the exchanges that were already updated, so that their nodes do not encrypt messages with a key the agbot does
+// not have.
+func (f *ExchangeFederation) RotateMessageKey(keyPath string, gracePeriodS int) error {
+	return exchange.RotateKeys(keyPath, gracePeriodS, func(publicKey []byte) error {
+		for ix, fe := range f.exchanges {
+			if err := exchange.PatchAgbotMessageKey(fe, publicKey); err != nil {
+				err = fmt.Errorf("%v exchange: %v", fe.DisplayName(), err)
+				if rerr := f.restoreMessageKey(keyPath, f.exchanges[:ix]); rerr != nil {
+					err = fmt.Errorf("%v, and unable to restore the current key, %v", err, rerr)
+				}
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Publish the current messaging public key to the input exchanges.
+func (f *ExchangeFederation) restoreMessageKey(keyPath string, exchanges []*FederatedExchange) error {
+	pubKey, _, err := exchange.GetKeys(keyPath)
+	if err != nil {
+		return err
+	}
+	publicKey, err := exchange.MarshalPublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+	for _, fe := range exchanges {
+		if err := exchange.PatchAgbotMessageKey(fe, publicKey); err != nil {
+			return fmt.Errorf("%v exchange: %v", fe.DisplayName(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+//go:build unit
+// +build unit
+
+package agreementbot
+
+import (
+	"encoding/base64"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/exchange/exchangetest"
+	"github.com/open-horizon/anax/policy"
+)
+
+// Set up an agbot whose primary exchange holds the east org, and which also serves the west org in another exchange.
+func federationTestSetup(t *testing.T) (*exchangetest.Server, *exchangetest.Server, *config.HorizonConfig, *ExchangeFederation) {
+	east := exchangetest.NewServer()
+	t.Cleanup(east.Close)
+	west := exchangetest.NewServer()
+	t.Cleanup(west.Close)
+
+	east.AddOrg("east")
+	west.AddOrg("west")
+	agbot, err := east.NewAgbot(t.TempDir(), "east", "ag1", "easttoken")
+	if err != nil {
+		t.Fatalf("unable to set up the agbot, error %v", err)
+	}
+	west.AddAgbot("west", "ag1", "westtoken")
+
+	cfg := agbot.Config
+	cfg.AgreementBot.Exchanges = []config.AgbotExchange{{
+		Name:          "west",
+		ExchangeURL:   west.ExchangeURL(),
+		CSSURL:        west.CSSURL(),
+		ExchangeId:    "west/ag1",
+		ExchangeToken: "westtoken",
+		Orgs:          []string{"west"},
+	}}
+	return east, west, cfg, NewExchangeFederation(cfg)
+}
+
+func Test_ExchangeFederation_routing(t *testing.T) {
+	_, _, _, fed := federationTestSetup(t)
+
+	if !fed.IsFederated() || len(fed.Exchanges()) != 2 {
+		t.Errorf("expecting 2 exchanges, have %v", fed)
+	}
+	if fe := fed.ForOrg("west"); fe.Name != "west" || fe.GetExchangeId() != "west/ag1" {
+		t.Errorf("expecting the west exchange for org west, have %v", fe)
+	} else if fe := fed.ForId("west/node1"); fe.Name != "west" {
+		t.Errorf("expecting the west exchange for node west/node1, have %v", fe)
+	} else if fe := fed.ForOrg("other"); fe != fed.Primary() || fe.GetExchangeId() != "east/ag1" {
+		t.Errorf("expecting the primary exchange for an unlisted org, have %v", fe)
+	}
+
+	// Search sessions in the primary exchange keep their keys.
+	if key := fed.SearchSessionKey("east/pol1"); key != "east/pol1" {
+		t.Errorf("expecting session key east/pol1, have %v", key)
+	} else if key := fed.SearchSessionKey("west/pol1"); key != "west/west/pol1" {
+		t.Errorf("expecting session key west/west/pol1, have %v", key)
+	}
+
+	if orgs := fed.OrgsIn(fed.Primary(), []string{"east", "west", "other"}); len(orgs) != 2 || orgs[0] != "east" || orgs[1] != "other" {
+		t.Errorf("expecting orgs east and other in the primary exchange, have %v", orgs)
+	}
+}
+
+func Test_ExchangeFederation_search(t *testing.T) {
+	east, west, _, fed := federationTestSetup(t)
+
+	// The same pattern name exists in both exchanges, with a registered node in each.
+	for _, s := range []*exchangetest.Server{east, west} {
+		org := "east"
+		if s == west {
+			org = "west"
+		}
+		s.Put("orgs/"+org+"/patterns/p1", exchange.Pattern{Label: "p1", Services: []exchange.ServiceReference{}})
+		s.AddNode(org, "node1", "nodetoken", org+"/p1")
+		s.Put("orgs/"+org+"/nodes/node1", map[string]interface{}{"token": "nodetoken", "pattern": org + "/p1", "publicKey": "a2V5"})
+	}
+
+	for _, org := range []string{"east", "west"} {
+		ids := make([]string, 0)
+		search := exchange.GetHTTPAgbotPatternNodeSearchHandler(fed.ForOrg(org))
+		if _, err := search(exchange.CreateSearchPatternRequest(), org, org+"/p1", 10, func(page []exchange.SearchResultDevice) error {
+			for _, dev := range page {
+				ids = append(ids, dev.Id)
+			}
+			return nil
+		}); err != nil || len(ids) != 1 || ids[0] != org+"/node1" {
+			t.Errorf("expecting node %v/node1, have %v, error %v", org, ids, err)
+		}
+	}
+}
+
+func Test_ExchangeFederation_RotateMessageKey(t *testing.T) {
+	east, west, _, fed := federationTestSetup(t)
+	t.Setenv("HZN_VAR_BASE", t.TempDir())
+	if _, _, err := exchange.ReloadKeys(""); err != nil {
+		t.Fatalf("unable to generate keys, error %v", err)
+	}
+
+	publicKey := func(s *exchangetest.Server, org string) interface{} {
+		var agbot map[string]interface{}
+		s.Get("orgs/"+org+"/agbots/ag1", &agbot)
+		return agbot["publicKey"]
+	}
+
+	// The new key is published to both exchanges.
+	if err := fed.RotateMessageKey("", 3600); err != nil {
+		t.Fatalf("unable to rotate the message key, error %v", err)
+	} else if key := publicKey(east, "east"); key == "" || key != publicKey(west, "west") {
+		t.Errorf("expecting the same key in both exchanges, have %v and %v", key, publicKey(west, "west"))
+	}
+
+	// If one of the exchanges rejects the key, the current key is kept, and it is put back in the exchanges that were
+	// already given the new key.
+	current, _, _ := exchange.GetKeys("")
+	currentBytes, _ := exchange.MarshalPublicKey(current)
+	fault := west.AddFault(exchangetest.Fault{Method: "PATCH", Status: http.StatusForbidden})
+	defer west.RemoveFault(fault)
+	if err := fed.RotateMessageKey("", 3600); err == nil {
+		t.Errorf("expecting an error when the west exchange rejects the key")
+	} else if key, _, _ := exchange.GetKeys(""); key.N.Cmp(current.N) != 0 {
+		t.Errorf("expecting the current key to be kept")
+	} else if key := publicKey(east, "east"); key != base64.StdEncoding.EncodeToString(currentBytes) {
+		t.Errorf("expecting the current key in the east exchange, have %v", key)
+	}
+}
+
+// Set up a node in the west exchange with an agreement for a deployment policy of the west org.
+func federationTestAgreement(west *exchangetest.Server) *persistence.Agreement {
+	west.Put("orgs/west/nodes/node1", map[string]interface{}{"token": "nodetoken", "nodeType": "device", "publicKey": "a2V5", "lastHeartbeat": cutil.FormattedTime()})
+	west.Put("orgs/west/nodes/node1/policy", map[string]interface{}{})
+	west.Put("orgs/west/nodes/node1/agreements/ag1", map[string]interface{}{"state": "Finalized Agreement"})
+	west.Put("orgs/west/services/svc1_1.0.0_"+runtime.GOARCH, map[string]interface{}{
+		"label": "svc1", "url": "svc1", "version": "1.0.0", "arch": runtime.GOARCH, "sharable": "multiple",
+	})
+	west.Put("orgs/west/business/policies/bp1", map[string]interface{}{
+		"label":   "bp1",
+		"service": map[string]interface{}{"name": "svc1", "org": "west", "arch": "*", "serviceVersions": []interface{}{map[string]interface{}{"version": "1.0.0"}}},
+	})
+	return &persistence.Agreement{
+		CurrentAgreementId:  "ag1",
+		Org:                 "west",
+		DeviceId:            "west/node1",
+		DeviceType:          persistence.DEVICE_TYPE_DEVICE,
+		PolicyName:          "west/bp1",
+		AgreementProtocol:   policy.BasicProtocol,
+		ServiceId:           []string{"west/svc1_1.0.0_" + runtime.GOARCH},
+		NHMissingHBInterval: 600,
+	}
+}
+
+// Set up the basic protocol handler and the agbot worker of the agbot, with a database that holds the agreement.
+func federationTestWorker(t *testing.T, cfg *config.HorizonConfig, ag *persistence.Agreement) (*BasicProtocolHandler, *AgreementBotWorker) {
+	db, err := persistence.InitDatabase(cfg)
+	if err != nil {
+		t.Fatalf("unable to open the agbot database, error %v", err)
+	}
+	t.Cleanup(db.Close)
+	if err := db.AgreementAttempt(ag.CurrentAgreementId, ag.Org, ag.DeviceId, ag.DeviceType, ag.PolicyName, "", "", "", ag.AgreementProtocol, "", ag.ServiceId, policy.NodeHealth{MissingHBInterval: ag.NHMissingHBInterval}, 60, 600); err != nil {
+		t.Fatalf("unable to save the agreement, error %v", err)
+	}
+
+	cph := NewBasicProtocolHandler(policy.BasicProtocol, cfg, db, nil, make(chan events.Message, 10), nil, nil, nil)
+	w := NewAgreementBotWorker("AgBot", cfg, db, nil)
+	w.consumerPH.Add(policy.BasicProtocol, cph)
+	return cph, w
+}
+
+// A change to a deployment policy of an org in the west exchange is checked against the node in that exchange, so the
+// agreement is kept.
+func Test_ExchangeFederation_policyChange(t *testing.T) {
+	_, west, cfg, _ := federationTestSetup(t)
+	ag := federationTestAgreement(west)
+	cph, _ := federationTestWorker(t, cfg, ag)
+
+	if policyMatches, noNewPriority, _ := cph.HandlePolicyChangeForAgreement(*ag, nil, cph); !policyMatches || !noNewPriority {
+		t.Errorf("expecting agreement %v to still be in policy, have %v and %v", ag.CurrentAgreementId, policyMatches, noNewPriority)
+	}
+}
+
+// The health of a node in the west exchange is read from the west exchange, so the agreement is not cancelled.
+func Test_ExchangeFederation_nodeHealth(t *testing.T) {
+	_, west, cfg, _ := federationTestSetup(t)
+	ag := federationTestAgreement(west)
+	ag.AgreementFinalizedTime = uint64(time.Now().Unix())
+	cph, w := federationTestWorker(t, cfg, ag)
+
+	w.NHManager.SetNodeOrgs([]persistence.Agreement{*ag}, policy.BasicProtocol)
+	if _, err := w.VerifyNodeHealth(ag, cph); err != nil {
+		t.Fatalf("unable to verify the node health, error %v", err)
+	} else if w.NHManager.NodeOutOfPolicy(ag.Pattern, ag.Org, ag.DeviceId, ag.NHMissingHBInterval) {
+		t.Errorf("expecting node %v to be heartbeating, have %v", ag.DeviceId, w.NHManager)
+	} else if len(cph.WorkQueue().InboundHigh()) != 0 {
+		t.Errorf("expecting agreement %v not to be cancelled", ag.CurrentAgreementId)
+	}
+}
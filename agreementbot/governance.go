@@ -14,6 +14,7 @@ import (
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/exchangecommon"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/worker"
 	"math"
 	"net/http"
 	"strings"
@@ -58,14 +59,15 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 			var err error
 
 			// check if the org exists on the exchange or not
-			getOrganization := exchange.GetHTTPExchangeOrgHandler(w)
+			ec := w.exchanges.ForOrg(org)
+			getOrganization := exchange.GetHTTPExchangeOrgHandler(ec)
 			if _, err = getOrganization(org); err != nil {
 				// org does not exist is returned as an error
 				glog.V(5).Infof(AWlogString(fmt.Sprintf("unable to get organization %v: %v", org, err)))
 				exchPolsMetadata = make(map[string]exchange.ExchangeBusinessPolicy)
 			} else {
 				// Query exchange for all business policies in the org
-				getBusinessPolicies := exchange.GetHTTPBusinessPoliciesHandler(w)
+				getBusinessPolicies := exchange.GetHTTPBusinessPoliciesHandler(ec)
 				if exchPolsMetadata, err = getBusinessPolicies(org, ""); err != nil {
 					glog.Errorf("unable to get business polices for org %v, error %v", org, err)
 					continue
@@ -83,13 +85,14 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 			var err error
 
 			// check if the org exists on the exchange or not
-			if _, err = exchange.GetOrganization(w.Config.Collaborators.HTTPClientFactory, org, w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken()); err != nil {
+			ec := w.exchanges.ForOrg(org)
+			if _, err = exchange.GetOrganization(w.Config.Collaborators.HTTPClientFactory, org, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken()); err != nil {
 				// org does not exist is returned as an error
 				glog.V(5).Infof(AWlogString(fmt.Sprintf("unable to get organization %v: %v", org, err)))
 				exchangePatternMetadata = make(map[string]exchange.Pattern)
 			} else {
 				// Query exchange for all patterns in the org
-				if exchangePatternMetadata, err = exchange.GetPatterns(w.Config.Collaborators.HTTPClientFactory, org, "", w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken()); err != nil {
+				if exchangePatternMetadata, err = exchange.GetPatterns(w.Config.Collaborators.HTTPClientFactory, org, "", ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken()); err != nil {
 					glog.Errorf("unable to get patterns for org %v, error %v", org, err)
 					continue
 				}
@@ -127,7 +130,7 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 						if timeout == 0 {
 							timeout, _ = w.SetAgreementTimeouts(ag, agp)
 						}
-						now := uint64(time.Now().Unix())
+						now := uint64(worker.GetClock().Now().Unix())
 						if ag.AgreementCreationTime+timeout < now {
 							// Start timing out the agreement
 							w.TerminateAgreement(&ag, protocolHandler.GetTerminationCode(TERM_REASON_NOT_FINALIZED_TIMEOUT))
@@ -153,9 +156,12 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 					if timeout == 0 {
 						_, timeout = w.SetAgreementTimeouts(ag, agp)
 					}
-					now := uint64(time.Now().Unix())
+					now := uint64(worker.GetClock().Now().Unix())
 					if ag.AgreementCreationTime+timeout < now {
 						w.nodeSearch.AddRetry(ag.PolicyName, ag.AgreementCreationTime-w.BaseWorker.Manager.Config.GetAgbotRetryLookBackWindow())
+						recordProposal(ag.Org, ag.PolicyName, PROPOSAL_TIMEDOUT)
+						logAgreementEvent(w.db, persistence.SEVERITY_WARN, persistence.EC_AB_PROPOSAL_TIMEDOUT, &ag, TERM_REASON_NO_REPLY,
+							fmt.Sprintf("Node %v did not reply to the agreement proposal within %v seconds.", ag.DeviceId, timeout))
 						w.TerminateAgreement(&ag, protocolHandler.GetTerminationCode(TERM_REASON_NO_REPLY))
 					}
 				}
@@ -218,9 +224,13 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 											if val, ok := updatedSecretsMap[secretLookupKey]; ok {
 												newBS[serviceSecretName] = val
 											} else {
-												details, err := w.secretProvider.GetSecretDetails(w.GetExchangeId(), w.GetExchangeToken(), exchange.GetOrg(updatedSecretName), secretUser, secretNode, secretName)
+												secretEc := w.exchanges.ForId(updatedSecretName)
+												details, err := w.secretProvider.GetSecretDetails(secretEc.GetExchangeId(), secretEc.GetExchangeToken(), exchange.GetOrg(updatedSecretName), secretUser, secretNode, secretName)
 												if err != nil {
 													glog.Errorf(logString(fmt.Sprintf("error retrieving secret %v for policy %v, error: %v", updatedSecretName, ag.PolicyName, err)))
+													secretPropagationErrors.Inc(ag.Org)
+													logAgreementEvent(w.db, persistence.SEVERITY_ERROR, persistence.EC_AB_SECRET_UPDATE_FAILED, &ag, err.Error(),
+														fmt.Sprintf("Unable to retrieve the updated secret %v for node %v.", updatedSecretName, ag.DeviceId))
 													if updateSecretNode != "" {
 														secretExistsMap[updatedSecretName] = false
 													}
@@ -243,9 +253,13 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 											if val, ok := updatedSecretsMap[secretLookupKey]; ok {
 												newBS[serviceSecretName] = val
 											} else {
-												details, err := w.secretProvider.GetSecretDetails(w.GetExchangeId(), w.GetExchangeToken(), exchange.GetOrg(updatedSecretName), secretUser, "", secretName)
+												secretEc := w.exchanges.ForId(updatedSecretName)
+												details, err := w.secretProvider.GetSecretDetails(secretEc.GetExchangeId(), secretEc.GetExchangeToken(), exchange.GetOrg(updatedSecretName), secretUser, "", secretName)
 												if err != nil {
 													glog.Errorf(logString(fmt.Sprintf("error retrieving secret %v for policy %v, error: %v", updatedSecretName, ag.PolicyName, err)))
+													secretPropagationErrors.Inc(ag.Org)
+													logAgreementEvent(w.db, persistence.SEVERITY_ERROR, persistence.EC_AB_SECRET_UPDATE_FAILED, &ag, err.Error(),
+														fmt.Sprintf("Unable to retrieve the updated secret %v for node %v.", updatedSecretName, ag.DeviceId))
 													if updateSecretNode == "" {
 														secretExistsMap[updatedSecretName] = false
 													}
@@ -282,6 +296,9 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 
 						// Send the Update Agreement protocol message
 						protocolHandler.UpdateAgreement(&ag, basicprotocol.MsgUpdateTypeSecret, updatedBindings, protocolHandler)
+						secretPropagationCounter.Inc(ag.Org)
+						logAgreementEvent(w.db, persistence.SEVERITY_INFO, persistence.EC_AB_SECRET_UPDATE_SENT, &ag, "",
+							fmt.Sprintf("Sent the updated secrets %v to node %v.", updatedSecrets, ag.DeviceId))
 
 						if _, err := w.db.AgreementSecretUpdateTime(ag.CurrentAgreementId, agp, newestUpdateTime); err != nil {
 							glog.Errorf(logString(fmt.Sprintf("unable to save secret update time for %s, error: %v", ag.CurrentAgreementId, err)))
@@ -296,7 +313,7 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 					if timeout == 0 {
 						_, timeout = w.SetAgreementTimeouts(ag, agp)
 					}
-					if uint64(time.Now().Unix())-ag.LastPolicyUpdateTime > timeout {
+					if uint64(worker.GetClock().Now().Unix())-ag.LastPolicyUpdateTime > timeout {
 						// exceeded timeout waiting for update reply. cancel the agreement
 						glog.V(3).Infof("agreement %v has timed out while waiting for reply to a policy change update", ag.CurrentAgreementId)
 						w.TerminateAgreement(&ag, protocolHandler.GetTerminationCode(TERM_REASON_NO_REPLY))
@@ -446,7 +463,8 @@ func (w *AgreementBotWorker) governHAPartners() {
 				glog.Infof(logString(fmt.Sprintf("checking for workload usage %v that are waiting for upgrading", wlu.String())))
 			}
 			// Setup variables to track the state of the HA group that the current workload usage record belongs to.
-			device, err := GetDevice(w.GetHTTPFactory().NewHTTPClient(nil), wlu.DeviceId, w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken())
+			ec := w.exchanges.ForId(wlu.DeviceId)
+			device, err := GetDevice(w.GetHTTPFactory().NewHTTPClient(nil), wlu.DeviceId, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
 			if err != nil {
 				glog.Errorf(logString(fmt.Sprintf("error getting device %v, error: %v", wlu.DeviceId, err)))
 				return
@@ -528,7 +546,8 @@ func (w *AgreementBotWorker) checkWorkloadUsageAgreement(partnerWLU *persistence
 		// Check to make sure the partner is heart-beating to the exchange. This should tell us if we can expect this device to
 		// complete an agreement at some time, or not.
 
-		if dev, err := GetDevice(w.Config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), partnerWLU.DeviceId, w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken()); err != nil {
+		ec := w.exchanges.ForId(partnerWLU.DeviceId)
+		if dev, err := GetDevice(w.Config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), partnerWLU.DeviceId, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken()); err != nil {
 			glog.Errorf(logString(fmt.Sprintf("error obtaining device %v heartbeat state: %v", partnerWLU.DeviceId, err)))
 		} else if len(dev.LastHeartbeat) != 0 && (uint64(cutil.TimeInSeconds(dev.LastHeartbeat, cutil.ExchangeTimeFormat)+300) > uint64(time.Now().Unix())) {
 			// If the device is still alive (heart beat received in the last 5 mins), then assume this partner is trying to make an
@@ -580,7 +599,7 @@ func (w *AgreementBotWorker) WorkloadRunningOnDevice(deviceId string, agId strin
 		glog.Infof(logString(fmt.Sprintf("Getting service status for device %v, agreement %v", deviceId, agId)))
 	}
 
-	status, err := exchange.GetNodeFullStatus(w, deviceId)
+	status, err := exchange.GetNodeFullStatus(w.exchanges.ForId(deviceId), deviceId)
 	if err != nil {
 		return false, fmt.Errorf("Failed to get the node status: %v", err)
 	}
@@ -649,8 +668,11 @@ func (w *AgreementBotWorker) VerifyNodeHealth(ag *persistence.Agreement, cph Con
 		return 0, nil
 	}
 
+	// The node health is read from the exchange that holds the pattern or deployment policy, which only has the nodes of
+	// the node orgs that live in it.
 	nodeHealthHandler := func(pattern string, org string, nodeOrgs []string, lastCallTime string) (*exchange.NodeHealthStatus, error) {
-		return exchange.GetNodeHealthStatus(w.Config.Collaborators.HTTPClientFactory, pattern, org, nodeOrgs, lastCallTime, w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken())
+		ec := w.exchanges.ForOrg(org)
+		return exchange.GetNodeHealthStatus(ec.GetHTTPFactory(), pattern, org, w.exchanges.OrgsIn(ec, nodeOrgs), lastCallTime, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
 	}
 
 	if glog.V(5) {
@@ -680,6 +702,8 @@ func (w *AgreementBotWorker) TerminateAgreement(ag *persistence.Agreement, reaso
 	// Update the database
 	if _, err := w.db.AgreementTimedout(ag.CurrentAgreementId, ag.AgreementProtocol); err != nil {
 		glog.Errorf(logString(fmt.Sprintf("error marking agreement %v terminate: %v", ag.CurrentAgreementId, err)))
+	} else {
+		recordAgreementTransition(ag.AgreementProtocol, AG_STATE_TERMINATING)
 	}
 
 	// Queue up a command for an agreement worker to do the blockchain work
@@ -765,6 +789,19 @@ func (w *AgreementBotWorker) GovernArchivedAgreements() int {
 	return 0
 }
 
+// Govern the event logs, periodically deleting the ones that are older than the agbot configuration, EventLogMaxAgeH.
+func (w *AgreementBotWorker) GovernEventLogs() int {
+	ageLimit := w.Config.AgreementBot.GetEventLogMaxAgeH()
+	before := uint64(time.Now().Unix() - int64(ageLimit*3600))
+
+	if count, err := w.db.DeleteEventLogsBefore(before); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to delete event logs older than %v hour(s), error: %v", ageLimit, err)))
+	} else if count > 0 {
+		glog.V(3).Infof(logString(fmt.Sprintf("event log purge deleted %v event logs older than %v hour(s)", count, ageLimit)))
+	}
+	return 0
+}
+
 // Govern the active agreements, reporting which ones need a blockchain running so that the blockchain workers
 // can keep them running.
 func (w *AgreementBotWorker) GovernBlockchainNeeds() int {
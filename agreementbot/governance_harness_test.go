@@ -0,0 +1,80 @@
+//go:build unit
+// +build unit
+
+package agreementbot
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	_ "github.com/open-horizon/anax/agreementbot/persistence/bolt"
+	"github.com/open-horizon/anax/exchange/exchangetest"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/worker"
+)
+
+// A proposal that the node does not reply to is terminated once the protocol timeout has passed on the worker clock.
+func Test_governAgreements_proposalTimeout(t *testing.T) {
+	os.Setenv("HZN_VAR_BASE", t.TempDir())
+	s := exchangetest.NewServer()
+	defer s.Close()
+	s.AddOrg("myorg")
+	s.AddNode("myorg", "node1", "nodetoken", "")
+	s.Put("orgs/myorg/services/svc1_1.0.0_"+runtime.GOARCH, map[string]interface{}{
+		"label": "svc1", "url": "svc1", "version": "1.0.0", "arch": runtime.GOARCH, "sharable": "multiple",
+	})
+	s.Put("orgs/myorg/business/policies/bp1", map[string]interface{}{
+		"label":   "bp1",
+		"service": map[string]interface{}{"name": "svc1", "org": "myorg", "arch": "*", "serviceVersions": []interface{}{map[string]interface{}{"version": "1.0.0"}}},
+	})
+	agbot, err := s.NewAgbot(t.TempDir(), "myorg", "ag1", "agtoken")
+	if err != nil {
+		t.Fatalf("unable to set up the agbot, error %v", err)
+	}
+
+	// The harness clock starts before the agreement is created, which is timestamped with the real clock.
+	h := worker.NewHarness(time.Now())
+	defer h.Close()
+
+	db, err := persistence.InitDatabase(agbot.Config)
+	if err != nil {
+		t.Fatalf("unable to open the agbot database, error %v", err)
+	}
+	defer db.Close()
+
+	h.Add(NewAgreementBotWorker("AgBot", agbot.Config, db, nil))
+	if err := h.Settle(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	agId := "ag0001"
+	if err := db.AgreementAttempt(agId, "myorg", "myorg/node1", "device", "myorg/bp1", "", "", "", policy.BasicProtocol, "", []string{"myorg/svc1"}, policy.NodeHealth{}, 60, 600); err != nil {
+		t.Fatalf("unable to save the agreement, error %v", err)
+	} else if _, err := db.AgreementUpdate(agId, "proposal", "policy", policy.DataVerification{}, 0, "hash", "sig", policy.BasicProtocol, 1); err != nil {
+		t.Fatalf("unable to update the agreement, error %v", err)
+	}
+
+	timedOut := func() bool {
+		ag, err := db.FindSingleAgreementByAgreementId(agId, policy.BasicProtocol, []persistence.AFilter{})
+		if err != nil {
+			t.Fatalf("unable to read the agreement, error %v", err)
+		}
+		return ag == nil || ag.AgreementTimedout != 0
+	}
+
+	if err := h.Advance(30 * time.Second); err != nil {
+		t.Fatalf("%v", err)
+	} else if timedOut() {
+		t.Errorf("expecting the proposal to be pending before the protocol timeout")
+	}
+
+	if err := h.Advance(time.Minute); err != nil {
+		t.Fatalf("%v", err)
+	} else if !timedOut() {
+		t.Errorf("expecting the proposal to be terminated after the protocol timeout")
+	}
+}
@@ -0,0 +1,87 @@
+package agreementbot
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/metrics"
+)
+
+// The metrics exposed by the agbot on the /metrics API. Exchange call latency and errors are recorded by the
+// exchange package itself.
+
+// Proposal outcomes
+const PROPOSAL_SENT = "sent"
+const PROPOSAL_ACCEPTED = "accepted"
+const PROPOSAL_REJECTED = "rejected"
+const PROPOSAL_TIMEDOUT = "timedout"
+
+// Agreement lifecycle states
+const AG_STATE_ATTEMPTED = "attempted"
+const AG_STATE_PROPOSED = "proposed"
+const AG_STATE_REPLIED = "replied"
+const AG_STATE_FINALIZED = "finalized"
+const AG_STATE_TERMINATING = "terminating"
+const AG_STATE_ARCHIVED = "archived"
+
+var proposalCounter = metrics.NewCounterVec("anax_agbot_proposals_total",
+	"Agreement proposals by org, deployment policy or pattern, and outcome (sent, accepted, rejected, timedout).", "org", "policy", "result")
+
+var agreementTransitionCounter = metrics.NewCounterVec("anax_agbot_agreement_transitions_total",
+	"Agreement lifecycle transitions by agreement protocol and new state.", "protocol", "state")
+
+var agreementCancelCounter = metrics.NewCounterVec("anax_agbot_agreement_cancellations_total",
+	"Agreements cancelled by the agbot, by agreement protocol and termination reason.", "protocol", "reason")
+
+var workQueueDepth = metrics.NewGaugeVec("anax_agbot_workqueue_depth",
+	"Number of work items buffered in the prioritized agreement work queue, by priority.", "priority")
+
+var workQueueWait = metrics.NewHistogramVec("anax_agbot_workqueue_wait_seconds",
+	"Time work items spend in the prioritized agreement work queue before a worker picks them up, by priority.", metrics.DurationBuckets, "priority")
+
+var secretUpdatesDetected = metrics.NewCounterVec("anax_agbot_secret_updates_detected_total",
+	"Secret changes detected in the secrets provider.")
+
+var secretPropagationCounter = metrics.NewCounterVec("anax_agbot_secret_propagations_total",
+	"Agreements that were sent updated secrets, by agreement org.", "org")
+
+var secretPropagationErrors = metrics.NewCounterVec("anax_agbot_secret_propagation_errors_total",
+	"Errors retrieving updated secret details for an agreement, by agreement org.", "org")
+
+var partitionGauge = metrics.NewGaugeVec("anax_agbot_partitions",
+	"Number of database partitions by owner.", "owner")
+
+var partitionClaims = metrics.NewCounterVec("anax_agbot_partition_claims_total",
+	"Stale database partitions taken over by this agbot.")
+
+func recordProposal(org string, policyName string, result string) {
+	proposalCounter.Inc(org, policyName, result)
+}
+
+func recordAgreementTransition(protocol string, state string) {
+	agreementTransitionCounter.Inc(protocol, state)
+}
+
+// Refresh the partition ownership gauge from the database. This is called when the metrics are scraped because
+// partition ownership is maintained by the database, not by the agbot.
+func refreshPartitionMetrics(db persistence.AgbotDatabase) {
+	if db == nil {
+		return
+	}
+
+	partitions, err := db.FindPartitions()
+	if err != nil {
+		glog.Errorf(APIlogString(fmt.Sprintf("error finding partitions for metrics, error: %v", err)))
+		return
+	}
+
+	partitionGauge.Reset()
+	for _, p := range partitions {
+		if owner, err := db.GetPartitionOwner(p); err != nil {
+			glog.Errorf(APIlogString(fmt.Sprintf("error finding partition %v owner for metrics, error: %v", p, err)))
+		} else {
+			partitionGauge.Inc(owner)
+		}
+	}
+}
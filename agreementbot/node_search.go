@@ -24,7 +24,7 @@ type NodeSearch struct {
 	db                   persistence.AgbotDatabase
 	pm                   *policy.PolicyManager
 	ph                   *ConsumerPHMgr
-	ec                   exchange.ExchangeContext
+	exchanges            *ExchangeFederation // The exchanges that hold the nodes, patterns and policies to search.
 	msgs                 chan events.Message // Outgoing internal event messages are placed here.
 	nextScanIntervalS    uint64              // The interval between scans when there are changes in the system. It allows the system to process existing work before injecting new agreements.
 	errRecanIntervalS    uint64              // The interval between scans if error occured during last scan
@@ -60,13 +60,13 @@ func NewNodeSearch() *NodeSearch {
 }
 
 // Give the object a chance to initialize itself.
-func (n *NodeSearch) Init(db persistence.AgbotDatabase, pm *policy.PolicyManager, ph *ConsumerPHMgr, msgs chan events.Message, ec exchange.ExchangeContext, cfg *config.HorizonConfig) {
+func (n *NodeSearch) Init(db persistence.AgbotDatabase, pm *policy.PolicyManager, ph *ConsumerPHMgr, msgs chan events.Message, exchanges *ExchangeFederation, cfg *config.HorizonConfig) {
 
 	n.db = db
 	n.pm = pm
 	n.ph = ph
 	n.msgs = msgs
-	n.ec = ec
+	n.exchanges = exchanges
 	n.nextScanIntervalS = cfg.AgreementBot.NewContractIntervalS
 	n.errRecanIntervalS = cfg.GetAgbotErrReschanInterval()
 	n.fullRescanIntervalS = cfg.GetAgbotFullRescan()
@@ -307,106 +307,115 @@ func (n *NodeSearch) getOrderedPolicies(org string) []policy.Policy {
 }
 
 // Search the exchange and make agreements with any device that is eligible based on the policies we have and
-// agreement protocols that we support. If the search did not process all the possible node matches, return false
-// to indicate that there are more nodes to be processed.
+// agreement protocols that we support. The devices are queued for agreements a page at a time, as the search results
+// are read. If the search did not process all the possible node matches, return false to indicate that there are
+// more nodes to be processed.
 func (n *NodeSearch) searchNodesAndMakeAgreements(consumerPolicy *policy.Policy, org string, polName string, polLastUpdateTime uint64) (bool, error) {
 
 	endOfResults := true
 
-	if devices, err := n.searchExchange(consumerPolicy, org, polName, polLastUpdateTime); err != nil {
-		glog.Errorf(AWlogString(fmt.Sprintf("received error searching for %v, error: %v", consumerPolicy, err)))
-		return endOfResults, err
+	// Get all the agreements for this policy that are still active.
+	pendingAgreementFilter := func() persistence.AFilter {
+		return func(a persistence.Agreement) bool {
+			return a.PolicyName == consumerPolicy.Header.Name && a.AgreementTimedout == 0
+		}
+	}
 
-	} else {
+	ags := make(map[string][]persistence.Agreement)
 
-		// Remember whether or not this search returned all the possible nodes.
-		if uint64(len(*devices)) == n.batchSize {
-			endOfResults = false
+	// The agreements with this policy could be part of any supported agreement protocol.
+	for _, agp := range policy.AllAgreementProtocols() {
+		// Find all agreements that are in progress. They might be waiting for a reply or not yet finalized.
+		// TODO: To support more than 1 agreement (maxagreements > 1) with this device for this policy, we need to adjust this logic.
+		if agreements, err := n.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), pendingAgreementFilter()}, agp); err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("received error trying to find pending agreements for protocol %v: %v", agp, err)))
+		} else {
+			ags[agp] = agreements
 		}
+	}
 
-		// Get all the agreements for this policy that are still active.
-		pendingAgreementFilter := func() persistence.AFilter {
-			return func(a persistence.Agreement) bool {
-				return a.PolicyName == consumerPolicy.Header.Name && a.AgreementTimedout == 0
-			}
-		}
+	makeAgreements := func(devices []exchange.SearchResultDevice) error {
+		n.makeAgreements(devices, consumerPolicy, org, polName, ags)
+		return nil
+	}
 
-		ags := make(map[string][]persistence.Agreement)
+	if found, err := n.searchExchange(consumerPolicy, org, polName, polLastUpdateTime, makeAgreements); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("received error searching for %v, error: %v", consumerPolicy, err)))
+		return endOfResults, err
 
-		// The agreements with this policy could be part of any supported agreement protocol.
-		for _, agp := range policy.AllAgreementProtocols() {
-			// Find all agreements that are in progress. They might be waiting for a reply or not yet finalized.
-			// TODO: To support more than 1 agreement (maxagreements > 1) with this device for this policy, we need to adjust this logic.
-			if agreements, err := n.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), pendingAgreementFilter()}, agp); err != nil {
-				glog.Errorf(AWlogString(fmt.Sprintf("received error trying to find pending agreements for protocol %v: %v", agp, err)))
-			} else {
-				ags[agp] = agreements
-			}
-		}
+	} else if uint64(found) == n.batchSize {
+		// Remember whether or not this search returned all the possible nodes.
+		endOfResults = false
+	}
 
-		// For each Scan(), clear the cache only once when there are devices returned from the search api.
-		if n.clearExchangeCache && len(*devices) != 0 {
-			glog.V(5).Infof("Clearing cache for all resources.")
-			exchange.ClearAllResourceCache()
-			n.clearExchangeCache = false
-		}
+	return endOfResults, nil
 
-		for _, dev := range *devices {
+}
 
-			glog.V(3).Infof(AWlogString(fmt.Sprintf("picked up %v for policy %v.", dev.ShortString(), consumerPolicy.Header.Name)))
-			glog.V(5).Infof(AWlogString(fmt.Sprintf("picked up %v", dev)))
+// Queue agreement attempts with the devices from a page of search results. The input list of agreements has already
+// been filtered to include only agreements using the input policy.
+func (n *NodeSearch) makeAgreements(devices []exchange.SearchResultDevice, consumerPolicy *policy.Policy, org string, polName string, ags map[string][]persistence.Agreement) {
 
-			// Check for agreements already in progress with this device
-			if found := n.alreadyMakingAgreementWith(&dev, consumerPolicy, ags); found {
-				glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, agreement attempt already in progress with %v", dev.Id, consumerPolicy.Header.Name)))
-				continue
-			}
+	// For each Scan(), clear the cache only once when there are devices returned from the search api.
+	if n.clearExchangeCache && len(devices) != 0 {
+		glog.V(5).Infof("Clearing cache for all resources.")
+		exchange.ClearAllResourceCache()
+		n.clearExchangeCache = false
+	}
 
-			// If the device is not ready to make agreements yet, then skip it.
-			if dev.PublicKey == "" {
-				glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, node is not ready to exchange messages", dev.Id)))
-				continue
-			}
+	for _, dev := range devices {
 
-			producerPolicy := policy.Policy_Factory(consumerPolicy.Header.Name)
-
-			// Get the cached service policies from the business policy manager. The returned value
-			// is a map keyed by the service id.
-			// There could be many service versions defined in a business policy.
-			// The policy manager only caches the ones that are used by an old agreement for this business policy.
-			// The cached ones may not be what the new agreement will use. If the new agreement chooses a
-			// new service version, then the new service policy will be put into the cache.
-			svcPolicies := make(map[string]externalpolicy.ExternalPolicy, 0)
-			if consumerPolicy.PatternId == "" {
-				svcPolicies = businessPolManager.GetServicePoliciesForPolicy(org, polName)
-			}
+		glog.V(3).Infof(AWlogString(fmt.Sprintf("picked up %v for policy %v.", dev.ShortString(), consumerPolicy.Header.Name)))
+		glog.V(5).Infof(AWlogString(fmt.Sprintf("picked up %v", dev)))
 
-			// Select a worker pool based on the agreement protocol that will be used. This is decided by the
-			// consumer policy.
-			protocol := policy.Select_Protocol(producerPolicy, consumerPolicy)
-			cmd := NewMakeAgreementCommand(*producerPolicy, *consumerPolicy, org, polName, dev, svcPolicies)
+		// Check for agreements already in progress with this device
+		if found := n.alreadyMakingAgreementWith(&dev, consumerPolicy, ags); found {
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, agreement attempt already in progress with %v", dev.Id, consumerPolicy.Header.Name)))
+			continue
+		}
 
-			bcType, bcName, bcOrg := producerPolicy.RequiresKnownBC(protocol)
+		// If the device is not ready to make agreements yet, then skip it.
+		if dev.PublicKey == "" {
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, node is not ready to exchange messages", dev.Id)))
+			continue
+		}
 
-			if !n.ph.Has(protocol) {
-				glog.Errorf(AWlogString(fmt.Sprintf("unable to find protocol handler for %v.", protocol)))
-			} else if bcType != "" && !n.ph.Get(protocol).IsBlockchainWritable(bcType, bcName, bcOrg) {
-				// Get that blockchain running if it isn't up.
-				glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, requires blockchain %v %v %v that isnt ready yet.", dev.Id, bcType, bcName, bcOrg)))
-				n.msgs <- events.NewNewBCContainerMessage(events.NEW_BC_CLIENT, bcType, bcName, bcOrg, n.ec.GetExchangeURL(), n.ec.GetExchangeId(), n.ec.GetExchangeToken())
-				continue
-			} else if !n.ph.Get(protocol).AcceptCommand(cmd) {
-				glog.Errorf(AWlogString(fmt.Sprintf("protocol handler for %v not accepting new agreement commands.", protocol)))
-			} else {
-				n.ph.Get(protocol).HandleMakeAgreement(cmd, n.ph.Get(protocol))
-				glog.V(5).Infof(AWlogString(fmt.Sprintf("queued agreement attempt for policy %v and node %v using protocol %v", consumerPolicy.Header.Name, dev.Id, protocol)))
-			}
+		producerPolicy := policy.Policy_Factory(consumerPolicy.Header.Name)
+
+		// Get the cached service policies from the business policy manager. The returned value
+		// is a map keyed by the service id.
+		// There could be many service versions defined in a business policy.
+		// The policy manager only caches the ones that are used by an old agreement for this business policy.
+		// The cached ones may not be what the new agreement will use. If the new agreement chooses a
+		// new service version, then the new service policy will be put into the cache.
+		svcPolicies := make(map[string]externalpolicy.ExternalPolicy, 0)
+		if consumerPolicy.PatternId == "" {
+			svcPolicies = businessPolManager.GetServicePoliciesForPolicy(org, polName)
 		}
 
+		// Select a worker pool based on the agreement protocol that will be used. This is decided by the
+		// consumer policy.
+		protocol := policy.Select_Protocol(producerPolicy, consumerPolicy)
+		cmd := NewMakeAgreementCommand(*producerPolicy, *consumerPolicy, org, polName, dev, svcPolicies)
+
+		bcType, bcName, bcOrg := producerPolicy.RequiresKnownBC(protocol)
+
+		if !n.ph.Has(protocol) {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to find protocol handler for %v.", protocol)))
+		} else if bcType != "" && !n.ph.Get(protocol).IsBlockchainWritable(bcType, bcName, bcOrg) {
+			// Get that blockchain running if it isn't up.
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, requires blockchain %v %v %v that isnt ready yet.", dev.Id, bcType, bcName, bcOrg)))
+			ec := n.exchanges.ForId(dev.Id)
+			n.msgs <- events.NewNewBCContainerMessage(events.NEW_BC_CLIENT, bcType, bcName, bcOrg, ec.GetExchangeURL(), ec.GetExchangeId(), ec.GetExchangeToken())
+			continue
+		} else if !n.ph.Get(protocol).AcceptCommand(cmd) {
+			glog.Errorf(AWlogString(fmt.Sprintf("protocol handler for %v not accepting new agreement commands.", protocol)))
+		} else {
+			// The work queue blocks when it is full, which holds back the reading of the next page of search results.
+			n.ph.Get(protocol).HandleMakeAgreement(cmd, n.ph.Get(protocol))
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("queued agreement attempt for policy %v and node %v using protocol %v", consumerPolicy.Header.Name, dev.Id, protocol)))
+		}
 	}
-
-	return endOfResults, nil
-
 }
 
 // Check all agreement protocol buckets to see if there are any agreements with this device.
@@ -440,16 +449,22 @@ func (n *NodeSearch) alreadyMakingAgreementWith(dev *exchange.SearchResultDevice
 // There are 2 ways to search the exchange; (a) by pattern and service or workload URL, or (b) by business policy.
 // If the agbot is working with a policy file that was generated from a pattern, then it will do searches
 // by pattern. If the agbot is working with a business policy, then it will do searches by the business policy.
-func (n *NodeSearch) searchExchange(pol *policy.Policy, polOrg string, polName string, polLastUpdateTime uint64) (*[]exchange.SearchResultDevice, error) {
+//
+// The devices found are given to the handler a page at a time, and the number of devices found is returned. A pattern
+// search returns every node that matches, in one response, so its pages are read from the response as they are
+// handled. A business policy search returns one page, of up to batchSize nodes, per search.
+//
+// The search is made in the exchange that holds the pattern or policy, so only the nodes in that exchange are found.
+func (n *NodeSearch) searchExchange(pol *policy.Policy, polOrg string, polName string, polLastUpdateTime uint64, handler exchange.SearchResultPageHandler) (int, error) {
 
 	// If it is a pattern based policy, search by workload URL and pattern.
 	if pol.PatternId != "" {
 		// Get a list of node orgs that the agbot is serving for this pattern.
-		nodeOrgs := patternManager.GetServedNodeOrgs(polOrg, exchange.GetId(pol.PatternId))
+		ec := n.exchanges.ForOrg(polOrg)
+		nodeOrgs := n.servedNodeOrgs(ec, pol.PatternId, patternManager.GetServedNodeOrgs(polOrg, exchange.GetId(pol.PatternId)))
 		if len(nodeOrgs) == 0 {
 			glog.V(3).Infof(AWlogString(fmt.Sprintf("Policy file for pattern %v exists but currently the agbot is not serving this policy for any organizations.", pol.PatternId)))
-			empty := make([]exchange.SearchResultDevice, 0, 0)
-			return &empty, nil
+			return 0, nil
 		}
 
 		arch := ""
@@ -475,11 +490,11 @@ func (n *NodeSearch) searchExchange(pol *policy.Policy, polOrg string, polName s
 		glog.V(3).Infof(AWlogString(fmt.Sprintf("searching %v with %v", pol.PatternId, ser)))
 
 		// Invoke the exchange
-		devs, err := exchange.GetHTTPAgbotPatternNodeSearchHandler(n.ec)(ser, polOrg, pol.PatternId)
+		found, err := exchange.GetHTTPAgbotPatternNodeSearchHandler(ec)(ser, polOrg, pol.PatternId, int(n.batchSize), handler)
 		if err == nil {
-			glog.V(3).Infof(AWlogString(fmt.Sprintf("found %v devices in exchange.", len(*devs))))
+			glog.V(3).Infof(AWlogString(fmt.Sprintf("found %v devices in exchange.", found)))
 		}
-		return devs, err
+		return found, err
 
 	} else {
 
@@ -498,18 +513,19 @@ func (n *NodeSearch) searchExchange(pol *policy.Policy, polOrg string, polName s
 		// processed by this Agbot. The Exchange uses the search session number as a key to know how much of the total result
 		// set has already been returned. This allows the exchange to return alternating pages of the result set to different
 		// Agbot instances.
-		searchSession, changedSince, err := n.db.ObtainSearchSession(pol.Header.Name)
+		sessionKey := n.exchanges.SearchSessionKey(pol.Header.Name)
+		searchSession, changedSince, err := n.db.ObtainSearchSession(sessionKey)
 		if err != nil {
 			glog.Errorf(AWlogString(fmt.Sprintf("unable to start a new search session for %v, error: %v", pol.Header.Name, err)))
-			return nil, err
+			return 0, err
 		}
 
 		// Get a list of node orgs that the agbot is serving for this business policy.
-		nodeOrgs := businessPolManager.GetServedNodeOrgs(polOrg, polName)
+		ec := n.exchanges.ForOrg(polOrg)
+		nodeOrgs := n.servedNodeOrgs(ec, pol.Header.Name, businessPolManager.GetServedNodeOrgs(polOrg, polName))
 		if len(nodeOrgs) == 0 {
 			glog.V(3).Infof(AWlogString(fmt.Sprintf("Business policy %v exists but currently the agbot is not serving this policy for any organizations.", pol.Header.Name)))
-			empty := make([]exchange.SearchResultDevice, 0, 0)
-			return &empty, nil
+			return 0, nil
 		}
 
 		// To make the search more efficient, the exchange only searches the nodes that have been changed since bp_check_time.
@@ -538,14 +554,13 @@ func (n *NodeSearch) searchExchange(pol *policy.Policy, polOrg string, polName s
 		// it is possible that the exchange could have different sessions for different policies, but that should never get out
 		// of sync with the agbot. The error handling in this loop is intended to compensate if the agbot session ever gets out
 		// of sync with the exchange session.
-		devs := make([]exchange.SearchResultDevice, 0, 0)
 		for {
 
 			glog.V(3).Infof(AWlogString(fmt.Sprintf("searching %v with %v", pol.Header.Name, ser)))
 			// Invoke the exchange and return the device list or any hard errors that occur.
-			resp, err := exchange.GetHTTPAgbotPolicyNodeSearchHandler(n.ec)(&ser, polOrg, polName)
+			resp, err := exchange.GetHTTPAgbotPolicyNodeSearchHandler(ec)(&ser, polOrg, polName)
 			if err != nil {
-				return nil, err
+				return 0, err
 			} else if resp.Session != "" {
 				glog.Errorf(AWlogString(fmt.Sprintf("for %v search session is out of sync: %v", pol.Header.Name, resp)))
 				// To get the agbot back in sync, we will need to use the exchange session until it is exhausted.
@@ -560,7 +575,7 @@ func (n *NodeSearch) searchExchange(pol *policy.Policy, polOrg string, polName s
 					// Update the DB with the new changedSince value, indicating that the scan is complete. This update also
 					// ends the current search session for this policy.
 					glog.V(3).Infof(AWlogString(fmt.Sprintf("for %v ending Session: %v", pol.Header.Name, searchSession)))
-					if sessionEnded, err := n.db.UpdateSearchSessionChangedSince(changedSince, currentSearchStart, pol.Header.Name); err != nil {
+					if sessionEnded, err := n.db.UpdateSearchSessionChangedSince(changedSince, currentSearchStart, sessionKey); err != nil {
 						glog.Errorf(AWlogString(fmt.Sprintf("unable to update search session changed since, error: %v", err)))
 					} else {
 						if sessionEnded {
@@ -572,17 +587,31 @@ func (n *NodeSearch) searchExchange(pol *policy.Policy, polOrg string, polName s
 					glog.V(3).Infof(AWlogString(fmt.Sprintf("for %v Session: %v scan not complete", pol.Header.Name, searchSession)))
 					n.SetRescanNeeded()
 				}
-				devs = resp.Devices
 			}
-			glog.V(3).Infof(AWlogString(fmt.Sprintf("found %v devices in exchange.", len(devs))))
-			return &devs, nil
+			glog.V(3).Infof(AWlogString(fmt.Sprintf("found %v devices in exchange.", len(resp.Devices))))
+			if len(resp.Devices) != 0 {
+				if err := handler(resp.Devices); err != nil {
+					return len(resp.Devices), err
+				}
+			}
+			return len(resp.Devices), nil
 		}
 	}
 }
 
+// Returns the node orgs that live in the same exchange as the pattern or policy being searched. The served orgs in
+// other exchanges can't be searched from this exchange.
+func (n *NodeSearch) servedNodeOrgs(ec *FederatedExchange, polName string, nodeOrgs []string) []string {
+	orgs := n.exchanges.OrgsIn(ec, nodeOrgs)
+	if len(orgs) != len(nodeOrgs) {
+		glog.Warningf(AWlogString(fmt.Sprintf("%v is in the %v exchange, skipping the served node orgs in other exchanges: %v", polName, ec.DisplayName(), nodeOrgs)))
+	}
+	return orgs
+}
+
 func (n *NodeSearch) AddRetry(policyName string, changedSince uint64) {
 	n.SetRescanNeeded()
-	if err := n.db.ResetPolicyChangedSince(policyName, changedSince); err != nil {
+	if err := n.db.ResetPolicyChangedSince(n.exchanges.SearchSessionKey(policyName), changedSince); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("unable to update %v search session changed since, error: %v", policyName, err)))
 	}
 }
@@ -0,0 +1,142 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// The bolt DB bucket name for the agbot event logs. The keys are the record ids, big endian, so that the event logs
+// are in the order they were saved.
+const EVENT_LOGS_BUCKET = "agbot_event_logs"
+
+func eventLogKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+func (db *AgbotBoltDB) SaveEventLog(el *persistence.AgbotEventLog) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(EVENT_LOGS_BUCKET))
+		if err != nil {
+			return err
+		}
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("Unable to get the next event log id, error: %v", err)
+		}
+		el.Id = strconv.FormatUint(id, 10)
+
+		if serial, err := json.Marshal(el); err != nil {
+			return fmt.Errorf("Unable to serialize event log %v, error: %v", el, err)
+		} else if err := b.Put(eventLogKey(id), serial); err != nil {
+			return fmt.Errorf("Unable to save event log %v, error: %v", el, err)
+		}
+		return nil
+	})
+}
+
+func (db *AgbotBoltDB) FindEventLogs(selectors map[string][]persistence.Selector, query persistence.EventLogQuery) ([]persistence.AgbotEventLog, string, error) {
+
+	cursor, err := persistence.ParseEventLogCursor(query.Cursor)
+	if err != nil {
+		return nil, "", err
+	} else if err := persistence.ValidateEventLogOrder(query.Order); err != nil {
+		return nil, "", err
+	}
+	desc := query.Order == persistence.EVENTLOG_ORDER_DESC
+
+	evlogs := make([]persistence.AgbotEventLog, 0)
+	next := ""
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_LOGS_BUCKET))
+		if b == nil {
+			return nil
+		}
+
+		// Position the cursor on the first event log after the page cursor.
+		c := b.Cursor()
+		var k, v []byte
+		if desc {
+			if cursor == 0 {
+				k, v = c.Last()
+			} else if k, v = c.Seek(eventLogKey(cursor)); k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		} else {
+			k, v = c.Seek(eventLogKey(cursor + 1))
+		}
+
+		for ; k != nil; k, v = step(c, desc) {
+			var el persistence.AgbotEventLog
+			if err := json.Unmarshal(v, &el); err != nil {
+				glog.Errorf("Unable to deserialize agbot event log db record: %v. Error: %v", v, err)
+				continue
+			} else if !persistence.InEventLogWindow(&el, query) || !el.Matches(selectors) {
+				continue
+			}
+
+			if query.Limit > 0 && len(evlogs) >= query.Limit {
+				next = evlogs[len(evlogs)-1].Id
+				break
+			}
+			evlogs = append(evlogs, el)
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		return nil, "", readErr
+	}
+	return evlogs, next, nil
+}
+
+func step(c *bolt.Cursor, desc bool) ([]byte, []byte) {
+	if desc {
+		return c.Prev()
+	}
+	return c.Next()
+}
+
+func (db *AgbotBoltDB) DeleteEventLogsBefore(timestamp uint64) (int, error) {
+	count := 0
+	err := db.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_LOGS_BUCKET))
+		if b == nil {
+			return nil
+		}
+
+		// The event logs are in the order they were saved, so stop at the first one that is new enough. The keys are
+		// deleted after the scan because deleting through a bolt cursor skips the next key.
+		old := make([][]byte, 0)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var el persistence.AgbotEventLog
+			if err := json.Unmarshal(v, &el); err != nil {
+				glog.Errorf("Unable to deserialize agbot event log db record: %v. Error: %v", v, err)
+			} else if el.Timestamp >= timestamp {
+				break
+			}
+			old = append(old, k)
+		}
+
+		for _, k := range old {
+			if err := b.Delete(k); err != nil {
+				return fmt.Errorf("Unable to delete event log %v, error: %v", binary.BigEndian.Uint64(k), err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package bolt
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func utsetup(t *testing.T) (*AgbotBoltDB, func()) {
+	dir, err := os.MkdirTemp("", "utagbotdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := bolt.Open(path.Join(dir, "anax-agbot.db"), 0600, nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return &AgbotBoltDB{db: db}, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_EventLogs(t *testing.T) {
+	db, cleanup := utsetup(t)
+	defer cleanup()
+
+	// Save event logs for two nodes, one second apart.
+	for i := 0; i < 6; i++ {
+		node := "myorg/node1"
+		if i%2 == 1 {
+			node = "myorg/node2"
+		}
+		el := persistence.NewAgbotEventLog(persistence.SEVERITY_INFO, persistence.EC_AB_PROPOSAL_SENT, "Sent agreement proposal.", "ag1", node, "myorg", "myorg/pol1", "")
+		el.Timestamp = uint64(1000 + i)
+		if err := db.SaveEventLog(el); err != nil {
+			t.Fatal(err)
+		}
+		assert.NotEqual(t, "", el.Id, "The record id should be assigned.")
+	}
+
+	selectors, _ := persistence.ConvertToSelectors(map[string][]string{"node_id": {"myorg/node1"}})
+	evlogs, next, err := db.FindEventLogs(selectors, persistence.EventLogQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", next)
+	if assert.Equal(t, 3, len(evlogs)) {
+		assert.Equal(t, "1", evlogs[0].Id)
+		assert.Equal(t, "5", evlogs[2].Id)
+	}
+
+	// Page through the newest event logs.
+	evlogs, next, err = db.FindEventLogs(nil, persistence.EventLogQuery{Limit: 2, Order: persistence.EVENTLOG_ORDER_DESC})
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(evlogs)) {
+		assert.Equal(t, "6", evlogs[0].Id)
+		assert.Equal(t, "5", next)
+	}
+	evlogs, _, err = db.FindEventLogs(nil, persistence.EventLogQuery{Limit: 2, Order: persistence.EVENTLOG_ORDER_DESC, Cursor: next})
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(evlogs)) {
+		assert.Equal(t, "4", evlogs[0].Id)
+	}
+
+	// The time window.
+	evlogs, _, err = db.FindEventLogs(nil, persistence.EventLogQuery{Since: 1002, Until: 1003})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(evlogs))
+
+	_, _, err = db.FindEventLogs(nil, persistence.EventLogQuery{Cursor: "abc"})
+	assert.NotNil(t, err, "The cursor is not valid.")
+
+	// Delete the oldest event logs.
+	count, err := db.DeleteEventLogsBefore(1004)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+	evlogs, _, err = db.FindEventLogs(nil, persistence.EventLogQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(evlogs))
+}
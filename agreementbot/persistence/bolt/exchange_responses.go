@@ -0,0 +1,47 @@
+package bolt
+
+import (
+	"fmt"
+	"github.com/boltdb/bolt"
+)
+
+const EXCHANGE_RESPONSES_BUCKET = "exchange_responses" // The bolt DB bucket name for the cached exchange responses.
+
+func (db *AgbotBoltDB) LoadExchangeResponses() (map[string][]byte, error) {
+	responses := make(map[string][]byte)
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EXCHANGE_RESPONSES_BUCKET)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				// The slices are only valid during the transaction.
+				responses[string(k)] = append([]byte{}, v...)
+				return nil
+			})
+		}
+		return nil
+	})
+
+	return responses, readErr
+}
+
+func (db *AgbotBoltDB) SaveExchangeResponse(key string, value []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if b, err := tx.CreateBucketIfNotExists([]byte(EXCHANGE_RESPONSES_BUCKET)); err != nil {
+			return err
+		} else if err := b.Put([]byte(key), value); err != nil {
+			return fmt.Errorf("Unable to save cached exchange response %v, error: %v", key, err)
+		}
+		return nil
+	})
+}
+
+func (db *AgbotBoltDB) DeleteExchangeResponse(key string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EXCHANGE_RESPONSES_BUCKET)); b == nil {
+			return nil
+		} else if err := b.Delete([]byte(key)); err != nil {
+			return fmt.Errorf("Unable to delete cached exchange response %v, error: %v", key, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,96 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+)
+
+const MESSAGE_NONCES_BUCKET = "message_nonces"       // The bolt DB bucket name for the nonces of the received messages.
+const TIMESTAMP_SENDERS_BUCKET = "timestamp_senders" // The bolt DB bucket name for the senders known to send timestamps.
+
+type messageNonce struct {
+	MsgId   int   `json:"msg_id"`
+	Expires int64 `json:"expires"`
+}
+
+func (db *AgbotBoltDB) SaveMessageNonce(nonce string, msgId int, expires int64) (int, error) {
+	firstId := msgId
+
+	writeErr := db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(MESSAGE_NONCES_BUCKET))
+		if err != nil {
+			return err
+		}
+
+		if existing := b.Get([]byte(nonce)); existing != nil {
+			var mn messageNonce
+			if err := json.Unmarshal(existing, &mn); err != nil {
+				return fmt.Errorf("Unable to demarshal message nonce %v, error: %v", nonce, err)
+			}
+			firstId = mn.MsgId
+			return nil
+		}
+
+		if serial, err := json.Marshal(messageNonce{MsgId: msgId, Expires: expires}); err != nil {
+			return fmt.Errorf("Unable to serialize message nonce %v, error: %v", nonce, err)
+		} else if err := b.Put([]byte(nonce), serial); err != nil {
+			return fmt.Errorf("Unable to save message nonce %v, error: %v", nonce, err)
+		}
+		return nil
+	})
+
+	return firstId, writeErr
+}
+
+func (db *AgbotBoltDB) DeleteExpiredMessageNonces(now int64) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MESSAGE_NONCES_BUCKET))
+		if b == nil {
+			return nil
+		}
+
+		// Keys cannot be deleted while iterating with ForEach, so collect them first.
+		expired := make([][]byte, 0, 10)
+		if err := b.ForEach(func(k, v []byte) error {
+			var mn messageNonce
+			if err := json.Unmarshal(v, &mn); err != nil || mn.Expires < now {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return fmt.Errorf("Unable to delete message nonce %s, error: %v", k, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (db *AgbotBoltDB) SaveTimestampSender(fingerprint string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if b, err := tx.CreateBucketIfNotExists([]byte(TIMESTAMP_SENDERS_BUCKET)); err != nil {
+			return err
+		} else if err := b.Put([]byte(fingerprint), []byte{1}); err != nil {
+			return fmt.Errorf("Unable to save message sender %v, error: %v", fingerprint, err)
+		}
+		return nil
+	})
+}
+
+func (db *AgbotBoltDB) IsTimestampSender(fingerprint string) (bool, error) {
+	known := false
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(TIMESTAMP_SENDERS_BUCKET)); b != nil {
+			known = b.Get([]byte(fingerprint)) != nil
+		}
+		return nil
+	})
+
+	return known, readErr
+}
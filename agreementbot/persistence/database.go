@@ -112,4 +112,23 @@ type AgbotDatabase interface {
 	GetHAUpgradingWorkload(org string, haGroupName string, policyName string) (*UpgradingHAGroupWorkload, error)
 	UpdateHAUpgradingWorkloadForGroupAndPolicy(org string, haGroupName string, policyName string, deviceId string) error
 	InsertHAUpgradingWorkloadForGroupAndPolicy(org string, haGroupName string, policyName string, deviceId string) (string, error)
+
+	// Functions related to persistence of the cached responses of exchange resources. The responses are serialized by the exchange package.
+	LoadExchangeResponses() (map[string][]byte, error)
+	SaveExchangeResponse(key string, value []byte) error
+	DeleteExchangeResponse(key string) error
+
+	// Functions related to persistence of the nonces of the received messages, and the senders known to send timestamps,
+	// so that a message cannot be replayed after a restart or to another agbot. SaveMessageNonce returns the exchange message
+	// id that the nonce was first saved with.
+	SaveMessageNonce(nonce string, msgId int, expires int64) (int, error)
+	DeleteExpiredMessageNonces(now int64) error
+	SaveTimestampSender(fingerprint string) error
+	IsTimestampSender(fingerprint string) (bool, error)
+
+	// Functions related to persistence of the agbot's event log. FindEventLogs returns the event logs that match the selectors
+	// within the time window of the query, a page at a time, and the cursor of the next page.
+	SaveEventLog(el *AgbotEventLog) error
+	FindEventLogs(selectors map[string][]Selector, query EventLogQuery) ([]AgbotEventLog, string, error)
+	DeleteEventLogsBefore(timestamp uint64) (int, error)
 }
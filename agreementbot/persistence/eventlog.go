@@ -0,0 +1,149 @@
+package persistence
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	agentpersistence "github.com/open-horizon/anax/persistence"
+)
+
+// The agbot's event log records the decisions that the agbot makes about the nodes, so that a user can find out why a
+// node did or did not get a workload. The selectors and the query are the same as the agent's event log, so that the
+// same selection strings work with both.
+type Selector = agentpersistence.Selector
+type EventLogQuery = agentpersistence.EventLogQuery
+
+// Severities of the agbot event logs.
+const (
+	SEVERITY_INFO  = agentpersistence.SEVERITY_INFO
+	SEVERITY_WARN  = agentpersistence.SEVERITY_WARN
+	SEVERITY_ERROR = agentpersistence.SEVERITY_ERROR
+)
+
+const (
+	EVENTLOG_ORDER_ASC  = agentpersistence.EVENTLOG_ORDER_ASC
+	EVENTLOG_ORDER_DESC = agentpersistence.EVENTLOG_ORDER_DESC
+)
+
+// Event codes of the agbot event logs.
+const (
+	EC_AB_PROPOSAL_NOT_SENT    = "proposal_not_sent"
+	EC_AB_PROPOSAL_SENT        = "proposal_sent"
+	EC_AB_PROPOSAL_ACCEPTED    = "proposal_accepted"
+	EC_AB_PROPOSAL_REJECTED    = "proposal_rejected"
+	EC_AB_PROPOSAL_TIMEDOUT    = "proposal_timedout"
+	EC_AB_AGREEMENT_CANCELLED  = "agreement_cancelled"
+	EC_AB_POLICY_CHANGED       = "policy_changed"
+	EC_AB_POLICY_DELETED       = "policy_deleted"
+	EC_AB_SECRET_UPDATE_SENT   = "secret_update_sent"
+	EC_AB_SECRET_UPDATE_FAILED = "secret_update_failed"
+)
+
+type AgbotEventLog struct {
+	Id          string `json:"record_id"` // unique primary key for records, assigned by the database
+	Timestamp   uint64 `json:"timestamp"`
+	Severity    string `json:"severity"` // info, warning or error
+	Message     string `json:"message"`
+	EventCode   string `json:"event_code"`
+	AgreementId string `json:"agreement_id,omitempty"`
+	NodeId      string `json:"node_id,omitempty"` // the org qualified id of the node
+	Org         string `json:"org,omitempty"`     // the org of the deployment policy or pattern
+	Policy      string `json:"policy,omitempty"`  // the name of the deployment policy or pattern
+	Reason      string `json:"reason,omitempty"`  // why the agreement was rejected or cancelled
+}
+
+func (e AgbotEventLog) String() string {
+	return fmt.Sprintf("Id: %v, Timestamp: %v, Severity: %v, EventCode: %v, AgreementId: %v, NodeId: %v, Org: %v, Policy: %v, Reason: %v, Message: %v",
+		e.Id, e.Timestamp, e.Severity, e.EventCode, e.AgreementId, e.NodeId, e.Org, e.Policy, e.Reason, e.Message)
+}
+
+func NewAgbotEventLog(severity string, eventCode string, message string, agreementId string, nodeId string, org string, policyName string, reason string) *AgbotEventLog {
+	return &AgbotEventLog{
+		Timestamp:   uint64(time.Now().Unix()),
+		Severity:    severity,
+		Message:     message,
+		EventCode:   eventCode,
+		AgreementId: agreementId,
+		NodeId:      nodeId,
+		Org:         org,
+		Policy:      policyName,
+		Reason:      reason,
+	}
+}
+
+// Checks if the event log matches the selectors. An unknown attribute name never matches.
+func (e AgbotEventLog) Matches(selectors map[string][]Selector) bool {
+	for s_attr, s_vals := range selectors {
+		var attr interface{}
+		switch s_attr {
+		case "record_id":
+			attr = e.Id
+		case "timestamp":
+			attr = e.Timestamp
+		case "time_since":
+			attr = (uint64(time.Now().Unix()) - e.Timestamp) / 3600
+		case "severity":
+			attr = e.Severity
+		case "message":
+			attr = e.Message
+		case "event_code":
+			attr = e.EventCode
+		case "agreement_id":
+			attr = e.AgreementId
+		case "node_id":
+			attr = e.NodeId
+		case "org":
+			attr = e.Org
+		case "policy":
+			attr = e.Policy
+		case "reason":
+			attr = e.Reason
+		default:
+			return false
+		}
+
+		if m, _, _ := agentpersistence.MatchAttributeValue(attr, s_vals); !m {
+			return false
+		}
+	}
+	return true
+}
+
+// Convert the query parameters of the /eventlog API into selectors.
+func ConvertToSelectors(selections map[string][]string) (map[string][]Selector, error) {
+	return agentpersistence.ConvertToSelectors(selections)
+}
+
+// Take the time window, limit, cursor and order out of the query parameters of the /eventlog API, so that the rest are
+// selectors.
+func GetEventLogQuery(selections map[string][]string, now time.Time) (*EventLogQuery, error) {
+	return agentpersistence.GetEventLogQuery(selections, now, func(cursor string) error {
+		_, err := ParseEventLogCursor(cursor)
+		return err
+	}, nil)
+}
+
+// The record ids of the agbot event logs are increasing numbers, the cursor of a page is the id of its last event log.
+func ParseEventLogCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	} else if id, err := strconv.ParseUint(cursor, 10, 64); err != nil || id == 0 {
+		return 0, fmt.Errorf("The cursor %v is not valid.", cursor)
+	} else {
+		return id, nil
+	}
+}
+
+// Returns an error if the order of the query is not valid.
+func ValidateEventLogOrder(order string) error {
+	if order != "" && order != EVENTLOG_ORDER_ASC && order != EVENTLOG_ORDER_DESC {
+		return fmt.Errorf("The order %v is not valid, it must be %v or %v.", order, EVENTLOG_ORDER_ASC, EVENTLOG_ORDER_DESC)
+	}
+	return nil
+}
+
+// Returns true if the event log is in the time window of the query.
+func InEventLogWindow(e *AgbotEventLog, query EventLogQuery) bool {
+	return e.Timestamp >= query.Since && (query.Until == 0 || e.Timestamp <= query.Until)
+}
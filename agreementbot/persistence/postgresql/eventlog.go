@@ -0,0 +1,124 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to manage the agbot event logs. The event logs of all the agbots are in
+// one table, so that a user can see every decision made about a node, whichever agbot made it.
+//
+// schema:
+// id:        The record id of the event log, in the order the event logs were saved.
+// timestamp: The time of the event, in seconds since the epoch.
+// log:       The event log, a JSON blob. The blob schema is defined by the AgbotEventLog struct in the persistence package.
+//
+
+// Create the event log table. This table will not be partitioned as it is shared between agbots
+const EVENT_LOGS_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS agbot_event_logs (
+	id        bigserial PRIMARY KEY,
+	timestamp bigint NOT NULL,
+	log       jsonb NOT NULL
+);`
+
+const EVENT_LOGS_CREATE_INDEX = `CREATE INDEX IF NOT EXISTS timestamp_index_on_agbot_event_logs ON agbot_event_logs (timestamp);`
+
+const EVENT_LOGS_INSERT = `INSERT INTO agbot_event_logs (timestamp, log) VALUES ($1, $2) RETURNING id;`
+
+const EVENT_LOGS_QUERY_ASC = `SELECT id, log FROM agbot_event_logs WHERE timestamp >= $1 AND timestamp <= $2 AND id > $3 ORDER BY id ASC;`
+
+const EVENT_LOGS_QUERY_DESC = `SELECT id, log FROM agbot_event_logs WHERE timestamp >= $1 AND timestamp <= $2 AND id < $3 ORDER BY id DESC;`
+
+const EVENT_LOGS_DELETE_BEFORE = `DELETE FROM agbot_event_logs WHERE timestamp < $1;`
+
+func (db *AgbotPostgresqlDB) SaveEventLog(el *persistence.AgbotEventLog) error {
+	// The record id is assigned by the database, so it is filled in when the log is read back.
+	el.Id = ""
+	if serial, err := json.Marshal(el); err != nil {
+		return fmt.Errorf("error serializing event log %v, error: %v", el, err)
+	} else {
+		var id uint64
+		if err := db.db.QueryRow(EVENT_LOGS_INSERT, int64(el.Timestamp), serial).Scan(&id); err != nil {
+			return fmt.Errorf("error saving event log %v, error: %v", el, err)
+		}
+		el.Id = strconv.FormatUint(id, 10)
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) FindEventLogs(selectors map[string][]persistence.Selector, query persistence.EventLogQuery) ([]persistence.AgbotEventLog, string, error) {
+
+	cursor, err := persistence.ParseEventLogCursor(query.Cursor)
+	if err != nil {
+		return nil, "", err
+	} else if err := persistence.ValidateEventLogOrder(query.Order); err != nil {
+		return nil, "", err
+	}
+
+	until := int64(math.MaxInt64)
+	if query.Until != 0 {
+		until = int64(query.Until)
+	}
+
+	sqlStr := EVENT_LOGS_QUERY_ASC
+	after := int64(cursor)
+	if query.Order == persistence.EVENTLOG_ORDER_DESC {
+		sqlStr = EVENT_LOGS_QUERY_DESC
+		if cursor == 0 {
+			after = math.MaxInt64
+		}
+	}
+
+	rows, err := db.db.Query(sqlStr, int64(query.Since), until, after)
+	if err != nil {
+		return nil, "", fmt.Errorf("error querying database for event logs, error: %v", err)
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+
+	evlogs := make([]persistence.AgbotEventLog, 0)
+	next := ""
+	for rows.Next() {
+		var id uint64
+		var logBytes []byte
+		if err := rows.Scan(&id, &logBytes); err != nil {
+			return nil, "", fmt.Errorf("error scanning row for event log, error: %v", err)
+		}
+
+		var el persistence.AgbotEventLog
+		if err := json.Unmarshal(logBytes, &el); err != nil {
+			glog.Errorf("Unable to deserialize agbot event log db record: %v. Error: %v", string(logBytes), err)
+			continue
+		}
+		el.Id = strconv.FormatUint(id, 10)
+
+		if !el.Matches(selectors) {
+			continue
+		} else if query.Limit > 0 && len(evlogs) >= query.Limit {
+			next = evlogs[len(evlogs)-1].Id
+			break
+		}
+		evlogs = append(evlogs, el)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating event logs, error: %v", err)
+	}
+	return evlogs, next, nil
+}
+
+func (db *AgbotPostgresqlDB) DeleteEventLogsBefore(timestamp uint64) (int, error) {
+	if res, err := db.db.Exec(EVENT_LOGS_DELETE_BEFORE, int64(timestamp)); err != nil {
+		return 0, fmt.Errorf("error deleting event logs before %v, error: %v", timestamp, err)
+	} else if count, err := res.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("error counting deleted event logs, error: %v", err)
+	} else {
+		return int(count), nil
+	}
+}
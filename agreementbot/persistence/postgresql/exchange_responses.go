@@ -0,0 +1,66 @@
+package postgresql
+
+import (
+	"fmt"
+)
+
+// Constants for the SQL statements that are used to manage the cached responses of exchange resources. The cached
+// responses are shared by all the agbots, since they all read the same exchange resources.
+//
+// schema:
+// key:     The user and URL of the exchange request.
+// value:   The cached response, serialized by the exchange package.
+// updated: The time when the response was cached.
+//
+
+// Create the cached exchange response table. This table will not be partitioned as it is shared between agbots
+const EXCHANGE_RESPONSES_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS exchange_responses (
+	key   text  PRIMARY KEY,
+	value bytea NOT NULL,
+	updated timestamp with time zone DEFAULT current_timestamp
+);`
+
+const EXCHANGE_RESPONSES_QUERY_ALL = `SELECT key, value FROM exchange_responses;`
+
+const EXCHANGE_RESPONSES_UPSERT = `INSERT INTO exchange_responses (key, value) VALUES ($1, $2)
+	ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated = current_timestamp;`
+
+const EXCHANGE_RESPONSES_DELETE = `DELETE FROM exchange_responses WHERE key = $1;`
+
+func (db *AgbotPostgresqlDB) LoadExchangeResponses() (map[string][]byte, error) {
+	responses := make(map[string][]byte)
+
+	rows, err := db.db.Query(EXCHANGE_RESPONSES_QUERY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database for cached exchange responses, error: %v", err)
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("error scanning row for cached exchange response, error: %v", err)
+		}
+		responses[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cached exchange responses, error: %v", err)
+	}
+	return responses, nil
+}
+
+func (db *AgbotPostgresqlDB) SaveExchangeResponse(key string, value []byte) error {
+	if _, err := db.db.Exec(EXCHANGE_RESPONSES_UPSERT, key, value); err != nil {
+		return fmt.Errorf("error saving cached exchange response %v, error: %v", key, err)
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) DeleteExchangeResponse(key string) error {
+	if _, err := db.db.Exec(EXCHANGE_RESPONSES_DELETE, key); err != nil {
+		return fmt.Errorf("error deleting cached exchange response %v, error: %v", key, err)
+	}
+	return nil
+}
@@ -116,6 +116,25 @@ func (db *AgbotPostgresqlDB) Initialize(cfg *config.HorizonConfig) error {
 			return fmt.Errorf("unable to create ha workload add if not present function, error: %v", err)
 		}
 
+		// Create the cached exchange response table. Do not partition it.
+		if _, err := db.db.Exec(EXCHANGE_RESPONSES_CREATE_MAIN_TABLE); err != nil {
+			return fmt.Errorf("unable to create exchange responses table, error: %v", err)
+		}
+
+		// Create the message replay tables. Do not partition them.
+		if _, err := db.db.Exec(MESSAGE_NONCES_CREATE_MAIN_TABLE); err != nil {
+			return fmt.Errorf("unable to create message nonces table, error: %v", err)
+		} else if _, err := db.db.Exec(TIMESTAMP_SENDERS_CREATE_MAIN_TABLE); err != nil {
+			return fmt.Errorf("unable to create timestamp senders table, error: %v", err)
+		}
+
+		// Create the event log table. Do not partition it.
+		if _, err := db.db.Exec(EVENT_LOGS_CREATE_MAIN_TABLE); err != nil {
+			return fmt.Errorf("unable to create event logs table, error: %v", err)
+		} else if _, err := db.db.Exec(EVENT_LOGS_CREATE_INDEX); err != nil {
+			return fmt.Errorf("unable to create event logs table index, error: %v", err)
+		}
+
 		glog.V(3).Infof("Postgresql primary partition database tables exist.")
 
 		// Migrate the database tables if necessary. Extract the current schema version from the version table,
@@ -0,0 +1,78 @@
+package postgresql
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Constants for the SQL statements that are used to detect replayed messages. The tables are shared by all the
+// agbots, since they all read the same messages from the exchange.
+//
+// message_nonces schema:
+// nonce:   The nonce of a received message, in hex.
+// msg_id:  The exchange message id of the message that the nonce was first received in.
+// expires: The time, in seconds since the epoch, after which a message with the nonce is rejected for its age.
+//
+// timestamp_senders schema:
+// fingerprint: The fingerprint of the key of a sender that has sent a message with a timestamp.
+//
+
+// Create the message nonce table. This table will not be partitioned as it is shared between agbots
+const MESSAGE_NONCES_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS message_nonces (
+	nonce   text    PRIMARY KEY,
+	msg_id  integer NOT NULL,
+	expires bigint  NOT NULL
+);`
+
+// Create the timestamp sender table. This table will not be partitioned as it is shared between agbots
+const TIMESTAMP_SENDERS_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS timestamp_senders (
+	fingerprint text PRIMARY KEY
+);`
+
+// The insert does nothing when the nonce is already there, so the message id it was first saved with is queried.
+const MESSAGE_NONCES_INSERT = `INSERT INTO message_nonces (nonce, msg_id, expires) VALUES ($1, $2, $3)
+	ON CONFLICT (nonce) DO NOTHING;`
+
+const MESSAGE_NONCES_QUERY = `SELECT msg_id FROM message_nonces WHERE nonce = $1;`
+
+const MESSAGE_NONCES_DELETE_EXPIRED = `DELETE FROM message_nonces WHERE expires < $1;`
+
+const TIMESTAMP_SENDERS_INSERT = `INSERT INTO timestamp_senders (fingerprint) VALUES ($1) ON CONFLICT (fingerprint) DO NOTHING;`
+
+const TIMESTAMP_SENDERS_QUERY = `SELECT fingerprint FROM timestamp_senders WHERE fingerprint = $1;`
+
+func (db *AgbotPostgresqlDB) SaveMessageNonce(nonce string, msgId int, expires int64) (int, error) {
+	if _, err := db.db.Exec(MESSAGE_NONCES_INSERT, nonce, msgId, expires); err != nil {
+		return 0, fmt.Errorf("error saving message nonce %v, error: %v", nonce, err)
+	}
+
+	firstId := 0
+	if err := db.db.QueryRow(MESSAGE_NONCES_QUERY, nonce).Scan(&firstId); err != nil {
+		return 0, fmt.Errorf("error querying message nonce %v, error: %v", nonce, err)
+	}
+	return firstId, nil
+}
+
+func (db *AgbotPostgresqlDB) DeleteExpiredMessageNonces(now int64) error {
+	if _, err := db.db.Exec(MESSAGE_NONCES_DELETE_EXPIRED, now); err != nil {
+		return fmt.Errorf("error deleting expired message nonces, error: %v", err)
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) SaveTimestampSender(fingerprint string) error {
+	if _, err := db.db.Exec(TIMESTAMP_SENDERS_INSERT, fingerprint); err != nil {
+		return fmt.Errorf("error saving message sender %v, error: %v", fingerprint, err)
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) IsTimestampSender(fingerprint string) (bool, error) {
+	var fp string
+	if err := db.db.QueryRow(TIMESTAMP_SENDERS_QUERY, fingerprint).Scan(&fp); err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("error querying message sender %v, error: %v", fingerprint, err)
+	}
+	return true, nil
+}
@@ -15,9 +15,11 @@ import (
 type PrioritizedWorkQueue struct {
 	inboundHigh         chan *AgreementWork // This is the high priority inbound channel.
 	workQueueBufferHigh []*AgreementWork    // The internal work queue buffer for the high inbound channel.
+	enqueueTimesHigh    []time.Time         // The time each item in the high buffer was queued, used for metrics.
 
 	inboundLow         chan *AgreementWork // This is the low priority inbound channel.
 	workQueueBufferLow []*AgreementWork    // The internal work queue buffer for the low inbound channel.
+	enqueueTimesLow    []time.Time         // The time each item in the low buffer was queued, used for metrics.
 
 	recv       chan *AgreementWork // This is the channel where workers listen/block for work.
 	bufferLock sync.Mutex          // A lock that protects access to the work queue buffers.
@@ -31,8 +33,10 @@ func NewPrioritizedWorkQueue(bufferSize uint64, statInterval int, maxRecords int
 	n := &PrioritizedWorkQueue{
 		inboundHigh:         make(chan *AgreementWork, bufferSize),
 		workQueueBufferHigh: make([]*AgreementWork, 0, bufferSize*2),
+		enqueueTimesHigh:    make([]time.Time, 0, bufferSize*2),
 		inboundLow:          make(chan *AgreementWork, bufferSize),
 		workQueueBufferLow:  make([]*AgreementWork, 0, bufferSize*2),
+		enqueueTimesLow:     make([]time.Time, 0, bufferSize*2),
 		recv:                make(chan *AgreementWork),
 		bufferSize:          bufferSize,
 		queueHistory:        NewPrioritizedWorkQueueHistory(statInterval, maxRecords),
@@ -122,12 +126,17 @@ func (n *PrioritizedWorkQueue) RemoveHighPriorityBufferHead() {
 	n.bufferLock.Lock()
 	defer n.bufferLock.Unlock()
 	n.workQueueBufferHigh = n.workQueueBufferHigh[1:]
+	workQueueWait.Observe(time.Since(n.enqueueTimesHigh[0]).Seconds(), HIGH_PRIORITY)
+	n.enqueueTimesHigh = n.enqueueTimesHigh[1:]
+	workQueueDepth.Set(float64(len(n.workQueueBufferHigh)), HIGH_PRIORITY)
 }
 
 func (n *PrioritizedWorkQueue) AddToHighPriorityBuffer(w *AgreementWork) {
 	n.bufferLock.Lock()
 	defer n.bufferLock.Unlock()
 	n.workQueueBufferHigh = append(n.workQueueBufferHigh, w)
+	n.enqueueTimesHigh = append(n.enqueueTimesHigh, time.Now())
+	workQueueDepth.Set(float64(len(n.workQueueBufferHigh)), HIGH_PRIORITY)
 }
 
 func (n *PrioritizedWorkQueue) LowPriorityBufferLen() int {
@@ -146,12 +155,17 @@ func (n *PrioritizedWorkQueue) RemoveLowPriorityBufferHead() {
 	n.bufferLock.Lock()
 	defer n.bufferLock.Unlock()
 	n.workQueueBufferLow = n.workQueueBufferLow[1:]
+	workQueueWait.Observe(time.Since(n.enqueueTimesLow[0]).Seconds(), LOW_PRIORITY)
+	n.enqueueTimesLow = n.enqueueTimesLow[1:]
+	workQueueDepth.Set(float64(len(n.workQueueBufferLow)), LOW_PRIORITY)
 }
 
 func (n *PrioritizedWorkQueue) AddToLowPriorityBuffer(w *AgreementWork) {
 	n.bufferLock.Lock()
 	defer n.bufferLock.Unlock()
 	n.workQueueBufferLow = append(n.workQueueBufferLow, w)
+	n.enqueueTimesLow = append(n.enqueueTimesLow, time.Now())
+	workQueueDepth.Set(float64(len(n.workQueueBufferLow)), LOW_PRIORITY)
 }
 
 const HIGH_PRIORITY = "high"
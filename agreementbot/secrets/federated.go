@@ -0,0 +1,195 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/open-horizon/anax/config"
+)
+
+// An agbot that serves orgs in more than one exchange keeps the secrets of each exchange apart. Each exchange has
+// its own secrets provider, which authenticates with the agbot's identity in that exchange and uses the exchange's
+// vault, or the agbot's vault when the exchange does not configure one. The secrets of an org are always read
+// from and written to the provider of the exchange that holds the org.
+
+type FederatedSecrets struct {
+	newProvider func() AgbotSecrets
+	primary     AgbotSecrets
+	providers   []AgbotSecrets          // All the providers, the primary exchange's first.
+	orgs        map[string]AgbotSecrets // The providers of the additional exchanges, keyed by the orgs they hold.
+}
+
+func NewFederatedSecrets(newProvider func() AgbotSecrets) *FederatedSecrets {
+	return &FederatedSecrets{
+		newProvider: newProvider,
+		orgs:        make(map[string]AgbotSecrets),
+	}
+}
+
+// Initialize a provider for the primary exchange and one for each additional exchange. A provider is given a copy
+// of the config with the agbot's identity and vault in its exchange.
+func (f *FederatedSecrets) Initialize(cfg *config.HorizonConfig) error {
+	f.primary = f.newProvider()
+	f.providers = []AgbotSecrets{f.primary}
+	if err := f.primary.Initialize(cfg); err != nil {
+		return err
+	}
+
+	for _, ex := range cfg.AgreementBot.Exchanges {
+		exCfg := *cfg
+		exCfg.AgreementBot.ExchangeURL = ex.ExchangeURL
+		exCfg.AgreementBot.ExchangeURLs = nil
+		exCfg.AgreementBot.ExchangeId = ex.ExchangeId
+		exCfg.AgreementBot.ExchangeToken = ex.ExchangeToken
+		if ex.Vault != (config.VaultConfig{}) {
+			exCfg.AgreementBot.Vault = ex.Vault
+		}
+
+		provider := f.newProvider()
+		if err := provider.Initialize(&exCfg); err != nil {
+			return errors.New(fmt.Sprintf("unable to initialize the secrets provider for exchange %v, error %v", ex.Name, err))
+		}
+		f.providers = append(f.providers, provider)
+		for _, org := range ex.Orgs {
+			f.orgs[org] = provider
+		}
+	}
+	return nil
+}
+
+// Returns the provider of the exchange that holds an org.
+func (f *FederatedSecrets) forOrg(org string) AgbotSecrets {
+	if provider, ok := f.orgs[org]; ok {
+		return provider
+	}
+	return f.primary
+}
+
+// Log in to the providers that are not ready, and renew the login of the others, so that a provider that cannot be
+// reached does not keep the others from renewing.
+func (f *FederatedSecrets) Login() error {
+	errs := make([]string, 0, len(f.providers))
+	for _, provider := range f.providers {
+		var err error
+		if !provider.IsReady() {
+			err = provider.Login()
+		} else {
+			err = provider.Renew()
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (f *FederatedSecrets) Renew() error {
+	return f.Login()
+}
+
+func (f *FederatedSecrets) Close() {
+	for _, provider := range f.providers {
+		provider.Close()
+	}
+}
+
+// The providers are ready when all of them are logged in.
+func (f *FederatedSecrets) IsReady() bool {
+	for _, provider := range f.providers {
+		if !provider.IsReady() {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the oldest of the last interactions with the providers, so that a vault that has not been reached shows up.
+func (f *FederatedSecrets) GetLastVaultStatus() uint64 {
+	last := f.primary.GetLastVaultStatus()
+	for _, provider := range f.providers {
+		if status := provider.GetLastVaultStatus(); status < last {
+			last = status
+		}
+	}
+	return last
+}
+
+func (f *FederatedSecrets) ListAllSecrets(user, token, org, path string) ([]string, error) {
+	return f.forOrg(org).ListAllSecrets(user, token, org, path)
+}
+
+func (f *FederatedSecrets) ListOrgSecret(user, token, org, path string) error {
+	return f.forOrg(org).ListOrgSecret(user, token, org, path)
+}
+
+func (f *FederatedSecrets) ListOrgSecrets(user, token, org, path string) ([]string, error) {
+	return f.forOrg(org).ListOrgSecrets(user, token, org, path)
+}
+
+func (f *FederatedSecrets) CreateOrgSecret(user, token, org, path string, data SecretDetails) error {
+	return f.forOrg(org).CreateOrgSecret(user, token, org, path, data)
+}
+
+func (f *FederatedSecrets) DeleteOrgSecret(user, token, org, path string) error {
+	return f.forOrg(org).DeleteOrgSecret(user, token, org, path)
+}
+
+func (f *FederatedSecrets) ListOrgUserSecret(user, token, org, path string) error {
+	return f.forOrg(org).ListOrgUserSecret(user, token, org, path)
+}
+
+func (f *FederatedSecrets) ListOrgUserSecrets(user, token, org, path string) ([]string, error) {
+	return f.forOrg(org).ListOrgUserSecrets(user, token, org, path)
+}
+
+func (f *FederatedSecrets) CreateOrgUserSecret(user, token, org, path string, data SecretDetails) error {
+	return f.forOrg(org).CreateOrgUserSecret(user, token, org, path, data)
+}
+
+func (f *FederatedSecrets) DeleteOrgUserSecret(user, token, org, path string) error {
+	return f.forOrg(org).DeleteOrgUserSecret(user, token, org, path)
+}
+
+func (f *FederatedSecrets) ListOrgNodeSecret(user, token, org, path string) error {
+	return f.forOrg(org).ListOrgNodeSecret(user, token, org, path)
+}
+
+func (f *FederatedSecrets) ListOrgNodeSecrets(user, token, org, node, path string) ([]string, error) {
+	return f.forOrg(org).ListOrgNodeSecrets(user, token, org, node, path)
+}
+
+func (f *FederatedSecrets) CreateOrgNodeSecret(user, token, org, path string, data SecretDetails) error {
+	return f.forOrg(org).CreateOrgNodeSecret(user, token, org, path, data)
+}
+
+func (f *FederatedSecrets) DeleteOrgNodeSecret(user, token, org, path string) error {
+	return f.forOrg(org).DeleteOrgNodeSecret(user, token, org, path)
+}
+
+func (f *FederatedSecrets) ListUserNodeSecret(user, token, org, path string) error {
+	return f.forOrg(org).ListUserNodeSecret(user, token, org, path)
+}
+
+func (f *FederatedSecrets) ListUserNodeSecrets(user, token, org, node, path string) ([]string, error) {
+	return f.forOrg(org).ListUserNodeSecrets(user, token, org, node, path)
+}
+
+func (f *FederatedSecrets) CreateUserNodeSecret(user, token, org, path string, data SecretDetails) error {
+	return f.forOrg(org).CreateUserNodeSecret(user, token, org, path, data)
+}
+
+func (f *FederatedSecrets) DeleteUserNodeSecret(user, token, org, path string) error {
+	return f.forOrg(org).DeleteUserNodeSecret(user, token, org, path)
+}
+
+func (f *FederatedSecrets) GetSecretDetails(user, token, org, secretUser, secretNode, secretName string) (SecretDetails, error) {
+	return f.forOrg(org).GetSecretDetails(user, token, org, secretUser, secretNode, secretName)
+}
+
+func (f *FederatedSecrets) GetSecretMetadata(secretOrg, secretUser, secretNode, secretName string) (SecretMetadata, error) {
+	return f.forOrg(secretOrg).GetSecretMetadata(secretOrg, secretUser, secretNode, secretName)
+}
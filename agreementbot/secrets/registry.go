@@ -11,21 +11,28 @@ import (
 // method is driven. This mechanism prevents the need for the secrets package to import each of the
 // secrets implementation packages. The only tricky part of this is that name of each secrets implementation is hard
 // coded here and in the implementation's call to the Register() method. Sharing constants would re-introduce
-// the package dependency that we want to avoid.
-type SecretsProviderRegistry map[string]AgbotSecrets
+// the package dependency that we want to avoid. An implementation registers a function that returns a new,
+// uninitialized instance, because an agbot that serves more than one exchange uses an instance for each of them.
+type SecretsProviderRegistry map[string]func() AgbotSecrets
 
 var SecretsProviders = SecretsProviderRegistry{}
 
-func Register(name string, as AgbotSecrets) {
-	SecretsProviders[name] = as
+func Register(name string, newProvider func() AgbotSecrets) {
+	SecretsProviders[name] = newProvider
 }
 
 // Initialize the underlying Agbot Secrets implementation depending on what is configured. If vault is configured, it is used.
-// If nothing is configured, an error is returned.
+// If nothing is configured, an error is returned. When the agbot serves additional exchanges, the secrets of the orgs in
+// each exchange are kept apart, see FederatedSecrets.
 func InitSecrets(cfg *config.HorizonConfig) (AgbotSecrets, error) {
 
 	if cfg.IsVaultConfigured() {
-		secretsObj := SecretsProviders["vault"]
+		var secretsObj AgbotSecrets
+		if len(cfg.AgreementBot.Exchanges) == 0 {
+			secretsObj = SecretsProviders["vault"]()
+		} else {
+			secretsObj = NewFederatedSecrets(SecretsProviders["vault"])
+		}
 		return secretsObj, secretsObj.Initialize(cfg)
 
 	}
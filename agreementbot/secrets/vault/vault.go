@@ -18,7 +18,7 @@ import (
 // This function registers an uninitialized agbot secrets implementation with the secrets plugin registry. The plugin's Initialize
 // method is used to configure the object.
 func init() {
-	secrets.Register("vault", new(AgbotVaultSecrets))
+	secrets.Register("vault", func() secrets.AgbotSecrets { return new(AgbotVaultSecrets) })
 }
 
 // This is the time format used by the vault.
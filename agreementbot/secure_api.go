@@ -23,7 +23,6 @@ import (
 	"net/http"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/golang/glog"
 	"github.com/gorilla/mux"
@@ -53,6 +52,7 @@ type SecureAPI struct {
 	em             *events.EventStateManager
 	shutdownError  string
 	secretProvider secrets.AgbotSecrets
+	exchanges      *ExchangeFederation
 }
 
 func NewSecureAPIListener(name string, config *config.HorizonConfig, db persistence.AgbotDatabase, s secrets.AgbotSecrets) *SecureAPI {
@@ -68,6 +68,7 @@ func NewSecureAPIListener(name string, config *config.HorizonConfig, db persiste
 		db:             db,
 		em:             events.NewEventStateManager(),
 		secretProvider: s,
+		exchanges:      NewExchangeFederation(config),
 	}
 
 	listener.listen()
@@ -107,6 +108,13 @@ func (a *SecureAPI) NewEvent(ev events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (a *SecureAPI) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 func (a *SecureAPI) saveShutdownError(msg events.Message) {
 	switch msg.(type) {
 	case *events.NodeShutdownCompleteMessage:
@@ -119,8 +127,10 @@ func (a *SecureAPI) GetName() string {
 	return a.name
 }
 
+// Users are authenticated by the exchange that holds their org.
 func (a *SecureAPI) createUserExchangeContext(userId string, passwd string) exchange.ExchangeContext {
-	return exchange.NewCustomExchangeContext(userId, passwd, a.Config.AgreementBot.ExchangeURL, a.Config.GetAgbotCSSURL(), newHTTPClientFactory())
+	ec := a.exchanges.ForId(userId)
+	return exchange.NewCustomExchangeContext(userId, passwd, ec.GetExchangeURL(), ec.GetCSSURL(), newHTTPClientFactory())
 }
 
 func (a *SecureAPI) setCommonHeaders(w http.ResponseWriter) http.ResponseWriter {
@@ -925,8 +935,9 @@ func (a *SecureAPI) authenticateWithExchange(authHeaderTokenValue string, user s
 	user_ec := a.createUserExchangeContext(user, userTokenPasswd)
 
 	// Invoke the exchange API to verify the user.
+	targetURL := fmt.Sprintf("%vorgs/%v/%v/%v", user_ec.GetExchangeURL(), orgId, authType, userId)
 	retryCount := user_ec.GetHTTPFactory().RetryCount
-	retryInterval := user_ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := exchange.NewRetryBackoff(user_ec.GetHTTPFactory(), targetURL)
 	for {
 		retryCount = retryCount - 1
 
@@ -937,8 +948,6 @@ func (a *SecureAPI) authenticateWithExchange(authHeaderTokenValue string, user s
 			resp = new(exchange.GetDevicesResponse)
 		}
 
-		targetURL := fmt.Sprintf("%vorgs/%v/%v/%v", user_ec.GetExchangeURL(), orgId, authType, userId)
-
 		if err, tpErr := exchange.InvokeExchange(a.httpClient, "GET", targetURL, user, userTokenPasswd, nil, &resp); err != nil {
 			glog.Errorf(APIlogString(err.Error()))
 
@@ -953,7 +962,7 @@ func (a *SecureAPI) authenticateWithExchange(authHeaderTokenValue string, user s
 			if retryCount <= 0 {
 				return nil, "", fmt.Errorf("Exceeded %v retries for error: %v", retryCount, tpErr)
 			}
-			time.Sleep(time.Duration(retryInterval) * time.Second)
+			retryBackoff.Wait()
 			continue
 		} else if authType == UserTypeCred {
 			// iterate through the users returned by the Exchange (should only be one)
@@ -9,6 +9,7 @@ import (
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/metrics"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/worker"
@@ -87,12 +88,25 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	router.HandleFunc("/status", a.status).Methods("GET", "OPTIONS")
 	router.HandleFunc("/status/workers", a.workerstatus).Methods("GET", "OPTIONS")
 
+	// Stream the events passed between the workers, for debugging. Requires the event tap token.
+	router.HandleFunc("/debug/eventtap", a.eventTap).Methods("GET", "OPTIONS")
+
+	// Liveness and readiness probes
+	router.HandleFunc("/health/live", a.liveness).Methods("GET", "OPTIONS")
+	router.HandleFunc("/health/ready", a.readiness).Methods("GET", "OPTIONS")
+
+	// Metrics for Prometheus scraping, only when enabled in the config
+	if a.Config.Edge.EnableMetrics {
+		router.HandleFunc("/metrics", metrics.Handler(func() { UpdateAgentMetrics(a.db) })).Methods("GET", "OPTIONS")
+	}
+
 	// Used by the Registration UI to obtain a random token string
 	router.HandleFunc("/token/random", tokenRandom).Methods("GET", "OPTIONS")
 
 	// Used to configure a node to participate in the Horizon platform
 	router.HandleFunc("/node", a.node).Methods("GET", "HEAD", "POST", "PATCH", "DELETE", "OPTIONS")
 	router.HandleFunc("/node/configstate", a.nodeconfigstate).Methods("GET", "HEAD", "PUT", "OPTIONS")
+	router.HandleFunc("/node/messagekey", a.nodemessagekey).Methods("GET", "PUT", "OPTIONS")
 	router.HandleFunc("/node/policy", a.nodepolicy).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
 	router.HandleFunc("/node/userinput", a.nodeuserinput).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
 
@@ -104,6 +118,8 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	router.HandleFunc("/eventlog/all", a.eventlog).Methods("GET", "OPTIONS")
 	// delete all eventlogs from previous registrations
 	router.HandleFunc("/eventlog/prune", a.eventlog).Methods("DELETE", "OPTIONS")
+	// stream the eventlogs as they are saved
+	router.HandleFunc("/eventlog/stream", a.eventlogStream).Methods("GET", "OPTIONS")
 	//get the active surface errors for this node
 	router.HandleFunc("/eventlog/surface", a.surface).Methods("GET", "OPTIONS")
 
@@ -158,14 +174,52 @@ func (a *API) listen(cfg *config.HorizonConfig) {
 		})
 	}
 
+	// The callers have to authenticate when it is turned on in the config. The API is not served at all when the
+	// authentication can't be set up, rather than served without it.
+	router := a.router(true)
+	authCfg := cfg.Edge.APIAuth
+	if authCfg.Enabled() {
+		auth, err := newAPIAuth(authCfg)
+		if err != nil {
+			glog.Fatalf(apiLogString(fmt.Sprintf("Failed to set up the API authentication, error %v", err)))
+		}
+		router.Use(auth.middleware)
+		glog.Infof(apiLogString(fmt.Sprintf("API authentication enabled: %v", authCfg)))
+	}
+	handler := nocache(router)
+
 	// This routine does not need to be a subworker because there is no way to terminate it. It will terminate when
 	// the main anax process goes away.
 	go func() {
-		if err := http.ListenAndServe(cfg.Edge.APIListen, nocache(a.router(true))); err != nil {
+		server := &http.Server{Addr: cfg.Edge.APIListen, Handler: handler}
+		var err error
+		if authCfg.TLSCertFile != "" {
+			if server.TLSConfig, err = getAPITLSConfig(authCfg); err == nil {
+				err = server.ListenAndServeTLS(authCfg.TLSCertFile, authCfg.TLSKeyFile)
+			}
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil {
 			glog.Fatalf(apiLogString(fmt.Sprintf("Failed to start listener on %v, error %v", cfg.Edge.APIListen, err)))
 		}
 	}()
 
+	// The API is also served on a unix socket when one is configured, the callers on the socket are identified by
+	// their user id.
+	if authCfg.UnixSocket != "" {
+		go func() {
+			listener, err := listenAPIUnixSocket(authCfg.UnixSocket)
+			if err == nil {
+				server := &http.Server{Handler: handler, ConnContext: apiConnContext}
+				err = server.Serve(listener)
+			}
+			if err != nil {
+				glog.Fatalf(apiLogString(fmt.Sprintf("Failed to start listener on %v, error %v", authCfg.UnixSocket, err)))
+			}
+		}()
+	}
+
 }
 
 // Worker framework functions
@@ -210,6 +264,15 @@ func (a *API) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (a *API) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.BlockchainClientInitializedMessage{},
+		&events.BlockchainClientStoppingMessage{},
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 func (a *API) GetName() string {
 	return a.name
 }
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// The roles of the callers of the agent's REST API. A caller with a role can do everything that the lower roles can.
+type APIRole int
+
+const (
+	API_ROLE_NONE     APIRole = iota // The caller has not authenticated.
+	API_ROLE_READONLY                // The caller can get the node's state.
+	API_ROLE_ADMIN                   // The caller can also change the node's state.
+)
+
+func (r APIRole) String() string {
+	switch r {
+	case API_ROLE_READONLY:
+		return "read-only"
+	case API_ROLE_ADMIN:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// The roles required by the methods of the routes that differ from the default, by route template. By default, GET
+// and HEAD need the read-only role, OPTIONS needs no role so that browsers can make preflight requests, and the other
+// methods change the node's state and need the admin role.
+var apiRouteRoles = map[string]map[string]APIRole{
+	// The probes are called by the container runtime, which has no credentials.
+	"/health/live":  {"GET": API_ROLE_NONE},
+	"/health/ready": {"GET": API_ROLE_NONE},
+
+	// The event tap checks its own token.
+	"/debug/eventtap": {"GET": API_ROLE_NONE},
+
+	// Attributes and user input can hold credentials, e.g. for docker registries.
+	"/attribute":      {"GET": API_ROLE_ADMIN, "HEAD": API_ROLE_ADMIN},
+	"/attribute/{id}": {"GET": API_ROLE_ADMIN, "HEAD": API_ROLE_ADMIN},
+	"/node/userinput": {"GET": API_ROLE_ADMIN, "HEAD": API_ROLE_ADMIN},
+	"/service/config": {"GET": API_ROLE_ADMIN},
+}
+
+// Returns the role needed to call the method on the route with the given template.
+func RequiredAPIRole(routeTemplate string, method string) APIRole {
+	if roles, ok := apiRouteRoles[routeTemplate]; ok {
+		if role, ok := roles[method]; ok {
+			return role
+		}
+	}
+	switch method {
+	case http.MethodOptions:
+		return API_ROLE_NONE
+	case http.MethodGet, http.MethodHead:
+		return API_ROLE_READONLY
+	default:
+		return API_ROLE_ADMIN
+	}
+}
+
+// The key of the request context value holding the user id of the process at the other end of a unix socket.
+type peerUIDKey struct{}
+
+// The authentication of the callers of the API, set up from the APIAuth section of the configuration.
+type apiAuth struct {
+	adminToken    string
+	readOnlyToken string
+	adminUIDs     map[uint32]bool
+	adminCNs      map[string]bool
+}
+
+func newAPIAuth(cfg config.APIAuthConfig) (*apiAuth, error) {
+	if cfg.ClientCAFile != "" && cfg.TLSCertFile == "" {
+		return nil, fmt.Errorf("APIAuth.ClientCAFile requires APIAuth.TLSCertFile")
+	} else if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("APIAuth.TLSCertFile and APIAuth.TLSKeyFile must be set together")
+	}
+
+	auth := &apiAuth{
+		adminUIDs: map[uint32]bool{0: true},
+		adminCNs:  make(map[string]bool),
+	}
+	for _, uid := range cfg.AdminUIDs {
+		auth.adminUIDs[uint32(uid)] = true
+	}
+	for _, cn := range cfg.AdminCNs {
+		auth.adminCNs[cn] = true
+	}
+
+	// The admin token is only readable by root, any local user can read the read-only token.
+	var err error
+	if auth.adminToken, err = loadAPIToken(cfg.AdminTokenFile, 0600); err != nil {
+		return nil, err
+	} else if auth.readOnlyToken, err = loadAPIToken(cfg.ReadOnlyTokenFile, 0644); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// Returns the token in the file, creating the file with a random token when it does not exist.
+func loadAPIToken(tokenFile string, perm os.FileMode) (string, error) {
+	if tokenFile == "" {
+		return "", nil
+	}
+
+	token, err := os.ReadFile(tokenFile)
+	if os.IsNotExist(err) {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("unable to generate the API token, error: %v", err)
+		}
+		token = []byte(hex.EncodeToString(b))
+		if err := os.MkdirAll(path.Dir(tokenFile), 0755); err != nil {
+			return "", fmt.Errorf("unable to create the directory of API token file %v, error: %v", tokenFile, err)
+		} else if err := os.WriteFile(tokenFile, token, perm); err != nil {
+			return "", fmt.Errorf("unable to write API token file %v, error: %v", tokenFile, err)
+		}
+		glog.V(3).Infof(apiLogString(fmt.Sprintf("created API token file %v", tokenFile)))
+	} else if err != nil {
+		return "", fmt.Errorf("unable to read API token file %v, error: %v", tokenFile, err)
+	}
+
+	t := strings.TrimSpace(string(token))
+	if t == "" {
+		return "", fmt.Errorf("API token file %v is empty", tokenFile)
+	}
+	return t, nil
+}
+
+// Returns the role of the caller and how it authenticated. A bearer token has to be valid when it is sent, otherwise
+// the caller is authenticated by the unix socket it called over or by its client certificate.
+func (auth *apiAuth) authenticate(r *http.Request) (APIRole, string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header {
+			return API_ROLE_NONE, "", fmt.Errorf("the Authorization header is not a bearer token")
+		} else if matchAPIToken(token, auth.adminToken) {
+			return API_ROLE_ADMIN, "admin token", nil
+		} else if matchAPIToken(token, auth.readOnlyToken) {
+			return API_ROLE_READONLY, "read-only token", nil
+		}
+		return API_ROLE_NONE, "", fmt.Errorf("the bearer token is not valid")
+	}
+
+	if uid, ok := r.Context().Value(peerUIDKey{}).(uint32); ok {
+		if auth.adminUIDs[uid] {
+			return API_ROLE_ADMIN, fmt.Sprintf("unix socket uid %v", uid), nil
+		}
+		return API_ROLE_READONLY, fmt.Sprintf("unix socket uid %v", uid), nil
+	}
+
+	if r.TLS != nil && len(r.TLS.VerifiedChains) != 0 && len(r.TLS.VerifiedChains[0]) != 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if auth.adminCNs[cn] {
+			return API_ROLE_ADMIN, fmt.Sprintf("client certificate %v", cn), nil
+		}
+		return API_ROLE_READONLY, fmt.Sprintf("client certificate %v", cn), nil
+	}
+
+	return API_ROLE_NONE, "", nil
+}
+
+func matchAPIToken(token string, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// The router middleware that rejects the requests whose caller does not have the role the route requires.
+func (auth *apiAuth) middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if t, err := route.GetPathTemplate(); err == nil {
+				template = t
+			}
+		}
+
+		required := RequiredAPIRole(template, r.Method)
+		if required == API_ROLE_NONE {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// get message printer with the language passed in from the header
+		lan := r.Header.Get("Accept-Language")
+		if lan == "" {
+			lan = i18n.DEFAULT_LANGUAGE
+		}
+		msgPrinter := i18n.GetMessagePrinterWithLocale(lan)
+
+		errorHandler := GetHTTPErrorHandler(w)
+		role, caller, err := auth.authenticate(r)
+		if err != nil || role == API_ROLE_NONE {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			if err != nil {
+				errorHandler(NewUnauthorizedError(msgPrinter.Sprintf("%v %v is not authorized, %v.", r.Method, r.URL.Path, err)))
+			} else {
+				errorHandler(NewUnauthorizedError(msgPrinter.Sprintf("%v %v requires authentication.", r.Method, r.URL.Path)))
+			}
+			return
+		} else if role < required {
+			errorHandler(NewForbiddenError(msgPrinter.Sprintf("%v %v requires the %v role, the caller authenticated with the %v has the %v role.", r.Method, r.URL.Path, required, caller, role)))
+			return
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("%v %v authorized for the %v with the %v role", r.Method, r.URL.Path, caller, role)))
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Returns the TLS configuration of the API server, which asks the callers for a client certificate when the client
+// CAs are configured.
+func getAPITLSConfig(cfg config.APIAuthConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCAFile != "" {
+		caBytes, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read APIAuth.ClientCAFile %v, error: %v", cfg.ClientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("APIAuth.ClientCAFile %v does not hold any PEM encoded certificates", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// Returns a listener on the unix socket that any local user can connect to. The callers are authenticated by their
+// user id.
+func listenAPIUnixSocket(socketPath string) (net.Listener, error) {
+	if err := os.MkdirAll(path.Dir(socketPath), 0755); err != nil {
+		return nil, fmt.Errorf("unable to create the directory of the API socket %v, error: %v", socketPath, err)
+	} else if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to remove the old API socket %v, error: %v", socketPath, err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	} else if err := os.Chmod(socketPath, 0666); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("unable to set the mode of the API socket %v, error: %v", socketPath, err)
+	}
+	return listener, nil
+}
+
+// Adds the user id of the process at the other end of a unix socket connection to the context of its requests.
+func apiConnContext(ctx context.Context, c net.Conn) context.Context {
+	if uc, ok := c.(*net.UnixConn); ok {
+		if uid, err := getPeerUID(uc); err != nil {
+			glog.Warningf(apiLogString(fmt.Sprintf("unable to get the user id of the caller on the API socket, error: %v", err)))
+		} else {
+			return context.WithValue(ctx, peerUIDKey{}, uid)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net"
+	"syscall"
+)
+
+// Returns the user id of the process at the other end of the unix socket connection.
+func getPeerUID(c *net.UnixConn) (uint32, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return 0, err
+	} else if credErr != nil {
+		return 0, credErr
+	}
+	return cred.Uid, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// The agent only runs on linux, the callers on the API socket can't be identified elsewhere.
+func getPeerUID(c *net.UnixConn) (uint32, error) {
+	return 0, fmt.Errorf("the user id of a unix socket peer is not supported on %v", runtime.GOOS)
+}
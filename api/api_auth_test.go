@@ -0,0 +1,204 @@
+//go:build unit
+// +build unit
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/open-horizon/anax/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RequiredAPIRole(t *testing.T) {
+	assert.Equal(t, API_ROLE_READONLY, RequiredAPIRole("/node", "GET"))
+	assert.Equal(t, API_ROLE_READONLY, RequiredAPIRole("/node/policy", "HEAD"))
+	assert.Equal(t, API_ROLE_ADMIN, RequiredAPIRole("/node", "DELETE"))
+	assert.Equal(t, API_ROLE_ADMIN, RequiredAPIRole("/node/policy", "PUT"))
+	assert.Equal(t, API_ROLE_ADMIN, RequiredAPIRole("/node/policy", "PATCH"))
+	assert.Equal(t, API_ROLE_NONE, RequiredAPIRole("/node", "OPTIONS"))
+
+	// The routes that differ from the default.
+	assert.Equal(t, API_ROLE_NONE, RequiredAPIRole("/health/live", "GET"))
+	assert.Equal(t, API_ROLE_NONE, RequiredAPIRole("/debug/eventtap", "GET"))
+	assert.Equal(t, API_ROLE_ADMIN, RequiredAPIRole("/node/userinput", "GET"))
+	assert.Equal(t, API_ROLE_ADMIN, RequiredAPIRole("/attribute/{id}", "GET"))
+}
+
+func Test_newAPIAuth_tokens(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.APIAuthConfig{
+		AdminTokenFile:    path.Join(dir, "horizon", "api.admin.token"),
+		ReadOnlyTokenFile: path.Join(dir, "horizon", "api.readonly.token"),
+	}
+	auth, err := newAPIAuth(cfg)
+	assert.Nil(t, err)
+
+	// The tokens are generated, the admin token can only be read by its owner.
+	if info, err := os.Stat(cfg.AdminTokenFile); assert.Nil(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+	if info, err := os.Stat(cfg.ReadOnlyTokenFile); assert.Nil(t, err) {
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	}
+	assert.NotEmpty(t, auth.adminToken)
+	assert.NotEqual(t, auth.adminToken, auth.readOnlyToken)
+
+	// Existing tokens are kept.
+	assert.Nil(t, os.WriteFile(cfg.AdminTokenFile, []byte("admintoken\n"), 0600))
+	auth, err = newAPIAuth(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "admintoken", auth.adminToken)
+
+	_, err = newAPIAuth(config.APIAuthConfig{ClientCAFile: path.Join(dir, "ca.crt")})
+	assert.NotNil(t, err)
+	_, err = newAPIAuth(config.APIAuthConfig{TLSCertFile: path.Join(dir, "server.crt")})
+	assert.NotNil(t, err)
+}
+
+func Test_apiAuth_middleware(t *testing.T) {
+	auth := &apiAuth{
+		adminToken:    "admintoken",
+		readOnlyToken: "readonlytoken",
+		adminUIDs:     map[uint32]bool{0: true},
+		adminCNs:      map[string]bool{"admin.example.com": true},
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router := mux.NewRouter()
+	router.HandleFunc("/node", ok).Methods("GET", "DELETE", "OPTIONS")
+	router.HandleFunc("/node/policy", ok).Methods("GET", "PUT")
+	router.HandleFunc("/health/live", ok).Methods("GET")
+	router.Use(auth.middleware)
+
+	call := func(method string, url string, authorization string, ctx context.Context, cn string) int {
+		req := httptest.NewRequest(method, url, nil)
+		if ctx != nil {
+			req = req.WithContext(ctx)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		if cn != "" {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// No credentials.
+	assert.Equal(t, http.StatusUnauthorized, call("GET", "/node", "", nil, ""))
+	assert.Equal(t, http.StatusOK, call("GET", "/health/live", "", nil, ""))
+	assert.Equal(t, http.StatusOK, call("OPTIONS", "/node", "", nil, ""))
+
+	// Tokens.
+	assert.Equal(t, http.StatusOK, call("GET", "/node", "Bearer readonlytoken", nil, ""))
+	assert.Equal(t, http.StatusForbidden, call("DELETE", "/node", "Bearer readonlytoken", nil, ""))
+	assert.Equal(t, http.StatusForbidden, call("PUT", "/node/policy", "Bearer readonlytoken", nil, ""))
+	assert.Equal(t, http.StatusOK, call("DELETE", "/node", "Bearer admintoken", nil, ""))
+	assert.Equal(t, http.StatusOK, call("PUT", "/node/policy", "Bearer admintoken", nil, ""))
+	assert.Equal(t, http.StatusUnauthorized, call("GET", "/node", "Bearer wrong", nil, ""))
+	assert.Equal(t, http.StatusUnauthorized, call("GET", "/node", "admintoken", nil, ""))
+
+	// Unix socket peers.
+	root := context.WithValue(context.Background(), peerUIDKey{}, uint32(0))
+	user := context.WithValue(context.Background(), peerUIDKey{}, uint32(1000))
+	assert.Equal(t, http.StatusOK, call("DELETE", "/node", "", root, ""))
+	assert.Equal(t, http.StatusOK, call("GET", "/node", "", user, ""))
+	assert.Equal(t, http.StatusForbidden, call("DELETE", "/node", "", user, ""))
+
+	// A token that is not valid is rejected even on the socket.
+	assert.Equal(t, http.StatusUnauthorized, call("DELETE", "/node", "Bearer wrong", root, ""))
+
+	// Client certificates.
+	assert.Equal(t, http.StatusOK, call("PUT", "/node/policy", "", nil, "admin.example.com"))
+	assert.Equal(t, http.StatusOK, call("GET", "/node/policy", "", nil, "monitor.example.com"))
+	assert.Equal(t, http.StatusForbidden, call("PUT", "/node/policy", "", nil, "monitor.example.com"))
+}
+
+// The callers on the unix socket are identified by their user id.
+func Test_apiAuth_unixSocket(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the user id of a unix socket peer is only supported on linux")
+	}
+
+	socketPath := path.Join(t.TempDir(), "run", "api.sock")
+	listener, err := listenAPIUnixSocket(socketPath)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if info, err := os.Stat(socketPath); assert.Nil(t, err) {
+		assert.Equal(t, os.FileMode(0666), info.Mode().Perm())
+	}
+
+	auth := &apiAuth{adminUIDs: map[uint32]bool{0: true}}
+	router := mux.NewRouter()
+	router.HandleFunc("/node", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }).Methods("GET", "DELETE")
+	router.Use(auth.middleware)
+
+	server := &http.Server{Handler: router, ConnContext: apiConnContext}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+
+	resp, err := client.Get("http://localhost/node")
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, "http://localhost/node", nil)
+	expected := http.StatusForbidden
+	if os.Getuid() == 0 {
+		expected = http.StatusOK
+	}
+	resp, err = client.Do(req)
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, expected, resp.StatusCode)
+	}
+}
+
+// With only the unix socket configured, the callers are authenticated by their user id on the socket, and the callers
+// on APIListen cannot authenticate.
+func Test_apiAuth_socketOnly(t *testing.T) {
+	cfg := config.APIAuthConfig{UnixSocket: path.Join(t.TempDir(), "api.sock"), AdminUIDs: []int{1000}}
+	assert.True(t, cfg.Enabled())
+
+	auth, err := newAPIAuth(cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/node", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }).Methods("GET", "DELETE")
+	router.Use(auth.middleware)
+
+	call := func(method string, ctx context.Context) int {
+		req := httptest.NewRequest(method, "/node", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, call("GET", context.Background()))
+	assert.Equal(t, http.StatusOK, call("DELETE", context.WithValue(context.Background(), peerUIDKey{}, uint32(1000))))
+	assert.Equal(t, http.StatusOK, call("GET", context.WithValue(context.Background(), peerUIDKey{}, uint32(1001))))
+	assert.Equal(t, http.StatusForbidden, call("DELETE", context.WithValue(context.Background(), peerUIDKey{}, uint32(1001))))
+}
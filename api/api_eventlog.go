@@ -3,9 +3,12 @@ package api
 import (
 	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // get the eventlogs for current registration.
@@ -36,9 +39,24 @@ func (a *API) eventlog(w http.ResponseWriter, r *http.Request) {
 
 		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v with selection %v. Language: %v", r.Method, resource, r.Form, lan)))
 
-		if out, err := FindEventLogsForOutput(a.db, all_loags, r.Form, msgPrinter); err != nil {
+		query, err := GetEventLogQuery(r.Form, time.Now(), msgPrinter)
+		if err != nil {
+			errorHandler(NewAPIUserInputError(err.Error(), "selection"))
+			return
+		}
+
+		if query.IsEmpty() {
+			if out, err := FindEventLogsForOutput(a.db, all_loags, r.Form, msgPrinter); err != nil {
+				errorHandler(NewSystemError(msgPrinter.Sprintf("Error getting %v for output, error %v", resource, err)))
+			} else {
+				writeResponse(w, out, http.StatusOK)
+			}
+		} else if out, next, err := FindEventLogsPageForOutput(a.db, all_loags, r.Form, *query, msgPrinter); err != nil {
 			errorHandler(NewSystemError(msgPrinter.Sprintf("Error getting %v for output, error %v", resource, err)))
 		} else {
+			if next != "" {
+				w.Header().Set(EVENTLOG_NEXT_CURSOR_HEADER, next)
+			}
 			writeResponse(w, out, http.StatusOK)
 		}
 	case "DELETE":
@@ -81,6 +99,59 @@ func (a *API) eventlog(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// stream the eventlogs as they are saved, as JSON lines.
+func (a *API) eventlogStream(w http.ResponseWriter, r *http.Request) {
+
+	resource := "eventlog/stream"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		// get message printer with the language passed in from the header
+		lan := r.Header.Get("Accept-Language")
+		if lan == "" {
+			lan = i18n.DEFAULT_LANGUAGE
+		}
+		msgPrinter := i18n.GetMessagePrinterWithLocale(lan)
+
+		if err := r.ParseForm(); err != nil {
+			errorHandler(NewAPIUserInputError(msgPrinter.Sprintf("Error parsing the selections %v. %v", r.Form, err), "selection"))
+			return
+		}
+
+		s, err := persistence.ConvertToSelectors(r.Form)
+		if err != nil {
+			errorHandler(NewAPIUserInputError(msgPrinter.Sprintf("Error converting the selections into Selectors: %v", err), "selection"))
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			errorHandler(NewSystemError(msgPrinter.Sprintf("Error getting %v for output, error %v", resource, "streaming is not supported")))
+			return
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v with selection %v. Language: %v", r.Method, resource, r.Form, lan)))
+
+		sub := eventlog.Subscribe(eventlog.DEFAULT_STREAM_BUFFER)
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		if err := StreamEventLogsForOutput(w, flusher.Flush, sub, s, msgPrinter, r.Context().Done()); err != nil {
+			glog.V(3).Infof(apiLogString(fmt.Sprintf("Stopped streaming %v, error %v", resource, err)))
+		}
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (a *API) surface(w http.ResponseWriter, r *http.Request) {
 	resource := "eventlog/surface"
 	errorHandler := GetHTTPErrorHandler(w)
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/worker"
+	"net/http"
+)
+
+// stream the events passed between the workers, as JSON lines. The event tap is for debugging, it is only served when
+// it is turned on in the configuration and the request has the event tap's token.
+func (a *API) eventTap(w http.ResponseWriter, r *http.Request) {
+
+	resource := "debug/eventtap"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		// get message printer with the language passed in from the header
+		lan := r.Header.Get("Accept-Language")
+		if lan == "" {
+			lan = i18n.DEFAULT_LANGUAGE
+		}
+		msgPrinter := i18n.GetMessagePrinterWithLocale(lan)
+
+		if !worker.EventTapEnabled() {
+			errorHandler(NewNotFoundError(msgPrinter.Sprintf("The event tap is not enabled. Set EventTap.TokenFile in the anax configuration file to enable it."), "eventtap"))
+			return
+		} else if !worker.ValidEventTapAuthorization(r.Header.Get("Authorization")) {
+			errorHandler(NewUnauthorizedError(msgPrinter.Sprintf("The request does not have the event tap token.")))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			errorHandler(NewAPIUserInputError(msgPrinter.Sprintf("Error parsing the selections %v. %v", r.Form, err), "selection"))
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			errorHandler(NewSystemError(msgPrinter.Sprintf("Error getting %v for output, error %v", resource, "streaming is not supported")))
+			return
+		}
+
+		glog.V(3).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v with selection %v. Language: %v", r.Method, resource, r.Form, lan)))
+
+		sub := worker.SubscribeEventTap(worker.DEFAULT_EVENT_TAP_BUFFER, worker.GetEventTapFilter(r.Form))
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		if err := worker.StreamTappedEvents(w, flusher.Flush, sub, r.Context().Done()); err != nil {
+			glog.V(3).Infof(apiLogString(fmt.Sprintf("Stopped streaming %v, error %v", resource, err)))
+		}
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
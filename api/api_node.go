@@ -247,6 +247,41 @@ func (a *API) nodeconfigstate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (a *API) nodemessagekey(w http.ResponseWriter, r *http.Request) {
+
+	resource := "node/messagekey"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if out, err := FindNodeMessageKeyForOutput(); err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+		} else {
+			writeResponse(w, out, http.StatusOK)
+		}
+
+	case "PUT":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		rotate := func() error {
+			return exchange.RotateNodeMessageKey(a, a.Config.Edge.GetMessageKeyGracePeriodS())
+		}
+
+		if errHandled, out := RotateNodeMessageKey(errorHandler, rotate, a.db); !errHandled {
+			writeResponse(w, out, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, PUT, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (a *API) nodepolicy(w http.ResponseWriter, r *http.Request) {
 
 	resource := "node/policy"
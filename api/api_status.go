@@ -1,10 +1,13 @@
 package api
 
 import (
+	"fmt"
+	"github.com/golang/glog"
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/worker"
 	"net/http"
+	"time"
 )
 
 func (a *API) status(w http.ResponseWriter, r *http.Request) {
@@ -46,3 +49,40 @@ func (a *API) workerstatus(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+// Lightweight health probes, they do not call the exchange.
+func (a *API) liveness(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		threshold := time.Duration(a.Config.Watchdog.GetLivenessThresholdS()) * time.Second
+		health := FindLivenessForOutput(worker.GetWorkerStatusManager(), threshold)
+		writeHealthResponse(w, health)
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *API) readiness(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		health := FindReadinessForOutput(a.db, worker.GetWorkerStatusManager())
+		writeHealthResponse(w, health)
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeHealthResponse(w http.ResponseWriter, health *HealthStatus) {
+	if health.IsOK() {
+		writeResponse(w, health, http.StatusOK)
+	} else {
+		glog.Warningf(apiLogString(fmt.Sprintf("health check failed: %v", health)))
+		writeResponse(w, health, http.StatusServiceUnavailable)
+	}
+}
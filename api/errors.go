@@ -134,6 +134,36 @@ func NewNotFoundError(err string, input string) *NotFoundError {
 	}
 }
 
+// Unauthorized errors are returned when a request does not carry the credentials the API requires.
+type UnauthorizedError struct {
+	msg string
+}
+
+func (e UnauthorizedError) Error() string {
+	return e.msg
+}
+
+func NewUnauthorizedError(err string) *UnauthorizedError {
+	return &UnauthorizedError{
+		msg: err,
+	}
+}
+
+// Forbidden errors are returned when the caller is authenticated but its role does not allow the request.
+type ForbiddenError struct {
+	msg string
+}
+
+func (e ForbiddenError) Error() string {
+	return e.msg
+}
+
+func NewForbiddenError(err string) *ForbiddenError {
+	return &ForbiddenError{
+		msg: err,
+	}
+}
+
 // System Errors are generally unexpected, infrastructural problems that just need to be reported out to the caller.
 type SystemError struct {
 	msg string
@@ -222,6 +252,16 @@ func GetHTTPErrorHandler(w http.ResponseWriter) ErrorHandler {
 				apiErr := NewAPIUserInputError(notErr.Err, notErr.Input)
 				writeInputErr(w, http.StatusNotFound, apiErr)
 
+			case *UnauthorizedError:
+				authErr := err.(*UnauthorizedError)
+				glog.Warningf(apiLogString(authErr.Error()))
+				http.Error(w, authErr.Error(), http.StatusUnauthorized)
+
+			case *ForbiddenError:
+				forbiddenErr := err.(*ForbiddenError)
+				glog.Warningf(apiLogString(forbiddenErr.Error()))
+				http.Error(w, forbiddenErr.Error(), http.StatusForbidden)
+
 			case *ServiceUnavailableError:
 				suErr := err.(*ServiceUnavailableError)
 				glog.Errorf(apiLogString(suErr.Error()))
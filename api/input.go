@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/exchangesync"
 	"github.com/open-horizon/anax/microservice"
 	"github.com/open-horizon/anax/persistence"
@@ -36,6 +37,9 @@ type HorizonDevice struct {
 	TokenValid         *bool        `json:"token_valid,omitempty"`
 	HAGroup            *string      `json:"ha_group,omitempty"`
 	Config             *Configstate `json:"configstate,omitempty"`
+	Offline            *bool        `json:"offline,omitempty"`                // The node can't reach the exchange, its writes are queued
+	OfflineSince       *uint64      `json:"offline_since,omitempty"`          // When the node went offline
+	QueuedWrites       *int         `json:"queued_exchange_writes,omitempty"` // Writes waiting to be sent to the exchange
 }
 
 func (h HorizonDevice) String() string {
@@ -95,7 +99,17 @@ func (h HorizonDevice) String() string {
 		tv = *h.TokenValid
 	}
 
-	return fmt.Sprintf("Id: %v, Org: %v, Pattern: %v, Name: %v, NodeType: %v, ClusterNamespace: %v, NamespaceScoped: %v, HAGroup: %v, Token: [%v], TokenLastValidTime: %v, TokenValid: %v, %v", id, org, pat, name, nodeType, clusterNs, isNS, ha_group, cred, tlvt, tv, h.Config)
+	offline := false
+	if h.Offline != nil {
+		offline = *h.Offline
+	}
+
+	queued := 0
+	if h.QueuedWrites != nil {
+		queued = *h.QueuedWrites
+	}
+
+	return fmt.Sprintf("Id: %v, Org: %v, Pattern: %v, Name: %v, NodeType: %v, ClusterNamespace: %v, NamespaceScoped: %v, HAGroup: %v, Token: [%v], TokenLastValidTime: %v, TokenValid: %v, Offline: %v, QueuedWrites: %v, %v", id, org, pat, name, nodeType, clusterNs, isNS, ha_group, cred, tlvt, tv, offline, queued, h.Config)
 }
 
 // This is a type conversion function but note that the token field within the persistent
@@ -124,6 +138,16 @@ func ConvertFromPersistentHorizonDevice(pDevice *persistence.ExchangeDevice) *Ho
 	}
 	hDevice.HAGroup = &haGroup
 
+	if since := exchange.GetOfflineSince(); since != 0 {
+		offline := true
+		offlineSince := uint64(since)
+		hDevice.Offline = &offline
+		hDevice.OfflineSince = &offlineSince
+	}
+	if queued := exchange.GetQueuedExchangeWriteCount(); queued != 0 {
+		hDevice.QueuedWrites = &queued
+	}
+
 	if pDevice.NodeType == persistence.DEVICE_TYPE_CLUSTER {
 		ns := cutil.GetClusterNamespace()
 		hDevice.ClusterNamespace = &ns
@@ -54,6 +54,11 @@ const (
 	EL_API_ERR_GET_SREFS_FOR_PATTERN  = "Error getting service references for pattern %v. %v"
 	EL_API_IGNORE_TYPE_MISMATCH       = "Ignoring service. %v"
 
+	// from path_node_messagekey.go
+	EL_API_NODE_MSG_KEY_ROTATED     = "Node messaging key rotated, the new key fingerprint is %v."
+	EL_API_ERR_NODE_MSG_KEY_ROTATE  = "Error rotating the node messaging key. %v"
+	EL_API_ERR_NODE_MSG_KEY_NOT_REG = "Error rotating the node messaging key. The node must be in 'configured' state."
+
 	// from path_node_policy.go
 	EL_API_NEW_NODE_POL     = "New node policy: %v"
 	EL_API_NODE_POL_DELETED = "Deleted node policy"
@@ -136,6 +141,11 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_API_ERR_GET_SREFS_FOR_PATTERN)
 	msgPrinter.Sprintf(EL_API_IGNORE_TYPE_MISMATCH)
 
+	// from path_node_messagekey.go
+	msgPrinter.Sprintf(EL_API_NODE_MSG_KEY_ROTATED)
+	msgPrinter.Sprintf(EL_API_ERR_NODE_MSG_KEY_ROTATE)
+	msgPrinter.Sprintf(EL_API_ERR_NODE_MSG_KEY_NOT_REG)
+
 	// from path_node_policy.go
 	msgPrinter.Sprintf(EL_API_NEW_NODE_POL)
 	msgPrinter.Sprintf(EL_API_NODE_POL_DELETED)
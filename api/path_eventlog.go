@@ -6,30 +6,66 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/stream"
 	"golang.org/x/text/message"
+	"io"
 	"sort"
+	"time"
 )
 
+// The response header that holds the cursor of the next page of event logs.
+const EVENTLOG_NEXT_CURSOR_HEADER = "X-Eventlog-Next-Cursor"
+
+// Take the time window, limit, cursor and order out of the selections, so that the rest are selectors.
+func GetEventLogQuery(selections map[string][]string, now time.Time, msgPrinter *message.Printer) (*persistence.EventLogQuery, error) {
+	return persistence.GetEventLogQuery(selections, now, func(cursor string) error {
+		_, err := persistence.ParseEventLogCursor(cursor)
+		return err
+	}, msgPrinter)
+}
+
 // This API returns the event logs saved on the db.
 func FindEventLogsForOutput(db *bolt.DB, all_logs bool, selections map[string][]string, msgPrinter *message.Printer) ([]persistence.EventLog, error) {
+	if event_logs, _, err := FindEventLogsPageForOutput(db, all_logs, selections, persistence.EventLogQuery{}, msgPrinter); err != nil {
+		return nil, err
+	} else {
+		sort.Sort(EventLogByRecordId(event_logs))
+		return event_logs, nil
+	}
+}
+
+// This API returns a page of the event logs saved on the db in the time window of the query, in time order, and the
+// cursor of the next page.
+func FindEventLogsPageForOutput(db *bolt.DB, all_logs bool, selections map[string][]string, query persistence.EventLogQuery, msgPrinter *message.Printer) ([]persistence.EventLog, string, error) {
 
-	glog.V(5).Infof(apiLogString(fmt.Sprintf("Getting event logs from the db. The selectors are: %v.", selections)))
+	glog.V(5).Infof(apiLogString(fmt.Sprintf("Getting event logs from the db. The selectors are: %v. The query is: %v.", selections, query)))
 
 	//convert to selectors
 	s, err := persistence.ConvertToSelectors(selections)
 	if err != nil {
-		return nil, fmt.Errorf("%s", msgPrinter.Sprintf("Error converting the selections into Selectors: %v", err))
+		return nil, "", fmt.Errorf("%s", msgPrinter.Sprintf("Error converting the selections into Selectors: %v", err))
 	} else {
 		glog.V(5).Infof(apiLogString(fmt.Sprintf("Converted selections into a map of persistence.Selector arrays: %v.", s)))
 	}
 
 	// get the event logs
-	if event_logs, err := eventlog.GetEventLogs(db, all_logs, s, msgPrinter); err != nil {
-		return nil, err
-	} else {
-		sort.Sort(EventLogByRecordId(event_logs))
-		return event_logs, nil
-	}
+	return eventlog.GetEventLogsPage(db, all_logs, s, query, msgPrinter)
+}
+
+// This API writes the event logs that match the selectors to the output as they are saved, one JSON object per line,
+// until done is closed or the output fails. The flush function pushes what has been written to the client.
+func StreamEventLogsForOutput(out io.Writer, flush func(), sub *eventlog.EventLogSubscription, s map[string][]persistence.Selector, msgPrinter *message.Printer, done <-chan struct{}) error {
+
+	glog.V(5).Infof(apiLogString(fmt.Sprintf("Streaming event logs. The selectors are: %v.", s)))
+
+	return stream.Write(out, flush, sub, func(el persistence.EventLog) (interface{}, bool) {
+		// Translate the message before matching, the same as the event logs that are read from the db.
+		if el.MessageMeta != nil && el.MessageMeta.MessageKey != "" {
+			el.Message = msgPrinter.Sprintf(el.MessageMeta.MessageKey, el.MessageMeta.MessageArgs...)
+			el.MessageMeta = nil
+		}
+		return el, el.Matches(s)
+	}, done)
 }
 
 // This API deletes the selected event logs saved on the db.
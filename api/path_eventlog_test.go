@@ -4,13 +4,17 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/stretchr/testify/assert"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -116,3 +120,71 @@ func Test_FindEventLogsForOutput(t *testing.T) {
 	}
 
 }
+
+func Test_StreamEventLogsForOutput(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	msgPrinter := i18n.GetMessagePrinterWithLocale("en")
+
+	sub := eventlog.Subscribe(10)
+	defer sub.Close()
+
+	selectors, _ := persistence.ConvertToSelectors(map[string][]string{"agreement_id": {"agreementId1"}})
+
+	var out bytes.Buffer
+	done := make(chan struct{})
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- StreamEventLogsForOutput(&out, func() {}, sub, selectors, msgPrinter, done)
+	}()
+
+	// Only the logs of the selected agreement are streamed.
+	eventlog.LogAgreementEvent2(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("proposal received for %v.", "agreementId1"), persistence.EC_RECEIVED_PROPOSAL, "agreementId1", persistence.WorkloadInfo{"http://top1.com", "myorg", "1.0.0", "amd64"}, []persistence.ServiceSpec{}, "consumerId", "Basic")
+	eventlog.LogAgreementEvent2(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("proposal received for %v.", "agreementId2"), persistence.EC_RECEIVED_PROPOSAL, "agreementId2", persistence.WorkloadInfo{"http://top2.com", "myorg", "1.0.0", "amd64"}, []persistence.ServiceSpec{}, "consumerId", "Basic")
+
+	// Closing the subscription ends the stream once the logs that were sent are written.
+	sub.Close()
+	assert.Nil(t, <-streamErr, "The stream should end without an error.")
+	close(done)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 1, len(lines), "Only one event log should be streamed.")
+
+	var el persistence.EventLogRaw
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &el), "The streamed event log should be JSON.")
+	assert.Equal(t, "proposal received for agreementId1.", el.Message, "The message should be translated.")
+	assert.Nil(t, el.MessageMeta, "The message meta should not be streamed.")
+}
+
+func Test_GetEventLogQuery(t *testing.T) {
+
+	msgPrinter := i18n.GetMessagePrinterWithLocale("en")
+	now := time.Unix(1700000000, 0)
+
+	selections := map[string][]string{"since": {"1h"}, "until": {"2023-11-14T22:13:20Z"}, "limit": {"10"}, "order": {"DESC"}, "severity": {"error"}}
+	query, err := GetEventLogQuery(selections, now, msgPrinter)
+	assert.Nil(t, err, "The query should be valid.")
+	assert.Equal(t, uint64(1700000000-3600), query.Since, "The since time should be an hour ago.")
+	assert.Equal(t, uint64(1700000000), query.Until, "The until time should be parsed.")
+	assert.Equal(t, 10, query.Limit)
+	assert.Equal(t, persistence.EVENTLOG_ORDER_DESC, query.Order)
+	assert.Equal(t, map[string][]string{"severity": {"error"}}, selections, "Only the selectors should be left.")
+
+	query, err = GetEventLogQuery(map[string][]string{"since": {"1699990000"}}, now, msgPrinter)
+	assert.Nil(t, err, "The query should be valid.")
+	assert.Equal(t, uint64(1699990000), query.Since, "The since time should be seconds since the epoch.")
+
+	_, err = GetEventLogQuery(map[string][]string{"since": {"yesterday"}}, now, msgPrinter)
+	assert.NotNil(t, err, "The since time is not valid.")
+	_, err = GetEventLogQuery(map[string][]string{"limit": {"-1"}}, now, msgPrinter)
+	assert.NotNil(t, err, "The limit is not valid.")
+	_, err = GetEventLogQuery(map[string][]string{"order": {"sideways"}}, now, msgPrinter)
+	assert.NotNil(t, err, "The order is not valid.")
+	_, err = GetEventLogQuery(map[string][]string{"cursor": {"abc"}}, now, msgPrinter)
+	assert.NotNil(t, err, "The cursor is not valid.")
+}
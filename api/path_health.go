@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+)
+
+// The overall status reported by the /health APIs.
+const (
+	HEALTH_STATUS_OK   = "ok"
+	HEALTH_STATUS_FAIL = "fail"
+)
+
+// The output of the /health/live and /health/ready APIs. The HTTP status code is 200 when the status is ok and 503
+// when it is not, so that a probe does not need to parse the body.
+type HealthStatus struct {
+	Status string               `json:"status"`
+	Checks []worker.HealthCheck `json:"checks"`
+}
+
+func (h HealthStatus) String() string {
+	return fmt.Sprintf("Status: %v, Checks: %v", h.Status, h.Checks)
+}
+
+func (h *HealthStatus) IsOK() bool {
+	return h.Status == HEALTH_STATUS_OK
+}
+
+func NewHealthStatus(checks []worker.HealthCheck) *HealthStatus {
+	status := HEALTH_STATUS_OK
+	for _, check := range checks {
+		if !check.Ready {
+			status = HEALTH_STATUS_FAIL
+		}
+	}
+	return &HealthStatus{
+		Status: status,
+		Checks: checks,
+	}
+}
+
+// The agent is alive as long as the API is responding and no worker has been handling the same command for longer
+// than the input threshold. Nothing else is checked, a restart will not fix an unreachable exchange or container
+// runtime, so those only make the agent unready.
+func FindLivenessForOutput(wsm *worker.WorkerStatusManager, threshold time.Duration) *HealthStatus {
+	wedged := wsm.WedgedWorkers(threshold)
+	return NewHealthStatus([]worker.HealthCheck{{Name: "workers", Ready: len(wedged) == 0, Detail: strings.Join(wedged, ", ")}})
+}
+
+// The agent is ready when its database can be read, it is not in the middle of a configstate change, and its workers
+// and everything they depend on are working. An unconfigured agent is ready, it is waiting to be registered.
+func FindReadinessForOutput(db *bolt.DB, wsm *worker.WorkerStatusManager) *HealthStatus {
+	checks := make([]worker.HealthCheck, 0)
+
+	dbCheck := worker.HealthCheck{Name: "database", Ready: true}
+	stateCheck := worker.HealthCheck{Name: "configstate", Ready: true, Detail: persistence.CONFIGSTATE_UNCONFIGURED}
+	if dev, err := persistence.FindExchangeDevice(db); err != nil {
+		dbCheck.Ready = false
+		dbCheck.Detail = err.Error()
+		stateCheck.Ready = false
+		stateCheck.Detail = "unknown"
+	} else if dev != nil {
+		stateCheck.Detail = dev.Config.State
+		stateCheck.Ready = dev.Config.State == persistence.CONFIGSTATE_CONFIGURED || dev.Config.State == persistence.CONFIGSTATE_UNCONFIGURED
+	}
+	checks = append(checks, dbCheck, stateCheck)
+
+	checks = append(checks, wsm.ReadinessChecks()...)
+	return NewHealthStatus(checks)
+}
@@ -0,0 +1,97 @@
+//go:build unit
+// +build unit
+
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+)
+
+func Test_FindReadinessForOutput(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	wsm := worker.NewWorkerStatusManager()
+	wsm.SetWorkerStatus("Container", worker.STATUS_INITIALIZED)
+	runtimeUp := true
+	wsm.SetReadinessCheck("Container", "docker", func() error {
+		if !runtimeUp {
+			return errors.New("runtime down")
+		}
+		return nil
+	})
+
+	// An unconfigured agent is ready.
+	if health := FindReadinessForOutput(db, wsm); !health.IsOK() {
+		t.Errorf("unconfigured agent should be ready, was %v", health)
+	} else if len(health.Checks) != 4 {
+		t.Errorf("expecting 4 checks, have %v", health.Checks)
+	} else if health.Checks[1].Detail != persistence.CONFIGSTATE_UNCONFIGURED {
+		t.Errorf("configstate should be unconfigured, was %v", health.Checks[1])
+	}
+
+	// An agent that is being configured is not ready.
+	dev, err := persistence.SaveNewExchangeDevice(db, "id", "token", "name", persistence.DEVICE_TYPE_DEVICE, "org", "pattern", persistence.CONFIGSTATE_CONFIGURING, persistence.SoftwareVersion{})
+	if err != nil {
+		t.Fatalf("error saving device: %v", err)
+	} else if health := FindReadinessForOutput(db, wsm); health.IsOK() || health.Checks[1].Ready {
+		t.Errorf("configuring agent should not be ready, was %v", health)
+	}
+
+	if _, err := dev.SetConfigstate(db, "id", persistence.CONFIGSTATE_CONFIGURED); err != nil {
+		t.Fatalf("error setting configstate: %v", err)
+	} else if health := FindReadinessForOutput(db, wsm); !health.IsOK() {
+		t.Errorf("configured agent should be ready, was %v", health)
+	}
+
+	// A failing dependency makes the agent unready.
+	runtimeUp = false
+	if health := FindReadinessForOutput(db, wsm); health.IsOK() {
+		t.Errorf("agent should not be ready, was %v", health)
+	} else if health.Checks[3].Name != "docker" || health.Checks[3].Detail != "runtime down" {
+		t.Errorf("unexpected docker check %v", health.Checks[3])
+	}
+
+	// Unless the worker that registered it has terminated.
+	wsm.SetWorkerStatus("Container", worker.STATUS_TERMINATED)
+	if health := FindReadinessForOutput(db, wsm); !health.IsOK() || len(health.Checks) != 3 {
+		t.Errorf("agent should be ready, was %v", health)
+	}
+
+	// A worker that failed to initialize makes the agent unready.
+	wsm.SetWorkerStatus("Governance", worker.STATUS_INIT_FAILED)
+	if health := FindReadinessForOutput(db, wsm); health.IsOK() || health.Checks[2].Ready {
+		t.Errorf("agent should not be ready, was %v", health)
+	}
+}
+
+func Test_FindLivenessForOutput(t *testing.T) {
+
+	wsm := worker.NewWorkerStatusManager()
+	wsm.SetWorkerQueue("Governance", make(chan worker.Command, 10))
+
+	if health := FindLivenessForOutput(wsm, time.Minute); !health.IsOK() {
+		t.Errorf("agent should be alive, was %v", health)
+	}
+
+	wsm.CommandStarted("Governance", "*governance.CleanupExecutionCommand")
+	if health := FindLivenessForOutput(wsm, time.Minute); !health.IsOK() {
+		t.Errorf("agent should be alive, was %v", health)
+	} else if health := FindLivenessForOutput(wsm, 0); health.IsOK() {
+		t.Errorf("agent with a wedged worker should not be alive, was %v", health)
+	}
+
+	wsm.CommandEnded("Governance")
+	if health := FindLivenessForOutput(wsm, 0); !health.IsOK() {
+		t.Errorf("agent should be alive, was %v", health)
+	}
+}
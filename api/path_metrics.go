@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/metrics"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/worker"
+)
+
+// The states reported for agreements and service instances on the /metrics API.
+const (
+	METRIC_STATE_CREATED     = "created"
+	METRIC_STATE_ACCEPTED    = "accepted"
+	METRIC_STATE_FINALIZED   = "finalized"
+	METRIC_STATE_EXECUTING   = "executing"
+	METRIC_STATE_TERMINATING = "terminating"
+	METRIC_STATE_ARCHIVED    = "archived"
+	METRIC_STATE_STARTING    = "starting"
+	METRIC_STATE_RUNNING     = "running"
+	METRIC_STATE_FAILED      = "failed"
+	METRIC_STATE_CLEANUP     = "cleanup"
+)
+
+var agreementGauge = metrics.NewGaugeVec("anax_agreements",
+	"Number of agreements on this node, by state.", "state")
+
+var serviceInstanceGauge = metrics.NewGaugeVec("anax_service_instances",
+	"Number of service instances on this node that are not archived, by state.", "state")
+
+// Return the state of an agreement for the /metrics API.
+func agreementMetricState(ag *persistence.EstablishedAgreement) string {
+	if ag.Archived {
+		return METRIC_STATE_ARCHIVED
+	} else if ag.AgreementTerminatedTime != 0 {
+		return METRIC_STATE_TERMINATING
+	} else if ag.AgreementExecutionStartTime != 0 {
+		return METRIC_STATE_EXECUTING
+	} else if ag.AgreementFinalizedTime != 0 {
+		return METRIC_STATE_FINALIZED
+	} else if ag.AgreementAcceptedTime != 0 {
+		return METRIC_STATE_ACCEPTED
+	}
+	return METRIC_STATE_CREATED
+}
+
+// Return the state of a service instance for the /metrics API.
+func serviceInstanceMetricState(msi *persistence.MicroserviceInstance) string {
+	if msi.CleanupStartTime != 0 {
+		return METRIC_STATE_CLEANUP
+	} else if msi.ExecutionFailureCode != 0 {
+		return METRIC_STATE_FAILED
+	} else if msi.ExecutionStartTime != 0 {
+		return METRIC_STATE_RUNNING
+	}
+	return METRIC_STATE_STARTING
+}
+
+// Refresh the metrics that are derived from the local database and the worker status. This is called each time
+// the metrics are scraped.
+func UpdateAgentMetrics(db *bolt.DB) {
+	worker.RefreshWorkerMetrics()
+
+	if agreements, err := persistence.FindEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), []persistence.EAFilter{}); err != nil {
+		glog.Errorf(apiLogString(fmt.Sprintf("error finding agreements for metrics, error %v", err)))
+	} else {
+		agreementGauge.Reset()
+		for _, ag := range agreements {
+			agreementGauge.Inc(agreementMetricState(&ag))
+		}
+	}
+
+	if instances, err := persistence.FindMicroserviceInstances(db, []persistence.MIFilter{persistence.UnarchivedMIFilter()}); err != nil {
+		glog.Errorf(apiLogString(fmt.Sprintf("error finding service instances for metrics, error %v", err)))
+	} else {
+		serviceInstanceGauge.Reset()
+		for _, msi := range instances {
+			serviceInstanceGauge.Inc(serviceInstanceMetricState(&msi))
+		}
+	}
+}
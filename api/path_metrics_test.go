@@ -0,0 +1,61 @@
+//go:build unit
+// +build unit
+
+package api
+
+import (
+	"github.com/open-horizon/anax/persistence"
+	"testing"
+)
+
+func Test_UpdateAgentMetrics(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	// Agreement 1 is new, agreement 2 is archived and agreement 3 is accepted.
+	sp := persistence.ServiceSpec{Url: "http://sensor.org", Org: "myorg"}
+	sps := []persistence.ServiceSpec{sp}
+
+	wi, _ := persistence.NewWorkloadInfo("url", "org", "version", "")
+	if _, err := persistence.NewEstablishedAgreement(db, "name1", "agreementId1", "consumerId", "{}", "Basic", 1, sps, "signature", "address", "bcType", "bcName", "bcOrg", wi, 180); err != nil {
+		t.Errorf("error writing agreement1: %v", err)
+	} else if _, err := persistence.NewEstablishedAgreement(db, "name1", "agreementId2", "consumerId", "{}", "Basic", 1, sps, "signature", "address", "bcType", "bcName", "bcOrg", wi, 180); err != nil {
+		t.Errorf("error writing agreement2: %v", err)
+	} else if _, err := persistence.NewEstablishedAgreement(db, "name1", "agreementId3", "consumerId", "{}", "Basic", 1, sps, "signature", "address", "bcType", "bcName", "bcOrg", wi, 180); err != nil {
+		t.Errorf("error writing agreement3: %v", err)
+	} else if _, err := persistence.ArchiveEstablishedAgreement(db, "agreementId2", "Basic"); err != nil {
+		t.Errorf("error archiving agreement2: %v", err)
+	} else if _, err := persistence.AgreementStateAccepted(db, "agreementId3", "Basic"); err != nil {
+		t.Errorf("error accepting agreement3: %v", err)
+	}
+
+	// One running service instance and one that failed.
+	if msi, err := persistence.NewMicroserviceInstance(db, "http://sensor1.org", "myorg", "1.2.0", "1", []persistence.ServiceInstancePathElement{}, false); err != nil {
+		t.Errorf("error writing service instance: %v", err)
+	} else if _, err := persistence.UpdateMSInstanceExecutionState(db, msi.GetKey(), true, 0, ""); err != nil {
+		t.Errorf("error updating service instance: %v", err)
+	}
+	if msi, err := persistence.NewMicroserviceInstance(db, "http://sensor2.org", "myorg", "1.0.0", "2", []persistence.ServiceInstancePathElement{}, false); err != nil {
+		t.Errorf("error writing service instance: %v", err)
+	} else if _, err := persistence.UpdateMSInstanceExecutionState(db, msi.GetKey(), false, 1, "failed"); err != nil {
+		t.Errorf("error updating service instance: %v", err)
+	}
+
+	UpdateAgentMetrics(db)
+
+	if v := agreementGauge.Get(METRIC_STATE_CREATED); v != 1 {
+		t.Errorf("expecting 1 created agreement, have %v", v)
+	} else if v := agreementGauge.Get(METRIC_STATE_ARCHIVED); v != 1 {
+		t.Errorf("expecting 1 archived agreement, have %v", v)
+	} else if v := agreementGauge.Get(METRIC_STATE_ACCEPTED); v != 1 {
+		t.Errorf("expecting 1 accepted agreement, have %v", v)
+	} else if v := serviceInstanceGauge.Get(METRIC_STATE_RUNNING); v != 1 {
+		t.Errorf("expecting 1 running service instance, have %v", v)
+	} else if v := serviceInstanceGauge.Get(METRIC_STATE_FAILED); v != 1 {
+		t.Errorf("expecting 1 failed service instance, have %v", v)
+	}
+}
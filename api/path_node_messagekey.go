@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// Return information about the node's messaging key.
+func FindNodeMessageKeyForOutput() (*exchange.MessageKeyInfo, error) {
+	return exchange.GetMessageKeyInfo("")
+}
+
+// Replace the node's messaging key and publish the new public key in the exchange. The old key can still be used to
+// decrypt messages for the grace period. The node must be registered, otherwise there is nowhere to publish the key.
+func RotateNodeMessageKey(errorHandler ErrorHandler,
+	rotate func() error,
+	db *bolt.DB) (bool, *exchange.MessageKeyInfo) {
+
+	pDevice, err := persistence.FindExchangeDevice(db)
+	if err != nil {
+		return errorHandler(NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err))), nil
+	} else if pDevice == nil || pDevice.Config.State != persistence.CONFIGSTATE_CONFIGURED {
+		LogDeviceEvent(db, persistence.SEVERITY_ERROR,
+			persistence.NewMessageMeta(EL_API_ERR_NODE_MSG_KEY_NOT_REG),
+			persistence.EC_ERROR_NODE_MESSAGE_KEY_ROTATION, pDevice)
+		return errorHandler(NewConflictError("the node must be registered before its messaging key can be rotated")), nil
+	}
+
+	if err := rotate(); err != nil {
+		LogDeviceEvent(db, persistence.SEVERITY_ERROR,
+			persistence.NewMessageMeta(EL_API_ERR_NODE_MSG_KEY_ROTATE, err.Error()),
+			persistence.EC_ERROR_NODE_MESSAGE_KEY_ROTATION, pDevice)
+		return errorHandler(NewSystemError(fmt.Sprintf("Unable to rotate the node messaging key, error %v", err))), nil
+	}
+
+	info, err := FindNodeMessageKeyForOutput()
+	if err != nil {
+		return errorHandler(NewSystemError(fmt.Sprintf("Unable to get the node messaging key, error %v", err))), nil
+	}
+
+	LogDeviceEvent(db, persistence.SEVERITY_INFO,
+		persistence.NewMessageMeta(EL_API_NODE_MSG_KEY_ROTATED, info.Fingerprint),
+		persistence.EC_NODE_MESSAGE_KEY_ROTATED, pDevice)
+	return false, info
+}
@@ -12,16 +12,17 @@ import (
 )
 
 type Configuration struct {
-	ExchangeAPI     string `json:"exchange_api"`
-	ExchangeVersion string `json:"exchange_version,omitempty"`
-	MinExchVersion  string `json:"required_minimum_exchange_version"`
-	PrefExchVersion string `json:"preferred_exchange_version"`
-	MMSAPI          string `json:"mms_api"`
-	AgbotAPI        string `json:"agbot_api,omitempty"`
-	Arch            string `json:"architecture"`
-	HorizonVersion  string `json:"horizon_version"`
-	CertVersion     string `json:"cert_file_version,omitempty"`   // the certificate file version from the agent file
-	ConfigVersion   string `json:"config_file_version,omitempty"` // the config file version from the agent file
+	ExchangeAPI     string            `json:"exchange_api"`
+	ExchangeVersion string            `json:"exchange_version,omitempty"`
+	MinExchVersion  string            `json:"required_minimum_exchange_version"`
+	PrefExchVersion string            `json:"preferred_exchange_version"`
+	MMSAPI          string            `json:"mms_api"`
+	AgbotAPI        string            `json:"agbot_api,omitempty"`
+	ActiveEndpoints map[string]string `json:"active_endpoints,omitempty"` // The endpoint in use for each management hub service
+	Arch            string            `json:"architecture"`
+	HorizonVersion  string            `json:"horizon_version"`
+	CertVersion     string            `json:"cert_file_version,omitempty"`   // the certificate file version from the agent file
+	ConfigVersion   string            `json:"config_file_version,omitempty"` // the config file version from the agent file
 }
 
 // These fields are filled in by the API specific code, not the common code.
@@ -54,6 +55,7 @@ func NewInfo(httpClientFactory *config.HTTPClientFactory, exchangeUrl string, mm
 		Configuration: &Configuration{
 			ExchangeAPI:     exchangeUrl,
 			ExchangeVersion: exch_version,
+			ActiveEndpoints: httpClientFactory.ActiveEndpoints(),
 			MinExchVersion:  version.MINIMUM_EXCHANGE_VERSION,
 			PrefExchVersion: version.PREFERRED_EXCHANGE_VERSION,
 			MMSAPI:          mmsUrl,
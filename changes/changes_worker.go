@@ -14,6 +14,7 @@ import (
 	"github.com/open-horizon/anax/version"
 	"github.com/open-horizon/anax/worker"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -33,18 +34,18 @@ const (
 type ChangesWorker struct {
 	worker.BaseWorker      // embedded field
 	db                     *bolt.DB
-	pollInterval           int    // The current change polling interval. This interval will float between Min and Max intervals.
-	pollHBRestoredInterval int    // When the node heartbeat fails, this will be used to store the poll interval to return to once the heartbeat is restored
-	pollMinInterval        int    // The minimum time to wait between polls to the exchange.
-	pollMaxInterval        int    // The maximum time to wait between polls to the exchange.
-	pollAdjustment         int    // The amount to increase the polling time, each time it is increased.
-	pollInitTime           int64  // The time when the polling starts 10sec interval.
-	agreementReached       bool   // True when ths node has seen at least one agreement.
-	noMsgCount             int    // How many consecutive polls have returned no changes.
-	changeID               uint64 // The current change Id in the exchange.
-	lastHeartbeat          int64  // Last time a heartbeat was successful.
-	heartBeatFailed        bool   // Remember that the heartbeat has failed.
-	noworkDispatch         int64  // The last time the NoWorkHandler was dispatched.
+	pollInterval           int          // The current change polling interval. This interval will float between Min and Max intervals.
+	pollHBRestoredInterval int          // When the node heartbeat fails, this will be used to store the poll interval to return to once the heartbeat is restored
+	pollMinInterval        int          // The minimum time to wait between polls to the exchange.
+	pollMaxInterval        int          // The maximum time to wait between polls to the exchange.
+	pollAdjustment         int          // The amount to increase the polling time, each time it is increased.
+	pollInitTime           int64        // The time when the polling starts 10sec interval.
+	agreementReached       bool         // True when ths node has seen at least one agreement.
+	noMsgCount             int          // How many consecutive polls have returned no changes.
+	changeID               uint64       // The current change Id in the exchange.
+	lastHeartbeat          atomic.Int64 // Last time a heartbeat was successful. It is also read by the readiness check.
+	heartBeatFailed        atomic.Bool  // Remember that the heartbeat has failed. It is also read by the readiness check.
+	noworkDispatch         int64        // The last time the NoWorkHandler was dispatched.
 }
 
 func NewChangesWorker(name string, cfg *config.HorizonConfig, db *bolt.DB) *ChangesWorker {
@@ -67,8 +68,7 @@ func NewChangesWorker(name string, cfg *config.HorizonConfig, db *bolt.DB) *Chan
 		noMsgCount:             0,
 		agreementReached:       false,
 		changeID:               0,
-		heartBeatFailed:        false,
-		noworkDispatch:         time.Now().Unix(),
+		noworkDispatch:         worker.GetClock().Now().Unix(),
 	}
 
 	// Initialize the change state tracking from the local DB.
@@ -82,6 +82,9 @@ func NewChangesWorker(name string, cfg *config.HorizonConfig, db *bolt.DB) *Chan
 		glog.V(3).Info(chglog(fmt.Sprintf("restore exchange change state after restart: %v", chgState)))
 	}
 
+	// The node is not ready while the heartbeat is failing.
+	worker.SetReadinessCheck("exchange_heartbeat", worker.heartbeatReadiness)
+
 	glog.Info(chglog(fmt.Sprintf("Starting ExchangeChanges worker")))
 
 	// The initial poll interval is changed dynamically by the NoWorkHandler when it detects that it can increase
@@ -108,8 +111,9 @@ func (w *ChangesWorker) Initialize() bool {
 
 	// setting up the polling initial time
 	if w.GetExchangeToken() != "" {
-		w.pollInitTime = time.Now().Unix()
+		w.pollInitTime = worker.GetClock().Now().Unix()
 	}
+	changesPollInterval.Set(float64(w.pollInterval))
 
 	// If there are already agreements, then we can allow the polling interval to grow. If not, the first agreement
 	// that gets made will allow the poller interval to grow.
@@ -187,6 +191,20 @@ func (w *ChangesWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *ChangesWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.AgreementReachedMessage{},
+		&events.ProposalAcceptedMessage{},
+		&events.NodePolicyMessage{},
+		&events.NodeUserInputMessage{},
+		&events.GovernanceWorkloadCancelationMessage{},
+		&events.ExchangeChangesShutdownMessage{},
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 // Handle commands that are placed on the command queue.
 func (w *ChangesWorker) CommandHandler(command worker.Command) bool {
 
@@ -199,7 +217,7 @@ func (w *ChangesWorker) CommandHandler(command worker.Command) bool {
 		// Therefore, if there is a steady flow of commands coming into the command handler, the noworkhandler
 		// might never get control. Given that, we will sometimes check for changes in the exchange outside the
 		// noworkhandler if it hasnt been dispatched in a while.
-		if w.GetExchangeToken() != "" && (time.Since(time.Unix(w.noworkDispatch, 0)).Seconds() >= float64(w.pollInterval)) {
+		if w.GetExchangeToken() != "" && (worker.GetClock().Since(time.Unix(w.noworkDispatch, 0)).Seconds() >= float64(w.pollInterval)) {
 			glog.V(5).Infof(chglog(fmt.Sprintf("early dispatch checking for changes")))
 			w.findAndProcessChanges()
 		}
@@ -238,7 +256,7 @@ func (w *ChangesWorker) NoWorkHandler() {
 // Go get the latest changes and process them, notifying other workers that they might have work to do.
 func (w *ChangesWorker) findAndProcessChanges() {
 
-	w.noworkDispatch = time.Now().Unix()
+	w.noworkDispatch = worker.GetClock().Now().Unix()
 
 	// If there is no last known change id, then we havent initialized yet, so do nothing.
 	maxRecords := 1000
@@ -256,6 +274,14 @@ func (w *ChangesWorker) findAndProcessChanges() {
 
 	// Call the exchange to retrieve any changes since our last known change id.
 	changes, err := exchange.GetHTTPExchangeChangeHandler(w)(w.changeID, maxRecords, nil)
+	if err != nil {
+		changesPolls.Inc(POLL_FAILED)
+	} else {
+		changesPolls.Inc(POLL_SUCCEEDED)
+		if changes != nil {
+			changesReceived.Add(float64(len(changes.Changes)))
+		}
+	}
 
 	// Handle heartbeat state changes and errors. Returns true if there was an error to be handled.
 	if w.handleHeartbeatStateAndError(changes, err) {
@@ -359,6 +385,18 @@ func (w *ChangesWorker) postProcessChanges(changes *exchange.ExchangeChanges, in
 	}
 }
 
+// The readiness check for the heartbeat. It uses the same grace period as the heartbeat failure event, so a node is
+// not made unready by a single failed call to a busy exchange.
+func (w *ChangesWorker) heartbeatReadiness() error {
+	if !w.heartBeatFailed.Load() {
+		return nil
+	} else if last := w.lastHeartbeat.Load(); last == 0 {
+		return fmt.Errorf("no successful heartbeat since the agent started")
+	} else {
+		return fmt.Errorf("no successful heartbeat since %v", time.Unix(last, 0).Format(time.RFC3339))
+	}
+}
+
 // Process any error from the /changes API and update the heartbeat state appropriately. Return true if the
 // caller should not proceed to process the response.
 func (w *ChangesWorker) handleHeartbeatStateAndError(changes *exchange.ExchangeChanges, err error) bool {
@@ -379,8 +417,11 @@ func (w *ChangesWorker) handleHeartbeatStateAndError(changes *exchange.ExchangeC
 			// time limit for a heartbeat failure. The heartbeat could have failed because the exchange is under load and we are
 			// unable to connect to it, the node might still have network connectivity so there is a grace period before declaring
 			// that there is a heartbeat problem.
-			if !w.heartBeatFailed && time.Since(time.Unix(w.lastHeartbeat, 0)).Seconds() > float64(w.Config.Edge.ExchangeHeartbeat) {
-				w.heartBeatFailed = true
+			if !w.heartBeatFailed.Load() && worker.GetClock().Since(time.Unix(w.lastHeartbeat.Load(), 0)).Seconds() > float64(w.Config.Edge.ExchangeHeartbeat) {
+				w.heartBeatFailed.Store(true)
+
+				// Queue the node's writes to the exchange until it is reachable again.
+				exchange.SetOffline(true)
 
 				eventlog.LogNodeEvent(w.db, persistence.SEVERITY_ERROR,
 					persistence.NewMessageMeta(EL_AG_NODE_HB_FAILED, exchange.GetOrg(w.GetExchangeId()), exchange.GetId(w.GetExchangeId()), err.Error()),
@@ -392,17 +433,27 @@ func (w *ChangesWorker) handleHeartbeatStateAndError(changes *exchange.ExchangeC
 		return true
 	} else {
 		// Record the last good heartbeat
-		w.lastHeartbeat = time.Now().Unix()
+		w.lastHeartbeat.Store(worker.GetClock().Now().Unix())
 
 		if w.pollHBRestoredInterval != 0 {
 			w.updatePollingInterval(UPDATE_TYPE_HB_RESTORED)
 		}
 
+		// Send the writes that were queued while the node was offline before anything else is written to the exchange.
+		// If the replay fails, the node stays offline and the replay is tried again on the next heartbeat.
+		if exchange.GetQueuedExchangeWriteCount() != 0 {
+			if err := exchange.ReplayExchangeWrites(w.GetHTTPFactory(), w.GetExchangeId(), w.GetExchangeToken()); err != nil {
+				glog.Errorf(chglog(fmt.Sprintf("unable to replay queued exchange writes, error %v", err)))
+				return true
+			}
+		}
+		exchange.SetOffline(false)
+
 		// The node could be transitioning from disconnected to connected state.
-		if w.heartBeatFailed {
+		if w.heartBeatFailed.Load() {
 			// Let other workers know that the heartbeat is restored. The message is sent out only when the heartbeat state
 			// changes from failed to successful.
-			w.heartBeatFailed = false
+			w.heartBeatFailed.Store(false)
 
 			glog.V(3).Infof(chglog(fmt.Sprintf("node heartbeat restored")))
 			eventlog.LogNodeEvent(w.db, persistence.SEVERITY_INFO,
@@ -464,7 +515,7 @@ func (w *ChangesWorker) updatePollingInterval(updateType string) {
 	} else if updateType == UPDATE_TYPE_NO_CHANGES {
 		// This is the case where there are no new changes.
 		// Slowly increasing the time interval between polls to the exchange for changes.
-		if (!w.agreementReached && (w.pollInitTime == 0 || time.Since(time.Unix(w.pollInitTime, 0)).Seconds() < float64(w.Config.Edge.InitialPollingBuffer))) || (w.pollInterval >= w.pollMaxInterval) {
+		if (!w.agreementReached && (w.pollInitTime == 0 || worker.GetClock().Since(time.Unix(w.pollInitTime, 0)).Seconds() < float64(w.Config.Edge.InitialPollingBuffer))) || (w.pollInterval >= w.pollMaxInterval) {
 			return
 		}
 
@@ -512,6 +563,8 @@ func (w *ChangesWorker) updatePollingInterval(updateType string) {
 	} else {
 		glog.Warningf(chglog(fmt.Sprintf("The update type '%v' passed to the updatePollingInterval function is not supported.", updateType)))
 	}
+
+	changesPollInterval.Set(float64(w.pollInterval))
 }
 
 // This function gets called when the device registers and is assigned an id and token which can be used to authenticate
@@ -521,7 +574,7 @@ func (w *ChangesWorker) handleDeviceRegistration(cmd *DeviceRegisteredCommand) {
 	w.EC = worker.NewExchangeContext(fmt.Sprintf("%v/%v", msg.Org(), msg.DeviceId()), msg.Token(), w.Config.Edge.ExchangeURL, w.Config.GetCSSURL(), w.Config.Edge.AgbotURL, newLimitedRetryHTTPFactory(w.Config.Collaborators.HTTPClientFactory))
 
 	// set up initial polling time
-	w.pollInitTime = time.Now().Unix()
+	w.pollInitTime = worker.GetClock().Now().Unix()
 
 	// Retrieve the node's heartbeat configuration from the node itself, and update the worker.
 	w.getHeartbeatIntervals()
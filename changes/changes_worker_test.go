@@ -0,0 +1,54 @@
+//go:build unit
+// +build unit
+
+package changes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+// The poll interval only starts to grow once the initial polling buffer has passed, and then grows by the
+// adjustment each time the max/current interval polls in a row have no changes.
+func Test_updatePollingInterval_backoff(t *testing.T) {
+	clock := worker.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	worker.SetClock(clock)
+	defer worker.SetClock(nil)
+
+	cfg := &config.HorizonConfig{Edge: config.Config{InitialPollingBuffer: 120}}
+	w := &ChangesWorker{
+		BaseWorker:      worker.NewBaseWorker("ExchangeChanges", cfg, nil),
+		pollInterval:    10,
+		pollMinInterval: 10,
+		pollMaxInterval: 30,
+		pollAdjustment:  10,
+		pollInitTime:    clock.Now().Unix(),
+	}
+
+	for i := 0; i < 10; i++ {
+		w.updatePollingInterval(UPDATE_TYPE_NO_CHANGES)
+	}
+	assert.Equal(t, 10, w.pollInterval)
+
+	clock.Advance(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		w.updatePollingInterval(UPDATE_TYPE_NO_CHANGES)
+	}
+	assert.Equal(t, 20, w.pollInterval)
+	assert.Equal(t, 20, w.GetNoWorkInterval())
+
+	// Growing stops at the max.
+	for i := 0; i < 10; i++ {
+		w.updatePollingInterval(UPDATE_TYPE_NO_CHANGES)
+	}
+	assert.Equal(t, 30, w.pollInterval)
+
+	w.updatePollingInterval(UPDATE_TYPE_HB_FAILED)
+	assert.Equal(t, 10, w.pollInterval)
+	w.updatePollingInterval(UPDATE_TYPE_HB_RESTORED)
+	assert.Equal(t, 30, w.pollInterval)
+}
@@ -0,0 +1,20 @@
+package changes
+
+import (
+	"github.com/open-horizon/anax/metrics"
+)
+
+// Exchange change polling metrics, exposed by the agent's /metrics API.
+const (
+	POLL_SUCCEEDED = "success"
+	POLL_FAILED    = "failure"
+)
+
+var changesPolls = metrics.NewCounterVec("anax_changes_polls_total",
+	"Calls to the exchange /changes API by the agent, by result (success or failure). These calls are also the node heartbeat.", "result")
+
+var changesReceived = metrics.NewCounterVec("anax_changes_received_total",
+	"Exchange changes received by the agent.")
+
+var changesPollInterval = metrics.NewGaugeVec("anax_changes_poll_interval_seconds",
+	"The current interval between calls to the exchange /changes API.")
@@ -0,0 +1,93 @@
+package agreementbot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	agbot "github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/eventlog"
+	"github.com/open-horizon/anax/i18n"
+)
+
+type EventLog struct {
+	Id          string `json:"record_id"`
+	Timestamp   string `json:"timestamp"` // converted to "yyyy-mm-dd hh:mm:ss" format
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	EventCode   string `json:"event_code"`
+	AgreementId string `json:"agreement_id,omitempty"`
+	NodeId      string `json:"node_id,omitempty"`
+	Org         string `json:"org,omitempty"`
+	Policy      string `json:"policy,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// List the agbot's event logs. The selections and options are the same as hzn eventlog list.
+func EventLogList(detail bool, selections []string, options eventlog.ListOptions) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// set env to call agbot url
+	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
+	}
+
+	url_s := "eventlog"
+	params := []string{}
+	if len(selections) > 0 {
+		if s, err := eventlog.GetSelectionString(selections); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
+		} else {
+			params = append(params, s)
+		}
+	}
+	if q := options.QueryString(); q != "" {
+		params = append(params, q)
+	}
+	if len(params) > 0 {
+		url_s = fmt.Sprintf("%v?%v", url_s, strings.Join(params, "&"))
+	}
+
+	apiOutput := make([]agbot.AgbotEventLog, 0)
+	_, header, _ := cliutils.HorizonGetWithHeader(url_s, []int{200}, &apiOutput, false)
+
+	var output interface{}
+	if detail {
+		long_output := make([]EventLog, len(apiOutput))
+		for i, v := range apiOutput {
+			long_output[i] = EventLog{
+				Id:          v.Id,
+				Timestamp:   cliutils.ConvertTime(v.Timestamp),
+				Severity:    v.Severity,
+				Message:     v.Message,
+				EventCode:   v.EventCode,
+				AgreementId: v.AgreementId,
+				NodeId:      v.NodeId,
+				Org:         v.Org,
+				Policy:      v.Policy,
+				Reason:      v.Reason,
+			}
+		}
+		output = long_output
+	} else {
+		short_output := make([]string, len(apiOutput))
+		for i, v := range apiOutput {
+			t := time.Unix(int64(v.Timestamp), 0)
+			short_output[i] = fmt.Sprintf("%v:   %v", t.Format("2006-01-02 15:04:05"), v.Message)
+		}
+		output = short_output
+	}
+
+	jsonBytes, err := cliutils.DisplayAsJson(output)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn agbot eventlog list' output: %v", err))
+	}
+	fmt.Printf("%s\n", jsonBytes)
+
+	if next := header.Get("X-Eventlog-Next-Cursor"); next != "" {
+		fmt.Fprintln(os.Stderr, msgPrinter.Sprintf("There are more event logs. To list them, run the command again with --cursor %v", next))
+	}
+}
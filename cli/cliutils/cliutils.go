@@ -3,6 +3,7 @@ package cliutils
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -42,6 +43,11 @@ const (
 	JSON_INDENT         = "  "
 	MUST_REGISTER_FIRST = "this command can not be run before running 'hzn register'"
 
+	// The default locations of the credentials for the anax api, used when the agent requires authentication
+	DEFAULT_AGENT_API_ADMIN_TOKEN_FILE    = "/var/horizon/api.admin.token"
+	DEFAULT_AGENT_API_READONLY_TOKEN_FILE = "/var/horizon/api.readonly.token"
+	DEFAULT_AGENT_API_SOCKET              = "/var/run/horizon/api.sock"
+
 	// Exit Codes
 	CLI_INPUT_ERROR    = 1 // we actually don't have control over the usage exit code that kingpin returns, so use the same code for input errors we catch ourselves
 	JSON_PARSING_ERROR = 3
@@ -571,6 +577,103 @@ func GetHorizonUrlBase() string {
 	}
 }
 
+// isAgentAPI returns false when the horizon api url has been pointed at the agbot api, the agent's credentials are only
+// sent to the agent.
+func isAgentAPI() bool {
+	agbotUrl := strings.TrimSuffix(os.Getenv("HZN_AGBOT_API"), "/")
+	return agbotUrl == "" || agbotUrl != GetHorizonUrlBase()
+}
+
+// GetHorizonAPIToken returns the bearer token for the anax api. It is the value of env var HZN_AGENT_API_TOKEN, or
+// else the contents of the file named by env var HZN_AGENT_API_TOKEN_FILE, or else of the first of the default admin
+// and read-only token files that the user can read. Returns an empty string when there is no token.
+func GetHorizonAPIToken() string {
+	if token := os.Getenv("HZN_AGENT_API_TOKEN"); token != "" {
+		return token
+	}
+
+	tokenFiles := []string{DEFAULT_AGENT_API_ADMIN_TOKEN_FILE, DEFAULT_AGENT_API_READONLY_TOKEN_FILE}
+	if tokenFile := os.Getenv("HZN_AGENT_API_TOKEN_FILE"); tokenFile != "" {
+		tokenFiles = []string{tokenFile}
+	}
+	for _, tokenFile := range tokenFiles {
+		if token, err := os.ReadFile(tokenFile); err == nil && strings.TrimSpace(string(token)) != "" {
+			Verbose(i18n.GetMessagePrinter().Sprintf("Using the anax api token in %v", tokenFile))
+			return strings.TrimSpace(string(token))
+		}
+	}
+	return ""
+}
+
+// GetHorizonAPISocket returns the unix socket to call the anax api on. It is the value of env var
+// HZN_AGENT_API_SOCKET, or else the default socket if it exists and HORIZON_URL is not set. Returns an empty string
+// when the api is called over tcp.
+func GetHorizonAPISocket() string {
+	if socket := os.Getenv("HZN_AGENT_API_SOCKET"); socket != "" {
+		return socket
+	} else if os.Getenv("HORIZON_URL") == "" {
+		if info, err := os.Stat(DEFAULT_AGENT_API_SOCKET); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return DEFAULT_AGENT_API_SOCKET
+		}
+	}
+	return ""
+}
+
+// GetHorizonHTTPClient returns the http client for the anax api. The client calls the api on its unix socket when
+// there is one, and presents the client certificate in env vars HZN_AGENT_API_CLIENT_CERT and HZN_AGENT_API_CLIENT_KEY
+// when they are set. The server certificate of an https HORIZON_URL is verified with the CA certs in env var
+// HZN_AGENT_API_CA_CERT, when it is set.
+func GetHorizonHTTPClient(timeout int) *http.Client {
+	httpClient := GetHTTPClient(timeout)
+	if !isAgentAPI() {
+		return httpClient
+	}
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	transport := httpClient.Transport.(*http.Transport)
+	if socket := GetHorizonAPISocket(); socket != "" {
+		Verbose(msgPrinter.Sprintf("Calling the anax api on unix socket %v", socket))
+		dialer := &net.Dialer{Timeout: transport.TLSHandshakeTimeout}
+		transport.Dial = nil
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	}
+
+	if certFile := os.Getenv("HZN_AGENT_API_CLIENT_CERT"); certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, os.Getenv("HZN_AGENT_API_CLIENT_KEY"))
+		if err != nil {
+			Fatal(CLI_INPUT_ERROR, msgPrinter.Sprintf("Unable to load the client certificate %v for the anax api: %v", certFile, err))
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile := os.Getenv("HZN_AGENT_API_CA_CERT"); caFile != "" {
+		caBytes, err := os.ReadFile(caFile)
+		if err != nil {
+			Fatal(CLI_INPUT_ERROR, msgPrinter.Sprintf("Unable to read the CA certificate %v for the anax api: %v", caFile, err))
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			Fatal(CLI_INPUT_ERROR, msgPrinter.Sprintf("The CA certificate file %v for the anax api does not hold any PEM encoded certificates", caFile))
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	return httpClient
+}
+
+// addHorizonAuthorization adds the anax api token to the request, when there is one.
+func addHorizonAuthorization(req *http.Request) {
+	if !isAgentAPI() {
+		return
+	}
+	if token := GetHorizonAPIToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
 // GetHorizonContainerIndex returns expected horizon container index based on the HORIZON_URL port binding
 // e.g. if horizon container is running on 8081 port it's index would be 1 and expected container name is horizon1
 func GetHorizonContainerIndex() (int, error) {
@@ -665,12 +768,18 @@ func printHorizonRestError(apiMethod string, err error) {
 // Only if the actual code matches the 1st element in goodHttpCodes, will it parse the body into the specified structure.
 // If quiet is true, then the error will be returned, the function returns back to the caller instead of exiting out.
 func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, quiet bool) (httpCode int, retError error) {
+	httpCode, _, retError = HorizonGetWithHeader(urlSuffix, goodHttpCodes, structure, quiet)
+	return
+}
+
+// HorizonGetWithHeader is HorizonGet that also returns the response header, e.g. for the cursor of the next page.
+func HorizonGetWithHeader(urlSuffix string, goodHttpCodes []int, structure interface{}, quiet bool) (httpCode int, header http.Header, retError error) {
 	retError = nil
 
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	httpClient := GetHTTPClient(0)
+	httpClient := GetHorizonHTTPClient(0)
 
 	url := GetHorizonUrlBase() + "/" + urlSuffix
 	apiMsg := http.MethodGet + " " + url
@@ -682,6 +791,7 @@ func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, qu
 	}
 	req.Close = true
 	req.Header.Add("Accept", "application/json")
+	addHorizonAuthorization(req)
 
 	// add the language request to the http header
 	localeTag, err := i18n.GetLocale()
@@ -713,6 +823,7 @@ func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, qu
 		}
 	}
 	httpCode = resp.StatusCode
+	header = resp.Header
 	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
 	if !isGoodCode(httpCode, goodHttpCodes) {
 		if quiet {
@@ -752,6 +863,56 @@ func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, qu
 	return
 }
 
+// HorizonGetStream runs a GET on an anax api that streams its response and returns the response body for the caller
+// to read as the data arrives. The caller must close the body. The request has no timeout, it lasts as long as the
+// caller reads the body. If the http code is not the expected one, the code is returned without a body.
+func HorizonGetStream(urlSuffix string, goodHttpCode int) (httpCode int, body io.ReadCloser) {
+	return HorizonGetStreamWithAuth(urlSuffix, goodHttpCode, "")
+}
+
+// HorizonGetStreamWithAuth is HorizonGetStream for an api that requires authorization. The authorization is sent as
+// the value of the Authorization header when it is not empty.
+func HorizonGetStreamWithAuth(urlSuffix string, goodHttpCode int, authorization string) (httpCode int, body io.ReadCloser) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	httpClient := GetHorizonHTTPClient(0)
+	httpClient.Timeout = 0
+
+	url := GetHorizonUrlBase() + "/" + urlSuffix
+	apiMsg := http.MethodGet + " " + url
+	Verbose(apiMsg)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		Fatal(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
+	}
+	req.Header.Add("Accept", "application/x-ndjson")
+	if authorization != "" {
+		req.Header.Add("Authorization", authorization)
+	} else {
+		addHorizonAuthorization(req)
+	}
+
+	// add the language request to the http header
+	localeTag, err := i18n.GetLocale()
+	if err != nil {
+		localeTag = language.English
+	}
+	req.Header.Add("Accept-Language", localeTag.String())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		printHorizonRestError(apiMsg, err)
+	}
+	httpCode = resp.StatusCode
+	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
+	if httpCode != goodHttpCode {
+		resp.Body.Close()
+		return httpCode, nil
+	}
+	return httpCode, resp.Body
+}
+
 // HorizonDelete runs a DELETE on the anax api.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes []int, quiet bool) (httpCode int, retError error) {
@@ -765,7 +926,7 @@ func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes
 	if IsDryRun() {
 		return 204, nil
 	}
-	httpClient := GetHTTPClient(0)
+	httpClient := GetHorizonHTTPClient(0)
 	req, err := http.NewRequest(http.MethodDelete, url, nil)
 	if err != nil {
 		if quiet {
@@ -776,6 +937,7 @@ func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes
 		}
 	}
 	req.Close = true
+	addHorizonAuthorization(req)
 
 	resp, err := httpClient.Do(req)
 	if resp != nil && resp.Body != nil {
@@ -828,7 +990,7 @@ func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body i
 	if IsDryRun() {
 		return 201, "", nil
 	}
-	httpClient := GetHTTPClient(0)
+	httpClient := GetHorizonHTTPClient(0)
 
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
@@ -865,6 +1027,7 @@ func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body i
 	}
 	req.Close = true
 	req.Header.Add("Accept", "application/json")
+	addHorizonAuthorization(req)
 	if bodyIsBytes {
 		req.Header.Add("Content-Length", strconv.Itoa(len(jsonBytes)))
 	} else {
@@ -1,13 +1,18 @@
 package eventlog
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -24,7 +29,7 @@ type EventLog struct {
 
 // This function takes a list of selection strings. validate them and
 // convert them to the format that the the anax api can take.
-func getSelectionString(selections []string) (string, error) {
+func GetSelectionString(selections []string) (string, error) {
 	valid_sel := regexp.MustCompile(`^([^~=><]+)([~=><])(.*)$`)
 
 	sels := []string{}
@@ -69,7 +74,7 @@ func Delete(selections []string, force bool) {
 	url_s := "eventlog"
 
 	if len(selections) > 0 {
-		if s, err := getSelectionString(selections); err != nil {
+		if s, err := GetSelectionString(selections); err != nil {
 			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
 		} else {
 			url_s = fmt.Sprintf("%v?%v", url_s, s)
@@ -106,7 +111,41 @@ func Prune(force bool) {
 	}
 }
 
-func List(all bool, detail bool, selections []string, tailing bool) {
+// The time window, page and order of the event logs to list.
+type ListOptions struct {
+	Since  string
+	Until  string
+	Limit  int
+	Cursor string
+	Order  string
+}
+
+// Returns the query parameters of the options.
+func (o ListOptions) QueryString() string {
+	params := url.Values{}
+	if o.Since != "" {
+		params.Set("since", o.Since)
+	}
+	if o.Until != "" {
+		params.Set("until", o.Until)
+	}
+	if o.Limit > 0 {
+		params.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		params.Set("cursor", o.Cursor)
+	}
+	if o.Order != "" {
+		params.Set("order", o.Order)
+	}
+	return params.Encode()
+}
+
+func List(all bool, detail bool, selections []string, tailing bool, options ListOptions) {
+
+	if tailing && (options.Order == "desc" || options.Limit > 0 || options.Cursor != "") {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("The -f flag cannot be used with the --limit, --cursor or --order desc flags."))
+	}
 
 	// format the eventlog api string
 	url_s := "eventlog"
@@ -114,57 +153,59 @@ func List(all bool, detail bool, selections []string, tailing bool) {
 		url_s = fmt.Sprintf("%v/all", url_s)
 	}
 
+	sel_s := ""
 	if len(selections) > 0 {
-		if s, err := getSelectionString(selections); err != nil {
+		if s, err := GetSelectionString(selections); err != nil {
 			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
 		} else {
-			url_s = fmt.Sprintf("%v?%v", url_s, s)
+			sel_s = s
+		}
+	}
+	params := []string{}
+	for _, p := range []string{sel_s, options.QueryString()} {
+		if p != "" {
+			params = append(params, p)
+		}
+	}
+	if len(params) > 0 {
+		url_s = fmt.Sprintf("%v?%v", url_s, strings.Join(params, "&"))
+	}
+
+	// When tailing, open the stream of new event logs before listing the existing ones, so that none are missed
+	// in between. Older agents do not have the stream, they are polled instead.
+	var stream io.ReadCloser
+	if tailing {
+		stream_s := "eventlog/stream"
+		if sel_s != "" {
+			stream_s = fmt.Sprintf("%v?%v", stream_s, sel_s)
+		}
+		if httpCode, body := cliutils.HorizonGetStream(stream_s, http.StatusOK); body != nil {
+			stream = body
+			defer stream.Close()
+		} else {
+			cliutils.Verbose(i18n.GetMessagePrinter().Sprintf("The event log stream is not available, HTTP code %v. Polling the event log instead.", httpCode))
 		}
 	}
 
 	for {
 		// get the eventlog from anax
 		apiOutput := make([]persistence.EventLogRaw, 0)
-		cliutils.HorizonGet(url_s, []int{200}, &apiOutput, false)
+		_, header, _ := cliutils.HorizonGetWithHeader(url_s, []int{200}, &apiOutput, false)
 
 		//output
-
-		if detail {
-			long_output := make([]EventLog, len(apiOutput))
-			for i, v := range apiOutput {
-				long_output[i].Id = v.Id
-				long_output[i].Timestamp = cliutils.ConvertTime(v.Timestamp)
-				long_output[i].Severity = v.Severity
-				long_output[i].Message = v.Message
-				long_output[i].EventCode = v.EventCode
-				long_output[i].SourceType = v.SourceType
-				long_output[i].Source = v.Source
-			}
-
-			jsonBytes, err := cliutils.DisplayAsJson(long_output)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
-			}
-			if len(jsonBytes) > 3 {
-				fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
-			}
-		} else {
-			short_output := make([]string, len(apiOutput))
-			for i, v := range apiOutput {
-				t := time.Unix(int64(v.Timestamp), 0)
-				short_output[i] = fmt.Sprintf("%v:   %v", t.Format("2006-01-02 15:04:05"), v.Message)
-			}
-			jsonBytes, err := cliutils.DisplayAsJson(short_output)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
-			}
-
-			if len(jsonBytes) > 3 {
-				fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
-			}
+		printEventLogs(apiOutput, detail)
+		if next := header.Get("X-Eventlog-Next-Cursor"); next != "" {
+			fmt.Fprintln(os.Stderr, i18n.GetMessagePrinter().Sprintf("There are more event logs. To list them, run the command again with --cursor %v", next))
 		}
 
-		if tailing {
+		if tailing && stream != nil {
+			lastId := uint64(0)
+			if len(apiOutput) > 0 {
+				lastId, _ = strconv.ParseUint(apiOutput[len(apiOutput)-1].Id, 10, 64)
+			}
+			followStream(stream, lastId, detail)
+			break
+		} else if tailing {
 			// selection contraints for most recent records
 			var newselect []string
 
@@ -179,7 +220,7 @@ func List(all bool, detail bool, selections []string, tailing bool) {
 			// select for most recent records if any
 			if len(apiOutput) > 0 {
 				newselect = append(newselect, fmt.Sprintf("record_id>%v", apiOutput[len(apiOutput)-1].Id))
-				if s, err := getSelectionString(newselect); err != nil {
+				if s, err := GetSelectionString(newselect); err != nil {
 					cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
 				} else {
 					url_s = fmt.Sprintf("eventlog?%v", s)
@@ -193,6 +234,69 @@ func List(all bool, detail bool, selections []string, tailing bool) {
 	}
 }
 
+// Print the event logs from the stream as they arrive, skipping the ones that were already listed, until the agent
+// closes the stream.
+func followStream(stream io.Reader, lastId uint64, detail bool) {
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			// heartbeat
+			continue
+		}
+
+		var el persistence.EventLogRaw
+		if err := json.Unmarshal([]byte(line), &el); err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to unmarshal the event log stream: %v", err))
+		}
+		if id, err := strconv.ParseUint(el.Id, 10, 64); err == nil && id <= lastId {
+			continue
+		}
+		printEventLogs([]persistence.EventLogRaw{el}, detail)
+	}
+	if err := scanner.Err(); err != nil {
+		cliutils.Fatal(cliutils.HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("failed to read the event log stream: %v", err))
+	}
+}
+
+func printEventLogs(apiOutput []persistence.EventLogRaw, detail bool) {
+	if detail {
+		long_output := make([]EventLog, len(apiOutput))
+		for i, v := range apiOutput {
+			long_output[i].Id = v.Id
+			long_output[i].Timestamp = cliutils.ConvertTime(v.Timestamp)
+			long_output[i].Severity = v.Severity
+			long_output[i].Message = v.Message
+			long_output[i].EventCode = v.EventCode
+			long_output[i].SourceType = v.SourceType
+			long_output[i].Source = v.Source
+		}
+
+		jsonBytes, err := cliutils.DisplayAsJson(long_output)
+		if err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
+		}
+		if len(jsonBytes) > 3 {
+			fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
+		}
+	} else {
+		short_output := make([]string, len(apiOutput))
+		for i, v := range apiOutput {
+			t := time.Unix(int64(v.Timestamp), 0)
+			short_output[i] = fmt.Sprintf("%v:   %v", t.Format("2006-01-02 15:04:05"), v.Message)
+		}
+		jsonBytes, err := cliutils.DisplayAsJson(short_output)
+		if err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
+		}
+
+		if len(jsonBytes) > 3 {
+			fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
+		}
+	}
+}
+
 func ListSurfaced(long bool) {
 	apiOutput := make([]persistence.SurfaceError, 0)
 	cliutils.HorizonGet("eventlog/surface", []int{200}, &apiOutput, false)
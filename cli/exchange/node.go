@@ -1,6 +1,7 @@
 package exchange
 
 import (
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -16,8 +17,10 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/semanticversion"
+	"io"
 	"net/http"
 	"os"
+	"sort"
 )
 
 type ExchangeNodes struct {
@@ -57,7 +60,10 @@ func NodeList(org string, credToUse string, node string, namesOnly bool) {
 	if node == "*" {
 		node = ""
 	}
-	if namesOnly && node == "" {
+	if node == "" {
+		// An org can have a very large number of nodes, so they are read one at a time.
+		nodeListAll(org, credToUse, nodeOrg, namesOnly)
+	} else if namesOnly {
 		// Only display the names
 		var resp ExchangeNodes
 		cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes"+cliutils.AddSlash(node), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &resp)
@@ -82,6 +88,120 @@ func NodeList(org string, credToUse string, node string, namesOnly bool) {
 	}
 }
 
+// Display all the nodes in an org, or their names, sorted by id. The exchange's response is decoded a node at a time,
+// and the output is the same as for a response that is read all at once.
+func nodeListAll(org string, credToUse string, nodeOrg string, namesOnly bool) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	resp := cliutils.ExchangeGetResponse("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes", cliutils.OrgAndCreds(org, credToUse))
+	defer resp.Body.Close()
+	cliutils.Verbose(msgPrinter.Sprintf("HTTP code: %d", resp.StatusCode))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s, output: %s", resp.StatusCode, "GET orgs/"+nodeOrg+"/nodes", cliutils.GetRespBodyAsString(resp.Body)))
+	}
+
+	// The nodes are kept as they were received, rather than as exchange.Device, and are displayed sorted by id.
+	ids := []string{}
+	nodes := make(map[string]json.RawMessage)
+	err := decodeNodes(resp.Body, func(id string, raw json.RawMessage) error {
+		ids = append(ids, id)
+		if !namesOnly {
+			nodes[id] = raw
+		}
+		return nil
+	})
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to read 'exchange node list' response: %v", err))
+	}
+	sort.Strings(ids)
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	open, close := "{", "}"
+	if namesOnly {
+		open, close = "[", "]"
+	}
+	if len(ids) == 0 {
+		fmt.Fprintf(out, "%v%v\n", open, close)
+		return
+	}
+
+	fmt.Fprintf(out, "%v\n", open)
+	for ix, id := range ids {
+		if ix != 0 {
+			fmt.Fprint(out, ",\n")
+		}
+		name, _ := json.Marshal(id)
+		if namesOnly {
+			fmt.Fprintf(out, "%v%s", cliutils.JSON_INDENT, name)
+			continue
+		}
+
+		var node exchange.Device
+		if err := json.Unmarshal(nodes[id], &node); err != nil {
+			out.Flush()
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to read 'exchange node list' response: %v", err))
+		} else if nodeBytes, err := json.MarshalIndent(node, cliutils.JSON_INDENT, cliutils.JSON_INDENT); err != nil {
+			out.Flush()
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'exchange node list' output: %v", err))
+		} else {
+			fmt.Fprintf(out, "%v%s: %s", cliutils.JSON_INDENT, name, nodeBytes)
+		}
+		// Let the node's memory go once it is displayed.
+		delete(nodes, id)
+	}
+	fmt.Fprintf(out, "\n%v\n", close)
+}
+
+// Decode the nodes of an exchange response, {"nodes":{"org/id":{...}, ...}, ...}, one at a time.
+func decodeNodes(body io.Reader, handler func(id string, node json.RawMessage) error) error {
+	dec := json.NewDecoder(body)
+	if t, err := dec.Token(); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else if t != json.Delim('{') {
+		return fmt.Errorf("expected a JSON object, found %v", t)
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		} else if key != "nodes" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if t, err := dec.Token(); err != nil {
+			return err
+		} else if t == nil {
+			continue
+		} else if t != json.Delim('{') {
+			return fmt.Errorf("expected a map of nodes, found %v", t)
+		}
+		for dec.More() {
+			id, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			var node json.RawMessage
+			if err := dec.Decode(&node); err != nil {
+				return err
+			} else if err := handler(fmt.Sprint(id), node); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Create a node with the given information.
 // The function will check if the node exists. If it exists, then only the token will be updated.
 // If checkNode is false, it means that the node existance has been check somewhere else, the function will
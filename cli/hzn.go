@@ -155,6 +155,15 @@ Environment Variables:
 	agbotCacheServedOrg := agbotCacheCmd.Command("servedorg | sorg", msgPrinter.Sprintf("List served pattern orgs and deployment policy orgs.")).Alias("sorg").Alias("servedorg")
 	agbotCacheServedOrgList := agbotCacheServedOrg.Command("list | ls", msgPrinter.Sprintf("Display served pattern orgs and deployment policy orgs.")).Alias("ls").Alias("list")
 
+	agbotEventlogCmd := agbotCmd.Command("eventlog | ev", msgPrinter.Sprintf("List the event logs of the decisions this Horizon agreement bot made about edge nodes.")).Alias("ev").Alias("eventlog")
+	agbotEventlogListCmd := agbotEventlogCmd.Command("list | ls", msgPrinter.Sprintf("List the event logs of the decisions this Horizon agreement bot made about edge nodes, such as the proposals it sent and the agreements it cancelled.")).Alias("ls").Alias("list")
+	agbotEventlogListLong := agbotEventlogListCmd.Flag("long", msgPrinter.Sprintf("List event logs with details.")).Short('l').Bool()
+	agbotEventlogListSelect := agbotEventlogListCmd.Flag("select", msgPrinter.Sprintf("Selection string. This flag can be repeated which means 'AND'. Each flag should be in the format of attribute=value, attribute~value, \"attribute>value\" or \"attribute<value\", where '~' means contains. The attribute names are record_id, timestamp, time_since (unit is hours), severity, message, event_code, agreement_id, node_id, org, policy and reason.")).Short('s').Strings()
+	agbotEventlogListSince := agbotEventlogListCmd.Flag("since", msgPrinter.Sprintf("List the event logs logged at or after this time. The time can be seconds since the epoch, an RFC3339 time such as 2024-01-02T15:04:05Z, or a duration such as 1h or 30m meaning that long ago.")).String()
+	agbotEventlogListUntil := agbotEventlogListCmd.Flag("until", msgPrinter.Sprintf("List the event logs logged at or before this time, in the same formats as --since.")).String()
+	agbotEventlogListLimit := agbotEventlogListCmd.Flag("limit", msgPrinter.Sprintf("The most event logs listed. When there are more, the cursor to list the next ones is displayed.")).Int()
+	agbotEventlogListCursor := agbotEventlogListCmd.Flag("cursor", msgPrinter.Sprintf("List the event logs after this cursor, as displayed by a previous command with --limit.")).String()
+	agbotEventlogListOrder := agbotEventlogListCmd.Flag("order", msgPrinter.Sprintf("The order of the event logs by time, asc or desc.")).Enum("asc", "desc")
 	agbotListCmd := agbotCmd.Command("list | ls", msgPrinter.Sprintf("Display general information about this Horizon agbot node.")).Alias("ls").Alias("list")
 	agbotPolicyCmd := agbotCmd.Command("policy | pol", msgPrinter.Sprintf("List the policies this Horizon agreement bot hosts.")).Alias("pol").Alias("policy")
 	agbotPolicyListCmd := agbotPolicyCmd.Command("list | ls", msgPrinter.Sprintf("List policies this Horizon agreement bot hosts.")).Alias("ls").Alias("list")
@@ -287,6 +296,11 @@ Environment Variables:
 	listAllEventlogs := eventlogListCmd.Flag("all", msgPrinter.Sprintf("List all the event logs including the previous registrations.")).Short('a').Bool()
 	listDetailedEventlogs := eventlogListCmd.Flag("long", msgPrinter.Sprintf("List event logs with details.")).Short('l').Bool()
 	listSelectedEventlogs := eventlogListCmd.Flag("select", msgPrinter.Sprintf("Selection string. This flag can be repeated which means 'AND'. Each flag should be in the format of attribute=value, attribute~value, \"attribute>value\" or \"attribute<value\", where '~' means contains. The common attribute names are timestamp, time_since (unit is hours), severity, message, event_code, source_type, agreement_id, service_url etc. Use the '-l' flag to see all the attribute names.")).Short('s').Strings()
+	listEventlogsSince := eventlogListCmd.Flag("since", msgPrinter.Sprintf("List the event logs logged at or after this time. The time can be seconds since the epoch, an RFC3339 time such as 2024-01-02T15:04:05Z, or a duration such as 1h or 30m meaning that long ago.")).String()
+	listEventlogsUntil := eventlogListCmd.Flag("until", msgPrinter.Sprintf("List the event logs logged at or before this time, in the same formats as --since.")).String()
+	listEventlogsLimit := eventlogListCmd.Flag("limit", msgPrinter.Sprintf("The most event logs listed. When there are more, the cursor to list the next ones is displayed.")).Int()
+	listEventlogsCursor := eventlogListCmd.Flag("cursor", msgPrinter.Sprintf("List the event logs after this cursor, as displayed by a previous command with --limit.")).String()
+	listEventlogsOrder := eventlogListCmd.Flag("order", msgPrinter.Sprintf("The order of the event logs by time, asc or desc.")).Enum("asc", "desc")
 	eventlogDeleteCmd := eventlogCmd.Command("delete | del", msgPrinter.Sprintf("Delete all the event logs or those matching the provided selectors.")).Alias("del").Alias("delete")
 	deleteSelectedEventlogs := eventlogDeleteCmd.Flag("select", msgPrinter.Sprintf("Selection string. This flag can be repeated which means 'AND'. Each flag should be in the format of attribute=value, attribute~value, \"attribute>value\" or\"attribute<value\", where '~' means contains. The common attribute names are timestamp, time_since (unit is hours), severity, message, event_code, source_type, agreement_id, service_url etc. Use the '-l' flag to see all the attribute names.")).Short('s').Strings()
 	deleteEventLogsForce := eventlogDeleteCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
@@ -807,6 +821,12 @@ Environment Variables:
 	utilVerifyCmd := utilCmd.Command("verify | vf", msgPrinter.Sprintf("Verify that the signature specified via -s is a valid signature for the text in stdin.")).Alias("vf").Alias("verify")
 	utilVerifyPubKeyFile := utilVerifyCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of public key file (that corresponds to the private key that was used to sign) to verify the signature of stdin.")).Short('K').Required().ExistingFile()
 	utilVerifySig := utilVerifyCmd.Flag("signature", msgPrinter.Sprintf("The supposed signature of stdin.")).Short('s').Required().String()
+	utilEventTapCmd := utilCmd.Command("eventtap", msgPrinter.Sprintf("Display the events passed between the workers of the agent, or of the agbot, as they happen. The event tap must be enabled in the anax configuration file. Press Ctrl-C to stop."))
+	utilEventTapAgbot := utilEventTapCmd.Flag("agbot", msgPrinter.Sprintf("Display the events of the agbot instead of the agent.")).Bool()
+	utilEventTapEventIds := utilEventTapCmd.Flag("event-id", msgPrinter.Sprintf("Only display the events with this id, for example AGREEMENT_REACHED. This flag can be repeated.")).Short('e').Strings()
+	utilEventTapWorkers := utilEventTapCmd.Flag("worker", msgPrinter.Sprintf("Only display the events sent by or to this worker, for example Governance. This flag can be repeated.")).Short('w').Strings()
+	utilEventTapTokenFile := utilEventTapCmd.Flag("token-file", msgPrinter.Sprintf("The file that holds the event tap token, the EventTap.TokenFile setting of the anax configuration file. The default is %v.", utilcmds.DEFAULT_EVENTTAP_TOKEN_FILE)).Short('t').String()
+	utilEventTapLong := utilEventTapCmd.Flag("long", msgPrinter.Sprintf("Display each event as JSON.")).Short('l').Bool()
 
 	smCmd := app.Command("secretsmanager | sm", msgPrinter.Sprintf("List and manage secrets in the secrets manager. NOTE: You must authenticate as an administrator to list secrets available to the entire organization. Secrets are not supported on cluster agents.")).Alias("sm").Alias("secretsmanager")
 	smOrg := smCmd.Flag("org", msgPrinter.Sprintf("The Horizon organization ID. If not specified, HZN_ORG_ID will be used as a default.")).Short('o').String()
@@ -1344,7 +1364,7 @@ Environment Variables:
 	case statusCmd.FullCommand():
 		status.DisplayStatus(*statusLong, false)
 	case eventlogListCmd.FullCommand():
-		eventlog.List(*listAllEventlogs, *listDetailedEventlogs, *listSelectedEventlogs, *listTail)
+		eventlog.List(*listAllEventlogs, *listDetailedEventlogs, *listSelectedEventlogs, *listTail, eventlog.ListOptions{Since: *listEventlogsSince, Until: *listEventlogsUntil, Limit: *listEventlogsLimit, Cursor: *listEventlogsCursor, Order: *listEventlogsOrder})
 	case eventlogDeleteCmd.FullCommand():
 		eventlog.Delete(*deleteSelectedEventlogs, *deleteEventLogsForce)
 	case eventlogPruneCmd.FullCommand():
@@ -1371,6 +1391,8 @@ Environment Variables:
 		agreementbot.AgreementList(*agbotlistArchivedAgreements, *agbotAgreement)
 	case agbotAgreementCancelCmd.FullCommand():
 		agreementbot.AgreementCancel(*agbotCancelAgreementId, *agbotCancelAllAgreements)
+	case agbotEventlogListCmd.FullCommand():
+		agreementbot.EventLogList(*agbotEventlogListLong, *agbotEventlogListSelect, eventlog.ListOptions{Since: *agbotEventlogListSince, Until: *agbotEventlogListUntil, Limit: *agbotEventlogListLimit, Cursor: *agbotEventlogListCursor, Order: *agbotEventlogListOrder})
 	case agbotListCmd.FullCommand():
 		agreementbot.List()
 	case agbotPolicyListCmd.FullCommand():
@@ -1383,6 +1405,8 @@ Environment Variables:
 		status.DisplayStatus(*agbotStatusLong, true)
 	case utilConfigConvCmd.FullCommand():
 		utilcmds.ConvertConfig(*utilConfigConvFile)
+	case utilEventTapCmd.FullCommand():
+		utilcmds.EventTap(*utilEventTapAgbot, *utilEventTapEventIds, *utilEventTapWorkers, *utilEventTapTokenFile, *utilEventTapLong)
 	case mmsStatusCmd.FullCommand():
 		sync_service.Status(*mmsOrg, *mmsUserPw)
 	case mmsObjectListCmd.FullCommand():
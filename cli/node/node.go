@@ -31,6 +31,9 @@ type NodeAndStatus struct {
 	TokenValid         *bool       `json:"token_valid"`           // removed omitempty
 	HAGroup            *string     `json:"ha_group"`
 	Config             Configstate `json:"configstate"` // removed omitempty
+	Offline            *bool       `json:"offline,omitempty"`
+	OfflineSince       string      `json:"offline_since,omitempty"`
+	QueuedWrites       *int        `json:"queued_exchange_writes,omitempty"`
 	// from apicommon.Info
 	Configuration *apicommon.Configuration `json:"configuration"`
 	Connectivity  map[string]bool          `json:"connectivity,omitempty"`
@@ -55,6 +58,11 @@ func (n *NodeAndStatus) CopyNodeInto(horDevice *api.HorizonDevice) {
 	if horDevice.Config.LastUpdateTime != nil {
 		n.Config.LastUpdateTime = cliutils.ConvertTime(*horDevice.Config.LastUpdateTime)
 	}
+	n.Offline = horDevice.Offline
+	if horDevice.OfflineSince != nil {
+		n.OfflineSince = cliutils.ConvertTime(*horDevice.OfflineSince)
+	}
+	n.QueuedWrites = horDevice.QueuedWrites
 }
 
 // CopyStatusInto copies the status info into our output struct
@@ -0,0 +1,97 @@
+package utilcmds
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+)
+
+// The token file that is read when none is specified, it is the path suggested in the documentation of the event tap.
+const DEFAULT_EVENTTAP_TOKEN_FILE = "/var/horizon/eventtap.token"
+
+// An event as it was dispatched to the workers of anax, as streamed by the /debug/eventtap api.
+type TappedEvent struct {
+	Time    int64    `json:"time"`
+	EventId string   `json:"event_id"`
+	Type    string   `json:"type"`
+	Source  string   `json:"source"`
+	Workers []string `json:"workers,omitempty"`
+	Message string   `json:"message"`
+}
+
+// Print the events passed between the workers of the agent, or of the agbot, as they are dispatched, until the
+// command is interrupted or anax closes the stream.
+func EventTap(agbot bool, eventIds []string, workers []string, tokenFile string, long bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if agbot {
+		// set env to call agbot url
+		if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
+		}
+	}
+
+	if tokenFile == "" {
+		tokenFile = DEFAULT_EVENTTAP_TOKEN_FILE
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("unable to read the event tap token file %v, error %v. The event tap must be enabled in the anax configuration file, and this command is usually run as root.", tokenFile, err))
+	}
+
+	query := url.Values{}
+	for _, id := range eventIds {
+		query.Add("event_id", id)
+	}
+	for _, w := range workers {
+		query.Add("worker", w)
+	}
+	url_s := "debug/eventtap"
+	if len(query) > 0 {
+		url_s = fmt.Sprintf("%v?%v", url_s, query.Encode())
+	}
+
+	httpCode, body := cliutils.HorizonGetStreamWithAuth(url_s, http.StatusOK, "Bearer "+strings.TrimSpace(string(token)))
+	if body == nil {
+		if httpCode == http.StatusNotFound {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("the event tap is not enabled, set EventTap.TokenFile in the anax configuration file to enable it."))
+		} else if httpCode == http.StatusUnauthorized {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("the event tap token in %v was not accepted.", tokenFile))
+		}
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("unable to open the event tap, HTTP code %v.", httpCode))
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			// heartbeat
+			continue
+		}
+
+		var te TappedEvent
+		if err := json.Unmarshal([]byte(line), &te); err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal the event tap stream: %v", err))
+		}
+		if long {
+			fmt.Println(line)
+		} else {
+			t := time.Unix(te.Time, 0)
+			fmt.Printf("%v:   %v -> [%v] %v\n", t.Format("2006-01-02 15:04:05"), te.Source, strings.Join(te.Workers, ","), te.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("failed to read the event tap stream: %v", err))
+	}
+}
@@ -334,6 +334,15 @@ func (w ClusterUpgradeWorker) NewEvent(incoming events.Message) {
 
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w ClusterUpgradeWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.AgentPackageDownloadedMessage{},
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 func (w *ClusterUpgradeWorker) CommandHandler(command worker.Command) bool {
 	glog.Infof(cuwlog(fmt.Sprintf("Handling command %v", command)))
 	switch command.(type) {
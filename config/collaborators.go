@@ -200,6 +200,27 @@ func newHTTPClientFactory(hConfig HorizonConfig) (*HTTPClientFactory, error) {
 		TLSClientConfig:       &tlsConf,
 	}
 
+	// A replay answers the requests from a recording, otherwise they can be recorded as they are sent.
+	var roundTripper http.RoundTripper = transport
+	if hConfig.ExchangeClient.ReplayFile != "" {
+		replay, err := newReplayTransport(hConfig.ExchangeClient.ReplayFile, hConfig.ExchangeClient.GetRecordMaxFiles())
+		if err != nil {
+			return nil, err
+		}
+		roundTripper = replay
+	} else if hConfig.ExchangeClient.RecordFile != "" {
+		recorder, err := newTrafficRecorder(hConfig.ExchangeClient.RecordFile, hConfig.ExchangeClient.GetRecordMaxSizeMB(), hConfig.ExchangeClient.GetRecordMaxFiles())
+		if err != nil {
+			return nil, err
+		}
+		roundTripper = &recordingTransport{base: transport, recorder: recorder}
+	}
+
+	// Send the requests for the management hub services to their active endpoints.
+	if endpoints := newEndpointsFromConfig(hConfig, isAgbot); len(endpoints) != 0 {
+		roundTripper = newEndpointTransport(roundTripper, endpoints)
+	}
+
 	clientFunc := func(overrideTimeoutS *uint) *http.Client {
 		var timeoutS uint
 
@@ -214,7 +235,7 @@ func newHTTPClientFactory(hConfig HorizonConfig) (*HTTPClientFactory, error) {
 			// body reading. This means that you must set the timeout according
 			// to the total payload size you expect
 			Timeout:   time.Second * time.Duration(timeoutS),
-			Transport: transport,
+			Transport: roundTripper,
 		}
 	}
 
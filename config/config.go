@@ -17,14 +17,23 @@ const ExchangeMessageNoDynamicPollEnvvarName = "HZN_NO_DYNAMIC_POLL"
 const OldMgmtHubCertPath = "HZN_ICP_CA_CERT_PATH"
 const ManagementHubCertPath = "HZN_MGMT_HUB_CERT_PATH"
 const AnaxAPIPort = "HZN_AGENT_PORT"
+const EnableMetricsEnvvarName = "HZN_AGENT_ENABLE_METRICS"
+const OTLPEndpointEnvvarName = "OTEL_EXPORTER_OTLP_ENDPOINT"
+const TracingExporterOTLP = "otlp"
 const ESSHTTPClientTimeoutEnvvarName = "HZN_FSS_HTTP_ESS_CLIENT_TIMEOUT"
 const ESSHTTPObjClientTimeoutEnvvarName = "HZN_FSS_HTTP_ESS_OBJ_CLIENT_TIMEOUT"
 
 type HorizonConfig struct {
-	Edge          Config
-	AgreementBot  AGConfig
-	Collaborators Collaborators
-	ArchSynonyms  ArchSynonyms
+	Edge           Config
+	AgreementBot   AGConfig
+	Collaborators  Collaborators
+	ArchSynonyms   ArchSynonyms
+	Tracing        TracingConfig
+	Watchdog       WatchdogConfig
+	EventQueues    EventQueueConfig
+	EventTap       EventTapConfig
+	Messaging      MessagingConfig
+	ExchangeClient ExchangeClientConfig
 }
 
 // This is the configuration options for Edge component flavor of Anax
@@ -41,7 +50,9 @@ type Config struct {
 	TrustSystemCACerts               bool   // If equal to true, the HTTP client factory will set up clients that trust CA certs provided by a Linux distribution (see https://golang.org/pkg/crypto/x509/#SystemCertPool and https://golang.org/src/crypto/x509/root_linux.go)
 	CACertsPath                      string // Path to a file containing PEM-encoded x509 certs HTTP clients in Anax will trust (additive to the configuration option "TrustSystemCACerts")
 	ExchangeURL                      string
+	ExchangeURLs                     []string // Alternate exchange URLs, used in order when ExchangeURL can't be reached.
 	AgbotURL                         string
+	AgbotURLs                        []string // Alternate agbot URLs, used in order when AgbotURL can't be reached.
 	DefaultHTTPClientTimeoutS        uint
 	HTTPIdleConnectionTimeout        uint // Will be seconds for agbot and milliseconds for agent
 	PolicyPath                       string
@@ -76,6 +87,18 @@ type Config struct {
 	K8sCRInstallTimeoutS             int64     // The number of seconds to wait for the custom resouce to install successfully before it is considered a failure
 	SecretsManagerFilePath           string    // The filepath for the secrets manager to store secrets in the agent filesystem
 	NodeMgmtWorkDirectory            string    // The filepath for the node management policy updates to use
+	EnableMetrics                    bool      // Serve the /metrics API in the Prometheus text format. The default is false.
+	MessageKeyRotationIntervalH      int       // How often the node's message key is replaced, in hours. The default is 0, which means the key is only replaced on demand.
+	MessageKeyGracePeriodS           int       // How long a replaced message key can still be used to decrypt messages that were in flight. The default is 3600 seconds.
+
+	// The retention of the event logs in the node's database.
+	EventLog EventLogConfig
+
+	// The journal of the events that start workloads and services, replayed when the agent restarts.
+	EventJournal EventJournalConfig
+
+	// The authentication of the callers of the agent's REST API and the roles they are given.
+	APIAuth APIAuthConfig
 
 	// these Ids could be provided in config or discovered after startup by the system
 	BlockchainAccountId        string
@@ -102,6 +125,7 @@ type AGConfig struct {
 	ProcessGovernanceIntervalS    uint64           // How long the gov sleeps before general gov checks (new payloads, interval payments, etc).
 	IgnoreContractWithAttribs     string           // A comma seperated list of contract attributes. If set, the contracts that contain one or more of the attributes will be ignored. The default is "ethereum_account".
 	ExchangeURL                   string           // The URL of the Horizon exchange. If not configured, the exchange will not be used.
+	ExchangeURLs                  []string         // Alternate exchange URLs, used in order when ExchangeURL can't be reached.
 	ExchangeHeartbeat             int              // Seconds between heartbeats to the exchange
 	ExchangeId                    string           // The id of the agbot, not the userid of the exchange user. Must be org qualified.
 	ExchangeToken                 string           // The agbot's authentication token
@@ -111,6 +135,8 @@ type AGConfig struct {
 	ExchangeMessageTTLScaleFactor float64          // Scale factor for thee time the exchange will keep this ,essage before automatically deleting it. Scaled relativee to the max heeartbeat interval
 	MessageKeyPath                string           // The path to the location of messaging keys
 	MessageKeyCheck               int              // The interval (in seconds) indicating how often the agbot checks its own object in the exchange to ensure that the message key is still available.
+	MessageKeyRotationIntervalH   int              // How often the agbot's message key is replaced, in hours. The default is 0, which means the key is only replaced on demand.
+	MessageKeyGracePeriodS        int              // How long a replaced message key can still be used to decrypt messages that were in flight. The default is 3600 seconds.
 	DefaultWorkloadPW             string           // The default workload password if none is specified in the policy file
 	APIListen                     string           // Host and port for the API to listen on
 	SecureAPIListenHost           string           // The host for the secure API to listen on
@@ -118,8 +144,10 @@ type AGConfig struct {
 	SecureAPIServerCert           string           // The path to the certificate file for the secure api
 	SecureAPIServerKey            string           // The path to the server key file for the secure api
 	PurgeArchivedAgreementHours   int              // Number of hours to leave an archived agreement in the database before automatically deleting it
+	EventLogMaxAgeH               int              // Event logs older than this, in hours, are deleted, defaults to 720 (30 days). Set it to a negative number to keep logs regardless of their age.
 	CheckUpdatedPolicyS           int              // The number of seconds to wait between checks for an updated policy file. Zero means auto checking is turned off.
 	CSSURL                        string           // The URL used to access the CSS.
+	CSSURLs                       []string         // Alternate CSS URLs, used in order when CSSURL can't be reached.
 	CSSSSLCert                    string           // The path to the client side SSL certificate for the CSS.
 	MMSGarbageCollectionInterval  int64            // The amount of time to wait between MMS object cache garbage collection scans.
 	AgreementBatchSize            uint64           // The number of nodes that the agbot will process in a batch.
@@ -136,6 +164,22 @@ type AGConfig struct {
 	SecretsUpdateCheckMaxInterval int              // As the runtime increases the SecretsUpdateCheckInterval, this value is the maximum that value can attain.
 	SecretsUpdateCheckIncrement   int              // The number of seconds to increment the SecretsUpdateCheckInterval when its time to increase the poll interval.
 	CSSDestinationBatchSize       int              // The max number of destination updates to send to CSS in a single update.
+	Exchanges                     []AgbotExchange  // Additional exchanges that this agbot serves. Orgs not listed in any of them are in the exchange at ExchangeURL.
+}
+
+// An exchange, other than the one at AGConfig.ExchangeURL, that an agbot serves. Each org lives in only one exchange.
+type AgbotExchange struct {
+	Name          string      // A short, unique name for the exchange, such as a region. It is used to partition the agbot's state.
+	ExchangeURL   string      // The URL of the exchange.
+	CSSURL        string      // The URL used to access the CSS that is paired with this exchange.
+	ExchangeId    string      // The id of the agbot in this exchange. Must be org qualified.
+	ExchangeToken string      // The agbot's authentication token in this exchange.
+	Orgs          []string    // The orgs that live in this exchange.
+	Vault         VaultConfig // The vault that holds the secrets of the orgs in this exchange. The default is the agbot's Vault.
+}
+
+func (e AgbotExchange) String() string {
+	return fmt.Sprintf("Name: %v, ExchangeURL: %v, CSSURL: %v, ExchangeId: %v, ExchangeToken: %v, Orgs: %v, Vault: {%v}", e.Name, e.ExchangeURL, e.CSSURL, e.ExchangeId, "******", e.Orgs, &e.Vault)
 }
 
 // Contains the hashicorp vault configuration used within AGConfig.
@@ -144,6 +188,364 @@ type VaultConfig struct {
 	SSLCertPath string // The SSL certificate for the vault.
 }
 
+// Contains the configuration for tracing agreement processing. Tracing is disabled when no exporter is configured.
+type TracingConfig struct {
+	Exporter     string // The span exporter, either "otlp" or "file".
+	OTLPEndpoint string // The base URL of the OpenTelemetry collector used by the otlp exporter, e.g. http://localhost:4318.
+	FilePath     string // The file that the file exporter appends spans to.
+	ServiceName  string // The service name reported with every span, defaults to anax-agent or anax-agbot.
+}
+
+func (c TracingConfig) String() string {
+	return fmt.Sprintf("Exporter: %v, OTLPEndpoint: %v, FilePath: %v, ServiceName: %v", c.Exporter, c.OTLPEndpoint, c.FilePath, c.ServiceName)
+}
+
+// Contains the configuration of the watchdog that looks for workers that have stopped processing commands.
+type WatchdogConfig struct {
+	Disable               bool // Turn off the watchdog.
+	CheckIntervalS        int  // How often the workers are checked, defaults to 30 seconds.
+	StuckThresholdS       int  // How long a worker can spend handling one command before it is considered stuck, defaults to 300 seconds.
+	QueueThresholdPercent int  // How full a worker's command queue can get before it is considered backlogged, defaults to 90 percent.
+	DumpStacks            bool // Log the stacks of all go routines when a worker is found to be stuck.
+	LivenessThresholdS    int  // How long a worker can spend handling one command before /health/live fails, defaults to 1800 seconds.
+}
+
+// The queues of events waiting to be delivered to each worker. Each worker has its own queue, so that a slow worker
+// does not hold up the delivery of events to the others.
+type EventQueueConfig struct {
+	Capacity int                               // The most events queued for a worker, defaults to 200.
+	Overflow string                            // What to do when a worker's queue is full: "block" (the default), "drop-oldest" or "coalesce".
+	Workers  map[string]EventQueueWorkerConfig // Settings for individual workers, by worker name, e.g. {"AgBot": {"Capacity": 2000}}.
+}
+
+// The debug stream of the events passed between the workers, served by the /debug/eventtap API. The stream is off
+// unless a token file is configured. The file holds the token that clients have to send, it is created with a random
+// token when it does not exist.
+type EventTapConfig struct {
+	TokenFile string // The file holding the token of the event tap, e.g. /var/horizon/eventtap.token.
+}
+
+func (c EventTapConfig) String() string {
+	return fmt.Sprintf("TokenFile: %v", c.TokenFile)
+}
+
+// The settings of one worker's event queue. Zero values are taken from the EventQueueConfig.
+type EventQueueWorkerConfig struct {
+	Capacity int
+	Overflow string
+}
+
+func (c EventQueueConfig) String() string {
+	return fmt.Sprintf("Capacity: %v, Overflow: %v, Workers: %v", c.Capacity, c.Overflow, c.Workers)
+}
+
+// Returns the capacity and overflow policy of the named worker's event queue.
+func (c EventQueueConfig) GetWorkerQueue(name string) (int, string) {
+	capacity, overflow := c.Capacity, c.Overflow
+	if wc, ok := c.Workers[name]; ok {
+		if wc.Capacity > 0 {
+			capacity = wc.Capacity
+		}
+		if wc.Overflow != "" {
+			overflow = wc.Overflow
+		}
+	}
+	if capacity <= 0 {
+		capacity = EventQueueCapacity_DEFAULT
+	}
+	if overflow == "" {
+		overflow = EventQueueOverflowBlock
+	}
+	return capacity, overflow
+}
+
+// The security settings for the messages that agents and agbots send each other through the exchange.
+type MessagingConfig struct {
+	MaxEnvelopeVersion int // The highest message envelope version that is sent and advertised, defaults to 2. Set it to 1 to only use RSA.
+	MaxMessageAgeS     int // Messages whose sender timestamp is older than this are rejected, defaults to 86400 seconds.
+}
+
+func (c MessagingConfig) String() string {
+	return fmt.Sprintf("MaxEnvelopeVersion: %v, MaxMessageAgeS: %v", c.MaxEnvelopeVersion, c.MaxMessageAgeS)
+}
+
+func (c MessagingConfig) GetMaxEnvelopeVersion() int {
+	if c.MaxEnvelopeVersion <= 0 || c.MaxEnvelopeVersion > MessageEnvelopeVersion_MAX {
+		return MessageEnvelopeVersion_MAX
+	}
+	return c.MaxEnvelopeVersion
+}
+
+func (c MessagingConfig) GetMaxMessageAgeS() int {
+	if c.MaxMessageAgeS <= 0 {
+		return MaxMessageAgeS_DEFAULT
+	}
+	return c.MaxMessageAgeS
+}
+
+// The retention of the agent's event logs. The logs are pruned in the background. When there are too many logs, or they
+// take too much space, the oldest logs of the lowest severity are deleted first.
+type EventLogConfig struct {
+	MaxAgeH            int                  // Event logs older than this, in hours, are deleted, defaults to 2160 (90 days). Set it to a negative number to keep logs regardless of their age.
+	SeverityMaxAgeH    map[string]int       // The age, in hours, at which the logs of a severity are deleted, e.g. {"info": 168}. Overrides MaxAgeH for that severity.
+	MaxCount           int                  // The most event logs kept, defaults to 20000. Set it to a negative number for no limit.
+	MaxSizeMB          int                  // The most space the event logs can take in the database, defaults to 50 MB. Set it to a negative number for no limit.
+	PruneIntervalS     int                  // How often the event logs are pruned, defaults to 3600 seconds.
+	CompactThresholdMB int                  // Compact the database when the agent starts if it has more free space than this, defaults to 20 MB. Set it to a negative number to turn off compaction.
+	Sinks              []EventLogSinkConfig // Where the event logs are forwarded to as they are saved, in addition to the database.
+}
+
+func (c EventLogConfig) String() string {
+	return fmt.Sprintf("MaxAgeH: %v, SeverityMaxAgeH: %v, MaxCount: %v, MaxSizeMB: %v, PruneIntervalS: %v, CompactThresholdMB: %v, Sinks: %v", c.MaxAgeH, c.SeverityMaxAgeH, c.MaxCount, c.MaxSizeMB, c.PruneIntervalS, c.CompactThresholdMB, c.Sinks)
+}
+
+// Returns the age in hours at which the logs of a severity are deleted. A negative return value means the logs are
+// kept regardless of their age.
+func (c EventLogConfig) GetMaxAgeH(severity string) int {
+	if h, ok := c.SeverityMaxAgeH[severity]; ok && h != 0 {
+		return h
+	} else if c.MaxAgeH == 0 {
+		return EventLogMaxAgeH_DEFAULT
+	}
+	return c.MaxAgeH
+}
+
+// A negative return value means there is no limit.
+func (c EventLogConfig) GetMaxCount() int {
+	if c.MaxCount == 0 {
+		return EventLogMaxCount_DEFAULT
+	}
+	return c.MaxCount
+}
+
+// A negative return value means there is no limit.
+func (c EventLogConfig) GetMaxSizeMB() int {
+	if c.MaxSizeMB == 0 {
+		return EventLogMaxSizeMB_DEFAULT
+	}
+	return c.MaxSizeMB
+}
+
+func (c EventLogConfig) GetPruneIntervalS() int {
+	if c.PruneIntervalS <= 0 {
+		return EventLogPruneIntervalS_DEFAULT
+	}
+	return c.PruneIntervalS
+}
+
+// A negative return value means the database is not compacted.
+func (c EventLogConfig) GetCompactThresholdMB() int {
+	if c.CompactThresholdMB == 0 {
+		return DBCompactThresholdMB_DEFAULT
+	}
+	return c.CompactThresholdMB
+}
+
+// The write-ahead journal of the events that start workloads and services. An event is journaled before it is
+// dispatched to the workers and removed when the work it started has finished, so that the events in flight when the
+// agent stops are dispatched again when it restarts. The journal is off when no event types are listed.
+type EventJournalConfig struct {
+	Events  []string // The types of the events that are journaled, "AgreementReachedMessage" and "ImageFetchMessage".
+	MaxAgeH int      // Journaled events older than this, in hours, are dropped instead of replayed, defaults to 24.
+}
+
+func (c EventJournalConfig) String() string {
+	return fmt.Sprintf("Events: %v, MaxAgeH: %v", c.Events, c.MaxAgeH)
+}
+
+// The authentication of the callers of the agent's REST API. The API is open to anyone who can reach APIListen unless
+// one of the token files, the UnixSocket or the ClientCAFile is configured. Then each caller has to authenticate, with a bearer token,
+// a client certificate or by calling over the unix socket, and is given the admin or the read-only role. Read-only
+// callers can get the node's state but not change it.
+type APIAuthConfig struct {
+	AdminTokenFile    string   // The file holding the bearer token of the admin role, created with a random token when it does not exist.
+	ReadOnlyTokenFile string   // The file holding the bearer token of the read-only role, created with a random token when it does not exist.
+	UnixSocket        string   // Also serve the API on this unix socket. Callers running as root or as one of the AdminUIDs are admins, others are read-only.
+	AdminUIDs         []int    // The user ids, besides root, that are admins when they call over the unix socket.
+	TLSCertFile       string   // Serve APIListen over TLS with this server certificate.
+	TLSKeyFile        string   // The key of the TLSCertFile.
+	ClientCAFile      string   // Authenticate the callers that present a client certificate signed by one of these CA certs. Requires TLSCertFile.
+	AdminCNs          []string // The common names of the client certificates that are admins, other client certificates are read-only.
+}
+
+func (c APIAuthConfig) String() string {
+	return fmt.Sprintf("AdminTokenFile: %v, ReadOnlyTokenFile: %v, UnixSocket: %v, AdminUIDs: %v, TLSCertFile: %v, TLSKeyFile: %v, ClientCAFile: %v, AdminCNs: %v",
+		c.AdminTokenFile, c.ReadOnlyTokenFile, c.UnixSocket, c.AdminUIDs, c.TLSCertFile, c.TLSKeyFile, c.ClientCAFile, c.AdminCNs)
+}
+
+// Returns true when the callers of the agent's REST API have to authenticate.
+func (c APIAuthConfig) Enabled() bool {
+	return c.AdminTokenFile != "" || c.ReadOnlyTokenFile != "" || c.UnixSocket != "" || c.ClientCAFile != ""
+}
+
+func (c EventJournalConfig) GetMaxAgeH() int {
+	if c.MaxAgeH <= 0 {
+		return EventJournalMaxAgeH_DEFAULT
+	}
+	return c.MaxAgeH
+}
+
+// A destination that the event logs are forwarded to. The event logs are held in a buffer while the destination
+// cannot be reached, and the oldest are dropped when the buffer is full.
+type EventLogSinkConfig struct {
+	Type       string // The kind of destination, "syslog", "file" or "otlp".
+	Address    string // For syslog, the remote syslog server, e.g. udp://logs.example.com:514. For otlp, the base URL of the OpenTelemetry collector, e.g. http://localhost:4318.
+	Path       string // For file, the file that the event logs are appended to, one JSON object per line.
+	MaxSizeMB  int    // For file, the size at which the file is rotated, defaults to 10 MB.
+	MaxFiles   int    // For file, the number of rotated files that are kept, defaults to 3.
+	BufferSize int    // The most event logs held while the destination cannot be reached, defaults to 1000.
+}
+
+func (c EventLogSinkConfig) String() string {
+	return fmt.Sprintf("Type: %v, Address: %v, Path: %v, MaxSizeMB: %v, MaxFiles: %v, BufferSize: %v", c.Type, c.Address, c.Path, c.MaxSizeMB, c.MaxFiles, c.BufferSize)
+}
+
+func (c EventLogSinkConfig) GetMaxSizeMB() int {
+	if c.MaxSizeMB <= 0 {
+		return EventLogSinkMaxSizeMB_DEFAULT
+	}
+	return c.MaxSizeMB
+}
+
+func (c EventLogSinkConfig) GetMaxFiles() int {
+	if c.MaxFiles <= 0 {
+		return EventLogSinkMaxFiles_DEFAULT
+	}
+	return c.MaxFiles
+}
+
+func (c EventLogSinkConfig) GetBufferSize() int {
+	if c.BufferSize <= 0 {
+		return EventLogSinkBufferSize_DEFAULT
+	}
+	return c.BufferSize
+}
+
+// The settings that keep the agents and agbots from overloading the exchange, especially while it recovers from an outage.
+type ExchangeClientConfig struct {
+	RateLimitPerS           float64 // Calls per second allowed to each class of exchange resource, defaults to 10 for an agent and 100 for an agbot. Set it to a negative number to turn off rate limiting.
+	RateLimitBurst          int     // Calls to a class of exchange resource that can be made at once before the rate limit applies, defaults to twice RateLimitPerS.
+	BreakerFailureThreshold int     // Consecutive transport errors or 5xx responses from a host that open its circuit, defaults to 5. Set it to a negative number to turn off the circuit breaker.
+	BreakerOpenS            int     // How long a circuit stays open before a probe call is let through, defaults to 30 seconds.
+	BackoffMaxS             int     // The longest wait between retries of a failed call, defaults to 300 seconds.
+	FailoverThreshold       int     // Consecutive failures of the active management hub endpoint that switch to the next endpoint, defaults to 3.
+	FailbackS               int     // How long a failed endpoint is not used, and how often the preferred endpoints are probed, defaults to 300 seconds.
+	RecordFile              string  // Record the management hub requests and responses, with secrets redacted, to this file. Off if empty.
+	RecordMaxSizeMB         int     // The size at which the record file is rotated, defaults to 10 MB.
+	RecordMaxFiles          int     // The number of rotated record files kept, defaults to 3.
+	ReplayFile              string  // Answer the management hub requests with the responses recorded in this file, and its rotated files, instead of sending them. For reproducing problems, not for production use.
+}
+
+func (c ExchangeClientConfig) String() string {
+	return fmt.Sprintf("RateLimitPerS: %v, RateLimitBurst: %v, BreakerFailureThreshold: %v, BreakerOpenS: %v, BackoffMaxS: %v, FailoverThreshold: %v, FailbackS: %v, RecordFile: %v, RecordMaxSizeMB: %v, RecordMaxFiles: %v, ReplayFile: %v", c.RateLimitPerS, c.RateLimitBurst, c.BreakerFailureThreshold, c.BreakerOpenS, c.BackoffMaxS, c.FailoverThreshold, c.FailbackS, c.RecordFile, c.RecordMaxSizeMB, c.RecordMaxFiles, c.ReplayFile)
+}
+
+// The rate limit depends on whether the client is an agent or an agbot, an agbot makes many more calls. A negative
+// return value means there is no rate limit.
+func (c ExchangeClientConfig) GetRateLimitPerS(agbot bool) float64 {
+	if c.RateLimitPerS < 0 {
+		return -1
+	} else if c.RateLimitPerS > 0 {
+		return c.RateLimitPerS
+	} else if agbot {
+		return ExchangeRateLimitPerS_AGBOT_DEFAULT
+	}
+	return ExchangeRateLimitPerS_AGENT_DEFAULT
+}
+
+func (c ExchangeClientConfig) GetRateLimitBurst(agbot bool) int {
+	if c.RateLimitBurst > 0 {
+		return c.RateLimitBurst
+	} else if burst := int(2 * c.GetRateLimitPerS(agbot)); burst > 0 {
+		return burst
+	}
+	return 1
+}
+
+// A negative return value means the circuit breaker is turned off.
+func (c ExchangeClientConfig) GetBreakerFailureThreshold() int {
+	if c.BreakerFailureThreshold < 0 {
+		return -1
+	} else if c.BreakerFailureThreshold == 0 {
+		return ExchangeBreakerFailureThreshold_DEFAULT
+	}
+	return c.BreakerFailureThreshold
+}
+
+func (c ExchangeClientConfig) GetBreakerOpenS() int {
+	if c.BreakerOpenS <= 0 {
+		return ExchangeBreakerOpenS_DEFAULT
+	}
+	return c.BreakerOpenS
+}
+
+func (c ExchangeClientConfig) GetBackoffMaxS() int {
+	if c.BackoffMaxS <= 0 {
+		return ExchangeBackoffMaxS_DEFAULT
+	}
+	return c.BackoffMaxS
+}
+
+func (c ExchangeClientConfig) GetFailoverThreshold() int {
+	if c.FailoverThreshold <= 0 {
+		return EndpointFailoverThreshold_DEFAULT
+	}
+	return c.FailoverThreshold
+}
+
+func (c ExchangeClientConfig) GetFailbackS() int {
+	if c.FailbackS <= 0 {
+		return EndpointFailbackS_DEFAULT
+	}
+	return c.FailbackS
+}
+
+func (c ExchangeClientConfig) GetRecordMaxSizeMB() int {
+	if c.RecordMaxSizeMB <= 0 {
+		return TrafficRecordMaxSizeMB_DEFAULT
+	}
+	return c.RecordMaxSizeMB
+}
+
+func (c ExchangeClientConfig) GetRecordMaxFiles() int {
+	if c.RecordMaxFiles <= 0 {
+		return TrafficRecordMaxFiles_DEFAULT
+	}
+	return c.RecordMaxFiles
+}
+
+func (c WatchdogConfig) String() string {
+	return fmt.Sprintf("Disable: %v, CheckIntervalS: %v, StuckThresholdS: %v, QueueThresholdPercent: %v, DumpStacks: %v, LivenessThresholdS: %v", c.Disable, c.CheckIntervalS, c.StuckThresholdS, c.QueueThresholdPercent, c.DumpStacks, c.LivenessThresholdS)
+}
+
+func (c WatchdogConfig) GetCheckIntervalS() int {
+	if c.CheckIntervalS <= 0 {
+		return 30
+	}
+	return c.CheckIntervalS
+}
+
+func (c WatchdogConfig) GetStuckThresholdS() int {
+	if c.StuckThresholdS <= 0 {
+		return 300
+	}
+	return c.StuckThresholdS
+}
+
+func (c WatchdogConfig) GetQueueThresholdPercent() int {
+	if c.QueueThresholdPercent <= 0 || c.QueueThresholdPercent > 100 {
+		return 90
+	}
+	return c.QueueThresholdPercent
+}
+
+func (c WatchdogConfig) GetLivenessThresholdS() int {
+	if c.LivenessThresholdS <= 0 {
+		return 1800
+	}
+	return c.LivenessThresholdS
+}
+
 func (c *HorizonConfig) GetSecretsMount() string {
 	return HZN_SECRETS_MOUNT
 }
@@ -316,6 +718,28 @@ func (a *AGConfig) GetExchangeMessageTTL(maxHeartbeatInterval int) int {
 	return int(float64(hbInterval) * scaleFactor)
 }
 
+func (c *Config) GetMessageKeyGracePeriodS() int {
+	if c.MessageKeyGracePeriodS <= 0 {
+		return MessageKeyGracePeriodS_DEFAULT
+	}
+	return c.MessageKeyGracePeriodS
+}
+
+func (a *AGConfig) GetMessageKeyGracePeriodS() int {
+	if a.MessageKeyGracePeriodS <= 0 {
+		return MessageKeyGracePeriodS_DEFAULT
+	}
+	return a.MessageKeyGracePeriodS
+}
+
+// A negative return value means the event logs are kept regardless of their age.
+func (a *AGConfig) GetEventLogMaxAgeH() int {
+	if a.EventLogMaxAgeH == 0 {
+		return AgbotEventLogMaxAgeH_DEFAULT
+	}
+	return a.EventLogMaxAgeH
+}
+
 func (c *Config) GetNodeMgmtDirectory() string {
 	if c.NodeMgmtWorkDirectory == "" {
 		return fmt.Sprintf("%v/nmp", getDefaultBase())
@@ -342,17 +766,22 @@ func getDefaultRunBase() string {
 // some configuration is provided by envvars; in this case we populate this config object from expected envvars
 func enrichFromEnvvars(config *HorizonConfig) error {
 
-	if exchangeURL := os.Getenv(ExchangeURLEnvvarName); exchangeURL != "" {
+	// The URLs can be comma separated lists, the first URL is preferred and the others are alternates.
+	if exchangeURL, alternates := splitEndpoints(os.Getenv(ExchangeURLEnvvarName)); exchangeURL != "" {
 		config.Edge.ExchangeURL = exchangeURL
+		config.Edge.ExchangeURLs = alternates
 		config.AgreementBot.ExchangeURL = exchangeURL
+		config.AgreementBot.ExchangeURLs = alternates
 	}
 
-	if fssCSSURL := os.Getenv(FileSyncServiceCSSURLEnvvarName); fssCSSURL != "" {
+	if fssCSSURL, alternates := splitEndpoints(os.Getenv(FileSyncServiceCSSURLEnvvarName)); fssCSSURL != "" {
 		config.Edge.FileSyncService.CSSURL = fssCSSURL
+		config.Edge.FileSyncService.CSSURLs = alternates
 	}
 
-	if agbotURL := os.Getenv(AgbotURLEnvvarName); agbotURL != "" {
+	if agbotURL, alternates := splitEndpoints(os.Getenv(AgbotURLEnvvarName)); agbotURL != "" {
 		config.Edge.AgbotURL = agbotURL
+		config.Edge.AgbotURLs = alternates
 	}
 
 	if vaultURL := os.Getenv(VaultURLEnvvarName); vaultURL != "" {
@@ -363,6 +792,17 @@ func enrichFromEnvvars(config *HorizonConfig) error {
 		config.Edge.ExchangeMessageDynamicPoll = false
 	}
 
+	if enableMetrics := os.Getenv(EnableMetricsEnvvarName); enableMetrics != "" {
+		config.Edge.EnableMetrics = enableMetrics == "true"
+	}
+
+	if otlpEndpoint := os.Getenv(OTLPEndpointEnvvarName); otlpEndpoint != "" {
+		config.Tracing.OTLPEndpoint = otlpEndpoint
+		if config.Tracing.Exporter == "" {
+			config.Tracing.Exporter = TracingExporterOTLP
+		}
+	}
+
 	if apiPort := os.Getenv(AnaxAPIPort); apiPort != "" {
 		if config.Edge.APIListen != "" {
 			listen := strings.Split(config.Edge.APIListen, ":")
@@ -478,6 +918,18 @@ func Read(file string) (*HorizonConfig, error) {
 		if config.AgreementBot.ExchangeURL != "" {
 			config.AgreementBot.ExchangeURL = strings.TrimRight(config.AgreementBot.ExchangeURL, "/") + "/"
 		}
+		for ix, u := range config.Edge.ExchangeURLs {
+			config.Edge.ExchangeURLs[ix] = strings.TrimRight(u, "/") + "/"
+		}
+		for ix, u := range config.AgreementBot.ExchangeURLs {
+			config.AgreementBot.ExchangeURLs[ix] = strings.TrimRight(u, "/") + "/"
+		}
+		for ix, ex := range config.AgreementBot.Exchanges {
+			config.AgreementBot.Exchanges[ix].ExchangeURL = strings.TrimRight(ex.ExchangeURL, "/") + "/"
+		}
+		if err := config.AgreementBot.validateExchanges(); err != nil {
+			return nil, err
+		}
 
 		// add a slash at the back of the PolicyPath
 		if config.Edge.PolicyPath != "" {
@@ -510,7 +962,7 @@ func Read(file string) (*HorizonConfig, error) {
 }
 
 func (c *HorizonConfig) String() string {
-	return fmt.Sprintf("Edge: {%v}, AgreementBot: {%v}, Collaborators: {%v}, ArchSynonyms: {%v}", c.Edge.String(), c.AgreementBot.String(), c.Collaborators.String(), c.ArchSynonyms)
+	return fmt.Sprintf("Edge: {%v}, AgreementBot: {%v}, Collaborators: {%v}, ArchSynonyms: {%v}, Tracing: {%v}, Watchdog: {%v}, EventQueues: {%v}, EventTap: {%v}, Messaging: {%v}, ExchangeClient: {%v}", c.Edge.String(), c.AgreementBot.String(), c.Collaborators.String(), c.ArchSynonyms, c.Tracing, c.Watchdog, c.EventQueues, c.EventTap, c.Messaging, c.ExchangeClient)
 }
 
 func (con *Config) String() string {
@@ -526,7 +978,9 @@ func (con *Config) String() string {
 		", TrustSystemCACerts: %v"+
 		", CACertsPath: %v"+
 		", ExchangeURL: %v"+
+		", ExchangeURLs: %v"+
 		", AgbotURL: %v"+
+		", AgbotURLs: %v"+
 		", DefaultHTTPClientTimeoutS: %v"+
 		", HTTPIdleConnectionTimeout: %v"+
 		", PolicyPath: %v"+
@@ -550,16 +1004,21 @@ func (con *Config) String() string {
 		", NodeCheckIntervalS: %v"+
 		", FileSyncService: {%v}"+
 		", InitialPollingBuffer: {%v}"+
+		", MessageKeyRotationIntervalH: %v"+
+		", MessageKeyGracePeriodS: %v"+
+		", EventLog: {%v}"+
+		", EventJournal: {%v}"+
+		", APIAuth: {%v}"+
 		", BlockchainAccountId: %v"+
 		", BlockchainDirectoryAddress %v",
 		con.ServiceStorage, con.APIListen, con.DBPath, con.DockerEndpoint, con.DockerCredFilePath, con.DefaultCPUSet,
-		con.DefaultServiceRegistrationRAM, con.StaticWebContent, con.PublicKeyPath, con.TrustSystemCACerts, con.CACertsPath, con.ExchangeURL, con.AgbotURL,
+		con.DefaultServiceRegistrationRAM, con.StaticWebContent, con.PublicKeyPath, con.TrustSystemCACerts, con.CACertsPath, con.ExchangeURL, con.ExchangeURLs, con.AgbotURL, con.AgbotURLs,
 		con.DefaultHTTPClientTimeoutS, con.HTTPIdleConnectionTimeout, con.PolicyPath, con.ExchangeHeartbeat, con.AgreementTimeoutS,
 		con.DVPrefix, con.RegistrationDelayS, con.ExchangeMessageTTL, con.ExchangeMessageDynamicPoll, con.ExchangeMessagePollInterval,
 		con.ExchangeMessagePollMaxInterval, con.ExchangeMessagePollIncrement, con.UserPublicKeyPath, con.ReportDeviceStatus,
 		con.TrustCertUpdatesFromOrg, con.TrustDockerAuthFromOrg, con.ServiceUpgradeCheckIntervalS, con.MultipleAnaxInstances,
 		con.DefaultServiceRetryCount, con.DefaultServiceRetryDuration, con.NodeCheckIntervalS, con.FileSyncService.String(),
-		con.InitialPollingBuffer, con.BlockchainAccountId, con.BlockchainDirectoryAddress)
+		con.InitialPollingBuffer, con.MessageKeyRotationIntervalH, con.MessageKeyGracePeriodS, con.EventLog.String(), con.EventJournal, con.APIAuth, con.BlockchainAccountId, con.BlockchainDirectoryAddress)
 }
 
 func (agc *AGConfig) String() string {
@@ -580,6 +1039,7 @@ func (agc *AGConfig) String() string {
 		", ProcessGovernanceIntervalS: %v"+
 		", IgnoreContractWithAttribs: %v"+
 		", ExchangeURL: %v"+
+		", ExchangeURLs: %v"+
 		", ExchangeHeartbeat: %v"+
 		", ExchangeId: %v"+
 		", ExchangeToken: %v"+
@@ -587,6 +1047,8 @@ func (agc *AGConfig) String() string {
 		", ActiveDeviceTimeoutS: %v"+
 		", ExchangeMessageTTL: %v"+
 		", MessageKeyPath: %v"+
+		", MessageKeyRotationIntervalH: %v"+
+		", MessageKeyGracePeriodS: %v"+
 		", DefaultWorkloadPW: %v"+
 		", APIListen: %v"+
 		", SecureAPIListenHost: %v"+
@@ -594,8 +1056,10 @@ func (agc *AGConfig) String() string {
 		", SecureAPIServerCert: %v"+
 		", SecureAPIServerkey: %v"+
 		", PurgeArchivedAgreementHours: %v"+
+		", EventLogMaxAgeH: %v"+
 		", CheckUpdatedPolicyS: %v"+
 		", CSSURL: %v"+
+		", CSSURLs: %v"+
 		", CSSSSLCert: %v"+
 		", CSSDestinationBatchSize: %v"+
 		", AgreementBatchSize: %v"+
@@ -610,16 +1074,40 @@ func (agc *AGConfig) String() string {
 		", Vault: {%v}"+
 		", SecretsUpdateCheckInterval: %v"+
 		", SecretsUpdateCheckMaxInterval: %v"+
-		", SecretsUpdateCheckIncrement: %v",
+		", SecretsUpdateCheckIncrement: %v"+
+		", Exchanges: %v",
 		agc.TxLostDelayTolerationSeconds, agc.AgreementWorkers, agc.DBPath, agc.Postgresql.String(),
 		agc.PartitionStale, agc.ProtocolTimeoutS, agc.AgreementTimeoutS, agc.NoDataIntervalS, agc.ActiveAgreementsURL,
 		agc.ActiveAgreementsUser, mask, agc.PolicyPath, agc.NewContractIntervalS, agc.ProcessGovernanceIntervalS,
-		agc.IgnoreContractWithAttribs, agc.ExchangeURL, agc.ExchangeHeartbeat, agc.ExchangeId,
-		mask, agc.DVPrefix, agc.ActiveDeviceTimeoutS, agc.ExchangeMessageTTL, agc.MessageKeyPath, mask, agc.APIListen,
+		agc.IgnoreContractWithAttribs, agc.ExchangeURL, agc.ExchangeURLs, agc.ExchangeHeartbeat, agc.ExchangeId,
+		mask, agc.DVPrefix, agc.ActiveDeviceTimeoutS, agc.ExchangeMessageTTL, agc.MessageKeyPath, agc.MessageKeyRotationIntervalH, agc.MessageKeyGracePeriodS, mask, agc.APIListen,
 		agc.SecureAPIListenHost, agc.SecureAPIListenPort, agc.SecureAPIServerCert, agc.SecureAPIServerKey,
-		agc.PurgeArchivedAgreementHours, agc.CheckUpdatedPolicyS, agc.CSSURL, agc.CSSSSLCert, agc.CSSDestinationBatchSize, agc.AgreementBatchSize,
+		agc.PurgeArchivedAgreementHours, agc.EventLogMaxAgeH, agc.CheckUpdatedPolicyS, agc.CSSURL, agc.CSSURLs, agc.CSSSSLCert, agc.CSSDestinationBatchSize, agc.AgreementBatchSize,
 		agc.AgreementQueueSize, agc.MessageQueueScale, agc.QueueHistorySize, agc.FullRescanS, agc.ErrRescanS, agc.MaxExchangeChanges,
-		agc.RetryLookBackWindow, agc.PolicySearchOrder, agc.Vault, agc.SecretsUpdateCheckInterval, agc.SecretsUpdateCheckMaxInterval, agc.SecretsUpdateCheckIncrement)
+		agc.RetryLookBackWindow, agc.PolicySearchOrder, agc.Vault, agc.SecretsUpdateCheckInterval, agc.SecretsUpdateCheckMaxInterval, agc.SecretsUpdateCheckIncrement, agc.Exchanges)
+}
+
+// Each additional exchange needs its own name and agbot identity, and an org can only live in one exchange.
+func (agc *AGConfig) validateExchanges() error {
+	names := make(map[string]bool)
+	orgs := make(map[string]string)
+	for _, ex := range agc.Exchanges {
+		if ex.Name == "" || strings.Contains(ex.Name, "/") {
+			return fmt.Errorf("AgreementBot.Exchanges: the exchange at %v must have a name that does not contain '/'", ex.ExchangeURL)
+		} else if names[ex.Name] {
+			return fmt.Errorf("AgreementBot.Exchanges: exchange name %v is used more than once", ex.Name)
+		} else if ex.ExchangeId == "" || ex.ExchangeToken == "" || len(ex.Orgs) == 0 {
+			return fmt.Errorf("AgreementBot.Exchanges: exchange %v must have an ExchangeId, an ExchangeToken and at least one org", ex.Name)
+		}
+		names[ex.Name] = true
+		for _, org := range ex.Orgs {
+			if other, ok := orgs[org]; ok {
+				return fmt.Errorf("AgreementBot.Exchanges: org %v is in both exchange %v and exchange %v", org, other, ex.Name)
+			}
+			orgs[org] = ex.Name
+		}
+	}
+	return nil
 }
 
 func (c *VaultConfig) String() string {
@@ -72,3 +72,27 @@ func Test_enrichFromEnvvars_success(t *testing.T) {
 	}
 
 }
+
+func Test_validateExchanges(t *testing.T) {
+	west := AgbotExchange{Name: "west", ExchangeURL: "https://west/v1/", ExchangeId: "west/ag1", ExchangeToken: "token", Orgs: []string{"west"}}
+
+	agc := AGConfig{Exchanges: []AgbotExchange{west}}
+	if err := agc.validateExchanges(); err != nil {
+		t.Errorf("expecting a valid exchange, have error %v", err)
+	}
+
+	noName := west
+	noName.Name = ""
+	badName := west
+	badName.Name = "a/b"
+	noOrgs := west
+	noOrgs.Orgs = nil
+	east := west
+	east.Name = "east"
+	for _, exchanges := range [][]AgbotExchange{{noName}, {badName}, {noOrgs}, {west, west}, {west, east}} {
+		agc := AGConfig{Exchanges: exchanges}
+		if err := agc.validateExchanges(); err == nil {
+			t.Errorf("expecting an error for exchanges %v", exchanges)
+		}
+	}
+}
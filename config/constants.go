@@ -96,6 +96,80 @@ const EdgeMaxAgreementPrelaunchTimeM_DEFAULT = 10
 // The Default interval at which the agbot verifies that its message key is present in the exchange.
 const AgbotMessageKeyCheck_DEFAULT = 60
 
+// The Default time a replaced message key can still be used to decrypt messages.
+const MessageKeyGracePeriodS_DEFAULT = 3600
+
+// The highest message envelope version this runtime supports.
+const MessageEnvelopeVersion_MAX = 2
+
+// The Default age after which a message is rejected as a possible replay.
+const MaxMessageAgeS_DEFAULT = 86400
+
+// The Default rate limits for calls to each class of exchange resource.
+const ExchangeRateLimitPerS_AGENT_DEFAULT = 10
+const ExchangeRateLimitPerS_AGBOT_DEFAULT = 100
+
+// The Default number of consecutive failures that open the circuit to an exchange host.
+const ExchangeBreakerFailureThreshold_DEFAULT = 5
+
+// The Default time an open circuit waits before a probe call is let through.
+const ExchangeBreakerOpenS_DEFAULT = 30
+
+// The Default longest wait between retries of a failed exchange call.
+const ExchangeBackoffMaxS_DEFAULT = 300
+
+// The Default number of consecutive failures of a management hub endpoint that switch to the next endpoint.
+const EndpointFailoverThreshold_DEFAULT = 3
+
+// The Default time a failed management hub endpoint is not used, and between probes of the preferred endpoints.
+const EndpointFailbackS_DEFAULT = 300
+
+// The Default size at which the management hub traffic record file is rotated.
+const TrafficRecordMaxSizeMB_DEFAULT = 10
+
+// The Default number of rotated management hub traffic record files that are kept.
+const TrafficRecordMaxFiles_DEFAULT = 3
+
+// The Default age at which the agent's event logs are deleted, 90 days.
+const EventLogMaxAgeH_DEFAULT = 2160
+
+// The Default age at which the agbot's event logs are deleted, 30 days.
+const AgbotEventLogMaxAgeH_DEFAULT = 720
+
+// The Default number of event logs kept by the agent.
+const EventLogMaxCount_DEFAULT = 20000
+
+// The Default space that the agent's event logs can take in its database.
+const EventLogMaxSizeMB_DEFAULT = 50
+
+// The Default time between prunes of the agent's event logs.
+const EventLogPruneIntervalS_DEFAULT = 3600
+
+// The Default free space in the agent's database that makes it compacted when the agent starts.
+const DBCompactThresholdMB_DEFAULT = 20
+
+// The Default size at which an event log file sink is rotated.
+const EventLogSinkMaxSizeMB_DEFAULT = 10
+
+// The Default number of rotated event log files that are kept.
+const EventLogSinkMaxFiles_DEFAULT = 3
+
+// The Default number of event logs held for a sink that cannot be reached.
+const EventLogSinkBufferSize_DEFAULT = 1000
+
+// The Default number of events queued for each worker.
+const EventQueueCapacity_DEFAULT = 200
+
+// The policies for a worker's event queue when it is full. Block holds up the delivery of events to every worker until
+// there is room, drop-oldest drops the oldest queued event, and coalesce replaces the queued event with the same key,
+// blocking if there is none.
+const EventQueueOverflowBlock = "block"
+const EventQueueOverflowDropOldest = "drop-oldest"
+const EventQueueOverflowCoalesce = "coalesce"
+
+// The Default age after which a journaled event is no longer replayed, 1 day.
+const EventJournalMaxAgeH_DEFAULT = 24
+
 // The Default anax API port number
 const AnaxAPIPortDefault = "8510"
 
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/metrics"
+)
+
+// A management hub service, e.g. the exchange, can be reached through more than one endpoint, such as regional replicas
+// behind different load balancers. The rest of anax builds its URLs from the first (preferred) endpoint. The HTTP
+// clients from the HTTPClientFactory send those requests to the endpoint in use, the active endpoint.
+//
+// The active endpoint is sticky, it is used until it fails a number of times in a row. Then the next endpoint that has
+// not failed recently becomes active. The endpoints that are preferred over the active one are probed from time to
+// time, and the most preferred healthy endpoint becomes active again.
+
+const endpointProbeTimeoutS = 10
+
+var endpointSwitches = metrics.NewCounterVec("anax_endpoint_switches_total",
+	"Changes of the active endpoint of a management hub service, by service (exchange, css or agbot).", "service")
+
+type Endpoints struct {
+	Name      string
+	urls      []string
+	threshold int
+	failback  time.Duration
+	probe     func(url string) bool
+
+	lock      sync.Mutex
+	active    int
+	failures  int         // Consecutive failures of the active endpoint.
+	downUntil []time.Time // An endpoint that failed is not used again until then.
+	checked   time.Time   // The last time the active endpoint changed or the preferred endpoints were probed.
+	probing   bool
+}
+
+// Returns nil if there are no endpoints.
+func NewEndpoints(name string, urls []string, threshold int, failbackS int) *Endpoints {
+	e := &Endpoints{
+		Name:      name,
+		threshold: threshold,
+		failback:  time.Duration(failbackS) * time.Second,
+		checked:   time.Now(),
+	}
+	for _, u := range urls {
+		if u = strings.TrimRight(u, "/"); u != "" {
+			e.urls = append(e.urls, u)
+		}
+	}
+	if len(e.urls) == 0 {
+		return nil
+	}
+	e.downUntil = make([]time.Time, len(e.urls))
+	return e
+}
+
+func (e *Endpoints) String() string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return fmt.Sprintf("Name: %v, URLs: %v, Active: %v, Failures: %v", e.Name, e.urls, e.urls[e.active], e.failures)
+}
+
+// Returns the endpoint in use.
+func (e *Endpoints) Active() string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.urls[e.active]
+}
+
+// Returns the index of the endpoint that a URL belongs to, or -1, and the rest of the URL. When the endpoint URLs
+// overlap, the URL belongs to the endpoint with the longest matching prefix.
+func (e *Endpoints) match(u string) (int, string) {
+	matched, matchedRest := -1, ""
+	for ix, prefix := range e.urls {
+		if rest := strings.TrimPrefix(u, prefix); rest != u && (rest == "" || rest[0] == '/' || rest[0] == '?') {
+			if matched == -1 || len(prefix) > len(e.urls[matched]) {
+				matched, matchedRest = ix, rest
+			}
+		}
+	}
+	return matched, matchedRest
+}
+
+// Rewrite a URL of any of the endpoints to use the active endpoint. Returns the rewritten URL, the index of the active
+// endpoint and true, or false if the URL does not belong to these endpoints.
+func (e *Endpoints) resolve(u string) (string, int, bool) {
+	ix, rest := e.match(u)
+	if ix == -1 {
+		return u, -1, false
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	// Look for a preferred endpoint that is healthy again.
+	if e.active != 0 && e.probe != nil && !e.probing && time.Since(e.checked) >= e.failback {
+		e.probing = true
+		go e.probePreferred(e.active)
+	}
+	return e.urls[e.active] + rest, e.active, true
+}
+
+// Record the outcome of a request sent to an endpoint. Requests that were sent before the active endpoint changed are
+// ignored.
+func (e *Endpoints) record(ix int, healthy bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if ix != e.active || len(e.urls) == 1 {
+		return
+	} else if healthy {
+		e.failures = 0
+		return
+	}
+
+	e.failures++
+	if e.failures < e.threshold {
+		return
+	}
+
+	// Use the next endpoint that has not failed recently. If they all have, just use the next one.
+	now := time.Now()
+	e.downUntil[e.active] = now.Add(e.failback)
+	next := (e.active + 1) % len(e.urls)
+	for i := 1; i < len(e.urls); i++ {
+		if cand := (e.active + i) % len(e.urls); now.After(e.downUntil[cand]) {
+			next = cand
+			break
+		}
+	}
+	glog.Warningf("%v endpoint %v failed %v times, switching to %v", e.Name, e.urls[e.active], e.failures, e.urls[next])
+	e.setActive(next, now)
+}
+
+// The caller must hold the lock.
+func (e *Endpoints) setActive(ix int, now time.Time) {
+	e.active = ix
+	e.failures = 0
+	e.checked = now
+	endpointSwitches.Inc(e.Name)
+}
+
+// Probe the endpoints that are preferred over the active one, in order, and switch to the first healthy one.
+func (e *Endpoints) probePreferred(active int) {
+	healthy := -1
+	for ix := 0; ix < active; ix++ {
+		if e.probe(e.urls[ix]) {
+			healthy = ix
+			break
+		}
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.probing = false
+	now := time.Now()
+	if healthy != -1 && e.active == active {
+		glog.Infof("%v endpoint %v is healthy, switching back from %v", e.Name, e.urls[healthy], e.urls[active])
+		e.downUntil[healthy] = time.Time{}
+		e.setActive(healthy, now)
+	} else {
+		e.checked = now
+	}
+}
+
+// An HTTP transport that sends the requests for a set of endpoints to their active endpoint and keeps track of the
+// health of the endpoints.
+type endpointTransport struct {
+	base      http.RoundTripper
+	endpoints []*Endpoints
+}
+
+func newEndpointTransport(base http.RoundTripper, endpoints []*Endpoints) *endpointTransport {
+	t := &endpointTransport{base: base, endpoints: endpoints}
+	for _, e := range endpoints {
+		e.probe = t.probe
+	}
+	return t
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, e := range t.endpoints {
+		resolved, ix, ok := e.resolve(req.URL.String())
+		if !ok {
+			continue
+		}
+
+		if resolved != req.URL.String() {
+			u, err := url.Parse(resolved)
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.URL = u
+			req.Host = u.Host
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		e.record(ix, err == nil && resp.StatusCode != http.StatusBadGateway && resp.StatusCode != http.StatusServiceUnavailable && resp.StatusCode != http.StatusGatewayTimeout)
+		return resp, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Returns the URL that a request for the given URL is sent to.
+func (t *endpointTransport) ResolveURL(u string) string {
+	for _, e := range t.endpoints {
+		if resolved, _, ok := e.resolve(u); ok {
+			return resolved
+		}
+	}
+	return u
+}
+
+// An endpoint is healthy if it answers a request, with anything but a server error.
+func (t *endpointTransport) probe(u string) bool {
+	client := &http.Client{Transport: t.base, Timeout: endpointProbeTimeoutS * time.Second}
+	resp, err := client.Get(u + "/")
+	if err != nil {
+		glog.V(3).Infof("probe of endpoint %v failed, error: %v", u, err)
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// Returns the active endpoint of each service that has endpoints, e.g. exchange, css or agbot.
+func (h *HTTPClientFactory) ActiveEndpoints() map[string]string {
+	if h == nil || h.NewHTTPClient == nil {
+		return nil
+	} else if t, ok := h.NewHTTPClient(nil).Transport.(*endpointTransport); !ok || len(t.endpoints) == 0 {
+		return nil
+	} else {
+		active := make(map[string]string)
+		for _, e := range t.endpoints {
+			active[e.Name] = e.Active()
+		}
+		return active
+	}
+}
+
+// Split a comma separated list of URLs, e.g. from an environment variable, into the preferred URL and the alternates.
+func splitEndpoints(value string) (string, []string) {
+	alternates := make([]string, 0)
+	for _, u := range strings.Split(value, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			alternates = append(alternates, u)
+		}
+	}
+	if len(alternates) == 0 {
+		return "", nil
+	}
+	return alternates[0], alternates[1:]
+}
+
+// Returns the endpoints of the management hub services that this process uses.
+func newEndpointsFromConfig(hConfig HorizonConfig, isAgbot bool) []*Endpoints {
+	threshold := hConfig.ExchangeClient.GetFailoverThreshold()
+	failback := hConfig.ExchangeClient.GetFailbackS()
+
+	sets := make(map[string][]string)
+	if isAgbot {
+		sets["exchange"] = append([]string{hConfig.AgreementBot.ExchangeURL}, hConfig.AgreementBot.ExchangeURLs...)
+		sets["css"] = append([]string{hConfig.AgreementBot.CSSURL}, hConfig.AgreementBot.CSSURLs...)
+	} else {
+		sets["exchange"] = append([]string{hConfig.Edge.ExchangeURL}, hConfig.Edge.ExchangeURLs...)
+		sets["css"] = append([]string{hConfig.Edge.FileSyncService.CSSURL}, hConfig.Edge.FileSyncService.CSSURLs...)
+		sets["agbot"] = append([]string{hConfig.Edge.AgbotURL}, hConfig.Edge.AgbotURLs...)
+	}
+
+	names := make([]string, 0, len(sets))
+	for name := range sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	endpoints := make([]*Endpoints, 0)
+	for _, name := range names {
+		// The preferred URL has to be configured, the alternates are only used in its place.
+		if urls := sets[name]; urls[0] == "" {
+			continue
+		} else if e := NewEndpoints(name, urls, threshold, failback); e != nil {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
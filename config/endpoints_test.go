@@ -0,0 +1,137 @@
+//go:build unit
+// +build unit
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A fake management hub endpoint that can be taken down.
+type fakeEndpoint struct {
+	down     atomic.Bool
+	requests atomic.Int32
+}
+
+func (f *fakeEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	if f.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+func Test_EndpointFailover(t *testing.T) {
+	primary, secondary := &fakeEndpoint{}, &fakeEndpoint{}
+	ps, ss := httptest.NewServer(primary), httptest.NewServer(secondary)
+	defer ps.Close()
+	defer ss.Close()
+
+	e := NewEndpoints("exchange", []string{ps.URL + "/v1/", ss.URL + "/v1"}, 2, 0)
+	client := &http.Client{Transport: newEndpointTransport(http.DefaultTransport, []*Endpoints{e})}
+	get := func() int {
+		resp, err := client.Get(ps.URL + "/v1/orgs/myorg")
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The active endpoint is used until it fails threshold times in a row.
+	primary.down.Store(true)
+	if get() != http.StatusServiceUnavailable || e.Active() != ps.URL+"/v1" {
+		t.Errorf("expecting the primary endpoint to stay active after 1 failure, active is %v", e.Active())
+	}
+	if get(); e.Active() != ss.URL+"/v1" {
+		t.Errorf("expecting the secondary endpoint to be active, active is %v", e.Active())
+	}
+	if get() != http.StatusOK || secondary.requests.Load() != 1 {
+		t.Errorf("expecting the request to be sent to the secondary endpoint")
+	}
+
+	// Unrelated URLs are not rewritten.
+	resp, err := client.Get(ps.URL + "/v1x/orgs")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if secondary.requests.Load() != 1 {
+		t.Errorf("expecting unrelated requests to go to the primary endpoint")
+	}
+
+	// Once the primary endpoint is healthy again, a probe switches back to it.
+	primary.down.Store(false)
+	get()
+	for i := 0; i < 50 && e.Active() != ps.URL+"/v1"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if e.Active() != ps.URL+"/v1" {
+		t.Errorf("expecting the primary endpoint to be active again, active is %v", e.Active())
+	}
+}
+
+func Test_EndpointFailover_sticky(t *testing.T) {
+	e := NewEndpoints("css", []string{"https://a/css", "https://b/css", "https://c/css"}, 1, 300)
+	e.probe = func(string) bool { return true }
+
+	// A failure of an endpoint that is no longer active does not move the active endpoint again.
+	u, ix, _ := e.resolve("https://a/css/api/v1/objects")
+	if u != "https://a/css/api/v1/objects" {
+		t.Errorf("unexpected resolved URL %v", u)
+	}
+	e.record(ix, false)
+	e.record(ix, false)
+	if e.Active() != "https://b/css" {
+		t.Errorf("expecting b to be active, active is %v", e.Active())
+	}
+
+	// The endpoint that failed recently is skipped, and the preferred endpoints are not probed before the failback time.
+	_, ix, _ = e.resolve("https://a/css/api/v1/objects")
+	e.record(ix, false)
+	if e.Active() != "https://c/css" {
+		t.Errorf("expecting c to be active, active is %v", e.Active())
+	} else if u, _, _ := e.resolve("https://b/css?x=1"); u != "https://c/css?x=1" {
+		t.Errorf("unexpected resolved URL %v", u)
+	} else if e.probing {
+		t.Errorf("preferred endpoints should not be probed before the failback time")
+	}
+}
+
+// A URL that matches nested endpoint URLs belongs to the endpoint with the longest prefix.
+func Test_EndpointMatch_nested(t *testing.T) {
+	e := NewEndpoints("css", []string{"https://a", "https://a/css", "https://b/css"}, 1, 0)
+
+	if ix, rest := e.match("https://a/css/api/v1/objects"); ix != 1 || rest != "/api/v1/objects" {
+		t.Errorf("expecting https://a/css to match, matched %v with %v", ix, rest)
+	} else if ix, rest := e.match("https://a/cssx/objects"); ix != 0 || rest != "/cssx/objects" {
+		t.Errorf("expecting https://a to match, matched %v with %v", ix, rest)
+	} else if ix, rest := e.match("https://a"); ix != 0 || rest != "" {
+		t.Errorf("expecting https://a to match, matched %v with %v", ix, rest)
+	} else if ix, _ := e.match("https://c/css"); ix != -1 {
+		t.Errorf("expecting no match, matched %v", ix)
+	}
+
+	// The order of the endpoints does not matter.
+	e = NewEndpoints("css", []string{"https://b/css", "https://a/css", "https://a"}, 1, 0)
+	if ix, rest := e.match("https://a/css?x=1"); ix != 1 || rest != "?x=1" {
+		t.Errorf("expecting https://a/css to match, matched %v with %v", ix, rest)
+	}
+}
+
+func Test_splitEndpoints(t *testing.T) {
+	if u, alt := splitEndpoints(""); u != "" || alt != nil {
+		t.Errorf("expecting no endpoints, have %v %v", u, alt)
+	}
+	if u, alt := splitEndpoints("https://a/v1"); u != "https://a/v1" || len(alt) != 0 {
+		t.Errorf("expecting one endpoint, have %v %v", u, alt)
+	}
+	if u, alt := splitEndpoints(" https://a/v1 , https://b/v1,,"); u != "https://a/v1" || len(alt) != 1 || alt[0] != "https://b/v1" {
+		t.Errorf("expecting two endpoints, have %v %v", u, alt)
+	}
+}
@@ -0,0 +1,451 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// The HTTP clients from the HTTPClientFactory can record the requests they send to the management hub, and the
+// responses, to a file. The records are JSON, one per line, with the credentials and other secrets redacted. The file
+// is rotated when it reaches its maximum size. A recording can be replayed, the clients then answer each request with
+// the response that was recorded for it instead of sending it, so that the decisions an agent or agbot made in the
+// field can be reproduced locally.
+
+// Bodies larger than this, or that are not JSON or text, are not recorded.
+const maxRecordedBodySize = 1024 * 1024
+
+const redacted = "********"
+
+// The response headers that are recorded, the others are left out because they can carry credentials.
+var recordedHeaders = []string{"Content-Type", "Etag", "Last-Modified", "Retry-After"}
+
+// The request headers that carry credentials, in canonical form. The headers with a secret name are redacted too.
+var secretHeaders = map[string]bool{"Authorization": true, "Proxy-Authorization": true, "Cookie": true}
+
+// A request and its response, or the transport error it failed with.
+type trafficRecord struct {
+	Seq                uint64            `json:"seq"`
+	Time               string            `json:"time"`
+	DurationMs         int64             `json:"durationMs"`
+	Method             string            `json:"method"`
+	URL                string            `json:"url"`
+	User               string            `json:"user,omitempty"`
+	RequestHeader      map[string]string `json:"requestHeader,omitempty"`
+	RequestBody        string            `json:"requestBody,omitempty"`
+	RequestBodyOmitted bool              `json:"requestBodyOmitted,omitempty"` // The request body was not recorded.
+	Status             int               `json:"status,omitempty"`
+	Header             map[string]string `json:"header,omitempty"`
+	ResponseBody       string            `json:"responseBody,omitempty"`
+	BodyOmitted        bool              `json:"bodyOmitted,omitempty"` // The response body was not recorded.
+	Error              string            `json:"error,omitempty"`
+}
+
+// Returns the key that a replayed request is matched with a recorded one by. The host is left out, so that a
+// recording can be replayed with a different management hub configuration.
+func (r *trafficRecord) key() string {
+	if u, err := url.Parse(r.URL); err == nil {
+		return r.Method + " " + u.RequestURI()
+	}
+	return r.Method + " " + r.URL
+}
+
+// Writes the records to a file, rotating it when it is full.
+type trafficRecorder struct {
+	lock     sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	seq      uint64
+}
+
+func newTrafficRecorder(path string, maxSizeMB int, maxFiles int) (*trafficRecorder, error) {
+	r := &trafficRecorder{path: path, maxSize: int64(maxSizeMB) * 1024 * 1024, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	glog.Warningf("Recording the management hub traffic to %v", path)
+	return r, nil
+}
+
+// The caller must hold the lock, or be the constructor.
+func (r *trafficRecorder) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open traffic record file %v, error: %v", r.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("unable to stat traffic record file %v, error: %v", r.path, err)
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// Rename the file to file.1, file.1 to file.2 and so on, dropping the oldest, and start a new file. The caller must
+// hold the lock.
+func (r *trafficRecorder) rotate() error {
+	r.file.Close()
+	os.Remove(fmt.Sprintf("%v.%v", r.path, r.maxFiles))
+	for ix := r.maxFiles - 1; ix >= 1; ix-- {
+		os.Rename(fmt.Sprintf("%v.%v", r.path, ix), fmt.Sprintf("%v.%v", r.path, ix+1))
+	}
+	if r.maxFiles > 0 {
+		os.Rename(r.path, r.path+".1")
+	} else {
+		os.Remove(r.path)
+	}
+	return r.open()
+}
+
+func (r *trafficRecorder) write(rec *trafficRecord) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.seq++
+	rec.Seq = r.seq
+	b, err := json.Marshal(rec)
+	if err != nil {
+		glog.Errorf("unable to serialize traffic record for %v %v, error: %v", rec.Method, rec.URL, err)
+		return
+	}
+	b = append(b, '\n')
+
+	if r.size > 0 && r.size+int64(len(b)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			glog.Errorf("unable to rotate traffic record file, error: %v", err)
+			return
+		}
+	}
+	if n, err := r.file.Write(b); err != nil {
+		glog.Errorf("unable to write traffic record to %v, error: %v", r.path, err)
+	} else {
+		r.size += int64(n)
+	}
+}
+
+// An HTTP transport that records the requests and responses that go through it.
+type recordingTransport struct {
+	base     http.RoundTripper
+	recorder *trafficRecorder
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &trafficRecord{
+		Time:   time.Now().UTC().Format(time.RFC3339Nano),
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+	}
+	if user, _, ok := req.BasicAuth(); ok {
+		rec.User = user
+	}
+	if len(req.Header) != 0 {
+		rec.RequestHeader = redactHeader(req.Header)
+	}
+
+	// The request body can only be read once, so it is replaced with a copy. A large body is not recorded, the same as
+	// a large response body.
+	if req.Body != nil && req.Body != http.NoBody && isTextual(req.Header.Get("Content-Type")) {
+		if req.ContentLength > maxRecordedBodySize {
+			rec.RequestBodyOmitted = true
+		} else {
+			body, err := io.ReadAll(io.LimitReader(req.Body, maxRecordedBodySize+1))
+			if err != nil {
+				req.Body.Close()
+				return nil, err
+			}
+			reqBody := req.Body
+			req = req.Clone(req.Context())
+			if len(body) > maxRecordedBodySize {
+				// Send the part that was read and the rest of the body, as if it had not been read.
+				rec.RequestBodyOmitted = true
+				req.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(body), reqBody), reqBody}
+			} else {
+				reqBody.Close()
+				req.Body = io.NopCloser(bytes.NewReader(body))
+				rec.RequestBody = redactBody(body)
+			}
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	rec.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		rec.Error = err.Error()
+		t.recorder.write(rec)
+		return resp, err
+	}
+
+	rec.Status = resp.StatusCode
+	rec.Header = make(map[string]string)
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			rec.Header[h] = v
+		}
+	}
+
+	if !isTextual(resp.Header.Get("Content-Type")) || resp.ContentLength > maxRecordedBodySize {
+		rec.BodyOmitted = true
+	} else {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxRecordedBodySize+1))
+		if err != nil || len(body) > maxRecordedBodySize {
+			// Give the caller the part that was read and the rest of the body, as if it had not been read.
+			rec.BodyOmitted = true
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		} else {
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			rec.ResponseBody = redactBody(body)
+		}
+	}
+	t.recorder.write(rec)
+	return resp, nil
+}
+
+// Returns true for the content types whose bodies are recorded. The exchange does not always set a content type.
+func isTextual(contentType string) bool {
+	return contentType == "" || strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/")
+}
+
+// Returns true if a JSON field, query parameter or header holds a credential. The public keys that the nodes and
+// agbots exchange messages with are not secret, and are kept so that a replayed agbot can still make agreements.
+func isSecretField(name string) bool {
+	lower := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+	if strings.HasSuffix(lower, "publickey") || strings.HasSuffix(lower, "pubkey") {
+		return false
+	} else if strings.Contains(lower, "secret") {
+		return true
+	}
+	for _, suffix := range []string{"token", "password", "passwd", "pw", "key"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the URL with the password of its user info and the values of its secret query parameters redacted. The
+// query is only rewritten when something is redacted, so that the other URLs are recorded as they were sent.
+func redactURL(u *url.URL) string {
+	out := *u
+	if _, ok := u.User.Password(); ok {
+		out.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	query := u.Query()
+	changed := false
+	for name, values := range query {
+		if isSecretField(name) {
+			for ix := range values {
+				values[ix] = redacted
+			}
+			changed = true
+		}
+	}
+	if changed {
+		out.RawQuery = query.Encode()
+	}
+	return out.String()
+}
+
+// Returns the request headers, with the values of the ones that carry credentials redacted.
+func redactHeader(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		if secretHeaders[http.CanonicalHeaderKey(name)] || isSecretField(name) {
+			out[name] = redacted
+		} else {
+			out[name] = strings.Join(values, ", ")
+		}
+	}
+	return out
+}
+
+// Returns a body with the values of the fields that hold credentials redacted. A body that is not JSON is returned
+// unchanged.
+func redactBody(body []byte) string {
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return string(body)
+	}
+	if b, err := json.Marshal(redactValue(value)); err == nil {
+		return string(b)
+	}
+	return string(body)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if str, ok := field.(string); ok && str != "" && isSecretField(name) {
+				v[name] = redacted
+			} else if inputs, ok := field.([]interface{}); ok && name == "inputs" {
+				v[name] = redactInputs(inputs)
+			} else {
+				v[name] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for ix := range v {
+			v[ix] = redactValue(v[ix])
+		}
+	}
+	// Maps and slices are redacted in place.
+	return value
+}
+
+// The user input variables of a service are a list of names and values, in the userInput of nodes, patterns and
+// deployment policies. Any of the values can be a credential, whatever the name of the variable.
+func redactInputs(inputs []interface{}) []interface{} {
+	for _, input := range inputs {
+		if variable, ok := input.(map[string]interface{}); ok && variable["value"] != nil {
+			variable["value"] = redacted
+		}
+	}
+	return inputs
+}
+
+// An HTTP transport that answers each request with a recorded response, without sending it. The responses recorded
+// for a request are returned in the order they were recorded, and the last one is repeated once they are used up, so
+// that polling workers keep running. A request is matched by its method, path and query, or by its method and path if
+// there is no record with the same query. A request that was not recorded gets a 404.
+type replayTransport struct {
+	lock    sync.Mutex
+	records map[string][]*trafficRecord // Keyed by method, path and query.
+	byPath  map[string][]*trafficRecord // The records of requests with a query, keyed by method and path.
+	next    map[string]int              // The position of the next record to replay in each list.
+}
+
+// Read the records from a file and from its rotated files, oldest first.
+func newReplayTransport(path string, maxFiles int) (*replayTransport, error) {
+	files := make([]string, 0)
+	for ix := maxFiles; ix >= 1; ix-- {
+		if rotated := fmt.Sprintf("%v.%v", path, ix); fileExists(rotated) {
+			files = append(files, rotated)
+		}
+	}
+	files = append(files, path)
+
+	t := &replayTransport{
+		records: make(map[string][]*trafficRecord),
+		byPath:  make(map[string][]*trafficRecord),
+		next:    make(map[string]int),
+	}
+	count := 0
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open traffic record file %v, error: %v", file, err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 4*maxRecordedBodySize)
+		for line := 1; scanner.Scan(); line++ {
+			rec := new(trafficRecord)
+			if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("unable to read traffic record at %v:%v, error: %v", file, line, err)
+			}
+			t.add(rec)
+			count++
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read traffic record file %v, error: %v", file, err)
+		}
+	}
+	glog.Warningf("Replaying %v recorded management hub requests from %v, requests are not sent to the management hub", count, path)
+	return t, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (t *replayTransport) add(rec *trafficRecord) {
+	key := rec.key()
+	t.records[key] = append(t.records[key], rec)
+	if ix := strings.Index(key, "?"); ix != -1 {
+		t.byPath[key[:ix]] = append(t.byPath[key[:ix]], rec)
+	}
+}
+
+// Returns the next recorded response for a request, nil if there is none.
+func (t *replayTransport) find(req *http.Request) *trafficRecord {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// The request is matched with the records by its redacted URL, as it was recorded.
+	key := (&trafficRecord{Method: req.Method, URL: redactURL(req.URL)}).key()
+	recs, next := t.records[key], key
+	if ix := strings.Index(key, "?"); len(recs) == 0 && ix != -1 {
+		recs, next = t.byPath[key[:ix]], "path "+key[:ix]
+	}
+	if len(recs) == 0 {
+		return nil
+	}
+
+	ix := t.next[next]
+	if ix < len(recs)-1 {
+		t.next[next] = ix + 1
+	}
+	return recs[ix]
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	rec := t.find(req)
+	if rec == nil {
+		glog.Warningf("no recorded response for %v %v", req.Method, req.URL)
+		return replayResponse(req, http.StatusNotFound, nil, `{"code":"not found","msg":"not recorded"}`), nil
+	} else if rec.Error != "" {
+		return nil, errors.New(rec.Error)
+	}
+	glog.V(5).Infof("replaying record %v for %v %v", rec.Seq, req.Method, req.URL)
+	return replayResponse(req, rec.Status, rec.Header, rec.ResponseBody), nil
+}
+
+func replayResponse(req *http.Request, status int, header map[string]string, body string) *http.Response {
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%v %v", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	for h, v := range header {
+		resp.Header.Set(h, v)
+	}
+	return resp
+}
@@ -0,0 +1,226 @@
+//go:build unit
+// +build unit
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func Test_TrafficRecordAndReplay(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		fmt.Fprintf(w, `{"count":%v,"nodes":{"myorg/n1":{"token":"nodesecret","name":"n1"}}}`, count)
+	}))
+	defer server.Close()
+
+	file := path.Join(t.TempDir(), "traffic.log")
+	recorder, err := newTrafficRecorder(file, 1, 2)
+	if err != nil {
+		t.Fatalf("unable to create recorder, error %v", err)
+	}
+	client := &http.Client{Transport: &recordingTransport{base: http.DefaultTransport, recorder: recorder}}
+
+	get := func(client *http.Client, u string) (int, string) {
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	// The caller gets the real response, the record has the credentials redacted.
+	put, _ := http.NewRequest(http.MethodPut, server.URL+"/v1/orgs/myorg/nodes/n1", strings.NewReader(`{"token":"nodesecret","pattern":""}`))
+	put.Header.Set("Content-Type", "application/json")
+	put.SetBasicAuth("myorg/n1", "nodesecret")
+	if resp, err := client.Do(put); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else {
+		resp.Body.Close()
+	}
+	if _, body := get(client, server.URL+"/v1/orgs/myorg/nodes/n1"); !strings.Contains(body, "nodesecret") {
+		t.Errorf("expecting the real response, have %v", body)
+	}
+	get(client, server.URL+"/v1/orgs/myorg/nodes/n1")
+	get(client, server.URL+"/v1/orgs/myorg/nodes/n1?attr=name")
+
+	if b, err := os.ReadFile(file); err != nil {
+		t.Errorf("unable to read record file, error %v", err)
+	} else if records := string(b); strings.Count(records, "\n") != 4 || strings.Contains(records, "nodesecret") || strings.Contains(records, "Set-Cookie") || !strings.Contains(records, `\"token\":\"********\"`) {
+		t.Errorf("expecting 4 redacted records, have %v", records)
+	}
+
+	// The responses are replayed in order and the last one is repeated, without sending the requests.
+	replay, err := newReplayTransport(file, 2)
+	if err != nil {
+		t.Fatalf("unable to read recording, error %v", err)
+	}
+	client = &http.Client{Transport: replay}
+	for ix, expected := range []int{2, 3, 3} {
+		if status, body := get(client, "http://elsewhere/v1/orgs/myorg/nodes/n1"); status != http.StatusOK || !strings.Contains(body, fmt.Sprintf(`"count":%v`, expected)) {
+			t.Errorf("expecting response %v to request %v, have %v %v", expected, ix, status, body)
+		}
+	}
+	if _, body := get(client, "http://elsewhere/v1/orgs/myorg/nodes/n1?attr=name"); !strings.Contains(body, `"count":4`) {
+		t.Errorf("expecting the response to the request with the same query, have %v", body)
+	}
+	if status, _ := get(client, "http://elsewhere/v1/orgs/myorg/nodes/n2"); status != http.StatusNotFound {
+		t.Errorf("expecting 404 for a request that was not recorded, have %v", status)
+	}
+	if count != 4 {
+		t.Errorf("expecting the replayed requests not to be sent, the server received %v", count)
+	}
+}
+
+func Test_TrafficRecordRotation(t *testing.T) {
+	file := path.Join(t.TempDir(), "traffic.log")
+	recorder, err := newTrafficRecorder(file, 1, 2)
+	if err != nil {
+		t.Fatalf("unable to create recorder, error %v", err)
+	}
+
+	// Each record is over a third of the maximum size, so only two fit in a file.
+	body := strings.Repeat("x", 400*1024)
+	for ix := 0; ix < 7; ix++ {
+		recorder.write(&trafficRecord{Method: http.MethodGet, URL: fmt.Sprintf("http://host/v1/%v", ix), Status: http.StatusOK, ResponseBody: body})
+	}
+
+	for _, f := range []string{file, file + ".1", file + ".2"} {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("expecting file %v, error %v", f, err)
+		}
+	}
+	if _, err := os.Stat(file + ".3"); err == nil {
+		t.Errorf("expecting only 2 rotated files")
+	}
+
+	// The replay reads the rotated files oldest first, the first two records written are gone.
+	if replay, err := newReplayTransport(file, 2); err != nil {
+		t.Errorf("unable to read recording, error %v", err)
+	} else if _, ok := replay.records["GET /v1/1"]; ok {
+		t.Errorf("expecting the oldest records to have been dropped")
+	} else if recs := replay.records["GET /v1/2"]; len(recs) != 1 || recs[0].Seq != 3 {
+		t.Errorf("expecting record 3 to be kept, have %v", recs)
+	}
+}
+
+func Test_TrafficRecordRedactInputs(t *testing.T) {
+	body := redactBody([]byte(`{"userInput":[{"serviceOrgid":"myorg","serviceUrl":"svc1","inputs":[{"name":"PASSWORD","value":"pw1"},{"name":"COUNT","value":3}]}],"properties":[{"name":"color","value":"blue"}]}`))
+	if strings.Contains(body, "pw1") || strings.Contains(body, `"value":3`) {
+		t.Errorf("expecting the user input values to be redacted, have %v", body)
+	} else if !strings.Contains(body, `"name":"PASSWORD"`) || !strings.Contains(body, `"value":"blue"`) {
+		t.Errorf("expecting the user input names and the properties to be kept, have %v", body)
+	}
+}
+
+func Test_TrafficRecordRedactFields(t *testing.T) {
+	body := redactBody([]byte(`{"clientSecret":"s1","secretValue":"s2","workloadPW":"s3","apiKey":"s4","privateKey":"s5","auth_token":"s6","publicKey":"pk","agbotPubKey":"apk","name":"n1"}`))
+	for _, secret := range []string{"s1", "s2", "s3", "s4", "s5", "s6"} {
+		if strings.Contains(body, `"`+secret+`"`) {
+			t.Errorf("expecting %v to be redacted, have %v", secret, body)
+		}
+	}
+	for _, kept := range []string{"pk", "apk", "n1"} {
+		if !strings.Contains(body, `"`+kept+`"`) {
+			t.Errorf("expecting %v to be kept, have %v", kept, body)
+		}
+	}
+}
+
+func Test_TrafficRecordRedactRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"token":%q}`, r.URL.Query().Get("token"))
+	}))
+	defer server.Close()
+
+	file := path.Join(t.TempDir(), "traffic.log")
+	recorder, err := newTrafficRecorder(file, 1, 2)
+	if err != nil {
+		t.Fatalf("unable to create recorder, error %v", err)
+	}
+	client := &http.Client{Transport: &recordingTransport{base: http.DefaultTransport, recorder: recorder}}
+
+	// The credentials in the headers, the user info and the query are redacted, the other values are kept.
+	u := strings.Replace(server.URL, "http://", "http://myorg%2Fn1:nodesecret@", 1) + "/v1/orgs/myorg/nodes/n1/msgs?token=querysecret&maxmsgs=10"
+	req, _ := http.NewRequest(http.MethodGet, u, nil)
+	req.Header.Set("Authorization", "Bearer headersecret")
+	req.Header.Set("Cookie", "session=cookiesecret")
+	req.Header.Set("X-Api-Key", "keysecret")
+	req.Header.Set("Accept", "application/json")
+	if resp, err := client.Do(req); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else {
+		resp.Body.Close()
+	}
+
+	if b, err := os.ReadFile(file); err != nil {
+		t.Errorf("unable to read record file, error %v", err)
+	} else if records := string(b); strings.Contains(records, "secret") {
+		t.Errorf("expecting the credentials to be redacted, have %v", records)
+	} else if !strings.Contains(records, "maxmsgs=10") || !strings.Contains(records, `"Accept":"application/json"`) || !strings.Contains(records, `"Authorization":"********"`) {
+		t.Errorf("expecting the other query parameters and headers to be kept, have %v", records)
+	}
+
+	// A request with credentials in its query is matched with its redacted record.
+	replay, err := newReplayTransport(file, 2)
+	if err != nil {
+		t.Fatalf("unable to read recording, error %v", err)
+	}
+	client = &http.Client{Transport: replay}
+	if resp, err := client.Get("http://elsewhere/v1/orgs/myorg/nodes/n1/msgs?token=othersecret&maxmsgs=10"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if resp.StatusCode != http.StatusOK {
+		t.Errorf("expecting the recorded response, have %v", resp.StatusCode)
+	} else {
+		resp.Body.Close()
+	}
+}
+
+func Test_TrafficRecordLargeRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"received":%v}`, len(b))
+	}))
+	defer server.Close()
+
+	file := path.Join(t.TempDir(), "traffic.log")
+	recorder, err := newTrafficRecorder(file, 1, 2)
+	if err != nil {
+		t.Fatalf("unable to create recorder, error %v", err)
+	}
+	client := &http.Client{Transport: &recordingTransport{base: http.DefaultTransport, recorder: recorder}}
+
+	// The whole body is sent whether or not its length is known up front, but it is not recorded.
+	body := strings.Repeat("x", maxRecordedBodySize+10)
+	for _, r := range []io.Reader{strings.NewReader(body), io.MultiReader(strings.NewReader(body))} {
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/v1/orgs/myorg/nodes/n1", r)
+		req.Header.Set("Content-Type", "application/json")
+		if resp, err := client.Do(req); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		} else if b, _ := io.ReadAll(resp.Body); string(b) != fmt.Sprintf(`{"received":%v}`, len(body)) {
+			t.Errorf("expecting the whole body to be sent, have %s", b)
+		} else {
+			resp.Body.Close()
+		}
+	}
+
+	if b, err := os.ReadFile(file); err != nil {
+		t.Errorf("unable to read record file, error %v", err)
+	} else if records := string(b); strings.Contains(records, "xxxx") {
+		t.Errorf("expecting the request body not to be recorded")
+	} else if strings.Count(records, `"requestBodyOmitted":true`) != 2 {
+		t.Errorf("expecting the request bodies to be marked as omitted, have %v", records)
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/tracing"
 )
 
 // ==============================================================================================================
 type WorkloadConfigureCommand struct {
+	tracing.Carrier
 	DeploymentDescription  *containermessage.DeploymentDescription
 	AgreementLaunchContext *events.AgreementLaunchContext
 }
@@ -27,10 +29,12 @@ func (c WorkloadConfigureCommand) ShortString() string {
 }
 
 func (b *ContainerWorker) NewWorkloadConfigureCommand(deploymentDescription *containermessage.DeploymentDescription, agreementLaunchContext *events.AgreementLaunchContext) *WorkloadConfigureCommand {
-	return &WorkloadConfigureCommand{
+	c := &WorkloadConfigureCommand{
 		DeploymentDescription:  deploymentDescription,
 		AgreementLaunchContext: agreementLaunchContext,
 	}
+	c.SetTraceContext(events.LaunchContextTrace(agreementLaunchContext))
+	return c
 }
 
 // ==============================================================================================================
@@ -87,6 +91,7 @@ func (b *ContainerWorker) NewContainerMaintenanceCommand(protocol string, agreem
 
 // ==============================================================================================================
 type WorkloadShutdownCommand struct {
+	tracing.Carrier
 	AgreementProtocol  string
 	CurrentAgreementId string
 	Deployment         persistence.DeploymentConfig
@@ -106,12 +111,14 @@ func (c WorkloadShutdownCommand) ShortString() string {
 }
 
 func (b *ContainerWorker) NewWorkloadShutdownCommand(protocol string, currentAgreementId string, deployment persistence.DeploymentConfig, agreements []string) *WorkloadShutdownCommand {
-	return &WorkloadShutdownCommand{
+	c := &WorkloadShutdownCommand{
 		AgreementProtocol:  protocol,
 		CurrentAgreementId: currentAgreementId,
 		Deployment:         deployment,
 		Agreements:         agreements,
 	}
+	c.SetTraceContext(tracing.AgreementTraceContext(currentAgreementId))
+	return c
 }
 
 // ==============================================================================================================
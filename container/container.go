@@ -2,6 +2,7 @@ package container
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -671,10 +672,25 @@ func NewContainerWorker(name string, config *config.HorizonConfig, db *bolt.DB,
 	}
 	worker.SetDeferredDelay(15)
 
+	// Services can not be started when the container runtime is not responding.
+	if client != nil {
+		worker.SetReadinessCheck(API_SERVER_TYPE_DOCKER, worker.dockerReadiness)
+	}
+
 	worker.Start(worker, 0)
 	return worker
 }
 
+// The readiness check for the container runtime.
+func (cw *ContainerWorker) dockerReadiness() error {
+	ctx, cancel := context.WithTimeout(context.Background(), worker.READINESS_CHECK_TIMEOUT)
+	defer cancel()
+	if err := cw.client.PingWithContext(ctx); err != nil {
+		return fmt.Errorf("unable to reach the container runtime at %v, error: %v", cw.Config.Edge.DockerEndpoint, err)
+	}
+	return nil
+}
+
 func (w *ContainerWorker) Messages() chan events.Message {
 	return w.BaseWorker.Manager.Messages
 }
@@ -771,6 +787,20 @@ func (w *ContainerWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *ContainerWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.ImageFetchMessage{},
+		&events.GovernanceMaintenanceMessage{},
+		&events.GovernanceWorkloadCancelationMessage{},
+		&events.ContainerStopMessage{},
+		&events.MicroserviceMaintenanceMessage{},
+		&events.MicroserviceCancellationMessage{},
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 func MakeBridge(client *docker.Client, name string, infrastructure, sharedPattern, isDev bool) (*docker.Network, error) {
 
 	// Labels on the docker network indicate attributes about the network.
@@ -116,6 +116,15 @@ func (w *DownloadWorker) NewEvent(incoming events.Message) {
 	}
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *DownloadWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.NMPStartDownloadMessage{},
+		&events.NodeShutdownCompleteMessage{},
+		&events.EdgeRegisteredExchangeMessage{},
+	}
+}
+
 // Download the given object from css
 func (w *DownloadWorker) DownloadCSSObject(org string, objType string, objId string, filePath string, nmpName string) error {
 	glog.Infof(dwlog(fmt.Sprintf("Attempting to download css file %v/%v/%v to file %v", org, objType, objId, filePath)))
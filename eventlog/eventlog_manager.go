@@ -9,63 +9,73 @@ import (
 // Save the eventlog into the db
 func LogEvent(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code string, source_type string, source persistence.EventSourceInterface) error {
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, source_type, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
 }
 
 // Save the agreement eventlog into the db
 func LogAgreementEvent(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code string, ag persistence.EstablishedAgreement) error {
 	source := persistence.NewAgreementEventSourceFromAg(ag)
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, persistence.SRC_TYPE_AG, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
 }
 
 // Save the agreement eventlog into the db
 func LogAgreementEvent2(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code, agreement_id string, workload persistence.WorkloadInfo, dependent_svcs persistence.ServiceSpecs, consumer_id, protocol string) error {
 	source := persistence.NewAgreementEventSource(agreement_id, workload, dependent_svcs, consumer_id, protocol)
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, persistence.SRC_TYPE_AG, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
 }
 
 // Save the service eventlog into the db
 func LogServiceEvent(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code string, msi persistence.MicroserviceInstance) error {
 	source := persistence.NewServiceEventSourceFromServiceInstance(msi)
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, persistence.SRC_TYPE_SVC, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
 }
 
 // Save the service eventlog into the db
 func LogServiceEvent2(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code, instance_id, service_url, org, version, arch string, agreement_ids []string) error {
 	source := persistence.NewServiceEventSource(instance_id, service_url, org, version, arch, agreement_ids)
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, persistence.SRC_TYPE_SVC, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
 }
 
 // Save the service eventlog into the db
 func LogServiceEvent3(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code string, msdef persistence.MicroserviceDefinition) error {
 	source := persistence.NewServiceEventSourceFromServiceDef(msdef)
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, persistence.SRC_TYPE_SVC, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
 }
 
 // Save the node eventlog into the db
 func LogNodeEvent(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code, node_id, org, pattern, config_state string) error {
 	source := persistence.NewNodeEventSource(node_id, org, pattern, config_state)
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, persistence.SRC_TYPE_NODE, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
 }
 
 // Save the database eventlog into the db
 func LogDatabaseEvent(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code string) error {
 	source := persistence.NewDatabaseEventSource()
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, persistence.SRC_TYPE_DB, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
 }
 
 // Save the database eventlog into the db
 func LogExchangeEvent(db *bolt.DB, severity string, message_meta *persistence.MessageMeta, event_code, exchange_url string) error {
 	source := persistence.NewExchangeEventSource(exchange_url)
 	eventlog := persistence.NewEventLog(severity, message_meta, event_code, persistence.SRC_TYPE_EXCH, source)
-	return persistence.SaveEventLog(db, eventlog)
+	return saveEventLog(db, eventlog)
+}
+
+// Save the eventlog, count it for the /metrics API and send it to the eventlog stream subscribers.
+func saveEventLog(db *bolt.DB, eventlog *persistence.EventLog) error {
+	eventCounter.Inc(eventlog.Severity, eventlog.SourceType)
+	if err := persistence.SaveEventLog(db, eventlog); err != nil {
+		return err
+	}
+	publish(*eventlog)
+	return nil
 }
 
 // Get event logs from the db.
@@ -96,6 +106,12 @@ func GetEventLogs(db *bolt.DB, all_logs bool, selectors map[string][]persistence
 	return persistence.FindEventLogsWithSelectors(db, all_logs, selectors, msgPrinter)
 }
 
+// Get a page of the event logs in the time window of the query, in time order. Returns the cursor of the next page,
+// which is empty when there are no more logs.
+func GetEventLogsPage(db *bolt.DB, all_logs bool, selectors map[string][]persistence.Selector, query persistence.EventLogQuery, msgPrinter *message.Printer) ([]persistence.EventLog, string, error) {
+	return persistence.FindEventLogsPage(db, all_logs, selectors, query, msgPrinter)
+}
+
 func DeleteEventLogs(db *bolt.DB, selectors map[string][]persistence.Selector, msgPrinter *message.Printer) (int, error) {
 	return persistence.DeleteEventLogsWithSelectors(db, selectors, msgPrinter)
 }
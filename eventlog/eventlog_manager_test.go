@@ -448,6 +448,8 @@ func Test_LogDatabaseEvent(t *testing.T) {
 	}
 	defer cleanTestDir(dir)
 
+	errCount := eventCounter.Get(persistence.SEVERITY_ERROR, persistence.SRC_TYPE_DB)
+
 	// save event logs
 	if err := LogDatabaseEvent(db, persistence.SEVERITY_ERROR, persistence.NewMessageMeta("Error saving blah into db."), persistence.EC_DATABASE_ERROR); err != nil {
 		t.Errorf("error saving event log: %v", err)
@@ -455,6 +457,8 @@ func Test_LogDatabaseEvent(t *testing.T) {
 		t.Errorf("error saving event log: %v", err)
 	}
 
+	assert.Equal(t, errCount+2, eventCounter.Get(persistence.SEVERITY_ERROR, persistence.SRC_TYPE_DB), "The error events should be counted.")
+
 	// get event logs
 	if elogs, err := GetEventLogs(db, true, map[string][]persistence.Selector{}, nil); err != nil {
 		t.Errorf("error getting event logs: %v", err)
@@ -0,0 +1,248 @@
+package eventlog
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+)
+
+const SINK_SYSLOG = "syslog"
+const SINK_FILE = "file"
+const SINK_OTLP = "otlp"
+
+// The most event logs sent to a sink at once.
+const EXPORT_BATCH_SIZE = 100
+
+// How often the buffered event logs are sent to the sinks.
+const EXPORT_INTERVAL_S = 2
+
+// The wait before sending to a sink again after it failed, doubled on each failure up to the maximum.
+const EXPORT_RETRY_MIN_S = 5
+const EXPORT_RETRY_MAX_S = 300
+
+// An event log as it is sent to the sinks. The message is in English, and the message key and arguments that it was
+// made from are kept so that the log platform can group the events. The node is added so that the events of many
+// nodes can be told apart.
+type ExportRecord struct {
+	persistence.EventLog
+	NodeId  string `json:"node_id,omitempty"`
+	NodeOrg string `json:"node_org,omitempty"`
+}
+
+// Sinks are destinations that the event logs are forwarded to.
+type Sink interface {
+	// Send the records in order. Returns the number of records that were sent, so that a failed send is retried from
+	// the first record that was not sent.
+	Send(records []ExportRecord) (int, error)
+	Close() error
+	String() string
+}
+
+// Create the sink described by the configuration.
+func NewSink(cfg config.EventLogSinkConfig, httpClient *http.Client) (Sink, error) {
+	switch cfg.Type {
+	case SINK_SYSLOG:
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("the %v sink requires an Address", SINK_SYSLOG)
+		}
+		return NewSyslogSink(cfg.Address)
+	case SINK_FILE:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("the %v sink requires a Path", SINK_FILE)
+		}
+		return NewFileSink(cfg.Path, cfg.GetMaxSizeMB(), cfg.GetMaxFiles())
+	case SINK_OTLP:
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("the %v sink requires an Address", SINK_OTLP)
+		}
+		return NewOTLPSink(cfg.Address, httpClient), nil
+	default:
+		return nil, fmt.Errorf("unsupported sink %v, must be %v, %v or %v", cfg.Type, SINK_SYSLOG, SINK_FILE, SINK_OTLP)
+	}
+}
+
+// The exporter forwards the event logs to a sink on its own go routine. It gets the event logs from a subscription,
+// so a sink that is slow or unavailable never holds up the workers that log the events. While the sink fails, the
+// event logs are buffered and sent again with an increasing wait, and the oldest are dropped when the buffer is full.
+// The node's identity is read when the exporter starts and again each time the buffered event logs are sent, rather
+// than for every event log, so that a registration or unregistration is picked up within the send interval.
+type Exporter struct {
+	db         *bolt.DB
+	nodeId     string
+	nodeOrg    string
+	sink       Sink
+	bufferSize int
+	sub        *EventLogSubscription
+	pending    []ExportRecord
+	failures   int
+	retryAt    time.Time
+	stop       chan bool
+	stopped    chan bool
+}
+
+func NewExporter(db *bolt.DB, sink Sink, bufferSize int) *Exporter {
+	e := &Exporter{
+		db:         db,
+		sink:       sink,
+		bufferSize: bufferSize,
+		sub:        Subscribe(bufferSize),
+		pending:    make([]ExportRecord, 0, EXPORT_BATCH_SIZE),
+		stop:       make(chan bool),
+		stopped:    make(chan bool),
+	}
+	e.readNode()
+	return e
+}
+
+func (e *Exporter) String() string {
+	return fmt.Sprintf("Sink: %v, BufferSize: %v, Pending: %v, Failures: %v", e.sink, e.bufferSize, len(e.pending), e.failures)
+}
+
+func (e *Exporter) run() {
+	ticker := time.NewTicker(EXPORT_INTERVAL_S * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case el := <-e.sub.Events:
+			e.add(el)
+			if len(e.pending) >= EXPORT_BATCH_SIZE {
+				e.send()
+			}
+		case <-ticker.C:
+			e.readNode()
+			e.send()
+		case <-e.stop:
+			// Make one last attempt to send what is buffered, whether or not a retry is due.
+			e.sub.Close()
+			for el := range e.sub.Events {
+				e.add(el)
+			}
+			e.retryAt = time.Time{}
+			e.send()
+			if len(e.pending) > 0 {
+				sinkDropped.Add(float64(len(e.pending)), e.sink.String())
+				glog.Warningf(exLogString(fmt.Sprintf("%v event logs were not sent to %v before shutting down", len(e.pending), e.sink)))
+			}
+			if err := e.sink.Close(); err != nil {
+				glog.Errorf(exLogString(fmt.Sprintf("error closing %v, error: %v", e.sink, err)))
+			}
+			close(e.stopped)
+			return
+		}
+	}
+}
+
+// Buffer an event log, dropping the oldest one when the buffer is full.
+func (e *Exporter) add(el persistence.EventLog) {
+	if len(e.pending) >= e.bufferSize {
+		e.pending = e.pending[1:]
+		sinkDropped.Inc(e.sink.String())
+	}
+	e.pending = append(e.pending, e.newRecord(el))
+}
+
+func (e *Exporter) newRecord(el persistence.EventLog) ExportRecord {
+	if el.MessageMeta != nil && el.MessageMeta.MessageKey != "" {
+		el.Message = i18n.GetMessagePrinter().Sprintf(el.MessageMeta.MessageKey, el.MessageMeta.MessageArgs...)
+	}
+	return ExportRecord{EventLog: el, NodeId: e.nodeId, NodeOrg: e.nodeOrg}
+}
+
+// Read the identity of the node that is added to the records. It is kept when the node cannot be read.
+func (e *Exporter) readNode() {
+	if dev, err := persistence.FindExchangeDevice(e.db); err != nil {
+		glog.Errorf(exLogString(fmt.Sprintf("unable to read the node, error: %v", err)))
+	} else if dev == nil {
+		e.nodeId, e.nodeOrg = "", ""
+	} else {
+		e.nodeId, e.nodeOrg = dev.Id, dev.Org
+	}
+}
+
+// Send the buffered event logs in batches, unless the sink is waiting for a retry.
+func (e *Exporter) send() {
+	if len(e.pending) == 0 || time.Now().Before(e.retryAt) {
+		return
+	}
+
+	for len(e.pending) > 0 {
+		batch := e.pending
+		if len(batch) > EXPORT_BATCH_SIZE {
+			batch = batch[:EXPORT_BATCH_SIZE]
+		}
+
+		sent, err := e.sink.Send(batch)
+		e.pending = e.pending[sent:]
+		sinkExported.Add(float64(sent), e.sink.String())
+
+		if err != nil {
+			e.failures++
+			wait := EXPORT_RETRY_MIN_S << uint(e.failures-1)
+			if wait > EXPORT_RETRY_MAX_S || wait <= 0 {
+				wait = EXPORT_RETRY_MAX_S
+			}
+			e.retryAt = time.Now().Add(time.Duration(wait) * time.Second)
+			sinkErrors.Inc(e.sink.String())
+			glog.Errorf(exLogString(fmt.Sprintf("error sending %v event logs to %v, retrying in %v seconds, error: %v", len(e.pending), e.sink, wait, err)))
+			return
+		}
+	}
+
+	// Start afresh so that the buffer does not keep the memory of a large backlog.
+	e.pending = make([]ExportRecord, 0, EXPORT_BATCH_SIZE)
+	if e.failures > 0 {
+		glog.Infof(exLogString(fmt.Sprintf("resumed sending event logs to %v", e.sink)))
+	}
+	e.failures = 0
+}
+
+func (e *Exporter) shutdown() {
+	close(e.stop)
+	<-e.stopped
+}
+
+var exportersLock sync.Mutex
+var exporters []*Exporter
+
+// Start forwarding the event logs to the configured sinks. A sink that cannot be created is logged and skipped,
+// the others are started.
+func StartExport(db *bolt.DB, sinks []config.EventLogSinkConfig, httpClient *http.Client) {
+	exportersLock.Lock()
+	defer exportersLock.Unlock()
+
+	for _, cfg := range sinks {
+		sink, err := NewSink(cfg, httpClient)
+		if err != nil {
+			glog.Errorf(exLogString(fmt.Sprintf("unable to create event log sink %v, error: %v", cfg, err)))
+			continue
+		}
+		e := NewExporter(db, sink, cfg.GetBufferSize())
+		exporters = append(exporters, e)
+		go e.run()
+		glog.V(3).Infof(exLogString(fmt.Sprintf("started forwarding event logs to %v", sink)))
+	}
+}
+
+// Stop forwarding the event logs, sending the ones that are buffered first.
+func StopExport() {
+	exportersLock.Lock()
+	stopping := exporters
+	exporters = nil
+	exportersLock.Unlock()
+
+	for _, e := range stopping {
+		e.shutdown()
+	}
+}
+
+var exLogString = func(v interface{}) string {
+	return fmt.Sprintf("EventLog Export: %v", v)
+}
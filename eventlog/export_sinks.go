@@ -0,0 +1,377 @@
+package eventlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-horizon/anax/persistence"
+)
+
+// The tag that the event logs are sent to syslog with.
+const SYSLOG_TAG = "anax"
+
+// The path of the OTLP/HTTP logs API, relative to the collector endpoint.
+const OTLP_LOGS_PATH = "/v1/logs"
+
+// The instrumentation scope reported with every OTLP log record.
+const OTLP_SCOPE_NAME = "github.com/open-horizon/anax/eventlog"
+
+// Sends the event logs to a remote syslog server, one message per event log. The message is the event log as JSON,
+// so that the log platform can parse its source. The syslog severity follows the event log severity.
+type SyslogSink struct {
+	Network string
+	Address string
+	writer  *syslog.Writer
+}
+
+// The address is the syslog server with its protocol, e.g. udp://logs.example.com:514. The protocol defaults to udp.
+func NewSyslogSink(address string) (*SyslogSink, error) {
+	network, host := "udp", address
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		network, host = u.Scheme, u.Host
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unsupported syslog protocol %v, must be udp or tcp", network)
+	}
+	return &SyslogSink{Network: network, Address: host}, nil
+}
+
+func (s *SyslogSink) String() string {
+	return fmt.Sprintf("syslog %v://%v", s.Network, s.Address)
+}
+
+func (s *SyslogSink) Send(records []ExportRecord) (int, error) {
+	// The connection is made when it is first needed, and again after a failure, so that the agent starts while the
+	// syslog server is down.
+	if s.writer == nil {
+		w, err := syslog.Dial(s.Network, s.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, SYSLOG_TAG)
+		if err != nil {
+			return 0, fmt.Errorf("unable to connect to %v, error: %v", s, err)
+		}
+		s.writer = w
+	}
+
+	for ix, rec := range records {
+		body, err := json.Marshal(rec)
+		if err != nil {
+			return ix, fmt.Errorf("unable to encode event log %v, error: %v", rec.Id, err)
+		}
+
+		msg := string(body)
+		switch rec.Severity {
+		case persistence.SEVERITY_FATAL:
+			err = s.writer.Crit(msg)
+		case persistence.SEVERITY_ERROR:
+			err = s.writer.Err(msg)
+		case persistence.SEVERITY_WARN:
+			err = s.writer.Warning(msg)
+		default:
+			err = s.writer.Info(msg)
+		}
+		if err != nil {
+			s.writer.Close()
+			s.writer = nil
+			return ix, fmt.Errorf("unable to send event log %v to %v, error: %v", rec.Id, s, err)
+		}
+	}
+	return len(records), nil
+}
+
+func (s *SyslogSink) Close() error {
+	if s.writer == nil {
+		return nil
+	}
+	return s.writer.Close()
+}
+
+// Appends the event logs to a local file, one JSON object per line, for a log shipper to pick up. The file is
+// renamed to file.1 when it is full, file.1 to file.2 and so on, and the oldest file is removed.
+type FileSink struct {
+	Path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func NewFileSink(path string, maxSizeMB int, maxFiles int) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("unable to create directory for event log file %v, error: %v", path, err)
+	}
+	s := &FileSink{Path: path, maxSize: int64(maxSizeMB) * 1024 * 1024, maxFiles: maxFiles}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) String() string {
+	return fmt.Sprintf("file %v", s.Path)
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open event log file %v, error: %v", s.Path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("unable to stat event log file %v, error: %v", s.Path, err)
+	}
+	s.file, s.size = file, info.Size()
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	s.file.Close()
+	s.file = nil
+	os.Remove(fmt.Sprintf("%v.%v", s.Path, s.maxFiles))
+	for ix := s.maxFiles - 1; ix >= 1; ix-- {
+		os.Rename(fmt.Sprintf("%v.%v", s.Path, ix), fmt.Sprintf("%v.%v", s.Path, ix+1))
+	}
+	if s.maxFiles > 0 {
+		os.Rename(s.Path, s.Path+".1")
+	} else {
+		os.Remove(s.Path)
+	}
+	return s.open()
+}
+
+func (s *FileSink) Send(records []ExportRecord) (int, error) {
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	for ix, rec := range records {
+		body, err := json.Marshal(rec)
+		if err != nil {
+			return ix, fmt.Errorf("unable to encode event log %v, error: %v", rec.Id, err)
+		}
+		body = append(body, '\n')
+
+		if s.size > 0 && s.size+int64(len(body)) > s.maxSize {
+			if err := s.rotate(); err != nil {
+				return ix, fmt.Errorf("unable to rotate event log file %v, error: %v", s.Path, err)
+			}
+		}
+		n, err := s.file.Write(body)
+		s.size += int64(n)
+		if err != nil {
+			return ix, fmt.Errorf("unable to write event log %v to %v, error: %v", rec.Id, s.Path, err)
+		}
+	}
+	return len(records), nil
+}
+
+func (s *FileSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// The OTLP/JSON encoding of a batch of log records, as defined by the OpenTelemetry protocol's
+// ExportLogsServiceRequest.
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// The OpenTelemetry severity numbers of the event log severities.
+func otlpSeverityNumber(severity string) int {
+	switch severity {
+	case persistence.SEVERITY_FATAL:
+		return 21
+	case persistence.SEVERITY_ERROR:
+		return 17
+	case persistence.SEVERITY_WARN:
+		return 13
+	default:
+		return 9
+	}
+}
+
+// Encode a batch of event logs as an OTLP/JSON ExportLogsServiceRequest. The records of each node are grouped under a
+// resource for the node. The body is the message, and the event code, message key and arguments and the fields of
+// the source are attributes, prefixed with event.source. for the source.
+func EncodeOTLPLogs(records []ExportRecord) ([]byte, error) {
+	resources := make([]otlpResourceLogs, 0)
+	byNode := make(map[string]int)
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	for _, rec := range records {
+		attrs := map[string]string{
+			"event.record_id":   rec.Id,
+			"event.code":        rec.EventCode,
+			"event.source_type": rec.SourceType,
+		}
+		if rec.MessageMeta != nil {
+			attrs["event.message_key"] = rec.MessageMeta.MessageKey
+			if len(rec.MessageMeta.MessageArgs) > 0 {
+				if args, err := json.Marshal(rec.MessageMeta.MessageArgs); err == nil {
+					attrs["event.message_args"] = string(args)
+				}
+			}
+		}
+		if rec.Source != nil {
+			source, err := json.Marshal(rec.Source)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode the source of event log %v, error: %v", rec.Id, err)
+			}
+			fields := make(map[string]interface{})
+			if err := json.Unmarshal(source, &fields); err != nil {
+				return nil, fmt.Errorf("unable to decode the source of event log %v, error: %v", rec.Id, err)
+			}
+			for k, v := range fields {
+				if str, ok := v.(string); ok {
+					attrs["event.source."+k] = str
+				} else if b, err := json.Marshal(v); err == nil {
+					attrs["event.source."+k] = string(b)
+				}
+			}
+		}
+
+		node := rec.NodeOrg + "/" + rec.NodeId
+		ix, ok := byNode[node]
+		if !ok {
+			resAttrs := map[string]string{"service.name": "anax-agent"}
+			if rec.NodeId != "" {
+				resAttrs["horizon.node.id"] = rec.NodeId
+				resAttrs["horizon.node.org"] = rec.NodeOrg
+			}
+			resources = append(resources, otlpResourceLogs{
+				Resource:  otlpResource{Attributes: otlpAttributes(resAttrs)},
+				ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: OTLP_SCOPE_NAME}, LogRecords: []otlpLogRecord{}}},
+			})
+			ix = len(resources) - 1
+			byNode[node] = ix
+		}
+
+		scope := &resources[ix].ScopeLogs[0]
+		scope.LogRecords = append(scope.LogRecords, otlpLogRecord{
+			TimeUnixNano:         strconv.FormatInt(time.Unix(int64(rec.Timestamp), 0).UnixNano(), 10),
+			ObservedTimeUnixNano: observed,
+			SeverityNumber:       otlpSeverityNumber(rec.Severity),
+			SeverityText:         strings.ToUpper(rec.Severity),
+			Body:                 otlpAnyValue{StringValue: rec.Message},
+			Attributes:           otlpAttributes(attrs),
+		})
+	}
+
+	return json.Marshal(otlpLogsRequest{ResourceLogs: resources})
+}
+
+func otlpAttributes(attrs map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: attrs[k]}})
+	}
+	return kvs
+}
+
+// Sends the event logs to an OpenTelemetry collector using OTLP over HTTP with the JSON encoding.
+type OTLPSink struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// The endpoint is the base URL of the collector, e.g. http://localhost:4318.
+func NewOTLPSink(endpoint string, httpClient *http.Client) *OTLPSink {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, OTLP_LOGS_PATH) {
+		url += OTLP_LOGS_PATH
+	}
+	return &OTLPSink{
+		URL:        url,
+		HTTPClient: httpClient,
+	}
+}
+
+func (s *OTLPSink) String() string {
+	return fmt.Sprintf("otlp %v", s.URL)
+}
+
+// The batch is sent in one request, so it is either all sent or not at all.
+func (s *OTLPSink) Send(records []ExportRecord) (int, error) {
+	body, err := EncodeOTLPLogs(records)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("unable to create request for %v, error: %v", s.URL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("unable to send event logs to %v, error: %v", s.URL, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return 0, fmt.Errorf("collector %v returned status %v", s.URL, resp.StatusCode)
+	}
+	return len(records), nil
+}
+
+func (s *OTLPSink) Close() error {
+	return nil
+}
@@ -0,0 +1,206 @@
+//go:build unit
+// +build unit
+
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+// A sink that fails until it is told to work, and remembers what it was sent.
+type testSink struct {
+	fail bool
+	sent []ExportRecord
+}
+
+func (s *testSink) Send(records []ExportRecord) (int, error) {
+	if s.fail {
+		return 0, errors.New("sink is down")
+	}
+	s.sent = append(s.sent, records...)
+	return len(records), nil
+}
+
+func (s *testSink) Close() error   { return nil }
+func (s *testSink) String() string { return "test" }
+
+func testEventLog(id string, severity string) persistence.EventLog {
+	el := persistence.NewEventLog(severity, persistence.NewMessageMeta("Start node configuration/registration for node %v.", "mynode"), persistence.EC_START_NODE_CONFIG_REG, persistence.SRC_TYPE_NODE, persistence.NewNodeEventSource("mynode", "myorg", "", persistence.CONFIGSTATE_CONFIGURED))
+	el.Id = id
+	return *el
+}
+
+func Test_NewSink(t *testing.T) {
+	_, err := NewSink(config.EventLogSinkConfig{Type: SINK_OTLP}, http.DefaultClient)
+	assert.NotNil(t, err, "The otlp sink requires an address.")
+	_, err = NewSink(config.EventLogSinkConfig{Type: "kafka"}, http.DefaultClient)
+	assert.NotNil(t, err, "The sink type is not supported.")
+	_, err = NewSink(config.EventLogSinkConfig{Type: SINK_SYSLOG, Address: "http://logs.example.com:514"}, http.DefaultClient)
+	assert.NotNil(t, err, "The syslog protocol is not supported.")
+
+	sink, err := NewSink(config.EventLogSinkConfig{Type: SINK_SYSLOG, Address: "tcp://logs.example.com:514"}, http.DefaultClient)
+	assert.Nil(t, err, "The syslog sink should be created without connecting.")
+	assert.Equal(t, "syslog tcp://logs.example.com:514", sink.String())
+}
+
+func Test_Exporter_retry(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	sink := &testSink{fail: true}
+	e := NewExporter(db, sink, 2)
+	defer e.sub.Close()
+
+	e.add(testEventLog("1", persistence.SEVERITY_INFO))
+	e.send()
+	assert.Equal(t, 1, e.failures, "The send should fail.")
+	assert.Equal(t, 1, len(e.pending), "The event log should be kept for a retry.")
+
+	// The buffer holds the newest event logs.
+	e.add(testEventLog("2", persistence.SEVERITY_INFO))
+	e.add(testEventLog("3", persistence.SEVERITY_ERROR))
+	assert.Equal(t, 2, len(e.pending), "The oldest event log should be dropped.")
+
+	// Nothing is sent before the retry is due.
+	sink.fail = false
+	e.send()
+	assert.Equal(t, 0, len(sink.sent), "The retry should not be due yet.")
+
+	e.retryAt = time.Now()
+	e.send()
+	assert.Equal(t, 0, e.failures, "The send should work.")
+	if assert.Equal(t, 2, len(sink.sent), "The buffered event logs should be sent.") {
+		assert.Equal(t, "2", sink.sent[0].Id)
+		assert.Equal(t, "Start node configuration/registration for node mynode.", sink.sent[0].Message, "The message should be in English.")
+		assert.NotNil(t, sink.sent[0].MessageMeta, "The message meta should be kept.")
+	}
+}
+
+// The node's identity is read when the exporter starts and when the event logs are sent, not for each event log.
+func Test_Exporter_node(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	if _, err := persistence.SaveNewExchangeDevice(db, "mynode", "token", "mynode", "device", "myorg", "", persistence.CONFIGSTATE_CONFIGURED, persistence.SoftwareVersion{persistence.AGENT_VERSION: "1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	e := NewExporter(db, &testSink{}, 10)
+	defer e.sub.Close()
+
+	if err := persistence.DeleteExchangeDevice(db); err != nil {
+		t.Fatal(err)
+	}
+	e.add(testEventLog("1", persistence.SEVERITY_INFO))
+	e.readNode()
+	e.add(testEventLog("2", persistence.SEVERITY_INFO))
+
+	assert.Equal(t, "mynode", e.pending[0].NodeId, "The node read at the start should be used.")
+	assert.Equal(t, "myorg", e.pending[0].NodeOrg)
+	assert.Equal(t, "", e.pending[1].NodeId, "The node should be gone once it is read again.")
+}
+
+func Test_FileSink(t *testing.T) {
+	dir, err := os.MkdirTemp("", "utsink-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+
+	file := path.Join(dir, "events", "eventlog.json")
+	sink, err := NewFileSink(file, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	n, err := sink.Send([]ExportRecord{{EventLog: testEventLog("1", persistence.SEVERITY_INFO), NodeId: "mynode", NodeOrg: "myorg"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	assert.True(t, scanner.Scan(), "The event log should be written.")
+
+	var rec map[string]interface{}
+	assert.Nil(t, json.Unmarshal(scanner.Bytes(), &rec))
+	assert.Equal(t, "1", rec["record_id"])
+	assert.Equal(t, "mynode", rec["node_id"])
+	assert.Equal(t, "myorg", rec["event_source"].(map[string]interface{})["node_org"], "The source should be written with the event log.")
+
+	// The file is rotated when it is full.
+	sink.size = sink.maxSize
+	_, err = sink.Send([]ExportRecord{{EventLog: testEventLog("2", persistence.SEVERITY_INFO)}})
+	assert.Nil(t, err)
+	_, err = os.Stat(file + ".1")
+	assert.Nil(t, err, "The full file should be rotated.")
+}
+
+func Test_OTLPSink(t *testing.T) {
+	var body []byte
+	var reqPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqPath = r.URL.Path
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	el := testEventLog("1", persistence.SEVERITY_ERROR)
+	el.Message = "Start node configuration/registration for node mynode."
+
+	sink := NewOTLPSink(server.URL, server.Client())
+	n, err := sink.Send([]ExportRecord{{EventLog: el, NodeId: "mynode", NodeOrg: "myorg"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, OTLP_LOGS_PATH, reqPath)
+
+	var req otlpLogsRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Equal(t, 1, len(req.ResourceLogs)) && assert.Equal(t, 1, len(req.ResourceLogs[0].ScopeLogs[0].LogRecords)) {
+		rec := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+		assert.Equal(t, 17, rec.SeverityNumber)
+		assert.Equal(t, "Start node configuration/registration for node mynode.", rec.Body.StringValue)
+		attrs := make(map[string]string)
+		for _, kv := range rec.Attributes {
+			attrs[kv.Key] = kv.Value.StringValue
+		}
+		assert.Equal(t, persistence.EC_START_NODE_CONFIG_REG, attrs["event.code"])
+		assert.Equal(t, "mynode", attrs["event.source.node_id"])
+		assert.Equal(t, `["mynode"]`, attrs["event.message_args"])
+	}
+
+	// A collector error fails the whole batch.
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	n, err = sink.Send([]ExportRecord{{EventLog: testEventLog("2", persistence.SEVERITY_INFO)}})
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+}
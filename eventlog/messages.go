@@ -0,0 +1,27 @@
+package eventlog
+
+import (
+	"github.com/open-horizon/anax/i18n"
+)
+
+// messages for eventlog
+const (
+	EL_EVENTLOG_PRUNED         = "Event log pruned, %v expired events and %v events over the retention limits were deleted, %v events are left."
+	EL_EVENTLOG_UNABLE_PRUNE   = "Unable to prune the event log, error: %v"
+	EL_DATABASE_COMPACTED      = "Database compacted, the database file shrunk by %v bytes."
+	EL_DATABASE_UNABLE_COMPACT = "Unable to compact the database, error: %v"
+)
+
+// This is does nothing useful at run time.
+// This code is only used at compile time to make the eventlog messages get into the catalog so that
+// they can be translated.
+// The event log messages will be saved in English. But the CLI can request them in different languages.
+func MarkI18nMessages() {
+	// get message printer. anax default language is English
+	msgPrinter := i18n.GetMessagePrinter()
+
+	msgPrinter.Sprintf(EL_EVENTLOG_PRUNED)
+	msgPrinter.Sprintf(EL_EVENTLOG_UNABLE_PRUNE)
+	msgPrinter.Sprintf(EL_DATABASE_COMPACTED)
+	msgPrinter.Sprintf(EL_DATABASE_UNABLE_COMPACT)
+}
@@ -0,0 +1,27 @@
+package eventlog
+
+import (
+	"github.com/open-horizon/anax/metrics"
+)
+
+// Event log metrics, exposed by the agent's /metrics API.
+var eventCounter = metrics.NewCounterVec("anax_eventlog_events_total",
+	"Events written to the agent's event log, by severity and source type.", "severity", "source_type")
+
+var prunedCounter = metrics.NewCounterVec("anax_eventlog_pruned_total",
+	"Events deleted from the agent's event log by the retention limits, by severity.", "severity")
+
+var eventLogCount = metrics.NewGaugeVec("anax_eventlog_events",
+	"Events in the agent's event log after the last prune.")
+
+var streamDroppedCounter = metrics.NewCounterVec("anax_eventlog_stream_dropped_total",
+	"Events not sent to an event log stream client because it fell behind.")
+
+var sinkExported = metrics.NewCounterVec("anax_eventlog_sink_exported_total",
+	"Events sent to an event log sink, by sink.", "sink")
+
+var sinkErrors = metrics.NewCounterVec("anax_eventlog_sink_errors_total",
+	"Failed attempts to send events to an event log sink, by sink.", "sink")
+
+var sinkDropped = metrics.NewCounterVec("anax_eventlog_sink_dropped_total",
+	"Events dropped because an event log sink could not keep up or could not be reached, by sink.", "sink")
@@ -0,0 +1,124 @@
+package eventlog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+)
+
+// name for the subworker
+const EVENTLOG_PRUNE = "EventLogPrune"
+
+// The pruner keeps the agent's event log within the retention limits in the configuration, so that the database
+// and the event log queries, which read every event, do not grow without bound on long lived nodes.
+type EventLogPruner struct {
+	worker.BaseWorker // embedded field
+	db                *bolt.DB
+}
+
+func NewEventLogPruner(name string, cfg *config.HorizonConfig, db *bolt.DB) *EventLogPruner {
+	worker := &EventLogPruner{
+		BaseWorker: worker.NewBaseWorker(name, cfg, nil),
+		db:         db,
+	}
+
+	glog.Info(pruneLogString(fmt.Sprintf("Starting EventLogPruner worker, retention: %v", cfg.Edge.EventLog)))
+	worker.Start(worker, 0)
+	return worker
+}
+
+func (w *EventLogPruner) Messages() chan events.Message {
+	return w.BaseWorker.Manager.Messages
+}
+
+func (w *EventLogPruner) Initialize() bool {
+	w.DispatchSubworker(EVENTLOG_PRUNE, w.prune, w.Config.Edge.EventLog.GetPruneIntervalS(), false)
+	return true
+}
+
+func (w *EventLogPruner) NewEvent(incoming events.Message) {
+	switch incoming.(type) {
+	case *events.NodeShutdownCompleteMessage:
+		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
+		switch msg.Event().Id {
+		case events.UNCONFIGURE_COMPLETE:
+			w.Commands <- worker.NewTerminateCommand("shutdown")
+		}
+	}
+}
+
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *EventLogPruner) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
+func (w *EventLogPruner) prune() int {
+	if _, err := PruneEventLogs(w.db, w.Config.Edge.EventLog, time.Now()); err != nil {
+		glog.Errorf(pruneLogString(fmt.Sprintf("unable to prune the event log, error: %v", err)))
+	}
+	return 0
+}
+
+// Returns the retention limits in the configuration.
+func GetRetention(cfg config.EventLogConfig) persistence.EventLogRetention {
+	retention := persistence.EventLogRetention{
+		MaxAgeS:  make(map[string]int64),
+		MaxCount: cfg.GetMaxCount(),
+		MaxBytes: cfg.GetMaxSizeMB() * 1024 * 1024,
+	}
+	for _, severity := range []string{persistence.SEVERITY_INFO, persistence.SEVERITY_WARN, persistence.SEVERITY_ERROR, persistence.SEVERITY_FATAL} {
+		retention.MaxAgeS[severity] = int64(cfg.GetMaxAgeH(severity)) * 3600
+	}
+	return retention
+}
+
+// Delete the events that are outside the retention limits. The pruning is itself logged as an event, when events
+// were deleted or the pruning failed.
+func PruneEventLogs(db *bolt.DB, cfg config.EventLogConfig, now time.Time) (*persistence.EventLogPruneResult, error) {
+	retention := GetRetention(cfg)
+	result, err := persistence.PruneEventLogs(db, retention, now)
+	if err != nil {
+		LogDatabaseEvent(db, persistence.SEVERITY_ERROR,
+			persistence.NewMessageMeta(EL_EVENTLOG_UNABLE_PRUNE, err.Error()),
+			persistence.EC_ERROR_EVENTLOG_PRUNE)
+		return nil, err
+	}
+
+	glog.V(3).Infof(pruneLogString(fmt.Sprintf("pruned the event log with retention %v: %v", retention, result)))
+	for severity, count := range result.BySeverity {
+		prunedCounter.Add(float64(count), severity)
+	}
+	eventLogCount.Set(float64(result.Remaining))
+
+	if result.Deleted() > 0 {
+		LogDatabaseEvent(db, persistence.SEVERITY_INFO,
+			persistence.NewMessageMeta(EL_EVENTLOG_PRUNED, result.Expired, result.OverLimit, result.Remaining),
+			persistence.EC_EVENTLOG_PRUNED)
+	}
+	return result, nil
+}
+
+// Log the outcome of the database compaction that is done when the agent starts, before the database is opened.
+func LogDatabaseCompaction(db *bolt.DB, shrunk int64, err error) {
+	if err != nil {
+		LogDatabaseEvent(db, persistence.SEVERITY_WARN,
+			persistence.NewMessageMeta(EL_DATABASE_UNABLE_COMPACT, err.Error()),
+			persistence.EC_ERROR_DATABASE_COMPACT)
+	} else if shrunk > 0 {
+		LogDatabaseEvent(db, persistence.SEVERITY_INFO,
+			persistence.NewMessageMeta(EL_DATABASE_COMPACTED, shrunk),
+			persistence.EC_DATABASE_COMPACTED)
+	}
+}
+
+var pruneLogString = func(v interface{}) string {
+	return fmt.Sprintf("EventLogPruner: %v", v)
+}
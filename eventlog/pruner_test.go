@@ -0,0 +1,50 @@
+//go:build unit
+// +build unit
+
+package eventlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetRetention(t *testing.T) {
+	retention := GetRetention(config.EventLogConfig{SeverityMaxAgeH: map[string]int{persistence.SEVERITY_INFO: 24, persistence.SEVERITY_FATAL: -1}, MaxCount: -1})
+	assert.Equal(t, int64(24*3600), retention.MaxAgeS[persistence.SEVERITY_INFO], "The info logs have their own age.")
+	assert.Equal(t, int64(config.EventLogMaxAgeH_DEFAULT*3600), retention.MaxAgeS[persistence.SEVERITY_ERROR], "The error logs have the default age.")
+	assert.True(t, retention.MaxAgeS[persistence.SEVERITY_FATAL] < 0, "The fatal logs are kept regardless of their age.")
+	assert.True(t, retention.MaxCount < 0, "There is no count limit.")
+	assert.Equal(t, config.EventLogMaxSizeMB_DEFAULT*1024*1024, retention.MaxBytes, "The size limit is the default.")
+}
+
+func Test_PruneEventLogs(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	for i := 0; i < 5; i++ {
+		LogDatabaseEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("test event %v", i), persistence.EC_DATABASE_ERROR)
+	}
+
+	// Nothing to prune, the pruning is not logged.
+	result, err := PruneEventLogs(db, config.EventLogConfig{}, time.Now())
+	assert.Nil(t, err, "Error pruning eventlogs.")
+	assert.Equal(t, 0, result.Deleted(), "No logs should be deleted.")
+
+	// The pruning is logged once logs are deleted.
+	result, err = PruneEventLogs(db, config.EventLogConfig{MaxCount: 2}, time.Now())
+	assert.Nil(t, err, "Error pruning eventlogs.")
+	assert.Equal(t, 3, result.Deleted(), "3 logs should be deleted.")
+
+	logs, err := GetEventLogs(db, true, map[string][]persistence.Selector{"event_code": {{Op: "=", MatchValue: persistence.EC_EVENTLOG_PRUNED}}}, nil)
+	assert.Nil(t, err, "Error retrieving eventlogs.")
+	assert.Equal(t, 1, len(logs), "The pruning should be logged.")
+	assert.Equal(t, "Event log pruned, 0 expired events and 3 events over the retention limits were deleted, 2 events are left.", logs[0].Message, "The pruning message.")
+}
@@ -0,0 +1,24 @@
+package eventlog
+
+import (
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/stream"
+)
+
+// The event logs that a subscription can fall behind by before new logs are dropped for it.
+const DEFAULT_STREAM_BUFFER = 100
+
+// A subscription to the event logs as they are saved, used to stream them to the API clients and the sinks.
+type EventLogSubscription = stream.Subscription[persistence.EventLog]
+
+var subscriptions = stream.NewBroadcaster[persistence.EventLog]("event log", DEFAULT_STREAM_BUFFER, func() { streamDroppedCounter.Inc() })
+
+// Subscribe to the event logs saved from now on. The caller must close the subscription when it is done with it.
+func Subscribe(bufferSize int) *EventLogSubscription {
+	return subscriptions.Subscribe(bufferSize, nil)
+}
+
+// Send a saved event log to all the subscribers.
+func publish(el persistence.EventLog) {
+	subscriptions.Publish(el)
+}
@@ -0,0 +1,40 @@
+//go:build unit
+// +build unit
+
+package eventlog
+
+import (
+	"testing"
+
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Subscribe(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	sub := Subscribe(2)
+
+	for i := 0; i < 3; i++ {
+		LogDatabaseEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("test event %v", i), persistence.EC_DATABASE_ERROR)
+	}
+
+	// The subscriber fell behind, the last log is dropped.
+	assert.Equal(t, 1, sub.Dropped(), "One log should be dropped.")
+	el := <-sub.Events
+	assert.Equal(t, "1", el.Id, "The first log should be delivered with its record id.")
+	el = <-sub.Events
+	assert.Equal(t, "2", el.Id, "The second log should be delivered.")
+
+	// Nothing is delivered once the subscription is closed.
+	sub.Close()
+	LogDatabaseEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("test event %v", 4), persistence.EC_DATABASE_ERROR)
+	_, ok := <-sub.Events
+	assert.False(t, ok, "The events channel should be closed.")
+	sub.Close()
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/open-horizon/anax/exchangecommon"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 	"time"
 )
 
@@ -153,6 +154,13 @@ type Message interface {
 	ShortString() string
 }
 
+// A message that reports the latest state of something, rather than a change to it, can be coalesced. A newer message
+// replaces the queued message with the same key, when a worker's event queue is configured to coalesce. An empty key
+// means the message can't be coalesced.
+type CoalescableMessage interface {
+	CoalesceKey() string
+}
+
 type LaunchContext interface {
 	ContainerConfig() ContainerConfig
 	ShortString() string
@@ -823,6 +831,7 @@ func NewEdgeConfigCompleteMessage(evId EventId) *EdgeConfigCompleteMessage {
 // Anax device side fires this event when an agreement is reached so that it can begin
 // downloading containers. The Agreement is not final until it is seen in the blockchain.
 type AgreementReachedMessage struct {
+	tracing.Carrier
 	event         Event
 	launchContext *AgreementLaunchContext
 }
@@ -849,15 +858,18 @@ func (e *AgreementReachedMessage) LaunchContext() *AgreementLaunchContext {
 
 func NewAgreementMessage(id EventId, lc *AgreementLaunchContext) *AgreementReachedMessage {
 
-	return &AgreementReachedMessage{
+	m := &AgreementReachedMessage{
 		event: Event{
 			Id: id,
 		},
 		launchContext: lc,
 	}
+	m.SetTraceContext(LaunchContextTrace(lc))
+	return m
 }
 
 type ImageFetchMessage struct {
+	tracing.Carrier
 	event                 Event
 	DeploymentDescription *containermessage.DeploymentDescription
 	LaunchContext         interface{}
@@ -884,7 +896,7 @@ func (b *ImageFetchMessage) ShortString() string {
 
 func NewImageFetchMessage(id EventId, deploymentDescription *containermessage.DeploymentDescription, launchContext interface{}, err error) *ImageFetchMessage {
 
-	return &ImageFetchMessage{
+	m := &ImageFetchMessage{
 		event: Event{
 			Id: id,
 		},
@@ -892,6 +904,8 @@ func NewImageFetchMessage(id EventId, deploymentDescription *containermessage.De
 		LaunchContext:         launchContext,
 		Error:                 err,
 	}
+	m.SetTraceContext(LaunchContextTrace(launchContext))
+	return m
 }
 
 // Governance messages
@@ -936,6 +950,7 @@ func NewGovernanceMaintenanceMessage(id EventId, protocol string, agreementId st
 }
 
 type GovernanceWorkloadCancelationMessage struct {
+	tracing.Carrier
 	GovernanceMaintenanceMessage
 	Message
 	Cause EndContractCause
@@ -961,14 +976,17 @@ func NewGovernanceWorkloadCancelationMessage(id EventId, cause EndContractCause,
 
 	govMaint := NewGovernanceMaintenanceMessage(id, protocol, agreementId, clusterNamespace, deployment)
 
-	return &GovernanceWorkloadCancelationMessage{
+	m := &GovernanceWorkloadCancelationMessage{
 		GovernanceMaintenanceMessage: *govMaint,
 		Cause:                        cause,
 	}
+	m.SetTraceContext(tracing.AgreementTraceContext(agreementId))
+	return m
 }
 
 // Workload messages
 type WorkloadMessage struct {
+	tracing.Carrier
 	event             Event
 	AgreementProtocol string
 	AgreementId       string
@@ -993,7 +1011,7 @@ func (b WorkloadMessage) Event() Event {
 
 func NewWorkloadMessage(id EventId, protocol string, agreementId string, deployment persistence.DeploymentConfig) *WorkloadMessage {
 
-	return &WorkloadMessage{
+	m := &WorkloadMessage{
 		event: Event{
 			Id: id,
 		},
@@ -1001,6 +1019,8 @@ func NewWorkloadMessage(id EventId, protocol string, agreementId string, deploym
 		AgreementId:       agreementId,
 		Deployment:        deployment,
 	}
+	m.SetTraceContext(tracing.AgreementTraceContext(agreementId))
+	return m
 }
 
 // Container messages
@@ -1953,6 +1973,11 @@ func (w *NodeHeartbeatStateChangeMessage) ShortString() string {
 	return fmt.Sprintf("Event: %v, NodeOrg: %v, NodeId: %v", w.event, w.NodeOrg, w.NodeId)
 }
 
+// Only the latest heartbeat state of a node matters.
+func (w *NodeHeartbeatStateChangeMessage) CoalesceKey() string {
+	return fmt.Sprintf("NodeHeartbeatStateChange/%v/%v", w.NodeOrg, w.NodeId)
+}
+
 func NewNodeHeartbeatStateChangeMessage(id EventId, node_org string, node_id string) *NodeHeartbeatStateChangeMessage {
 	return &NodeHeartbeatStateChangeMessage{
 		event: Event{
@@ -2099,6 +2124,15 @@ func (w *ExchangeChangeMessage) GetResourceBeforeChange() interface{} {
 	return w.resourceBeforeChange
 }
 
+// A change message without the change itself only says that a kind of exchange resource changed, so one such message
+// can stand for several.
+func (w *ExchangeChangeMessage) CoalesceKey() string {
+	if w.change != nil || w.resourceBeforeChange != nil {
+		return ""
+	}
+	return fmt.Sprintf("ExchangeChange/%v", w.event.Id)
+}
+
 func NewExchangeChangeMessage(id EventId) *ExchangeChangeMessage {
 	return &ExchangeChangeMessage{
 		event: Event{
@@ -2147,6 +2181,15 @@ func GetLaunchContext(launchContext interface{}) LaunchContext {
 	return nil
 }
 
+// Return the trace context for work done on behalf of a launch context. Only launch contexts for an agreement are
+// traced, each agreement having its own trace.
+func LaunchContextTrace(launchContext interface{}) tracing.SpanContext {
+	if lc, ok := launchContext.(*AgreementLaunchContext); ok && lc != nil {
+		return tracing.AgreementTraceContext(lc.AgreementId)
+	}
+	return tracing.SpanContext{}
+}
+
 type SecretUpdatesMessage struct {
 	event   Event
 	Updates SecretUpdates // Holds a list of secrets that have been updated and the affected policies
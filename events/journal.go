@@ -0,0 +1,178 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+)
+
+// The message types that can be written to the event journal, so that they are dispatched again when the agent
+// restarts before the work they started has finished.
+const (
+	JOURNAL_AGREEMENT_REACHED = "AgreementReachedMessage" // An agreement's workload is ready to be downloaded
+	JOURNAL_IMAGE_FETCH       = "ImageFetchMessage"       // The images of a workload or service were downloaded
+)
+
+func JournaledMessageTypes() []string {
+	return []string{JOURNAL_AGREEMENT_REACHED, JOURNAL_IMAGE_FETCH}
+}
+
+// The journaled messages are keyed by the work they are part of, either the launch of an agreement's workload or the
+// launch of a service instance.
+func agreementJournalKey(agreementId string) string {
+	return fmt.Sprintf("agreement/%v", agreementId)
+}
+
+func serviceJournalKey(instanceKey string) string {
+	return fmt.Sprintf("service/%v", instanceKey)
+}
+
+func launchContextJournalKey(launchContext interface{}) string {
+	switch lc := launchContext.(type) {
+	case *AgreementLaunchContext:
+		if lc != nil {
+			return agreementJournalKey(lc.AgreementId)
+		}
+	case *ContainerLaunchContext:
+		if lc != nil {
+			return serviceJournalKey(lc.Name)
+		}
+	}
+	return ""
+}
+
+// Returns the journal type and key of a message that can be journaled, or empty strings if the message is not one
+// that can be journaled. Image fetch errors are not journaled, they end the work instead.
+func GetJournalKey(msg Message) (string, string) {
+	switch m := msg.(type) {
+	case *AgreementReachedMessage:
+		if m.Event().Id == AGREEMENT_REACHED && m.LaunchContext() != nil {
+			return JOURNAL_AGREEMENT_REACHED, agreementJournalKey(m.LaunchContext().AgreementId)
+		}
+	case *ImageFetchMessage:
+		if m.Event().Id == IMAGE_FETCHED {
+			if key := launchContextJournalKey(m.LaunchContext); key != "" {
+				return JOURNAL_IMAGE_FETCH, key
+			}
+		}
+	}
+	return "", ""
+}
+
+// Returns the key of the journaled work that a message says has finished, successfully or not, or an empty string.
+func GetJournalCompletionKey(msg Message) string {
+	switch m := msg.(type) {
+	case *WorkloadMessage:
+		if m.Event().Id == EXECUTION_BEGUN || m.Event().Id == EXECUTION_FAILED {
+			return agreementJournalKey(m.AgreementId)
+		}
+	case *ContainerMessage:
+		if m.Event().Id == EXECUTION_BEGUN || m.Event().Id == EXECUTION_FAILED {
+			return serviceJournalKey(m.LaunchContext.Name)
+		}
+	case *ImageFetchMessage:
+		if m.Event().Id != IMAGE_FETCHED {
+			return launchContextJournalKey(m.LaunchContext)
+		}
+	case *GovernanceWorkloadCancelationMessage:
+		if m.Event().Id == AGREEMENT_ENDED {
+			return agreementJournalKey(m.AgreementId)
+		}
+	}
+	return ""
+}
+
+// The serialized form of a journaled message. Only one of the launch contexts is set.
+type journaledMessage struct {
+	EventId                EventId                                 `json:"event_id"`
+	AgreementLaunchContext *AgreementLaunchContext                 `json:"agreement_launch_context,omitempty"`
+	ContainerLaunchContext *ContainerLaunchContext                 `json:"container_launch_context,omitempty"`
+	DeploymentDescription  *containermessage.DeploymentDescription `json:"deployment_description,omitempty"`
+}
+
+func EncodeJournaledMessage(msg Message) ([]byte, error) {
+	jm := journaledMessage{EventId: msg.Event().Id}
+
+	switch m := msg.(type) {
+	case *AgreementReachedMessage:
+		jm.AgreementLaunchContext = m.LaunchContext()
+	case *ImageFetchMessage:
+		jm.DeploymentDescription = m.DeploymentDescription
+		switch lc := m.LaunchContext.(type) {
+		case *AgreementLaunchContext:
+			jm.AgreementLaunchContext = lc
+		case *ContainerLaunchContext:
+			jm.ContainerLaunchContext = lc
+		default:
+			return nil, fmt.Errorf("unable to journal %T with launch context %T", msg, m.LaunchContext)
+		}
+	default:
+		return nil, fmt.Errorf("unable to journal %T", msg)
+	}
+
+	return json.Marshal(jm)
+}
+
+func DecodeJournaledMessage(msgType string, payload []byte) (Message, error) {
+	var jm journaledMessage
+	if err := json.Unmarshal(payload, &jm); err != nil {
+		return nil, fmt.Errorf("unable to demarshal journaled %v, error: %v", msgType, err)
+	}
+
+	switch msgType {
+	case JOURNAL_AGREEMENT_REACHED:
+		if jm.AgreementLaunchContext == nil {
+			return nil, fmt.Errorf("journaled %v has no launch context", msgType)
+		}
+		return NewAgreementMessage(jm.EventId, jm.AgreementLaunchContext), nil
+
+	case JOURNAL_IMAGE_FETCH:
+		if jm.AgreementLaunchContext != nil {
+			return NewImageFetchMessage(jm.EventId, jm.DeploymentDescription, jm.AgreementLaunchContext, nil), nil
+		} else if jm.ContainerLaunchContext != nil {
+			return NewImageFetchMessage(jm.EventId, jm.DeploymentDescription, jm.ContainerLaunchContext, nil), nil
+		}
+		return nil, fmt.Errorf("journaled %v has no launch context", msgType)
+	}
+
+	return nil, fmt.Errorf("unknown journaled message type %v", msgType)
+}
+
+// Returns true if the work started by a journaled message still has to be done, so that replaying the message does not
+// start a workload or service a second time. An agreement's workload has to be started if the agreement is still
+// active and its workload has not started yet, and a service instance likewise.
+func IsJournaledMessageNeeded(db *bolt.DB, msg Message) (bool, error) {
+	var launchContext interface{}
+	switch m := msg.(type) {
+	case *AgreementReachedMessage:
+		launchContext = m.LaunchContext()
+	case *ImageFetchMessage:
+		launchContext = m.LaunchContext
+	}
+
+	switch lc := launchContext.(type) {
+	case *AgreementLaunchContext:
+		if ags, err := persistence.FindEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(lc.AgreementId)}); err != nil {
+			return false, err
+		} else if len(ags) != 1 {
+			return false, nil
+		} else {
+			return ags[0].AgreementTerminatedTime == 0 && ags[0].AgreementExecutionStartTime == 0, nil
+		}
+
+	case *ContainerLaunchContext:
+		if msi, err := persistence.FindMicroserviceInstanceWithKey(db, lc.Name); err != nil {
+			return false, err
+		} else if msi == nil {
+			return false, nil
+		} else {
+			return !msi.Archived && msi.CleanupStartTime == 0 && msi.ExecutionStartTime == 0, nil
+		}
+	}
+
+	return false, nil
+}
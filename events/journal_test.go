@@ -0,0 +1,72 @@
+//go:build unit
+// +build unit
+
+package events
+
+import (
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Journaled messages are decoded to the same message they were encoded from.
+func Test_JournaledMessage_roundtrip(t *testing.T) {
+
+	alc := &AgreementLaunchContext{
+		AgreementProtocol: "Basic",
+		AgreementId:       "ag1",
+		Configure:         ContainerConfig{Deployment: "{}", DeploymentSignature: "sig"},
+		Microservices:     []MicroserviceSpec{{SpecRef: "svc1", Org: "myorg", Version: "1.0.0"}},
+	}
+	msgType, key := GetJournalKey(NewAgreementMessage(AGREEMENT_REACHED, alc))
+	assert.Equal(t, JOURNAL_AGREEMENT_REACHED, msgType)
+	assert.Equal(t, "agreement/ag1", key)
+
+	payload, err := EncodeJournaledMessage(NewAgreementMessage(AGREEMENT_REACHED, alc))
+	assert.Nil(t, err)
+	msg, err := DecodeJournaledMessage(msgType, payload)
+	assert.Nil(t, err)
+	if arm, ok := msg.(*AgreementReachedMessage); assert.True(t, ok) {
+		assert.Equal(t, AGREEMENT_REACHED, arm.Event().Id)
+		assert.Equal(t, alc, arm.LaunchContext())
+	}
+
+	clc := &ContainerLaunchContext{
+		Name:        "myorg_svc1_1.0.0_abc",
+		ServicePath: []persistence.ServiceInstancePathElement{*persistence.NewServiceInstancePathElement("svc1", "myorg", "1.0.0")},
+	}
+	dd := &containermessage.DeploymentDescription{Services: map[string]*containermessage.Service{"svc1": {Image: "svc1:1.0.0"}}}
+	msgType, key = GetJournalKey(NewImageFetchMessage(IMAGE_FETCHED, dd, clc, nil))
+	assert.Equal(t, JOURNAL_IMAGE_FETCH, msgType)
+	assert.Equal(t, "service/myorg_svc1_1.0.0_abc", key)
+
+	payload, err = EncodeJournaledMessage(NewImageFetchMessage(IMAGE_FETCHED, dd, clc, nil))
+	assert.Nil(t, err)
+	msg, err = DecodeJournaledMessage(msgType, payload)
+	assert.Nil(t, err)
+	if ifm, ok := msg.(*ImageFetchMessage); assert.True(t, ok) {
+		assert.Equal(t, IMAGE_FETCHED, ifm.Event().Id)
+		assert.Equal(t, clc, ifm.LaunchContext)
+		assert.Equal(t, "svc1:1.0.0", ifm.DeploymentDescription.Services["svc1"].Image)
+	}
+
+	_, err = DecodeJournaledMessage("NodeShutdownMessage", payload)
+	assert.NotNil(t, err)
+}
+
+// Only the events that end the journaled work have a completion key.
+func Test_GetJournalCompletionKey(t *testing.T) {
+
+	alc := &AgreementLaunchContext{AgreementProtocol: "Basic", AgreementId: "ag1"}
+	clc := ContainerLaunchContext{Name: "svc1"}
+
+	assert.Equal(t, "agreement/ag1", GetJournalCompletionKey(NewWorkloadMessage(EXECUTION_BEGUN, "Basic", "ag1", nil)))
+	assert.Equal(t, "agreement/ag1", GetJournalCompletionKey(NewWorkloadMessage(EXECUTION_FAILED, "Basic", "ag1", nil)))
+	assert.Equal(t, "agreement/ag1", GetJournalCompletionKey(NewImageFetchMessage(IMAGE_FETCH_ERROR, nil, alc, nil)))
+	assert.Equal(t, "service/svc1", GetJournalCompletionKey(NewContainerMessage(EXECUTION_BEGUN, clc, "", "")))
+	assert.Equal(t, "service/svc1", GetJournalCompletionKey(NewImageFetchMessage(IMAGE_SIG_VERIF_ERROR, nil, &clc, nil)))
+
+	assert.Equal(t, "", GetJournalCompletionKey(NewImageFetchMessage(IMAGE_FETCHED, nil, alc, nil)))
+	assert.Equal(t, "", GetJournalCompletionKey(NewAgreementMessage(AGREEMENT_REACHED, alc)))
+}
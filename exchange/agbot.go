@@ -135,6 +135,23 @@ func CreateAgbotPublicKeyPatch(keyPath string) *PatchAgbotPublicKey {
 	return pdr
 }
 
+// Publish a new messaging public key in the agbot's exchange resource.
+func PatchAgbotMessageKey(ec ExchangeContext, publicKey []byte) error {
+	pdr := &PatchAgbotPublicKey{
+		PublicKey: publicKey,
+	}
+
+	var resp interface{}
+	resp = new(PostDeviceResponse)
+	targetURL := ec.GetExchangeURL() + "orgs/" + GetOrg(ec.GetExchangeId()) + "/agbots/" + GetId(ec.GetExchangeId())
+
+	if err := InvokeExchangeRetryOnTransportError(ec.GetHTTPFactory(), "PATCH", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), pdr, &resp); err != nil {
+		return err
+	}
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("patched messaging key for agbot %v: %v", ec.GetExchangeId(), pdr.ShortString())))
+	return nil
+}
+
 func GetAgbotDeploymentPols(ec ExchangeContext) (map[string]ServedBusinessPolicy, error) {
 
 	var resp interface{}
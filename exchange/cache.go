@@ -2,6 +2,7 @@ package exchange
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/golang/glog"
@@ -300,6 +301,7 @@ func DeleteCacheResource(resourceType string, resourceKey string) interface{} {
 // DeleteOrgCachedResources will delete all cached resources from the given org
 func DeleteOrgCachedResources(org string) {
 	glog.V(5).Infof("Delete all resources from org %v", org)
+	MarkCachedResponsesStale(org, "")
 	if ExchangeResourceCache == nil || ExchangeResourceCache.allResources == nil {
 		return
 	}
@@ -324,6 +326,17 @@ func DeleteCacheNodeWriteThru(nodeOrg string, nodeId string) *Device {
 	return nodeDef
 }
 
+// DeleteCacheNodeIfKeyChanged will delete the given node when its cached messaging key is not the input key, so that the
+// next read gets the key that the node rotated to from the exchange.
+func DeleteCacheNodeIfKeyChanged(nodeOrg string, nodeId string, publicKey []byte) bool {
+	if nodeDef := GetNodeFromCache(nodeOrg, nodeId); nodeDef != nil && nodeDef.PublicKey != base64.StdEncoding.EncodeToString(publicKey) {
+		glog.V(3).Infof("Messaging key for node %s/%s has changed, removing it from the cache", nodeOrg, nodeId)
+		DeleteCacheResource(NODE_DEF_TYPE_CACHE, NodeCacheMapKey(nodeOrg, nodeId))
+		return true
+	}
+	return false
+}
+
 // UpdateCacheNodePutWriteThru will update the cached node with the provided changed node def being put to the exchange
 // the device request is returned with the fields used to update the device erased. This allows us to ensure all the pdr fields are being used to update the cached device
 func UpdateCacheNodePutWriteThru(nodeOrg string, nodeId string, cachedDevice *Device, pdr *PutDeviceRequest) {
@@ -420,8 +433,11 @@ func UpdateCacheNodePatchWriteThru(nodeOrg string, nodeId string, cachedDevice *
 	}
 }
 
-// DeleteCacheResourceFromChange takes an ExchangeChange and attempts to delete the now out-of-date exchange cache resource if it is present
+// DeleteCacheResourceFromChange takes an ExchangeChange and attempts to delete the now out-of-date exchange cache resource if it is present.
+// The cached exchange responses of the changed resource are marked stale so that they are downloaded again on the next read.
 func DeleteCacheResourceFromChange(change ExchangeChange, nodeId string) interface{} {
+	markCachedResponsesStaleFromChange(change)
+
 	if change.IsService() {
 		id, arch, _ := svcInformationFromSvcId(change.ID)
 		DeleteCacheResource(SVC_DEF_TYPE_CACHE, ServiceCacheMapKey(change.OrgID, id, arch))
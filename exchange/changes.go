@@ -3,7 +3,6 @@ package exchange
 import (
 	"fmt"
 	"github.com/golang/glog"
-	"time"
 )
 
 // The LastUpdated field is explicitly omitted due to a pending change to the datatype of the field.
@@ -231,7 +230,7 @@ func GetExchangeChangeID(ec ExchangeContext) (*ExchangeChangeIDResponse, error)
 	targetURL := fmt.Sprintf("%vchanges/maxchangeid", ec.GetExchangeURL())
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -239,13 +238,13 @@ func GetExchangeChangeID(ec ExchangeContext) (*ExchangeChangeIDResponse, error)
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -282,7 +281,7 @@ func GetExchangeChanges(ec ExchangeContext, changeId uint64, maxRecords int, org
 	targetURL := fmt.Sprintf("%vorgs/%v/changes", ec.GetExchangeURL(), GetOrg(ec.GetExchangeId()))
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "POST", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), &req, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -290,13 +289,13 @@ func GetExchangeChanges(ec ExchangeContext, changeId uint64, maxRecords int, org
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
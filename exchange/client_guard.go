@@ -0,0 +1,358 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+)
+
+// The client side protection of the exchange, shared by every worker in the process. Each call made with InvokeExchange
+// is rate limited by the class of exchange resource it is for, and fails immediately while the circuit to the exchange
+// host is open. Retries of failed calls wait for a jittered exponential backoff so that agents which saw the same outage
+// do not all retry at the same moment, and wait even longer when the retry budget of the resource class is used up.
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// The retry budget of a resource class works like the retry throttling in gRPC. Each transport error takes a token
+// from the budget and each successful call gives back a fraction of one. Once the budget is down to half, retries of
+// calls to that class wait for the longest backoff.
+const retryBudgetMax = 10.0
+const retryBudgetSuccessCredit = 0.1
+
+// A token bucket rate limiter. Tokens are borrowed when the bucket is empty, so callers are served in the order they
+// arrived and each one is told how long to wait for its turn.
+type tokenBucket struct {
+	ratePerS float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(ratePerS float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		ratePerS: ratePerS,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     now,
+	}
+}
+
+// Take a token, returning how long the caller has to wait before using it.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.ratePerS
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	b.tokens -= 1
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.ratePerS * float64(time.Second))
+}
+
+// The circuit breaker of an exchange host. It opens after a run of consecutive failures, then lets a single probe call
+// through once the open period is over. The circuit closes when the probe succeeds and opens again, for twice as
+// long, when it fails.
+type circuitBreaker struct {
+	state        circuitState
+	failures     int
+	opens        int
+	openUntil    time.Time
+	probeStarted time.Time
+}
+
+type clientGuard struct {
+	lock     sync.Mutex
+	config   config.ExchangeClientConfig
+	agbot    bool
+	limiters map[string]*tokenBucket
+	breakers map[string]*circuitBreaker
+	budgets  map[string]float64
+}
+
+func newClientGuard(c config.ExchangeClientConfig, agbot bool) *clientGuard {
+	return &clientGuard{
+		config:   c,
+		agbot:    agbot,
+		limiters: make(map[string]*tokenBucket),
+		breakers: make(map[string]*circuitBreaker),
+		budgets:  make(map[string]float64),
+	}
+}
+
+// The guard is only turned on by the agent and the agbot, calls made by other users of this package are not limited.
+var exchangeClientGuard *clientGuard
+
+func ConfigureExchangeClient(c config.ExchangeClientConfig, agbot bool) {
+	exchangeClientGuard = newClientGuard(c, agbot)
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("exchange client settings: %v", c)))
+}
+
+// Wait for the rate limiter of the resource class, or fail when the circuit to the exchange host is open.
+func (g *clientGuard) admit(host string, class string) error {
+	g.lock.Lock()
+	now := time.Now()
+	if err := g.allow(host, now); err != nil {
+		g.lock.Unlock()
+		exchangeCircuitRejected.Inc(class)
+		return err
+	}
+
+	wait := time.Duration(0)
+	if rate := g.config.GetRateLimitPerS(g.agbot); rate > 0 {
+		limiter, ok := g.limiters[class]
+		if !ok {
+			limiter = newTokenBucket(rate, g.config.GetRateLimitBurst(g.agbot), now)
+			g.limiters[class] = limiter
+		}
+		wait = limiter.take(now)
+	}
+	g.lock.Unlock()
+
+	if wait > 0 {
+		exchangeThrottled.Inc(class)
+		exchangeThrottleWait.Add(wait.Seconds(), class)
+		if glog.V(5) {
+			glog.Infof(rpclogString(fmt.Sprintf("rate limit for %v reached, waiting %v", class, wait)))
+		}
+		time.Sleep(wait)
+	}
+	return nil
+}
+
+// Returns an error when no call can be made to the host. The caller must hold the guard's lock.
+func (g *clientGuard) allow(host string, now time.Time) error {
+	if g.config.GetBreakerFailureThreshold() < 0 {
+		return nil
+	}
+
+	cb, ok := g.breakers[host]
+	if !ok {
+		return nil
+	}
+
+	switch cb.state {
+	case circuitOpen:
+		if now.Before(cb.openUntil) {
+			return errors.New(fmt.Sprintf("Invocation of exchange at %v rejected, the circuit is open after %v consecutive failures, the next attempt will be made at %v", host, cb.failures, cb.openUntil.Format(time.RFC3339)))
+		}
+		// This call is the probe.
+		g.setState(host, cb, circuitHalfOpen)
+		cb.probeStarted = now
+	case circuitHalfOpen:
+		// Only one probe at a time, unless the last one was lost.
+		if now.Sub(cb.probeStarted) < time.Duration(g.config.GetBreakerOpenS())*time.Second {
+			return errors.New(fmt.Sprintf("Invocation of exchange at %v rejected, the circuit is half open and waiting for the result of a probe call", host))
+		}
+		cb.probeStarted = now
+	}
+	return nil
+}
+
+// Record the outcome of a call. Transport errors and 5xx responses are failures of the host. Other responses,
+// except for 408 and 429 which only say that the host is busy, show that the host is working.
+func (g *clientGuard) record(host string, class string, status int, tpErr error) {
+	failed := status >= http.StatusInternalServerError || (tpErr != nil && status == 0)
+	succeeded := status != 0 && !failed && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	budget, ok := g.budgets[class]
+	if !ok {
+		budget = retryBudgetMax
+	}
+	if tpErr != nil {
+		budget -= 1
+		if budget < 0 {
+			budget = 0
+		}
+	} else if succeeded {
+		budget += retryBudgetSuccessCredit
+		if budget > retryBudgetMax {
+			budget = retryBudgetMax
+		}
+	}
+	g.budgets[class] = budget
+
+	threshold := g.config.GetBreakerFailureThreshold()
+	if threshold < 0 {
+		return
+	}
+
+	cb, ok := g.breakers[host]
+	if !ok {
+		if !failed {
+			return
+		}
+		cb = &circuitBreaker{}
+		g.breakers[host] = cb
+	}
+
+	if succeeded {
+		if cb.state != circuitClosed {
+			glog.Infof(rpclogString(fmt.Sprintf("circuit to %v closed, the exchange is responding again", host)))
+		}
+		g.setState(host, cb, circuitClosed)
+		cb.failures = 0
+		cb.opens = 0
+	} else if failed {
+		cb.failures += 1
+		if cb.state == circuitHalfOpen || (cb.state == circuitClosed && cb.failures >= threshold) {
+			cb.opens += 1
+			openFor := jitter(g.openPeriod(cb.opens))
+			cb.openUntil = time.Now().Add(openFor)
+			g.setState(host, cb, circuitOpen)
+			glog.Warningf(rpclogString(fmt.Sprintf("circuit to %v opened for %v after %v consecutive failures", host, openFor, cb.failures)))
+		}
+	}
+}
+
+// The open period doubles each time a probe fails, up to the longest backoff.
+func (g *clientGuard) openPeriod(opens int) time.Duration {
+	return exponential(time.Duration(g.config.GetBreakerOpenS())*time.Second, opens-1, time.Duration(g.config.GetBackoffMaxS())*time.Second)
+}
+
+func (g *clientGuard) setState(host string, cb *circuitBreaker, state circuitState) {
+	cb.state = state
+	exchangeCircuitState.Set(float64(state), host)
+}
+
+func (g *clientGuard) retryBudgetExhausted(class string) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	budget, ok := g.budgets[class]
+	return ok && budget <= retryBudgetMax/2
+}
+
+// Make a call to the exchange through the guard. The call is given a copy of the HTTP client that records the status
+// of the response, so that the guard can tell a 5xx response from other request errors.
+func guardExchangeCall(httpClient *http.Client, urlPath string, call func(*http.Client) (error, error)) (error, error) {
+	g := exchangeClientGuard
+	if g == nil || httpClient == nil {
+		return call(httpClient)
+	}
+
+	// The circuit is to the host that the request is sent to, the transport can send it to an alternate endpoint.
+	host := urlPath
+	if r, ok := httpClient.Transport.(interface{ ResolveURL(string) string }); ok {
+		host = r.ResolveURL(urlPath)
+	}
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	class := ExchangeResourceClass(urlPath)
+
+	if err := g.admit(host, class); err != nil {
+		return nil, err
+	}
+
+	recorder := &statusRecorder{base: httpClient.Transport}
+	if recorder.base == nil {
+		recorder.base = http.DefaultTransport
+	}
+	guarded := *httpClient
+	guarded.Transport = recorder
+
+	err, tpErr := call(&guarded)
+	g.record(host, class, recorder.status, tpErr)
+	return err, tpErr
+}
+
+type statusRecorder struct {
+	base   http.RoundTripper
+	status int
+}
+
+func (r *statusRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if resp != nil {
+		r.status = resp.StatusCode
+	}
+	return resp, err
+}
+
+// The wait between the retries of a failed exchange call. The first wait is the retry interval of the HTTP client
+// factory, and each one after that is twice as long up to the longest backoff. Each wait is jittered.
+type RetryBackoff struct {
+	interval time.Duration
+	class    string
+	attempt  int
+}
+
+func NewRetryBackoff(httpClientFactory *config.HTTPClientFactory, urlPath string) *RetryBackoff {
+	return &RetryBackoff{
+		interval: time.Duration(httpClientFactory.GetRetryInterval()) * time.Second,
+		class:    ExchangeResourceClass(urlPath),
+	}
+}
+
+// Returns how long to wait before the next retry.
+func (b *RetryBackoff) Next() time.Duration {
+	maxBackoff := time.Duration(config.ExchangeBackoffMaxS_DEFAULT) * time.Second
+	g := exchangeClientGuard
+	if g != nil {
+		maxBackoff = time.Duration(g.config.GetBackoffMaxS()) * time.Second
+	}
+
+	wait := exponential(b.interval, b.attempt, maxBackoff)
+	if g != nil && g.retryBudgetExhausted(b.class) {
+		exchangeRetryBudgetExhausted.Inc(b.class)
+		wait = maxBackoff
+	}
+	b.attempt += 1
+	exchangeRetries.Inc(b.class)
+	return jitter(wait)
+}
+
+// Wait before the next retry.
+func (b *RetryBackoff) Wait() {
+	time.Sleep(b.Next())
+}
+
+// Double the base duration attempt times, without going over the max.
+func exponential(base time.Duration, attempt int, max time.Duration) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// Pick a random duration between half of d and d.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
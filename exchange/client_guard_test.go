@@ -0,0 +1,155 @@
+//go:build unit
+// +build unit
+
+package exchange
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/config"
+)
+
+func guardTestSetup(t *testing.T, c config.ExchangeClientConfig) {
+	ConfigureExchangeClient(c, false)
+	t.Cleanup(func() { exchangeClientGuard = nil })
+}
+
+func getOrg(url string) (error, error) {
+	var resp interface{}
+	resp = new(GetOrganizationResponse)
+	return InvokeExchange(http.DefaultClient, "GET", url+"/v1/orgs/myorg", "", "", nil, &resp)
+}
+
+func Test_CircuitBreaker(t *testing.T) {
+	guardTestSetup(t, config.ExchangeClientConfig{RateLimitPerS: -1, BreakerFailureThreshold: 3})
+
+	var calls int32
+	healthy := atomic.Bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if healthy.Load() {
+			w.Write([]byte("{}"))
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	// The circuit opens after 3 failures, then calls are rejected without reaching the exchange.
+	for i := 0; i < 3; i++ {
+		if _, tpErr := getOrg(server.URL); tpErr == nil {
+			t.Fatalf("expecting a transport error")
+		}
+	}
+	if _, tpErr := getOrg(server.URL); tpErr == nil {
+		t.Errorf("expecting the call to be rejected")
+	} else if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("expecting 3 calls to the exchange, was %v", calls)
+	}
+
+	host := server.Listener.Addr().String()
+	if cb := exchangeClientGuard.breakers[host]; cb.state != circuitOpen {
+		t.Fatalf("expecting the circuit to be open, was %v", cb.state)
+	}
+
+	// Once the open period is over, one probe is let through. It fails, so the circuit opens again.
+	exchangeClientGuard.breakers[host].openUntil = time.Now()
+	if _, tpErr := getOrg(server.URL); tpErr == nil {
+		t.Errorf("expecting the probe to fail")
+	} else if cb := exchangeClientGuard.breakers[host]; cb.state != circuitOpen || atomic.LoadInt32(&calls) != 4 {
+		t.Errorf("expecting the circuit to be open again after 4 calls, was %v after %v calls", cb.state, calls)
+	}
+
+	// A successful probe closes the circuit.
+	healthy.Store(true)
+	exchangeClientGuard.breakers[host].openUntil = time.Now()
+	if err, tpErr := getOrg(server.URL); err != nil || tpErr != nil {
+		t.Errorf("expecting the probe to succeed, was %v %v", err, tpErr)
+	} else if cb := exchangeClientGuard.breakers[host]; cb.state != circuitClosed || cb.failures != 0 {
+		t.Errorf("expecting the circuit to be closed, was %v with %v failures", cb.state, cb.failures)
+	}
+}
+
+func Test_CircuitBreaker_halfOpen(t *testing.T) {
+	guardTestSetup(t, config.ExchangeClientConfig{BreakerFailureThreshold: 1, BreakerOpenS: 60})
+	g := exchangeClientGuard
+
+	g.record("exch", "nodes", http.StatusBadGateway, nil)
+	g.breakers["exch"].openUntil = time.Now()
+
+	// Only one probe at a time.
+	if err := g.allow("exch", time.Now()); err != nil {
+		t.Errorf("expecting the probe to be let through, error %v", err)
+	} else if err := g.allow("exch", time.Now()); err == nil {
+		t.Errorf("expecting a second call to be rejected while the probe is running")
+	} else if err := g.allow("exch", time.Now().Add(61*time.Second)); err != nil {
+		t.Errorf("expecting a new probe once the last one is lost, error %v", err)
+	}
+
+	// Responses that only say the exchange is busy neither open nor close the circuit.
+	g.record("exch", "nodes", http.StatusTooManyRequests, nil)
+	if g.breakers["exch"].state != circuitHalfOpen {
+		t.Errorf("expecting the circuit to stay half open, was %v", g.breakers["exch"].state)
+	}
+	g.record("exch", "nodes", http.StatusNotFound, nil)
+	if g.breakers["exch"].state != circuitClosed {
+		t.Errorf("expecting the circuit to be closed, was %v", g.breakers["exch"].state)
+	}
+}
+
+func Test_TokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(2, 2, now)
+
+	// The burst is available at once, then callers wait their turn.
+	if w := b.take(now); w != 0 {
+		t.Errorf("expecting no wait, was %v", w)
+	} else if w := b.take(now); w != 0 {
+		t.Errorf("expecting no wait, was %v", w)
+	} else if w := b.take(now); w != 500*time.Millisecond {
+		t.Errorf("expecting a 500ms wait, was %v", w)
+	} else if w := b.take(now); w != time.Second {
+		t.Errorf("expecting a 1s wait, was %v", w)
+	}
+
+	// Tokens are refilled over time, up to the burst.
+	if w := b.take(now.Add(time.Hour)); w != 0 {
+		t.Errorf("expecting no wait, was %v", w)
+	} else if b.tokens != 1 {
+		t.Errorf("expecting 1 token left, was %v", b.tokens)
+	}
+}
+
+func Test_RetryBackoff(t *testing.T) {
+	guardTestSetup(t, config.ExchangeClientConfig{BackoffMaxS: 60})
+	url := "https://exch.com/v1/orgs/myorg/nodes/n1"
+
+	b := NewRetryBackoff(&config.HTTPClientFactory{RetryInterval: 10}, url)
+	for _, max := range []time.Duration{10, 20, 40, 60, 60} {
+		max = max * time.Second
+		if w := b.Next(); w < max/2 || w > max {
+			t.Errorf("expecting a wait between %v and %v, was %v", max/2, max, w)
+		}
+	}
+
+	// Once the retry budget is used up, every retry waits for the longest backoff.
+	for i := 0; i < 6; i++ {
+		exchangeClientGuard.record("exch.com", "nodes", 0, http.ErrHandlerTimeout)
+	}
+	b = NewRetryBackoff(&config.HTTPClientFactory{RetryInterval: 10}, url)
+	if w := b.Next(); w < 30*time.Second {
+		t.Errorf("expecting a wait of at least 30s, was %v", w)
+	}
+
+	// Successful calls earn the budget back.
+	for i := 0; i < 20; i++ {
+		exchangeClientGuard.record("exch.com", "nodes", http.StatusOK, nil)
+	}
+	if exchangeClientGuard.retryBudgetExhausted("nodes") {
+		t.Errorf("expecting the retry budget to be available")
+	}
+}
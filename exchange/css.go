@@ -12,7 +12,6 @@ import (
 	"os"
 	"path"
 	"strconv"
-	"time"
 )
 
 type MetaDataList []common.MetaData
@@ -97,7 +96,7 @@ func GetObjectsByService(ec ExchangeContext, org string, serviceId string) (*Obj
 	url = ec.GetCSSURL() + url + fmt.Sprintf("?destination_policy=true&service=%v", serviceId)
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", url, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -105,13 +104,13 @@ func GetObjectsByService(ec ExchangeContext, org string, serviceId string) (*Obj
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -140,7 +139,7 @@ func GetUpdatedObjects(ec ExchangeContext, org string, since int64) (*ObjectDest
 	}
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", url, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -148,13 +147,13 @@ func GetUpdatedObjects(ec ExchangeContext, org string, since int64) (*ObjectDest
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -176,7 +175,7 @@ func AddOrRemoveDestinations(ec ExchangeContext, org string, objType string, obj
 	url = ec.GetCSSURL() + url
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "POST", url, ec.GetExchangeId(), ec.GetExchangeToken(), postDestsRequest, &resp); err != nil {
@@ -185,13 +184,13 @@ func AddOrRemoveDestinations(ec ExchangeContext, org string, objType string, obj
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -218,7 +217,7 @@ func GetObject(ec ExchangeContext, org string, objID string, objType string) (*c
 	url = ec.GetCSSURL() + url
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", url, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -226,13 +225,13 @@ func GetObject(ec ExchangeContext, org string, objID string, objType string) (*c
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -267,7 +266,7 @@ func GetCSSObjectsByType(ec ExchangeContext, org string, objType string) (*MetaD
 	}
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", url, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -275,13 +274,13 @@ func GetCSSObjectsByType(ec ExchangeContext, org string, objType string) (*MetaD
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -293,6 +292,13 @@ func GetCSSObjectsByType(ec ExchangeContext, org string, objType string) (*MetaD
 
 // Get the object data
 func GetObjectData(ec ExchangeContext, org string, objType string, objId string, filePath string, fileName string, objectMeta *common.MetaData, saveToTempFile bool) error {
+	reader := &countingReader{}
+	err := getObjectData(ec, org, objType, objId, filePath, fileName, saveToTempFile, reader)
+	observeObjectDownload(objType, reader.count, err)
+	return err
+}
+
+func getObjectData(ec ExchangeContext, org string, objType string, objId string, filePath string, fileName string, saveToTempFile bool, reader *countingReader) error {
 	url := path.Join("/api/v1/objects", org, objType, objId, "data")
 	url = ec.GetCSSURL() + url
 
@@ -312,7 +318,7 @@ func GetObjectData(ec ExchangeContext, org string, objType string, objId string,
 	timeoutS := uint(0)
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 	for {
 		response, err := ec.GetHTTPFactory().NewHTTPClient(&timeoutS).Do(request)
 
@@ -328,7 +334,7 @@ func GetObjectData(ec ExchangeContext, org string, objType string, objId string,
 				if ec.GetHTTPFactory().RetryCount != 0 {
 					retryCount--
 				}
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, err)
@@ -342,7 +348,8 @@ func GetObjectData(ec ExchangeContext, org string, objType string, objId string,
 			return fmt.Errorf("Failed to create folder %v for agent upgrade files: %s\n", filePath, err)
 		}
 
-		err = cutil.WriteDateStreamToFile(response.Body, path.Join(filePath, fileName))
+		reader.reader = response.Body
+		err = cutil.WriteDateStreamToFile(reader, path.Join(filePath, fileName))
 		if err != nil {
 			return fmt.Errorf("Failed to read the body of a get containing the data for the object: %s\n", err)
 		}
@@ -386,7 +393,7 @@ func GetObjectDataByChunk(ec ExchangeContext, org string, objType string, objId
 	defer file.Close()
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 
 	// When getting data out of CSS, sometimes it takes a while. We need a longer client timeout than the default 30 seconds. Setting to 0 so haproxy timeout will be longer than client timeout.
 	// If we get an haproxy timeout, that will be a transport error and we will retry.
@@ -407,7 +414,7 @@ func GetObjectDataByChunk(ec ExchangeContext, org string, objType string, objId
 				if ec.GetHTTPFactory().RetryCount != 0 {
 					retryCount--
 				}
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return false, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, err)
@@ -423,11 +430,12 @@ func GetObjectDataByChunk(ec ExchangeContext, org string, objType string, objId
 				return false, fmt.Errorf("Failed to seek to the offset %d of a file. Error: %v", startOffset, err)
 			}
 
-			_, err = io.Copy(file, response.Body)
+			written, err := io.Copy(file, response.Body)
 			if err != nil && err != io.EOF {
 				return false, fmt.Errorf("Failed to write to file. Error: %v", err)
 			}
 
+			cssObjectDownloadBytes.Add(float64(written), objType)
 			return false, nil
 		} else if response != nil && response.StatusCode == http.StatusOK {
 			// received all data
@@ -439,6 +447,8 @@ func GetObjectDataByChunk(ec ExchangeContext, org string, objType string, objId
 				return false, fmt.Errorf("Failed to write to file. Error: %v", err)
 			} else if written != int64(endOffset-startOffset+1) {
 				return false, fmt.Errorf("Failed to write all the data to file.")
+			} else {
+				observeObjectDownload(objType, written, nil)
 			}
 
 			return true, nil
@@ -476,7 +486,7 @@ func GetObjectDestinations(ec ExchangeContext, org string, objID string, objType
 	url = ec.GetCSSURL() + url
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", url, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -484,13 +494,13 @@ func GetObjectDestinations(ec ExchangeContext, org string, objID string, objType
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -524,7 +534,7 @@ func SetPolicyReceived(ec ExchangeContext, objPol *ObjectDestinationPolicy) erro
 	url = ec.GetCSSURL() + url
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), url)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "PUT", url, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -532,13 +542,13 @@ func SetPolicyReceived(ec ExchangeContext, objPol *ObjectDestinationPolicy) erro
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -0,0 +1,643 @@
+package exchange
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"golang.org/x/crypto/sha3"
+)
+
+// Messages are sent in a versioned envelope. Version 1 is the original envelope, an AES-GCM encrypted WrappedMessage
+// whose key is encrypted with the receiver's RSA public key, and which is signed with the sender's RSA private key.
+// Version 2 is a hybrid envelope, the AES-GCM key is derived from an X25519 key agreement with the receiver, the
+// envelope header is bound to the ciphertext as GCM additional data, and the whole envelope is signed with the sender's
+// Ed25519 key. The X25519 and Ed25519 keys are bound to the sender's RSA identity with an RSA signature, so the RSA
+// key published in the exchange is still the root of trust.
+//
+// A runtime only sends version 2 to a counterpart that has advertised it, in the WrappedMessage of a version 1 message
+// or in a version 2 message, so older agents and agbots keep working. Both versions carry a sender timestamp and a
+// random nonce, which the receiver uses to reject messages that are too old or that have been seen before.
+
+const (
+	ENVELOPE_V1 = 1
+	ENVELOPE_V2 = 2
+)
+
+const envelopeV2Suite = "x25519-ed25519-aes256gcm"
+const envelopeV2Info = "open-horizon anax message envelope v2"
+const keyBindingContext = "open-horizon anax message key binding"
+
+// How far ahead of the receiver's clock a sender timestamp can be.
+const maxClockSkew = 5 * time.Minute
+
+// How long the capabilities advertised by a counterpart are remembered after its last message. A counterpart that is
+// downgraded to an older release gets the version 1 envelope again once this has expired.
+const peerCapabilitiesTTL = time.Hour
+
+var hybridFileName = "hybridMessagingKeys.pem"
+
+// The messaging settings, set once at startup.
+var messagingConfig config.MessagingConfig
+
+func ConfigureMessaging(c config.MessagingConfig) {
+	messagingConfig = c
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("message envelope settings: %v", c)))
+}
+
+// The on the wire form of the version 2 envelope header. The marshalled header is signed and is used as the GCM
+// additional data, so none of it can be changed without the receiver noticing.
+type EnvelopeHeader struct {
+	Version      int    `json:"version"`
+	Suite        string `json:"suite"`
+	Timestamp    int64  `json:"timestamp"`    // Seconds since the epoch, when the sender created the message
+	Nonce        []byte `json:"nonce"`        // Random message identifier used to detect replays
+	EphemeralKey []byte `json:"ephemeralKey"` // The sender's one time X25519 public key
+	RecipientKey []byte `json:"recipientKey"` // The receiver's X25519 public key
+	SenderKey    []byte `json:"senderKey"`    // The sender's Ed25519 public key
+	IV           []byte `json:"iv"`           // The GCM nonce
+}
+
+func (h EnvelopeHeader) String() string {
+	return fmt.Sprintf("Version: %v, Suite: %v, Timestamp: %v, Nonce: %x", h.Version, h.Suite, h.Timestamp, h.Nonce)
+}
+
+// The plain text of a version 2 envelope.
+type envelopePayload struct {
+	Msg          []byte `json:"msg"`
+	SignerPubKey []byte `json:"signerPubkey"` // The sender's RSA public key
+	X25519Key    []byte `json:"x25519Key"`
+	KeyBinding   []byte `json:"keyBinding"`
+}
+
+// The X25519 and Ed25519 keys of this runtime. They are created with the RSA keys and kept in the same directory.
+type hybridKeys struct {
+	sign    ed25519.PrivateKey
+	exch    *ecdh.PrivateKey
+	binding []byte          // The RSA signature over the public keys
+	boundTo *rsa.PrivateKey // The RSA key that made the binding
+}
+
+// Protected by the KeyLock.
+var gHybridKeys *hybridKeys
+var gKeyPath string
+
+// The result of opening a message, in either envelope version.
+type openedMessage struct {
+	msg        []byte
+	signerKey  *rsa.PublicKey
+	version    int
+	timestamp  int64
+	nonce      []byte
+	maxVersion int
+	exchKey    []byte
+	signKey    []byte
+}
+
+// Load this runtime's hybrid keys, creating them if necessary. The caller must hold the KeyLock. The keys are created
+// without overwriting an existing file, so that agbot instances that share the key directory end up with the same keys.
+func loadHybridKeys() (*hybridKeys, error) {
+	if gHybridKeys != nil {
+		return gHybridKeys, nil
+	}
+
+	fileName := filepath.Join(keyDir(gKeyPath), hybridFileName)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		if err := createHybridKeyFile(fileName); err != nil {
+			return nil, err
+		}
+	}
+
+	hk := new(hybridKeys)
+	rest, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read hybrid key file %v, error: %v", fileName, err))
+	}
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		} else if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to parse hybrid key file %v, error: %v", fileName, err))
+		} else {
+			switch k := key.(type) {
+			case ed25519.PrivateKey:
+				hk.sign = k
+			case *ecdh.PrivateKey:
+				hk.exch = k
+			}
+		}
+	}
+	if hk.sign == nil || hk.exch == nil {
+		return nil, errors.New(fmt.Sprintf("Hybrid key file %v does not contain both an Ed25519 and an X25519 key", fileName))
+	}
+
+	gHybridKeys = hk
+	return gHybridKeys, nil
+}
+
+func createHybridKeyFile(fileName string) error {
+	_, signKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not generate Ed25519 key, error %v", err))
+	}
+	exchKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not generate X25519 key, error %v", err))
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not create hybrid key file %v, error %v", fileName, err))
+	}
+	defer os.Remove(tmpFile.Name())
+
+	for _, key := range []interface{}{signKey, exchKey} {
+		if keyBytes, err := x509.MarshalPKCS8PrivateKey(key); err != nil {
+			tmpFile.Close()
+			return errors.New(fmt.Sprintf("Could not marshal hybrid key, error %v", err))
+		} else if err := pem.Encode(tmpFile, &pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}); err != nil {
+			tmpFile.Close()
+			return errors.New(fmt.Sprintf("Could not encode hybrid key to file %v, error %v", fileName, err))
+		}
+	}
+	if err := tmpFile.Chmod(0600); err != nil {
+		tmpFile.Close()
+		return errors.New(fmt.Sprintf("Could not chmod hybrid key file %v, error %v", fileName, err))
+	} else if err := tmpFile.Close(); err != nil {
+		return errors.New(fmt.Sprintf("Could not close hybrid key file %v, error %v", fileName, err))
+	}
+
+	// Link fails if another process created the file first, in which case its keys are used.
+	if err := os.Link(tmpFile.Name(), fileName); err != nil && !os.IsExist(err) {
+		return errors.New(fmt.Sprintf("Could not create hybrid key file %v, error %v", fileName, err))
+	}
+	return nil
+}
+
+// Return a copy of the hybrid keys, bound to the input RSA key. Only the runtime's own RSA key can be used, so a
+// caller that passes some other key gets nil and sends a version 1 envelope.
+func boundHybridKeys(rsaKey *rsa.PrivateKey) *hybridKeys {
+	KeyLock.Lock()
+	defer KeyLock.Unlock()
+
+	if rsaKey == nil || rsaKey != gPrivateKey {
+		return nil
+	}
+	hk, err := loadHybridKeys()
+	if err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("unable to load hybrid messaging keys, error %v", err)))
+		return nil
+	}
+	if hk.boundTo != rsaKey {
+		exchKey := hk.exch.PublicKey().Bytes()
+		if binding, err := signKeyBinding(rsaKey, hk.sign.Public().(ed25519.PublicKey), exchKey); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf("unable to bind hybrid messaging keys, error %v", err)))
+			return nil
+		} else {
+			hk.binding = binding
+			hk.boundTo = rsaKey
+		}
+	}
+	res := *hk
+	return &res
+}
+
+func keyBindingDigest(signKey []byte, exchKey []byte) [32]byte {
+	return sha3.Sum256(append(append([]byte(keyBindingContext), signKey...), exchKey...))
+}
+
+func signKeyBinding(rsaKey *rsa.PrivateKey, signKey []byte, exchKey []byte) ([]byte, error) {
+	digest := keyBindingDigest(signKey, exchKey)
+	return rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA3_256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+}
+
+func verifyKeyBinding(rsaKey *rsa.PublicKey, signKey []byte, exchKey []byte, binding []byte) error {
+	digest := keyBindingDigest(signKey, exchKey)
+	return rsa.VerifyPSS(rsaKey, crypto.SHA3_256, digest[:], binding, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+}
+
+func newMessageNonce() ([]byte, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error getting random message nonce, error %v", err))
+	}
+	return nonce, nil
+}
+
+// Derive the AES-256 key from an X25519 shared secret, binding it to both public keys.
+func deriveEnvelopeKey(shared []byte, ephemeralKey []byte, recipientKey []byte) ([]byte, error) {
+	return hkdf.Key(sha256.New, shared, append(append([]byte{}, ephemeralKey...), recipientKey...), envelopeV2Info, 32)
+}
+
+// Construct a version 2 envelope for a receiver that has advertised its X25519 key.
+func constructEnvelopeV2(message []byte, senderPublicKey *rsa.PublicKey, hk *hybridKeys, receiverExchKey []byte) (*ExchangeMessage, error) {
+
+	recipient, err := ecdh.X25519().NewPublicKey(receiverExchKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error using receiver X25519 key, error %v", err))
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error generating ephemeral X25519 key, error %v", err))
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error in X25519 key agreement, error %v", err))
+	}
+	key, err := deriveEnvelopeKey(shared, ephemeral.PublicKey().Bytes(), receiverExchKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error deriving envelope key, error %v", err))
+	}
+
+	nonce, err := newMessageNonce()
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, 12)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error getting random iv, error %v", err))
+	}
+
+	header := EnvelopeHeader{
+		Version:      ENVELOPE_V2,
+		Suite:        envelopeV2Suite,
+		Timestamp:    time.Now().Unix(),
+		Nonce:        nonce,
+		EphemeralKey: ephemeral.PublicKey().Bytes(),
+		RecipientKey: receiverExchKey,
+		SenderKey:    hk.sign.Public().(ed25519.PublicKey),
+		IV:           iv,
+	}
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error marshalling envelope header, error %v", err))
+	}
+
+	pubKey, err := MarshalPublicKey(senderPublicKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error marshalling sender public key, error %v", err))
+	}
+	payload, err := json.Marshal(envelopePayload{
+		Msg:          message,
+		SignerPubKey: pubKey,
+		X25519Key:    hk.exch.PublicKey().Bytes(),
+		KeyBinding:   hk.binding,
+	})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error marshalling envelope payload, error %v", err))
+	}
+
+	gcmCipher, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := gcmCipher.Seal(nil, iv, payload, headerBytes)
+
+	return &ExchangeMessage{
+		Version:    ENVELOPE_V2,
+		Header:     headerBytes,
+		Ciphertext: ciphertext,
+		Signature:  ed25519.Sign(hk.sign, append(append([]byte{}, headerBytes...), ciphertext...)),
+	}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if blockCipher, err := aes.NewCipher(key); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error getting AES block cipher object, error %v", err))
+	} else if gcmCipher, err := cipher.NewGCM(blockCipher); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error getting GCM block cipher object, error %v", err))
+	} else {
+		return gcmCipher, nil
+	}
+}
+
+// The error returned when a message was not encrypted for any of the receiver's keys. The receiver's keys could have
+// been rotated by another process that shares them, so reloading the keys might allow the message to be decrypted.
+type KeyMismatchError struct {
+	Msg string
+}
+
+func (e *KeyMismatchError) Error() string {
+	return e.Msg
+}
+
+func IsKeyMismatchError(err error) bool {
+	_, ok := err.(*KeyMismatchError)
+	return ok
+}
+
+// Open a version 2 envelope with this runtime's X25519 key. If the envelope was encrypted for a different key, another
+// agbot instance might have created the shared keys, so they are reloaded once.
+func openEnvelopeV2(em *ExchangeMessage) (*openedMessage, error) {
+
+	if len(em.Header) == 0 || len(em.Ciphertext) == 0 || len(em.Signature) == 0 {
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling exchange message, one of header, ciphertext or signature has length zero"))
+	}
+
+	header := new(EnvelopeHeader)
+	if err := json.Unmarshal(em.Header, header); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling envelope header, error %v", err))
+	} else if header.Version != ENVELOPE_V2 || header.Suite != envelopeV2Suite {
+		return nil, errors.New(fmt.Sprintf("Error unsupported envelope %v %v", header.Version, header.Suite))
+	} else if len(header.SenderKey) != ed25519.PublicKeySize {
+		return nil, errors.New(fmt.Sprintf("Error envelope sender key is not an Ed25519 key"))
+	} else if !ed25519.Verify(header.SenderKey, append(append([]byte{}, em.Header...), em.Ciphertext...), em.Signature) {
+		return nil, errors.New(fmt.Sprintf("Error verifying envelope signature"))
+	}
+
+	KeyLock.Lock()
+	hk, err := loadHybridKeys()
+	if err == nil && string(hk.exch.PublicKey().Bytes()) != string(header.RecipientKey) {
+		gHybridKeys = nil
+		hk, err = loadHybridKeys()
+	}
+	KeyLock.Unlock()
+	if err != nil {
+		return nil, err
+	} else if string(hk.exch.PublicKey().Bytes()) != string(header.RecipientKey) {
+		return nil, &KeyMismatchError{Msg: fmt.Sprintf("Error envelope was not encrypted for this runtime's X25519 key")}
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(header.EphemeralKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error using ephemeral X25519 key, error %v", err))
+	}
+	shared, err := hk.exch.ECDH(ephemeral)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error in X25519 key agreement, error %v", err))
+	}
+	key, err := deriveEnvelopeKey(shared, header.EphemeralKey, header.RecipientKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error deriving envelope key, error %v", err))
+	}
+	gcmCipher, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcmCipher.Open(nil, header.IV, em.Ciphertext, em.Header)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error decrypting message, error %v", err))
+	}
+
+	payload := new(envelopePayload)
+	if err := json.Unmarshal(plaintext, payload); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling envelope payload, error %v", err))
+	}
+	signerKey, err := DemarshalPublicKey(payload.SignerPubKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error demarshalling sender public key, %v", err))
+	} else if err := verifyKeyBinding(signerKey, header.SenderKey, payload.X25519Key, payload.KeyBinding); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error verifying sender key binding, error %v", err))
+	}
+
+	return &openedMessage{
+		msg:        payload.Msg,
+		signerKey:  signerKey,
+		version:    ENVELOPE_V2,
+		timestamp:  header.Timestamp,
+		nonce:      header.Nonce,
+		maxVersion: ENVELOPE_V2,
+		exchKey:    payload.X25519Key,
+		signKey:    header.SenderKey,
+	}, nil
+}
+
+// ---------------- counterpart capabilities ----------------
+
+type peerCapabilities struct {
+	maxVersion int
+	exchKey    []byte
+	seen       time.Time
+}
+
+// The capabilities of the counterparts this runtime has received messages from, keyed by the fingerprint of their RSA
+// public key.
+var peers = make(map[string]peerCapabilities)
+var peersLock sync.Mutex
+
+func keyFingerprint(key *rsa.PublicKey) string {
+	if b, err := MarshalPublicKey(key); err != nil {
+		return ""
+	} else {
+		return fmt.Sprintf("%x", sha256.Sum256(b))
+	}
+}
+
+// Remember what a counterpart advertised in a message that has passed the replay checks.
+func learnPeer(om *openedMessage) {
+	fp := keyFingerprint(om.signerKey)
+	if fp == "" {
+		return
+	}
+
+	pc := peerCapabilities{maxVersion: ENVELOPE_V1, seen: time.Now()}
+	if om.maxVersion >= ENVELOPE_V2 && len(om.exchKey) != 0 {
+		pc.maxVersion = ENVELOPE_V2
+		pc.exchKey = om.exchKey
+	}
+
+	peersLock.Lock()
+	defer peersLock.Unlock()
+	for key, p := range peers {
+		if time.Since(p.seen) > peerCapabilitiesTTL {
+			delete(peers, key)
+		}
+	}
+	peers[fp] = pc
+}
+
+// Return the capabilities of the counterpart with the input RSA public key, or nil if it has not sent a message recently.
+func getPeer(key *rsa.PublicKey) *peerCapabilities {
+	fp := keyFingerprint(key)
+
+	peersLock.Lock()
+	defer peersLock.Unlock()
+	if p, ok := peers[fp]; ok && time.Since(p.seen) <= peerCapabilitiesTTL {
+		return &p
+	}
+	return nil
+}
+
+// ---------------- replay detection ----------------
+
+// A place to keep the nonces of the received messages, and the senders that are known to send timestamps, across
+// restarts. The agent uses its bolt DB and the agbot uses its database, which is shared by the agbot instances that
+// read the same messages.
+type ReplayStore interface {
+	// Save the nonce if it has not been saved yet, and return the exchange message id that it was first saved with.
+	SaveMessageNonce(nonce string, msgId int, expires int64) (int, error)
+	DeleteExpiredMessageNonces(now int64) error
+	SaveTimestampSender(fingerprint string) error
+	IsTimestampSender(fingerprint string) (bool, error)
+}
+
+type replayEntry struct {
+	msgId   int
+	expires time.Time
+}
+
+// The nonces of the messages received recently. The exchange message id is remembered with each nonce, because a
+// message is read from the exchange again if it was not deleted before the next poll. A replayed message has a new
+// exchange message id. The nonces are kept in memory and, when there is a store, in the store so that they survive a
+// restart.
+type replayCache struct {
+	lock      sync.Mutex
+	seen      map[string]replayEntry
+	senders   map[string]bool // The senders known to send timestamps
+	lastPrune time.Time
+	store     ReplayStore
+}
+
+var messageReplayCache = newReplayCache(nil)
+
+func newReplayCache(store ReplayStore) *replayCache {
+	return &replayCache{
+		seen:    make(map[string]replayEntry),
+		senders: make(map[string]bool),
+		store:   store,
+	}
+}
+
+// Keep the nonces of the received messages, and the senders known to send timestamps, in the input store.
+func ConfigureReplayStore(store ReplayStore) {
+	messageReplayCache = newReplayCache(store)
+}
+
+func (r *replayCache) check(nonce []byte, msgId int, expires time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastPrune) > time.Minute {
+		for key, e := range r.seen {
+			if now.After(e.expires) {
+				delete(r.seen, key)
+			}
+		}
+		if r.store != nil {
+			if err := r.store.DeleteExpiredMessageNonces(now.Unix()); err != nil {
+				glog.Errorf(rpclogString(fmt.Sprintf("unable to delete expired message nonces, error %v", err)))
+			}
+		}
+		r.lastPrune = now
+	}
+
+	key := string(nonce)
+	if e, ok := r.seen[key]; ok && e.msgId != msgId {
+		return errors.New(fmt.Sprintf("message nonce %x was already received in message %v", nonce, e.msgId))
+	}
+	r.seen[key] = replayEntry{msgId: msgId, expires: expires}
+
+	// The nonce could have been received before a restart, or by another agbot instance.
+	if r.store != nil {
+		if firstId, err := r.store.SaveMessageNonce(fmt.Sprintf("%x", nonce), msgId, expires.Unix()); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf("unable to save message nonce %x, error %v", nonce, err)))
+		} else if firstId != msgId {
+			return errors.New(fmt.Sprintf("message nonce %x was already received in message %v", nonce, firstId))
+		}
+	}
+	return nil
+}
+
+// Remember that the sender with the input key fingerprint sends timestamps.
+func (r *replayCache) addTimestampSender(fp string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.senders[fp] {
+		return
+	} else if r.store != nil {
+		if err := r.store.SaveTimestampSender(fp); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf("unable to save message sender %v, error %v", fp, err)))
+			return
+		}
+	}
+	r.senders[fp] = true
+}
+
+// Returns true when the sender with the input key fingerprint has sent a message with a timestamp.
+func (r *replayCache) isTimestampSender(fp string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.senders[fp] {
+		return true
+	} else if r.store != nil {
+		if known, err := r.store.IsTimestampSender(fp); err != nil {
+			// Assume it is known, a message without a timestamp is rejected and will be sent again.
+			glog.Errorf(rpclogString(fmt.Sprintf("unable to find message sender %v, error %v", fp, err)))
+			return true
+		} else if known {
+			r.senders[fp] = true
+			return true
+		}
+	}
+	return false
+}
+
+// Open a message received from the exchange, with any of the receiver's RSA private keys, and check that it is not
+// a replay. The message id is the exchange's id for the message. Messages from counterparts that do not send a
+// timestamp are accepted, unless the counterpart has ever sent a message with one, which means it is running a
+// release that always sends it.
+func ReceiveExchangeMessage(encryptedMessage []byte, receiverPrivateKeys []*rsa.PrivateKey, msgId int) ([]byte, *rsa.PublicKey, error) {
+	om, err := openExchangeMessage(encryptedMessage, receiverPrivateKeys)
+	if err != nil {
+		messagesRejected.Inc("invalid")
+		return nil, nil, err
+	}
+
+	fp := keyFingerprint(om.signerKey)
+	if om.timestamp == 0 || len(om.nonce) == 0 {
+		if messageReplayCache.isTimestampSender(fp) {
+			messagesRejected.Inc("missing_timestamp")
+			return nil, nil, errors.New(fmt.Sprintf("Error message has no timestamp, but the sender is known to send one"))
+		}
+		messagesReceived.Inc(fmt.Sprintf("%v", om.version))
+		return om.msg, om.signerKey, nil
+	}
+
+	maxAge := time.Duration(messagingConfig.GetMaxMessageAgeS()) * time.Second
+	sent := time.Unix(om.timestamp, 0)
+	if time.Since(sent) > maxAge {
+		messagesRejected.Inc("expired")
+		return nil, nil, errors.New(fmt.Sprintf("Error message was sent at %v, which is more than %v ago", sent, maxAge))
+	} else if time.Until(sent) > maxClockSkew {
+		messagesRejected.Inc("future")
+		return nil, nil, errors.New(fmt.Sprintf("Error message was sent at %v, which is in the future", sent))
+	} else if err := messageReplayCache.check(om.nonce, msgId, sent.Add(maxAge+maxClockSkew)); err != nil {
+		messagesRejected.Inc("replay")
+		return nil, nil, errors.New(fmt.Sprintf("Error possible replay, %v", err))
+	}
+
+	messageReplayCache.addTimestampSender(fp)
+	learnPeer(om)
+	messagesReceived.Inc(fmt.Sprintf("%v", om.version))
+	return om.msg, om.signerKey, nil
+}
+
+// Remove this runtime's hybrid keys. The caller must hold the KeyLock.
+func deleteHybridKeys(dir string) error {
+	gHybridKeys = nil
+	if err := os.Remove(filepath.Join(dir, hybridFileName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+//go:build unit
+// +build unit
+
+package exchange
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/config"
+	"golang.org/x/crypto/sha3"
+)
+
+// Build a version 1 message the way a sender would, with the input timestamp and nonce. A zero timestamp builds the
+// message an older release would send.
+func v1Message(t *testing.T, sender *rsa.PrivateKey, receiver *rsa.PublicKey, timestamp int64, nonce []byte) []byte {
+	digest := sha3.Sum256([]byte("proposal"))
+	signature, err := rsa.SignPSS(rand.Reader, sender, crypto.SHA3_256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	if err != nil {
+		t.Fatalf("Could not sign message, error %v", err)
+	}
+	pubKey, _ := MarshalPublicKey(&sender.PublicKey)
+	wmBytes, _ := json.Marshal(WrappedMessage{Msg: []byte("proposal"), Signature: signature, SignerPubKey: pubKey, Timestamp: timestamp, Nonce: nonce})
+	encrypted, key, iv, err := symmetricallyEncrypt(wmBytes)
+	if err != nil {
+		t.Fatalf("Could not encrypt message, error %v", err)
+	}
+	svBytes, _ := json.Marshal(SymmetricValues{Key: key, Nonce: iv})
+	sv, err := rsa.EncryptOAEP(sha3.New256(), rand.Reader, receiver, svBytes, []byte(""))
+	if err != nil {
+		t.Fatalf("Could not encrypt symmetric values, error %v", err)
+	}
+	return encodeMessage(t, newExchangeMessage(encrypted, sv))
+}
+
+func envelopeTestSetup(t *testing.T, c config.MessagingConfig) {
+	keyTestSetup(t)
+	ConfigureMessaging(c)
+	peers = make(map[string]peerCapabilities)
+	messageReplayCache = newReplayCache(nil)
+}
+
+func TestEnvelope_negotiation(t *testing.T) {
+	envelopeTestSetup(t, config.MessagingConfig{})
+	pubKey, privKey, _ := GetKeys("")
+	keys := []*rsa.PrivateKey{privKey}
+
+	// Nothing is known about the receiver yet, so the version 1 envelope is used, advertising version 2.
+	em, err := ConstructExchangeMessage([]byte("proposal"), pubKey, privKey, pubKey)
+	if err != nil {
+		t.Fatalf("Could not construct message, error %v", err)
+	} else if em.Version != 0 || len(em.WrappedMessage) == 0 {
+		t.Fatalf("expecting a version 1 envelope, was %v", em)
+	} else if msg, signer, err := ReceiveExchangeMessage(encodeMessage(t, em), keys, 1); err != nil {
+		t.Fatalf("Could not receive message, error %v", err)
+	} else if string(msg) != "proposal" || keyFingerprint(signer) != keyFingerprint(pubKey) {
+		t.Errorf("unexpected message %s from %v", msg, signer)
+	}
+
+	// The receiver has advertised version 2, so it is used from now on.
+	em, err = ConstructExchangeMessage([]byte("reply"), pubKey, privKey, pubKey)
+	if err != nil {
+		t.Fatalf("Could not construct message, error %v", err)
+	} else if em.Version != ENVELOPE_V2 || len(em.WrappedMessage) != 0 {
+		t.Fatalf("expecting a version 2 envelope, was %v", em)
+	}
+	v2 := encodeMessage(t, em)
+	if msg, signer, err := ReceiveExchangeMessage(v2, keys, 2); err != nil {
+		t.Fatalf("Could not receive message, error %v", err)
+	} else if string(msg) != "reply" || keyFingerprint(signer) != keyFingerprint(pubKey) {
+		t.Errorf("unexpected message %s from %v", msg, signer)
+	}
+
+	// A message that is read again from the exchange is accepted, a replay is not.
+	if _, _, err := ReceiveExchangeMessage(v2, keys, 2); err != nil {
+		t.Errorf("message read again should be accepted, error %v", err)
+	} else if _, _, err := ReceiveExchangeMessage(v2, keys, 3); err == nil {
+		t.Errorf("replayed message should be rejected")
+	}
+
+	// The header is bound to the ciphertext.
+	tampered := *em
+	header := new(EnvelopeHeader)
+	json.Unmarshal(tampered.Header, header)
+	header.Timestamp = time.Now().Unix() + 1
+	tampered.Header, _ = json.Marshal(header)
+	if _, _, err := ReceiveExchangeMessage(encodeMessage(t, &tampered), keys, 4); err == nil {
+		t.Errorf("tampered message should be rejected")
+	}
+
+	// Version 2 can be turned off.
+	ConfigureMessaging(config.MessagingConfig{MaxEnvelopeVersion: ENVELOPE_V1})
+	if em, err := ConstructExchangeMessage([]byte("reply"), pubKey, privKey, pubKey); err != nil {
+		t.Fatalf("Could not construct message, error %v", err)
+	} else if em.Version != 0 {
+		t.Errorf("expecting a version 1 envelope, was %v", em)
+	}
+}
+
+func TestEnvelope_replayProtection(t *testing.T) {
+	envelopeTestSetup(t, config.MessagingConfig{MaxMessageAgeS: 600})
+	_, privKey, _ := GetKeys("")
+	keys := []*rsa.PrivateKey{privKey}
+	sender, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	// A message from an older release has no timestamp and is accepted.
+	if _, _, err := ReceiveExchangeMessage(v1Message(t, sender, &privKey.PublicKey, 0, nil), keys, 1); err != nil {
+		t.Errorf("legacy message should be accepted, error %v", err)
+	}
+
+	now := time.Now().Unix()
+	if _, _, err := ReceiveExchangeMessage(v1Message(t, sender, &privKey.PublicKey, now-601, []byte("nonce-1")), keys, 2); err == nil {
+		t.Errorf("old message should be rejected")
+	} else if _, _, err := ReceiveExchangeMessage(v1Message(t, sender, &privKey.PublicKey, now+3600, []byte("nonce-2")), keys, 3); err == nil {
+		t.Errorf("message from the future should be rejected")
+	} else if _, _, err := ReceiveExchangeMessage(v1Message(t, sender, &privKey.PublicKey, now, []byte("nonce-3")), keys, 4); err != nil {
+		t.Errorf("message should be accepted, error %v", err)
+	} else if _, _, err := ReceiveExchangeMessage(v1Message(t, sender, &privKey.PublicKey, now, []byte("nonce-3")), keys, 5); err == nil {
+		t.Errorf("message with a reused nonce should be rejected")
+	}
+
+	// Now that the sender is known to send timestamps, a message without one is a possible replay.
+	if _, _, err := ReceiveExchangeMessage(v1Message(t, sender, &privKey.PublicKey, 0, nil), keys, 6); err == nil {
+		t.Errorf("message without a timestamp should be rejected")
+	}
+}
+
+// A replay store that is kept across the simulated restarts of the test.
+type testReplayStore struct {
+	nonces  map[string]int
+	senders map[string]bool
+}
+
+func (s *testReplayStore) SaveMessageNonce(nonce string, msgId int, expires int64) (int, error) {
+	if firstId, ok := s.nonces[nonce]; ok {
+		return firstId, nil
+	}
+	s.nonces[nonce] = msgId
+	return msgId, nil
+}
+
+func (s *testReplayStore) DeleteExpiredMessageNonces(now int64) error { return nil }
+
+func (s *testReplayStore) SaveTimestampSender(fingerprint string) error {
+	s.senders[fingerprint] = true
+	return nil
+}
+
+func (s *testReplayStore) IsTimestampSender(fingerprint string) (bool, error) {
+	return s.senders[fingerprint], nil
+}
+
+func TestEnvelope_replayAfterRestart(t *testing.T) {
+	envelopeTestSetup(t, config.MessagingConfig{MaxMessageAgeS: 600})
+	_, privKey, _ := GetKeys("")
+	keys := []*rsa.PrivateKey{privKey}
+	sender, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	store := &testReplayStore{nonces: make(map[string]int), senders: make(map[string]bool)}
+	ConfigureReplayStore(store)
+	msg := v1Message(t, sender, &privKey.PublicKey, time.Now().Unix(), []byte("nonce-1"))
+	if _, _, err := ReceiveExchangeMessage(msg, keys, 1); err != nil {
+		t.Fatalf("message should be accepted, error %v", err)
+	}
+
+	// The in-memory state is lost in a restart, the store is not.
+	ConfigureReplayStore(store)
+	peers = make(map[string]peerCapabilities)
+	if _, _, err := ReceiveExchangeMessage(msg, keys, 1); err != nil {
+		t.Errorf("message read again should be accepted, error %v", err)
+	} else if _, _, err := ReceiveExchangeMessage(msg, keys, 2); err == nil {
+		t.Errorf("replayed message should be rejected")
+	} else if _, _, err := ReceiveExchangeMessage(v1Message(t, sender, &privKey.PublicKey, 0, nil), keys, 3); err == nil {
+		t.Errorf("message without a timestamp should be rejected")
+	}
+}
+
+// Only a message that was not encrypted for the receiver's keys is reported as a key mismatch, a rejected replay is not.
+func TestEnvelope_keyMismatchError(t *testing.T) {
+	envelopeTestSetup(t, config.MessagingConfig{MaxMessageAgeS: 600})
+	_, privKey, _ := GetKeys("")
+	keys := []*rsa.PrivateKey{privKey}
+	sender, _ := rsa.GenerateKey(rand.Reader, 2048)
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	msg := v1Message(t, sender, &privKey.PublicKey, time.Now().Unix(), []byte("nonce-1"))
+	if _, _, err := ReceiveExchangeMessage(msg, keys, 1); err != nil {
+		t.Fatalf("message should be accepted, error %v", err)
+	} else if _, _, err := ReceiveExchangeMessage(msg, keys, 2); err == nil || IsKeyMismatchError(err) {
+		t.Errorf("replayed message should be rejected without a key mismatch, error %v", err)
+	}
+
+	msg = v1Message(t, sender, &other.PublicKey, time.Now().Unix(), []byte("nonce-2"))
+	if _, _, err := ReceiveExchangeMessage(msg, keys, 3); !IsKeyMismatchError(err) {
+		t.Errorf("message for another key should be a key mismatch, error %v", err)
+	}
+}
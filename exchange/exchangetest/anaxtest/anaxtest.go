@@ -0,0 +1,110 @@
+// Package anaxtest starts the workers of the agents and agbots set up by the exchangetest package, so that tests can
+// run the agreement protocol end to end against the fake exchange and CSS. It is separate from exchangetest because
+// the tests of the worker packages use exchangetest.
+//
+// The messaging keys are held in process wide variables, so the agents and agbots started in a test share them. The
+// HZN_VAR_BASE environment variable should point at a test directory, since the keys are written below it.
+package anaxtest
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreement"
+	"github.com/open-horizon/anax/agreementbot"
+	agbotPersistence "github.com/open-horizon/anax/agreementbot/persistence"
+	_ "github.com/open-horizon/anax/agreementbot/persistence/bolt"
+	"github.com/open-horizon/anax/changes"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/exchange/exchangetest"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/worker"
+)
+
+// The workers of an agent or an agbot, and the go routine that dispatches the events between them.
+type Runtime struct {
+	Name    string
+	AgbotDB agbotPersistence.AgbotDatabase // The agbot's database, nil for an agent.
+	workers []*worker.BaseWorker
+	done    chan bool
+}
+
+func (r *Runtime) String() string {
+	return fmt.Sprintf("Name: %v, Workers: %v", r.Name, len(r.workers))
+}
+
+// The workers must have been added to the registry.
+func newRuntime(name string, registry *worker.MessageHandlerRegistry, workers []*worker.BaseWorker, close func()) *Runtime {
+	r := &Runtime{Name: name, workers: workers, done: make(chan bool, 1)}
+	go func() {
+		registry.ProcessEventMessages()
+		close()
+		r.done <- true
+	}()
+	return r
+}
+
+// Stop the workers and wait for them to terminate, then close the database. Workers wait for their subworkers, so
+// this can take as long as the longest subworker interval.
+func (r *Runtime) Stop() {
+	for _, w := range r.workers {
+		glog.V(3).Infof("anaxtest: stopping %v of %v", w.GetName(), r.Name)
+		w.Commands <- worker.NewBeginShutdownCommand()
+		w.Commands <- worker.NewTerminateCommand("test complete")
+	}
+	<-r.done
+}
+
+// Start the agent's workers that take part in the agreement protocol: the agreement worker, which publishes the
+// node's messaging key and answers proposals, the worker that polls the exchange for changes and the worker that
+// receives the node's messages when there are new ones. The node's
+// configuration is completed, as it is at the end of hzn register, so the node's key is published and agbots can
+// make agreements with it. The workers that run the workloads are not started, so an agreement is reached but its
+// workload is not executed.
+func StartAgent(a *exchangetest.Agent) (*Runtime, error) {
+	usingPattern, err := persistence.MigrateExchangeDevice(a.DB)
+	if err != nil {
+		return nil, err
+	} else if err := os.MkdirAll(a.Config.Edge.PolicyPath, 0700); err != nil {
+		return nil, err
+	}
+	pm, err := policy.Initialize(a.Config.Edge.PolicyPath, a.Config.ArchSynonyms, nil, !usingPattern, true)
+	if err != nil {
+		return nil, err
+	}
+
+	aw := agreement.NewAgreementWorker("Agreement", a.Config, a.DB, pm)
+	cw := changes.NewChangesWorker("ExchangeChanges", a.Config, a.DB)
+	mw := exchange.NewExchangeMessageWorker("ExchangeMessages", a.Config, a.DB)
+
+	registry := worker.NewMessageHandlerRegistry()
+	registry.Add(aw)
+	registry.Add(cw)
+	registry.Add(mw)
+	aw.NewEvent(events.NewEdgeConfigCompleteMessage(events.NEW_DEVICE_CONFIG_COMPLETE))
+	return newRuntime(a.Id, registry, []*worker.BaseWorker{&aw.BaseWorker, &cw.BaseWorker, &mw.BaseWorker}, func() {}), nil
+}
+
+// Start the agbot's workers: the agreement bot worker, which searches for nodes and makes agreements with them, and
+// the worker that follows the changes in the exchange. The agbot's database is created in its directory, and closed
+// when the agbot is stopped. The agbot has no secrets provider.
+func StartAgbot(a *exchangetest.Agbot) (*Runtime, error) {
+	db, err := agbotPersistence.InitDatabase(a.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	aw := agreementbot.NewAgreementBotWorker("AgBot", a.Config, db, nil)
+	cw := agreementbot.NewChangesWorker("AgBot ExchangeChanges", a.Config)
+
+	registry := worker.NewMessageHandlerRegistry()
+	registry.Add(aw)
+	registry.Add(cw)
+	r := newRuntime(a.Id, registry, []*worker.BaseWorker{&aw.BaseWorker, &cw.BaseWorker}, func() { db.Close() })
+	r.AgbotDB = db
+	return r, nil
+}
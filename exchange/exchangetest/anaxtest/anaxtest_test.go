@@ -0,0 +1,132 @@
+//go:build unit
+// +build unit
+
+package anaxtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+
+	agbotPersistence "github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/exchange/exchangetest"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/rsapss-tool/sign"
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	flag.Set("logtostderr", "true")
+	flag.Set("v", os.Getenv("ANAXTEST_V"))
+	os.Exit(m.Run())
+}
+
+// An agbot and an agent reach an agreement through the fake exchange, for a deployment policy that the node's
+// built-in properties satisfy.
+func Test_Agreement(t *testing.T) {
+	os.Setenv("HZN_VAR_BASE", t.TempDir())
+	s := exchangetest.NewServer()
+	defer s.Close()
+	s.AddOrg("myorg")
+
+	agent, err := s.NewAgent(t.TempDir(), "myorg", "node1", "token1", "")
+	if err != nil {
+		t.Fatalf("unable to set up the agent, error %v", err)
+	}
+	defer agent.Close()
+
+	deployment := `{"services":{"svc1":{"image":"svc1:1.0.0"}}}`
+	s.Put("orgs/myorg/services/svc1_1.0.0_"+runtime.GOARCH, map[string]interface{}{
+		"label": "svc1", "url": "svc1", "version": "1.0.0", "arch": runtime.GOARCH, "sharable": "multiple",
+		"public": true, "deployment": deployment, "deploymentSignature": signDeployment(t, agent, deployment),
+	})
+	s.Put("orgs/myorg/business/policies/bp1", map[string]interface{}{
+		"label":       "bp1",
+		"service":     map[string]interface{}{"name": "svc1", "org": "myorg", "arch": "*", "serviceVersions": []interface{}{map[string]interface{}{"version": "1.0.0"}}},
+		"constraints": []string{"openhorizon.allowPrivileged == false"},
+	})
+
+	agbot, err := s.NewAgbot(t.TempDir(), "myorg", "ag1", "agtoken")
+	if err != nil {
+		t.Fatalf("unable to set up the agbot, error %v", err)
+	}
+
+	agentRuntime, err := StartAgent(agent)
+	if err != nil {
+		t.Fatalf("unable to start the agent, error %v", err)
+	}
+	defer agentRuntime.Stop()
+
+	// Start the agbot once the node's key is published, so that its first search finds the node.
+	for i := 0; i < 30 && !nodeKeyPublished(s, "myorg/node1"); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	agbotRuntime, err := StartAgbot(agbot)
+	if err != nil {
+		t.Fatalf("unable to start the agbot, error %v", err)
+	}
+	defer agbotRuntime.Stop()
+
+	// The agent accepts the agbot's proposal, and the agbot finalizes the agreement when it receives the reply.
+	for i := 0; i < 120; i++ {
+		if agId := finalizedAgreement(agent, agbotRuntime); agId != "" {
+			t.Logf("agreement %v", agId)
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	t.Errorf("expecting an agreement")
+}
+
+func nodeKeyPublished(s *exchangetest.Server, id string) bool {
+	var node exchange.Device
+	return s.Get("orgs/"+exchange.GetOrg(id)+"/nodes/"+exchange.GetId(id), &node) && node.PublicKey != ""
+}
+
+// Sign the deployment with a new key, and trust the key on the agent as hzn key import does.
+func signDeployment(t *testing.T, agent *exchangetest.Agent, deployment string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate a signing key, error %v", err)
+	}
+	pubKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unable to marshal the signing key, error %v", err)
+	}
+
+	keyDir := agent.Config.UserPublicKeyPath()
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		t.Fatalf("unable to create %v, error %v", keyDir, err)
+	} else if err := os.WriteFile(path.Join(keyDir, "svc1.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubKey}), 0600); err != nil {
+		t.Fatalf("unable to trust the signing key, error %v", err)
+	}
+
+	hasher := sha256.New()
+	hasher.Write([]byte(deployment))
+	signature, err := sign.Sha256HashOfInput(key, hasher)
+	if err != nil {
+		t.Fatalf("unable to sign the deployment, error %v", err)
+	}
+	return signature
+}
+
+// Returns the id of the agent's agreement once the agbot has finalized it.
+func finalizedAgreement(agent *exchangetest.Agent, agbot *Runtime) string {
+	if ags, err := persistence.FindEstablishedAgreementsAllProtocols(agent.DB, []string{"Basic"}, []persistence.EAFilter{}); err != nil || len(ags) != 1 {
+		return ""
+	} else if ag, err := agbot.AgbotDB.FindSingleAgreementByAgreementId(ags[0].CurrentAgreementId, "Basic", []agbotPersistence.AFilter{}); err != nil || ag == nil || ag.AgreementFinalizedTime == 0 {
+		return ""
+	} else {
+		return ag.CurrentAgreementId
+	}
+}
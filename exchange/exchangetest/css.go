@@ -0,0 +1,220 @@
+package exchangetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/edge-sync-service/common"
+)
+
+// An object in the fake CSS.
+type cssObject struct {
+	meta         common.MetaData
+	data         []byte
+	destinations []common.DestinationsStatus
+	received     bool // The destination policy has been received by an agbot.
+	modified     time.Time
+}
+
+func objectKey(org string, objType string, objId string) string {
+	return org + "/" + objType + "/" + objId
+}
+
+// Create or replace an object in the CSS, as a user does with hzn mms object publish.
+func (s *Server) PutObject(meta common.MetaData, data []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.putObject(meta, data)
+}
+
+// The caller must hold the lock.
+func (s *Server) putObject(meta common.MetaData, data []byte) {
+	key := objectKey(meta.DestOrgID, meta.ObjectType, meta.ObjectID)
+	obj, ok := s.objects[key]
+	if !ok {
+		obj = &cssObject{destinations: make([]common.DestinationsStatus, 0)}
+		s.objects[key] = obj
+	}
+	if meta.DestinationPolicy != nil && meta.DestinationPolicy.Timestamp == 0 {
+		meta.DestinationPolicy.Timestamp = time.Now().UnixNano()
+	}
+	if data != nil {
+		obj.data = data
+		meta.ObjectSize = int64(len(data))
+	}
+	obj.meta = meta
+	obj.received = false
+	obj.modified = time.Now()
+}
+
+// Returns the destinations of an object, nil if the object does not exist.
+func (s *Server) ObjectDestinations(org string, objType string, objId string) []common.DestinationsStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if obj, ok := s.objects[objectKey(org, objType, objId)]; ok {
+		return append([]common.DestinationsStatus{}, obj.destinations...)
+	}
+	return nil
+}
+
+// Serve the CSS API. The segments are the parts of the path after /css. The caller must hold the lock.
+func (s *Server) serveCSS(w http.ResponseWriter, r *http.Request, segs []string, body []byte) {
+	if len(segs) < 4 || segs[0] != "api" || segs[1] != "v1" || segs[2] != "objects" {
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+		return
+	}
+
+	org, rest := segs[3], segs[4:]
+	if len(rest) == 0 && r.Method == http.MethodGet {
+		s.listObjects(w, r, org)
+		return
+	} else if len(rest) < 2 {
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+		return
+	}
+
+	key := objectKey(org, rest[0], rest[1])
+	obj, exists := s.objects[key]
+	if !exists && !(len(rest) == 2 && r.Method == http.MethodPut) {
+		http.Error(w, fmt.Sprintf("object %v not found", key), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(rest) == 2 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, obj.meta)
+
+	case len(rest) == 2 && r.Method == http.MethodPut:
+		var put struct {
+			Meta common.MetaData `json:"meta"`
+			Data []byte          `json:"data"`
+		}
+		if err := json.Unmarshal(body, &put); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		put.Meta.DestOrgID, put.Meta.ObjectType, put.Meta.ObjectID = org, rest[0], rest[1]
+		s.putObject(put.Meta, put.Data)
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(rest) == 2 && r.Method == http.MethodDelete:
+		obj.meta.Deleted = true
+		obj.modified = time.Now()
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(rest) == 3 && rest[2] == "data" && r.Method == http.MethodGet:
+		// Handles the Range header of the requests for a chunk of the data.
+		http.ServeContent(w, r, rest[1], obj.modified, bytes.NewReader(obj.data))
+
+	case len(rest) == 3 && rest[2] == "data" && r.Method == http.MethodPut:
+		obj.data = body
+		obj.meta.ObjectSize = int64(len(body))
+		obj.modified = time.Now()
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(rest) == 3 && rest[2] == "destinations" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, obj.destinations)
+
+	case len(rest) == 3 && rest[2] == "destinations" && r.Method == http.MethodPost:
+		var req exchange.PostDestsRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		obj.destinations = updateDestinations(obj.destinations, req)
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(rest) == 3 && rest[2] == "policyreceived" && r.Method == http.MethodPut:
+		obj.received = true
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+	}
+}
+
+// List the objects of an org. With destination_policy=true, the destination policies of the objects that have one are
+// listed, without the ones that have been received unless received=true. Otherwise the metadata of the objects is
+// listed.
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request, org string) {
+	query := r.URL.Query()
+	since, _ := strconv.ParseInt(query.Get("since"), 10, 64)
+
+	keys := make([]string, 0)
+	for key := range s.objects {
+		if strings.HasPrefix(key, org+"/") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	policies := make([]common.ObjectDestinationPolicy, 0)
+	metas := make([]common.MetaData, 0)
+	for _, key := range keys {
+		obj := s.objects[key]
+		if query.Get("destination_policy") == "true" {
+			pol := obj.meta.DestinationPolicy
+			if pol == nil || obj.meta.Deleted || (obj.received && query.Get("received") != "true") || pol.Timestamp < since ||
+				(query.Get("service") != "" && !hasService(pol, query.Get("service"))) {
+				continue
+			}
+			policies = append(policies, common.ObjectDestinationPolicy{
+				OrgID:             org,
+				ObjectType:        obj.meta.ObjectType,
+				ObjectID:          obj.meta.ObjectID,
+				DestinationPolicy: pol,
+				Destinations:      obj.destinations,
+			})
+		} else if (query.Get("deleted") != "false" || !obj.meta.Deleted) && (query.Get("objectType") == "" || query.Get("objectType") == obj.meta.ObjectType) {
+			metas = append(metas, obj.meta)
+		}
+	}
+
+	if query.Get("destination_policy") == "true" && len(policies) != 0 {
+		writeJSON(w, http.StatusOK, policies)
+	} else if query.Get("destination_policy") != "true" && len(metas) != 0 {
+		writeJSON(w, http.StatusOK, metas)
+	} else {
+		http.Error(w, "no objects found", http.StatusNotFound)
+	}
+}
+
+// Returns true if the policy has affinity for the service, given as org/name.
+func hasService(pol *common.Policy, service string) bool {
+	for _, svc := range pol.Services {
+		if svc.OrgID+"/"+svc.ServiceName == service {
+			return true
+		}
+	}
+	return false
+}
+
+// Add or remove the destinations, each in the form type:id. The destinations that are added are delivered right away.
+func updateDestinations(dests []common.DestinationsStatus, req exchange.PostDestsRequest) []common.DestinationsStatus {
+	for _, d := range req.Destinations {
+		parts := strings.SplitN(d, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		ix := -1
+		for i, dest := range dests {
+			if dest.DestType == parts[0] && dest.DestID == parts[1] {
+				ix = i
+			}
+		}
+		if req.Action == "add" && ix == -1 {
+			dests = append(dests, common.DestinationsStatus{DestType: parts[0], DestID: parts[1], Status: common.Delivered})
+		} else if req.Action == "remove" && ix != -1 {
+			dests = append(dests[:ix], dests[ix+1:]...)
+		}
+	}
+	return dests
+}
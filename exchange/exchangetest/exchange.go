@@ -0,0 +1,755 @@
+package exchangetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/version"
+)
+
+const maskedToken = "********"
+
+// Add an organization. The exchange returns the heartbeat intervals of an org even when they are not set.
+func (s *Server) AddOrg(org string) {
+	s.Put(resourcePath(org), exchange.Organization{Label: org, Description: org, HeartbeatIntv: &exchange.HeartbeatIntervals{}})
+}
+
+// Add a user, who can create nodes and other resources through the API.
+func (s *Server) AddUser(org string, id string, password string, admin bool) {
+	s.Put(resourcePath(org, "users", id), exchange.UserDefinition{Password: password, Admin: admin, Email: id + "@" + org})
+}
+
+// Add a node, as the node owner does before the agent registers it.
+func (s *Server) AddNode(org string, id string, token string, pattern string) {
+	s.Put(resourcePath(org, "nodes", id), map[string]interface{}{
+		"token":              token,
+		"name":               id,
+		"owner":              RootUser,
+		"nodeType":           "device",
+		"pattern":            pattern,
+		"registeredServices": []exchange.Microservice{},
+		"softwareVersions":   map[string]string{},
+		"publicKey":          "",
+		"heartbeatIntervals": exchange.HeartbeatIntervals{},
+	})
+}
+
+// Add an agbot. The agbot serves the patterns and deployment policies added with Put to its patterns and businesspols.
+func (s *Server) AddAgbot(org string, id string, token string) {
+	s.Put(resourcePath(org, "agbots", id), map[string]interface{}{
+		"token":     token,
+		"name":      id,
+		"owner":     RootUser,
+		"publicKey": "",
+	})
+}
+
+// Make an agbot serve the deployment policies of an org, "*" for all of them, to the nodes of an org.
+func (s *Server) AddServedPolicy(agbotId string, policyOrg string, policy string, nodeOrg string) {
+	s.Put(resourcePath(exchange.GetOrg(agbotId), "agbots", exchange.GetId(agbotId), "businesspols", policyOrg+"_"+policy+"_"+nodeOrg),
+		exchange.ServedBusinessPolicy{BusinessPolOrg: policyOrg, BusinessPol: policy, NodeOrg: nodeOrg})
+}
+
+// Make an agbot serve the patterns of an org, "*" for all of them, to the nodes of an org.
+func (s *Server) AddServedPattern(agbotId string, patternOrg string, pattern string, nodeOrg string) {
+	s.Put(resourcePath(exchange.GetOrg(agbotId), "agbots", exchange.GetId(agbotId), "patterns", patternOrg+"_"+pattern+"_"+nodeOrg),
+		exchange.ServedPattern{PatternOrg: patternOrg, Pattern: pattern, NodeOrg: nodeOrg})
+}
+
+// Create or replace a resource, e.g. Put("orgs/myorg/patterns/p1", pattern). The change is recorded, as if a user had
+// made it.
+func (s *Server) Put(resourcePath string, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.store(resourcePath, value)
+	s.recordResourceChange(resourcePath, exchange.CHANGE_OPERATION_CREATED_MODIFIED)
+}
+
+// Read a resource into value. Returns false if the resource does not exist.
+func (s *Server) Get(resourcePath string, value interface{}) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if doc, ok := s.docs[resourcePath]; !ok {
+		return false
+	} else if b, err := json.Marshal(doc); err != nil {
+		panic(fmt.Sprintf("unable to serialize %v, error: %v", resourcePath, err))
+	} else if err := json.Unmarshal(b, value); err != nil {
+		panic(fmt.Sprintf("unable to read %v into %T, error: %v", resourcePath, value, err))
+	}
+	return true
+}
+
+// Delete a resource and the resources under it.
+func (s *Server) Delete(resourcePath string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.docs[resourcePath]; ok {
+		s.deleteTree(resourcePath)
+		s.recordResourceChange(resourcePath, exchange.CHANGE_OPERATION_DELETED)
+	}
+}
+
+// Returns the number of messages waiting for a node or agbot, e.g. "orgs/myorg/nodes/node1".
+func (s *Server) MessageCount(resourcePath string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.msgs[resourcePath])
+}
+
+// Record the change that the agent and the agbot are told about when a resource changes. The caller must hold the lock.
+func (s *Server) recordResourceChange(key string, operation string) {
+	segs := strings.Split(key, "/")
+	if len(segs) < 2 || segs[0] != "orgs" {
+		return
+	}
+	org := segs[1]
+	segs = segs[2:]
+
+	resource, id := "", ""
+	switch {
+	case len(segs) == 0:
+		resource, id = exchange.RESOURCE_ORG, org
+	case len(segs) == 1 && segs[0] == "AgentFileVersion":
+		resource = exchange.RESOURCE_AGENT_FILE_VERSION
+	case segs[0] == "nodes" && len(segs) == 2:
+		resource, id = exchange.RESOURCE_NODE, segs[1]
+	case segs[0] == "nodes" && len(segs) > 2:
+		id = segs[1]
+		resource = map[string]string{
+			"policy":               exchange.RESOURCE_NODE_POLICY,
+			"status":               exchange.RESOURCE_NODE_STATUS,
+			"errors":               exchange.RESOURCE_NODE_ERROR,
+			"agreements":           exchange.RESOURCE_NODE_AGREEMENTS,
+			"managementStatus":     exchange.RESOURCE_NMP_STATUS,
+			"services_configstate": exchange.RESOURCE_NODE_SERVICES_CONFIGSTATE,
+		}[segs[2]]
+	case segs[0] == "agbots" && len(segs) == 2:
+		resource, id = exchange.RESOURCE_AGBOT, segs[1]
+	case segs[0] == "agbots" && len(segs) > 2:
+		id = segs[1]
+		resource = map[string]string{
+			"businesspols": exchange.RESOURCE_AGBOT_SERVED_POLICY,
+			"patterns":     exchange.RESOURCE_AGBOT_SERVED_PATTERN,
+			"agreements":   exchange.RESOURCE_AGBOT_AGREEMENTS,
+		}[segs[2]]
+	case segs[0] == "services" && len(segs) == 2:
+		resource, id = exchange.RESOURCE_SERVICE, segs[1]
+	case segs[0] == "services" && len(segs) == 3 && segs[2] == "policy":
+		resource, id = exchange.RESOURCE_AGBOT_SERVICE_POLICY, segs[1]
+	case segs[0] == "patterns" && len(segs) == 2:
+		resource, id = exchange.RESOURCE_AGBOT_PATTERN, segs[1]
+	case segs[0] == "business" && len(segs) == 3:
+		resource, id = exchange.RESOURCE_AGBOT_POLICY, segs[2]
+	case segs[0] == exchange.NMPExchangeResource && len(segs) == 2:
+		resource, id = exchange.RESOURCE_NMP, segs[1]
+	case segs[0] == "hagroups" && len(segs) == 2:
+		resource, id = exchange.RESOURCE_HA_GROUP, segs[1]
+	}
+
+	if resource != "" {
+		s.recordChange(org, resource, id, operation)
+	}
+}
+
+// Serve the exchange API. The segments are the parts of the path after /v1. The caller must hold the lock.
+func (s *Server) serveExchange(w http.ResponseWriter, r *http.Request, user string, segs []string, body []byte) {
+	if len(segs) == 2 && segs[0] == "changes" && segs[1] == "maxchangeid" && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, exchange.ExchangeChangeIDResponse{MaxChangeID: uint64(len(s.changes))})
+		return
+	} else if len(segs) < 2 || segs[0] != "orgs" {
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+		return
+	}
+
+	org, rest := segs[1], segs[2:]
+	if _, ok := s.docs[resourcePath(org)]; !ok {
+		writeResponse(w, http.StatusNotFound, fmt.Sprintf("org %v not found", org))
+		return
+	}
+
+	switch {
+	case len(rest) == 0:
+		s.serveResource(w, r, resourcePath(org), "orgs", org, body)
+	case rest[0] == "changes" && len(rest) == 1 && r.Method == http.MethodPost:
+		s.serveChanges(w, org, body)
+	case rest[0] == "users" && len(rest) == 2:
+		s.serveResource(w, r, resourcePath(org, rest...), "users", org+"/"+rest[1], body)
+	case rest[0] == "nodes":
+		s.serveNodes(w, r, user, org, rest[1:], body)
+	case rest[0] == "agbots":
+		s.serveAgbots(w, r, user, org, rest[1:], body)
+	case rest[0] == "services":
+		s.serveServices(w, r, org, rest[1:], body)
+	case rest[0] == "patterns":
+		s.servePatterns(w, r, org, rest[1:], body)
+	case rest[0] == "business" && len(rest) > 1 && rest[1] == "policies":
+		s.serveBusinessPolicies(w, r, org, rest[2:], body)
+	case rest[0] == "search" && len(rest) == 2 && rest[1] == "nodehealth" && r.Method == http.MethodPost:
+		s.serveNodeHealth(w, org, "", body)
+	case rest[0] == "hagroups":
+		s.serveHAGroups(w, r, org, rest[1:], body)
+	case rest[0] == exchange.NMPExchangeResource && len(rest) == 1:
+		s.serveCollection(w, r, resourcePath(org, rest[0]), false, "managementPolicy", func(id string) string { return org + "/" + id })
+	case rest[0] == exchange.NMPExchangeResource && len(rest) == 2:
+		s.serveResource(w, r, resourcePath(org, rest...), "managementPolicy", org+"/"+rest[1], body)
+	case rest[0] == "AgentFileVersion" && len(rest) == 1:
+		s.serveDocument(w, r, resourcePath(org, rest[0]), body)
+	default:
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+	}
+}
+
+func (s *Server) serveNodes(w http.ResponseWriter, r *http.Request, user string, org string, rest []string, body []byte) {
+	if len(rest) == 0 {
+		s.serveCollection(w, r, resourcePath(org, "nodes"), false, "nodes", func(id string) string { return org + "/" + id })
+		return
+	}
+
+	node := resourcePath(org, "nodes", rest[0])
+	if _, ok := s.docs[node]; !ok && (len(rest) > 1 || r.Method != http.MethodPut) {
+		writeResponse(w, http.StatusNotFound, fmt.Sprintf("node %v/%v not found", org, rest[0]))
+		return
+	}
+
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodPut:
+		s.putIdentity(w, node, user, body)
+	case len(rest) == 1:
+		s.serveResource(w, r, node, "nodes", org+"/"+rest[0], body)
+	case len(rest) == 2 && rest[1] == "heartbeat" && r.Method == http.MethodPost:
+		s.docs[node]["lastHeartbeat"] = now()
+		writeResponse(w, http.StatusCreated, "heartbeat successful")
+	case len(rest) == 2 && (rest[1] == "policy" || rest[1] == "status" || rest[1] == "errors"):
+		s.serveDocument(w, r, resourcePath(org, "nodes", rest[0], rest[1]), body)
+	case len(rest) == 2 && rest[1] == "services_configstate" && r.Method == http.MethodPost:
+		s.postConfigState(w, node, body)
+	case rest[1] == "agreements" && len(rest) == 2:
+		s.serveCollection(w, r, node+"/agreements", true, "agreements", func(id string) string { return id })
+	case rest[1] == "agreements" && len(rest) == 3 && r.Method == http.MethodPut:
+		var state exchange.PutAgreementState
+		if err := json.Unmarshal(body, &state); err != nil {
+			writeResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.store(node+"/agreements/"+rest[2], exchange.DeviceAgreement{Service: state.Services, State: state.State, AgreementService: state.AgreementService})
+		s.recordResourceChange(node+"/agreements/"+rest[2], exchange.CHANGE_OPERATION_CREATED_MODIFIED)
+		writeResponse(w, http.StatusCreated, "agreement added or updated")
+	case rest[1] == "agreements" && len(rest) == 3:
+		s.serveResource(w, r, node+"/agreements/"+rest[2], "agreements", rest[2], body)
+	case rest[1] == "msgs":
+		s.serveMessages(w, r, user, node, exchange.RESOURCE_NODE_MSG, rest[2:], body)
+	case rest[1] == "managementStatus" && len(rest) == 2:
+		s.serveCollection(w, r, node+"/managementStatus", true, "managementStatus", func(id string) string { return org + "/" + id })
+	case rest[1] == "managementStatus" && len(rest) == 3:
+		s.serveResource(w, r, node+"/managementStatus/"+rest[2], "managementStatus", org+"/"+rest[2], body)
+	default:
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+	}
+}
+
+func (s *Server) serveAgbots(w http.ResponseWriter, r *http.Request, user string, org string, rest []string, body []byte) {
+	if len(rest) == 0 {
+		s.serveCollection(w, r, resourcePath(org, "agbots"), false, "agbots", func(id string) string { return org + "/" + id })
+		return
+	}
+
+	agbot := resourcePath(org, "agbots", rest[0])
+	if _, ok := s.docs[agbot]; !ok && (len(rest) > 1 || r.Method != http.MethodPut) {
+		writeResponse(w, http.StatusNotFound, fmt.Sprintf("agbot %v/%v not found", org, rest[0]))
+		return
+	}
+
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodPut:
+		s.putIdentity(w, agbot, user, body)
+	case len(rest) == 1:
+		s.serveResource(w, r, agbot, "agbots", org+"/"+rest[0], body)
+	case len(rest) == 2 && rest[1] == "heartbeat" && r.Method == http.MethodPost:
+		s.docs[agbot]["lastHeartbeat"] = now()
+		writeResponse(w, http.StatusCreated, "heartbeat successful")
+	case (rest[1] == "businesspols" || rest[1] == "patterns") && len(rest) == 2:
+		envelope := map[string]string{"businesspols": "businessPols", "patterns": "patterns"}[rest[1]]
+		s.serveCollection(w, r, agbot+"/"+rest[1], false, envelope, func(id string) string { return id })
+	case (rest[1] == "businesspols" || rest[1] == "patterns" || rest[1] == "agreements") && len(rest) == 3:
+		s.serveResource(w, r, agbot+"/"+rest[1]+"/"+rest[2], rest[1], rest[2], body)
+	case rest[1] == "agreements" && len(rest) == 2:
+		s.serveCollection(w, r, agbot+"/agreements", true, "agreements", func(id string) string { return id })
+	case rest[1] == "msgs":
+		s.serveMessages(w, r, user, agbot, exchange.RESOURCE_AGBOT_MSG, rest[2:], body)
+	default:
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+	}
+}
+
+func (s *Server) serveServices(w http.ResponseWriter, r *http.Request, org string, rest []string, body []byte) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		// Filter the services by url, version and arch, like the exchange does.
+		query := r.URL.Query()
+		services := make(map[string]document)
+		for id, doc := range s.children(resourcePath(org, "services")) {
+			if matchesQuery(doc, query, "url", "version", "arch") {
+				services[org+"/"+id] = public(doc)
+			}
+		}
+		writeEnvelope(w, "services", services)
+	case len(rest) == 1:
+		s.serveResource(w, r, resourcePath(org, "services", rest[0]), "services", org+"/"+rest[0], body)
+	case len(rest) == 2 && rest[1] == "policy":
+		s.serveDocument(w, r, resourcePath(org, "services", rest[0], "policy"), body)
+	case len(rest) >= 2 && (rest[1] == "dockauths" || rest[1] == "keys") && r.Method == http.MethodGet:
+		if _, ok := s.docs[resourcePath(org, "services", rest[0])]; !ok || len(rest) > 2 {
+			writeResponse(w, http.StatusNotFound, "unknown resource")
+		} else {
+			writeJSON(w, http.StatusOK, []string{})
+		}
+	default:
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+	}
+}
+
+func (s *Server) servePatterns(w http.ResponseWriter, r *http.Request, org string, rest []string, body []byte) {
+	switch {
+	case len(rest) == 0:
+		s.serveCollection(w, r, resourcePath(org, "patterns"), false, "patterns", func(id string) string { return org + "/" + id })
+	case len(rest) == 1:
+		s.serveResource(w, r, resourcePath(org, "patterns", rest[0]), "patterns", org+"/"+rest[0], body)
+	case len(rest) == 2 && rest[1] == "search" && r.Method == http.MethodPost:
+		s.searchPattern(w, org, rest[0], body)
+	case len(rest) == 2 && rest[1] == "nodehealth" && r.Method == http.MethodPost:
+		s.serveNodeHealth(w, org, org+"/"+rest[0], body)
+	case len(rest) >= 2 && rest[1] == "keys" && r.Method == http.MethodGet:
+		if _, ok := s.docs[resourcePath(org, "patterns", rest[0])]; !ok || len(rest) > 2 {
+			writeResponse(w, http.StatusNotFound, "unknown resource")
+		} else {
+			writeJSON(w, http.StatusOK, []string{})
+		}
+	default:
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+	}
+}
+
+func (s *Server) serveBusinessPolicies(w http.ResponseWriter, r *http.Request, org string, rest []string, body []byte) {
+	switch {
+	case len(rest) == 0:
+		s.serveCollection(w, r, resourcePath(org, "business", "policies"), false, "businessPolicy", func(id string) string { return org + "/" + id })
+	case len(rest) == 1:
+		s.serveResource(w, r, resourcePath(org, "business", "policies", rest[0]), "businessPolicy", org+"/"+rest[0], body)
+	case len(rest) == 2 && rest[1] == "search" && r.Method == http.MethodPost:
+		s.searchPolicy(w, org, rest[0], body)
+	default:
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+	}
+}
+
+func (s *Server) serveHAGroups(w http.ResponseWriter, r *http.Request, org string, rest []string, body []byte) {
+	groups := make([]document, 0)
+	if len(rest) == 0 && r.Method == http.MethodGet {
+		children := s.children(resourcePath(org, "hagroups"))
+		for _, id := range sortedIds(children) {
+			groups = append(groups, children[id])
+		}
+	} else if len(rest) == 1 && r.Method == http.MethodGet {
+		if doc, ok := s.docs[resourcePath(org, "hagroups", rest[0])]; ok {
+			groups = append(groups, doc)
+		}
+	} else if len(rest) == 1 {
+		if r.Method == http.MethodPut || r.Method == http.MethodPost {
+			if doc, err := toDocument(body); err == nil {
+				doc["name"] = rest[0]
+				body, _ = json.Marshal(doc)
+			}
+		}
+		s.serveResource(w, r, resourcePath(org, "hagroups", rest[0]), "nodeGroups", rest[0], body)
+		return
+	}
+
+	if len(groups) == 0 {
+		writeResponse(w, http.StatusNotFound, "no HA groups found")
+	} else {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"nodeGroups": groups})
+	}
+}
+
+// Serve the changes since a change id, in the caller's org and the orgs it asked for, "*" for all of them.
+func (s *Server) serveChanges(w http.ResponseWriter, org string, body []byte) {
+	var req exchange.GetExchangeChangesRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	orgs := map[string]bool{org: true}
+	for _, o := range req.Orgs {
+		orgs[o] = true
+	}
+
+	resp := exchange.ExchangeChanges{
+		Changes:            make([]exchange.ExchangeChange, 0),
+		MostRecentChangeID: uint64(len(s.changes)),
+		ExchangeVersion:    version.PREFERRED_EXCHANGE_VERSION,
+	}
+	for _, change := range s.changes {
+		if change.ResourceChanges[0].ChangeID < req.ChangeId || !(orgs["*"] || orgs[change.OrgID]) {
+			continue
+		}
+		resp.Changes = append(resp.Changes, change)
+		if req.MaxRecords > 0 && len(resp.Changes) == req.MaxRecords {
+			resp.MostRecentChangeID = change.ResourceChanges[0].ChangeID
+			break
+		}
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// Return the nodes that a deployment policy can be deployed to, a page at a time. A search session ends with a page
+// that is not full.
+func (s *Server) searchPolicy(w http.ResponseWriter, org string, policy string, body []byte) {
+	var req exchange.SearchExchBusinessPolRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeResponse(w, http.StatusBadRequest, err.Error())
+		return
+	} else if _, ok := s.docs[resourcePath(org, "business", "policies", policy)]; !ok {
+		writeResponse(w, http.StatusNotFound, fmt.Sprintf("deployment policy %v/%v not found", org, policy))
+		return
+	}
+
+	changedSince := time.Unix(int64(req.ChangedSince), 0)
+	nodes := s.searchNodes(req.NodeOrgIds, org, func(doc document) bool {
+		updated, err := time.Parse(cutil.ExchangeTimeFormat, fmt.Sprint(doc["lastUpdated"]))
+		return doc["pattern"] == "" && (err != nil || !updated.Before(changedSince))
+	})
+
+	session := org + "/" + policy + "/" + req.Session
+	offset := s.sessions[session]
+	if offset > len(nodes) {
+		offset = len(nodes)
+	}
+	page := nodes[offset:]
+	if req.NumEntries > 0 && uint64(len(page)) >= req.NumEntries {
+		page = page[:req.NumEntries]
+		s.sessions[session] = offset + len(page)
+	} else {
+		delete(s.sessions, session)
+	}
+	writeJSON(w, http.StatusCreated, exchange.SearchExchBusinessPolResponse{Devices: page})
+}
+
+// Return the nodes that use a pattern and have registered the service.
+func (s *Server) searchPattern(w http.ResponseWriter, org string, pattern string, body []byte) {
+	var req exchange.SearchExchangePatternRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeResponse(w, http.StatusBadRequest, err.Error())
+		return
+	} else if _, ok := s.docs[resourcePath(org, "patterns", pattern)]; !ok {
+		writeResponse(w, http.StatusNotFound, fmt.Sprintf("pattern %v/%v not found", org, pattern))
+		return
+	}
+
+	nodes := s.searchNodes(req.NodeOrgIds, org, func(doc document) bool {
+		if doc["pattern"] != org+"/"+pattern || (req.Arch != "" && doc["arch"] != "" && doc["arch"] != req.Arch) {
+			return false
+		} else if req.ServiceURL == "" {
+			return true
+		}
+		services, _ := doc["registeredServices"].([]interface{})
+		for _, svc := range services {
+			if m, ok := svc.(map[string]interface{}); ok && (m["url"] == req.ServiceURL || strings.HasSuffix(fmt.Sprint(m["url"]), "/"+req.ServiceURL)) {
+				return true
+			}
+		}
+		return false
+	})
+	if req.NumEntries > 0 && len(nodes) > req.NumEntries {
+		nodes = nodes[:req.NumEntries]
+	}
+	writeJSON(w, http.StatusCreated, exchange.SearchExchangePatternResponse{Devices: nodes})
+}
+
+// Returns the registered nodes in the orgs, or in the default org, that match, sorted by id. A node is registered when
+// it has a messaging key.
+func (s *Server) searchNodes(orgs []string, defaultOrg string, match func(document) bool) []exchange.SearchResultDevice {
+	if len(orgs) == 0 {
+		orgs = []string{defaultOrg}
+	}
+
+	nodes := make([]exchange.SearchResultDevice, 0)
+	for _, org := range orgs {
+		children := s.children(resourcePath(org, "nodes"))
+		for _, id := range sortedIds(children) {
+			doc := children[id]
+			if key, _ := doc["publicKey"].(string); key != "" && match(doc) {
+				nodes = append(nodes, exchange.SearchResultDevice{Id: org + "/" + id, NodeType: fmt.Sprint(doc["nodeType"]), PublicKey: key})
+			}
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Id < nodes[j].Id })
+	return nodes
+}
+
+// Return the heartbeat and agreements of the nodes in the orgs, or of the nodes that use a pattern.
+func (s *Server) serveNodeHealth(w http.ResponseWriter, org string, pattern string, body []byte) {
+	var req exchange.NodeHealthStatusRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	orgs := req.NodeOrgIds
+	if len(orgs) == 0 {
+		orgs = []string{org}
+	}
+
+	resp := exchange.NodeHealthStatus{Nodes: make(map[string]exchange.NodeInfo)}
+	for _, o := range orgs {
+		for id, doc := range s.children(resourcePath(o, "nodes")) {
+			if pattern != "" && doc["pattern"] != pattern {
+				continue
+			}
+			info := exchange.NodeInfo{LastHeartbeat: fmt.Sprint(doc["lastHeartbeat"]), Agreements: make(map[string]exchange.AgreementObject)}
+			for agId := range s.children(resourcePath(o, "nodes", id, "agreements")) {
+				info.Agreements[agId] = exchange.AgreementObject{}
+			}
+			resp.Nodes[o+"/"+id] = info
+		}
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// Serve the messages waiting for a node or an agbot, and the messages sent to it.
+func (s *Server) serveMessages(w http.ResponseWriter, r *http.Request, user string, receiver string, resource string, rest []string, body []byte) {
+	segs := strings.Split(receiver, "/")
+	org, id := segs[1], segs[3]
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		msgs := s.msgs[receiver]
+		if max, err := strconv.Atoi(r.URL.Query().Get("maxmsgs")); err == nil && max > 0 && max < len(msgs) {
+			msgs = msgs[:max]
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"messages": append([]document{}, msgs...), "lastIndex": 0})
+
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var post exchange.PostMessage
+		if err := json.Unmarshal(body, &post); err != nil {
+			writeResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// The message carries the public key of the sender, an agbot when the receiver is a node and the other way around.
+		senderKind, senderField, keyField := "agbots", "agbotId", "agbotPubKey"
+		if resource == exchange.RESOURCE_AGBOT_MSG {
+			senderKind, senderField, keyField = "nodes", "nodeId", "nodePubKey"
+		}
+		sender := s.docs[resourcePath(exchange.GetOrg(user), senderKind, exchange.GetId(user))]
+		if sender == nil {
+			writeResponse(w, http.StatusBadRequest, fmt.Sprintf("%v is not in %v", user, senderKind))
+			return
+		}
+
+		s.msgId++
+		s.msgs[receiver] = append(s.msgs[receiver], document{
+			"msgId":       s.msgId,
+			senderField:   user,
+			keyField:      sender["publicKey"],
+			"message":     post.Message,
+			"timeSent":    now(),
+			"timeExpires": time.Now().Add(time.Duration(post.TTL) * time.Second).UTC().Format(cutil.ExchangeTimeFormat),
+		})
+		s.recordChange(org, resource, id, exchange.CHANGE_OPERATION_CREATED)
+		writeResponse(w, http.StatusCreated, fmt.Sprintf("message %v inserted", s.msgId))
+
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		for _, msg := range s.msgs[receiver] {
+			if strconv.Itoa(msg["msgId"].(int)) == rest[0] {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"messages": []document{msg}, "lastIndex": 0})
+				return
+			}
+		}
+		writeResponse(w, http.StatusNotFound, fmt.Sprintf("message %v not found", rest[0]))
+
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		for ix, msg := range s.msgs[receiver] {
+			if strconv.Itoa(msg["msgId"].(int)) == rest[0] {
+				s.msgs[receiver] = append(s.msgs[receiver][:ix], s.msgs[receiver][ix+1:]...)
+				writeResponse(w, http.StatusNoContent, "")
+				return
+			}
+		}
+		writeResponse(w, http.StatusNotFound, fmt.Sprintf("message %v not found", rest[0]))
+
+	default:
+		writeResponse(w, http.StatusNotFound, "unknown resource")
+	}
+}
+
+// Create or replace a node or an agbot. The token stays the same unless a new one is given.
+func (s *Server) putIdentity(w http.ResponseWriter, key string, user string, body []byte) {
+	doc, err := toDocument(body)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if old, ok := s.docs[key]; ok {
+		for _, field := range []string{"token", "owner", "lastHeartbeat", "ha_group"} {
+			if v, ok := doc[field]; !ok || v == "" {
+				doc[field] = old[field]
+			}
+		}
+	} else {
+		doc["owner"] = user
+	}
+	s.store(key, doc)
+	s.recordResourceChange(key, exchange.CHANGE_OPERATION_CREATED_MODIFIED)
+	writeResponse(w, http.StatusCreated, key+" added or updated")
+}
+
+// Set the configuration state of the node's registered services with the url, and the version if given.
+func (s *Server) postConfigState(w http.ResponseWriter, node string, body []byte) {
+	var state exchange.ServiceConfigState
+	if err := json.Unmarshal(body, &state); err != nil {
+		writeResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	services, _ := s.docs[node]["registeredServices"].([]interface{})
+	for _, svc := range services {
+		if m, ok := svc.(map[string]interface{}); ok && (state.Url == "" || m["url"] == cutil.FormOrgSpecUrl(state.Url, state.Org)) &&
+			(state.Version == "" || m["version"] == "" || m["version"] == state.Version) {
+			m["configState"] = state.ConfigState
+		}
+	}
+	s.recordResourceChange(node+"/services_configstate", exchange.CHANGE_OPERATION_MODIFIED)
+	writeResponse(w, http.StatusCreated, "services configstate updated")
+}
+
+// Serve the documents directly under a path, keyed by keyFn(id) in the envelope of the response. The collection can be
+// read, and deleted if it is deletable.
+func (s *Server) serveCollection(w http.ResponseWriter, r *http.Request, parent string, deletable bool, envelope string, keyFn func(string) string) {
+	children := s.children(parent)
+	switch r.Method {
+	case http.MethodGet:
+		docs := make(map[string]document)
+		for id, doc := range children {
+			docs[keyFn(id)] = public(doc)
+		}
+		writeEnvelope(w, envelope, docs)
+	case http.MethodDelete:
+		if !deletable || len(children) == 0 {
+			writeResponse(w, http.StatusNotFound, "nothing to delete")
+			return
+		}
+		for id := range children {
+			s.deleteTree(parent + "/" + id)
+		}
+		s.recordResourceChange(parent, exchange.CHANGE_OPERATION_DELETED)
+		writeResponse(w, http.StatusNoContent, "")
+	default:
+		writeResponse(w, http.StatusMethodNotAllowed, r.Method+" not supported")
+	}
+}
+
+// Serve a resource that the exchange returns in an envelope, e.g. {"patterns":{"myorg/p1":{...}}}.
+func (s *Server) serveResource(w http.ResponseWriter, r *http.Request, key string, envelope string, id string, body []byte) {
+	if r.Method == http.MethodGet {
+		if doc, ok := s.docs[key]; !ok {
+			writeResponse(w, http.StatusNotFound, key+" not found")
+		} else if envelope == "nodeGroups" {
+			writeJSON(w, http.StatusOK, map[string]interface{}{envelope: []document{public(doc)}})
+		} else {
+			writeEnvelope(w, envelope, map[string]document{id: public(doc)})
+		}
+		return
+	}
+	s.serveDocument(w, r, key, body)
+}
+
+// Serve a resource that the exchange returns as is, and the writes to any resource.
+func (s *Server) serveDocument(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	doc, exists := s.docs[key]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			writeResponse(w, http.StatusNotFound, key+" not found")
+		} else {
+			writeJSON(w, http.StatusOK, public(doc))
+		}
+
+	case http.MethodPut, http.MethodPost:
+		if newDoc, err := toDocument(body); err != nil {
+			writeResponse(w, http.StatusBadRequest, err.Error())
+		} else {
+			s.store(key, newDoc)
+			s.recordResourceChange(key, exchange.CHANGE_OPERATION_CREATED_MODIFIED)
+			writeResponse(w, http.StatusCreated, key+" added or updated")
+		}
+
+	case http.MethodPatch:
+		// Anax patches one field at a time, the other fields in the body are empty.
+		if patch, err := toDocument(body); err != nil {
+			writeResponse(w, http.StatusBadRequest, err.Error())
+		} else if !exists {
+			writeResponse(w, http.StatusNotFound, key+" not found")
+		} else {
+			for field, value := range patch {
+				if value != nil {
+					doc[field] = value
+				}
+			}
+			doc["lastUpdated"] = now()
+			s.recordResourceChange(key, exchange.CHANGE_OPERATION_MODIFIED)
+			writeResponse(w, http.StatusCreated, key+" updated")
+		}
+
+	case http.MethodDelete:
+		if !exists {
+			writeResponse(w, http.StatusNotFound, key+" not found")
+		} else {
+			s.deleteTree(key)
+			s.recordResourceChange(key, exchange.CHANGE_OPERATION_DELETED)
+			writeResponse(w, http.StatusNoContent, "")
+		}
+
+	default:
+		writeResponse(w, http.StatusMethodNotAllowed, r.Method+" not supported")
+	}
+}
+
+// The exchange returns 404 for an empty list.
+func writeEnvelope(w http.ResponseWriter, envelope string, docs map[string]document) {
+	if len(docs) == 0 {
+		writeResponse(w, http.StatusNotFound, "not found")
+	} else {
+		writeJSON(w, http.StatusOK, map[string]interface{}{envelope: docs, "lastIndex": 0})
+	}
+}
+
+// Returns a copy of a document with its secrets masked, like the exchange returns it.
+func public(doc document) document {
+	out := make(document, len(doc))
+	for field, value := range doc {
+		if field == "token" || field == "password" {
+			value = maskedToken
+		}
+		out[field] = value
+	}
+	return out
+}
+
+// Returns true if the document has the values of the query parameters that are set.
+func matchesQuery(doc document, query map[string][]string, params ...string) bool {
+	for _, param := range params {
+		if v := query[param]; len(v) != 0 && v[0] != "" && fmt.Sprint(doc[param]) != v[0] {
+			return false
+		}
+	}
+	return true
+}
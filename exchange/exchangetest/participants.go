@@ -0,0 +1,149 @@
+package exchangetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// An agent whose node is registered in the fake. Its configuration points at the fake and its database holds the
+// node's registration, as it is after hzn register, so the agent's workers can be started with them by
+// anaxtest.StartAgent.
+type Agent struct {
+	Id     string // The node id, in the form org/id.
+	Token  string
+	Config *config.HorizonConfig
+	DB     *bolt.DB
+}
+
+func (a *Agent) String() string {
+	return fmt.Sprintf("Id: %v, DBPath: %v, ExchangeURL: %v", a.Id, a.Config.Edge.DBPath, a.Config.Edge.ExchangeURL)
+}
+
+// Returns the exchange context of the node, for calls to the fake.
+func (a *Agent) ExchangeContext() exchange.ExchangeContext {
+	return exchange.NewCustomExchangeContext(a.Id, a.Token, a.Config.Edge.ExchangeURL, a.Config.GetCSSURL(), a.Config.Collaborators.HTTPClientFactory)
+}
+
+func (a *Agent) Close() error {
+	return a.DB.Close()
+}
+
+// Add a node to the fake and set up an agent for it in dir. The node publishes its messaging key, and so becomes
+// visible to node searches, when the agent's workers start.
+func (s *Server) NewAgent(dir string, org string, id string, token string, pattern string) (*Agent, error) {
+	s.AddNode(org, id, token, pattern)
+
+	cfg, err := s.readConfig(dir, map[string]interface{}{
+		"Edge": map[string]interface{}{
+			"DBPath":      dir,
+			"PolicyPath":  path.Join(dir, "policy.d"),
+			"ExchangeURL": s.ExchangeURL(),
+			"AgbotURL":    s.URL + "/agbot/",
+			"FileSyncService": map[string]interface{}{
+				"CSSURL":          s.CSSURL(),
+				"PersistencePath": path.Join(dir, "ess"),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path.Join(dir, "anax.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	patternId := ""
+	if pattern != "" {
+		patternId = org + "/" + pattern
+	}
+	if _, err := persistence.SaveNewExchangeDevice(db, id, token, id, persistence.DEVICE_TYPE_DEVICE, org, patternId, persistence.CONFIGSTATE_CONFIGURED, persistence.SoftwareVersion{}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Agent{Id: org + "/" + id, Token: token, Config: cfg, DB: db}, nil
+}
+
+// An agbot registered in the fake. Its configuration points at the fake and at a bolt database in its directory, the
+// database is created when anaxtest.StartAgbot starts its workers.
+type Agbot struct {
+	Id     string // The agbot id, in the form org/id.
+	Token  string
+	Config *config.HorizonConfig
+}
+
+func (a *Agbot) String() string {
+	return fmt.Sprintf("Id: %v, DBPath: %v, ExchangeURL: %v", a.Id, a.Config.AgreementBot.DBPath, a.Config.AgreementBot.ExchangeURL)
+}
+
+// Returns the exchange context of the agbot, for calls to the fake.
+func (a *Agbot) ExchangeContext() exchange.ExchangeContext {
+	return exchange.NewCustomExchangeContext(a.Id, a.Token, a.Config.AgreementBot.ExchangeURL, a.Config.GetAgbotCSSURL(), a.Config.Collaborators.HTTPClientFactory)
+}
+
+// Add an agbot to the fake and set up its configuration in dir. The agbot serves the deployment policies of its org.
+func (s *Server) NewAgbot(dir string, org string, id string, token string) (*Agbot, error) {
+	s.AddAgbot(org, id, token)
+	s.AddServedPolicy(org+"/"+id, org, "*", org)
+
+	cfg, err := s.readConfig(dir, map[string]interface{}{
+		"AgreementBot": map[string]interface{}{
+			"DBPath":        dir,
+			"PolicyPath":    path.Join(dir, "policy.d"),
+			"ExchangeURL":   s.ExchangeURL(),
+			"ExchangeId":    org + "/" + id,
+			"ExchangeToken": token,
+			"CSSURL":        s.CSSURL(),
+
+			// The agbot has no defaults for these, the values are those of the agbot container.
+			"AgreementWorkers":              2,
+			"ProtocolTimeoutScaleFactor":    3,
+			"AgreementTimeoutScaleFactor":   3,
+			"NoDataIntervalS":               300,
+			"NewContractIntervalS":          1,
+			"ProcessGovernanceIntervalS":    1,
+			"ExchangeVersionCheckIntervalM": 1,
+			"ExchangeHeartbeat":             60,
+			"ExchangeMessageTTLScaleFactor": 3,
+			"ActiveDeviceTimeoutS":          180,
+			"PurgeArchivedAgreementHours":   1,
+			"CheckUpdatedPolicyS":           15,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Agbot{Id: org + "/" + id, Token: token, Config: cfg}, nil
+}
+
+// Write a configuration file and read it the way anax does, so that the defaults are set and the HTTP clients are
+// created. The environment variables that point anax at a management hub must not be set.
+func (s *Server) readConfig(dir string, content map[string]interface{}) (*config.HorizonConfig, error) {
+	if err := os.MkdirAll(path.Join(dir, "policy.d"), 0700); err != nil {
+		return nil, err
+	}
+
+	file := path.Join(dir, "anax.json")
+	if b, err := json.MarshalIndent(content, "", "  "); err != nil {
+		return nil, err
+	} else if err := os.WriteFile(file, b, 0600); err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.Read(file)
+	if err != nil {
+		return nil, err
+	} else if cfg.Edge.DBPath != "" && cfg.Edge.ExchangeURL != s.ExchangeURL() || cfg.AgreementBot.DBPath != "" && cfg.AgreementBot.ExchangeURL != s.ExchangeURL() {
+		return nil, fmt.Errorf("the exchange URL of the configuration is overridden by the environment, unset %v", config.ExchangeURLEnvvarName)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,366 @@
+// Package exchangetest provides an in-process fake of the exchange and CSS APIs that anax uses, for tests that need
+// more than a stubbed handler function but less than the Docker based environment in test/.
+//
+// The fake keeps its state in memory. Resources are created by the tests through the Add and Put functions, or by
+// anax through the same API calls it makes against a real exchange. The documents are stored as the JSON that was
+// sent, so the fake does not need to know every field of every resource. Every change is recorded and served by the
+// /changes API, so the agent's and the agbot's change workers see them. Requests can be made to fail with faults.
+//
+// Credentials are checked against the tokens of the nodes and agbots, the passwords of the users and the root
+// credentials, but the fake does not check whether an identity is allowed to access a resource.
+package exchangetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/version"
+)
+
+const (
+	exchangePrefix = "/v1/"
+	cssPrefix      = "/css/"
+
+	RootUser     = "root/root"
+	RootPassword = "root"
+)
+
+// A JSON document stored in the fake.
+type document map[string]interface{}
+
+// A request received by the fake.
+type Request struct {
+	Method string
+	Path   string // The path of the request, without the /v1 or /css prefix.
+	User   string
+}
+
+func (r Request) String() string {
+	return fmt.Sprintf("%v %v by %v", r.Method, r.Path, r.User)
+}
+
+// A fault makes the fake fail the requests that match it, or delay them.
+type Fault struct {
+	Method string        // The HTTP method to fail, every method if empty.
+	Path   string        // A regular expression that the request path is matched against, every path if empty.
+	Status int           // The status code of the response. If 0 the request is served normally, after the delay.
+	Drop   bool          // Close the connection without a response, i.e. a transport error.
+	Delay  time.Duration // How long to wait before failing or serving the request.
+	Times  int           // The number of requests to fail, 0 to fail them until the fault is removed.
+
+	path     *regexp.Regexp
+	injected int
+}
+
+func (f *Fault) String() string {
+	return fmt.Sprintf("Method: %v, Path: %v, Status: %v, Drop: %v, Delay: %v, Times: %v, Injected: %v", f.Method, f.Path, f.Status, f.Drop, f.Delay, f.Times, f.injected)
+}
+
+func (f *Fault) matches(method string, urlPath string) bool {
+	return (f.Method == "" || f.Method == method) && (f.path == nil || f.path.MatchString(urlPath)) && (f.Times == 0 || f.injected < f.Times)
+}
+
+// The fake exchange and CSS.
+type Server struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	docs     map[string]document       // The exchange resources, keyed by their path, e.g. orgs/myorg/nodes/node1.
+	msgs     map[string][]document     // The messages waiting for each node and agbot, keyed by the path of the node or agbot.
+	objects  map[string]*cssObject     // The CSS objects, keyed by org/type/id.
+	changes  []exchange.ExchangeChange // All the changes made, in order.
+	sessions map[string]int            // The number of nodes returned so far by each policy search session.
+	faults   []*Fault
+	requests []Request
+	msgId    int
+}
+
+// Start a fake exchange and CSS with the IBM org, which holds the agent file versions.
+func NewServer() *Server {
+	s := &Server{
+		docs:     make(map[string]document),
+		msgs:     make(map[string][]document),
+		objects:  make(map[string]*cssObject),
+		sessions: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(s)
+	s.AddOrg("IBM")
+	return s
+}
+
+// The URL of the exchange API, in the form that anax expects in its configuration.
+func (s *Server) ExchangeURL() string {
+	return s.URL + exchangePrefix
+}
+
+// The URL of the CSS API.
+func (s *Server) CSSURL() string {
+	return s.URL + strings.TrimSuffix(cssPrefix, "/")
+}
+
+// Make the requests that match the fault fail. Returns the fault, to remove it later.
+func (s *Server) AddFault(f Fault) *Fault {
+	fault := f
+	if f.Path != "" {
+		fault.path = regexp.MustCompile(f.Path)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = append(s.faults, &fault)
+	return &fault
+}
+
+func (s *Server) RemoveFault(f *Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for ix, fault := range s.faults {
+		if fault == f {
+			s.faults = append(s.faults[:ix], s.faults[ix+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = nil
+}
+
+// Returns the number of requests that the fault has failed so far.
+func (s *Server) Injected(f *Fault) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return f.injected
+}
+
+// Returns the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// Returns the number of requests received with the method, or any method if empty, and a path that matches the
+// regular expression.
+func (s *Server) RequestCount(method string, pathExp string) int {
+	exp := regexp.MustCompile(pathExp)
+	count := 0
+	for _, r := range s.Requests() {
+		if (method == "" || r.Method == method) && exp.MatchString(r.Path) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) ResetRequests() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests = nil
+}
+
+// Returns the changes recorded so far, in order.
+func (s *Server) Changes() []exchange.ExchangeChange {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]exchange.ExchangeChange{}, s.changes...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	urlPath := path.Clean(r.URL.Path)
+	api := ""
+	if strings.HasPrefix(urlPath+"/", exchangePrefix) {
+		api, urlPath = exchangePrefix, strings.TrimPrefix(urlPath, strings.TrimSuffix(exchangePrefix, "/"))
+	} else if strings.HasPrefix(urlPath+"/", cssPrefix) {
+		api, urlPath = cssPrefix, strings.TrimPrefix(urlPath, strings.TrimSuffix(cssPrefix, "/"))
+	}
+	user, pw, _ := r.BasicAuth()
+
+	s.lock.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: urlPath, User: user})
+	var fault *Fault
+	for _, f := range s.faults {
+		if f.matches(r.Method, urlPath) {
+			f.injected++
+			fault = f
+			break
+		}
+	}
+	s.lock.Unlock()
+
+	if fault != nil {
+		time.Sleep(fault.Delay)
+		if fault.Drop {
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					conn.Close()
+					return
+				}
+			}
+			panic(http.ErrAbortHandler)
+		} else if fault.Status != 0 {
+			writeResponse(w, fault.Status, "fault injected")
+			return
+		}
+	}
+
+	if api == "" {
+		writeResponse(w, http.StatusNotFound, "unknown API")
+		return
+	}
+
+	var body []byte
+	if r.Body != nil {
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(r.Body)
+		body = buf.Bytes()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if api == exchangePrefix && urlPath == "/admin/version" {
+		w.Write([]byte(version.PREFERRED_EXCHANGE_VERSION))
+	} else if !s.authenticated(user, pw) {
+		writeResponse(w, http.StatusUnauthorized, "invalid credentials")
+	} else if api == exchangePrefix {
+		s.serveExchange(w, r, user, strings.Split(strings.Trim(urlPath, "/"), "/"), body)
+	} else {
+		s.serveCSS(w, r, strings.Split(strings.Trim(urlPath, "/"), "/"), body)
+	}
+}
+
+// The caller must hold the lock.
+func (s *Server) authenticated(user string, pw string) bool {
+	if user == RootUser {
+		return pw == RootPassword
+	}
+	org, id := exchange.GetOrg(user), exchange.GetId(user)
+	if org == "" || id == "" {
+		return false
+	}
+	for _, kind := range []string{"nodes", "agbots"} {
+		if doc := s.docs[resourcePath(org, kind, id)]; doc != nil && doc["token"] == pw {
+			return true
+		}
+	}
+	if doc := s.docs[resourcePath(org, "users", id)]; doc != nil && doc["password"] == pw {
+		return true
+	}
+	return false
+}
+
+// Record a change, for the change workers of the agent and the agbot. The caller must hold the lock.
+func (s *Server) recordChange(org string, resource string, id string, operation string) {
+	changeId := uint64(len(s.changes) + 1)
+	s.changes = append(s.changes, exchange.ExchangeChange{
+		OrgID:           org,
+		Resource:        resource,
+		ID:              id,
+		Operation:       operation,
+		ResourceChanges: []exchange.ResourceChange{{ChangeID: changeId}},
+	})
+}
+
+// Returns the path of a resource, e.g. orgs/myorg/nodes/node1.
+func resourcePath(org string, parts ...string) string {
+	return path.Join(append([]string{"orgs", org}, parts...)...)
+}
+
+// Returns the documents directly under a path, keyed by their ids. The caller must hold the lock.
+func (s *Server) children(parent string) map[string]document {
+	children := make(map[string]document)
+	for key, doc := range s.docs {
+		if id := strings.TrimPrefix(key, parent+"/"); id != key && !strings.Contains(id, "/") {
+			children[id] = doc
+		}
+	}
+	return children
+}
+
+// Remove a document and the documents under it. The caller must hold the lock.
+func (s *Server) deleteTree(key string) {
+	for k := range s.docs {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(s.docs, k)
+		}
+	}
+	delete(s.msgs, key)
+}
+
+// Store a value as a document. The caller must hold the lock.
+func (s *Server) store(key string, value interface{}) {
+	if doc, err := toDocument(value); err != nil {
+		panic(fmt.Sprintf("unable to store %v, error: %v", key, err))
+	} else {
+		doc["lastUpdated"] = now()
+		s.docs[key] = doc
+	}
+}
+
+func toDocument(value interface{}) (document, error) {
+	var b []byte
+	if raw, ok := value.([]byte); ok {
+		b = raw
+	} else if serial, err := json.Marshal(value); err != nil {
+		return nil, err
+	} else {
+		b = serial
+	}
+
+	doc := make(document)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func now() string {
+	return time.Now().UTC().Format(cutil.ExchangeTimeFormat)
+}
+
+// Returns the ids of a map of documents, sorted.
+func sortedIds(docs map[string]document) []string {
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Write the response that the exchange writes for a status code, {"code":...,"msg":...}.
+func writeResponse(w http.ResponseWriter, status int, msg string) {
+	code := "ok"
+	switch {
+	case status == http.StatusNoContent:
+		w.WriteHeader(status)
+		return
+	case status == http.StatusNotFound:
+		code = "not found"
+	case status == http.StatusUnauthorized:
+		code = "invalid credentials"
+	case status >= 400:
+		code = "error"
+	}
+	writeJSON(w, status, map[string]string{"code": code, "msg": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,182 @@
+//go:build unit
+// +build unit
+
+package exchangetest
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/edge-sync-service/common"
+)
+
+func Test_Server_agent(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddOrg("myorg")
+	s.Put("orgs/myorg/patterns/p1", exchange.Pattern{Label: "p1", Services: []exchange.ServiceReference{}})
+
+	agent, err := s.NewAgent(t.TempDir(), "myorg", "node1", "token1", "p1")
+	if err != nil {
+		t.Fatalf("unable to set up the agent, error %v", err)
+	}
+	defer agent.Close()
+	if dev, err := persistence.FindExchangeDevice(agent.DB); err != nil || dev == nil || dev.GetId() != "myorg/node1" || dev.Pattern != "myorg/p1" {
+		t.Errorf("expecting node myorg/node1 in the agent's database, have %v, error %v", dev, err)
+	}
+
+	// The agent reads its node and pattern, and publishes its messaging key.
+	ec := agent.ExchangeContext()
+	factory := ec.GetHTTPFactory()
+	if dev, err := exchange.GetExchangeDevice(factory, agent.Id, agent.Id, agent.Token, ec.GetExchangeURL()); err != nil || dev.Pattern != "p1" {
+		t.Errorf("expecting node with pattern p1, have %v, error %v", dev, err)
+	} else if patterns, err := exchange.GetPatterns(factory, "myorg", "p1", ec.GetExchangeURL(), agent.Id, agent.Token); err != nil || patterns["myorg/p1"].Label != "p1" {
+		t.Errorf("expecting pattern myorg/p1, have %v, error %v", patterns, err)
+	} else if err := exchange.PatchNodeMessageKey(ec, []byte("key1")); err != nil {
+		t.Errorf("unable to patch the node's key, error %v", err)
+	}
+
+	// Wrong credentials are rejected.
+	if _, err := exchange.GetExchangeDevice(factory, agent.Id, agent.Id, "wrong", ec.GetExchangeURL()); err == nil {
+		t.Errorf("expecting an error for wrong credentials")
+	}
+
+	// The change worker sees the changes made by the agent and by users.
+	s.Put("orgs/myorg/nodes/node1/policy", map[string]interface{}{"properties": []interface{}{}})
+	if changes, err := exchange.GetExchangeChanges(ec, 1, 100, []string{"myorg"}); err != nil {
+		t.Errorf("unable to get changes, error %v", err)
+	} else if last := changes.Changes[len(changes.Changes)-1]; !last.IsNodePolicy("myorg/node1") || changes.MostRecentChangeID != last.ResourceChanges[0].ChangeID {
+		t.Errorf("expecting a node policy change, have %v", changes)
+	} else if changes, err := exchange.GetExchangeChanges(ec, changes.MostRecentChangeID+1, 100, []string{"myorg"}); err != nil || len(changes.Changes) != 0 {
+		t.Errorf("expecting no new changes, have %v, error %v", changes, err)
+	}
+
+	// A message from an agbot is delivered to the node with the agbot's key.
+	s.AddAgbot("myorg", "ag1", "agtoken")
+	s.Put("orgs/myorg/agbots/ag1", map[string]interface{}{"token": "agtoken", "publicKey": "YWdrZXk="})
+	var resp interface{}
+	resp = new(exchange.PostDeviceResponse)
+	if err, tpErr := exchange.InvokeExchange(http.DefaultClient, "POST", ec.GetExchangeURL()+"orgs/myorg/nodes/node1/msgs", "myorg/ag1", "agtoken", exchange.PostMessage{Message: []byte("hello"), TTL: 60}, &resp); err != nil || tpErr != nil {
+		t.Errorf("unable to send a message, error %v %v", err, tpErr)
+	}
+	resp = new(exchange.GetDeviceMessageResponse)
+	if err, _ := exchange.InvokeExchange(http.DefaultClient, "GET", ec.GetExchangeURL()+"orgs/myorg/nodes/node1/msgs", agent.Id, agent.Token, nil, &resp); err != nil {
+		t.Errorf("unable to get messages, error %v", err)
+	} else if msgs := resp.(*exchange.GetDeviceMessageResponse).Messages; len(msgs) != 1 || string(msgs[0].Message) != "hello" || msgs[0].AgbotId != "myorg/ag1" || string(msgs[0].AgbotPubKey) != "agkey" {
+		t.Errorf("expecting the message from the agbot, have %v", msgs)
+	} else if err, _ := exchange.InvokeExchange(http.DefaultClient, "DELETE", ec.GetExchangeURL()+"orgs/myorg/nodes/node1/msgs/1", agent.Id, agent.Token, nil, &resp); err != nil {
+		t.Errorf("unable to delete the message, error %v", err)
+	} else if count := s.MessageCount("orgs/myorg/nodes/node1"); count != 0 {
+		t.Errorf("expecting no messages, have %v", count)
+	}
+}
+
+func Test_Server_policySearch(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddOrg("myorg")
+	s.Put("orgs/myorg/business/policies/bp1", map[string]string{"label": "bp1"})
+	for _, id := range []string{"n1", "n2", "n3"} {
+		s.AddNode("myorg", id, "token", "")
+		s.Put("orgs/myorg/nodes/"+id+"/policy", map[string]string{})
+	}
+	var node map[string]interface{}
+	for _, id := range []string{"n1", "n2", "n3"} {
+		s.Get("orgs/myorg/nodes/"+id, &node)
+		node["publicKey"] = "a2V5"
+		s.Put("orgs/myorg/nodes/"+id, node)
+	}
+	s.AddNode("myorg", "unregistered", "token", "")
+
+	agbot, err := s.NewAgbot(t.TempDir(), "myorg", "ag1", "agtoken")
+	if err != nil {
+		t.Fatalf("unable to set up the agbot, error %v", err)
+	}
+	ec := agbot.ExchangeContext()
+
+	// The registered nodes are returned a page at a time, until a page is not full.
+	req := &exchange.SearchExchBusinessPolRequest{NodeOrgIds: []string{"myorg"}, Session: "s1", NumEntries: 2}
+	if resp, err := exchange.GetPolicyNodes(ec, "myorg", "bp1", req); err != nil || len(resp.Devices) != 2 || resp.Devices[0].Id != "myorg/n1" {
+		t.Errorf("expecting the first page of nodes, have %v, error %v", resp, err)
+	} else if resp, err := exchange.GetPolicyNodes(ec, "myorg", "bp1", req); err != nil || len(resp.Devices) != 1 || resp.Devices[0].Id != "myorg/n3" {
+		t.Errorf("expecting the last page of nodes, have %v, error %v", resp, err)
+	} else if resp, err := exchange.GetPolicyNodes(ec, "myorg", "bp1", req); err != nil || len(resp.Devices) != 2 {
+		t.Errorf("expecting a new session to start over, have %v, error %v", resp, err)
+	}
+}
+
+func Test_Server_faults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddOrg("myorg")
+	s.AddNode("myorg", "node1", "token1", "")
+	url := s.ExchangeURL() + "orgs/myorg/nodes/node1"
+
+	var resp interface{}
+	resp = new(exchange.GetDevicesResponse)
+	f := s.AddFault(Fault{Method: "GET", Path: "/nodes/node1$", Status: http.StatusServiceUnavailable, Times: 1})
+	if _, tpErr := exchange.InvokeExchange(http.DefaultClient, "GET", url, "myorg/node1", "token1", nil, &resp); tpErr == nil {
+		t.Errorf("expecting the fault to fail the request")
+	} else if err, _ := exchange.InvokeExchange(http.DefaultClient, "GET", url, "myorg/node1", "token1", nil, &resp); err != nil {
+		t.Errorf("expecting the fault to fail only one request, error %v", err)
+	} else if s.Injected(f) != 1 {
+		t.Errorf("expecting the fault to be injected once, was %v", s.Injected(f))
+	}
+
+	if count := s.RequestCount("GET", "^/orgs/myorg/nodes/node1$"); count != 2 {
+		t.Errorf("expecting 2 requests, have %v", count)
+	}
+
+	// The HTTP client retries a dropped GET once on a reused connection, so every request is dropped.
+	s.AddFault(Fault{Drop: true})
+	if _, tpErr := exchange.InvokeExchange(http.DefaultClient, "GET", url, "myorg/node1", "token1", nil, &resp); tpErr == nil {
+		t.Errorf("expecting a transport error")
+	}
+	s.ClearFaults()
+}
+
+func Test_Server_css(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddOrg("myorg")
+	s.AddAgbot("myorg", "ag1", "agtoken")
+	s.PutObject(common.MetaData{
+		DestOrgID:         "myorg",
+		ObjectType:        "model",
+		ObjectID:          "m1",
+		DestinationPolicy: &common.Policy{Services: []common.ServiceID{{OrgID: "myorg", ServiceName: "svc1", Version: "1.0.0"}}},
+	}, []byte("0123456789"))
+
+	dir := t.TempDir()
+	agbot, err := s.NewAgbot(dir, "myorg", "ag1", "agtoken")
+	if err != nil {
+		t.Fatalf("unable to set up the agbot, error %v", err)
+	}
+	ec := agbot.ExchangeContext()
+
+	if meta, err := exchange.GetObject(ec, "myorg", "m1", "model"); err != nil || meta.ObjectSize != 10 {
+		t.Errorf("expecting object m1, have %v, error %v", meta, err)
+	} else if pols, err := exchange.GetUpdatedObjects(ec, "myorg", 0); err != nil || len(*pols) != 1 {
+		t.Errorf("expecting the policy of m1, have %v, error %v", pols, err)
+	} else if err := exchange.SetPolicyReceived(ec, &(*pols)[0]); err != nil {
+		t.Errorf("unable to mark the policy received, error %v", err)
+	} else if pols, err := exchange.GetUpdatedObjects(ec, "myorg", 1); err != nil || len(*pols) != 0 {
+		t.Errorf("expecting no updated policies after they are received, have %v, error %v", pols, err)
+	}
+
+	if err := exchange.AddOrRemoveDestinations(ec, "myorg", "model", "m1", &exchange.PostDestsRequest{Action: "add", Destinations: []string{"openhorizon.edgenode:node1"}}); err != nil {
+		t.Errorf("unable to add a destination, error %v", err)
+	} else if dests := s.ObjectDestinations("myorg", "model", "m1"); len(dests) != 1 || dests[0].DestID != "node1" {
+		t.Errorf("expecting destination node1, have %v", dests)
+	}
+
+	if _, err := exchange.GetObjectDataByChunk(ec, "myorg", "model", "m1", 2, 5, false, dir, "chunk", false); err != nil {
+		t.Errorf("unable to get a chunk of the data, error %v", err)
+	} else if b, err := os.ReadFile(path.Join(dir, "chunk")); err != nil || len(b) != 6 || string(b[2:]) != "2345" {
+		t.Errorf("expecting chunk 2345 at offset 2, have %q, error %v", string(b), err)
+	}
+}
@@ -479,12 +479,12 @@ func GetHTTPAgbotPolicyNodeSearchHandler(ec ExchangeContext) AgbotPolicyNodeSear
 	}
 }
 
-// A handler for searching for nodes by pattern.
-type AgbotPatternNodeSearchHandler func(req *SearchExchangePatternRequest, policyOrg string, patternId string) (*[]SearchResultDevice, error)
+// A handler for searching for nodes by pattern. The nodes are handed to the page handler a page at a time.
+type AgbotPatternNodeSearchHandler func(req *SearchExchangePatternRequest, policyOrg string, patternId string, pageSize int, handler SearchResultPageHandler) (int, error)
 
 func GetHTTPAgbotPatternNodeSearchHandler(ec ExchangeContext) AgbotPatternNodeSearchHandler {
-	return func(req *SearchExchangePatternRequest, policyOrg string, patternId string) (*[]SearchResultDevice, error) {
-		return GetPatternNodes(ec, policyOrg, patternId, req)
+	return func(req *SearchExchangePatternRequest, policyOrg string, patternId string, pageSize int, handler SearchResultPageHandler) (int, error) {
+		return SearchPatternNodes(ec, policyOrg, patternId, req, pageSize, handler)
 	}
 }
 
@@ -0,0 +1,562 @@
+package exchange
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+)
+
+// The messaging keys can be replaced while the runtime is running. Messages that were encrypted with the old public
+// key, e.g. a proposal that was sent just before the new key was published, can still be decrypted for a grace
+// period because the old private key is retained, in memory and in the filesystem, until the grace period expires.
+// Retired private key files are named with the time they expire, so that a restarted runtime can find them, followed
+// by the key's fingerprint, so that keys retired in the same second do not overwrite each other.
+
+const retiredKeySuffix = ".retired."
+
+type retiredKey struct {
+	key     *rsa.PrivateKey
+	expires time.Time
+	file    string
+}
+
+// The retired keys, protected by the KeyLock.
+var gRetiredKeys []retiredKey
+var gRetiredKeysLoaded bool
+
+// The new key while it is being published, protected by the KeyLock. Once it is published the other party could use
+// it before the rotation has completed.
+var gPendingKey *rsa.PrivateKey
+
+// The staged files of an interrupted rotation are only looked for when the keys are first loaded, because later
+// the files could belong to a rotation in progress, in this or in another process that shares the keys. Protected by
+// the KeyLock.
+var gStagedKeysChecked bool
+
+// True when a rotation that was interrupted by a restart was completed when the keys were loaded, protected by the
+// KeyLock. The new key might not have been published, so the caller has to publish it again.
+var gRecoveredRotation bool
+
+// Only one rotation can be in progress at a time.
+var rotateLock sync.Mutex
+
+// A rotation whose new key was published but whose key files could not all be renamed in place. The new key is
+// pending until they are. Protected by the rotateLock.
+type uncommittedRotation struct {
+	staged  []*stagedKeyFile
+	retired retiredKey
+	newKey  *rsa.PrivateKey
+}
+
+var gUncommittedRotation *uncommittedRotation
+
+// Information about the messaging keys, as reported by the APIs.
+type MessageKeyInfo struct {
+	Fingerprint string  `json:"fingerprint"`  // The SHA256 hash of the public key
+	Created     int64   `json:"created"`      // Seconds since the epoch
+	RetiredKeys []int64 `json:"retired_keys"` // When each retired key expires, in seconds since the epoch
+}
+
+func (m MessageKeyInfo) String() string {
+	return fmt.Sprintf("Fingerprint: %v, Created: %v, RetiredKeys: %v", m.Fingerprint, m.Created, m.RetiredKeys)
+}
+
+// Return the directory that holds the messaging keys.
+func keyDir(keyPath string) string {
+	snap_common := os.Getenv("HZN_VAR_BASE")
+	if len(snap_common) == 0 {
+		snap_common = config.HZN_VAR_BASE_DEFAULT
+	}
+	return filepath.Clean(filepath.Join(snap_common, keyPath))
+}
+
+// Encode a pem block into a file. The file is written under a temporary name and then renamed, so that a reader never
+// sees a partially written key.
+func writePemFile(fileName string, block *pem.Block) error {
+	if sf, err := stageKeyFile(fileName, block); err != nil {
+		return err
+	} else if err := commitKeyFiles([]*stagedKeyFile{sf}); err != nil {
+		discardKeyFiles([]*stagedKeyFile{sf})
+		return err
+	}
+	return nil
+}
+
+// A key file that has been written under a temporary name, and is renamed in place when it is committed.
+type stagedKeyFile struct {
+	tmpName string
+	name    string
+}
+
+// Encode a pem block into a temporary file in the directory of the input file name.
+func stageKeyFile(fileName string, block *pem.Block) (*stagedKeyFile, error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not create key file %v, error %v", fileName, err))
+	}
+
+	if err := tmpFile.Chmod(0600); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, errors.New(fmt.Sprintf("Could not chmod key file %v, error %v", fileName, err))
+	} else if err := pem.Encode(tmpFile, block); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, errors.New(fmt.Sprintf("Could not encode key to file %v, error %v", fileName, err))
+	} else if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, errors.New(fmt.Sprintf("Could not close key file %v, error %v", fileName, err))
+	}
+	return &stagedKeyFile{tmpName: tmpFile.Name(), name: fileName}, nil
+}
+
+// Rename the staged key files in place, in order. The files that have not been renamed when an error occurs are left
+// staged, the caller decides whether to try again or to remove them.
+func commitKeyFiles(staged []*stagedKeyFile) error {
+	for _, sf := range staged {
+		if err := os.Rename(sf.tmpName, sf.name); err != nil {
+			return errors.New(fmt.Sprintf("Could not rename key file to %v, error %v", sf.name, err))
+		}
+	}
+	return nil
+}
+
+// Remove the temporary files of staged key files that will not be committed.
+func discardKeyFiles(staged []*stagedKeyFile) {
+	for _, sf := range staged {
+		if err := os.Remove(sf.tmpName); err != nil && !os.IsNotExist(err) {
+			glog.Errorf(rpclogString(fmt.Sprintf("Unable to remove temporary key file %v, error %v", sf.tmpName, err)))
+		}
+	}
+}
+
+func privateKeyPem(privateKey *rsa.PrivateKey) *pem.Block {
+	return &pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: nil,
+		Bytes:   x509.MarshalPKCS1PrivateKey(privateKey)}
+}
+
+// Stage the public and private key files for the input key, so that both are written before either one replaces the
+// current key files. The public key is committed last because its modification time is used as the time the key was
+// created.
+func stageKeyFiles(dir string, privateKey *rsa.PrivateKey) ([]*stagedKeyFile, error) {
+	pubKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not marshal public key, error %v", err))
+	}
+
+	privFile, err := stageKeyFile(filepath.Join(dir, privFileName), privateKeyPem(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	pubFile, err := stageKeyFile(filepath.Join(dir, pubFileName), &pem.Block{
+		Type:    "PUBLIC KEY",
+		Headers: nil,
+		Bytes:   pubKeyBytes})
+	if err != nil {
+		discardKeyFiles([]*stagedKeyFile{privFile})
+		return nil, err
+	}
+	return []*stagedKeyFile{privFile, pubFile}, nil
+}
+
+// Write the public and private key files for the input key.
+func writeKeyFiles(dir string, privateKey *rsa.PrivateKey) error {
+	if staged, err := stageKeyFiles(dir, privateKey); err != nil {
+		return err
+	} else if err := commitKeyFiles(staged); err != nil {
+		discardKeyFiles(staged)
+		return err
+	}
+	return nil
+}
+
+func readPublicKeyFile(fileName string) (*rsa.PublicKey, error) {
+	if pubBytes, err := os.ReadFile(fileName); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read public key file %v, error: %v", fileName, err))
+	} else if pubBlock, _ := pem.Decode(pubBytes); pubBlock == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to extract pem block from public key file %v", fileName))
+	} else if publicKey, err := DemarshalPublicKey(pubBlock.Bytes); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse public key file %v, error: %v", fileName, err))
+	} else {
+		return publicKey, nil
+	}
+}
+
+func readPrivateKeyFile(fileName string) (*rsa.PrivateKey, error) {
+	if privBytes, err := os.ReadFile(fileName); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read private key file %v, error: %v", fileName, err))
+	} else if privBlock, _ := pem.Decode(privBytes); privBlock == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to extract pem block from private key file %v", fileName))
+	} else if privateKey, err := x509.ParsePKCS1PrivateKey(privBlock.Bytes); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse private key file %v, error: %v", fileName, err))
+	} else {
+		return privateKey, nil
+	}
+}
+
+// Load the retired keys from the filesystem, removing the ones that have expired. The caller must hold the KeyLock.
+func loadRetiredKeys(dir string) {
+	gRetiredKeys = nil
+	gRetiredKeysLoaded = true
+
+	files, err := filepath.Glob(filepath.Join(dir, privFileName+retiredKeySuffix+"*"))
+	if err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("Error finding retired messaging keys in %v, error %v", dir, err)))
+		return
+	}
+
+	for _, file := range files {
+		// The fingerprint is missing from the files retired by older runtimes.
+		expiryStr := strings.SplitN(strings.TrimPrefix(filepath.Base(file), privFileName+retiredKeySuffix), ".", 2)[0]
+		expiry, err := strconv.ParseInt(expiryStr, 10, 64)
+		if err != nil {
+			glog.Warningf(rpclogString(fmt.Sprintf("Ignoring retired messaging key file %v, the expiry time is not valid", file)))
+			continue
+		}
+		rk := retiredKey{expires: time.Unix(expiry, 0), file: file}
+		if time.Now().After(rk.expires) {
+			removeRetiredKey(rk)
+		} else if rk.key, err = readPrivateKeyFile(file); err != nil {
+			glog.Errorf(rpclogString(err.Error()))
+		} else {
+			gRetiredKeys = append(gRetiredKeys, rk)
+		}
+	}
+	sort.Slice(gRetiredKeys, func(i, j int) bool { return gRetiredKeys[i].expires.After(gRetiredKeys[j].expires) })
+}
+
+// Returns the name of the file of a retired key, made of the time it expires and the fingerprint of its public key.
+func retiredKeyFileName(dir string, rk retiredKey) string {
+	name := privFileName + retiredKeySuffix + strconv.FormatInt(rk.expires.Unix(), 10)
+	if pubBytes, err := MarshalPublicKey(&rk.key.PublicKey); err == nil {
+		name += fmt.Sprintf(".%x", sha256.Sum256(pubBytes))[:17]
+	}
+	return filepath.Join(dir, name)
+}
+
+func removeRetiredKey(rk retiredKey) {
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("Removing expired messaging key %v", rk.file)))
+	if err := os.Remove(rk.file); err != nil && !os.IsNotExist(err) {
+		glog.Errorf(rpclogString(fmt.Sprintf("Error removing expired messaging key %v, error %v", rk.file, err)))
+	}
+}
+
+// Drop the retired keys whose grace period has expired. The caller must hold the KeyLock.
+func pruneRetiredKeys() {
+	unexpired := make([]retiredKey, 0, len(gRetiredKeys))
+	for _, rk := range gRetiredKeys {
+		if time.Now().After(rk.expires) {
+			removeRetiredKey(rk)
+		} else {
+			unexpired = append(unexpired, rk)
+		}
+	}
+	gRetiredKeys = unexpired
+}
+
+// Return all the private keys that can be used to decrypt a message: the current key, the key being published by a
+// rotation, and the retired keys that are still within their grace period, newest first.
+func GetDecryptionKeys(keyPath string) ([]*rsa.PrivateKey, error) {
+	if _, privateKey, err := GetKeys(keyPath); err != nil {
+		return nil, err
+	} else {
+		KeyLock.Lock()
+		defer KeyLock.Unlock()
+
+		if !gRetiredKeysLoaded {
+			loadRetiredKeys(keyDir(keyPath))
+		}
+		pruneRetiredKeys()
+
+		keys := []*rsa.PrivateKey{privateKey}
+		if gPendingKey != nil {
+			keys = append(keys, gPendingKey)
+		}
+		for _, rk := range gRetiredKeys {
+			keys = append(keys, rk.key)
+		}
+		return keys, nil
+	}
+}
+
+// Forget the keys held in memory and load them from the filesystem again. This is used when another process that
+// shares the keys, e.g. another agbot instance, could have rotated them.
+func ReloadKeys(keyPath string) (*rsa.PublicKey, *rsa.PrivateKey, error) {
+	KeyLock.Lock()
+	gPublicKey = nil
+	gPrivateKey = nil
+	gRetiredKeys = nil
+	gRetiredKeysLoaded = false
+	gHybridKeys = nil
+	KeyLock.Unlock()
+
+	return GetKeys(keyPath)
+}
+
+// Return information about the messaging keys, creating them if necessary.
+func GetMessageKeyInfo(keyPath string) (*MessageKeyInfo, error) {
+	pubKey, _, err := GetKeys(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	pubBytes, err := MarshalPublicKey(pubKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error marshalling public key, error %v", err))
+	}
+
+	KeyLock.Lock()
+	defer KeyLock.Unlock()
+
+	info := &MessageKeyInfo{
+		Fingerprint: fmt.Sprintf("%x", sha256.Sum256(pubBytes)),
+		RetiredKeys: make([]int64, 0),
+	}
+	if fi, err := os.Stat(filepath.Join(keyDir(keyPath), pubFileName)); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to stat public key file, error %v", err))
+	} else {
+		info.Created = fi.ModTime().Unix()
+	}
+
+	if !gRetiredKeysLoaded {
+		loadRetiredKeys(keyDir(keyPath))
+	}
+	pruneRetiredKeys()
+	for _, rk := range gRetiredKeys {
+		info.RetiredKeys = append(info.RetiredKeys, rk.expires.Unix())
+	}
+	return info, nil
+}
+
+// Returns true when the messaging keys are older than the input interval. A zero interval means keys are only
+// rotated on demand.
+func KeyRotationDue(keyPath string, intervalH int) bool {
+	if intervalH <= 0 {
+		return false
+	} else if info, err := GetMessageKeyInfo(keyPath); err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("Error getting messaging key information, error %v", err)))
+		return false
+	} else {
+		return time.Since(time.Unix(info.Created, 0)) >= time.Duration(intervalH)*time.Hour
+	}
+}
+
+// Finish or undo a rotation that was interrupted by a restart, using the key files it left staged. The staged files
+// are committed in the order RotateKeys commits them: the retired key, then the private key and then the public key.
+// If the new public key file was staged, the whole new key was staged and could have been published, so the rotation
+// is completed and the old key is retained as a retired key. Otherwise the new key was never published, and the staged
+// files are removed. Returns true when a rotation was completed. The caller must hold the KeyLock.
+func recoverStagedKeys(dir string) bool {
+	if gStagedKeysChecked {
+		return false
+	}
+	gStagedKeysChecked = true
+
+	stagedFiles := func(pattern string) []*stagedKeyFile {
+		files, _ := filepath.Glob(filepath.Join(dir, pattern))
+		staged := make([]*stagedKeyFile, 0, len(files))
+		for _, file := range files {
+			staged = append(staged, &stagedKeyFile{tmpName: file, name: file[:strings.LastIndex(file, ".tmp")]})
+		}
+		return staged
+	}
+	retired := stagedFiles(privFileName + retiredKeySuffix + "*.tmp*")
+	priv := stagedFiles(privFileName + ".tmp*")
+	pub := stagedFiles(pubFileName + ".tmp*")
+	if len(retired)+len(priv)+len(pub) == 0 {
+		return false
+	}
+
+	// The new private key is in the staged private key file, or in the key file if it was already renamed.
+	privFile := filepath.Join(dir, privFileName)
+	if len(priv) == 1 {
+		privFile = priv[0].tmpName
+	}
+	complete := false
+	if len(pub) == 1 && len(priv) <= 1 {
+		if pubKey, err := readPublicKeyFile(pub[0].tmpName); err != nil {
+			glog.Errorf(rpclogString(err.Error()))
+		} else if privKey, err := readPrivateKeyFile(privFile); err != nil {
+			glog.Errorf(rpclogString(err.Error()))
+		} else {
+			complete = privKey.PublicKey.Equal(pubKey)
+		}
+		for _, rf := range retired {
+			if _, err := readPrivateKeyFile(rf.tmpName); err != nil {
+				glog.Errorf(rpclogString(err.Error()))
+				complete = false
+			}
+		}
+	}
+
+	staged := append(append(retired, priv...), pub...)
+	if !complete {
+		glog.Warningf(rpclogString(fmt.Sprintf("Removing the key files of an interrupted messaging key rotation in %v", dir)))
+		discardKeyFiles(staged)
+		return false
+	}
+
+	glog.Warningf(rpclogString(fmt.Sprintf("Completing an interrupted messaging key rotation in %v", dir)))
+	if err := commitKeyFiles(staged); err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("Unable to complete the messaging key rotation, it is tried again at the next start, error %v", err)))
+		return false
+	}
+	gRetiredKeysLoaded = false
+	return true
+}
+
+// Publish the current messaging public key again if an interrupted rotation was completed when the keys were loaded,
+// because the new key might not have been published before the restart. The old key is retained for the grace period,
+// so messages encrypted with the key in the exchange can be decrypted until it is published.
+func PublishRecoveredKey(keyPath string, publish func(publicKey []byte) error) error {
+	pubKey, _, err := GetKeys(keyPath)
+	if err != nil {
+		return err
+	}
+
+	KeyLock.Lock()
+	recovered := gRecoveredRotation
+	KeyLock.Unlock()
+	if !recovered {
+		return nil
+	}
+
+	if pubBytes, err := MarshalPublicKey(pubKey); err != nil {
+		return errors.New(fmt.Sprintf("Could not marshal public key, error %v", err))
+	} else if err := publish(pubBytes); err != nil {
+		return errors.New(fmt.Sprintf("Unable to publish recovered messaging key, error %v", err))
+	}
+
+	KeyLock.Lock()
+	gRecoveredRotation = false
+	KeyLock.Unlock()
+	return nil
+}
+
+// Replace the messaging keys. The new keys, and the old private key which is retained for the grace period, are
+// written to temporary files first. Then the new public key is published by the caller's function, e.g. to the node
+// or agbot resource in the exchange. If the files cannot be written or the key cannot be published, the current keys
+// are kept. Once it is published the files are renamed in place and the new keys are used. The old private key can
+// still decrypt the messages which were encrypted with the old public key until the grace period expires. If the
+// runtime stops before the files are renamed, the rotation is completed when the keys are loaded again, see
+// recoverStagedKeys.
+func RotateKeys(keyPath string, gracePeriodS int, publish func(publicKey []byte) error) error {
+	rotateLock.Lock()
+	defer rotateLock.Unlock()
+
+	// Make sure the current keys are loaded, so that there is something to retire.
+	if _, _, err := GetKeys(keyPath); err != nil {
+		return err
+	}
+
+	// The key of a previous rotation is already in use by the other party, so its files are saved before it is
+	// replaced.
+	if gUncommittedRotation != nil {
+		KeyLock.Lock()
+		err := completeRotation(gUncommittedRotation)
+		KeyLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not generate private key, error %v", err))
+	}
+	pubBytes, err := MarshalPublicKey(&newKey.PublicKey)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not marshal public key, error %v", err))
+	}
+
+	dir := keyDir(keyPath)
+	KeyLock.Lock()
+	if !gRetiredKeysLoaded {
+		loadRetiredKeys(dir)
+	}
+	retired := retiredKey{key: gPrivateKey, expires: time.Now().Add(time.Duration(gracePeriodS) * time.Second)}
+	KeyLock.Unlock()
+	retired.file = retiredKeyFileName(dir, retired)
+
+	// The retired key is committed first, so that the old key is never lost.
+	retiredFile, err := stageKeyFile(retired.file, privateKeyPem(retired.key))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to save retired messaging key, error %v", err))
+	}
+	keyFiles, err := stageKeyFiles(dir, newKey)
+	if err != nil {
+		discardKeyFiles([]*stagedKeyFile{retiredFile})
+		return errors.New(fmt.Sprintf("Unable to save new messaging key, error %v", err))
+	}
+	staged := append([]*stagedKeyFile{retiredFile}, keyFiles...)
+
+	KeyLock.Lock()
+	gPendingKey = newKey
+	KeyLock.Unlock()
+
+	if err := publish(pubBytes); err != nil {
+		discardKeyFiles(staged)
+		KeyLock.Lock()
+		gPendingKey = nil
+		KeyLock.Unlock()
+		return errors.New(fmt.Sprintf("Unable to publish new messaging key, error %v", err))
+	}
+
+	KeyLock.Lock()
+	defer KeyLock.Unlock()
+	return completeRotation(&uncommittedRotation{staged: staged, retired: retired, newKey: newKey})
+}
+
+// Rename the key files of a published rotation in place and start using the new key. The new key has been published,
+// so the other party could already be using it. If the files cannot be renamed the key stays pending, which allows
+// messages encrypted with it to be decrypted, and the files are left staged, so that the next rotation or the next
+// start can finish saving them. The caller must hold the rotateLock and the KeyLock.
+func completeRotation(rotation *uncommittedRotation) error {
+	for len(rotation.staged) != 0 {
+		if err := commitKeyFiles(rotation.staged[:1]); err != nil {
+			gUncommittedRotation = rotation
+			return errors.New(fmt.Sprintf("Unable to save new messaging key, error %v", err))
+		}
+		rotation.staged = rotation.staged[1:]
+	}
+	gUncommittedRotation = nil
+
+	gRetiredKeys = append([]retiredKey{rotation.retired}, gRetiredKeys...)
+	gPublicKey = &rotation.newKey.PublicKey
+	gPrivateKey = rotation.newKey
+	gPendingKey = nil
+
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("Rotated messaging keys, the old key can be used until %v", rotation.retired.expires)))
+	return nil
+}
+
+// Remove the retired keys from memory and from the filesystem. The caller must hold the KeyLock.
+func deleteRetiredKeys(dir string) error {
+	gRetiredKeys = nil
+	gRetiredKeysLoaded = false
+	files, err := filepath.Glob(filepath.Join(dir, privFileName+retiredKeySuffix+"*"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+//go:build unit
+// +build unit
+
+package exchange
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Setup an empty key directory and load new keys from it.
+func keyTestSetup(t *testing.T) {
+	_ = os.Setenv("HZN_VAR_BASE", t.TempDir())
+	if _, _, err := ReloadKeys(""); err != nil {
+		t.Fatalf("Could not generate keys, error %v", err)
+	}
+}
+
+// Encrypt a message for the current public key.
+func messageForCurrentKey(t *testing.T) []byte {
+	senderKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Could not generate sender key, error %v", err)
+	}
+	pubKey, _, _ := GetKeys("")
+	msg, err := ConstructExchangeMessage([]byte("proposal"), &senderKey.PublicKey, senderKey, pubKey)
+	if err != nil {
+		t.Fatalf("Could not construct message, error %v", err)
+	}
+	return encodeMessage(t, msg)
+}
+
+func encodeMessage(t *testing.T, msg *ExchangeMessage) []byte {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Could not marshal message, error %v", err)
+	}
+	return b
+}
+
+func TestRotateKeys(t *testing.T) {
+	keyTestSetup(t)
+	inFlight := messageForCurrentKey(t)
+
+	var published []byte
+	if err := RotateKeys("", 3600, func(publicKey []byte) error {
+		published = publicKey
+		return nil
+	}); err != nil {
+		t.Fatalf("Could not rotate keys, error %v", err)
+	}
+
+	pubKey, privKey, _ := GetKeys("")
+	if b, _ := MarshalPublicKey(pubKey); !bytes.Equal(b, published) {
+		t.Errorf("published key is not the current key")
+	}
+
+	// The in-flight message was encrypted for the old key, it can only be decrypted with the retired key.
+	if _, _, err := DeconstructExchangeMessage(inFlight, privKey); err == nil {
+		t.Errorf("message should not be decrypted with the new key")
+	} else if keys, err := GetDecryptionKeys(""); err != nil || len(keys) != 2 {
+		t.Errorf("expecting 2 decryption keys, have %v, error %v", len(keys), err)
+	} else if msg, _, err := DeconstructExchangeMessageWithKeys(inFlight, keys); err != nil {
+		t.Errorf("could not decrypt in-flight message, error %v", err)
+	} else if string(msg) != "proposal" {
+		t.Errorf("unexpected message %s", msg)
+	}
+
+	// New messages use the new key.
+	if _, _, err := DeconstructExchangeMessage(messageForCurrentKey(t), privKey); err != nil {
+		t.Errorf("could not decrypt new message, error %v", err)
+	}
+
+	// The retired key survives a restart.
+	if _, _, err := ReloadKeys(""); err != nil {
+		t.Fatalf("Could not reload keys, error %v", err)
+	} else if keys, _ := GetDecryptionKeys(""); len(keys) != 2 {
+		t.Errorf("expecting 2 decryption keys after a restart, have %v", len(keys))
+	} else if _, _, err := DeconstructExchangeMessageWithKeys(inFlight, keys); err != nil {
+		t.Errorf("could not decrypt in-flight message after a restart, error %v", err)
+	} else if info, err := GetMessageKeyInfo(""); err != nil || len(info.RetiredKeys) != 1 {
+		t.Errorf("expecting 1 retired key, have %v, error %v", info, err)
+	}
+}
+
+func TestRotateKeys_publishFails(t *testing.T) {
+	keyTestSetup(t)
+	inFlight := messageForCurrentKey(t)
+	before, _ := GetMessageKeyInfo("")
+
+	if err := RotateKeys("", 3600, func(publicKey []byte) error {
+		return errors.New("exchange down")
+	}); err == nil {
+		t.Fatalf("rotation should have failed")
+	}
+
+	if after, _ := GetMessageKeyInfo(""); after.Fingerprint != before.Fingerprint || len(after.RetiredKeys) != 0 {
+		t.Errorf("keys should not have changed, were %v, are %v", before, after)
+	} else if keys, _ := GetDecryptionKeys(""); len(keys) != 1 {
+		t.Errorf("expecting 1 decryption key, have %v", len(keys))
+	} else if _, _, err := DeconstructExchangeMessageWithKeys(inFlight, keys); err != nil {
+		t.Errorf("could not decrypt message, error %v", err)
+	}
+}
+
+// The key files are written before the new key is published, and only replace the current ones once it is.
+func TestRotateKeys_savedBeforePublish(t *testing.T) {
+	keyTestSetup(t)
+	before, _ := GetMessageKeyInfo("")
+	tmpFiles := filepath.Join(keyDir(""), "*.tmp*")
+
+	if err := RotateKeys("", 3600, func(publicKey []byte) error {
+		if files, _ := filepath.Glob(tmpFiles); len(files) != 3 {
+			t.Errorf("expecting the retired, private and public key files to be staged, found %v", files)
+		} else if privKey, err := readPrivateKeyFile(filepath.Join(keyDir(""), privFileName)); err != nil {
+			t.Errorf("could not read private key, error %v", err)
+		} else if b, _ := MarshalPublicKey(&privKey.PublicKey); fmt.Sprintf("%x", sha256.Sum256(b)) != before.Fingerprint {
+			t.Errorf("the private key file should not change before the new key is published")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Could not rotate keys, error %v", err)
+	}
+
+	if files, _ := filepath.Glob(tmpFiles); len(files) != 0 {
+		t.Errorf("staged key files should have been renamed, found %v", files)
+	}
+
+	// A failed publish leaves no staged files behind.
+	if err := RotateKeys("", 3600, func(publicKey []byte) error {
+		return errors.New("exchange down")
+	}); err == nil {
+		t.Errorf("rotation should have failed")
+	} else if files, _ := filepath.Glob(tmpFiles); len(files) != 0 {
+		t.Errorf("staged key files should have been removed, found %v", files)
+	}
+}
+
+// The new key is not published when it cannot be saved.
+func TestRotateKeys_saveFails(t *testing.T) {
+	keyTestSetup(t)
+	before, _ := GetMessageKeyInfo("")
+	if err := os.RemoveAll(keyDir("")); err != nil {
+		t.Fatalf("Could not remove key directory, error %v", err)
+	}
+
+	published := false
+	if err := RotateKeys("", 3600, func(publicKey []byte) error {
+		published = true
+		return nil
+	}); err == nil {
+		t.Errorf("rotation should have failed")
+	} else if published {
+		t.Errorf("the new key should not be published")
+	} else if pubKey, _, _ := GetKeys(""); pubKey == nil {
+		t.Errorf("the current key should still be in use")
+	} else if b, _ := MarshalPublicKey(pubKey); fmt.Sprintf("%x", sha256.Sum256(b)) != before.Fingerprint {
+		t.Errorf("the current key should not change")
+	}
+}
+
+func TestRotateKeys_graceExpired(t *testing.T) {
+	keyTestSetup(t)
+	inFlight := messageForCurrentKey(t)
+
+	if err := RotateKeys("", 0, func(publicKey []byte) error { return nil }); err != nil {
+		t.Fatalf("Could not rotate keys, error %v", err)
+	}
+
+	if keys, _ := GetDecryptionKeys(""); len(keys) != 1 {
+		t.Errorf("expecting 1 decryption key, have %v", len(keys))
+	} else if _, _, err := DeconstructExchangeMessageWithKeys(inFlight, keys); err == nil {
+		t.Errorf("message should not be decrypted after the grace period")
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(keyDir(""), privFileName+retiredKeySuffix+"*")); len(files) != 0 {
+		t.Errorf("expired key files should have been removed, found %v", files)
+	}
+}
+
+// Keys retired in the same second are kept in separate files.
+func TestRotateKeys_sameSecond(t *testing.T) {
+	keyTestSetup(t)
+	first := messageForCurrentKey(t)
+
+	if err := RotateKeys("", 3600, func(publicKey []byte) error { return nil }); err != nil {
+		t.Fatalf("Could not rotate keys, error %v", err)
+	}
+	second := messageForCurrentKey(t)
+	if err := RotateKeys("", 3600, func(publicKey []byte) error { return nil }); err != nil {
+		t.Fatalf("Could not rotate keys, error %v", err)
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(keyDir(""), privFileName+retiredKeySuffix+"*")); len(files) != 2 {
+		t.Errorf("expecting 2 retired key files, found %v", files)
+	}
+	if _, _, err := ReloadKeys(""); err != nil {
+		t.Fatalf("Could not reload keys, error %v", err)
+	}
+	keys, _ := GetDecryptionKeys("")
+	for _, msg := range [][]byte{first, second} {
+		if _, _, err := DeconstructExchangeMessageWithKeys(msg, keys); err != nil {
+			t.Errorf("could not decrypt in-flight message after a restart, error %v", err)
+		}
+	}
+}
+
+// A rotation interrupted after the new key was published is completed when the keys are loaded again, and the new
+// key is published again.
+func TestRotateKeys_interrupted(t *testing.T) {
+	keyTestSetup(t)
+	inFlight := messageForCurrentKey(t)
+
+	// Copy the key directory as it is while the new key is published, as if the runtime stopped at that point.
+	crashDir := t.TempDir()
+	var published []byte
+	if err := RotateKeys("", 3600, func(publicKey []byte) error {
+		published = publicKey
+		files, _ := filepath.Glob(filepath.Join(keyDir(""), "*"))
+		for _, file := range files {
+			if b, err := os.ReadFile(file); err != nil {
+				return err
+			} else if err := os.WriteFile(filepath.Join(crashDir, filepath.Base(file)), b, 0600); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Could not rotate keys, error %v", err)
+	}
+
+	_ = os.Setenv("HZN_VAR_BASE", crashDir)
+	gStagedKeysChecked = false
+	pubKey, _, err := ReloadKeys("")
+	if err != nil {
+		t.Fatalf("Could not reload keys, error %v", err)
+	} else if b, _ := MarshalPublicKey(pubKey); !bytes.Equal(b, published) {
+		t.Errorf("the published key should be the current key")
+	}
+	if files, _ := filepath.Glob(filepath.Join(crashDir, "*.tmp*")); len(files) != 0 {
+		t.Errorf("staged key files should have been renamed, found %v", files)
+	}
+	if keys, _ := GetDecryptionKeys(""); len(keys) != 2 {
+		t.Errorf("expecting 2 decryption keys, have %v", len(keys))
+	} else if _, _, err := DeconstructExchangeMessageWithKeys(inFlight, keys); err != nil {
+		t.Errorf("could not decrypt in-flight message, error %v", err)
+	}
+
+	var republished [][]byte
+	publish := func(publicKey []byte) error {
+		republished = append(republished, publicKey)
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := PublishRecoveredKey("", publish); err != nil {
+			t.Errorf("Could not publish recovered key, error %v", err)
+		}
+	}
+	if len(republished) != 1 || !bytes.Equal(republished[0], published) {
+		t.Errorf("the recovered key should be published once, published %v keys", len(republished))
+	}
+}
+
+// A rotation interrupted before the new key was staged completely is undone when the keys are loaded again.
+func TestRotateKeys_interruptedBeforeStaged(t *testing.T) {
+	keyTestSetup(t)
+	before, _ := GetMessageKeyInfo("")
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Could not generate key, error %v", err)
+	} else if _, err := stageKeyFile(filepath.Join(keyDir(""), privFileName), privateKeyPem(newKey)); err != nil {
+		t.Fatalf("Could not stage key, error %v", err)
+	}
+
+	gStagedKeysChecked = false
+	if _, _, err := ReloadKeys(""); err != nil {
+		t.Fatalf("Could not reload keys, error %v", err)
+	} else if after, _ := GetMessageKeyInfo(""); after.Fingerprint != before.Fingerprint {
+		t.Errorf("the current key should not change")
+	}
+	if files, _ := filepath.Glob(filepath.Join(keyDir(""), "*.tmp*")); len(files) != 0 {
+		t.Errorf("staged key files should have been removed, found %v", files)
+	}
+	if err := PublishRecoveredKey("", func(publicKey []byte) error {
+		t.Errorf("the key should not be published")
+		return nil
+	}); err != nil {
+		t.Errorf("Could not publish recovered key, error %v", err)
+	}
+}
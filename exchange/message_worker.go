@@ -12,7 +12,6 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/worker"
 	"strconv"
-	"time"
 )
 
 type DeviceMessage struct {
@@ -108,6 +107,16 @@ func (w *ExchangeMessageWorker) NewEvent(incoming events.Message) {
 	}
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *ExchangeMessageWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.NodeShutdownCompleteMessage{},
+		&events.ExchangeChangeMessage{},
+		&events.NodeHeartbeatStateChangeMessage{},
+	}
+}
+
 func (w *ExchangeMessageWorker) Initialize() bool {
 	return true
 }
@@ -148,12 +157,12 @@ func (w *ExchangeMessageWorker) handleMessages() bool {
 
 		glog.V(3).Infof(logString(fmt.Sprintf("reading message %v from the exchange", msg.MsgId)))
 
-		// First get my own keys
-		_, myPrivKey, _ := GetKeys("")
+		// First get my own keys, including the ones retained by a key rotation
+		myPrivKeys, _ := GetDecryptionKeys("")
 
 		// Deconstruct and decrypt the message. If there is a problem with the message, it will be deleted.
 		deleteMessage := true
-		if protocolMessage, receivedPubKey, err := DeconstructExchangeMessage(msg.Message, myPrivKey); err != nil {
+		if protocolMessage, receivedPubKey, err := ReceiveExchangeMessage(msg.Message, myPrivKeys, msg.MsgId); err != nil {
 			glog.Errorf(logString(fmt.Sprintf("unable to deconstruct exchange message %v, error %v", msg, err)))
 		} else if serializedPubKey, err := MarshalPublicKey(receivedPubKey); err != nil {
 			glog.Errorf(logString(fmt.Sprintf("unable to marshal the key from the encrypted message %v, error %v", receivedPubKey, err)))
@@ -185,10 +194,10 @@ func (w *ExchangeMessageWorker) getMessages() ([]DeviceMessage, error) {
 	var resp interface{}
 	resp = new(GetDeviceMessageResponse)
 
-	retryCount := w.GetHTTPFactory().RetryCount
-	retryInterval := w.GetHTTPFactory().GetRetryInterval()
-
 	targetURL := w.GetExchangeURL() + "orgs/" + GetOrg(w.GetExchangeId()) + "/nodes/" + GetId(w.GetExchangeId()) + "/msgs"
+
+	retryCount := w.GetHTTPFactory().RetryCount
+	retryBackoff := NewRetryBackoff(w.GetHTTPFactory(), targetURL)
 	for {
 		if err, tpErr := InvokeExchange(w.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, w.GetExchangeId(), w.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(logString(err.Error()))
@@ -196,13 +205,13 @@ func (w *ExchangeMessageWorker) getMessages() ([]DeviceMessage, error) {
 		} else if tpErr != nil {
 			glog.Warningf(logString(tpErr.Error()))
 			if w.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", w.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -217,10 +226,10 @@ func (w *ExchangeMessageWorker) deleteMessage(msg *DeviceMessage) error {
 	var resp interface{}
 	resp = new(PostDeviceResponse)
 
-	retryCount := w.GetHTTPFactory().RetryCount
-	retryInterval := w.GetHTTPFactory().GetRetryInterval()
-
 	targetURL := w.GetExchangeURL() + "orgs/" + GetOrg(w.GetExchangeId()) + "/nodes/" + GetId(w.GetExchangeId()) + "/msgs/" + strconv.Itoa(msg.MsgId)
+
+	retryCount := w.GetHTTPFactory().RetryCount
+	retryBackoff := NewRetryBackoff(w.GetHTTPFactory(), targetURL)
 	for {
 		if err, tpErr := InvokeExchange(w.GetHTTPFactory().NewHTTPClient(nil), "DELETE", targetURL, w.GetExchangeId(), w.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(logString(err.Error()))
@@ -228,13 +237,13 @@ func (w *ExchangeMessageWorker) deleteMessage(msg *DeviceMessage) error {
 		} else if tpErr != nil {
 			glog.Warningf(logString(tpErr.Error()))
 			if w.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return fmt.Errorf("Exceeded %v retries for error: %v", w.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
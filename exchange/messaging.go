@@ -4,6 +4,7 @@ import (
 	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -16,6 +17,7 @@ import (
 	"path"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/config"
@@ -35,9 +37,15 @@ import (
 type EncryptedWrappedMessage []byte
 type EncryptedSymmetricValues []byte
 
+// The fields of a version 1 envelope are WrappedMessage and SymmetricValues, the other fields are used by later
+// versions (see envelope.go). A version 1 envelope has no version field, so that older receivers can read it.
 type ExchangeMessage struct {
-	WrappedMessage  EncryptedWrappedMessage  `json:"wrappedMessage"`
-	SymmetricValues EncryptedSymmetricValues `json:"symmetricValues"`
+	WrappedMessage  EncryptedWrappedMessage  `json:"wrappedMessage,omitempty"`
+	SymmetricValues EncryptedSymmetricValues `json:"symmetricValues,omitempty"`
+	Version         int                      `json:"version,omitempty"`
+	Header          []byte                   `json:"header,omitempty"`
+	Ciphertext      []byte                   `json:"ciphertext,omitempty"`
+	Signature       []byte                   `json:"signature,omitempty"`
 }
 
 func newExchangeMessage(wMsg EncryptedWrappedMessage, sVal EncryptedSymmetricValues) *ExchangeMessage {
@@ -49,14 +57,26 @@ func newExchangeMessage(wMsg EncryptedWrappedMessage, sVal EncryptedSymmetricVal
 
 func (self ExchangeMessage) String() string {
 	res := ""
-	res += fmt.Sprintf("Wrapped Message: %v\n SymmetricValues: %v\n", self.WrappedMessage, self.SymmetricValues)
+	if self.Version >= ENVELOPE_V2 {
+		res += fmt.Sprintf("Version: %v\n Header: %s\n Ciphertext: %v\n", self.Version, self.Header, self.Ciphertext)
+	} else {
+		res += fmt.Sprintf("Wrapped Message: %v\n SymmetricValues: %v\n", self.WrappedMessage, self.SymmetricValues)
+	}
 	return res
 }
 
+// The optional fields are ignored by older receivers. The timestamp and nonce are used to detect replays, the other
+// fields advertise the sender's support for the version 2 envelope.
 type WrappedMessage struct {
 	Msg          []byte `json:"msg"`
 	Signature    []byte `json:"signature"`
 	SignerPubKey []byte `json:"signerPubkey"`
+	Timestamp    int64  `json:"timestamp,omitempty"`
+	Nonce        []byte `json:"nonce,omitempty"`
+	MaxVersion   int    `json:"maxVersion,omitempty"`
+	X25519Key    []byte `json:"x25519Key,omitempty"`
+	Ed25519Key   []byte `json:"ed25519Key,omitempty"`
+	KeyBinding   []byte `json:"keyBinding,omitempty"`
 }
 
 type SymmetricValues struct {
@@ -84,6 +104,21 @@ func ConstructExchangeMessage(message []byte, senderPublicKey *rsa.PublicKey, se
 		return nil, errors.New(fmt.Sprintf("Private key is not valid"))
 	}
 
+	// Use the version 2 envelope if the receiver has advertised it.
+	maxVersion := messagingConfig.GetMaxEnvelopeVersion()
+	var hk *hybridKeys
+	if maxVersion >= ENVELOPE_V2 {
+		hk = boundHybridKeys(senderPrivateKey)
+	}
+	if peer := getPeer(receiverPublicKey); hk != nil && peer != nil && peer.maxVersion >= ENVELOPE_V2 {
+		if em, err := constructEnvelopeV2(message, senderPublicKey, hk, peer.exchKey); err != nil {
+			return nil, err
+		} else {
+			messagesSent.Inc(fmt.Sprintf("%v", ENVELOPE_V2))
+			return em, nil
+		}
+	}
+
 	// Start constructing the message
 	err := error(nil)
 	glog.V(6).Infof("Creating ExchangeMessage for %s", message)
@@ -119,6 +154,16 @@ func ConstructExchangeMessage(message []byte, senderPublicKey *rsa.PublicKey, se
 		Msg:          message,
 		Signature:    signature,
 		SignerPubKey: pubKey,
+		Timestamp:    time.Now().Unix(),
+	}
+	if wrappedMessage.Nonce, err = newMessageNonce(); err != nil {
+		return nil, err
+	}
+	if hk != nil {
+		wrappedMessage.MaxVersion = ENVELOPE_V2
+		wrappedMessage.X25519Key = hk.exch.PublicKey().Bytes()
+		wrappedMessage.Ed25519Key = hk.sign.Public().(ed25519.PublicKey)
+		wrappedMessage.KeyBinding = hk.binding
 	}
 
 	// 4. symmetrically encrypt the WrappedMessage with a random symmetric key and nonce.
@@ -182,6 +227,7 @@ func ConstructExchangeMessage(message []byte, senderPublicKey *rsa.PublicKey, se
 
 	// 7. construct an ExchangeMessage from the encrypted WrappedMessage and the encrypted SymmetricValues.
 
+	messagesSent.Inc(fmt.Sprintf("%v", ENVELOPE_V1))
 	return newExchangeMessage(encryptedMessage, encryptedSymmetricValues), nil
 
 }
@@ -196,14 +242,34 @@ func ConstructExchangeMessage(message []byte, senderPublicKey *rsa.PublicKey, se
 // 5. extract the plain text message
 
 func DeconstructExchangeMessage(encryptedMessage []byte, receiverPrivateKey *rsa.PrivateKey) ([]byte, *rsa.PublicKey, error) {
+	return DeconstructExchangeMessageWithKeys(encryptedMessage, []*rsa.PrivateKey{receiverPrivateKey})
+}
+
+// Deconstruct a message that could have been encrypted for any of the receiver's private keys, e.g. the current key
+// and the keys retired by a rotation (see GetDecryptionKeys). The keys are tried in order. Messages received from the
+// exchange should be opened with ReceiveExchangeMessage, which also checks for replays.
+func DeconstructExchangeMessageWithKeys(encryptedMessage []byte, receiverPrivateKeys []*rsa.PrivateKey) ([]byte, *rsa.PublicKey, error) {
+	if om, err := openExchangeMessage(encryptedMessage, receiverPrivateKeys); err != nil {
+		return nil, nil, err
+	} else {
+		return om.msg, om.signerKey, nil
+	}
+}
+
+func openExchangeMessage(encryptedMessage []byte, receiverPrivateKeys []*rsa.PrivateKey) (*openedMessage, error) {
 
 	// Up front sanity checks
 	if len(encryptedMessage) == 0 {
-		return nil, nil, errors.New(fmt.Sprintf("Error message has length zero"))
-	} else if receiverPrivateKey == nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error Private key is nil"))
-	} else if err := receiverPrivateKey.Validate(); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error Private key is not valid"))
+		return nil, errors.New(fmt.Sprintf("Error message has length zero"))
+	} else if len(receiverPrivateKeys) == 0 {
+		return nil, errors.New(fmt.Sprintf("Error Private key is nil"))
+	}
+	for _, receiverPrivateKey := range receiverPrivateKeys {
+		if receiverPrivateKey == nil {
+			return nil, errors.New(fmt.Sprintf("Error Private key is nil"))
+		} else if err := receiverPrivateKey.Validate(); err != nil {
+			return nil, errors.New(fmt.Sprintf("Error Private key is not valid"))
+		}
 	}
 
 	err := error(nil)
@@ -214,9 +280,11 @@ func DeconstructExchangeMessage(encryptedMessage []byte, receiverPrivateKey *rsa
 
 	em := new(ExchangeMessage)
 	if err = json.Unmarshal(encryptedMessage, &em); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error unmarshalling exchange message %s, error %v", encryptedMessage, err))
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling exchange message %s, error %v", encryptedMessage, err))
+	} else if em.Version >= ENVELOPE_V2 {
+		return openEnvelopeV2(em)
 	} else if len(em.WrappedMessage) == 0 || len(em.SymmetricValues) == 0 {
-		return nil, nil, errors.New(fmt.Sprintf("Error unmarshalling exchange message, one of wrapped message %v or symmetric values %v has length zero.", em.WrappedMessage, em.SymmetricValues))
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling exchange message, one of wrapped message %v or symmetric values %v has length zero.", em.WrappedMessage, em.SymmetricValues))
 	}
 
 	glog.V(6).Infof("Encrypted Wrapped Message  %x", em.WrappedMessage)
@@ -230,15 +298,20 @@ func DeconstructExchangeMessage(encryptedMessage []byte, receiverPrivateKey *rsa
 	// What's the purpose of the label?
 	label := []byte("")
 	var receivedSymValues []byte
-	if receivedSymValues, err = rsa.DecryptOAEP(sha3.New256(), rand.Reader, receiverPrivateKey, em.SymmetricValues, label); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error decrypting Symmetric values from message, error %v", err))
+	for _, receiverPrivateKey := range receiverPrivateKeys {
+		if receivedSymValues, err = rsa.DecryptOAEP(sha3.New256(), rand.Reader, receiverPrivateKey, em.SymmetricValues, label); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, &KeyMismatchError{Msg: fmt.Sprintf("Error decrypting Symmetric values from message, error %v", err)}
 	}
 
 	sv := new(SymmetricValues)
 	if err = json.Unmarshal(receivedSymValues, &sv); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error unmarshalling symmetric values, error %v", err))
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling symmetric values, error %v", err))
 	} else if len(sv.Key) == 0 || len(sv.Nonce) == 0 {
-		return nil, nil, errors.New(fmt.Sprintf("Error unmarshalling symmetric values, one of key %v or nonce %v has length zero.", sv.Key, sv.Nonce))
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling symmetric values, one of key %v or nonce %v has length zero.", sv.Key, sv.Nonce))
 	}
 
 	// 3. use the symmetric key and nonce to decrypt the WrappedMessage.
@@ -246,16 +319,16 @@ func DeconstructExchangeMessage(encryptedMessage []byte, receiverPrivateKey *rsa
 
 	var receivedDecryptedMessage []byte
 	if receivedDecryptedMessage, err = symmetricallyDecrypt(em.WrappedMessage, sv.Key, sv.Nonce); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error decrypting message: %v", err))
+		return nil, errors.New(fmt.Sprintf("Error decrypting message: %v", err))
 	} else {
 		glog.V(6).Infof("Decrypted Wrapped Message %s", receivedDecryptedMessage)
 	}
 
 	wm := new(WrappedMessage)
 	if err = json.Unmarshal(receivedDecryptedMessage, &wm); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error unmarshalling wrapped message, error %v", err))
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling wrapped message, error %v", err))
 	} else if len(wm.Signature) == 0 || len(wm.SignerPubKey) == 0 {
-		return nil, nil, errors.New(fmt.Sprintf("Error unmarshalling wrapped message, one of signature %v or signer public key %v has length zero.", wm.Signature, wm.SignerPubKey))
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling wrapped message, one of signature %v or signer public key %v has length zero.", wm.Signature, wm.SignerPubKey))
 	} else {
 		glog.V(6).Infof("Decrypted Wrapped Signature  %x", wm.Signature)
 		glog.V(6).Infof("Decrypted Wrapped Public Key %x", wm.SignerPubKey)
@@ -265,9 +338,9 @@ func DeconstructExchangeMessage(encryptedMessage []byte, receiverPrivateKey *rsa
 
 	var receivedPubKey *rsa.PublicKey
 	if receivedPubKey, err = DemarshalPublicKey(wm.SignerPubKey); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error demarshalling sender public key, %v", err))
+		return nil, errors.New(fmt.Sprintf("Error demarshalling sender public key, %v", err))
 	} else if receivedPubKey == nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error demarshalling sender public key, returned public key is nil"))
+		return nil, errors.New(fmt.Sprintf("Error demarshalling sender public key, returned public key is nil"))
 	}
 
 	//Verify Signature
@@ -275,13 +348,30 @@ func DeconstructExchangeMessage(encryptedMessage []byte, receiverPrivateKey *rsa
 	glog.V(6).Infof("Digest %x", receivedDigest)
 
 	if err = rsa.VerifyPSS(receivedPubKey, crypto.SHA3_256, receivedDigest[:], wm.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error verifying signature, error %v", err))
+		return nil, errors.New(fmt.Sprintf("Error verifying signature, error %v", err))
 	} else {
 		glog.V(6).Infof("Signature verification successful")
 	}
 
-	// 5. extract the plain text message
-	return wm.Msg, receivedPubKey, nil
+	// 5. extract the plain text message, and what the sender advertised if it bound its hybrid keys to its RSA key.
+	om := &openedMessage{
+		msg:        wm.Msg,
+		signerKey:  receivedPubKey,
+		version:    ENVELOPE_V1,
+		timestamp:  wm.Timestamp,
+		nonce:      wm.Nonce,
+		maxVersion: ENVELOPE_V1,
+	}
+	if wm.MaxVersion >= ENVELOPE_V2 && len(wm.KeyBinding) != 0 {
+		if err := verifyKeyBinding(receivedPubKey, wm.Ed25519Key, wm.X25519Key, wm.KeyBinding); err != nil {
+			glog.Warningf(fmt.Sprintf("Ignoring the hybrid keys advertised by the sender, the key binding is not valid: %v", err))
+		} else {
+			om.maxVersion = wm.MaxVersion
+			om.exchKey = wm.X25519Key
+			om.signKey = wm.Ed25519Key
+		}
+	}
+	return om, nil
 }
 
 // Helper function that uses the PKI X.509 library to serialize an RSA key.
@@ -398,6 +488,7 @@ func GetKeys(keyPath string) (*rsa.PublicKey, *rsa.PrivateKey, error) {
 	if gPublicKey != nil {
 		return gPublicKey, gPrivateKey, nil
 	}
+	gKeyPath = keyPath
 
 	snap_common := os.Getenv("HZN_VAR_BASE")
 	if len(snap_common) == 0 {
@@ -407,46 +498,18 @@ func GetKeys(keyPath string) (*rsa.PublicKey, *rsa.PrivateKey, error) {
 	privFilepath := filepath.Clean(filepath.Join(snap_common, keyPath, privFileName))
 	pubFilepath := filepath.Clean(filepath.Join(snap_common, keyPath, pubFileName))
 
+	if recoverStagedKeys(filepath.Dir(privFilepath)) {
+		gRecoveredRotation = true
+	}
+
 	if _, ferr := os.Stat(privFilepath); os.IsNotExist(ferr) {
 
 		if privateKey, err := rsa.GenerateKey(rand.Reader, 2048); err != nil {
 			return nil, nil, errors.New(fmt.Sprintf("Could not generate private key, error %v", err))
-		} else if privFile, err := os.Create(privFilepath); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Could not create private key file %v, error %v", privFilepath, err))
-		} else if err := privFile.Chmod(0600); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Could not chmod private key file %v, error %v", privFilepath, err))
-		} else if pubFile, err := os.Create(pubFilepath); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Could not create public key file %v, error %v", pubFilepath, err))
-		} else if err := pubFile.Chmod(0600); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Could not chmod public key file %v, error %v", pubFilepath, err))
+		} else if err := writeKeyFiles(filepath.Dir(privFilepath), privateKey); err != nil {
+			return nil, nil, err
 		} else {
-			publicKey := &privateKey.PublicKey
-
-			if pubKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey); err != nil {
-				return nil, nil, errors.New(fmt.Sprintf("Could not marshal public key, error %v", err))
-			} else {
-				pubEnc := &pem.Block{
-					Type:    "PUBLIC KEY",
-					Headers: nil,
-					Bytes:   pubKeyBytes}
-				if err := pem.Encode(pubFile, pubEnc); err != nil {
-					return nil, nil, errors.New(fmt.Sprintf("Could not encode public key to file, error %v", err))
-				} else if err := pubFile.Close(); err != nil {
-					return nil, nil, errors.New(fmt.Sprintf("Could not close public key file %v, error %v", pubFilepath, err))
-				}
-			}
-
-			privEnc := &pem.Block{
-				Type:    "RSA PRIVATE KEY",
-				Headers: nil,
-				Bytes:   x509.MarshalPKCS1PrivateKey(privateKey)}
-			if err := pem.Encode(privFile, privEnc); err != nil {
-				return nil, nil, errors.New(fmt.Sprintf("Could not encode private key to file, error %v", err))
-			} else if err := privFile.Close(); err != nil {
-				return nil, nil, errors.New(fmt.Sprintf("Could not close private key file %v, error %v", privFilepath, err))
-			}
-
-			gPublicKey = publicKey
+			gPublicKey = &privateKey.PublicKey
 			gPrivateKey = privateKey
 		}
 	} else {
@@ -492,6 +555,17 @@ func DeleteKeys(keyPath string) error {
 
 	glog.V(5).Infof("Removing private key path %v, and public key path %v", cleanedPrivFilepath, cleanedPubFilepath)
 
+	// Delete the keys retained by a rotation
+	KeyLock.Lock()
+	err := deleteRetiredKeys(filepath.Dir(cleanedPrivFilepath))
+	if err == nil {
+		err = deleteHybridKeys(filepath.Dir(cleanedPrivFilepath))
+	}
+	KeyLock.Unlock()
+	if err != nil {
+		return err
+	}
+
 	// Delete both the private and public key files
 	if _, ferr := os.Stat(cleanedPrivFilepath); !os.IsNotExist(ferr) {
 		if err := os.Remove(cleanedPrivFilepath); err != nil {
@@ -0,0 +1,155 @@
+package exchange
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/open-horizon/anax/metrics"
+)
+
+// Metrics recorded for every call to the exchange, shared by the agent and the agbot.
+var exchangeRequestDuration = metrics.NewHistogramVec("anax_exchange_request_duration_seconds",
+	"Latency of exchange API calls, by HTTP method and exchange resource.", metrics.DurationBuckets, "method", "resource")
+
+var exchangeRequestErrors = metrics.NewCounterVec("anax_exchange_request_errors_total",
+	"Exchange API calls that failed, by HTTP method, exchange resource and error type (transport or request).", "method", "resource", "type")
+
+const METRIC_ERROR_TRANSPORT = "transport"
+const METRIC_ERROR_REQUEST = "request"
+
+var cssObjectDownloads = metrics.NewCounterVec("anax_css_object_downloads_total",
+	"Object data downloads from the CSS, by object type and result (success or failure).", "type", "result")
+
+var cssObjectDownloadBytes = metrics.NewCounterVec("anax_css_object_download_bytes_total",
+	"Bytes of object data downloaded from the CSS, by object type.", "type")
+
+const METRIC_DOWNLOAD_SUCCEEDED = "success"
+const METRIC_DOWNLOAD_FAILED = "failure"
+
+var messagesSent = metrics.NewCounterVec("anax_exchange_messages_sent_total",
+	"Messages constructed for another agent or agbot, by envelope version.", "version")
+
+var messagesReceived = metrics.NewCounterVec("anax_exchange_messages_received_total",
+	"Messages received from another agent or agbot, by envelope version.", "version")
+
+var messagesRejected = metrics.NewCounterVec("anax_exchange_messages_rejected_total",
+	"Messages received from another agent or agbot that were rejected, by reason (invalid, expired, future, replay, missing_timestamp).", "reason")
+
+var exchangeThrottled = metrics.NewCounterVec("anax_exchange_throttled_total",
+	"Exchange API calls delayed by the client rate limit, by exchange resource.", "resource")
+
+var exchangeThrottleWait = metrics.NewCounterVec("anax_exchange_throttle_wait_seconds_total",
+	"Time exchange API calls spent waiting for the client rate limit, by exchange resource.", "resource")
+
+var exchangeCircuitState = metrics.NewGaugeVec("anax_exchange_circuit_state",
+	"State of the circuit to each exchange host, 0 is closed, 1 is open and 2 is half open.", "host")
+
+var exchangeCircuitRejected = metrics.NewCounterVec("anax_exchange_circuit_rejected_total",
+	"Exchange API calls that were not made because the circuit to the exchange host was open, by exchange resource.", "resource")
+
+var exchangeRetries = metrics.NewCounterVec("anax_exchange_retries_total",
+	"Retries of exchange API calls that failed with a transport error, by exchange resource.", "resource")
+
+var exchangeRetryBudgetExhausted = metrics.NewCounterVec("anax_exchange_retry_budget_exhausted_total",
+	"Retries of exchange API calls that waited for the longest backoff because the retry budget was used up, by exchange resource.", "resource")
+
+var exchangeConditionalRequests = metrics.NewCounterVec("anax_exchange_conditional_requests_total",
+	"Conditional GETs of cached exchange resources, by exchange resource and result (not_modified or modified).", "resource", "result")
+
+const METRIC_NOT_MODIFIED = "not_modified"
+const METRIC_MODIFIED = "modified"
+
+var exchangeOffline = metrics.NewGaugeVec("anax_exchange_offline",
+	"1 while the node is offline and its writes to the exchange are queued, 0 otherwise.")
+
+var exchangeOutboundQueued = metrics.NewGaugeVec("anax_exchange_outbound_queue_length",
+	"Writes to the exchange waiting to be replayed when the node reconnects.")
+
+var exchangeQueuedWrites = metrics.NewCounterVec("anax_exchange_queued_writes_total",
+	"Writes to the exchange that were queued because the node was offline, by exchange resource.", "resource")
+
+var exchangeReplayedWrites = metrics.NewCounterVec("anax_exchange_replayed_writes_total",
+	"Queued writes replayed to the exchange, by exchange resource and result (sent or dropped).", "resource", "result")
+
+const METRIC_REPLAY_SENT = "sent"
+const METRIC_REPLAY_DROPPED = "dropped"
+
+// Record the outcome of an exchange invocation.
+func observeExchangeCall(method string, urlPath string, start time.Time, err error, tpErr error) {
+	resource := ExchangeResourceClass(urlPath)
+	exchangeRequestDuration.Observe(time.Since(start).Seconds(), method, resource)
+	if tpErr != nil {
+		exchangeRequestErrors.Inc(method, resource, METRIC_ERROR_TRANSPORT)
+	} else if err != nil {
+		exchangeRequestErrors.Inc(method, resource, METRIC_ERROR_REQUEST)
+	}
+}
+
+// Reduce an exchange URL to a low cardinality resource class that can be used as a metric label. The org and resource
+// ids are removed, leaving only the resource type names, e.g. .../orgs/myorg/nodes/mynode/agreements/123 becomes
+// nodes/agreements. URLs that are not org scoped keep their first path element, e.g. admin/version becomes admin.
+func ExchangeResourceClass(urlPath string) string {
+	path := urlPath
+	if u, err := url.Parse(urlPath); err == nil {
+		path = u.Path
+	}
+
+	parts := make([]string, 0)
+	for _, p := range strings.Split(path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+
+	for ix, p := range parts {
+		if p == "orgs" {
+			// The element after orgs is the org id, then resource types and ids alternate.
+			// A few resource types are made of 2 path elements (e.g. business/policies).
+			classes := make([]string, 0)
+			for jx := ix + 2; jx < len(parts); jx += 2 {
+				class := parts[jx]
+				if (class == "search" || class == "business") && jx+1 < len(parts) {
+					jx += 1
+					class += "/" + parts[jx]
+				}
+				classes = append(classes, class)
+			}
+			if len(classes) == 0 {
+				return "orgs"
+			}
+			return strings.Join(classes, "/")
+		}
+	}
+
+	// Skip the exchange API version prefix, if present.
+	for _, p := range parts {
+		if p != "v1" && p != "api" && p != "edge-exchange" {
+			return p
+		}
+	}
+	return "unknown"
+}
+
+// Record the outcome of an object data download from the CSS. Bytes downloaded by a failed download are counted too.
+func observeObjectDownload(objType string, bytes int64, err error) {
+	cssObjectDownloadBytes.Add(float64(bytes), objType)
+	if err != nil {
+		cssObjectDownloads.Inc(objType, METRIC_DOWNLOAD_FAILED)
+	} else {
+		cssObjectDownloads.Inc(objType, METRIC_DOWNLOAD_SUCCEEDED)
+	}
+}
+
+// An io.Reader that counts the bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,63 @@
+//go:build unit
+// +build unit
+
+package exchange
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ExchangeResourceClass(t *testing.T) {
+	tests := map[string]string{
+		"https://exch.com/edge-exchange/v1/orgs/myorg/nodes/node1/agreements/123": "nodes/agreements",
+		"https://exch.com/v1/orgs/myorg/nodes/node1":                              "nodes",
+		"https://exch.com/v1/orgs/myorg/business/policies/pol1":                   "business/policies",
+		"https://exch.com/v1/orgs/myorg/search/nodes/service":                     "search/nodes",
+		"https://exch.com/v1/orgs/myorg/agbots/ag1/msgs?maxmsgs=10":               "agbots/msgs",
+		"https://exch.com/v1/orgs/myorg/changes":                                  "changes",
+		"https://exch.com/v1/orgs/myorg":                                          "orgs",
+		"https://exch.com/v1/admin/version":                                       "admin",
+		"https://css.com/api/v1/objects/myorg/type/id":                            "objects",
+		"": "unknown",
+	}
+
+	for url, expected := range tests {
+		if class := ExchangeResourceClass(url); class != expected {
+			t.Errorf("for %v expected %v but got %v", url, expected, class)
+		}
+	}
+}
+
+func Test_ObserveExchangeCall(t *testing.T) {
+	url := "https://exch.com/v1/orgs/myorg/patterns/p1"
+	before := exchangeRequestErrors.Get("GET", "patterns", METRIC_ERROR_TRANSPORT)
+	count, _ := exchangeRequestDuration.Get("GET", "patterns")
+
+	observeExchangeCall("GET", url, time.Now(), nil, errors.New("connection refused"))
+	observeExchangeCall("GET", url, time.Now(), nil, nil)
+
+	if v := exchangeRequestErrors.Get("GET", "patterns", METRIC_ERROR_TRANSPORT); v != before+1 {
+		t.Errorf("expected %v transport errors, got %v", before+1, v)
+	} else if c, _ := exchangeRequestDuration.Get("GET", "patterns"); c != count+2 {
+		t.Errorf("expected %v observations, got %v", count+2, c)
+	}
+}
+
+func Test_ObserveObjectDownload(t *testing.T) {
+	before := cssObjectDownloadBytes.Get("model")
+	reader := &countingReader{reader: strings.NewReader("some object data")}
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	observeObjectDownload("model", reader.count, nil)
+	if v := cssObjectDownloadBytes.Get("model"); v != before+16 {
+		t.Errorf("expected %v bytes, got %v", before+16, v)
+	} else if v := cssObjectDownloads.Get("model", METRIC_DOWNLOAD_SUCCEEDED); v == 0 {
+		t.Errorf("expected the download to be counted")
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"strings"
-	"time"
 )
 
 // Structs and types for interacting with the device (node) object in the exchange
@@ -145,7 +144,7 @@ func GetExchangeDevice(httpClientFactory *config.HTTPClientFactory, deviceId str
 	}
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := NewRetryBackoff(httpClientFactory, targetURL)
 	for {
 		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "GET", targetURL, credId, credPasswd, nil, &resp); err != nil {
 			glog.Errorf(err.Error())
@@ -153,13 +152,13 @@ func GetExchangeDevice(httpClientFactory *config.HTTPClientFactory, deviceId str
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -260,20 +259,20 @@ func PutExchangeDevice(httpClientFactory *config.HTTPClientFactory, deviceId str
 	cachedNode := DeleteCacheNodeWriteThru(GetOrg(deviceId), GetId(deviceId))
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := NewRetryBackoff(httpClientFactory, targetURL)
 	for {
 		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "PUT", targetURL, deviceId, deviceToken, pdr, &resp); err != nil {
 			return nil, err
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -366,20 +365,20 @@ func PatchExchangeDevice(httpClientFactory *config.HTTPClientFactory, deviceId s
 	cachedNode := DeleteCacheNodeWriteThru(GetOrg(deviceId), GetId(deviceId))
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := NewRetryBackoff(httpClientFactory, targetURL)
 	for {
 		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "PATCH", targetURL, deviceId, deviceToken, pdr, &resp); err != nil {
 			return err
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -403,6 +402,31 @@ func CreatePatchDeviceKey() *PatchAgbotPublicKey {
 	return pdr
 }
 
+// Publish a new messaging public key in the node's exchange resource.
+func PatchNodeMessageKey(ec ExchangeContext, publicKey []byte) error {
+	pdr := &PatchAgbotPublicKey{
+		PublicKey: publicKey,
+	}
+
+	var resp interface{}
+	resp = new(PutDeviceResponse)
+	targetURL := ec.GetExchangeURL() + "orgs/" + GetOrg(ec.GetExchangeId()) + "/nodes/" + GetId(ec.GetExchangeId())
+
+	if err := InvokeExchangeRetryOnTransportError(ec.GetHTTPFactory(), "PATCH", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), pdr, &resp); err != nil {
+		return err
+	}
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("patched messaging key for node %v: %v", ec.GetExchangeId(), pdr.ShortString())))
+	DeleteCacheNodeWriteThru(GetOrg(ec.GetExchangeId()), GetId(ec.GetExchangeId()))
+	return nil
+}
+
+// Replace the node's messaging keys and publish the new public key in the exchange.
+func RotateNodeMessageKey(ec ExchangeContext, gracePeriodS int) error {
+	return RotateKeys("", gracePeriodS, func(publicKey []byte) error {
+		return PatchNodeMessageKey(ec, publicKey)
+	})
+}
+
 // This function will cause the messaging key to be created if it doesnt already exist.
 func keyBytes() []byte {
 	if pubKey, _, err := GetKeys(""); err != nil {
@@ -497,7 +521,7 @@ func GetNodeStatus(ec ExchangeContext, deviceId string) (*NodeStatus, error) {
 	targetURL := fmt.Sprintf("%vorgs/%v/nodes/%v/status", ec.GetExchangeURL(), GetOrg(deviceId), GetId(deviceId))
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -505,13 +529,13 @@ func GetNodeStatus(ec ExchangeContext, deviceId string) (*NodeStatus, error) {
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -535,7 +559,7 @@ func GetNodeFullStatus(ec ExchangeContext, deviceId string) (*DeviceStatus, erro
 	targetURL := fmt.Sprintf("%vorgs/%v/nodes/%v/status", ec.GetExchangeURL(), GetOrg(deviceId), GetId(deviceId))
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -543,13 +567,13 @@ func GetNodeFullStatus(ec ExchangeContext, deviceId string) (*DeviceStatus, erro
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -618,7 +642,7 @@ func GetSurfaceErrors(ec ExchangeContext, deviceId string) (*ExchangeSurfaceErro
 	targetURL := fmt.Sprintf("%vorgs/%v/nodes/%v/errors", ec.GetExchangeURL(), GetOrg(deviceId), GetId(deviceId))
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -626,13 +650,13 @@ func GetSurfaceErrors(ec ExchangeContext, deviceId string) (*ExchangeSurfaceErro
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -653,20 +677,22 @@ func PutSurfaceErrors(ec ExchangeContext, deviceId string, errorList *ExchangeSu
 	targetURL := fmt.Sprintf("%vorgs/%v/nodes/%v/errors", ec.GetExchangeURL(), GetOrg(deviceId), GetId(deviceId))
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 	for {
-		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "PUT", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), errorList, &resp); err != nil {
+		if QueueExchangeWrite("PUT", targetURL, "", errorList) {
+			return resp.(*PutDeviceResponse), nil
+		} else if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "PUT", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), errorList, &resp); err != nil {
 			return nil, err
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -683,7 +709,7 @@ func DeleteSurfaceErrors(ec ExchangeContext, deviceId string) error {
 	targetURL := fmt.Sprintf("%vorgs/%v/nodes/%v/errors", ec.GetExchangeURL(), GetOrg(deviceId), GetId(deviceId))
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "DELETE", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil && !strings.Contains(err.Error(), "status: 404") {
@@ -691,13 +717,13 @@ func DeleteSurfaceErrors(ec ExchangeContext, deviceId string) error {
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -53,6 +53,10 @@ func PutNodeManagementPolicyStatus(ec ExchangeContext, orgId string, nodeId stri
 	// set the working directory to an empty string as this is not in the exchange schema
 	nmpStatus.AgentUpgrade.BaseWorkingDirectory = ""
 
+	if QueueExchangeWrite("PUT", targetURL, "", nmpStatus) {
+		return resp.(*PutPostDeleteStandardResponse), nil
+	}
+
 	err := InvokeExchangeRetryOnTransportError(ec.GetHTTPFactory(), "PUT", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nmpStatus, &resp)
 	if err != nil {
 		return nil, err
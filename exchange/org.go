@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/config"
-	"time"
 )
 
 type UserDefinition struct {
@@ -69,7 +68,7 @@ func GetOrganization(httpClientFactory *config.HTTPClientFactory, org string, ex
 	targetURL := fmt.Sprintf("%vorgs/%v", exURL, org)
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := NewRetryBackoff(httpClientFactory, targetURL)
 	for {
 		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "GET", targetURL, id, token, nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -77,13 +76,13 @@ func GetOrganization(httpClientFactory *config.HTTPClientFactory, org string, ex
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -0,0 +1,184 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// An agent can lose its connection to the exchange for days. While it is offline, the writes that report the node's
+// state to the exchange, i.e. the node status, surfaced errors, service configuration states, node management status
+// and agreement states, are queued in the agent's database instead of being retried by each worker. A write to a
+// resource replaces the queued write to the same resource, so only the latest state is reported. When the node
+// reconnects, the queued writes are replayed in the order they were made.
+//
+// The node goes offline when its heartbeat has failed for longer than the heartbeat grace period, and back online once
+// the heartbeat succeeds and the queue has been replayed. While the queue is not empty new writes are queued too, so
+// that the exchange sees the writes in order.
+
+type outboundQueue struct {
+	db           *bolt.DB
+	replayLock   sync.Mutex   // Only one replay at a time.
+	offlineSince atomic.Int64 // When the node went offline, 0 when it is online.
+	queued       atomic.Int64 // The number of queued writes.
+}
+
+// The queue is only turned on by the agent.
+var exchangeOutboundQueue *outboundQueue
+
+// Turn on the outbound queue, the writes queued before the last restart are kept.
+func ConfigureOutboundQueue(db *bolt.DB) {
+	q := &outboundQueue{db: db}
+	if writes, err := persistence.FindQueuedExchangeWrites(db); err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("unable to load queued exchange writes, error: %v", err)))
+	} else {
+		q.queued.Store(int64(len(writes)))
+	}
+	exchangeOutboundQueued.Set(float64(q.queued.Load()))
+	exchangeOutboundQueue = q
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("exchange outbound queue loaded with %v writes", q.queued.Load())))
+}
+
+// Enter or leave offline mode.
+func SetOffline(offline bool) {
+	q := exchangeOutboundQueue
+	if q == nil {
+		return
+	} else if offline && q.offlineSince.CompareAndSwap(0, time.Now().Unix()) {
+		glog.Warningf(rpclogString(fmt.Sprintf("node is offline, writes to the exchange will be queued")))
+		exchangeOffline.Set(1)
+	} else if !offline && q.offlineSince.Swap(0) != 0 {
+		glog.Infof(rpclogString(fmt.Sprintf("node is online")))
+		exchangeOffline.Set(0)
+	}
+}
+
+// Returns true when the node is offline.
+func IsOffline() bool {
+	return GetOfflineSince() != 0
+}
+
+// Returns when the node went offline, or 0 if it is online.
+func GetOfflineSince() int64 {
+	if q := exchangeOutboundQueue; q != nil {
+		return q.offlineSince.Load()
+	}
+	return 0
+}
+
+// Returns the number of writes waiting to be replayed.
+func GetQueuedExchangeWriteCount() int {
+	if q := exchangeOutboundQueue; q != nil {
+		return int(q.queued.Load())
+	}
+	return 0
+}
+
+// Queue a write to the exchange when the node is offline, or when earlier writes are still queued. Returns true if the
+// write was queued, in which case the caller must not make the write itself. Writes to the same key replace each other,
+// the key defaults to the URL of the resource.
+func QueueExchangeWrite(method string, url string, key string, body interface{}) bool {
+	q := exchangeOutboundQueue
+	if q == nil || (q.offlineSince.Load() == 0 && q.queued.Load() == 0) {
+		return false
+	}
+
+	if key == "" {
+		key = strings.SplitN(url, "?", 2)[0]
+	}
+
+	var serial json.RawMessage
+	if body != nil {
+		if b, err := json.Marshal(body); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf("unable to serialize exchange write %v %v, error: %v", method, url, err)))
+			return false
+		} else {
+			serial = b
+		}
+	}
+
+	if replaced, err := persistence.QueueExchangeWrite(q.db, key, method, url, serial); err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("unable to queue exchange write %v %v, error: %v", method, url, err)))
+		return false
+	} else if !replaced {
+		exchangeOutboundQueued.Set(float64(q.queued.Add(1)))
+	}
+
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("queued exchange write %v %v", method, url)))
+	exchangeQueuedWrites.Inc(ExchangeResourceClass(url))
+	return true
+}
+
+// Send the queued writes to the exchange, in the order they were queued. A write that the exchange rejects is dropped,
+// because sending it again would not change the outcome. Replay stops at the first transport error, the remaining
+// writes stay queued.
+func ReplayExchangeWrites(httpClientFactory *config.HTTPClientFactory, user string, pw string) error {
+	q := exchangeOutboundQueue
+	if q == nil {
+		return nil
+	}
+
+	q.replayLock.Lock()
+	defer q.replayLock.Unlock()
+
+	// Writes can be queued while the queue is replayed, keep going until it is empty.
+	for {
+		writes, err := persistence.FindQueuedExchangeWrites(q.db)
+		if err != nil {
+			return fmt.Errorf("unable to read queued exchange writes, error: %v", err)
+		}
+		q.queued.Store(int64(len(writes)))
+		exchangeOutboundQueued.Set(float64(len(writes)))
+		if len(writes) == 0 {
+			return nil
+		}
+
+		glog.V(3).Infof(rpclogString(fmt.Sprintf("replaying %v queued exchange writes", len(writes))))
+		for _, w := range writes {
+			var params interface{}
+			if len(w.Body) != 0 {
+				params = w.Body
+			}
+
+			var resp interface{}
+			resp = ""
+			if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), w.Method, w.URL, user, pw, params, &resp); tpErr != nil {
+				return fmt.Errorf("replay of queued exchange write %v stopped, error: %v", w, tpErr)
+			} else if err != nil && !(w.Method == "DELETE" && strings.Contains(err.Error(), "status: 404")) {
+				glog.Warningf(rpclogString(fmt.Sprintf("dropping queued exchange write %v, error: %v", w, err)))
+				exchangeReplayedWrites.Inc(ExchangeResourceClass(w.URL), METRIC_REPLAY_DROPPED)
+			} else {
+				glog.V(5).Infof(rpclogString(fmt.Sprintf("replayed queued exchange write %v", w)))
+				exchangeReplayedWrites.Inc(ExchangeResourceClass(w.URL), METRIC_REPLAY_SENT)
+			}
+
+			if err := persistence.DeleteQueuedExchangeWrite(q.db, w.Seq); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Remove all the queued writes, e.g. when the node is unregistered.
+func ClearExchangeWrites() {
+	q := exchangeOutboundQueue
+	if q == nil {
+		return
+	}
+
+	q.replayLock.Lock()
+	defer q.replayLock.Unlock()
+	if err := persistence.DeleteQueuedExchangeWrites(q.db); err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("unable to delete queued exchange writes, error: %v", err)))
+	}
+	q.queued.Store(0)
+	exchangeOutboundQueued.Set(0)
+}
@@ -0,0 +1,142 @@
+//go:build unit
+// +build unit
+
+package exchange
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+)
+
+// A fake exchange that records the writes it receives and rejects writes to a missing agreement.
+type writeRecorder struct {
+	lock   sync.Mutex
+	writes []string
+}
+
+func (rec *writeRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rec.lock.Lock()
+	rec.writes = append(rec.writes, r.Method+" "+r.URL.Path+" "+string(body))
+	rec.lock.Unlock()
+
+	if r.URL.Path == "/v1/orgs/myorg/nodes/node1/agreements/missing" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"code":"ok","msg":""}`))
+}
+
+func Test_OutboundQueue(t *testing.T) {
+	dir := t.TempDir()
+	db, err := bolt.Open(path.Join(dir, "anax.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Could not open database, error %v", err)
+	}
+	defer db.Close()
+	t.Cleanup(func() { exchangeOutboundQueue = nil })
+
+	rec := &writeRecorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	url := server.URL + "/v1/orgs/myorg/nodes/node1"
+
+	// Nothing is queued while the node is online.
+	ConfigureOutboundQueue(db)
+	if QueueExchangeWrite("PUT", url+"/status", "", map[string]string{"state": "a"}) {
+		t.Errorf("write should not be queued while the node is online")
+	}
+
+	SetOffline(true)
+	if !IsOffline() || GetOfflineSince() == 0 {
+		t.Errorf("node should be offline")
+	}
+	QueueExchangeWrite("PUT", url+"/status", "", map[string]string{"state": "a"})
+	QueueExchangeWrite("PUT", url+"/agreements/ag1?noheartbeat=true", "", map[string]string{"state": "running"})
+	QueueExchangeWrite("PUT", url+"/agreements/missing?noheartbeat=true", "", map[string]string{"state": "running"})
+	QueueExchangeWrite("PUT", url+"/errors", "", map[string]string{"errors": "none"})
+
+	// Later writes replace the queued writes to the same resource and move to the end of the queue.
+	QueueExchangeWrite("PUT", url+"/status", "", map[string]string{"state": "b"})
+	QueueExchangeWrite("DELETE", url+"/agreements/ag1", "", nil)
+	if count := GetQueuedExchangeWriteCount(); count != 4 {
+		t.Errorf("expecting 4 queued writes, have %v", count)
+	}
+
+	// The queue survives a restart, new writes are queued until the queue is replayed.
+	ConfigureOutboundQueue(db)
+	if count := GetQueuedExchangeWriteCount(); count != 4 {
+		t.Errorf("expecting 4 queued writes after a restart, have %v", count)
+	} else if !QueueExchangeWrite("PUT", url+"/services_configstate", "", map[string]string{"state": "suspended"}) {
+		t.Errorf("writes should be queued while earlier writes are queued")
+	}
+
+	// The writes are replayed in order, the write that the exchange rejects is dropped.
+	factory := &config.HTTPClientFactory{NewHTTPClient: func(*uint) *http.Client { return http.DefaultClient }}
+	if err := ReplayExchangeWrites(factory, "myorg/node1", "token"); err != nil {
+		t.Errorf("unexpected replay error %v", err)
+	} else if count := GetQueuedExchangeWriteCount(); count != 0 {
+		t.Errorf("expecting no queued writes, have %v", count)
+	}
+
+	expected := []string{
+		"PUT /v1/orgs/myorg/nodes/node1/agreements/missing {\"state\":\"running\"}",
+		"PUT /v1/orgs/myorg/nodes/node1/errors {\"errors\":\"none\"}",
+		"PUT /v1/orgs/myorg/nodes/node1/status {\"state\":\"b\"}",
+		"DELETE /v1/orgs/myorg/nodes/node1/agreements/ag1 ",
+		"PUT /v1/orgs/myorg/nodes/node1/services_configstate {\"state\":\"suspended\"}",
+	}
+	if len(rec.writes) != len(expected) {
+		t.Fatalf("expecting writes %v, received %v", expected, rec.writes)
+	}
+	for ix, w := range expected {
+		if rec.writes[ix] != w {
+			t.Errorf("expecting write %v to be %v, was %v", ix, w, rec.writes[ix])
+		}
+	}
+
+	SetOffline(false)
+	if IsOffline() || QueueExchangeWrite("PUT", url+"/status", "", map[string]string{"state": "c"}) {
+		t.Errorf("node should be online")
+	}
+}
+
+func Test_OutboundQueue_transportError(t *testing.T) {
+	db, err := bolt.Open(path.Join(t.TempDir(), "anax.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Could not open database, error %v", err)
+	}
+	defer db.Close()
+	t.Cleanup(func() { exchangeOutboundQueue = nil })
+
+	server := httptest.NewServer(&writeRecorder{})
+	url := server.URL + "/v1/orgs/myorg/nodes/node1"
+	server.Close()
+
+	ConfigureOutboundQueue(db)
+	SetOffline(true)
+	QueueExchangeWrite("PUT", url+"/status", "", map[string]string{"state": "a"})
+	QueueExchangeWrite("PUT", url+"/errors", "", map[string]string{"errors": "none"})
+
+	// A transport error stops the replay and keeps the writes.
+	factory := &config.HTTPClientFactory{NewHTTPClient: func(*uint) *http.Client { return http.DefaultClient }}
+	if err := ReplayExchangeWrites(factory, "myorg/node1", "token"); err == nil {
+		t.Errorf("expecting a replay error")
+	} else if count := GetQueuedExchangeWriteCount(); count != 2 {
+		t.Errorf("expecting 2 queued writes, have %v", count)
+	}
+
+	ClearExchangeWrites()
+	if count := GetQueuedExchangeWriteCount(); count != 0 {
+		t.Errorf("expecting no queued writes, have %v", count)
+	}
+}
@@ -0,0 +1,182 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// A search can match tens of thousands of nodes. Rather than reading such a response into memory and then decoding it,
+// the response is decoded as it is read, and the nodes are handed to the caller a page at a time. The caller can
+// finish with each page before the next one is read, so the memory used depends on the page size, not on the number
+// of nodes found.
+
+// How long a streamed call can wait for the exchange to send more of the response. The time the caller spends
+// handling the pages is not counted, which is why the timeout of the HTTP client, which covers the whole call, is not
+// used for these calls.
+const streamIdleTimeoutS = 60
+
+// The number of nodes in a page when the caller does not set one.
+const defaultSearchPageSize = 300
+
+// Handles a page of search results. Returning an error ends the search.
+type SearchResultPageHandler func(page []SearchResultDevice) error
+
+// Invoke the exchange and decode the response as it is read. A 404 means there are no results and the decode function
+// is not called. The first return value is a hard error, the second is a transport error that can be retried.
+func invokeExchangeStream(httpClient *http.Client, method string, urlPath string, user string, pw string, params interface{}, decode func(io.Reader) error) (error, error) {
+	return guardExchangeCall(httpClient, urlPath, func(httpClient *http.Client) (error, error) {
+		start := time.Now()
+		err, tpErr := streamExchange(httpClient, method, urlPath, user, pw, params, decode)
+		observeExchangeCall(method, urlPath, start, err, tpErr)
+		return err, tpErr
+	})
+}
+
+func streamExchange(httpClient *http.Client, method string, urlPath string, user string, pw string, params interface{}, decode func(io.Reader) error) (error, error) {
+	requestBody := bytes.NewBuffer(nil)
+	if params != nil {
+		if jsonBytes, err := json.Marshal(params); err != nil {
+			return errors.New(fmt.Sprintf("Invocation of %v at %v with %v failed marshalling to json, error: %v", method, urlPath, params, err)), nil
+		} else {
+			requestBody = bytes.NewBuffer(jsonBytes)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, urlPath, requestBody)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invocation of %v at %v failed creating HTTP request, error: %v", method, urlPath, err)), nil
+	}
+	req.Header.Add("Accept", "application/json")
+	if method != http.MethodGet {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	addExchangeAuth(req, user, pw)
+
+	if glog.V(5) {
+		glog.Infof(rpclogString(fmt.Sprintf("Invoking exchange %v at %v with %v, streaming the response", method, urlPath, params)))
+	}
+
+	client := *httpClient
+	client.Timeout = 0
+	timeout := streamIdleTimeoutS * time.Second
+	timer := time.AfterFunc(timeout, cancel)
+	httpResp, err := client.Do(req)
+	timedOut := !timer.Stop()
+	if httpResp != nil && httpResp.Body != nil {
+		defer httpResp.Body.Close()
+	}
+
+	if timedOut || IsTransportError(httpResp, err) {
+		status := ""
+		if httpResp != nil {
+			status = httpResp.Status
+		}
+		return nil, errors.New(fmt.Sprintf("Invocation of %v at %v failed invoking HTTP request, error: %v, HTTP Status: %v", method, urlPath, err, status))
+	} else if err != nil {
+		return errors.New(fmt.Sprintf("Invocation of %v at %v failed invoking HTTP request, error: %v", method, urlPath, err)), nil
+	} else if httpResp.StatusCode == http.StatusNotFound {
+		if glog.V(5) {
+			glog.Infof(rpclogString(fmt.Sprintf("Got %v. Response to %v at %v has no results", httpResp.StatusCode, method, urlPath)))
+		}
+		return nil, nil
+	} else if httpResp.StatusCode != http.StatusOK && httpResp.StatusCode != http.StatusCreated {
+		out, _ := io.ReadAll(io.LimitReader(httpResp.Body, 4096))
+		return errors.New(fmt.Sprintf("Invocation of %v at %v failed invoking HTTP request, status: %v, response: %v", method, urlPath, httpResp.StatusCode, string(out))), nil
+	}
+
+	if err := decode(&idleTimeoutReader{r: httpResp.Body, timeout: timeout, cancel: cancel}); err != nil {
+		return errors.New(fmt.Sprintf("Invocation of %v at %v failed handling the response, error: %v", method, urlPath, err)), nil
+	}
+	return nil, nil
+}
+
+// A reader that cancels the request when a read waits too long for the exchange.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+func (i *idleTimeoutReader) Read(p []byte) (int, error) {
+	timer := time.AfterFunc(i.timeout, i.cancel)
+	n, err := i.r.Read(p)
+	if !timer.Stop() && err != nil {
+		err = fmt.Errorf("no data received for %v, error: %v", i.timeout, err)
+	}
+	return n, err
+}
+
+// Decode a search response, {"nodes":[...], ...}, handing the nodes to the handler a page at a time. Returns the
+// number of nodes decoded.
+func decodeNodePages(r io.Reader, pageSize int, handler SearchResultPageHandler) (int, error) {
+	if pageSize <= 0 {
+		pageSize = defaultSearchPageSize
+	}
+
+	dec := json.NewDecoder(r)
+	if t, err := dec.Token(); err != nil {
+		return 0, err
+	} else if t != json.Delim('{') {
+		return 0, fmt.Errorf("expected a JSON object, found %v", t)
+	}
+
+	count := 0
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return count, err
+		} else if key != "nodes" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return count, err
+			}
+			continue
+		}
+
+		if t, err := dec.Token(); err != nil {
+			return count, err
+		} else if t == nil {
+			continue
+		} else if t != json.Delim('[') {
+			return count, fmt.Errorf("expected a list of nodes, found %v", t)
+		}
+
+		page := make([]SearchResultDevice, 0, pageSize)
+		for dec.More() {
+			var dev SearchResultDevice
+			if err := dec.Decode(&dev); err != nil {
+				return count, err
+			}
+			page = append(page, dev)
+			count++
+			if len(page) == pageSize {
+				if err := handler(page); err != nil {
+					return count, err
+				}
+				page = make([]SearchResultDevice, 0, pageSize)
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return count, err
+		}
+		if len(page) != 0 {
+			if err := handler(page); err != nil {
+				return count, err
+			}
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return count, err
+	}
+	return count, nil
+}
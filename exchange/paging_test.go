@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/open-horizon/anax/config"
+)
+
+func Test_decodeNodePages(t *testing.T) {
+	body := `{"nodes":[{"id":"myorg/n1"},{"id":"myorg/n2"},{"id":"myorg/n3"},{"id":"myorg/n4"},{"id":"myorg/n5"}],"lastIndex":0}`
+
+	pages := make([]int, 0)
+	if count, err := decodeNodePages(strings.NewReader(body), 2, func(page []SearchResultDevice) error {
+		pages = append(pages, len(page))
+		return nil
+	}); err != nil || count != 5 {
+		t.Errorf("expecting 5 nodes, have %v, error %v", count, err)
+	} else if fmt.Sprint(pages) != "[2 2 1]" {
+		t.Errorf("expecting pages of 2, 2 and 1 nodes, have %v", pages)
+	}
+
+	// An error from the handler ends the search.
+	calls := 0
+	if _, err := decodeNodePages(strings.NewReader(body), 2, func(page []SearchResultDevice) error {
+		calls++
+		return errors.New("stop")
+	}); err == nil || calls != 1 {
+		t.Errorf("expecting the first page to end the search, have %v calls, error %v", calls, err)
+	}
+
+	// A response without nodes has no pages.
+	if count, err := decodeNodePages(strings.NewReader(`{"nodes":null}`), 2, func(page []SearchResultDevice) error {
+		t.Errorf("expecting no pages, have %v", page)
+		return nil
+	}); err != nil || count != 0 {
+		t.Errorf("expecting no nodes, have %v, error %v", count, err)
+	}
+}
+
+func Test_SearchPatternNodes(t *testing.T) {
+	status := http.StatusCreated
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SearchExchangePatternRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/orgs/myorg/patterns/p1/search" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(status)
+		devs := make([]SearchResultDevice, 0)
+		for ix := 0; ix < 7; ix++ {
+			devs = append(devs, SearchResultDevice{Id: fmt.Sprintf("myorg/n%v", ix), PublicKey: "key"})
+		}
+		json.NewEncoder(w).Encode(SearchExchangePatternResponse{Devices: devs})
+	}))
+	defer server.Close()
+
+	factory := &config.HTTPClientFactory{NewHTTPClient: func(*uint) *http.Client { return http.DefaultClient }}
+	ec := NewCustomExchangeContext("myorg/ag1", "token", server.URL+"/", "", factory)
+
+	ids := make([]string, 0)
+	if count, err := SearchPatternNodes(ec, "myorg", "myorg/p1", CreateSearchPatternRequest(), 3, func(page []SearchResultDevice) error {
+		if len(page) > 3 {
+			t.Errorf("expecting pages of at most 3 nodes, have %v", len(page))
+		}
+		for _, dev := range page {
+			ids = append(ids, dev.Id)
+		}
+		return nil
+	}); err != nil || count != 7 || len(ids) != 7 || ids[6] != "myorg/n6" {
+		t.Errorf("expecting 7 nodes, have %v %v, error %v", count, ids, err)
+	}
+
+	// No nodes match the pattern.
+	status = http.StatusNotFound
+	if devs, err := GetPatternNodes(ec, "myorg", "myorg/p1", CreateSearchPatternRequest()); err != nil || len(*devs) != 0 {
+		t.Errorf("expecting no nodes, have %v, error %v", devs, err)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/open-horizon/anax/exchangecommon"
 	"github.com/open-horizon/anax/policy"
 	"strings"
-	"time"
 )
 
 type Pattern struct {
@@ -223,7 +222,7 @@ func GetPatterns(httpClientFactory *config.HTTPClientFactory, org string, patter
 	}
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := NewRetryBackoff(httpClientFactory, targetURL)
 	for {
 		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "GET", targetURL, id, token, nil, &resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -231,13 +230,13 @@ func GetPatterns(httpClientFactory *config.HTTPClientFactory, org string, patter
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
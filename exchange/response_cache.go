@@ -0,0 +1,278 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// The responses to GETs of exchange resources that rarely change, i.e. service definitions, patterns, policies and
+// org definitions, are cached with their ETag and Last-Modified validators. When the resource is read again, the
+// validators are sent with the request so that the exchange can answer 304 instead of sending the whole resource. The
+// cache is persisted, so a restart revalidates the cached resources instead of downloading all of them again.
+//
+// Every read still goes to the exchange, the resource specific caches in cache.go are what avoid the calls. When
+// the exchange reports that a resource changed, its cached response is marked stale and the next read of it is not
+// conditional, so a change can't be missed because a validator did not change.
+
+// A place to keep the cached responses across restarts. The agent uses its bolt DB and the agbot uses its database.
+type ResponseCacheStore interface {
+	LoadExchangeResponses() (map[string][]byte, error)
+	SaveExchangeResponse(key string, value []byte) error
+	DeleteExchangeResponse(key string) error
+}
+
+type CachedResponse struct {
+	URL          string `json:"url"`
+	User         string `json:"user"`
+	Org          string `json:"org"`
+	Class        string `json:"class"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Body         []byte `json:"body"`
+	Updated      int64  `json:"updated"`
+	Stale        bool   `json:"stale,omitempty"`
+}
+
+func (r CachedResponse) String() string {
+	return fmt.Sprintf("URL: %v, User: %v, ETag: %v, LastModified: %v, Updated: %v, Stale: %v, Body: %v bytes", r.URL, r.User, r.ETag, r.LastModified, r.Updated, r.Stale, len(r.Body))
+}
+
+type responseCache struct {
+	lock    sync.Mutex
+	entries map[string]*CachedResponse
+	store   ResponseCacheStore
+}
+
+// The cache is only turned on by the agent and the agbot.
+var exchangeResponseCache *responseCache
+
+// Turn on the response cache and load the responses that were cached before the last restart.
+func ConfigureResponseCache(store ResponseCacheStore) {
+	c := &responseCache{
+		entries: make(map[string]*CachedResponse),
+		store:   store,
+	}
+
+	if store != nil {
+		if saved, err := store.LoadExchangeResponses(); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf("unable to load cached exchange responses, error: %v", err)))
+		} else {
+			for key, value := range saved {
+				entry := new(CachedResponse)
+				if err := json.Unmarshal(value, entry); err != nil {
+					glog.Warningf(rpclogString(fmt.Sprintf("discarding cached exchange response %v, error: %v", key, err)))
+					c.delete(key)
+				} else if !isCacheableResource(http.MethodGet, entry.Class) {
+					// An older release could have cached it.
+					glog.V(3).Infof(rpclogString(fmt.Sprintf("discarding cached exchange response %v, it is not cacheable", key)))
+					c.delete(key)
+				} else {
+					c.entries[key] = entry
+				}
+			}
+		}
+	}
+
+	exchangeResponseCache = c
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("exchange response cache loaded with %v responses", len(c.entries))))
+}
+
+// Remove all the cached responses, e.g. when the node is unregistered.
+func ClearResponseCache() {
+	c := exchangeResponseCache
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.entries {
+		c.delete(key)
+	}
+}
+
+// The resources that rarely change and hold no credentials, e.g. the docker auths of a service are not cached.
+var cacheableResources = map[string]bool{
+	"orgs":              true,
+	"patterns":          true,
+	"business/policies": true,
+	"services":          true,
+	"services/policy":   true,
+	"services/keys":     true,
+}
+
+// Only reads of the resources that rarely change are cached.
+func isCacheableResource(method string, class string) bool {
+	return method == http.MethodGet && cacheableResources[class]
+}
+
+func responseCacheKey(user string, urlPath string) string {
+	return user + " " + urlPath
+}
+
+// Add the validators of the cached response, if there is one, to the request. The returned cached response is
+// passed to complete once the response has been received.
+func (c *responseCache) prepare(req *http.Request, method string, user string, urlPath string) *CachedResponse {
+	if c == nil || !isCacheableResource(method, ExchangeResourceClass(urlPath)) {
+		return nil
+	}
+
+	c.lock.Lock()
+	var entry CachedResponse
+	cached, ok := c.entries[responseCacheKey(user, urlPath)]
+	if ok {
+		entry = *cached
+	}
+	c.lock.Unlock()
+	if !ok || entry.Stale {
+		return nil
+	}
+
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+	return &entry
+}
+
+// Update the cache from the response and return the body to use. When the exchange says that the resource has not
+// changed, the cached body is returned and the response is turned into a 200.
+func (c *responseCache) complete(cached *CachedResponse, method string, user string, urlPath string, httpResp *http.Response, body []byte) []byte {
+	if c == nil {
+		return body
+	}
+	class := ExchangeResourceClass(urlPath)
+	if !isCacheableResource(method, class) {
+		return body
+	}
+
+	key := responseCacheKey(user, urlPath)
+	switch httpResp.StatusCode {
+	case http.StatusNotModified:
+		if cached == nil {
+			return body
+		}
+		exchangeConditionalRequests.Inc(class, METRIC_NOT_MODIFIED)
+		if glog.V(5) {
+			glog.Infof(rpclogString(fmt.Sprintf("%v at %v has not changed, using the cached response", method, urlPath)))
+		}
+		httpResp.StatusCode = http.StatusOK
+		return cached.Body
+
+	case http.StatusOK:
+		if cached != nil {
+			exchangeConditionalRequests.Inc(class, METRIC_MODIFIED)
+		}
+		etag := httpResp.Header.Get("ETag")
+		lastModified := httpResp.Header.Get("Last-Modified")
+		if etag == "" && lastModified == "" {
+			// Nothing to revalidate the resource with.
+			c.remove(key)
+			return body
+		}
+		c.save(key, &CachedResponse{
+			URL:          urlPath,
+			User:         user,
+			Org:          resourceOrg(urlPath),
+			Class:        class,
+			ETag:         etag,
+			LastModified: lastModified,
+			Body:         body,
+			Updated:      time.Now().Unix(),
+		})
+
+	case http.StatusNotFound:
+		c.remove(key)
+	}
+	return body
+}
+
+func (c *responseCache) save(key string, entry *CachedResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = entry
+	c.persist(key, entry)
+}
+
+func (c *responseCache) remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.entries[key]; ok {
+		c.delete(key)
+	}
+}
+
+// Write the entry to the store. The caller must hold the cache lock.
+func (c *responseCache) persist(key string, entry *CachedResponse) {
+	if c.store == nil {
+		return
+	} else if value, err := json.Marshal(entry); err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("unable to serialize cached exchange response %v, error: %v", entry, err)))
+	} else if err := c.store.SaveExchangeResponse(key, value); err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("unable to save cached exchange response %v, error: %v", entry, err)))
+	}
+}
+
+// Remove the entry from memory and from the store. The caller must hold the cache lock.
+func (c *responseCache) delete(key string) {
+	delete(c.entries, key)
+	if c.store != nil {
+		if err := c.store.DeleteExchangeResponse(key); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf("unable to delete cached exchange response %v, error: %v", key, err)))
+		}
+	}
+}
+
+// Mark the cached responses of an org's resources stale, so that the next read of them is not conditional. An
+// empty class marks all of the org's resources.
+func MarkCachedResponsesStale(org string, class string) {
+	c := exchangeResponseCache
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key, entry := range c.entries {
+		if entry.Stale || entry.Org != org {
+			continue
+		} else if class == "" || entry.Class == class || strings.HasPrefix(entry.Class, class+"/") {
+			glog.V(5).Infof(rpclogString(fmt.Sprintf("cached response of %v is stale", entry.URL)))
+			entry.Stale = true
+			c.persist(key, entry)
+		}
+	}
+}
+
+// Mark the cached responses of the resources that a change is about stale.
+func markCachedResponsesStaleFromChange(change ExchangeChange) {
+	if change.IsService() || change.IsServicePolicy() {
+		MarkCachedResponsesStale(change.OrgID, "services")
+	} else if change.IsPattern() {
+		MarkCachedResponsesStale(change.OrgID, "patterns")
+	} else if change.IsDeploymentPolicy() {
+		MarkCachedResponsesStale(change.OrgID, "business/policies")
+	} else if change.IsOrg() {
+		// A created or deleted org marks all of its resources stale, see DeleteOrgCachedResources.
+		MarkCachedResponsesStale(change.OrgID, "orgs")
+	}
+}
+
+// Returns the org that an exchange URL is scoped to, or an empty string.
+func resourceOrg(urlPath string) string {
+	parts := strings.Split(strings.SplitN(urlPath, "?", 2)[0], "/")
+	for ix, p := range parts {
+		if p == "orgs" && ix+1 < len(parts) {
+			return parts[ix+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+//go:build unit
+// +build unit
+
+package exchange
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// A fake exchange that serves one pattern with an ETag and answers conditional GETs. It counts the responses that
+// include the pattern.
+type patternServer struct {
+	etag      atomic.Value
+	downloads int32
+	requests  int32
+}
+
+func (p *patternServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&p.requests, 1)
+	etag := `"` + p.etag.Load().(string) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	atomic.AddInt32(&p.downloads, 1)
+	w.Header().Set("ETag", etag)
+	w.Write([]byte(`{"patterns":{"myorg/p1":{"label":"` + p.etag.Load().(string) + `"}},"lastIndex":0}`))
+}
+
+func getPattern(t *testing.T, url string) string {
+	var resp interface{}
+	resp = new(GetPatternResponse)
+	if err, tpErr := InvokeExchange(http.DefaultClient, "GET", url+"/v1/orgs/myorg/patterns/p1", "myorg/node1", "token", nil, &resp); err != nil || tpErr != nil {
+		t.Fatalf("Could not get pattern, error %v %v", err, tpErr)
+	}
+	return resp.(*GetPatternResponse).Patterns["myorg/p1"].Label
+}
+
+func Test_ResponseCache(t *testing.T) {
+	db, err := bolt.Open(path.Join(t.TempDir(), "anax.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Could not open database, error %v", err)
+	}
+	defer db.Close()
+	t.Cleanup(func() { exchangeResponseCache = nil })
+
+	ps := &patternServer{}
+	ps.etag.Store("v1")
+	server := httptest.NewServer(ps)
+	defer server.Close()
+
+	ConfigureResponseCache(persistence.NewExchangeResponseStore(db))
+
+	// The second read is revalidated, the pattern is not sent again.
+	if label := getPattern(t, server.URL); label != "v1" {
+		t.Errorf("unexpected pattern %v", label)
+	} else if label := getPattern(t, server.URL); label != "v1" {
+		t.Errorf("unexpected cached pattern %v", label)
+	} else if ps.requests != 2 || ps.downloads != 1 {
+		t.Errorf("expecting 2 requests and 1 download, were %v and %v", ps.requests, ps.downloads)
+	}
+
+	// The cache survives a restart.
+	ConfigureResponseCache(persistence.NewExchangeResponseStore(db))
+	if label := getPattern(t, server.URL); label != "v1" {
+		t.Errorf("unexpected cached pattern %v after a restart", label)
+	} else if ps.downloads != 1 {
+		t.Errorf("expecting 1 download after a restart, were %v", ps.downloads)
+	}
+
+	// A changed pattern is downloaded.
+	ps.etag.Store("v2")
+	if label := getPattern(t, server.URL); label != "v2" {
+		t.Errorf("expecting the changed pattern, was %v", label)
+	} else if ps.downloads != 2 {
+		t.Errorf("expecting 2 downloads, were %v", ps.downloads)
+	}
+
+	// Once the exchange reports a change, the next read is not conditional.
+	DeleteCacheResourceFromChange(ExchangeChange{OrgID: "myorg", Resource: RESOURCE_AGBOT_PATTERN, ID: "p1"}, "")
+	getPattern(t, server.URL)
+	if ps.downloads != 3 {
+		t.Errorf("expecting 3 downloads, were %v", ps.downloads)
+	}
+	getPattern(t, server.URL)
+	if ps.downloads != 3 {
+		t.Errorf("expecting the pattern to be revalidated, were %v downloads", ps.downloads)
+	}
+
+	// Changes to other resources do not affect the cached pattern.
+	MarkCachedResponsesStale("myorg", "services")
+	MarkCachedResponsesStale("otherorg", "")
+	getPattern(t, server.URL)
+	if ps.downloads != 3 {
+		t.Errorf("expecting the pattern to be revalidated, were %v downloads", ps.downloads)
+	}
+
+	ClearResponseCache()
+	if saved, _ := persistence.NewExchangeResponseStore(db).LoadExchangeResponses(); len(saved) != 0 {
+		t.Errorf("expecting no saved responses, have %v", len(saved))
+	}
+}
+
+// Responses that are no longer cacheable, e.g. docker auths cached by an older release, are removed when the cache is
+// loaded.
+func Test_ResponseCache_discardNotCacheable(t *testing.T) {
+	db, err := bolt.Open(path.Join(t.TempDir(), "anax.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Could not open database, error %v", err)
+	}
+	defer db.Close()
+	t.Cleanup(func() { exchangeResponseCache = nil })
+
+	store := persistence.NewExchangeResponseStore(db)
+	dockAuths := `{"url":"https://exch.com/v1/orgs/myorg/services/svc1/dockauths","class":"services/dockauths","body":"c2VjcmV0"}`
+	pattern := `{"url":"https://exch.com/v1/orgs/myorg/patterns/p1","class":"patterns","body":"e30="}`
+	store.SaveExchangeResponse("dockauths", []byte(dockAuths))
+	store.SaveExchangeResponse("pattern", []byte(pattern))
+
+	ConfigureResponseCache(store)
+	if saved, _ := store.LoadExchangeResponses(); len(saved) != 1 || saved["pattern"] == nil {
+		t.Errorf("expecting only the pattern to be kept, have %v", saved)
+	}
+}
+
+func Test_isCacheableResource(t *testing.T) {
+	tests := map[string]bool{
+		"https://exch.com/v1/orgs/myorg":                                  true,
+		"https://exch.com/v1/orgs/myorg/services/svc1":                    true,
+		"https://exch.com/v1/orgs/myorg/services/svc1/policy":             true,
+		"https://exch.com/v1/orgs/myorg/services/svc1/keys":               true,
+		"https://exch.com/v1/orgs/myorg/services/svc1/dockauths":          false,
+		"https://exch.com/v1/orgs/myorg/services/svc1/dockauths/1":        false,
+		"https://exch.com/v1/orgs/myorg/patterns/p1":                      true,
+		"https://exch.com/v1/orgs/myorg/business/policies/pol1":           true,
+		"https://exch.com/v1/orgs/myorg/nodes/node1":                      false,
+		"https://exch.com/v1/orgs/myorg/agbots/ag1/businesspols":          false,
+		"https://exch.com/v1/orgs/myorg/changes":                          false,
+		"https://exch.com/v1/orgs/myorg/nodes/node1/services_configstate": false,
+	}
+
+	for url, expected := range tests {
+		if cacheable := isCacheableResource("GET", ExchangeResourceClass(url)); cacheable != expected {
+			t.Errorf("for %v expected %v but got %v", url, expected, cacheable)
+		}
+	}
+	if isCacheableResource("PUT", "services") {
+		t.Errorf("only GETs should be cached")
+	}
+}
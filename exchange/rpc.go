@@ -51,6 +51,15 @@ type PutPostDeleteStandardResponse struct {
 // This function is used to invoke an exchange API
 // For GET, the given resp parameter will be untouched when http returns code 404.
 func InvokeExchange(httpClient *http.Client, method string, urlPath string, user string, pw string, params interface{}, resp *interface{}) (error, error) {
+	return guardExchangeCall(httpClient, urlPath, func(httpClient *http.Client) (error, error) {
+		start := time.Now()
+		err, tpErr := invokeExchange(httpClient, method, urlPath, user, pw, params, resp)
+		observeExchangeCall(method, urlPath, start, err, tpErr)
+		return err, tpErr
+	})
+}
+
+func invokeExchange(httpClient *http.Client, method string, urlPath string, user string, pw string, params interface{}, resp *interface{}) (error, error) {
 
 	if len(method) == 0 {
 		return errors.New(fmt.Sprintf("Error invoking exchange, method name must be specified")), nil
@@ -119,16 +128,10 @@ func InvokeExchange(httpClient *http.Client, method string, urlPath string, user
 		if method != "GET" {
 			req.Header.Add("Content-Type", "application/json")
 		}
-		if user != "" && pw != "" {
-			if strings.HasPrefix(pw, "Bearer ") {
-				// pw is: Bearer <token>
-				req.Header.Add("Authorization", pw)
-				orgId, _ := cutil.SplitOrgSpecUrl(user)
-				req.Header.Add("X-Organization", orgId)
-			} else {
-				req.Header.Add("Authorization", fmt.Sprintf("Basic %v", base64.StdEncoding.EncodeToString([]byte(user+":"+pw))))
-			}
-		}
+		addExchangeAuth(req, user, pw)
+
+		// Revalidate the cached response of the resource, if there is one.
+		cached := exchangeResponseCache.prepare(req, method, user, urlPath)
 
 		// If the exchange is down, this call will return an error.
 		httpResp, err := httpClient.Do(req)
@@ -151,6 +154,7 @@ func InvokeExchange(httpClient *http.Client, method string, urlPath string, user
 					return errors.New(fmt.Sprintf("Invocation of %v at %v failed reading response message, HTTP Status %v, error: %v", method, urlPath, httpResp.Status, readErr)), nil
 				}
 			}
+			outBytes = exchangeResponseCache.complete(cached, method, user, urlPath, httpResp, outBytes)
 
 			// Handle special case of server error
 			if httpResp.StatusCode == http.StatusInternalServerError && strings.Contains(string(outBytes), "timed out") {
@@ -327,9 +331,23 @@ func InvokeExchange(httpClient *http.Client, method string, urlPath string, user
 	}
 }
 
+// Add the credentials to a request for the exchange.
+func addExchangeAuth(req *http.Request, user string, pw string) {
+	if user != "" && pw != "" {
+		if strings.HasPrefix(pw, "Bearer ") {
+			// pw is: Bearer <token>
+			req.Header.Add("Authorization", pw)
+			orgId, _ := cutil.SplitOrgSpecUrl(user)
+			req.Header.Add("X-Organization", orgId)
+		} else {
+			req.Header.Add("Authorization", fmt.Sprintf("Basic %v", base64.StdEncoding.EncodeToString([]byte(user+":"+pw))))
+		}
+	}
+}
+
 func InvokeExchangeRetryOnTransportError(httpClientFactory *config.HTTPClientFactory, method string, urlPath string, user string, pw string, params interface{}, resp *interface{}) error {
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := NewRetryBackoff(httpClientFactory, urlPath)
 	for {
 		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), method, urlPath, user, pw, params, resp); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -337,13 +355,13 @@ func InvokeExchangeRetryOnTransportError(httpClientFactory *config.HTTPClientFac
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -411,7 +429,7 @@ func GetExchangeVersion(httpClientFactory *config.HTTPClientFactory, exchangeUrl
 	}
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := NewRetryBackoff(httpClientFactory, targetURL)
 	for {
 		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "GET", targetURL, id, token, nil, &resp); err != nil {
 			glog.Errorf(err.Error())
@@ -419,13 +437,13 @@ func GetExchangeVersion(httpClientFactory *config.HTTPClientFactory, exchangeUrl
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return "", fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -496,7 +514,7 @@ func GetObjectSigningKeys(ec ExchangeContext, oType string, oURL string, oOrg st
 	key_names := make([]string, 0)
 
 	retryCount := ec.GetHTTPFactory().RetryCount
-	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 	for {
 		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp_KeyNames); err != nil {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -504,13 +522,13 @@ func GetObjectSigningKeys(ec ExchangeContext, oType string, oURL string, oOrg st
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if ec.GetHTTPFactory().RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -534,7 +552,7 @@ func GetObjectSigningKeys(ec ExchangeContext, oType string, oURL string, oOrg st
 		resp_KeyContent = ""
 
 		retryCount := ec.GetHTTPFactory().RetryCount
-		retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+		retryBackoff := NewRetryBackoff(ec.GetHTTPFactory(), targetURL)
 		for {
 			if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", fmt.Sprintf("%v/%v", targetURL, key), ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp_KeyContent); err != nil {
 				glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -542,13 +560,13 @@ func GetObjectSigningKeys(ec ExchangeContext, oType string, oURL string, oOrg st
 			} else if tpErr != nil {
 				glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 				if ec.GetHTTPFactory().RetryCount == 0 {
-					time.Sleep(time.Duration(retryInterval) * time.Second)
+					retryBackoff.Wait()
 					continue
 				} else if retryCount == 0 {
 					return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 				} else {
 					retryCount--
-					time.Sleep(time.Duration(retryInterval) * time.Second)
+					retryBackoff.Wait()
 					continue
 				}
 			} else {
@@ -6,6 +6,7 @@ import (
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/persistence"
+	"io"
 	"strings"
 	"time"
 )
@@ -112,26 +113,37 @@ func CreateSearchPatternRequest() *SearchExchangePatternRequest {
 	return ser
 }
 
+// Returns all the nodes found by a pattern search.
 func GetPatternNodes(ec ExchangeContext, policyOrg string, patternId string, req *SearchExchangePatternRequest) (*[]SearchResultDevice, error) {
-	// Invoke the exchange
-	var resp interface{}
-	resp = new(SearchExchangePatternResponse)
+	devs := make([]SearchResultDevice, 0, 0)
+	if _, err := SearchPatternNodes(ec, policyOrg, patternId, req, 0, func(page []SearchResultDevice) error {
+		devs = append(devs, page...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &devs, nil
+}
+
+// Search for the nodes of a pattern and hand them to the handler a page at a time, as the response is read. The exchange
+// returns all the nodes that match in one response, which can be very large. Returns the number of nodes found.
+func SearchPatternNodes(ec ExchangeContext, policyOrg string, patternId string, req *SearchExchangePatternRequest, pageSize int, handler SearchResultPageHandler) (int, error) {
 	targetURL := ec.GetExchangeURL() + "orgs/" + policyOrg + "/patterns/" + GetId(patternId) + "/search"
 	for {
-		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "POST", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), *req, &resp); err != nil {
-			if !strings.Contains(err.Error(), "status: 404") {
-				return nil, err
-			} else {
-				empty := make([]SearchResultDevice, 0, 0)
-				return &empty, nil
-			}
+		count := 0
+		decode := func(r io.Reader) error {
+			var err error
+			count, err = decodeNodePages(r, pageSize, handler)
+			return err
+		}
+		if err, tpErr := invokeExchangeStream(ec.GetHTTPFactory().NewHTTPClient(nil), "POST", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), *req, decode); err != nil {
+			return count, err
 		} else if tpErr != nil {
 			glog.Warningf(tpErr.Error())
 			time.Sleep(10 * time.Second)
 			continue
 		} else {
-			dev := resp.(*SearchExchangePatternResponse).Devices
-			return &dev, nil
+			return count, nil
 		}
 	}
 }
@@ -187,7 +199,7 @@ func GetNodeHealthStatus(httpClientFactory *config.HTTPClientFactory, pattern st
 	}
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := NewRetryBackoff(httpClientFactory, targetURL)
 	for {
 		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "POST", targetURL, id, token, &params, &resp); err != nil && !strings.Contains(err.Error(), "status: 404") {
 			glog.Errorf(rpclogString(fmt.Sprintf("%s", err.Error())))
@@ -195,13 +207,13 @@ func GetNodeHealthStatus(httpClientFactory *config.HTTPClientFactory, pattern st
 		} else if tpErr != nil {
 			glog.Warningf(rpclogString(fmt.Sprintf("%s", tpErr.Error())))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
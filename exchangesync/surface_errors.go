@@ -63,6 +63,12 @@ func UpdateSurfaceErrors(db *bolt.DB, pDevice persistence.ExchangeDevice, exchEr
 		}
 	}
 
+	// Errors that were removed from the local DB, e.g. because the condition that raised them was resolved, need to be
+	// removed from the exchange too.
+	if len(exchErrors) > len(updatedExchLogs) {
+		updated = true
+	}
+
 	glog.V(5).Infof("Saving errors to surface locally: %v", updatedExchLogs)
 	err = persistence.SaveSurfaceErrors(db, updatedExchLogs)
 	if err != nil {
@@ -91,6 +97,11 @@ func PersistingEAFilter(agreementPersistentTime int) persistence.EAFilter {
 // HasPersistentAgreement takes a recordID and returns true if there is a persistent agreement with the same workload on the node.
 func HasPersistentAgreement(db *bolt.DB, serviceResolverHandler exchange.ServiceResolverHandler, pDevice persistence.ExchangeDevice, msgPrinter *message.Printer, errorLog persistence.SurfaceError, agreementPersistentTime int) bool {
 	eventLog := persistence.GetEventLogObject(db, msgPrinter, errorLog.Record_id)
+
+	// Node errors are not about a workload, so an agreement does not resolve them.
+	if eventLog.SourceType == persistence.SRC_TYPE_NODE {
+		return false
+	}
 	workload := persistence.GetWorkloadInfo(eventLog)
 
 	allServices, err := getAllServicesFromAgreements(db, serviceResolverHandler, agreementPersistentTime)
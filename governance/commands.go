@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/tracing"
 )
 
 type StartGovernExecutionCommand struct {
+	tracing.Carrier
 	AgreementId       string
 	AgreementProtocol string
 	Deployment        persistence.DeploymentConfig
@@ -17,15 +19,18 @@ func (g StartGovernExecutionCommand) ShortString() string {
 }
 
 func (w *GovernanceWorker) NewStartGovernExecutionCommand(deployment persistence.DeploymentConfig, protocol string, agreementId string) *StartGovernExecutionCommand {
-	return &StartGovernExecutionCommand{
+	c := &StartGovernExecutionCommand{
 		AgreementId:       agreementId,
 		AgreementProtocol: protocol,
 		Deployment:        deployment,
 	}
+	c.SetTraceContext(tracing.AgreementTraceContext(agreementId))
+	return c
 }
 
 // ==============================================================================================================
 type CleanupExecutionCommand struct {
+	tracing.Carrier
 	AgreementProtocol string
 	AgreementId       string
 	Reason            uint
@@ -41,16 +46,19 @@ func (c CleanupExecutionCommand) ShortString() string {
 }
 
 func (w *GovernanceWorker) NewCleanupExecutionCommand(protocol string, agreementId string, reason uint, deployment persistence.DeploymentConfig) *CleanupExecutionCommand {
-	return &CleanupExecutionCommand{
+	c := &CleanupExecutionCommand{
 		AgreementProtocol: protocol,
 		AgreementId:       agreementId,
 		Reason:            reason,
 		Deployment:        deployment,
 	}
+	c.SetTraceContext(tracing.AgreementTraceContext(agreementId))
+	return c
 }
 
 // ==============================================================================================================
 type CleanupStatusCommand struct {
+	tracing.Carrier
 	AgreementProtocol string
 	AgreementId       string
 	Status            uint
@@ -62,11 +70,13 @@ func (c CleanupStatusCommand) ShortString() string {
 }
 
 func (w *GovernanceWorker) NewCleanupStatusCommand(protocol string, agreementId string, status uint) *CleanupStatusCommand {
-	return &CleanupStatusCommand{
+	c := &CleanupStatusCommand{
 		AgreementProtocol: protocol,
 		AgreementId:       agreementId,
 		Status:            status,
 	}
+	c.SetTraceContext(tracing.AgreementTraceContext(agreementId))
+	return c
 }
 
 // ==============================================================================================================
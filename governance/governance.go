@@ -85,7 +85,7 @@ func NewGovernanceWorker(name string, cfg *config.HorizonConfig, db *bolt.DB, pm
 		ShuttingDownCmd: nil,
 		limitedRetryEC:  lrec,
 		exchErrors:      cache.NewSimpleMapCache(),
-		noworkDispatch:  time.Now().Unix(),
+		noworkDispatch:  worker.GetClock().Now().Unix(),
 		essCleanedUp:    false,
 	}
 
@@ -398,6 +398,35 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *GovernanceWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.EdgeConfigCompleteMessage{},
+		&events.WorkloadMessage{},
+		&events.ImageFetchMessage{},
+		&events.InitAgreementCancelationMessage{},
+		&events.ApiAgreementCancelationMessage{},
+		&events.BlockchainClientInitializedMessage{},
+		&events.BlockchainClientStoppingMessage{},
+		&events.AccountFundedMessage{},
+		&events.EthBlockchainEventMessage{},
+		&events.ExchangeDeviceMessage{},
+		&events.ContainerMessage{},
+		&events.MicroserviceContainersDestroyedMessage{},
+		&events.NodeShutdownMessage{},
+		&events.NodeShutdownCompleteMessage{},
+		&events.SyncServiceCleanedUpMessage{},
+		&events.NodeHeartbeatStateChangeMessage{},
+		&events.ServiceConfigStateChangeMessage{},
+		&events.UpdatePolicyMessage{},
+		&events.NodePolicyMessage{},
+		&events.NodeUserInputMessage{},
+		&events.NodePatternMessage{},
+		&events.ExchangeChangeMessage{},
+	}
+}
+
 // Make sure that every agreement we have is in a valid state or proceeding to valid states in a timely fashion. If not,
 // cancel the agreement and allow the agbots to re-make them if necessary.
 func (w *GovernanceWorker) governAgreements() {
@@ -452,7 +481,7 @@ func (w *GovernanceWorker) governAgreements() {
 					}
 				}
 				// If we fall through to here, then the agreement is Not finalized yet, check for a timeout.
-				now := uint64(time.Now().Unix())
+				now := uint64(worker.GetClock().Now().Unix())
 				timeout := w.BaseWorker.Manager.Config.Edge.AgreementTimeoutS
 				if timeout == 0 {
 					timeout = ag.AgreementTimeout
@@ -478,7 +507,7 @@ func (w *GovernanceWorker) governAgreements() {
 				// For finalized agreements, make sure the workload has been started in time.
 				if ag.AgreementExecutionStartTime == 0 {
 					// workload not started yet and in an agreement ...
-					if (int64(ag.AgreementAcceptedTime) + (w.Config.Edge.MaxAgreementPrelaunchTimeM * 60)) < time.Now().Unix() {
+					if (int64(ag.AgreementAcceptedTime) + (w.Config.Edge.MaxAgreementPrelaunchTimeM * 60)) < worker.GetClock().Now().Unix() {
 						glog.Infof(logString(fmt.Sprintf("terminating agreement %v because it hasn't been launched in max allowed time. This could be because of a workload failure.", ag.CurrentAgreementId)))
 						reason := w.producerPH[ag.AgreementProtocol].GetTerminationCode(producer.TERM_REASON_NOT_EXECUTED_TIMEOUT)
 						eventlog.LogAgreementEvent(w.db, persistence.SEVERITY_INFO,
@@ -534,8 +563,11 @@ func (w *GovernanceWorker) governAgreements() {
 						glog.V(5).Infof(logString(fmt.Sprintf("agreement %v is still in policy.", ag.CurrentAgreementId)))
 					}
 
-					timeSinceVer := uint64(time.Now().Unix()) - ag.LastVerAttemptUpdateTime
-					if ag.FailedVerAttempts > 5 {
+					// The agbot can't verify agreements while the node is offline, the agreements keep running until it reconnects.
+					timeSinceVer := uint64(worker.GetClock().Now().Unix()) - ag.LastVerAttemptUpdateTime
+					if exchange.IsOffline() {
+						continue
+					} else if ag.FailedVerAttempts > 5 {
 						glog.Infof(logString(fmt.Sprintf("terminating agreement %v because it cannot be verified by the agreement bot.", ag.CurrentAgreementId)))
 						reason := w.producerPH[ag.AgreementProtocol].GetTerminationCode(producer.TERM_FAILED_AGREEMENT_VERIFY)
 						eventlog.LogAgreementEvent(w.db, persistence.SEVERITY_INFO,
@@ -781,6 +813,9 @@ func (w *GovernanceWorker) Initialize() bool {
 		w.producerPH[protocolName] = pph
 	}
 
+	// record the worker watchdog's alerts in the event log and surface them to the exchange
+	worker.GetWatchdog().AddAlertHandler(w.handleWatchdogAlert)
+
 	// report the device status to the exchange
 	w.DispatchSubworker(NODESTATUS, w.ReportDeviceStatus, 60, false)
 
@@ -810,7 +845,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 	// It's possible that the command handler stays busy enough that the noworkhandler never gets
 	// control. Usually that's not a problem for workers, but this worker is a special case because
 	// it handles shutdown processing.
-	if time.Since(time.Unix(w.noworkDispatch, 0)).Seconds() > float64(w.GetNoWorkInterval()) {
+	if worker.GetClock().Since(time.Unix(w.noworkDispatch, 0)).Seconds() > float64(w.GetNoWorkInterval()) {
 		w.NoWorkHandler()
 	}
 
@@ -1482,7 +1517,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 func (w *GovernanceWorker) NoWorkHandler() {
 
 	glog.V(3).Infof(logString(fmt.Sprintf("GovernanceWorker dispatching no work handler.")))
-	w.noworkDispatch = time.Now().Unix()
+	w.noworkDispatch = worker.GetClock().Now().Unix()
 
 	// Make sure that all known agreements are maintained, if we're not shutting down.
 	if !w.IsWorkerShuttingDown() {
@@ -1935,7 +1970,9 @@ func recordProducerAgreementState(httpClient *http.Client, url string, deviceId
 	resp = new(exchange.PostDeviceResponse)
 	targetURL := url + "orgs/" + exchange.GetOrg(deviceId) + "/nodes/" + exchange.GetId(deviceId) + "/agreements/" + agreementId + "?" + exchange.NOHEARTBEAT_PARAM
 	for {
-		if err, tpErr := exchange.InvokeExchange(httpClient, "PUT", targetURL, deviceId, token, &as, &resp); err != nil {
+		if exchange.QueueExchangeWrite("PUT", targetURL, "", &as) {
+			return nil
+		} else if err, tpErr := exchange.InvokeExchange(httpClient, "PUT", targetURL, deviceId, token, &as, &resp); err != nil {
 			glog.Errorf(logString(fmt.Sprintf("%s", err.Error())))
 			return err
 		} else if tpErr != nil {
@@ -1955,26 +1992,28 @@ func (w *GovernanceWorker) deleteProducerAgreement(url string, deviceId string,
 	glog.V(5).Infof(logString(fmt.Sprintf("deleting agreement %v in exchange", agreementId)))
 
 	httpClientFactory := w.GetHTTPFactory()
-	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
-
 	var resp interface{}
 	resp = new(exchange.PostDeviceResponse)
 	targetURL := url + "orgs/" + exchange.GetOrg(deviceId) + "/nodes/" + exchange.GetId(deviceId) + "/agreements/" + agreementId
+
+	retryCount := httpClientFactory.RetryCount
+	retryBackoff := exchange.NewRetryBackoff(httpClientFactory, targetURL)
 	for {
-		if err, tpErr := exchange.InvokeExchange(httpClientFactory.NewHTTPClient(nil), "DELETE", targetURL, deviceId, token, nil, &resp); err != nil && !strings.Contains(err.Error(), "status: 404") {
+		if exchange.QueueExchangeWrite("DELETE", targetURL, "", nil) {
+			return nil
+		} else if err, tpErr := exchange.InvokeExchange(httpClientFactory.NewHTTPClient(nil), "DELETE", targetURL, deviceId, token, nil, &resp); err != nil && !strings.Contains(err.Error(), "status: 404") {
 			glog.Errorf(logString(fmt.Sprintf("%s", err.Error())))
 			return err
 		} else if tpErr != nil {
 			glog.Warningf(logString(tpErr.Error()))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return errors.New(fmt.Sprintf("exceeded %v retries trying to delete node for %v", httpClientFactory.RetryCount, tpErr))
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
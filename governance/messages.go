@@ -26,6 +26,11 @@ const (
 	EL_GOV_ERR_RETRIEVE_DEVICE_FROM_DB        = "Error retrieving device from database. Error: %v"
 	EL_GOV_DEL_NODE_EXCH_PATTERN_FROM_DB      = "Error deleting node exchange pattern from the local database. %v"
 
+	// worker watchdog
+	EL_GOV_WORKER_STUCK     = "Worker %v has been handling %v for %v seconds."
+	EL_GOV_WORKER_BACKLOG   = "Worker %v is not keeping up, %v of %v queued commands are waiting."
+	EL_GOV_WORKER_RECOVERED = "Worker %v has recovered."
+
 	// exchange
 	EL_GOV_ERR_RETRIEVE_NODE_FROM_EXCH = "Error retrieving node %v from the Exchange: %v"
 	EL_GOV_ERR_UPDATE_REGSVCS_IN_EXCH  = "Error updating registeredServices for node %v in the Exchange: %v"
@@ -220,4 +225,9 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_GOV_ERR_VALIDATE_NEW_PATTERN)
 	msgPrinter.Sprintf(EL_GOV_NODE_KEEP_OLD_PATTERN)
 	msgPrinter.Sprintf(EL_GOV_NEW_PATTERN_VERIFIED)
+
+	// worker watchdog
+	msgPrinter.Sprintf(EL_GOV_WORKER_STUCK)
+	msgPrinter.Sprintf(EL_GOV_WORKER_BACKLOG)
+	msgPrinter.Sprintf(EL_GOV_WORKER_RECOVERED)
 }
@@ -0,0 +1,15 @@
+package governance
+
+import (
+	"github.com/open-horizon/anax/metrics"
+)
+
+// Service instance metrics, exposed by the agent's /metrics API.
+var serviceFailures = metrics.NewCounterVec("anax_service_failures_total",
+	"Service instance containers that failed to start or stopped unexpectedly, by service.", "service")
+
+var serviceRestarts = metrics.NewCounterVec("anax_service_restarts_total",
+	"Service instance retries started after a failure, by service.", "service")
+
+var serviceRollbacks = metrics.NewCounterVec("anax_service_rollbacks_total",
+	"Services rolled back to a lower version after exhausting their retries, by service.", "service")
@@ -751,6 +751,7 @@ func (w *GovernanceWorker) getMicroserviceRetryCount(msi *persistence.Microservi
 // This function will retry the dependent service containers. If the retry fails it will try with a lower version.
 func (w *GovernanceWorker) handleMicroserviceExecFailure(msdef *persistence.MicroserviceDefinition, msinst_key string) {
 	glog.V(3).Infof(logString(fmt.Sprintf("handle dependent service execution failure for %v", msinst_key)))
+	serviceFailures.Inc(cutil.FormOrgSpecUrl(msdef.SpecRef, msdef.Org))
 
 	need_retry := false
 	// check if we need to retry.
@@ -798,6 +799,7 @@ func (w *GovernanceWorker) handleMicroserviceExecFailure(msdef *persistence.Micr
 
 	if need_retry {
 		current_retry := msi.CurrentRetryCount + 1
+		serviceRestarts.Inc(cutil.FormOrgSpecUrl(msdef.SpecRef, msdef.Org))
 		// start the retry
 		eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_INFO,
 			persistence.NewMessageMeta(EL_GOV_START_SVC_RETRY, strconv.Itoa(int(current_retry)), msdef.SpecRef, msdef.Version),
@@ -816,6 +818,7 @@ func (w *GovernanceWorker) handleMicroserviceExecFailure(msdef *persistence.Micr
 	} else {
 		// rollback the microservice to lower version
 		// a new ms instance will be created if successful
+		serviceRollbacks.Inc(cutil.FormOrgSpecUrl(msdef.SpecRef, msdef.Org))
 		eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_INFO,
 			persistence.NewMessageMeta(EL_GOV_START_DOWNGRADE, msdef.Org, msdef.SpecRef, msdef.Version),
 			persistence.EC_START_DOWNGRADE_SERVICE,
@@ -38,6 +38,12 @@ func (c ChangePattern) Reset() {
 func (w *GovernanceWorker) handleNodeHeartbeatRestored(checkAll bool) error {
 	glog.V(5).Infof(logString(fmt.Sprintf("handling agreements after node heartbeat restored.")))
 
+	// The agbot can't be asked to verify agreements until the node is online.
+	if exchange.IsOffline() {
+		w.AddDeferredCommand(w.NewNodeHeartbeatRestoredCommand(checkAll))
+		return nil
+	}
+
 	if ags, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()}); err != nil {
 		eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
 			persistence.NewMessageMeta(EL_GOV_ERR_RETRIEVE_UNARCHIVED_AG_FROM_DB, err.Error()),
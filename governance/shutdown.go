@@ -11,6 +11,7 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/producer"
+	"github.com/open-horizon/anax/worker"
 	"runtime"
 	"strings"
 	"time"
@@ -155,6 +156,16 @@ func (w *GovernanceWorker) nodeShutdown(cmd *NodeShutdownCommand) {
 		return
 	}
 
+	// Delete the cached exchange responses, they belong to the node's user.
+	exchange.ClearResponseCache()
+
+	// The queued writes are about the node that is being unregistered.
+	exchange.ClearExchangeWrites()
+	exchange.SetOffline(false)
+
+	// The journaled events would start the node's workloads again.
+	worker.ClearEventJournal()
+
 	// remove the docker volumes that are created by anax if device type is "device"
 	if w.deviceType == persistence.DEVICE_TYPE_DEVICE {
 		if err := container.DeleteLeftoverDockerVolumes(w.db, w.Config); err != nil {
@@ -422,7 +433,7 @@ func (w *GovernanceWorker) patchNodeKey(httpClientFactory *config.HTTPClientFact
 	glog.V(3).Infof(logString(fmt.Sprintf("clearing messaging key in node entry: %v at %v", pdr, targetURL)))
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := exchange.NewRetryBackoff(httpClientFactory, targetURL)
 
 	for {
 		if err, tpErr := exchange.InvokeExchange(httpClientFactory.NewHTTPClient(nil), "PATCH", targetURL, w.GetExchangeId(), w.GetExchangeToken(), pdr, &resp); err != nil {
@@ -434,7 +445,7 @@ func (w *GovernanceWorker) patchNodeKey(httpClientFactory *config.HTTPClientFact
 		} else if tpErr != nil {
 			glog.Warningf(tpErr.Error())
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				// Break so that the rest of the function can do its cleanup.
@@ -442,7 +453,7 @@ func (w *GovernanceWorker) patchNodeKey(httpClientFactory *config.HTTPClientFact
 				break
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -560,7 +571,7 @@ func (w *GovernanceWorker) deleteNode(httpClientFactory *config.HTTPClientFactor
 	glog.V(3).Infof(logString(fmt.Sprintf("deleting node %v from exchange", w.GetExchangeId())))
 
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := exchange.NewRetryBackoff(httpClientFactory, targetURL)
 
 	for {
 		if err, tpErr := exchange.InvokeExchange(httpClientFactory.NewHTTPClient(nil), "DELETE", targetURL, w.GetExchangeId(), w.GetExchangeToken(), nil, &resp); err != nil {
@@ -568,13 +579,13 @@ func (w *GovernanceWorker) deleteNode(httpClientFactory *config.HTTPClientFactor
 		} else if tpErr != nil {
 			glog.Warningf(tpErr.Error())
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return errors.New(fmt.Sprintf("exceeded %v retries trying to delete node for %v", httpClientFactory.RetryCount, tpErr))
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -0,0 +1,38 @@
+package governance
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+)
+
+// Record the worker watchdog's alerts in the event log. Stuck and backlogged workers are surfaced to the exchange
+// until they recover, so that a node that has stopped processing agreements is visible to the management hub.
+func (w *GovernanceWorker) handleWatchdogAlert(alert worker.WatchdogAlert) {
+	pDevice, err := persistence.FindExchangeDevice(w.db)
+	if err != nil || pDevice == nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read node object to log watchdog alert %v, error: %v", alert, err)))
+		return
+	}
+
+	switch alert.Kind {
+	case worker.WATCHDOG_ALERT_STUCK:
+		eventlog.LogNodeEvent(w.db, persistence.SEVERITY_ERROR,
+			persistence.NewMessageMeta(EL_GOV_WORKER_STUCK, alert.Worker, alert.Command, int64(alert.InFlight.Seconds())),
+			persistence.EC_WORKER_STUCK,
+			pDevice.Id, pDevice.Org, pDevice.Pattern, pDevice.Config.State)
+	case worker.WATCHDOG_ALERT_BACKLOG:
+		eventlog.LogNodeEvent(w.db, persistence.SEVERITY_ERROR,
+			persistence.NewMessageMeta(EL_GOV_WORKER_BACKLOG, alert.Worker, alert.QueueLength, alert.QueueCapacity),
+			persistence.EC_WORKER_BACKLOG,
+			pDevice.Id, pDevice.Org, pDevice.Pattern, pDevice.Config.State)
+	case worker.WATCHDOG_ALERT_RECOVERED:
+		eventlog.LogNodeEvent(w.db, persistence.SEVERITY_INFO,
+			persistence.NewMessageMeta(EL_GOV_WORKER_RECOVERED, alert.Worker),
+			persistence.EC_WORKER_RECOVERED,
+			pDevice.Id, pDevice.Org, pDevice.Pattern, pDevice.Config.State)
+	}
+}
@@ -73,6 +73,16 @@ func (w *HelmWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *HelmWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.AgreementReachedMessage{},
+		&events.GovernanceWorkloadCancelationMessage{},
+		&events.GovernanceMaintenanceMessage{},
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 func (w *HelmWorker) CommandHandler(command worker.Command) bool {
 
 	switch command.(type) {
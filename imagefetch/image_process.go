@@ -10,6 +10,7 @@ import (
 	"github.com/open-horizon/anax/containermessage"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"strings"
 )
@@ -88,6 +89,16 @@ func (w *ImageFetchWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *ImageFetchWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.AgreementReachedMessage{},
+		&events.LoadContainerMessage{},
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 // append the auth attribute to the given auth maps
 func ExtractAuthAttributes(attributes []persistence.Attribute, dockerAuthConfigurations map[string][]docker.AuthConfiguration) error {
 
@@ -317,6 +328,7 @@ func (b *ImageFetchWorker) CommandHandler(command worker.Command) bool {
 }
 
 type FetchCommand struct {
+	tracing.Carrier
 	LaunchContext interface{}
 }
 
@@ -330,7 +342,9 @@ func (f FetchCommand) ShortString() string {
 }
 
 func (t *ImageFetchWorker) NewFetchCommand(launchContext interface{}) *FetchCommand {
-	return &FetchCommand{
+	c := &FetchCommand{
 		LaunchContext: launchContext,
 	}
+	c.SetTraceContext(events.LaunchContextTrace(launchContext))
+	return c
 }
@@ -134,6 +134,7 @@ func pullImageFromRepos(config config.Config, authConfigs map[string][]docker.Au
 
 		// try auths one at a time
 		var err error
+		pullStart := time.Now()
 		for i, auth := range auth_array {
 			err = pullSingleImageFromRepo(client, opts, auth)
 			if err == nil {
@@ -148,6 +149,7 @@ func pullImageFromRepos(config config.Config, authConfigs map[string][]docker.Au
 			glog.V(5).Infof("Pulling image %v without auth.", service.Image)
 			err = pullSingleImageFromRepo(client, opts, docker.AuthConfiguration{})
 		}
+		observeImagePull(client, service.Image, pullStart, err)
 
 		if err != nil {
 			glog.Errorf("Docker image pull(s) failed for docker image %v. Error: %v.", service.Image, err)
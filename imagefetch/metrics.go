@@ -0,0 +1,42 @@
+package imagefetch
+
+import (
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/metrics"
+	"time"
+)
+
+// Image pull metrics, exposed by the agent's /metrics API.
+const (
+	PULL_SUCCEEDED = "success"
+	PULL_FAILED    = "failure"
+)
+
+var imagePullDuration = metrics.NewHistogramVec("anax_image_pull_duration_seconds",
+	"Time taken to pull a service container image, including retries, by result (success or failure).", metrics.DurationBuckets, "result")
+
+var imagePullBytes = metrics.NewHistogramVec("anax_image_pull_bytes",
+	"Size of the service container images that were pulled successfully.", metrics.SizeBuckets)
+
+var imagePullBytesTotal = metrics.NewCounterVec("anax_image_pull_bytes_total",
+	"Total size of the service container images that were pulled successfully.")
+
+// Record the outcome of a single image pull. The size of a successfully pulled image is obtained from the
+// local docker image store.
+func observeImagePull(client *docker.Client, image string, start time.Time, err error) {
+	if err != nil {
+		imagePullDuration.Observe(time.Since(start).Seconds(), PULL_FAILED)
+		return
+	}
+
+	imagePullDuration.Observe(time.Since(start).Seconds(), PULL_SUCCEEDED)
+	if client == nil {
+		return
+	} else if img, err := client.InspectImage(image); err != nil {
+		glog.V(5).Infof("Unable to inspect image %v for metrics, error: %v", image, err)
+	} else if img != nil {
+		imagePullBytes.Observe(float64(img.Size))
+		imagePullBytesTotal.Add(float64(img.Size))
+	}
+}
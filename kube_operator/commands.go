@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/tracing"
 )
 
 type InstallCommand struct {
+	tracing.Carrier
 	LaunchContext interface{}
 }
 
@@ -20,12 +22,15 @@ func (i InstallCommand) ShortString() string {
 }
 
 func NewInstallCommand(launchContext interface{}) *InstallCommand {
-	return &InstallCommand{
+	c := &InstallCommand{
 		LaunchContext: launchContext,
 	}
+	c.SetTraceContext(events.LaunchContextTrace(launchContext))
+	return c
 }
 
 type UnInstallCommand struct {
+	tracing.Carrier
 	AgreementProtocol  string
 	CurrentAgreementId string
 	ClusterNamespace   string
@@ -37,12 +42,14 @@ func (u UnInstallCommand) ShortString() string {
 }
 
 func NewUnInstallCommand(agp string, agId string, clusterNamespace string, dc persistence.DeploymentConfig) *UnInstallCommand {
-	return &UnInstallCommand{
+	c := &UnInstallCommand{
 		AgreementProtocol:  agp,
 		CurrentAgreementId: agId,
 		ClusterNamespace:   clusterNamespace,
 		Deployment:         dc,
 	}
+	c.SetTraceContext(tracing.AgreementTraceContext(agId))
+	return c
 }
 
 type MaintenanceCommand struct {
@@ -10,15 +10,19 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/resource"
 	"github.com/open-horizon/anax/worker"
+	"k8s.io/client-go/kubernetes"
 	"path"
+	"sync"
 )
 
 type KubeWorker struct {
 	worker.BaseWorker
-	config    *config.HorizonConfig
-	db        *bolt.DB
-	authMgr   *resource.AuthenticationManager
-	secretMgr *resource.SecretsManager
+	config          *config.HorizonConfig
+	db              *bolt.DB
+	authMgr         *resource.AuthenticationManager
+	secretMgr       *resource.SecretsManager
+	readinessClient *kubernetes.Clientset // The client used by the readiness check, created on first use
+	readinessLock   sync.Mutex
 }
 
 func NewKubeWorker(name string, config *config.HorizonConfig, db *bolt.DB, am *resource.AuthenticationManager, sm *resource.SecretsManager) *KubeWorker {
@@ -29,11 +33,39 @@ func NewKubeWorker(name string, config *config.HorizonConfig, db *bolt.DB, am *r
 		authMgr:    am,
 		secretMgr:  sm,
 	}
+	// Services can not be started on a cluster node when the kubernetes API server is not responding.
+	worker.SetReadinessCheck("kubernetes", worker.kubeReadiness)
+
 	glog.Info(kwlog(fmt.Sprintf("Starting Kubernetes Worker")))
 	worker.Start(worker, 0)
 	return worker
 }
 
+// The readiness check for the kubernetes API server. It only applies to cluster nodes.
+func (w *KubeWorker) kubeReadiness() error {
+	if dev, err := persistence.FindExchangeDevice(w.db); err != nil || dev == nil || dev.GetNodeType() != persistence.DEVICE_TYPE_CLUSTER {
+		return nil
+	}
+
+	w.readinessLock.Lock()
+	defer w.readinessLock.Unlock()
+	if w.readinessClient == nil {
+		kubeConfig, err := cutil.NewKubeConfig()
+		if err != nil {
+			return err
+		}
+		kubeConfig.Timeout = worker.READINESS_CHECK_TIMEOUT
+		if w.readinessClient, err = kubernetes.NewForConfig(kubeConfig); err != nil {
+			return fmt.Errorf("unable to create kubernetes client, error: %v", err)
+		}
+	}
+
+	if _, err := w.readinessClient.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("unable to reach the kubernetes API server, error: %v", err)
+	}
+	return nil
+}
+
 func (w *KubeWorker) Messages() chan events.Message {
 	return w.BaseWorker.Manager.Messages
 }
@@ -93,6 +125,17 @@ func (w *KubeWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *KubeWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.AgreementReachedMessage{},
+		&events.GovernanceWorkloadCancelationMessage{},
+		&events.GovernanceMaintenanceMessage{},
+		&events.WorkloadUpdateMessage{},
+		&events.NodeShutdownCompleteMessage{},
+	}
+}
+
 func (w *KubeWorker) CommandHandler(command worker.Command) bool {
 	switch command.(type) {
 	case *InstallCommand:
@@ -18,6 +18,7 @@ import (
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/container"
 	"github.com/open-horizon/anax/download"
+	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/exchange"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
 	"github.com/open-horizon/anax/governance"
@@ -29,6 +30,7 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/resource"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"os"
 	"os/signal"
@@ -79,11 +81,26 @@ func main() {
 			panic(err)
 		}
 
-		edgeDB, err := bolt.Open(path.Join(cfg.Edge.DBPath, "anax.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+		// Give the space freed by deleted records, mostly pruned event logs, back to the filesystem. This can only be
+		// done while the database is closed.
+		dbFile := path.Join(cfg.Edge.DBPath, "anax.db")
+		var compacted int64
+		var compactErr error
+		if threshold := cfg.Edge.EventLog.GetCompactThresholdMB(); threshold >= 0 {
+			compacted, compactErr = persistence.CompactDatabase(dbFile, int64(threshold)*1024*1024)
+			if compactErr != nil {
+				glog.Warningf("Unable to compact the database %v: %v", dbFile, compactErr)
+			} else if compacted > 0 {
+				glog.Infof("Compacted the database %v, the file shrunk by %v bytes", dbFile, compacted)
+			}
+		}
+
+		edgeDB, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: 10 * time.Second})
 		if err != nil {
 			panic(err)
 		}
 		db = edgeDB
+		eventlog.LogDatabaseCompaction(db, compacted, compactErr)
 	}
 
 	// open Agreement Bot DB if necessary
@@ -105,6 +122,24 @@ func main() {
 	}
 	agbotSecrets = as
 
+	// Start tracing if an exporter is configured. The service name defaults to the role of this process.
+	if exporter, err := tracing.NewExporter(cfg.Tracing, cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil)); err != nil {
+		glog.Warningf("Unable to initialize tracing, continuing without tracing: %v", err)
+	} else if exporter != nil {
+		serviceName := cfg.Tracing.ServiceName
+		if serviceName == "" && db != nil {
+			serviceName = "anax-agent"
+		} else if serviceName == "" {
+			serviceName = "anax-agbot"
+		}
+		tracing.Start(serviceName, exporter)
+	}
+
+	// Forward the agent's event logs to the configured sinks.
+	if db != nil && len(cfg.Edge.EventLog.Sinks) != 0 {
+		eventlog.StartExport(db, cfg.Edge.EventLog.Sinks, cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil))
+	}
+
 	// start control signal handler
 	control := make(chan os.Signal, 1)
 	signal.Notify(control, os.Interrupt)
@@ -117,6 +152,7 @@ func main() {
 		glog.Infof("Closing up shop.")
 
 		pprof.StopCPUProfile()
+		eventlog.StopExport()
 		if db != nil {
 			db.Close()
 			// remove the local db
@@ -131,6 +167,7 @@ func main() {
 		if agbotDB != nil {
 			agbotDB.Close()
 		}
+		tracing.Stop()
 
 		os.Exit(0)
 	}()
@@ -163,8 +200,46 @@ func main() {
 	// Initialize the secrets manager to store secrets in the local db and in agent file system.
 	secretm := resource.NewSecretsManager(cfg, db)
 
+	// Set up the security of the messages exchanged between agents and agbots.
+	exchange.ConfigureMessaging(cfg.Messaging)
+
+	// Remember the nonces of the received messages across restarts, and across the agbots that share a database.
+	if db != nil {
+		exchange.ConfigureReplayStore(persistence.NewMessageReplayStore(db))
+	} else if agbotDB != nil {
+		exchange.ConfigureReplayStore(agbotDB)
+	}
+
+	// Protect the exchange from this process, an agbot makes many more calls than an agent.
+	exchange.ConfigureExchangeClient(cfg.ExchangeClient, db == nil)
+
+	// Cache the exchange resources that rarely change, so that a restart revalidates them instead of downloading them again.
+	if db != nil {
+		exchange.ConfigureResponseCache(persistence.NewExchangeResponseStore(db))
+	} else if agbotDB != nil {
+		exchange.ConfigureResponseCache(agbotDB)
+	}
+
+	// Queue the agent's writes to the exchange while the node is offline.
+	if db != nil {
+		exchange.ConfigureOutboundQueue(db)
+	}
+
+	// Journal the events that start the agent's workloads and services, so that they are replayed after a restart.
+	if db != nil {
+		if err := worker.ConfigureEventJournal(db, cfg.Edge.EventJournal); err != nil {
+			glog.Warningf("Unable to configure the event journal, continuing without it: %v", err)
+		}
+	}
+
+	// Allow the events passed between the workers to be streamed to debugging clients.
+	if err := worker.ConfigureEventTap(cfg.EventTap); err != nil {
+		glog.Warningf("Unable to configure the event tap, continuing without it: %v", err)
+	}
+
 	// start workers
 	workers := worker.NewMessageHandlerRegistry()
+	workers.SetEventQueueConfig(cfg.EventQueues)
 
 	workers.Add(agreementbot.NewAgreementBotWorker("AgBot", cfg, agbotDB, agbotSecrets))
 	if cfg.AgreementBot.APIListen != "" {
@@ -175,6 +250,9 @@ func main() {
 	}
 	if agbotDB != nil {
 		workers.Add(agreementbot.NewChangesWorker("AgBot ExchangeChanges", cfg))
+		for _, ex := range cfg.AgreementBot.Exchanges {
+			workers.Add(agreementbot.NewFederatedChangesWorker(fmt.Sprintf("AgBot ExchangeChanges %v", ex.Name), cfg, ex))
+		}
 	}
 
 	if db != nil {
@@ -193,6 +271,7 @@ func main() {
 		workers.Add(changes.NewChangesWorker("ExchangeChanges", cfg, db))
 		workers.Add(nodemanagement.NewNodeManagementWorker("NodeManagement", cfg, db))
 		workers.Add(download.NewDownloadWorker("Download", cfg, db))
+		workers.Add(eventlog.NewEventLogPruner("EventLogPruner", cfg, db))
 
 		// add cluster upgrade worker only when it is edge cluster
 		if cfg.Edge.DockerEndpoint == "" {
@@ -200,9 +279,13 @@ func main() {
 		}
 	}
 
+	// Watch for workers that stop processing their commands.
+	worker.GetWatchdog().Start(cfg.Watchdog)
+
 	// Get into the event processing loop until anax shuts itself down.
 	workers.ProcessEventMessages()
 
+	eventlog.StopExport()
 	if db != nil {
 		db.Close()
 
@@ -219,6 +302,7 @@ func main() {
 	if agbotDB != nil {
 		agbotDB.Close()
 	}
+	tracing.Stop()
 
 	glog.Info("Main process terminating")
 }
@@ -0,0 +1,490 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// A minimal implementation of the Prometheus data model (counters, gauges and histograms with labels) and the
+// Prometheus text exposition format. It is used by the agent and the agbot to expose their internal state on a /metrics
+// API so that standard monitoring tooling can scrape them.
+
+const (
+	COUNTER   = "counter"
+	GAUGE     = "gauge"
+	HISTOGRAM = "histogram"
+)
+
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// Buckets (in seconds) suitable for measuring the duration of network calls and internal processing steps.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Buckets (in bytes) suitable for measuring the size of downloaded content.
+var SizeBuckets = []float64{1024, 16 * 1024, 256 * 1024, 1024 * 1024, 16 * 1024 * 1024, 128 * 1024 * 1024, 512 * 1024 * 1024, 1024 * 1024 * 1024}
+
+// Every metric type implements this interface so that the registry can write it out.
+type Metric interface {
+	Name() string
+	Help() string
+	Type() string
+	write(w io.Writer) error
+}
+
+// The registry holds all the metrics known to the process.
+type Registry struct {
+	metrics map[string]Metric
+	lock    sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]Metric),
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+func GetRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register a metric. If a metric with the same name and type is already registered, the existing metric is returned
+// so that packages which are initialized more than once (e.g. in tests) share the same metric.
+func (r *Registry) Register(m Metric) Metric {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if existing, ok := r.metrics[m.Name()]; ok {
+		if existing.Type() != m.Type() {
+			glog.Errorf(mLogString(fmt.Sprintf("metric %v already registered as a %v, ignoring %v", m.Name(), existing.Type(), m.Type())))
+		}
+		return existing
+	}
+	r.metrics[m.Name()] = m
+	return m
+}
+
+// Write all registered metrics, sorted by name, in the Prometheus text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.lock.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	r.lock.Unlock()
+
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		r.lock.Lock()
+		m := r.metrics[name]
+		r.lock.Unlock()
+
+		if _, err := fmt.Fprintf(bw, "# HELP %v %v\n# TYPE %v %v\n", m.Name(), escapeHelp(m.Help()), m.Name(), m.Type()); err != nil {
+			return err
+		} else if err := m.write(bw); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// An HTTP handler that writes the default registry. The callers can provide functions that are called before the
+// metrics are written, so that gauges which are expensive to maintain continuously can be refreshed on demand.
+func Handler(refresh ...func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			for _, f := range refresh {
+				f()
+			}
+			w.Header().Set("Content-Type", CONTENT_TYPE)
+			w.WriteHeader(http.StatusOK)
+			if err := defaultRegistry.Write(w); err != nil {
+				glog.Errorf(mLogString(fmt.Sprintf("unable to write metrics, error: %v", err)))
+			}
+		case "OPTIONS":
+			w.Header().Set("Allow", "GET, OPTIONS")
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// The common parts of every metric with labels.
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+func (d *desc) Name() string {
+	return d.name
+}
+
+func (d *desc) Help() string {
+	return d.help
+}
+
+// Return the map key and the formatted label pairs for a set of label values. Missing values are treated as empty
+// strings and extra values are ignored so that a mis-instrumented call site never panics the caller.
+func (d *desc) labels(labelValues []string, extra ...string) (string, string) {
+	if len(labelValues) != len(d.labelNames) {
+		glog.Warningf(mLogString(fmt.Sprintf("metric %v expects labels %v, got values %v", d.name, d.labelNames, labelValues)))
+	}
+
+	values := make([]string, len(d.labelNames))
+	copy(values, labelValues)
+
+	pairs := make([]string, 0, len(values)+1)
+	for ix, ln := range d.labelNames {
+		pairs = append(pairs, fmt.Sprintf(`%v="%v"`, ln, escapeLabel(values[ix])))
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, fmt.Sprintf(`%v="%v"`, extra[0], escapeLabel(extra[1])))
+	}
+
+	return strings.Join(values, "\xff"), strings.Join(pairs, ",")
+}
+
+// A single value for a labelled counter or gauge.
+type sample struct {
+	labels string
+	value  float64
+}
+
+// A set of float values keyed by label values. Used by counters and gauges.
+type valueVec struct {
+	desc
+	mtype   string
+	samples map[string]*sample
+	lock    sync.Mutex
+}
+
+func (v *valueVec) Type() string {
+	return v.mtype
+}
+
+func (v *valueVec) update(labelValues []string, fn func(float64) float64) {
+	key, pairs := v.labels(labelValues)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	s, ok := v.samples[key]
+	if !ok {
+		s = &sample{labels: pairs}
+		v.samples[key] = s
+	}
+	s.value = fn(s.value)
+}
+
+func (v *valueVec) get(labelValues []string) float64 {
+	key, _ := v.labels(labelValues)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if s, ok := v.samples[key]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (v *valueVec) write(w io.Writer) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	for _, key := range sortedKeys(v.samples) {
+		s := v.samples[key]
+		if _, err := fmt.Fprintf(w, "%v%v %v\n", v.name, braces(s.labels), formatFloat(s.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A counter is a value that only goes up.
+type CounterVec struct {
+	valueVec
+}
+
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{
+		valueVec: valueVec{
+			desc:    desc{name: name, help: help, labelNames: labelNames},
+			mtype:   COUNTER,
+			samples: make(map[string]*sample),
+		},
+	}
+	if m, ok := defaultRegistry.Register(c).(*CounterVec); ok {
+		return m
+	}
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		glog.Warningf(mLogString(fmt.Sprintf("counter %v cannot be decreased by %v", c.name, value)))
+		return
+	}
+	c.update(labelValues, func(old float64) float64 { return old + value })
+}
+
+func (c *CounterVec) Get(labelValues ...string) float64 {
+	return c.get(labelValues)
+}
+
+// A gauge is a value that can go up and down.
+type GaugeVec struct {
+	valueVec
+}
+
+func NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{
+		valueVec: valueVec{
+			desc:    desc{name: name, help: help, labelNames: labelNames},
+			mtype:   GAUGE,
+			samples: make(map[string]*sample),
+		},
+	}
+	if m, ok := defaultRegistry.Register(g).(*GaugeVec); ok {
+		return m
+	}
+	return g
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.update(labelValues, func(old float64) float64 { return value })
+}
+
+func (g *GaugeVec) Add(value float64, labelValues ...string) {
+	g.update(labelValues, func(old float64) float64 { return old + value })
+}
+
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (g *GaugeVec) Get(labelValues ...string) float64 {
+	return g.get(labelValues)
+}
+
+// Remove all the samples from the gauge. Used when a gauge is recomputed from scratch, so that label values which no
+// longer exist disappear from the output.
+func (g *GaugeVec) Reset() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.samples = make(map[string]*sample)
+}
+
+// A gauge without labels whose value is obtained by calling a function when the metrics are written.
+type GaugeFunc struct {
+	desc
+	fn   func() float64
+	lock sync.Mutex
+}
+
+func NewGaugeFunc(name string, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{
+		desc: desc{name: name, help: help},
+		fn:   fn,
+	}
+	if m, ok := defaultRegistry.Register(g).(*GaugeFunc); ok {
+		// Replace the function of a previously registered gauge so that the newest owner of the gauge is reported.
+		m.SetFunction(fn)
+		return m
+	}
+	return g
+}
+
+func (g *GaugeFunc) Type() string {
+	return GAUGE
+}
+
+func (g *GaugeFunc) SetFunction(fn func() float64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.fn = fn
+}
+
+func (g *GaugeFunc) write(w io.Writer) error {
+	g.lock.Lock()
+	fn := g.fn
+	g.lock.Unlock()
+
+	if fn == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%v %v\n", g.name, formatFloat(fn()))
+	return err
+}
+
+// The observations for a single set of label values in a histogram.
+type histogramSample struct {
+	labelValues []string
+	counts      []uint64 // cumulative counts are computed when written, these are per bucket
+	count       uint64
+	sum         float64
+}
+
+// A histogram counts observations into configurable buckets.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	samples map[string]*histogramSample
+	lock    sync.Mutex
+}
+
+func NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, labelNames: labelNames},
+		buckets: b,
+		samples: make(map[string]*histogramSample),
+	}
+	if m, ok := defaultRegistry.Register(h).(*HistogramVec); ok {
+		return m
+	}
+	return h
+}
+
+func (h *HistogramVec) Type() string {
+	return HISTOGRAM
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key, _ := h.labels(labelValues)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s, ok := h.samples[key]
+	if !ok {
+		lv := make([]string, len(labelValues))
+		copy(lv, labelValues)
+		s = &histogramSample{labelValues: lv, counts: make([]uint64, len(h.buckets))}
+		h.samples[key] = s
+	}
+
+	for ix, upper := range h.buckets {
+		if value <= upper {
+			s.counts[ix] += 1
+			break
+		}
+	}
+	s.count += 1
+	s.sum += value
+}
+
+// Return the number of observations and their sum for the given label values.
+func (h *HistogramVec) Get(labelValues ...string) (uint64, float64) {
+	key, _ := h.labels(labelValues)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if s, ok := h.samples[key]; ok {
+		return s.count, s.sum
+	}
+	return 0, 0
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, key := range sortedKeys(h.samples) {
+		s := h.samples[key]
+		_, pairs := h.labels(s.labelValues)
+
+		cumulative := uint64(0)
+		for ix, upper := range h.buckets {
+			cumulative += s.counts[ix]
+			_, bucketPairs := h.labels(s.labelValues, "le", formatFloat(upper))
+			if _, err := fmt.Fprintf(w, "%v_bucket{%v} %v\n", h.name, bucketPairs, cumulative); err != nil {
+				return err
+			}
+		}
+		_, infPairs := h.labels(s.labelValues, "le", "+Inf")
+		if _, err := fmt.Fprintf(w, "%v_bucket{%v} %v\n", h.name, infPairs, s.count); err != nil {
+			return err
+		} else if _, err := fmt.Fprintf(w, "%v_sum%v %v\n", h.name, braces(pairs), formatFloat(s.sum)); err != nil {
+			return err
+		} else if _, err := fmt.Fprintf(w, "%v_count%v %v\n", h.name, braces(pairs), s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Utility functions
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+	switch samples := m.(type) {
+	case map[string]*sample:
+		for k := range samples {
+			keys = append(keys, k)
+		}
+	case map[string]*histogramSample:
+		for k := range samples {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func braces(pairs string) string {
+	if pairs == "" {
+		return ""
+	}
+	return "{" + pairs + "}"
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+var mLogString = func(v interface{}) string {
+	return fmt.Sprintf("Metrics: %v", v)
+}
@@ -0,0 +1,139 @@
+//go:build unit
+// +build unit
+
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Counter(t *testing.T) {
+	c := NewCounterVec("test_counter_total", "A test counter.", "org", "result")
+
+	c.Inc("org1", "sent")
+	c.Inc("org1", "sent")
+	c.Add(3, "org2", "rejected")
+	c.Add(-1, "org2", "rejected")
+
+	if v := c.Get("org1", "sent"); v != 2 {
+		t.Errorf("expected 2, got %v", v)
+	} else if v := c.Get("org2", "rejected"); v != 3 {
+		t.Errorf("expected 3, got %v", v)
+	} else if v := c.Get("org3", "sent"); v != 0 {
+		t.Errorf("expected 0, got %v", v)
+	}
+
+	// Registering the same metric again returns the original.
+	if c2 := NewCounterVec("test_counter_total", "A test counter.", "org", "result"); c2 != c {
+		t.Errorf("expected the registered counter to be returned")
+	}
+
+	out := writeRegistry(t)
+	if !strings.Contains(out, "# TYPE test_counter_total counter\n") {
+		t.Errorf("missing type line in %v", out)
+	} else if !strings.Contains(out, `test_counter_total{org="org1",result="sent"} 2`+"\n") {
+		t.Errorf("missing sample in %v", out)
+	} else if !strings.Contains(out, `test_counter_total{org="org2",result="rejected"} 3`+"\n") {
+		t.Errorf("missing sample in %v", out)
+	}
+}
+
+func Test_Gauge(t *testing.T) {
+	g := NewGaugeVec("test_gauge", "A test gauge.", "state")
+
+	g.Set(5, "active")
+	g.Dec("active")
+	g.Inc("archived")
+
+	if v := g.Get("active"); v != 4 {
+		t.Errorf("expected 4, got %v", v)
+	}
+
+	out := writeRegistry(t)
+	if !strings.Contains(out, `test_gauge{state="active"} 4`+"\n") {
+		t.Errorf("missing sample in %v", out)
+	}
+
+	g.Reset()
+	if out := writeRegistry(t); strings.Contains(out, `test_gauge{state="active"}`) {
+		t.Errorf("sample should have been reset in %v", out)
+	}
+
+	NewGaugeFunc("test_gauge_func", "A test gauge function.", func() float64 { return 7 })
+	if out := writeRegistry(t); !strings.Contains(out, "test_gauge_func 7\n") {
+		t.Errorf("missing gauge function in %v", out)
+	}
+}
+
+func Test_Histogram(t *testing.T) {
+	h := NewHistogramVec("test_duration_seconds", "A test histogram.", []float64{1, 0.1}, "method")
+
+	h.Observe(0.05, "GET")
+	h.Observe(0.5, "GET")
+	h.Observe(5, "GET")
+
+	if count, sum := h.Get("GET"); count != 3 || sum != 5.55 {
+		t.Errorf("expected 3 observations with sum 5.55, got %v and %v", count, sum)
+	}
+
+	out := writeRegistry(t)
+	expected := []string{
+		`test_duration_seconds_bucket{method="GET",le="0.1"} 1`,
+		`test_duration_seconds_bucket{method="GET",le="1"} 2`,
+		`test_duration_seconds_bucket{method="GET",le="+Inf"} 3`,
+		`test_duration_seconds_sum{method="GET"} 5.55`,
+		`test_duration_seconds_count{method="GET"} 3`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e+"\n") {
+			t.Errorf("missing %v in %v", e, out)
+		}
+	}
+}
+
+func Test_LabelEscaping(t *testing.T) {
+	c := NewCounterVec("test_escape_total", "Escaping.", "name")
+	c.Inc("a\"b\\c\nd")
+
+	if out := writeRegistry(t); !strings.Contains(out, `test_escape_total{name="a\"b\\c\nd"} 1`) {
+		t.Errorf("label not escaped in %v", out)
+	}
+}
+
+func Test_Handler(t *testing.T) {
+	refreshed := false
+	NewCounterVec("test_handler_total", "Handler.").Inc()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	Handler(func() { refreshed = true })(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %v", rr.Code)
+	} else if !refreshed {
+		t.Errorf("refresh function was not called")
+	} else if rr.Header().Get("Content-Type") != CONTENT_TYPE {
+		t.Errorf("wrong content type %v", rr.Header().Get("Content-Type"))
+	} else if !strings.Contains(rr.Body.String(), "test_handler_total 1\n") {
+		t.Errorf("missing sample in %v", rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/metrics", nil)
+	rr = httptest.NewRecorder()
+	Handler()(rr, req)
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %v", rr.Code)
+	}
+}
+
+func writeRegistry(t *testing.T) string {
+	var buf bytes.Buffer
+	if err := GetRegistry().Write(&buf); err != nil {
+		t.Fatalf("error writing registry: %v", err)
+	}
+	return buf.String()
+}
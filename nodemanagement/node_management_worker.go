@@ -424,6 +424,19 @@ func (n *NodeManagementWorker) NewEvent(incoming events.Message) {
 	}
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (n *NodeManagementWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.EdgeConfigCompleteMessage{},
+		&events.NodeShutdownCompleteMessage{},
+		&events.NodeShutdownMessage{},
+		&events.NMPDownloadCompleteMessage{},
+		&events.ExchangeChangeMessage{},
+		&events.ImageFetchMessage{},
+	}
+}
+
 func VerifyCompatible(nodePol *externalpolicy.ExternalPolicy, nodePattern string, nmPol *exchangecommon.ExchangeNodeManagementPolicy) (bool, error) {
 	if nodePattern != "" || len(nmPol.Patterns) > 0 {
 		if cutil.SliceContains(nmPol.Patterns, nodePattern) {
@@ -0,0 +1,169 @@
+package persistence
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+)
+
+// The bucket that holds the events that were dispatched to the workers but whose work has not finished yet. They are
+// replayed when the agent restarts.
+const EVENT_JOURNAL = "event_journal"
+
+// An event in the journal. The key identifies the work that the event is part of, e.g. the launch of an agreement's
+// workload. An event replaces the journaled event with the same key, so only the latest step of the work is kept.
+type JournaledEvent struct {
+	Seq       uint64          `json:"seq"`
+	Type      string          `json:"type"`
+	Key       string          `json:"key"`
+	Payload   json.RawMessage `json:"payload"`
+	Journaled int64           `json:"journaled"`
+}
+
+func (e JournaledEvent) String() string {
+	return fmt.Sprintf("Seq: %v, Type: %v, Key: %v, Journaled: %v, Payload: %v bytes", e.Seq, e.Type, e.Key, e.Journaled, len(e.Payload))
+}
+
+func eventJournalKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// Collect the journaled events with the given key, and their db keys. Events can't be deleted while iterating the
+// bucket.
+func findJournaledEvents(b *bolt.Bucket, key string) ([][]byte, []JournaledEvent, error) {
+	keys := make([][]byte, 0)
+	journaled := make([]JournaledEvent, 0)
+	err := b.ForEach(func(k, v []byte) error {
+		var e JournaledEvent
+		if err := json.Unmarshal(v, &e); err != nil {
+			glog.Errorf("Unable to demarshal journaled event, error: %v", err)
+		} else if e.Key == key {
+			keys = append(keys, append([]byte{}, k...))
+			journaled = append(journaled, e)
+		}
+		return nil
+	})
+	return keys, journaled, err
+}
+
+// Add an event to the end of the journal, replacing the journaled event with the same key. Returns the sequence number
+// of the new journal entry.
+func JournalEvent(db *bolt.DB, eventType string, key string, payload json.RawMessage) (uint64, error) {
+	var seq uint64
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(EVENT_JOURNAL))
+		if err != nil {
+			return err
+		}
+
+		if replaced, _, err := findJournaledEvents(b, key); err != nil {
+			return err
+		} else {
+			for _, k := range replaced {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+
+		if seq, err = b.NextSequence(); err != nil {
+			return err
+		}
+		e := JournaledEvent{
+			Seq:       seq,
+			Type:      eventType,
+			Key:       key,
+			Payload:   payload,
+			Journaled: time.Now().Unix(),
+		}
+		if serial, err := json.Marshal(e); err != nil {
+			return fmt.Errorf("Failed to serialize journaled event %v, error: %v", e, err)
+		} else {
+			return b.Put(eventJournalKey(seq), serial)
+		}
+	})
+
+	return seq, writeErr
+}
+
+// Returns the journaled events in the order they were journaled.
+func FindJournaledEvents(db *bolt.DB) ([]JournaledEvent, error) {
+	journaled := make([]JournaledEvent, 0)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EVENT_JOURNAL)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var e JournaledEvent
+				if err := json.Unmarshal(v, &e); err != nil {
+					glog.Errorf("Unable to demarshal journaled event, error: %v", err)
+				} else {
+					journaled = append(journaled, e)
+				}
+				return nil
+			})
+		}
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return journaled, nil
+}
+
+// Remove the journaled events with the given key, because the work they are part of has finished. Returns the events
+// that were removed.
+func DeleteJournaledEvents(db *bolt.DB, key string) ([]JournaledEvent, error) {
+	var deleted []JournaledEvent
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_JOURNAL))
+		if b == nil {
+			return nil
+		}
+
+		keys, done, err := findJournaledEvents(b, key)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return fmt.Errorf("Unable to delete journaled event %v, error: %v", binary.BigEndian.Uint64(k), err)
+			}
+		}
+		deleted = done
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func DeleteJournaledEvent(db *bolt.DB, seq uint64) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EVENT_JOURNAL)); b == nil {
+			return nil
+		} else if err := b.Delete(eventJournalKey(seq)); err != nil {
+			return fmt.Errorf("Unable to delete journaled event %v, error: %v", seq, err)
+		}
+		return nil
+	})
+}
+
+// Remove all the journaled events, e.g. when the node is unregistered.
+func DeleteEventJournal(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EVENT_JOURNAL)); b == nil {
+			return nil
+		} else {
+			return tx.DeleteBucket([]byte(EVENT_JOURNAL))
+		}
+	})
+}
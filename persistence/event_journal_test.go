@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+package persistence
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JournalEvent_replaces_same_key(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+
+	_, err = JournalEvent(db, "AgreementReachedMessage", "agreement/ag1", json.RawMessage(`{"a":1}`))
+	assert.Nil(t, err)
+	_, err = JournalEvent(db, "AgreementReachedMessage", "agreement/ag2", json.RawMessage(`{"a":2}`))
+	assert.Nil(t, err)
+	seq, err := JournalEvent(db, "ImageFetchMessage", "agreement/ag1", json.RawMessage(`{"a":3}`))
+	assert.Nil(t, err)
+
+	// The image fetch replaced the first agreement reached event, and is last in the journal.
+	journaled, err := FindJournaledEvents(db)
+	assert.Nil(t, err)
+	if assert.Len(t, journaled, 2) {
+		assert.Equal(t, "agreement/ag2", journaled[0].Key)
+		assert.Equal(t, "agreement/ag1", journaled[1].Key)
+		assert.Equal(t, "ImageFetchMessage", journaled[1].Type)
+		assert.Equal(t, seq, journaled[1].Seq)
+		assert.JSONEq(t, `{"a":3}`, string(journaled[1].Payload))
+	}
+}
+
+func Test_DeleteJournaledEvents(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+
+	// Nothing to delete before anything is journaled.
+	deleted, err := DeleteJournaledEvents(db, "agreement/ag1")
+	assert.Nil(t, err)
+	assert.Len(t, deleted, 0)
+
+	_, err = JournalEvent(db, "AgreementReachedMessage", "agreement/ag1", json.RawMessage(`{}`))
+	assert.Nil(t, err)
+	seq, err := JournalEvent(db, "ImageFetchMessage", "service/svc1", json.RawMessage(`{}`))
+	assert.Nil(t, err)
+
+	deleted, err = DeleteJournaledEvents(db, "agreement/ag1")
+	assert.Nil(t, err)
+	if assert.Len(t, deleted, 1) {
+		assert.Equal(t, "AgreementReachedMessage", deleted[0].Type)
+	}
+
+	assert.Nil(t, DeleteJournaledEvent(db, seq))
+	journaled, err := FindJournaledEvents(db)
+	assert.Nil(t, err)
+	assert.Len(t, journaled, 0)
+
+	_, err = JournalEvent(db, "ImageFetchMessage", "service/svc1", json.RawMessage(`{}`))
+	assert.Nil(t, err)
+	assert.Nil(t, DeleteEventJournal(db))
+	journaled, err = FindJournaledEvents(db)
+	assert.Nil(t, err)
+	assert.Len(t, journaled, 0)
+}
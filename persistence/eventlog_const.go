@@ -26,6 +26,12 @@ const (
 	EC_API_USER_INPUT_ERROR = "api_user_input_error"
 	EC_EXCHANGE_ERROR       = "exchange_error"
 
+	// event log retention
+	EC_EVENTLOG_PRUNED        = "eventlog_pruned"
+	EC_ERROR_EVENTLOG_PRUNE   = "error_eventlog_prune"
+	EC_DATABASE_COMPACTED     = "database_compacted"
+	EC_ERROR_DATABASE_COMPACT = "error_database_compact"
+
 	// initialization
 	EC_ERROR_CONTAINER_SYNC_ON_INIT = "error_container_sync_on_init"
 	EC_ERROR_AGREEMENT_SYNC_ON_INIT = "error_agreement_sync_on_init"
@@ -60,11 +66,20 @@ const (
 	EC_NODE_KEEP_OLD_PATTERN           = "node_keep_old_pattern"
 	EC_NEW_PATTERN_VERIFIED            = "new_pattern_verified"
 
+	// worker watchdog, the first message argument of these events is the worker name
+	EC_WORKER_STUCK     = "worker_stuck"
+	EC_WORKER_BACKLOG   = "worker_backlog"
+	EC_WORKER_RECOVERED = "worker_recovered"
+
 	// node unreggistratin
 	EC_START_NODE_UNREG    = "start_node_unregistration"
 	EC_NODE_UNREG_COMPLETE = "node_unregistration_complete"
 	EC_ERROR_NODE_UNREG    = "error_node_unregistration"
 
+	// node messaging key
+	EC_NODE_MESSAGE_KEY_ROTATED        = "node_message_key_rotated"
+	EC_ERROR_NODE_MESSAGE_KEY_ROTATION = "error_node_message_key_rotation"
+
 	// node heartbeat
 	EC_NODE_HEARTBEAT_FAILED   = "node_heartbeat_failed"
 	EC_NODE_HEARTBEAT_RESTORED = "node_heartbeat_restored"
@@ -0,0 +1,325 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/i18n"
+	"golang.org/x/text/message"
+)
+
+// The index of the event logs by time. The keys are the timestamp followed by the record id, both big endian, so that
+// the keys are in the order the logs were written. The values are the record ids.
+const EVENT_LOGS_BY_TIME = "event_logs_by_time"
+
+const (
+	EVENTLOG_ORDER_ASC  = "asc"
+	EVENTLOG_ORDER_DESC = "desc"
+)
+
+// The time window, order and page of an event log query.
+type EventLogQuery struct {
+	Since  uint64 // Only the logs at or after this time, in seconds since the epoch. 0 for no lower bound.
+	Until  uint64 // Only the logs at or before this time, in seconds since the epoch. 0 for no upper bound.
+	Limit  int    // The most logs returned. 0 for no limit.
+	Cursor string // Continue from the log after the cursor that was returned with the previous page.
+	Order  string // The order of the logs by time, asc (the default) or desc.
+}
+
+// The query parameters of the /eventlog APIs of the agent and the agbot that are not selectors.
+const (
+	EVENTLOG_PARAM_SINCE  = "since"
+	EVENTLOG_PARAM_UNTIL  = "until"
+	EVENTLOG_PARAM_LIMIT  = "limit"
+	EVENTLOG_PARAM_CURSOR = "cursor"
+	EVENTLOG_PARAM_ORDER  = "order"
+)
+
+// Take the time window, limit, cursor and order out of the query parameters of an /eventlog API, so that the rest are
+// selectors. The agent and the agbot page their event logs differently, so the input function checks the cursor.
+func GetEventLogQuery(selections map[string][]string, now time.Time, validateCursor func(cursor string) error, msgPrinter *message.Printer) (*EventLogQuery, error) {
+	if msgPrinter == nil {
+		msgPrinter = i18n.GetMessagePrinter()
+	}
+	query := new(EventLogQuery)
+
+	param := func(name string) string {
+		val := ""
+		if vals, ok := selections[name]; ok && len(vals) > 0 {
+			val = vals[0]
+		}
+		delete(selections, name)
+		return val
+	}
+
+	var err error
+	if query.Since, err = ParseEventLogTime(param(EVENTLOG_PARAM_SINCE), now); err != nil {
+		return nil, fmt.Errorf("%s", msgPrinter.Sprintf("Error parsing the %v parameter: %v", EVENTLOG_PARAM_SINCE, err))
+	} else if query.Until, err = ParseEventLogTime(param(EVENTLOG_PARAM_UNTIL), now); err != nil {
+		return nil, fmt.Errorf("%s", msgPrinter.Sprintf("Error parsing the %v parameter: %v", EVENTLOG_PARAM_UNTIL, err))
+	}
+
+	if limit := param(EVENTLOG_PARAM_LIMIT); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 0 {
+			return nil, fmt.Errorf("%s", msgPrinter.Sprintf("Error parsing the %v parameter: %v is not a positive number", EVENTLOG_PARAM_LIMIT, limit))
+		}
+	}
+
+	query.Cursor = param(EVENTLOG_PARAM_CURSOR)
+	if err := validateCursor(query.Cursor); err != nil {
+		return nil, fmt.Errorf("%s", msgPrinter.Sprintf("Error parsing the %v parameter: %v", EVENTLOG_PARAM_CURSOR, err))
+	}
+	query.Order = strings.ToLower(param(EVENTLOG_PARAM_ORDER))
+	if query.Order != "" && query.Order != EVENTLOG_ORDER_ASC && query.Order != EVENTLOG_ORDER_DESC {
+		return nil, fmt.Errorf("%s", msgPrinter.Sprintf("Error parsing the %v parameter: it must be %v or %v", EVENTLOG_PARAM_ORDER, EVENTLOG_ORDER_ASC, EVENTLOG_ORDER_DESC))
+	}
+
+	return query, nil
+}
+
+// A time can be seconds since the epoch, an RFC3339 time, or a duration such as 1h or 30m meaning that long ago.
+func ParseEventLogTime(val string, now time.Time) (uint64, error) {
+	if val == "" {
+		return 0, nil
+	} else if secs, err := strconv.ParseUint(val, 10, 64); err == nil {
+		return secs, nil
+	} else if t, err := time.Parse(time.RFC3339, val); err == nil {
+		return uint64(t.Unix()), nil
+	} else if d, err := time.ParseDuration(val); err == nil && d >= 0 {
+		return uint64(now.Add(-d).Unix()), nil
+	}
+	return 0, fmt.Errorf("%v is not seconds since the epoch, an RFC3339 time or a duration", val)
+}
+
+func (q EventLogQuery) String() string {
+	return fmt.Sprintf("Since: %v, Until: %v, Limit: %v, Cursor: %v, Order: %v", q.Since, q.Until, q.Limit, q.Cursor, q.Order)
+}
+
+// Returns true if the query has no time window, limit, cursor or order.
+func (q EventLogQuery) IsEmpty() bool {
+	return q.Since == 0 && q.Until == 0 && q.Limit == 0 && q.Cursor == "" && q.Order == ""
+}
+
+// Returns the index key of an event log. The record id is the decimal form of the bucket sequence number.
+func eventLogIndexKey(timestamp uint64, id string) []byte {
+	seq, _ := strconv.ParseUint(id, 10, 64)
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], timestamp)
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func indexEventLog(tx *bolt.Tx, timestamp uint64, id string) error {
+	if b, err := tx.CreateBucketIfNotExists([]byte(EVENT_LOGS_BY_TIME)); err != nil {
+		return err
+	} else {
+		return b.Put(eventLogIndexKey(timestamp, id), []byte(id))
+	}
+}
+
+func unindexEventLog(tx *bolt.Tx, timestamp uint64, id string) error {
+	if b := tx.Bucket([]byte(EVENT_LOGS_BY_TIME)); b != nil {
+		return b.Delete(eventLogIndexKey(timestamp, id))
+	}
+	return nil
+}
+
+// The databases whose event log index has been checked by this process.
+var eventLogIndexChecked sync.Map
+
+// Build the time index of the event logs, for a database from before the index existed, or rebuild it if it does not
+// have an entry for each event log, e.g. when an older agent saved logs without indexing them. The index is checked
+// once per process.
+func buildEventLogIndex(db *bolt.DB) error {
+	if _, checked := eventLogIndexChecked.Load(db); checked {
+		return nil
+	}
+
+	count := 0
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_LOGS))
+		if b == nil {
+			return nil
+		}
+		if idx := tx.Bucket([]byte(EVENT_LOGS_BY_TIME)); idx != nil && idx.Stats().KeyN == b.Stats().KeyN {
+			return nil
+		} else if idx != nil {
+			if err := tx.DeleteBucket([]byte(EVENT_LOGS_BY_TIME)); err != nil {
+				return err
+			}
+		}
+
+		idx, err := tx.CreateBucket([]byte(EVENT_LOGS_BY_TIME))
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var el EventLogBase
+			if err := json.Unmarshal(v, &el); err != nil {
+				glog.Errorf("Unable to deserialize event log db record: %v. Error: %v", v, err)
+				return nil
+			}
+			count++
+			return idx.Put(eventLogIndexKey(el.Timestamp, string(k)), k)
+		})
+	})
+	if err != nil {
+		return err
+	} else if count > 0 {
+		glog.V(3).Infof("Built the time index of %v event logs", count)
+	}
+	eventLogIndexChecked.Store(db, true)
+	return nil
+}
+
+// Returns the index key that a cursor from FindEventLogsPage points at, nil for an empty cursor.
+func ParseEventLogCursor(cursor string) ([]byte, error) {
+	if cursor == "" {
+		return nil, nil
+	} else if c, err := hex.DecodeString(cursor); err != nil || len(c) != 16 {
+		return nil, fmt.Errorf("The cursor %v is not valid.", cursor)
+	} else {
+		return c, nil
+	}
+}
+
+// Find the event logs that match the selectors within the time window of the query, in time order, a page at a time.
+// If all_logs is false, only the event logs for the current registration are returned. Returns the cursor of the next
+// page, which is empty when there are no more logs.
+func FindEventLogsPage(db *bolt.DB, all_logs bool, selectors map[string][]Selector, query EventLogQuery, msgPrinter *message.Printer) ([]EventLog, string, error) {
+	// separate base selectors from the source selectors
+	base_selectors, source_selectors := GroupSelectors(selectors)
+
+	cursor, err := ParseEventLogCursor(query.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if query.Order != "" && query.Order != EVENTLOG_ORDER_ASC && query.Order != EVENTLOG_ORDER_DESC {
+		return nil, "", fmt.Errorf("The order %v is not valid, it must be %v or %v.", query.Order, EVENTLOG_ORDER_ASC, EVENTLOG_ORDER_DESC)
+	}
+	desc := query.Order == EVENTLOG_ORDER_DESC
+
+	// The logs of the current registration are the ones after the last unregistration.
+	since := query.Since
+	if !all_logs {
+		if last_unreg, err := GetLastUnregistrationTime(db); err != nil {
+			return nil, "", fmt.Errorf("Faild to get the last unregistration time stamp from db. %v", err)
+		} else if last_unreg+1 > since {
+			since = last_unreg + 1
+		}
+	}
+
+	if msgPrinter == nil {
+		msgPrinter = i18n.GetMessagePrinter()
+	}
+
+	if err := buildEventLogIndex(db); err != nil {
+		return nil, "", err
+	}
+
+	lower := make([]byte, 16)
+	binary.BigEndian.PutUint64(lower[:8], since)
+	upper := bytes.Repeat([]byte{0xff}, 16)
+	if query.Until != 0 {
+		binary.BigEndian.PutUint64(upper[:8], query.Until)
+	}
+
+	evlogs := make([]EventLog, 0)
+	next := ""
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_LOGS))
+		idx := tx.Bucket([]byte(EVENT_LOGS_BY_TIME))
+		if b == nil || idx == nil {
+			return nil
+		}
+
+		c := idx.Cursor()
+		var k, v []byte
+		inRange := func(k []byte) bool {
+			return k != nil && bytes.Compare(k, lower) >= 0 && bytes.Compare(k, upper) <= 0
+		}
+
+		// Position the cursor on the first index entry of the page.
+		if desc {
+			start := upper
+			if cursor != nil && bytes.Compare(cursor, upper) < 0 {
+				start = cursor
+			}
+			if k, v = c.Seek(start); k == nil {
+				k, v = c.Last()
+			}
+			for k != nil && (bytes.Compare(k, start) > 0 || (cursor != nil && bytes.Equal(k, cursor))) {
+				k, v = c.Prev()
+			}
+		} else {
+			start := lower
+			if cursor != nil && bytes.Compare(cursor, lower) > 0 {
+				start = cursor
+			}
+			k, v = c.Seek(start)
+			if cursor != nil && bytes.Equal(k, cursor) {
+				k, v = c.Next()
+			}
+		}
+
+		for ; inRange(k); k, v = step(c, desc) {
+			if query.Limit > 0 && len(evlogs) >= query.Limit {
+				next = hex.EncodeToString(eventLogIndexKey(evlogs[len(evlogs)-1].Timestamp, evlogs[len(evlogs)-1].Id))
+				break
+			}
+
+			raw := b.Get(v)
+			if raw == nil {
+				continue
+			}
+
+			var el EventLogRaw
+			if err := json.Unmarshal(raw, &el); err != nil {
+				glog.Errorf("Unable to deserialize event log db record: %v. Error: %v", raw, err)
+				continue
+			}
+
+			// Use the given message printer to translate the message saved in MessageMeta and save it to Message.
+			if el.MessageMeta != nil && el.MessageMeta.MessageKey != "" {
+				el.Message = msgPrinter.Sprintf(el.MessageMeta.MessageKey, el.MessageMeta.MessageArgs...)
+				// set MessageMeta to nil so that it will not get displayed.
+				el.MessageMeta = nil
+			}
+
+			if el.EventLogBase.Matches(base_selectors) {
+				if esrc, err := GetRealEventSource(el.SourceType, el.Source); err != nil {
+					glog.Errorf("Unable to convert event source: %v. Error: %v", el.Source, err)
+				} else if (*esrc).Matches(source_selectors) {
+					pel := newEventLog1(el.Severity, el.Message, el.MessageMeta, el.EventCode, el.SourceType, *esrc)
+					pel.Id = el.Id
+					pel.Timestamp = el.Timestamp
+					evlogs = append(evlogs, *pel)
+				}
+			}
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		return nil, "", readErr
+	}
+	return evlogs, next, nil
+}
+
+func step(c *bolt.Cursor, desc bool) ([]byte, []byte) {
+	if desc {
+		return c.Prev()
+	}
+	return c.Next()
+}
@@ -0,0 +1,118 @@
+//go:build unit
+// +build unit
+
+package persistence
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func eventLogPageIds(logs []EventLog) []string {
+	ids := make([]string, 0, len(logs))
+	for _, el := range logs {
+		ids = append(ids, el.Id)
+	}
+	return ids
+}
+
+func Test_FindEventLogsPage(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	// Logs 1 to 5, one hour apart, the oldest first.
+	first := saveTestEventLog(t, db, SEVERITY_INFO, EC_DATABASE_ERROR, 5*3600)
+	for age := uint64(4); age >= 1; age-- {
+		saveTestEventLog(t, db, SEVERITY_INFO, EC_DATABASE_ERROR, age*3600)
+	}
+	now := first.Timestamp + 5*3600
+
+	// The time window.
+	logs, next, err := FindEventLogsPage(db, true, nil, EventLogQuery{Since: now - 3*3600, Until: now - 2*3600}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "4"}, eventLogPageIds(logs), "The logs in the time window should be returned.")
+	assert.Equal(t, "", next, "There should be no next page.")
+
+	// Pages in ascending order.
+	logs, next, err = FindEventLogsPage(db, true, nil, EventLogQuery{Limit: 2}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "2"}, eventLogPageIds(logs))
+	logs, next, err = FindEventLogsPage(db, true, nil, EventLogQuery{Limit: 2, Cursor: next}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "4"}, eventLogPageIds(logs))
+	logs, next, err = FindEventLogsPage(db, true, nil, EventLogQuery{Limit: 2, Cursor: next}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"5"}, eventLogPageIds(logs))
+	assert.Equal(t, "", next, "The last page should have no cursor.")
+
+	// Pages in descending order, with a selector.
+	logs, next, err = FindEventLogsPage(db, true, map[string][]Selector{"severity": {{Op: "=", MatchValue: SEVERITY_INFO}}}, EventLogQuery{Limit: 3, Order: EVENTLOG_ORDER_DESC}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"5", "4", "3"}, eventLogPageIds(logs))
+	logs, next, err = FindEventLogsPage(db, true, nil, EventLogQuery{Limit: 3, Order: EVENTLOG_ORDER_DESC, Cursor: next}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2", "1"}, eventLogPageIds(logs))
+
+	// The logs of previous registrations are left out.
+	assert.Nil(t, SaveLastUnregistrationTime(db, now-3*3600))
+	logs, _, err = FindEventLogsPage(db, false, nil, EventLogQuery{}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"4", "5"}, eventLogPageIds(logs))
+
+	_, _, err = FindEventLogsPage(db, true, nil, EventLogQuery{Cursor: "bogus"}, nil)
+	assert.NotNil(t, err, "The cursor is not valid.")
+}
+
+func Test_FindEventLogsPage_index(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		saveTestEventLog(t, db, SEVERITY_INFO, EC_DATABASE_ERROR, 0)
+	}
+
+	// A database from before the index existed is indexed by the first query.
+	db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte(EVENT_LOGS_BY_TIME))
+	})
+	logs, _, err := FindEventLogsPage(db, true, nil, EventLogQuery{}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(logs), "All the logs should be indexed.")
+
+	// Deleted logs are removed from the index.
+	count, err := DeleteEventLogsWithSelectors(db, map[string][]Selector{"record_id": {{Op: "=", MatchValue: "2"}}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	db.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, 2, tx.Bucket([]byte(EVENT_LOGS_BY_TIME)).Stats().KeyN, "The index should have an entry for each log.")
+		return nil
+	})
+}
+
+func Test_GetEventLogQuery(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	validCursor := func(cursor string) error { return nil }
+
+	selections := map[string][]string{"since": {"2023-11-14T21:13:20Z"}, "until": {"30m"}, "cursor": {"c1"}, "order": {"Asc"}, "severity": {"error"}}
+	query, err := GetEventLogQuery(selections, now, validCursor, nil)
+	assert.Nil(t, err, "The query should be valid.")
+	assert.Equal(t, EventLogQuery{Since: 1700000000 - 3600, Until: 1700000000 - 1800, Cursor: "c1", Order: EVENTLOG_ORDER_ASC}, *query)
+	assert.Equal(t, map[string][]string{"severity": {"error"}}, selections, "Only the selectors should be left.")
+
+	_, err = GetEventLogQuery(map[string][]string{"cursor": {"c1"}}, now, func(cursor string) error { return errors.New("bad cursor") }, nil)
+	assert.NotNil(t, err, "The cursor is checked by the caller's function.")
+	_, err = GetEventLogQuery(map[string][]string{"until": {"-1h"}}, now, validCursor, nil)
+	assert.NotNil(t, err, "A negative duration is not valid.")
+}
@@ -0,0 +1,249 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+)
+
+// The limits that the event logs are pruned to.
+type EventLogRetention struct {
+	MaxAgeS  map[string]int64 // The age, in seconds, at which the logs of a severity are deleted. Logs of a severity that is not in the map, or whose age is negative, are kept regardless of their age.
+	MaxCount int              // The most event logs kept. Negative or 0 for no limit.
+	MaxBytes int              // The most space the event logs can take in the database. Negative or 0 for no limit.
+}
+
+func (r EventLogRetention) String() string {
+	return fmt.Sprintf("MaxAgeS: %v, MaxCount: %v, MaxBytes: %v", r.MaxAgeS, r.MaxCount, r.MaxBytes)
+}
+
+// The event logs deleted by a prune.
+type EventLogPruneResult struct {
+	Expired    int            // The logs that were deleted because of their age.
+	OverLimit  int            // The logs that were deleted to get under the count and size limits.
+	Bytes      int            // The space that the deleted logs took in the database.
+	BySeverity map[string]int // The deleted logs by severity.
+	Remaining  int            // The logs that are left.
+}
+
+func (r EventLogPruneResult) String() string {
+	return fmt.Sprintf("Expired: %v, OverLimit: %v, Bytes: %v, BySeverity: %v, Remaining: %v", r.Expired, r.OverLimit, r.Bytes, r.BySeverity, r.Remaining)
+}
+
+func (r EventLogPruneResult) Deleted() int {
+	return r.Expired + r.OverLimit
+}
+
+// The order in which logs are deleted to get under the count and size limits, the lowest first.
+func severityRank(severity string) int {
+	switch severity {
+	case SEVERITY_FATAL:
+		return 3
+	case SEVERITY_ERROR:
+		return 2
+	case SEVERITY_WARN:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// An event log as much as the pruning needs to know about it.
+type eventLogEntry struct {
+	key       []byte
+	seq       uint64
+	timestamp uint64
+	severity  string
+	size      int
+}
+
+// Delete the event logs that are older than their severity's maximum age. Then, if there are still more logs than
+// the maximum count, or they take more than the maximum space, delete the oldest logs of the lowest severity until
+// they are under the limits. The logs of the errors currently surfaced to the exchange are kept.
+func PruneEventLogs(db *bolt.DB, retention EventLogRetention, now time.Time) (*EventLogPruneResult, error) {
+	surfaced := make(map[string]bool)
+	if surfaceErrors, err := FindSurfaceErrors(db); err != nil {
+		return nil, err
+	} else {
+		for _, se := range surfaceErrors {
+			surfaced[se.Record_id] = true
+		}
+	}
+
+	result := &EventLogPruneResult{BySeverity: make(map[string]int)}
+	nowS := uint64(now.Unix())
+
+	dbErr := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_LOGS))
+		if b == nil {
+			return nil
+		}
+
+		// Find the expired logs, and size up the rest. The keys are decimal sequence numbers, so the bucket is not in
+		// the order the logs were written.
+		expired := make([]eventLogEntry, 0)
+		kept := make([]eventLogEntry, 0)
+		count, bytes := 0, 0
+		if err := b.ForEach(func(k, v []byte) error {
+			var el EventLogBase
+			if err := json.Unmarshal(v, &el); err != nil {
+				glog.Errorf("Unable to deserialize event log db record: %v. Error: %v", v, err)
+				return nil
+			}
+			seq, _ := strconv.ParseUint(string(k), 10, 64)
+			entry := eventLogEntry{key: append([]byte{}, k...), seq: seq, timestamp: el.Timestamp, severity: el.Severity, size: len(k) + len(v)}
+			count++
+			bytes += entry.size
+			if surfaced[string(k)] {
+				return nil
+			} else if maxAge, ok := retention.MaxAgeS[el.Severity]; ok && maxAge >= 0 && el.Timestamp+uint64(maxAge) < nowS {
+				expired = append(expired, entry)
+			} else {
+				kept = append(kept, entry)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, entry := range expired {
+			if err := b.Delete(entry.key); err != nil {
+				return err
+			} else if err := unindexEventLog(tx, entry.timestamp, string(entry.key)); err != nil {
+				return err
+			}
+			result.Expired++
+			result.Bytes += entry.size
+			result.BySeverity[entry.severity]++
+			count--
+			bytes -= entry.size
+		}
+
+		// Get under the limits, deleting the oldest logs of the lowest severity first.
+		sort.Slice(kept, func(i, j int) bool {
+			if ri, rj := severityRank(kept[i].severity), severityRank(kept[j].severity); ri != rj {
+				return ri < rj
+			}
+			return kept[i].seq < kept[j].seq
+		})
+		for _, entry := range kept {
+			if (retention.MaxCount <= 0 || count <= retention.MaxCount) && (retention.MaxBytes <= 0 || bytes <= retention.MaxBytes) {
+				break
+			} else if err := b.Delete(entry.key); err != nil {
+				return err
+			} else if err := unindexEventLog(tx, entry.timestamp, string(entry.key)); err != nil {
+				return err
+			}
+			result.OverLimit++
+			result.Bytes += entry.size
+			result.BySeverity[entry.severity]++
+			count--
+			bytes -= entry.size
+		}
+
+		result.Remaining = count
+		return nil
+	})
+
+	if dbErr != nil {
+		return nil, dbErr
+	}
+	return result, nil
+}
+
+// Returns the free space in the database, i.e. the pages that were freed by deletes. Bolt reuses free pages but does
+// not give them back to the filesystem, so the database file only shrinks when it is compacted. The free space is
+// only known once a write transaction has ended, an empty one is rolled back to get it.
+func DatabaseFreeBytes(db *bolt.DB) (int64, error) {
+	if tx, err := db.Begin(true); err != nil {
+		return 0, err
+	} else if err := tx.Rollback(); err != nil {
+		return 0, err
+	}
+	return int64(db.Stats().FreeAlloc), nil
+}
+
+// Rewrite the database file at dbPath without its free pages, if it has more than thresholdBytes of free space. The
+// database must not be open. The buckets are copied into a new file which then replaces the database file, so a
+// failure leaves the database as it was. Returns the number of bytes the file shrunk by, 0 if it was not compacted.
+func CompactDatabase(dbPath string, thresholdBytes int64) (int64, error) {
+	info, err := os.Stat(dbPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	src, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	if free, err := DatabaseFreeBytes(src); err != nil {
+		return 0, err
+	} else if free <= thresholdBytes {
+		glog.V(3).Infof("Database %v has %v bytes of free space, not compacting it", dbPath, free)
+		return 0, nil
+	}
+
+	tmpPath := dbPath + ".compact"
+	os.Remove(tmpPath)
+	dst, err := bolt.Open(tmpPath, info.Mode(), &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return 0, err
+	}
+
+	copyErr := src.View(func(stx *bolt.Tx) error {
+		return dst.Update(func(dtx *bolt.Tx) error {
+			return stx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				if nb, err := dtx.CreateBucket(name); err != nil {
+					return err
+				} else {
+					return copyBucket(b, nb)
+				}
+			})
+		})
+	})
+	if err := dst.Close(); copyErr == nil && err != nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("Unable to compact database %v, error: %v", dbPath, copyErr)
+	}
+
+	src.Close()
+	newInfo, err := os.Stat(tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	} else if err := os.Rename(tmpPath, dbPath); err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+	return info.Size() - newInfo.Size(), nil
+}
+
+// Copy the keys, values and nested buckets of a bucket, keeping its sequence number.
+func copyBucket(src *bolt.Bucket, dst *bolt.Bucket) error {
+	if err := src.ForEach(func(k, v []byte) error {
+		if v == nil {
+			if nested, err := dst.CreateBucket(k); err != nil {
+				return err
+			} else {
+				return copyBucket(src.Bucket(k), nested)
+			}
+		}
+		return dst.Put(k, v)
+	}); err != nil {
+		return err
+	}
+	return dst.SetSequence(src.Sequence())
+}
@@ -0,0 +1,148 @@
+//go:build unit
+// +build unit
+
+package persistence
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+// Save an event log with the given severity and age.
+func saveTestEventLog(t *testing.T, db *bolt.DB, severity string, code string, ageS uint64) *EventLog {
+	source := NewNodeEventSource("node1", "myorg", "", CONFIGSTATE_CONFIGURED)
+	el := NewEventLog(severity, NewMessageMeta("test event %v", code), code, SRC_TYPE_NODE, source)
+	el.Timestamp = uint64(time.Now().Unix()) - ageS
+	if err := SaveEventLog(db, el); err != nil {
+		t.Fatalf("Error saving eventlog into db. %v", err)
+	}
+	return el
+}
+
+func eventLogIds(t *testing.T, db *bolt.DB) map[string]string {
+	logs, err := FindAllEventLogs(db)
+	if err != nil {
+		t.Fatalf("Error retrieving eventlogs. %v", err)
+	}
+	ids := make(map[string]string)
+	for _, el := range logs {
+		ids[el.Id] = el.Severity
+	}
+	return ids
+}
+
+func Test_PruneEventLogs_age(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+
+	oldInfo := saveTestEventLog(t, db, SEVERITY_INFO, EC_NODE_UPDATE_COMPLETE, 7200)
+	oldError := saveTestEventLog(t, db, SEVERITY_ERROR, EC_ERROR_NODE_UPDATE, 7200)
+	newInfo := saveTestEventLog(t, db, SEVERITY_INFO, EC_NODE_UPDATE_COMPLETE, 0)
+
+	// Info logs expire after an hour, errors are kept regardless of their age.
+	retention := EventLogRetention{MaxAgeS: map[string]int64{SEVERITY_INFO: 3600, SEVERITY_ERROR: -1}}
+	result, err := PruneEventLogs(db, retention, time.Now())
+	assert.Nil(t, err, "Error pruning eventlogs.")
+	assert.Equal(t, 1, result.Expired, "Only the old info log should expire.")
+	assert.Equal(t, 1, result.BySeverity[SEVERITY_INFO], "The expired log is an info log.")
+	assert.Equal(t, 2, result.Remaining, "Two logs should be left.")
+
+	ids := eventLogIds(t, db)
+	assert.NotContains(t, ids, oldInfo.Id, "The old info log should be deleted.")
+	assert.Contains(t, ids, oldError.Id, "The old error log should be kept.")
+	assert.Contains(t, ids, newInfo.Id, "The new info log should be kept.")
+}
+
+func Test_PruneEventLogs_limits(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+
+	// Enough logs that the keys are not in numeric order in the bucket.
+	logs := make([]*EventLog, 0)
+	for i := 0; i < 12; i++ {
+		severity := SEVERITY_INFO
+		if i%3 == 0 {
+			severity = SEVERITY_WARN
+		}
+		logs = append(logs, saveTestEventLog(t, db, severity, EC_NODE_UPDATE_COMPLETE, 0))
+	}
+
+	// A surfaced error is kept even though it is over the count limit.
+	stuck := saveTestEventLog(t, db, SEVERITY_ERROR, EC_WORKER_STUCK, 0)
+	surfaced, _ := FindSurfaceErrors(db)
+	assert.Equal(t, 1, len(surfaced), "The stuck worker error should be surfaced.")
+
+	// The info logs go first, oldest first, then the warnings.
+	result, err := PruneEventLogs(db, EventLogRetention{MaxCount: 3}, time.Now())
+	assert.Nil(t, err, "Error pruning eventlogs.")
+	assert.Equal(t, 10, result.OverLimit, "All but 3 logs should be deleted.")
+	assert.Equal(t, 8, result.BySeverity[SEVERITY_INFO], "All the info logs should be deleted.")
+	assert.Equal(t, 2, result.BySeverity[SEVERITY_WARN], "The oldest warnings should be deleted.")
+
+	ids := eventLogIds(t, db)
+	assert.Equal(t, 3, len(ids), "3 logs should be left.")
+	assert.Contains(t, ids, stuck.Id, "The surfaced error should be kept.")
+	assert.Contains(t, ids, logs[6].Id, "The second newest warning should be kept.")
+	assert.Contains(t, ids, logs[9].Id, "The newest warning should be kept.")
+
+	// The size limit deletes the warnings too, but not the surfaced error.
+	result, err = PruneEventLogs(db, EventLogRetention{MaxBytes: 1}, time.Now())
+	assert.Nil(t, err, "Error pruning eventlogs.")
+	assert.Equal(t, 2, result.OverLimit, "The warnings should be deleted.")
+	assert.Equal(t, 1, result.Remaining, "The surfaced error should be left.")
+}
+
+func Test_CompactDatabase(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	dbPath := path.Join(dir, "anax-ut.db")
+
+	for i := 0; i < 500; i++ {
+		saveTestEventLog(t, db, SEVERITY_INFO, EC_NODE_UPDATE_COMPLETE, 0)
+	}
+	kept := saveTestEventLog(t, db, SEVERITY_ERROR, EC_ERROR_NODE_UPDATE, 0)
+	if _, err := PruneEventLogs(db, EventLogRetention{MaxCount: 1}, time.Now()); err != nil {
+		t.Fatalf("Error pruning eventlogs. %v", err)
+	}
+	free, err := DatabaseFreeBytes(db)
+	assert.Nil(t, err, "Error getting the free space.")
+	assert.True(t, free > 0, "The pruned logs should leave free space.")
+	db.Close()
+
+	// Not compacted below the threshold.
+	shrunk, err := CompactDatabase(dbPath, free)
+	assert.Nil(t, err, "Error compacting the database.")
+	assert.Equal(t, int64(0), shrunk, "The database should not be compacted.")
+
+	before, _ := os.Stat(dbPath)
+	shrunk, err = CompactDatabase(dbPath, 0)
+	assert.Nil(t, err, "Error compacting the database.")
+	after, _ := os.Stat(dbPath)
+	assert.True(t, shrunk > 0 && after.Size() == before.Size()-shrunk, "The database file should shrink.")
+
+	// The records and the sequence survive the compaction.
+	db, err = bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ids := eventLogIds(t, db)
+	assert.Equal(t, 1, len(ids), "One log should be left.")
+	assert.Contains(t, ids, kept.Id, "The error log should be kept.")
+	next := saveTestEventLog(t, db, SEVERITY_INFO, EC_NODE_UPDATE_COMPLETE, 0)
+	assert.Equal(t, "502", next.Id, "The event log ids should continue after the compaction.")
+}
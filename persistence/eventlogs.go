@@ -249,8 +249,10 @@ func SaveEventLog(db *bolt.DB, event_log *EventLog) error {
 			serial, err := json.Marshal(*event_log)
 			if err != nil {
 				return fmt.Errorf("Failed to serialize the event log: %v. Error: %v", *event_log, err)
+			} else if err := bucket.Put([]byte(strKey), serial); err != nil {
+				return err
 			}
-			return bucket.Put([]byte(strKey), serial)
+			return indexEventLog(tx, event_log.Timestamp, strKey)
 		}
 	})
 
@@ -436,6 +438,7 @@ func DeleteEventLogsWithSelectors(db *bolt.DB, selectors map[string][]Selector,
 							glog.Errorf("Unable to convert event source: %v. Error: %v", el.Source, err)
 						} else if (*esrc).Matches(source_selectors) {
 							b.Delete(k)
+							unindexEventLog(tx, el.Timestamp, string(k))
 							count++
 						}
 					}
@@ -452,65 +455,8 @@ func DeleteEventLogsWithSelectors(db *bolt.DB, selectors map[string][]Selector,
 // find event logs from the db for the given given selectors.
 // If all_logs is false, only the event logs for the current registration is returned.
 func FindEventLogsWithSelectors(db *bolt.DB, all_logs bool, selectors map[string][]Selector, msgPrinter *message.Printer) ([]EventLog, error) {
-	// separate base selectors from the source selectors
-	base_selectors, source_selectors := GroupSelectors(selectors)
-
-	evlogs := make([]EventLog, 0)
-
-	last_unreg := uint64(0)
-	if !all_logs {
-		if l, err := GetLastUnregistrationTime(db); err != nil {
-			return nil, fmt.Errorf("Faild to get the last unregistration time stamp from db. %v", err)
-		} else {
-			last_unreg = l
-		}
-	}
-
-	if msgPrinter == nil {
-		msgPrinter = i18n.GetMessagePrinter()
-	}
-
-	// fetch logs
-	readErr := db.View(func(tx *bolt.Tx) error {
-
-		if b := tx.Bucket([]byte(EVENT_LOGS)); b != nil {
-			b.ForEach(func(k, v []byte) error {
-
-				var el EventLogRaw
-
-				if err := json.Unmarshal(v, &el); err != nil {
-					glog.Errorf("Unable to deserialize event log db record: %v. Error: %v", v, err)
-				} else {
-					// Use the given message printer to translate the message saved in MessageMeta and save it to Message.
-					if el.MessageMeta != nil && el.MessageMeta.MessageKey != "" {
-						el.Message = msgPrinter.Sprintf(el.MessageMeta.MessageKey, el.MessageMeta.MessageArgs...)
-						// set MessageMeta to nil so that it will not get displayed.
-						el.MessageMeta = nil
-					}
-
-					if (all_logs || el.Timestamp > last_unreg) && el.EventLogBase.Matches(base_selectors) {
-						if esrc, err := GetRealEventSource(el.SourceType, el.Source); err != nil {
-							glog.Errorf("Unable to convert event source: %v. Error: %v", el.Source, err)
-						} else if (*esrc).Matches(source_selectors) {
-							pel := newEventLog1(el.Severity, el.Message, el.MessageMeta, el.EventCode, el.SourceType, *esrc)
-							pel.Id = el.Id
-							pel.Timestamp = el.Timestamp
-							evlogs = append(evlogs, *pel)
-						}
-					}
-				}
-				return nil
-			})
-		}
-
-		return nil // end the transaction
-	})
-
-	if readErr != nil {
-		return nil, readErr
-	} else {
-		return evlogs, nil
-	}
+	evlogs, _, err := FindEventLogsPage(db, all_logs, selectors, EventLogQuery{}, msgPrinter)
+	return evlogs, err
 }
 
 // find all event logs from the db
@@ -476,3 +476,37 @@ func Test_SaveLastUnregistrationTime(t *testing.T) {
 	assert.False(t, e8.Matches(selectors), "Test eventlog Matches.")
 
 }
+
+func Test_WorkerSurfaceErrors(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	source := NewNodeEventSource("node1", "myorg", "", CONFIGSTATE_CONFIGURED)
+	stuck1 := NewEventLog(SEVERITY_ERROR, NewMessageMeta("Worker %v has been handling %v for %v seconds.", "Governance", "*governance.CleanupExecutionCommand", 400), EC_WORKER_STUCK, SRC_TYPE_NODE, source)
+	stuck2 := NewEventLog(SEVERITY_ERROR, NewMessageMeta("Worker %v has been handling %v for %v seconds.", "Container", "*container.WorkloadConfigureCommand", 300), EC_WORKER_STUCK, SRC_TYPE_NODE, source)
+	other := NewEventLog(SEVERITY_ERROR, NewMessageMeta("some error"), EC_ERROR_NODE_UPDATE, SRC_TYPE_NODE, source)
+	recovered := NewEventLog(SEVERITY_INFO, NewMessageMeta("Worker %v has recovered.", "Governance"), EC_WORKER_RECOVERED, SRC_TYPE_NODE, source)
+
+	for _, e := range []*EventLog{stuck1, stuck2, other} {
+		if err := SaveEventLog(db, e); err != nil {
+			t.Errorf("Error saving eventlog into db. %v", err)
+		}
+	}
+
+	surfaceErrors, err := FindSurfaceErrors(db)
+	assert.Nil(t, err, "Error retrieving surface errors.")
+	assert.Equal(t, 2, len(surfaceErrors), "Only the watchdog node errors should be surfaced.")
+
+	if err := SaveEventLog(db, recovered); err != nil {
+		t.Errorf("Error saving eventlog into db. %v", err)
+	}
+
+	surfaceErrors, err = FindSurfaceErrors(db)
+	assert.Nil(t, err, "Error retrieving surface errors.")
+	assert.Equal(t, 1, len(surfaceErrors), "The recovered worker's error should be cleared.")
+	assert.Equal(t, stuck2.Id, surfaceErrors[0].Record_id, "The other worker's error should still be surfaced.")
+}
@@ -0,0 +1,64 @@
+package persistence
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+)
+
+// The bucket that holds the cached responses of exchange resources, so that they can be revalidated with conditional
+// requests instead of downloaded again after a restart. The responses are serialized by the exchange package.
+const EXCHANGE_RESPONSES = "exchange_responses"
+
+type ExchangeResponseStore struct {
+	db *bolt.DB
+}
+
+func NewExchangeResponseStore(db *bolt.DB) *ExchangeResponseStore {
+	return &ExchangeResponseStore{db: db}
+}
+
+func (s *ExchangeResponseStore) LoadExchangeResponses() (map[string][]byte, error) {
+	responses := make(map[string][]byte)
+
+	readErr := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EXCHANGE_RESPONSES)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				// The slices are only valid during the transaction.
+				responses[string(k)] = append([]byte{}, v...)
+				return nil
+			})
+		}
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	glog.V(5).Infof("Loaded %v cached exchange responses", len(responses))
+	return responses, nil
+}
+
+func (s *ExchangeResponseStore) SaveExchangeResponse(key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if b, err := tx.CreateBucketIfNotExists([]byte(EXCHANGE_RESPONSES)); err != nil {
+			return err
+		} else if err := b.Put([]byte(key), value); err != nil {
+			return fmt.Errorf("Failed to save cached exchange response %v, error: %v", key, err)
+		}
+		return nil
+	})
+}
+
+func (s *ExchangeResponseStore) DeleteExchangeResponse(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EXCHANGE_RESPONSES)); b == nil {
+			return nil
+		} else if err := b.Delete([]byte(key)); err != nil {
+			return fmt.Errorf("Unable to delete cached exchange response %v, error: %v", key, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+)
+
+// The buckets that hold the nonces of the messages received from the agbots, and the agbots that are known to send
+// timestamps, so that a message cannot be replayed to the agent after a restart.
+const MESSAGE_NONCES = "message_nonces"
+const TIMESTAMP_SENDERS = "timestamp_senders"
+
+type messageNonce struct {
+	MsgId   int   `json:"msg_id"`
+	Expires int64 `json:"expires"`
+}
+
+type MessageReplayStore struct {
+	db *bolt.DB
+}
+
+func NewMessageReplayStore(db *bolt.DB) *MessageReplayStore {
+	return &MessageReplayStore{db: db}
+}
+
+// Save the nonce if it has not been saved yet, and return the exchange message id that it was first saved with.
+func (s *MessageReplayStore) SaveMessageNonce(nonce string, msgId int, expires int64) (int, error) {
+	firstId := msgId
+
+	writeErr := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(MESSAGE_NONCES))
+		if err != nil {
+			return err
+		}
+
+		if existing := b.Get([]byte(nonce)); existing != nil {
+			var mn messageNonce
+			if err := json.Unmarshal(existing, &mn); err != nil {
+				return fmt.Errorf("Unable to demarshal message nonce %v, error: %v", nonce, err)
+			}
+			firstId = mn.MsgId
+			return nil
+		}
+
+		if serial, err := json.Marshal(messageNonce{MsgId: msgId, Expires: expires}); err != nil {
+			return fmt.Errorf("Failed to serialize message nonce %v, error: %v", nonce, err)
+		} else if err := b.Put([]byte(nonce), serial); err != nil {
+			return fmt.Errorf("Failed to save message nonce %v, error: %v", nonce, err)
+		}
+		return nil
+	})
+
+	return firstId, writeErr
+}
+
+func (s *MessageReplayStore) DeleteExpiredMessageNonces(now int64) error {
+	deleted := 0
+
+	writeErr := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MESSAGE_NONCES))
+		if b == nil {
+			return nil
+		}
+
+		// Keys cannot be deleted while iterating with ForEach, so collect them first.
+		expired := make([][]byte, 0, 10)
+		if err := b.ForEach(func(k, v []byte) error {
+			var mn messageNonce
+			if err := json.Unmarshal(v, &mn); err != nil {
+				glog.Errorf("Unable to demarshal message nonce %s, deleting it, error: %v", k, err)
+				expired = append(expired, append([]byte{}, k...))
+			} else if mn.Expires < now {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return fmt.Errorf("Unable to delete message nonce %s, error: %v", k, err)
+			}
+		}
+		deleted = len(expired)
+		return nil
+	})
+
+	if writeErr == nil && deleted != 0 {
+		glog.V(5).Infof("Deleted %v expired message nonces", deleted)
+	}
+	return writeErr
+}
+
+func (s *MessageReplayStore) SaveTimestampSender(fingerprint string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if b, err := tx.CreateBucketIfNotExists([]byte(TIMESTAMP_SENDERS)); err != nil {
+			return err
+		} else if err := b.Put([]byte(fingerprint), []byte{1}); err != nil {
+			return fmt.Errorf("Failed to save message sender %v, error: %v", fingerprint, err)
+		}
+		return nil
+	})
+}
+
+func (s *MessageReplayStore) IsTimestampSender(fingerprint string) (bool, error) {
+	known := false
+
+	readErr := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(TIMESTAMP_SENDERS)); b != nil {
+			known = b.Get([]byte(fingerprint)) != nil
+		}
+		return nil // end transaction
+	})
+
+	return known, readErr
+}
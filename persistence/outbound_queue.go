@@ -0,0 +1,136 @@
+package persistence
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+)
+
+// The bucket that holds the writes to the exchange that were made while the node was offline. They are replayed in
+// order when the node reconnects to the exchange.
+const OUTBOUND_QUEUE = "outbound_queue"
+
+// A write to the exchange waiting for the node to reconnect. A write replaces the queued write with the same key, so
+// only the latest state of a resource is sent to the exchange.
+type QueuedExchangeWrite struct {
+	Seq    uint64          `json:"seq"`
+	Key    string          `json:"key"`
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Queued int64           `json:"queued"`
+}
+
+func (w QueuedExchangeWrite) String() string {
+	return fmt.Sprintf("Seq: %v, Key: %v, Method: %v, URL: %v, Queued: %v, Body: %v bytes", w.Seq, w.Key, w.Method, w.URL, w.Queued, len(w.Body))
+}
+
+func outboundQueueKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// Add a write to the end of the queue, replacing the queued write with the same key. Returns true if a queued write
+// was replaced.
+func QueueExchangeWrite(db *bolt.DB, key string, method string, url string, body json.RawMessage) (bool, error) {
+	replaced := false
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(OUTBOUND_QUEUE))
+		if err != nil {
+			return err
+		}
+
+		// Writes can't be deleted while iterating the bucket.
+		coalesced := make([][]byte, 0)
+		if err := b.ForEach(func(k, v []byte) error {
+			var w QueuedExchangeWrite
+			if err := json.Unmarshal(v, &w); err != nil {
+				glog.Errorf("Unable to demarshal queued exchange write, error: %v", err)
+			} else if w.Key == key {
+				coalesced = append(coalesced, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range coalesced {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			replaced = true
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		w := QueuedExchangeWrite{
+			Seq:    seq,
+			Key:    key,
+			Method: method,
+			URL:    url,
+			Body:   body,
+			Queued: time.Now().Unix(),
+		}
+		if serial, err := json.Marshal(w); err != nil {
+			return fmt.Errorf("Failed to serialize queued exchange write %v, error: %v", w, err)
+		} else {
+			return b.Put(outboundQueueKey(seq), serial)
+		}
+	})
+
+	return replaced, writeErr
+}
+
+// Returns the queued writes in the order they were queued.
+func FindQueuedExchangeWrites(db *bolt.DB) ([]QueuedExchangeWrite, error) {
+	writes := make([]QueuedExchangeWrite, 0)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(OUTBOUND_QUEUE)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var w QueuedExchangeWrite
+				if err := json.Unmarshal(v, &w); err != nil {
+					glog.Errorf("Unable to demarshal queued exchange write, error: %v", err)
+				} else {
+					writes = append(writes, w)
+				}
+				return nil
+			})
+		}
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return writes, nil
+}
+
+func DeleteQueuedExchangeWrite(db *bolt.DB, seq uint64) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(OUTBOUND_QUEUE)); b == nil {
+			return nil
+		} else if err := b.Delete(outboundQueueKey(seq)); err != nil {
+			return fmt.Errorf("Unable to delete queued exchange write %v, error: %v", seq, err)
+		}
+		return nil
+	})
+}
+
+// Remove all the queued writes, e.g. when the node is unregistered.
+func DeleteQueuedExchangeWrites(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(OUTBOUND_QUEUE)); b == nil {
+			return nil
+		} else {
+			return tx.DeleteBucket([]byte(OUTBOUND_QUEUE))
+		}
+	})
+}
@@ -86,7 +86,10 @@ func DeleteSurfaceErrors(db *bolt.DB) error {
 
 // NewErrorLog takes an eventLog object and puts it in the local db and exchange if it should be surfaced
 func NewErrorLog(db *bolt.DB, eventLog EventLog) bool {
-	if !IsSurfaceType(eventLog.EventCode) || !(eventLog.SourceType == SRC_TYPE_AG || eventLog.SourceType == SRC_TYPE_SVC) {
+	if eventLog.EventCode == EC_WORKER_RECOVERED {
+		return clearWorkerErrors(db, eventLog)
+	}
+	if !IsSurfaceType(eventLog.EventCode) || !(eventLog.SourceType == SRC_TYPE_AG || eventLog.SourceType == SRC_TYPE_SVC || (eventLog.SourceType == SRC_TYPE_NODE && isNodeSurfaceType(eventLog.EventCode))) {
 		return false
 	}
 	currentErrors, err := FindSurfaceErrors(db)
@@ -115,6 +118,73 @@ func NewErrorLog(db *bolt.DB, eventLog EventLog) bool {
 	return true
 }
 
+// Node errors are not about a workload, they are surfaced until the condition that caused them is resolved. The only
+// node errors that are surfaced are the ones raised by the worker watchdog.
+func isNodeSurfaceType(errorType string) bool {
+	return errorType == EC_WORKER_STUCK || errorType == EC_WORKER_BACKLOG
+}
+
+// Remove the surfaced watchdog errors for the worker that has recovered.
+func clearWorkerErrors(db *bolt.DB, recovered EventLog) bool {
+	worker := workerOf(recovered.MessageMeta)
+	if worker == "" {
+		return false
+	}
+
+	currentErrors, err := FindSurfaceErrors(db)
+	if err != nil {
+		glog.V(3).Infof("Error getting surface errors from local db. %v", err)
+		return false
+	}
+
+	remainingErrors := make([]SurfaceError, 0, len(currentErrors))
+	for _, currentError := range currentErrors {
+		if isNodeSurfaceType(currentError.Event_code) && workerOf(findMessageMeta(db, currentError.Record_id)) == worker {
+			continue
+		}
+		remainingErrors = append(remainingErrors, currentError)
+	}
+	if len(remainingErrors) == len(currentErrors) {
+		return false
+	}
+
+	if err = SaveSurfaceErrors(db, remainingErrors); err != nil {
+		glog.V(3).Infof("Error saving surface errors to local db. %v", err)
+	}
+	return true
+}
+
+// Return the name of the worker that a watchdog event log is about, given the event log's message.
+func workerOf(meta *MessageMeta) string {
+	if meta == nil || len(meta.MessageArgs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v", meta.MessageArgs[0])
+}
+
+// Return the untranslated message of an event log. The message is not available from GetEventLogObject because the
+// event logs it returns have already been translated.
+func findMessageMeta(db *bolt.DB, recordID string) *MessageMeta {
+	var el struct {
+		MessageMeta *MessageMeta `json:"message_meta,omitempty"`
+	}
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EVENT_LOGS)); b != nil {
+			if v := b.Get([]byte(recordID)); v != nil {
+				return json.Unmarshal(v, &el)
+			}
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		glog.V(3).Infof("Error reading event log %v from local db. %v", recordID, readErr)
+		return nil
+	}
+	return el.MessageMeta
+}
+
 // getErrorTypeList returns a slice containing the error types to surface to the exchange
 func getErrorTypeList() []string {
 	return []string{
@@ -127,6 +197,8 @@ func getErrorTypeList() []string {
 		EC_ERROR_START_SERVICE,
 		EC_ERROR_START_DEPENDENT_SERVICE,
 		EC_DEPENDENT_SERVICE_FAILED,
+		EC_WORKER_STUCK,
+		EC_WORKER_BACKLOG,
 	}
 
 }
@@ -21,7 +21,6 @@ import (
 	"github.com/open-horizon/anax/worker"
 	"strconv"
 	"strings"
-	"time"
 )
 
 const (
@@ -155,7 +154,7 @@ func (w *BaseProducerProtocolHandler) sendMessage(mt interface{}, pay []byte) er
 
 		httpClientFactory := w.ec.GetHTTPFactory()
 		retryCount := httpClientFactory.RetryCount
-		retryInterval := httpClientFactory.GetRetryInterval()
+		retryBackoff := exchange.NewRetryBackoff(httpClientFactory, targetURL)
 
 		for {
 			if err, tpErr := exchange.InvokeExchange(httpClientFactory.NewHTTPClient(nil), "POST", targetURL, w.ec.GetExchangeId(), w.ec.GetExchangeToken(), pm, &resp); err != nil {
@@ -163,13 +162,13 @@ func (w *BaseProducerProtocolHandler) sendMessage(mt interface{}, pay []byte) er
 			} else if tpErr != nil {
 				glog.Warningf(tpErr.Error())
 				if httpClientFactory.RetryCount == 0 {
-					time.Sleep(time.Duration(retryInterval) * time.Second)
+					retryBackoff.Wait()
 					continue
 				} else if retryCount == 0 {
 					return errors.New(fmt.Sprintf("exceeded %v retries trying to retrieve agbot for %v", httpClientFactory.RetryCount, tpErr))
 				} else {
 					retryCount--
-					time.Sleep(time.Duration(retryInterval) * time.Second)
+					retryBackoff.Wait()
 					continue
 				}
 			} else {
@@ -623,7 +622,7 @@ func (w *BaseProducerProtocolHandler) getAgbot(agbotId string, url string, devic
 
 	httpClientFactory := w.ec.GetHTTPFactory()
 	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
+	retryBackoff := exchange.NewRetryBackoff(httpClientFactory, targetURL)
 
 	for {
 		if err, tpErr := exchange.InvokeExchange(httpClientFactory.NewHTTPClient(nil), "GET", targetURL, deviceId, token, nil, &resp); err != nil {
@@ -632,13 +631,13 @@ func (w *BaseProducerProtocolHandler) getAgbot(agbotId string, url string, devic
 		} else if tpErr != nil {
 			glog.Warningf(BPPHlogString(w.Name(), tpErr.Error()))
 			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			} else if retryCount == 0 {
 				return nil, fmt.Errorf("%s", fmt.Sprintf("exceeded %v retries trying to retrieve agbot for %v", httpClientFactory.RetryCount, tpErr))
 			} else {
 				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				retryBackoff.Wait()
 				continue
 			}
 		} else {
@@ -52,11 +52,14 @@ func (auth *FSSAuthenticate) Authenticate(request *http.Request) (int, string, s
 	ok, vers, err := auth.AuthMgr.Authenticate(authId, appSecret)
 	if err != nil {
 		glog.Errorf(essALS(fmt.Sprintf("unable to verify %v, error %v", authId, err)))
+		observeESSRequest(request, ESS_AUTH_FAILED)
 		return authCode, "", ""
 	} else if !ok {
 		glog.Errorf(essALS(fmt.Sprintf("credentials for %v are not valid", authId)))
+		observeESSRequest(request, ESS_AUTH_FAILED)
 		return authCode, "", ""
 	}
+	observeESSRequest(request, ESS_AUTH_SUCCEEDED)
 
 	// The service identity is authenticated.
 	authCode = security.AuthAdmin
@@ -0,0 +1,38 @@
+package resource
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/open-horizon/anax/metrics"
+)
+
+// Embedded ESS API metrics, exposed by the agent's /metrics API.
+const (
+	ESS_AUTH_SUCCEEDED = "success"
+	ESS_AUTH_FAILED    = "failure"
+)
+
+var essAPIRequests = metrics.NewCounterVec("anax_ess_api_requests_total",
+	"Requests from services to the embedded ESS API, by HTTP method, operation (e.g. data for object data downloads) and authentication result.", "method", "operation", "result")
+
+// Record a request from a service to the ESS API.
+func observeESSRequest(request *http.Request, result string) {
+	operation := "other"
+	if request.URL != nil {
+		parts := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+		last := parts[len(parts)-1]
+		switch last {
+		case "data", "received", "consumed", "deleted", "status", "destinations":
+			operation = last
+		default:
+			for _, p := range parts {
+				if p == "objects" {
+					operation = "objects"
+					break
+				}
+			}
+		}
+	}
+	essAPIRequests.Inc(request.Method, operation, result)
+}
@@ -90,6 +90,14 @@ func (w *ResourceWorker) NewEvent(incoming events.Message) {
 	return
 }
 
+// The events handled by NewEvent, the other events are not queued for this worker.
+func (w *ResourceWorker) EventSubscriptions() []events.Message {
+	return []events.Message{
+		&events.EdgeRegisteredExchangeMessage{},
+		&events.NodeShutdownMessage{},
+	}
+}
+
 // Handle commands that are placed on the command queue.
 func (w *ResourceWorker) CommandHandler(command worker.Command) bool {
 
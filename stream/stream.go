@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// A stream sends the values that a component publishes, e.g. event logs or the events passed between the workers, to
+// the clients that subscribe to them as they are published. A client that does not keep up misses values rather than
+// slowing down the publisher.
+
+// How often an empty line is sent on an idle stream, so that the clients and any proxy or ssh tunnel in between do not
+// close the connection.
+const HEARTBEAT_S = 30
+
+// A subscription to the values published by a Broadcaster. The values are delivered on the Events channel, which is
+// closed when the subscription is closed. The values that were not delivered because the channel was full are counted
+// in Dropped.
+type Subscription[T any] struct {
+	Events      chan T
+	broadcaster *Broadcaster[T]
+	accept      func(v T) bool
+	lock        sync.Mutex
+	dropped     int
+	closed      bool
+}
+
+// Returns the number of values that were not delivered because the Events channel was full.
+func (s *Subscription[T]) Dropped() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.dropped
+}
+
+// Stop receiving values and close the Events channel.
+func (s *Subscription[T]) Close() {
+	s.broadcaster.lock.Lock()
+	delete(s.broadcaster.subscriptions, s)
+	s.broadcaster.lock.Unlock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.Events)
+	}
+}
+
+func (s *Subscription[T]) deliver(v T) {
+	if s.accept != nil && !s.accept(v) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.Events <- v:
+	default:
+		s.dropped++
+		if s.broadcaster.onDrop != nil {
+			s.broadcaster.onDrop()
+		}
+	}
+}
+
+// Sends the published values to its subscriptions.
+type Broadcaster[T any] struct {
+	name          string
+	bufferSize    int
+	onDrop        func()
+	lock          sync.Mutex
+	subscriptions map[*Subscription[T]]bool
+}
+
+// The name is used in the log messages, the buffer size is used when a subscriber does not choose one, and the onDrop
+// function, which can be nil, is called for every value that is not delivered to a subscriber.
+func NewBroadcaster[T any](name string, bufferSize int, onDrop func()) *Broadcaster[T] {
+	return &Broadcaster[T]{
+		name:          name,
+		bufferSize:    bufferSize,
+		onDrop:        onDrop,
+		subscriptions: make(map[*Subscription[T]]bool),
+	}
+}
+
+// Subscribe to the values published from now on that are accepted by the input function, or all of them when it is
+// nil. The caller must close the subscription when it is done with it.
+func (b *Broadcaster[T]) Subscribe(bufferSize int, accept func(v T) bool) *Subscription[T] {
+	if bufferSize <= 0 {
+		bufferSize = b.bufferSize
+	}
+	sub := &Subscription[T]{Events: make(chan T, bufferSize), broadcaster: b, accept: accept}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscriptions[sub] = true
+	return sub
+}
+
+// Returns true when there is at least one subscription, so that a publisher can skip building a value nobody reads.
+func (b *Broadcaster[T]) HasSubscribers() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.subscriptions) != 0
+}
+
+// Send a value to all the subscriptions.
+func (b *Broadcaster[T]) Publish(v T) {
+	b.lock.Lock()
+	subs := make([]*Subscription[T], 0, len(b.subscriptions))
+	for sub := range b.subscriptions {
+		subs = append(subs, sub)
+	}
+	b.lock.Unlock()
+
+	for _, sub := range subs {
+		sub.deliver(v)
+	}
+}
+
+// Write the values received by a subscription to the output, one JSON object per line, until done is closed, the
+// subscription is closed or the output fails. The prepare function, which can be nil, returns the form of a value that
+// is written, or false to skip it. The flush function pushes what has been written to the client.
+func Write[T any](out io.Writer, flush func(), sub *Subscription[T], prepare func(v T) (interface{}, bool), done <-chan struct{}) error {
+
+	heartbeat := time.NewTicker(time.Duration(HEARTBEAT_S) * time.Second)
+	defer heartbeat.Stop()
+
+	encoder := json.NewEncoder(out)
+	dropped := 0
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-heartbeat.C:
+			if _, err := out.Write([]byte("\n")); err != nil {
+				return err
+			}
+			flush()
+		case v, ok := <-sub.Events:
+			if !ok {
+				return nil
+			}
+			var output interface{} = v
+			if prepare != nil {
+				if output, ok = prepare(v); !ok {
+					continue
+				}
+			}
+			if err := encoder.Encode(output); err != nil {
+				return err
+			}
+			flush()
+		}
+
+		if d := sub.Dropped(); d > dropped {
+			glog.Warningf(fmt.Sprintf("A client of the %v stream fell behind, %v values were not sent to it.", sub.broadcaster.name, d-dropped))
+			dropped = d
+		}
+	}
+}
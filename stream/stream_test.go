@@ -0,0 +1,62 @@
+//go:build unit
+// +build unit
+
+package stream
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Broadcaster(t *testing.T) {
+	drops := 0
+	b := NewBroadcaster[int]("test", 2, func() { drops++ })
+	assert.False(t, b.HasSubscribers())
+
+	even := b.Subscribe(0, func(v int) bool { return v%2 == 0 })
+	all := b.Subscribe(10, nil)
+	assert.True(t, b.HasSubscribers())
+
+	for v := 1; v <= 6; v++ {
+		b.Publish(v)
+	}
+
+	// The even subscription takes the default buffer of 2, so the third even value is dropped.
+	assert.Equal(t, 1, even.Dropped())
+	assert.Equal(t, 0, all.Dropped())
+	assert.Equal(t, 1, drops)
+	assert.Equal(t, 2, <-even.Events)
+	assert.Equal(t, 4, <-even.Events)
+	assert.Len(t, all.Events, 6)
+
+	// Nothing is delivered once a subscription is closed.
+	even.Close()
+	b.Publish(8)
+	_, ok := <-even.Events
+	assert.False(t, ok, "The events channel should be closed.")
+	even.Close()
+	all.Close()
+	assert.False(t, b.HasSubscribers())
+}
+
+func Test_Write(t *testing.T) {
+	b := NewBroadcaster[int]("test", 10, nil)
+	sub := b.Subscribe(0, nil)
+	for v := 1; v <= 3; v++ {
+		b.Publish(v)
+	}
+	sub.Close()
+
+	// Closing the subscription ends the stream once the values that were sent are written.
+	var out bytes.Buffer
+	flushed := 0
+	err := Write(&out, func() { flushed++ }, sub, func(v int) (interface{}, bool) {
+		return map[string]int{"value": v}, v != 2
+	}, make(chan struct{}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{"value":1}`, `{"value":3}`}, strings.Split(strings.TrimSpace(out.String()), "\n"))
+	assert.Equal(t, 2, flushed)
+}
@@ -0,0 +1,243 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/open-horizon/anax/config"
+)
+
+const EXPORTER_OTLP = config.TracingExporterOTLP
+const EXPORTER_FILE = "file"
+
+// The path of the OTLP/HTTP traces API, relative to the collector endpoint.
+const OTLP_TRACES_PATH = "/v1/traces"
+
+// The instrumentation scope reported with every span.
+const SCOPE_NAME = "github.com/open-horizon/anax"
+
+// Exporters send batches of ended spans somewhere they can be viewed.
+type Exporter interface {
+	Export(serviceName string, spans []*Span) error
+	Shutdown() error
+}
+
+// The OTLP/JSON encoding of a batch of spans, as defined by the OpenTelemetry protocol's ExportTraceServiceRequest.
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Encode a batch of spans as an OTLP/JSON ExportTraceServiceRequest.
+func EncodeOTLP(serviceName string, spans []*Span) ([]byte, error) {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.lock.Lock()
+		o := otlpSpan{
+			TraceId:           s.Context.TraceId,
+			SpanId:            s.Context.SpanId,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+			Status:            otlpStatus{Code: s.Status, Message: s.StatusDesc},
+		}
+		if s.Parent.IsValid() && s.Parent.TraceId == s.Context.TraceId {
+			o.ParentSpanId = s.Parent.SpanId
+		}
+		s.lock.Unlock()
+		otlpSpans = append(otlpSpans, o)
+	}
+
+	req := otlpTraceRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: otlpAttributes(map[string]string{"service.name": serviceName})},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: SCOPE_NAME},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+	return json.Marshal(req)
+}
+
+func otlpAttributes(attrs map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: attrs[k]}})
+	}
+	return kvs
+}
+
+// Sends spans to an OpenTelemetry collector using OTLP over HTTP with the JSON encoding.
+type OTLPExporter struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// The endpoint is the base URL of the collector, e.g. http://localhost:4318.
+func NewOTLPExporter(endpoint string, httpClient *http.Client) *OTLPExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, OTLP_TRACES_PATH) {
+		url += OTLP_TRACES_PATH
+	}
+	return &OTLPExporter{
+		URL:        url,
+		HTTPClient: httpClient,
+	}
+}
+
+func (e *OTLPExporter) String() string {
+	return fmt.Sprintf("OTLP exporter URL: %v", e.URL)
+}
+
+func (e *OTLPExporter) Export(serviceName string, spans []*Span) error {
+	body, err := EncodeOTLP(serviceName, spans)
+	if err != nil {
+		return fmt.Errorf("unable to encode spans, error: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create request for %v, error: %v", e.URL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send spans to %v, error: %v", e.URL, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("collector %v returned status %v", e.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) Shutdown() error {
+	return nil
+}
+
+// Appends spans to a local file, one OTLP/JSON ExportTraceServiceRequest per line, so that traces can be collected
+// from nodes without network access to a collector and sent to one later.
+type FileExporter struct {
+	Path string
+	file *os.File
+	lock sync.Mutex
+}
+
+func NewFileExporter(path string) (*FileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("unable to create directory for trace file %v, error: %v", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open trace file %v, error: %v", path, err)
+	}
+	return &FileExporter{Path: path, file: f}, nil
+}
+
+func (e *FileExporter) String() string {
+	return fmt.Sprintf("File exporter path: %v", e.Path)
+}
+
+func (e *FileExporter) Export(serviceName string, spans []*Span) error {
+	body, err := EncodeOTLP(serviceName, spans)
+	if err != nil {
+		return fmt.Errorf("unable to encode spans, error: %v", err)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, err := e.file.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("unable to write spans to %v, error: %v", e.Path, err)
+	}
+	return nil
+}
+
+func (e *FileExporter) Shutdown() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.file.Close()
+}
+
+// Create the exporter selected by the tracing configuration. Returns nil when tracing is not configured.
+func NewExporter(cfg config.TracingConfig, httpClient *http.Client) (Exporter, error) {
+	switch cfg.Exporter {
+	case "":
+		return nil, nil
+	case EXPORTER_OTLP:
+		if cfg.OTLPEndpoint == "" {
+			return nil, fmt.Errorf("the %v exporter requires an OTLPEndpoint", EXPORTER_OTLP)
+		}
+		return NewOTLPExporter(cfg.OTLPEndpoint, httpClient), nil
+	case EXPORTER_FILE:
+		if cfg.FilePath == "" {
+			return nil, fmt.Errorf("the %v exporter requires a FilePath", EXPORTER_FILE)
+		}
+		return NewFileExporter(cfg.FilePath)
+	default:
+		return nil, fmt.Errorf("unsupported exporter %v, must be %v or %v", cfg.Exporter, EXPORTER_OTLP, EXPORTER_FILE)
+	}
+}
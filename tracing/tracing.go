@@ -0,0 +1,370 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/metrics"
+)
+
+// Span kinds, using the OTLP numbering.
+const (
+	SPAN_KIND_INTERNAL = 1
+	SPAN_KIND_SERVER   = 2
+	SPAN_KIND_CLIENT   = 3
+)
+
+// Span status codes, using the OTLP numbering.
+const (
+	STATUS_UNSET = 0
+	STATUS_OK    = 1
+	STATUS_ERROR = 2
+)
+
+// The maximum number of ended spans that are buffered for export. Spans that end while the buffer is full are
+// dropped so that tracing never blocks a worker.
+const SPAN_BUFFER_SIZE = 2048
+
+// The maximum number of spans sent to the exporter at once, and the longest time a span waits to be exported.
+const EXPORT_BATCH_SIZE = 256
+const EXPORT_INTERVAL_S = 5
+
+var spansExported = metrics.NewCounterVec("anax_tracing_spans_exported_total",
+	"Spans sent to the trace exporter.")
+
+var spansDropped = metrics.NewCounterVec("anax_tracing_spans_dropped_total",
+	"Spans dropped because the export buffer was full.")
+
+var exportErrors = metrics.NewCounterVec("anax_tracing_export_errors_total",
+	"Errors returned by the trace exporter.")
+
+// The identity of a span within a trace. The ids are lower case hex strings, as used in OTLP/JSON and in the
+// W3C traceparent header.
+type SpanContext struct {
+	TraceId string `json:"trace_id"`
+	SpanId  string `json:"span_id"`
+}
+
+func (c SpanContext) String() string {
+	return fmt.Sprintf("TraceId: %v, SpanId: %v", c.TraceId, c.SpanId)
+}
+
+func (c SpanContext) IsValid() bool {
+	return len(c.TraceId) == 32 && len(c.SpanId) == 16
+}
+
+// Return the W3C traceparent header value for this context.
+func (c SpanContext) TraceParent() string {
+	return fmt.Sprintf("00-%v-%v-01", c.TraceId, c.SpanId)
+}
+
+// Every agreement has its own trace. The trace id and the id of the agreement's root span are derived from the agreement
+// id, so the agbot and the agent, and every worker within them, put their spans for an agreement into the same trace
+// without having to pass the context between processes.
+func AgreementTraceContext(agreementId string) SpanContext {
+	if agreementId == "" {
+		return SpanContext{}
+	}
+	sum := sha256.Sum256([]byte(agreementId))
+	return SpanContext{
+		TraceId: hex.EncodeToString(sum[:16]),
+		SpanId:  hex.EncodeToString(sum[16:24]),
+	}
+}
+
+// Implemented by messages and commands that carry a trace context from one worker to another.
+type Traceable interface {
+	TraceContext() SpanContext
+	SetTraceContext(SpanContext)
+}
+
+// Embed a Carrier in a message or command to make it Traceable.
+type Carrier struct {
+	traceContext SpanContext
+}
+
+func (c Carrier) TraceContext() SpanContext {
+	return c.traceContext
+}
+
+func (c *Carrier) SetTraceContext(sc SpanContext) {
+	c.traceContext = sc
+}
+
+// Return the trace context carried by the input object, if there is one.
+func ContextOf(obj interface{}) SpanContext {
+	if t, ok := obj.(Traceable); ok {
+		return t.TraceContext()
+	}
+	return SpanContext{}
+}
+
+// A timed operation within a trace. All methods can be called on a nil span, which is what StartSpan returns when
+// tracing is disabled.
+type Span struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanContext
+	Kind       int
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]string
+	Status     int
+	StatusDesc string
+	lock       sync.Mutex
+	ended      bool
+	tracer     *Tracer
+}
+
+func (s *Span) String() string {
+	if s == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("Name: %v, %v, Parent: %v, Start: %v, End: %v, Attributes: %v, Status: %v %v",
+		s.Name, s.Context, s.Parent.SpanId, s.StartTime, s.EndTime, s.Attributes, s.Status, s.StatusDesc)
+}
+
+// Return the context of this span so that it can be used as the parent of other spans.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.Context
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Attributes[key] = fmt.Sprintf("%v", value)
+}
+
+func (s *Span) SetKind(kind int) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Kind = kind
+}
+
+// Mark the span as failed. A nil error leaves the status alone.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Status = STATUS_ERROR
+	s.StatusDesc = err.Error()
+}
+
+// End the span and queue it for export. Ending a span more than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.lock.Unlock()
+
+	s.tracer.queue(s)
+}
+
+// Holds the span for the work that a go routine is currently doing, so that calls made by that go routine, e.g. to the
+// exchange, can be recorded as children of it. All methods can be called on a nil holder.
+type ActiveSpan struct {
+	lock sync.Mutex
+	span *Span
+}
+
+func (a *ActiveSpan) Set(s *Span) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.span = s
+}
+
+func (a *ActiveSpan) Context() SpanContext {
+	if a == nil {
+		return SpanContext{}
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.span.SpanContext()
+}
+
+// The tracer collects ended spans and hands them to the exporter in batches, on its own go routine.
+type Tracer struct {
+	serviceName string
+	exporter    Exporter
+	spans       chan *Span
+	stop        chan bool
+	stopped     chan bool
+}
+
+var tracerLock sync.RWMutex
+var tracer *Tracer
+
+// Start tracing. Spans are exported by the input exporter, identified by the input service name.
+func Start(serviceName string, exporter Exporter) *Tracer {
+	t := &Tracer{
+		serviceName: serviceName,
+		exporter:    exporter,
+		spans:       make(chan *Span, SPAN_BUFFER_SIZE),
+		stop:        make(chan bool),
+		stopped:     make(chan bool),
+	}
+
+	tracerLock.Lock()
+	old := tracer
+	tracer = t
+	tracerLock.Unlock()
+
+	if old != nil {
+		old.shutdown()
+	}
+
+	go t.run()
+	glog.V(3).Infof(tLogString(fmt.Sprintf("started tracing for %v with exporter %v", serviceName, exporter)))
+	return t
+}
+
+// Stop tracing, exporting the spans that have already ended.
+func Stop() {
+	tracerLock.Lock()
+	t := tracer
+	tracer = nil
+	tracerLock.Unlock()
+
+	if t != nil {
+		t.shutdown()
+	}
+}
+
+func Enabled() bool {
+	return getTracer() != nil
+}
+
+func getTracer() *Tracer {
+	tracerLock.RLock()
+	defer tracerLock.RUnlock()
+	return tracer
+}
+
+// Start a span as a child of the input parent. If the parent is not valid, the span starts a new trace. Returns nil
+// when tracing is disabled.
+func StartSpan(name string, parent SpanContext) *Span {
+	sc := SpanContext{SpanId: newId(8)}
+	if parent.IsValid() {
+		sc.TraceId = parent.TraceId
+	} else {
+		sc.TraceId = newId(16)
+	}
+	return StartSpanWithContext(name, sc, parent)
+}
+
+// Start a span with a predetermined context, e.g. the root span of an agreement's trace.
+func StartSpanWithContext(name string, sc SpanContext, parent SpanContext) *Span {
+	t := getTracer()
+	if t == nil || !sc.IsValid() {
+		return nil
+	}
+
+	return &Span{
+		Name:       name,
+		Context:    sc,
+		Parent:     parent,
+		Kind:       SPAN_KIND_INTERNAL,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]string),
+		tracer:     t,
+	}
+}
+
+func (t *Tracer) queue(s *Span) {
+	select {
+	case t.spans <- s:
+	default:
+		spansDropped.Inc()
+	}
+}
+
+func (t *Tracer) run() {
+	batch := make([]*Span, 0, EXPORT_BATCH_SIZE)
+	ticker := time.NewTicker(EXPORT_INTERVAL_S * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case s := <-t.spans:
+			batch = append(batch, s)
+			if len(batch) >= EXPORT_BATCH_SIZE {
+				batch = t.export(batch)
+			}
+		case <-ticker.C:
+			batch = t.export(batch)
+		case <-t.stop:
+			// Export whatever is still buffered before shutting down the exporter.
+			// This is the only reader, so the receive cannot block.
+			for len(t.spans) > 0 {
+				batch = append(batch, <-t.spans)
+				if len(batch) >= EXPORT_BATCH_SIZE {
+					batch = t.export(batch)
+				}
+			}
+			t.export(batch)
+			if err := t.exporter.Shutdown(); err != nil {
+				glog.Errorf(tLogString(fmt.Sprintf("error shutting down exporter %v, error: %v", t.exporter, err)))
+			}
+			close(t.stopped)
+			return
+		}
+	}
+}
+
+// Export the batch and return an empty batch to be filled again.
+func (t *Tracer) export(batch []*Span) []*Span {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := t.exporter.Export(t.serviceName, batch); err != nil {
+		exportErrors.Inc()
+		glog.Errorf(tLogString(fmt.Sprintf("error exporting %v spans, error: %v", len(batch), err)))
+	} else {
+		spansExported.Add(float64(len(batch)))
+	}
+	return make([]*Span, 0, EXPORT_BATCH_SIZE)
+}
+
+func (t *Tracer) shutdown() {
+	close(t.stop)
+	<-t.stopped
+}
+
+// Return a random id of the input number of bytes, hex encoded.
+func newId(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		glog.Errorf(tLogString(fmt.Sprintf("unable to generate id, error: %v", err)))
+	}
+	return hex.EncodeToString(b)
+}
+
+var tLogString = func(v interface{}) string {
+	return fmt.Sprintf("Tracing: %v", v)
+}
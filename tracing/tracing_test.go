@@ -0,0 +1,212 @@
+//go:build unit
+// +build unit
+
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testExporter struct {
+	lock     sync.Mutex
+	spans    []*Span
+	shutdown bool
+}
+
+func (e *testExporter) Export(serviceName string, spans []*Span) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *testExporter) Shutdown() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func Test_AgreementTraceContext(t *testing.T) {
+	sc := AgreementTraceContext("a1")
+	if !sc.IsValid() {
+		t.Errorf("agreement trace context %v should be valid", sc)
+	} else if sc != AgreementTraceContext("a1") {
+		t.Errorf("agreement trace context should be the same for the same agreement")
+	} else if sc.TraceId == AgreementTraceContext("a2").TraceId {
+		t.Errorf("agreement trace context should be different for different agreements")
+	} else if AgreementTraceContext("").IsValid() {
+		t.Errorf("agreement trace context for an empty agreement id should not be valid")
+	} else if tp := sc.TraceParent(); tp != "00-"+sc.TraceId+"-"+sc.SpanId+"-01" {
+		t.Errorf("unexpected traceparent %v", tp)
+	}
+}
+
+func Test_Disabled(t *testing.T) {
+	Stop()
+	if Enabled() {
+		t.Errorf("tracing should not be enabled")
+	}
+
+	// A disabled tracer returns nil spans, which must be safe to use.
+	s := StartSpan("test", AgreementTraceContext("a1"))
+	if s != nil {
+		t.Errorf("span should be nil when tracing is disabled, was %v", s)
+	}
+	s.SetAttribute("k", "v")
+	s.SetError(os.ErrNotExist)
+	s.End()
+	if s.SpanContext().IsValid() {
+		t.Errorf("nil span should not have a valid context")
+	}
+}
+
+func Test_SpanExport(t *testing.T) {
+	e := &testExporter{}
+	Start("test-service", e)
+
+	root := AgreementTraceContext("a1")
+	s := StartSpanWithContext("agreement", root, SpanContext{})
+	c := StartSpan("child", s.SpanContext())
+	c.SetAttribute("count", 3)
+	c.SetError(os.ErrNotExist)
+	c.End()
+	c.End()
+	s.End()
+
+	Stop()
+
+	if !e.shutdown {
+		t.Errorf("exporter should be shut down")
+	} else if len(e.spans) != 2 {
+		t.Errorf("expected 2 exported spans, got %v", e.spans)
+	} else if e.spans[0].Context.TraceId != root.TraceId || e.spans[0].Parent != root {
+		t.Errorf("child span %v should be in the agreement trace", e.spans[0])
+	} else if e.spans[0].Attributes["count"] != "3" || e.spans[0].Status != STATUS_ERROR {
+		t.Errorf("child span %v is missing its attributes or status", e.spans[0])
+	} else if e.spans[1].Context != root {
+		t.Errorf("root span %v should have the agreement trace context", e.spans[1])
+	}
+}
+
+func Test_EncodeOTLP(t *testing.T) {
+	root := AgreementTraceContext("a1")
+	s := &Span{
+		Name:       "child",
+		Context:    SpanContext{TraceId: root.TraceId, SpanId: "0123456789abcdef"},
+		Parent:     root,
+		Kind:       SPAN_KIND_CLIENT,
+		Attributes: map[string]string{"b": "2", "a": "1"},
+	}
+
+	body, err := EncodeOTLP("svc", []*Span{s})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	req := otlpTraceRequest{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("unable to decode %v, error %v", string(body), err)
+	}
+
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("unexpected OTLP request %v", string(body))
+	}
+	if attrs := req.ResourceSpans[0].Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" || attrs[0].Value.StringValue != "svc" {
+		t.Errorf("unexpected resource attributes %v", attrs)
+	}
+	o := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if o.TraceId != root.TraceId || o.ParentSpanId != root.SpanId || o.Kind != SPAN_KIND_CLIENT {
+		t.Errorf("unexpected span %v", o)
+	} else if len(o.Attributes) != 2 || o.Attributes[0].Key != "a" {
+		t.Errorf("span attributes should be sorted, were %v", o.Attributes)
+	}
+}
+
+func Test_FileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.json")
+	e, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	Start("test-service", e)
+	StartSpan("one", SpanContext{}).End()
+	Stop()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unable to open %v, error %v", path, err)
+	}
+	defer f.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+		if !strings.Contains(scanner.Text(), `"name":"one"`) {
+			t.Errorf("unexpected line %v", scanner.Text())
+		}
+	}
+	if lines != 1 {
+		t.Errorf("expected 1 line in %v, got %v", path, lines)
+	}
+}
+
+func Test_Transport(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(TRACEPARENT_HEADER)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	e := &testExporter{}
+	Start("test-service", e)
+
+	root := AgreementTraceContext("a1")
+	parent := root
+	client := TracedClient(ts.Client(), func() SpanContext { return parent })
+
+	resp, err := client.Get(ts.URL + "/orgs/myorg/nodes?secret=x")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if !strings.HasPrefix(traceparent, "00-"+root.TraceId+"-") {
+		t.Errorf("request should carry the agreement trace in its traceparent, was %v", traceparent)
+	}
+
+	// Requests made outside of a trace are not recorded.
+	parent = SpanContext{}
+	resp, err = client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if traceparent != "" {
+		t.Errorf("request outside of a trace should not have a traceparent, was %v", traceparent)
+	}
+
+	Stop()
+
+	if len(e.spans) != 1 {
+		t.Fatalf("expected 1 exported span, got %v", e.spans)
+	}
+	s := e.spans[0]
+	if s.Name != "HTTP GET" || s.Kind != SPAN_KIND_CLIENT || s.Parent != root {
+		t.Errorf("unexpected span %v", s)
+	} else if s.Attributes["http.status_code"] != "404" || s.Status != STATUS_ERROR {
+		t.Errorf("span %v should record the failed status", s)
+	} else if s.Attributes["http.url"] != ts.URL+"/orgs/myorg/nodes" {
+		t.Errorf("span %v should record the URL without the query", s)
+	}
+}
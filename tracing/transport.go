@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/open-horizon/anax/config"
+)
+
+// The W3C trace context header.
+const TRACEPARENT_HEADER = "traceparent"
+
+// An http.RoundTripper that records a client span for each request sent while the Parent function returns a valid
+// span context, and passes the trace context on to the server in the traceparent header. Requests sent outside of a
+// trace are not recorded.
+type Transport struct {
+	Base   http.RoundTripper
+	Parent func() SpanContext
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	parent := SpanContext{}
+	if t.Parent != nil {
+		parent = t.Parent()
+	}
+
+	span := StartSpan(fmt.Sprintf("HTTP %v", req.Method), parent)
+	if !parent.IsValid() || span == nil {
+		return base.RoundTrip(req)
+	}
+
+	span.SetKind(SPAN_KIND_CLIENT)
+	span.SetAttribute("http.method", req.Method)
+	if req.URL != nil {
+		// The query string is left out because it can contain credentials or other sensitive input.
+		span.SetAttribute("http.url", fmt.Sprintf("%v://%v%v", req.URL.Scheme, req.URL.Host, req.URL.Path))
+	}
+
+	traced := req.Clone(req.Context())
+	traced.Header.Set(TRACEPARENT_HEADER, span.SpanContext().TraceParent())
+
+	resp, err := base.RoundTrip(traced)
+	if err != nil {
+		span.SetError(err)
+	} else {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+		if resp.StatusCode >= http.StatusBadRequest {
+			span.SetError(fmt.Errorf("HTTP status %v", resp.StatusCode))
+		}
+	}
+	span.End()
+	return resp, err
+}
+
+// Return a copy of the input client that traces its requests, using the input function to find the parent span.
+func TracedClient(client *http.Client, parent func() SpanContext) *http.Client {
+	if client == nil {
+		return nil
+	}
+	traced := *client
+	traced.Transport = &Transport{Base: client.Transport, Parent: parent}
+	return &traced
+}
+
+// Return a copy of the input HTTP client factory whose clients trace their requests. The input factory is returned
+// unchanged when tracing is disabled.
+func TracedFactory(factory *config.HTTPClientFactory, parent func() SpanContext) *config.HTTPClientFactory {
+	if factory == nil || factory.NewHTTPClient == nil || !Enabled() {
+		return factory
+	}
+	traced := *factory
+	traced.NewHTTPClient = func(overrideTimeoutS *uint) *http.Client {
+		return TracedClient(factory.NewHTTPClient(overrideTimeoutS), parent)
+	}
+	return &traced
+}
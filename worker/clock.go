@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// The workers get the time and wait for it through a Clock, so that tests can run them against a FakeClock and move
+// time forward instead of waiting for it. Anax always runs with the real clock.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+var clockLock sync.Mutex
+var clock Clock = realClock{}
+
+// Returns the clock used by the workers.
+func GetClock() Clock {
+	clockLock.Lock()
+	defer clockLock.Unlock()
+	return clock
+}
+
+// Set the clock used by the workers, nil sets the real clock. The workers pick up the clock each time they wait, so
+// it should be set before the workers are started.
+func SetClock(c Clock) {
+	clockLock.Lock()
+	defer clockLock.Unlock()
+	if c == nil {
+		c = realClock{}
+	}
+	clock = c
+}
+
+// A clock whose time only moves when Advance is called. The timers returned by After fire, in order, when the time
+// is advanced past them.
+type FakeClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	waits  uint64 // The calls to After, each is a go routine starting to wait on the clock
+}
+
+type fakeTimer struct {
+	deadline time.Time
+	c        chan time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.waits++
+	t := &fakeTimer{deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+		sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].deadline.Before(c.timers[j].deadline) })
+	}
+	return t.c
+}
+
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Move the time forward, firing the timers that are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+	for len(c.timers) != 0 && !c.timers[0].deadline.After(c.now) {
+		c.timers[0].c <- c.timers[0].deadline
+		c.timers = c.timers[1:]
+	}
+}
+
+// Returns the time when the next timer fires, false when there are no timers.
+func (c *FakeClock) NextTimer() (time.Time, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.timers) == 0 {
+		return time.Time{}, false
+	}
+	return c.timers[0].deadline, true
+}
+
+// Returns the number of timers that have not fired yet. A timer that was abandoned by a select that took another
+// case is still counted until its time comes.
+func (c *FakeClock) Timers() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.timers)
+}
+
+func (c *FakeClock) getWaits() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.waits
+}
@@ -0,0 +1,196 @@
+package worker
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+)
+
+// Events used to be handed to each worker's NewEvent by the event dispatcher itself, so a worker whose command queue
+// was full held up the delivery of every event to every worker. Now each worker has a bounded queue of events, that
+// a go routine per worker hands to the worker's NewEvent in order. When a worker's queue is full, its overflow policy
+// decides whether the dispatcher waits for room, the oldest event is dropped, or a queued event with the same key is
+// replaced by the new one.
+
+// A worker that only handles some types of events can list them, so that the other events are not queued for it. The
+// list holds a message of each type, e.g. &events.AgreementReachedMessage{}.
+type EventSubscriber interface {
+	EventSubscriptions() []events.Message
+}
+
+// The state and counters of a worker's event queue, reported by the /status/workers API.
+type EventQueueStatus struct {
+	Capacity      int      `json:"capacity"`
+	Overflow      string   `json:"overflow"`
+	Length        int      `json:"length"`
+	Subscriptions []string `json:"subscriptions,omitempty"` // The types of events queued for the worker, all types when empty
+	Queued        uint64   `json:"queued"`                  // Events added to the queue
+	Delivered     uint64   `json:"delivered"`               // Events handed to the worker
+	Filtered      uint64   `json:"filtered"`                // Events not queued because the worker does not subscribe to them
+	Dropped       uint64   `json:"dropped"`                 // Events dropped because the queue was full
+	Coalesced     uint64   `json:"coalesced"`               // Queued events replaced by a newer event with the same key
+	Blocked       uint64   `json:"blocked"`                 // Times the dispatcher waited for room in the queue
+}
+
+type eventQueue struct {
+	name          string
+	handler       MessageHandler
+	subscriptions map[reflect.Type]bool // nil when the worker gets every event
+	lock          sync.Mutex
+	changed       *sync.Cond
+	queue         []events.Message
+	closed        bool
+	status        EventQueueStatus
+}
+
+func newEventQueue(handler MessageHandler, cfg config.EventQueueConfig) *eventQueue {
+	name := handler.GetName()
+	capacity, overflow := cfg.GetWorkerQueue(name)
+	switch overflow {
+	case config.EventQueueOverflowBlock, config.EventQueueOverflowDropOldest, config.EventQueueOverflowCoalesce:
+	default:
+		glog.Warningf(mdLogString(fmt.Sprintf("unknown event queue overflow policy %v for %v, using %v", overflow, name, config.EventQueueOverflowBlock)))
+		overflow = config.EventQueueOverflowBlock
+	}
+
+	q := &eventQueue{
+		name:    name,
+		handler: handler,
+		queue:   make([]events.Message, 0, capacity),
+		status: EventQueueStatus{
+			Capacity: capacity,
+			Overflow: overflow,
+		},
+	}
+	q.changed = sync.NewCond(&q.lock)
+
+	if s, ok := handler.(EventSubscriber); ok {
+		q.subscriptions = make(map[reflect.Type]bool)
+		for _, msg := range s.EventSubscriptions() {
+			t := reflect.TypeOf(msg)
+			q.subscriptions[t] = true
+			q.status.Subscriptions = append(q.status.Subscriptions, t.String())
+		}
+		sort.Strings(q.status.Subscriptions)
+	}
+
+	return q
+}
+
+func coalesceKey(msg events.Message) string {
+	if cm, ok := msg.(events.CoalescableMessage); ok {
+		return cm.CoalesceKey()
+	}
+	return ""
+}
+
+// Add an event to the queue. Called by the event dispatcher, which waits here when the queue is full and the overflow
+// policy is to block. Returns false when the event is not queued because the worker does not subscribe to it or the
+// queue is closed.
+func (q *eventQueue) push(msg events.Message) bool {
+	if q.subscriptions != nil && !q.subscriptions[reflect.TypeOf(msg)] {
+		q.lock.Lock()
+		q.status.Filtered++
+		q.lock.Unlock()
+		return false
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed {
+		return false
+	}
+
+	if q.status.Overflow == config.EventQueueOverflowCoalesce {
+		if key := coalesceKey(msg); key != "" {
+			for i, queued := range q.queue {
+				if coalesceKey(queued) == key {
+					q.queue[i] = msg
+					q.status.Coalesced++
+					eventsCoalesced.Inc(q.name)
+					return true
+				}
+			}
+		}
+	}
+
+	if len(q.queue) >= q.status.Capacity {
+		if q.status.Overflow == config.EventQueueOverflowDropOldest {
+			glog.Warningf(mdLogString(fmt.Sprintf("event queue of %v is full, dropping %v", q.name, q.queue[0].ShortString())))
+			q.queue[0] = nil
+			q.queue = q.queue[1:]
+			q.status.Dropped++
+			eventsDropped.Inc(q.name)
+		} else {
+			glog.Warningf(mdLogString(fmt.Sprintf("event queue of %v is full, waiting for room", q.name)))
+			q.status.Blocked++
+			eventQueueBlocked.Inc(q.name)
+			for len(q.queue) >= q.status.Capacity && !q.closed {
+				q.changed.Wait()
+			}
+			if q.closed {
+				return false
+			}
+		}
+	}
+
+	q.queue = append(q.queue, msg)
+	q.status.Queued++
+	q.changed.Broadcast()
+	return true
+}
+
+// Hand the queued events to the worker, in order, until the queue is closed.
+func (q *eventQueue) run() {
+	for {
+		q.lock.Lock()
+		for len(q.queue) == 0 && !q.closed {
+			q.changed.Wait()
+		}
+		if q.closed {
+			q.lock.Unlock()
+			return
+		}
+		msg := q.queue[0]
+		q.queue[0] = nil
+		q.queue = q.queue[1:]
+		q.changed.Broadcast()
+		q.lock.Unlock()
+
+		glog.V(5).Infof(mdLogString(fmt.Sprintf("Delivering message to %v", q.name)))
+		q.handler.NewEvent(msg)
+		glog.V(5).Infof(mdLogString(fmt.Sprintf("Delivered message to %v", q.name)))
+
+		q.lock.Lock()
+		q.status.Delivered++
+		q.lock.Unlock()
+	}
+}
+
+// Stop delivering events to the worker, the queued events are discarded.
+func (q *eventQueue) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	q.queue = nil
+	q.changed.Broadcast()
+}
+
+func (q *eventQueue) getStatus() EventQueueStatus {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	s := q.status
+	s.Length = len(q.queue)
+	return s
+}
+
+func (q *eventQueue) length() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.queue)
+}
@@ -0,0 +1,140 @@
+//go:build unit
+// +build unit
+
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// A message handler that records the events given to it, and can be held up to let its queue fill.
+type queueTestHandler struct {
+	name      string
+	delivered chan events.Message
+	subs      []events.Message
+}
+
+func (h *queueTestHandler) GetName() string {
+	return h.name
+}
+
+func (h *queueTestHandler) NewEvent(incoming events.Message) {
+	h.delivered <- incoming
+}
+
+func (h *queueTestHandler) Messages() chan events.Message {
+	return nil
+}
+
+type queueTestSubscriber struct {
+	queueTestHandler
+}
+
+func (h *queueTestSubscriber) EventSubscriptions() []events.Message {
+	return h.subs
+}
+
+func newQueueTestHandler(name string) *queueTestHandler {
+	return &queueTestHandler{name: name, delivered: make(chan events.Message, 10)}
+}
+
+func queueConfig(name string, capacity int, overflow string) config.EventQueueConfig {
+	return config.EventQueueConfig{Workers: map[string]config.EventQueueWorkerConfig{name: {Capacity: capacity, Overflow: overflow}}}
+}
+
+func Test_EventQueue_drop_oldest(t *testing.T) {
+	q := newEventQueue(newQueueTestHandler("dropper"), queueConfig("dropper", 2, config.EventQueueOverflowDropOldest))
+
+	first := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n1")
+	second := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n2")
+	third := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n3")
+	q.push(first)
+	q.push(second)
+	q.push(third)
+
+	status := q.getStatus()
+	assert.Equal(t, 2, status.Length)
+	assert.Equal(t, uint64(3), status.Queued)
+	assert.Equal(t, uint64(1), status.Dropped)
+	assert.Equal(t, []events.Message{second, third}, q.queue)
+}
+
+func Test_EventQueue_coalesce(t *testing.T) {
+	q := newEventQueue(newQueueTestHandler("coalescer"), queueConfig("coalescer", 5, config.EventQueueOverflowCoalesce))
+
+	failed := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n1")
+	other := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n2")
+	restored := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_RESTORED, "org", "n1")
+	q.push(failed)
+	q.push(other)
+	q.push(restored)
+
+	// The newer state of n1 replaced the older one, in the older one's place.
+	status := q.getStatus()
+	assert.Equal(t, 2, status.Length)
+	assert.Equal(t, uint64(1), status.Coalesced)
+	assert.Equal(t, []events.Message{restored, other}, q.queue)
+
+	// Messages without a key are not coalesced.
+	q.push(events.NewNodeShutdownMessage(events.START_UNCONFIGURE, false, false))
+	q.push(events.NewNodeShutdownMessage(events.START_UNCONFIGURE, false, false))
+	assert.Equal(t, 4, q.length())
+}
+
+func Test_EventQueue_subscriptions(t *testing.T) {
+	h := &queueTestSubscriber{queueTestHandler: *newQueueTestHandler("subscriber")}
+	h.subs = []events.Message{&events.NodeHeartbeatStateChangeMessage{}}
+	q := newEventQueue(h, config.EventQueueConfig{})
+
+	q.push(events.NewNodeShutdownMessage(events.START_UNCONFIGURE, false, false))
+	q.push(events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n1"))
+
+	status := q.getStatus()
+	assert.Equal(t, config.EventQueueCapacity_DEFAULT, status.Capacity)
+	assert.Equal(t, config.EventQueueOverflowBlock, status.Overflow)
+	assert.Equal(t, []string{"*events.NodeHeartbeatStateChangeMessage"}, status.Subscriptions)
+	assert.Equal(t, 1, status.Length)
+	assert.Equal(t, uint64(1), status.Filtered)
+}
+
+func Test_EventQueue_block(t *testing.T) {
+	h := newQueueTestHandler("blocker")
+	h.delivered = make(chan events.Message)
+	q := newEventQueue(h, queueConfig("blocker", 1, config.EventQueueOverflowBlock))
+	go q.run()
+	defer q.close()
+
+	first := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n1")
+	second := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n2")
+	third := events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n3")
+
+	// The handler holds the first event, the second fills the queue, so the third waits for room.
+	q.push(first)
+	for q.length() != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	q.push(second)
+	pushed := make(chan bool)
+	go func() {
+		q.push(third)
+		pushed <- true
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("push did not wait for room in the queue")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The events are delivered in order once the handler takes them.
+	assert.Equal(t, first, <-h.delivered)
+	<-pushed
+	assert.Equal(t, second, <-h.delivered)
+	assert.Equal(t, third, <-h.delivered)
+	assert.Equal(t, uint64(1), q.getStatus().Blocked)
+}
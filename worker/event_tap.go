@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/stream"
+)
+
+// The event tap streams the events passed between the workers to debugging clients, as they are dispatched, so that
+// the chain of events behind a stalled agreement can be followed without restarting at a higher log level. Only the
+// ShortString form of each event is sent, it leaves out the secrets and large documents that some events carry.
+
+// The events that a tap client can fall behind by before new events are dropped for it.
+const DEFAULT_EVENT_TAP_BUFFER = 200
+
+// The source of the events replayed from the event journal, which were not sent by a worker.
+const EVENT_TAP_SOURCE_JOURNAL = "EventJournal"
+
+// An event as it was dispatched to the workers.
+type TappedEvent struct {
+	Time    int64    `json:"time"`              // When the event was dispatched, in seconds since the epoch.
+	EventId string   `json:"event_id"`          // The id of the event, e.g. AGREEMENT_REACHED.
+	Type    string   `json:"type"`              // The go type of the message, e.g. *events.AgreementReachedMessage.
+	Source  string   `json:"source"`            // The worker that sent the event.
+	Workers []string `json:"workers,omitempty"` // The workers the event was queued for.
+	Message string   `json:"message"`           // The ShortString of the message.
+}
+
+// Selects the tapped events sent to a client. An empty list selects everything.
+type EventTapFilter struct {
+	EventIds []string // The ids of the events to send.
+	Workers  []string // Only send the events sent by or queued for these workers.
+}
+
+func (f EventTapFilter) Matches(te *TappedEvent) bool {
+	if len(f.EventIds) != 0 && !containsString(f.EventIds, te.EventId) {
+		return false
+	}
+	if len(f.Workers) != 0 && !containsString(f.Workers, te.Source) {
+		for _, w := range te.Workers {
+			if containsString(f.Workers, w) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// A client of the event tap.
+type EventTapSubscription = stream.Subscription[TappedEvent]
+
+var tapSubscriptions = stream.NewBroadcaster[TappedEvent]("event tap", DEFAULT_EVENT_TAP_BUFFER, func() { eventTapDropped.Inc() })
+var tapLock sync.Mutex
+var tapToken string
+
+// Turn on the event tap when a token file is configured. The token is read from the file, or a random token is
+// written to it when the file does not exist yet.
+func ConfigureEventTap(cfg config.EventTapConfig) error {
+	tapLock.Lock()
+	defer tapLock.Unlock()
+	tapToken = ""
+
+	if cfg.TokenFile == "" {
+		return nil
+	}
+
+	token, err := os.ReadFile(cfg.TokenFile)
+	if os.IsNotExist(err) {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("unable to generate the event tap token, error: %v", err)
+		}
+		token = []byte(hex.EncodeToString(b))
+		if err := os.MkdirAll(path.Dir(cfg.TokenFile), 0700); err != nil {
+			return fmt.Errorf("unable to create the directory of event tap token file %v, error: %v", cfg.TokenFile, err)
+		} else if err := os.WriteFile(cfg.TokenFile, token, 0600); err != nil {
+			return fmt.Errorf("unable to write event tap token file %v, error: %v", cfg.TokenFile, err)
+		}
+		glog.V(3).Infof(mdLogString(fmt.Sprintf("created event tap token file %v", cfg.TokenFile)))
+	} else if err != nil {
+		return fmt.Errorf("unable to read event tap token file %v, error: %v", cfg.TokenFile, err)
+	}
+
+	tapToken = strings.TrimSpace(string(token))
+	if tapToken == "" {
+		return fmt.Errorf("event tap token file %v is empty", cfg.TokenFile)
+	}
+	return nil
+}
+
+// Returns true when the event tap is turned on.
+func EventTapEnabled() bool {
+	tapLock.Lock()
+	defer tapLock.Unlock()
+	return tapToken != ""
+}
+
+// Returns true when the value of an Authorization header is "Bearer " followed by the event tap's token. Always
+// false when the event tap is off.
+func ValidEventTapAuthorization(authHeader string) bool {
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == authHeader {
+		return false
+	}
+
+	tapLock.Lock()
+	defer tapLock.Unlock()
+	return tapToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(tapToken)) == 1
+}
+
+// Returns the filter selected by the event_id and worker query parameters of an event tap request. Each parameter
+// can be repeated.
+func GetEventTapFilter(query url.Values) EventTapFilter {
+	return EventTapFilter{EventIds: query["event_id"], Workers: query["worker"]}
+}
+
+// Subscribe to the events dispatched from now on that match the filter. The caller must close the subscription when
+// it is done with it.
+func SubscribeEventTap(bufferSize int, filter EventTapFilter) *EventTapSubscription {
+	return tapSubscriptions.Subscribe(bufferSize, func(te TappedEvent) bool { return filter.Matches(&te) })
+}
+
+// Send a dispatched event to the tap clients. Nothing is done when there are no clients.
+func tapEvent(msg events.Message, source string, workers []string) {
+	if !tapSubscriptions.HasSubscribers() {
+		return
+	}
+
+	te := TappedEvent{
+		Time:    time.Now().Unix(),
+		EventId: string(msg.Event().Id),
+		Type:    fmt.Sprintf("%T", msg),
+		Source:  source,
+		Workers: workers,
+		Message: msg.ShortString(),
+	}
+	tapSubscriptions.Publish(te)
+}
+
+// Write the tapped events to the output as they are dispatched, one JSON object per line, until done is closed or the
+// output fails. The flush function pushes what has been written to the client.
+func StreamTappedEvents(out io.Writer, flush func(), sub *EventTapSubscription, done <-chan struct{}) error {
+	return stream.Write(out, flush, sub, nil, done)
+}
@@ -0,0 +1,85 @@
+//go:build unit
+// +build unit
+
+package worker
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EventTapFilter(t *testing.T) {
+	te := &TappedEvent{EventId: "AGREEMENT_REACHED", Source: "Agreement", Workers: []string{"Governance", "ImageFetch"}}
+
+	assert.True(t, EventTapFilter{}.Matches(te))
+	assert.True(t, EventTapFilter{EventIds: []string{"AGREEMENT_ENDED", "AGREEMENT_REACHED"}}.Matches(te))
+	assert.False(t, EventTapFilter{EventIds: []string{"AGREEMENT_ENDED"}}.Matches(te))
+
+	// Workers match the sender and the receivers.
+	assert.True(t, EventTapFilter{Workers: []string{"Agreement"}}.Matches(te))
+	assert.True(t, EventTapFilter{Workers: []string{"ImageFetch"}}.Matches(te))
+	assert.False(t, EventTapFilter{Workers: []string{"Container"}}.Matches(te))
+	assert.False(t, EventTapFilter{EventIds: []string{"AGREEMENT_ENDED"}, Workers: []string{"Agreement"}}.Matches(te))
+}
+
+func Test_EventTap_dispatch(t *testing.T) {
+	workers := NewMessageHandlerRegistry()
+	h := &queueTestSubscriber{queueTestHandler: *newQueueTestHandler("subscriber")}
+	h.subs = []events.Message{&events.NodeHeartbeatStateChangeMessage{}}
+	workers.Add(h)
+	workers.Add(newQueueTestHandler("all"))
+	defer workers.Remove("subscriber")
+	defer workers.Remove("all")
+
+	sub := SubscribeEventTap(10, EventTapFilter{Workers: []string{"subscriber"}})
+	defer sub.Close()
+
+	eventHandler(events.NewNodeShutdownMessage(events.START_UNCONFIGURE, false, false), "Governance", workers)
+	eventHandler(events.NewNodeHeartbeatStateChangeMessage(events.NODE_HEARTBEAT_FAILED, "org", "n1"), "Governance", workers)
+
+	// Only the event queued for the subscriber is tapped.
+	sub.Close()
+	tapped := []TappedEvent{}
+	for te := range sub.Events {
+		tapped = append(tapped, te)
+	}
+	if assert.Len(t, tapped, 1) {
+		assert.Equal(t, string(events.NODE_HEARTBEAT_FAILED), tapped[0].EventId)
+		assert.Equal(t, "*events.NodeHeartbeatStateChangeMessage", tapped[0].Type)
+		assert.Equal(t, "Governance", tapped[0].Source)
+		assert.Equal(t, []string{"all", "subscriber"}, tapped[0].Workers)
+		assert.NotEmpty(t, tapped[0].Message)
+	}
+}
+
+func Test_ConfigureEventTap(t *testing.T) {
+	defer ConfigureEventTap(config.EventTapConfig{})
+
+	assert.Nil(t, ConfigureEventTap(config.EventTapConfig{}))
+	assert.False(t, EventTapEnabled())
+	assert.False(t, ValidEventTapAuthorization("Bearer "))
+
+	// A token is generated when the file does not exist.
+	tokenFile := path.Join(t.TempDir(), "horizon", "eventtap.token")
+	assert.Nil(t, ConfigureEventTap(config.EventTapConfig{TokenFile: tokenFile}))
+	assert.True(t, EventTapEnabled())
+
+	token, err := os.ReadFile(tokenFile)
+	assert.Nil(t, err)
+	if info, err := os.Stat(tokenFile); assert.Nil(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+	assert.True(t, ValidEventTapAuthorization("Bearer "+string(token)))
+	assert.False(t, ValidEventTapAuthorization(string(token)))
+	assert.False(t, ValidEventTapAuthorization("Bearer wrong"))
+
+	// An existing token is kept.
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("mytoken\n"), 0600))
+	assert.Nil(t, ConfigureEventTap(config.EventTapConfig{TokenFile: tokenFile}))
+	assert.True(t, ValidEventTapAuthorization("Bearer mytoken"))
+}
@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/open-horizon/anax/events"
+)
+
+// A Harness runs workers in tests against a FakeClock. It takes the place of ProcessEventMessages, dispatching the
+// events that the workers send each other, and moves the clock forward one timer at a time, letting the workers
+// settle after each one. A test of a timeout or a retry interval then runs in milliseconds and always sees the same
+// sequence of events.
+//
+// The workers must be created after the harness, so that they wait on its clock. The harness can't see inside a
+// worker, it considers the workers settled when the worker framework has seen no activity for a short real time.
+type Harness struct {
+	Clock    *FakeClock
+	Workers  *MessageHandlerRegistry
+	previous Clock
+	lock     sync.Mutex
+	events   []events.Message
+}
+
+// The source of the events that a test dispatches to the workers, as reported by the event tap.
+const HARNESS_EVENT_SOURCE = "Harness"
+
+// The real time that the workers have to be idle to be considered settled, and the most real time that Settle waits.
+const harnessQuietPeriod = 20 * time.Millisecond
+const harnessSettleTimeout = 30 * time.Second
+
+// Create a harness whose clock starts at the given time, and make it the clock of the workers. Close the harness to
+// restore the real clock.
+func NewHarness(start time.Time) *Harness {
+	h := &Harness{
+		Clock:    NewFakeClock(start),
+		Workers:  NewMessageHandlerRegistry(),
+		previous: GetClock(),
+	}
+	SetClock(h.Clock)
+	return h
+}
+
+// Add a worker to the harness, the events dispatched from now on are queued for it.
+func (h *Harness) Add(mh MessageHandler) {
+	h.Workers.Add(mh)
+}
+
+// Dispatch an event to the workers, as if a worker had sent it, and wait for the workers to settle.
+func (h *Harness) Dispatch(msg events.Message) error {
+	h.dispatch(msg, HARNESS_EVENT_SOURCE)
+	return h.Settle()
+}
+
+func (h *Harness) dispatch(msg events.Message, source string) {
+	h.lock.Lock()
+	h.events = append(h.events, msg)
+	h.lock.Unlock()
+	eventHandler(msg, source, h.Workers)
+}
+
+// Returns the events dispatched to the workers so far, in order, whether they were sent by the workers or the test.
+func (h *Harness) Events() []events.Message {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return append([]events.Message{}, h.events...)
+}
+
+// Move the clock forward, firing the timers that come due in order and letting the workers settle after each one.
+// The timers that the workers start while time is moving forward fire too, when they come due.
+func (h *Harness) Advance(d time.Duration) error {
+	target := h.Clock.Now().Add(d)
+	for {
+		next, ok := h.Clock.NextTimer()
+		if !ok || next.After(target) {
+			break
+		}
+		h.Clock.Advance(next.Sub(h.Clock.Now()))
+		if err := h.Settle(); err != nil {
+			return err
+		}
+	}
+	h.Clock.Advance(target.Sub(h.Clock.Now()))
+	return h.Settle()
+}
+
+// Dispatch the events sent by the workers until the workers have been idle for a short real time. Returns an error if
+// they are still busy after a long real time.
+func (h *Harness) Settle() error {
+	deadline := time.Now().Add(harnessSettleTimeout)
+	last := h.snapshot()
+	quietSince := time.Now()
+	for {
+		h.pump()
+		time.Sleep(time.Millisecond)
+
+		if s := h.snapshot(); !reflect.DeepEqual(s, last) {
+			last = s
+			quietSince = time.Now()
+		} else if time.Since(quietSince) >= harnessQuietPeriod {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("workers did not settle within %v", harnessSettleTimeout)
+		}
+	}
+}
+
+// Stop using the harness clock. The worker go routines are left waiting on the harness clock.
+func (h *Harness) Close() {
+	for name := range h.Workers.Handlers {
+		h.Workers.Remove(name)
+	}
+	SetClock(h.previous)
+}
+
+// Dispatch the events that the workers are sending.
+func (h *Harness) pump() {
+	for name, w := range h.Workers.Handlers {
+		select {
+		case ev := <-(*w).Messages():
+			h.dispatch(ev, name)
+		default:
+		}
+	}
+}
+
+// The counters that change while the workers are busy.
+func (h *Harness) snapshot() []uint64 {
+	h.lock.Lock()
+	s := []uint64{h.Clock.getWaits(), uint64(len(h.events))}
+	h.lock.Unlock()
+
+	names := make([]string, 0, len(h.Workers.Handlers))
+	for name := range h.Workers.Handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if q, ok := h.Workers.queues[name]; ok {
+			qs := q.getStatus()
+			s = append(s, qs.Queued, qs.Delivered, qs.Coalesced, qs.Dropped, uint64(qs.Length))
+		}
+
+		workerStatusManager.ManagerLock.Lock()
+		ws, ok := workerStatusManager.Workers[name]
+		workerStatusManager.ManagerLock.Unlock()
+		if ok {
+			ws.StatusLock.Lock()
+			if a := ws.Activity; a != nil {
+				inFlight := uint64(0)
+				if a.InFlightCommand != "" {
+					inFlight = 1
+				}
+				s = append(s, a.CommandsHandled, inFlight, uint64(len(a.queue)))
+			}
+			ws.StatusLock.Unlock()
+		}
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+//go:build unit
+// +build unit
+
+package worker
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// A worker that counts the times the framework calls it, so that tests can check when it was called.
+type harnessTestWorker struct {
+	BaseWorker
+	lock     sync.Mutex
+	noWork   int
+	polls    int
+	attempts int
+}
+
+func newHarnessTestWorker(t *testing.T, name string, noWorkInterval int, pollInterval int) *harnessTestWorker {
+	w := &harnessTestWorker{BaseWorker: NewBaseWorker(name, getBasicConfig(), nil)}
+	if pollInterval != 0 {
+		w.DispatchSubworker("poller", w.poll, pollInterval, true)
+	}
+	w.Start(w, noWorkInterval)
+	return w
+}
+
+func (w *harnessTestWorker) Messages() chan events.Message {
+	return w.BaseWorker.Manager.Messages
+}
+
+func (w *harnessTestWorker) NewEvent(incoming events.Message) {
+	if msg, ok := incoming.(*TestMessage); ok {
+		w.Commands <- NewTestCommand1(msg)
+	}
+}
+
+// The command fails twice, and is retried by the framework.
+func (w *harnessTestWorker) CommandHandler(command Command) bool {
+	switch command.(type) {
+	case *TestCommand1:
+		w.lock.Lock()
+		w.attempts++
+		retry := w.attempts < 3
+		w.lock.Unlock()
+		if retry {
+			w.AddDeferredCommand(command)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (w *harnessTestWorker) NoWorkHandler() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.noWork++
+}
+
+func (w *harnessTestWorker) poll() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.polls++
+	return 0
+}
+
+func (w *harnessTestWorker) counts() (int, int, int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.noWork, w.polls, w.attempts
+}
+
+func Test_FakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+
+	later := c.After(10 * time.Second)
+	sooner := c.After(5 * time.Second)
+	next, ok := c.NextTimer()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(5*time.Second), next)
+
+	c.Advance(5 * time.Second)
+	assert.Equal(t, start.Add(5*time.Second), <-sooner)
+	assert.Equal(t, 1, c.Timers())
+	select {
+	case <-later:
+		t.Fatal("the timer fired early")
+	default:
+	}
+
+	c.Advance(time.Minute)
+	assert.Equal(t, start.Add(10*time.Second), <-later)
+	assert.Equal(t, time.Minute+5*time.Second, c.Since(start))
+	assert.Equal(t, 0, c.Timers())
+}
+
+func Test_Harness_timers(t *testing.T) {
+	h := NewHarness(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer h.Close()
+
+	realStart := time.Now()
+	w := newHarnessTestWorker(t, "harnesstimers", 60, 30)
+	h.Add(w)
+	assert.Nil(t, h.Settle())
+
+	// An hour of worker time passes in a moment.
+	assert.Nil(t, h.Advance(time.Hour))
+	noWork, polls, _ := w.counts()
+	assert.Equal(t, 60, noWork)
+	assert.Equal(t, 120, polls)
+	assert.True(t, time.Since(realStart) < 30*time.Second)
+}
+
+func Test_Harness_deferred_commands(t *testing.T) {
+	h := NewHarness(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer h.Close()
+
+	w := newHarnessTestWorker(t, "harnessretry", 0, 0)
+	h.Add(w)
+	assert.Nil(t, h.Settle())
+
+	assert.Nil(t, h.Dispatch(NewTestMessage()))
+	_, _, attempts := w.counts()
+	assert.Equal(t, 1, attempts)
+	assert.Len(t, h.Events(), 1)
+
+	// Deferred commands are retried every 5 seconds until they succeed.
+	assert.Nil(t, h.Advance(4*time.Second))
+	_, _, attempts = w.counts()
+	assert.Equal(t, 1, attempts)
+
+	assert.Nil(t, h.Advance(time.Second))
+	_, _, attempts = w.counts()
+	assert.Equal(t, 2, attempts)
+
+	assert.Nil(t, h.Advance(time.Minute))
+	_, _, attempts = w.counts()
+	assert.Equal(t, 3, attempts)
+}
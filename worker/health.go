@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The maximum time a readiness check should take. Checks that call out to another process or the network should
+// use it as their timeout so that a health probe does not hang.
+const READINESS_CHECK_TIMEOUT = 5 * time.Second
+
+// A readiness check reports why something a worker depends on, such as the container runtime or the exchange, is not
+// usable. It returns nil when it is usable. Checks are called on the API's go routine, not on the worker's, so they
+// must be quick and must not touch worker state that is not safe for concurrent use.
+type ReadinessCheck func() error
+
+// The result of one health check, as reported by the /health APIs.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Ready  bool   `json:"ready"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (h HealthCheck) String() string {
+	return fmt.Sprintf("Name: %v, Ready: %v, Detail: %v", h.Name, h.Ready, h.Detail)
+}
+
+// Register a readiness check for a worker. The check is only run while the worker is running, so a worker that is
+// terminated on purpose, e.g. the container worker on a cluster node, does not make the node unready.
+func (w *WorkerStatusManager) SetReadinessCheck(name string, checkName string, check ReadinessCheck) {
+	w.ManagerLock.Lock()
+	defer w.ManagerLock.Unlock()
+
+	ws := w.getWorker(name)
+	ws.StatusLock.Lock()
+	defer ws.StatusLock.Unlock()
+	if ws.readiness == nil {
+		ws.readiness = make(map[string]ReadinessCheck)
+	}
+	ws.readiness[checkName] = check
+}
+
+// Return the readiness of the workers. The first check is for the workers themselves, a worker is not ready when its
+// initialization failed or when the watchdog has found it stuck. It is followed by the checks registered by running
+// workers, in name order.
+func (w *WorkerStatusManager) ReadinessChecks() []HealthCheck {
+	failed := make([]string, 0)
+	checks := make(map[string]ReadinessCheck)
+
+	w.ManagerLock.Lock()
+	for name, ws := range w.Workers {
+		ws.StatusLock.Lock()
+		switch {
+		case ws.Status == STATUS_INIT_FAILED:
+			failed = append(failed, fmt.Sprintf("%v %v", name, ws.Status))
+		case ws.Status == STATUS_TERMINATING || ws.Status == STATUS_TERMINATED:
+			// Terminated workers are not expected to be doing anything.
+		default:
+			if ws.Activity != nil && ws.Activity.Stuck {
+				failed = append(failed, fmt.Sprintf("%v stuck handling %v", name, ws.Activity.InFlightCommand))
+			}
+			for checkName, check := range ws.readiness {
+				checks[checkName] = check
+			}
+		}
+		ws.StatusLock.Unlock()
+	}
+	w.ManagerLock.Unlock()
+
+	sort.Strings(failed)
+	res := []HealthCheck{{Name: "workers", Ready: len(failed) == 0, Detail: strings.Join(failed, ", ")}}
+
+	// The checks can be slow, so they are run without holding any locks.
+	names := make([]string, 0, len(checks))
+	for checkName := range checks {
+		names = append(names, checkName)
+	}
+	sort.Strings(names)
+	for _, checkName := range names {
+		hc := HealthCheck{Name: checkName, Ready: true}
+		if err := checks[checkName](); err != nil {
+			hc.Ready = false
+			hc.Detail = err.Error()
+		}
+		res = append(res, hc)
+	}
+	return res
+}
+
+// Return the workers that have been handling the same command for at least the input duration, without relying on
+// the watchdog, which might be disabled. These workers are wedged, there is no point waiting for them any longer.
+func (w *WorkerStatusManager) WedgedWorkers(threshold time.Duration) []string {
+	w.ManagerLock.Lock()
+	defer w.ManagerLock.Unlock()
+
+	wedged := make([]string, 0)
+	for name, ws := range w.Workers {
+		ws.StatusLock.Lock()
+		if a := ws.Activity; a != nil && a.InFlightCommand != "" && time.Since(a.inFlightStart) >= threshold {
+			wedged = append(wedged, fmt.Sprintf("%v handling %v for %v", name, a.InFlightCommand, time.Since(a.inFlightStart).Round(time.Second)))
+		}
+		ws.StatusLock.Unlock()
+	}
+	sort.Strings(wedged)
+	return wedged
+}
+
+// Register a readiness check for this worker.
+func (w *BaseWorker) SetReadinessCheck(checkName string, check ReadinessCheck) {
+	workerStatusManager.SetReadinessCheck(w.GetName(), checkName, check)
+}
@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// The events dispatched to the workers only live in channels, so the events in flight when the process stops are lost.
+// The event journal is a write-ahead log of the events that start the launch of a workload or service. An event is
+// written to the journal before it is dispatched, and removed when the dispatcher sees the event that ends its work,
+// e.g. the workload's execution began or failed, or the agreement was cancelled. A later event for the same work
+// replaces the earlier one, so an image fetch completion replaces the agreement reached event that started it.
+//
+// When the process starts, the journaled events are dispatched again before any other event. An event is only replayed
+// if its work still has to be done, so a workload that started before the process stopped is not started again.
+
+type eventJournal struct {
+	db     *bolt.DB
+	types  map[string]bool // The event types that are journaled.
+	maxAge time.Duration   // Older events are dropped instead of replayed.
+}
+
+// The journal is only turned on by the agent, and only when event types are configured.
+var journal *eventJournal
+
+// Turn on the event journal. The events journaled before the last restart are replayed when the event dispatcher starts.
+func ConfigureEventJournal(db *bolt.DB, cfg config.EventJournalConfig) error {
+	if len(cfg.Events) == 0 {
+		journal = nil
+		return nil
+	}
+
+	types := make(map[string]bool)
+	for _, t := range cfg.Events {
+		if !cutil.SliceContains(events.JournaledMessageTypes(), t) {
+			return fmt.Errorf("event type %v can not be journaled, the supported types are %v", t, events.JournaledMessageTypes())
+		}
+		types[t] = true
+	}
+
+	journal = &eventJournal{
+		db:     db,
+		types:  types,
+		maxAge: time.Duration(cfg.GetMaxAgeH()) * time.Hour,
+	}
+	journal.updateLength()
+	glog.V(3).Infof(mdLogString(fmt.Sprintf("event journal configured with %v", cfg)))
+	return nil
+}
+
+// Write the event to the journal before it is dispatched, or remove the journaled events whose work it ends.
+func (j *eventJournal) record(msg events.Message) {
+	if key := events.GetJournalCompletionKey(msg); key != "" {
+		if done, err := persistence.DeleteJournaledEvents(j.db, key); err != nil {
+			glog.Errorf(mdLogString(fmt.Sprintf("unable to remove journaled events for %v, error: %v", key, err)))
+		} else if len(done) != 0 {
+			glog.V(5).Infof(mdLogString(fmt.Sprintf("removed journaled events for %v", key)))
+			for _, e := range done {
+				eventJournalCompletions.Inc(e.Type)
+			}
+			j.updateLength()
+		}
+	}
+
+	if t, key := events.GetJournalKey(msg); t != "" && j.types[t] {
+		if payload, err := events.EncodeJournaledMessage(msg); err != nil {
+			glog.Errorf(mdLogString(fmt.Sprintf("unable to journal %v, error: %v", msg.ShortString(), err)))
+		} else if seq, err := persistence.JournalEvent(j.db, t, key, payload); err != nil {
+			glog.Errorf(mdLogString(fmt.Sprintf("unable to journal %v, error: %v", msg.ShortString(), err)))
+		} else {
+			glog.V(5).Infof(mdLogString(fmt.Sprintf("journaled %v for %v as %v", t, key, seq)))
+			eventJournalWrites.Inc(t)
+			j.updateLength()
+		}
+	}
+}
+
+// Returns the journaled events that have to be dispatched again, in the order they were journaled. The journaled events
+// that are not replayed are removed from the journal. The replayed events stay in the journal until their work ends.
+func (j *eventJournal) replay(now time.Time) []events.Message {
+	journaled, err := persistence.FindJournaledEvents(j.db)
+	if err != nil {
+		glog.Errorf(mdLogString(fmt.Sprintf("unable to read the event journal, error: %v", err)))
+		return nil
+	}
+
+	replayed := make([]events.Message, 0, len(journaled))
+	for _, e := range journaled {
+		result := METRIC_JOURNAL_REPLAYED
+
+		if !j.types[e.Type] {
+			result = METRIC_JOURNAL_DROPPED
+		} else if now.Sub(time.Unix(e.Journaled, 0)) > j.maxAge {
+			result = METRIC_JOURNAL_EXPIRED
+		} else if msg, err := events.DecodeJournaledMessage(e.Type, e.Payload); err != nil {
+			glog.Errorf(mdLogString(fmt.Sprintf("unable to replay journaled event %v, error: %v", e, err)))
+			result = METRIC_JOURNAL_DROPPED
+		} else if needed, err := events.IsJournaledMessageNeeded(j.db, msg); err != nil {
+			// Leave the event in the journal, it can be replayed on the next restart.
+			glog.Errorf(mdLogString(fmt.Sprintf("unable to check if journaled event %v is still needed, error: %v", e, err)))
+			continue
+		} else if !needed {
+			result = METRIC_JOURNAL_SKIPPED
+		} else {
+			replayed = append(replayed, msg)
+		}
+
+		glog.V(3).Infof(mdLogString(fmt.Sprintf("journaled event %v %v", e, result)))
+		eventJournalReplays.Inc(e.Type, result)
+		if result != METRIC_JOURNAL_REPLAYED {
+			if err := persistence.DeleteJournaledEvent(j.db, e.Seq); err != nil {
+				glog.Errorf(mdLogString(fmt.Sprintf("unable to remove journaled event %v, error: %v", e, err)))
+			}
+		}
+	}
+
+	j.updateLength()
+	return replayed
+}
+
+// Remove all the journaled events, e.g. when the node is unregistered.
+func ClearEventJournal() {
+	j := journal
+	if j == nil {
+		return
+	} else if err := persistence.DeleteEventJournal(j.db); err != nil {
+		glog.Errorf(mdLogString(fmt.Sprintf("unable to delete the event journal, error: %v", err)))
+	}
+	eventJournalLength.Set(0)
+}
+
+func (j *eventJournal) updateLength() {
+	if journaled, err := persistence.FindJournaledEvents(j.db); err != nil {
+		glog.Errorf(mdLogString(fmt.Sprintf("unable to read the event journal, error: %v", err)))
+	} else {
+		eventJournalLength.Set(float64(len(journaled)))
+	}
+}
@@ -0,0 +1,137 @@
+//go:build unit
+// +build unit
+
+package worker
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func journalSetup(t *testing.T) *bolt.DB {
+	dir, err := os.MkdirTemp("", "utdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := bolt.Open(path.Join(dir, "anax-ut.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newJournalTestAgreement(t *testing.T, db *bolt.DB, agreementId string) {
+	wi, _ := persistence.NewWorkloadInfo("url", "org", "version", "")
+	if _, err := persistence.NewEstablishedAgreement(db, "name", agreementId, "consumerId", "{}", "Basic", 1, []persistence.ServiceSpec{}, "signature", "address", "", "", "", wi, 180); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ConfigureEventJournal(t *testing.T) {
+	db := journalSetup(t)
+	defer func() { journal = nil }()
+
+	assert.Nil(t, ConfigureEventJournal(db, config.EventJournalConfig{}))
+	assert.Nil(t, journal)
+
+	assert.NotNil(t, ConfigureEventJournal(db, config.EventJournalConfig{Events: []string{"NodeShutdownMessage"}}))
+
+	assert.Nil(t, ConfigureEventJournal(db, config.EventJournalConfig{Events: []string{events.JOURNAL_AGREEMENT_REACHED}}))
+	if assert.NotNil(t, journal) {
+		assert.True(t, journal.types[events.JOURNAL_AGREEMENT_REACHED])
+		assert.False(t, journal.types[events.JOURNAL_IMAGE_FETCH])
+		assert.Equal(t, 24*time.Hour, journal.maxAge)
+	}
+}
+
+func Test_EventJournal_record(t *testing.T) {
+	db := journalSetup(t)
+	j := &eventJournal{db: db, types: map[string]bool{events.JOURNAL_AGREEMENT_REACHED: true, events.JOURNAL_IMAGE_FETCH: true}, maxAge: time.Hour}
+
+	lc := &events.AgreementLaunchContext{AgreementProtocol: "Basic", AgreementId: "ag1"}
+	j.record(events.NewAgreementMessage(events.AGREEMENT_REACHED, lc))
+
+	journaled, _ := persistence.FindJournaledEvents(db)
+	if assert.Len(t, journaled, 1) {
+		assert.Equal(t, events.JOURNAL_AGREEMENT_REACHED, journaled[0].Type)
+		assert.Equal(t, "agreement/ag1", journaled[0].Key)
+	}
+
+	// The image fetch continues the work of the agreement reached event.
+	j.record(events.NewImageFetchMessage(events.IMAGE_FETCHED, &containermessage.DeploymentDescription{}, lc, nil))
+	journaled, _ = persistence.FindJournaledEvents(db)
+	if assert.Len(t, journaled, 1) {
+		assert.Equal(t, events.JOURNAL_IMAGE_FETCH, journaled[0].Type)
+	}
+
+	// Unrelated events do not change the journal.
+	j.record(events.NewWorkloadMessage(events.EXECUTION_BEGUN, "Basic", "ag2", nil))
+	journaled, _ = persistence.FindJournaledEvents(db)
+	assert.Len(t, journaled, 1)
+
+	// The workload started, so the work is done.
+	j.record(events.NewWorkloadMessage(events.EXECUTION_BEGUN, "Basic", "ag1", nil))
+	journaled, _ = persistence.FindJournaledEvents(db)
+	assert.Len(t, journaled, 0)
+
+	// Image fetch errors end the work rather than being journaled.
+	j.record(events.NewAgreementMessage(events.AGREEMENT_REACHED, lc))
+	j.record(events.NewImageFetchMessage(events.IMAGE_FETCH_ERROR, nil, lc, nil))
+	journaled, _ = persistence.FindJournaledEvents(db)
+	assert.Len(t, journaled, 0)
+
+	// Types that are not configured are not journaled.
+	j.types = map[string]bool{events.JOURNAL_IMAGE_FETCH: true}
+	j.record(events.NewAgreementMessage(events.AGREEMENT_REACHED, lc))
+	journaled, _ = persistence.FindJournaledEvents(db)
+	assert.Len(t, journaled, 0)
+}
+
+func Test_EventJournal_replay(t *testing.T) {
+	db := journalSetup(t)
+	j := &eventJournal{db: db, types: map[string]bool{events.JOURNAL_AGREEMENT_REACHED: true, events.JOURNAL_IMAGE_FETCH: true}, maxAge: time.Hour}
+
+	// ag1 has not started, ag2 has started, ag3 is not in the database.
+	newJournalTestAgreement(t, db, "ag1")
+	newJournalTestAgreement(t, db, "ag2")
+	if _, err := persistence.AgreementStateExecutionStarted(db, "ag2", "Basic"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"ag1", "ag2", "ag3"} {
+		j.record(events.NewAgreementMessage(events.AGREEMENT_REACHED, &events.AgreementLaunchContext{AgreementProtocol: "Basic", AgreementId: id}))
+	}
+
+	replayed := j.replay(time.Now())
+	if assert.Len(t, replayed, 1) {
+		msg, ok := replayed[0].(*events.AgreementReachedMessage)
+		if assert.True(t, ok) {
+			assert.Equal(t, events.AGREEMENT_REACHED, msg.Event().Id)
+			assert.Equal(t, "ag1", msg.LaunchContext().AgreementId)
+		}
+	}
+
+	// The replayed event stays in the journal until its work ends, the others are removed.
+	journaled, _ := persistence.FindJournaledEvents(db)
+	if assert.Len(t, journaled, 1) {
+		assert.Equal(t, "agreement/ag1", journaled[0].Key)
+	}
+
+	// An event that is too old is dropped.
+	replayed = j.replay(time.Now().Add(2 * time.Hour))
+	assert.Len(t, replayed, 0)
+	journaled, _ = persistence.FindJournaledEvents(db)
+	assert.Len(t, journaled, 0)
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"github.com/open-horizon/anax/metrics"
+)
+
+// Worker metrics, exposed by the /metrics API of the agent and the agbot.
+var workerStatusGauge = metrics.NewGaugeVec("anax_worker_status",
+	"Current status of each worker, the sample for the worker's current status is 1.", "worker", "status")
+
+var subworkerStatusGauge = metrics.NewGaugeVec("anax_subworker_status",
+	"Current status of each subworker, the sample for the subworker's current status is 1.", "worker", "subworker", "status")
+
+var workerRestarts = metrics.NewCounterVec("anax_worker_restarts_total",
+	"Number of times a worker or subworker was started again after it was first started.", "worker", "subworker")
+
+var handlerLatency = metrics.NewHistogramVec("anax_worker_handler_seconds",
+	"Time taken by each worker to handle a command.", metrics.DurationBuckets, "worker")
+
+var queueLengthGauge = metrics.NewGaugeVec("anax_worker_queue_length",
+	"Number of commands waiting in each worker's command queue.", "worker")
+
+var stuckGauge = metrics.NewGaugeVec("anax_worker_stuck",
+	"Set to 1 while the watchdog considers a worker stuck handling a command.", "worker")
+
+var watchdogAlerts = metrics.NewCounterVec("anax_worker_watchdog_alerts_total",
+	"Alerts raised by the worker watchdog, by worker and kind (stuck, backlog, recovered).", "worker", "kind")
+
+var eventQueueLengthGauge = metrics.NewGaugeVec("anax_worker_event_queue_length",
+	"Number of events waiting to be delivered to each worker.", "worker")
+
+var eventsDropped = metrics.NewCounterVec("anax_worker_events_dropped_total",
+	"Events dropped because a worker's event queue was full.", "worker")
+
+var eventsCoalesced = metrics.NewCounterVec("anax_worker_events_coalesced_total",
+	"Queued events replaced by a newer event with the same key.", "worker")
+
+var eventQueueBlocked = metrics.NewCounterVec("anax_worker_event_queue_blocked_total",
+	"Times the event dispatcher waited for room in a worker's full event queue.", "worker")
+
+var eventTapDropped = metrics.NewCounterVec("anax_event_tap_dropped_total",
+	"Events not sent to an event tap client because it fell behind.")
+
+var eventJournalLength = metrics.NewGaugeVec("anax_event_journal_length",
+	"Events in the event journal whose work has not finished yet.")
+
+var eventJournalWrites = metrics.NewCounterVec("anax_event_journal_writes_total",
+	"Events written to the event journal before they were dispatched, by event type.", "type")
+
+var eventJournalCompletions = metrics.NewCounterVec("anax_event_journal_completions_total",
+	"Journaled events removed because the work they started finished, by event type.", "type")
+
+var eventJournalReplays = metrics.NewCounterVec("anax_event_journal_replays_total",
+	"Journaled events found when the process started, by event type and result (replayed, skipped, expired or dropped).", "type", "result")
+
+const METRIC_JOURNAL_REPLAYED = "replayed"
+const METRIC_JOURNAL_SKIPPED = "skipped"
+const METRIC_JOURNAL_EXPIRED = "expired"
+const METRIC_JOURNAL_DROPPED = "dropped"
+
+// Refresh the worker status gauges from the worker status manager. This is called when the metrics are scraped
+// so that workers and subworkers that have gone away are no longer reported.
+func RefreshWorkerMetrics() {
+	wsm := GetWorkerStatusManager()
+	wsm.ManagerLock.Lock()
+	defer wsm.ManagerLock.Unlock()
+
+	workerStatusGauge.Reset()
+	subworkerStatusGauge.Reset()
+	queueLengthGauge.Reset()
+	eventQueueLengthGauge.Reset()
+	for name, ws := range wsm.Workers {
+		ws.StatusLock.Lock()
+		workerStatusGauge.Set(1, name, ws.Status)
+		for subname, status := range ws.SubworkerStatus {
+			subworkerStatusGauge.Set(1, name, subname, status)
+		}
+		if ws.Activity != nil {
+			queueLengthGauge.Set(float64(len(ws.Activity.queue)), name)
+		}
+		if ws.eventQueue != nil {
+			eventQueueLengthGauge.Set(float64(ws.eventQueue.length()), name)
+		}
+		ws.StatusLock.Unlock()
+	}
+}
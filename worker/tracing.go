@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"fmt"
+
+	"github.com/open-horizon/anax/tracing"
+)
+
+// Return the trace context of the command the worker is currently handling, if it is part of a trace.
+func (w *BaseWorker) ActiveTraceContext() tracing.SpanContext {
+	return w.active.Context()
+}
+
+// Start a span for a command that carries a trace context. The span covers the worker's handling of the command, so
+// exchange calls made while handling the command are recorded as children of it.
+func (w *BaseWorker) startCommandSpan(command Command) *tracing.Span {
+	parent := tracing.ContextOf(command)
+	if !parent.IsValid() {
+		return nil
+	}
+	span := tracing.StartSpan(fmt.Sprintf("%v %T", w.GetName(), command), parent)
+	span.SetAttribute("worker", w.GetName())
+	w.active.Set(span)
+	return span
+}
+
+func (w *BaseWorker) endCommandSpan(span *tracing.Span, handled bool) {
+	if span == nil {
+		return
+	}
+	if !handled {
+		span.SetError(fmt.Errorf("unknown command"))
+	}
+	w.active.Set(nil)
+	span.End()
+}
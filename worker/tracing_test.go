@@ -0,0 +1,110 @@
+//go:build unit
+// +build unit
+
+package worker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/tracing"
+)
+
+type testExporter struct {
+	lock  sync.Mutex
+	spans []*tracing.Span
+}
+
+func (e *testExporter) Export(serviceName string, spans []*tracing.Span) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *testExporter) Shutdown() error {
+	return nil
+}
+
+// A command that is part of an agreement's trace.
+type TracedTestCommand struct {
+	tracing.Carrier
+}
+
+func (t *TracedTestCommand) ShortString() string {
+	return "TracedTestCommand"
+}
+
+// A worker that calls the exchange while handling a command.
+type TracingTestWorker struct {
+	BaseWorker
+	URL string
+}
+
+func (t *TracingTestWorker) Initialize() bool {
+	return true
+}
+
+func (t *TracingTestWorker) NoWorkHandler() {}
+
+func (t *TracingTestWorker) CommandHandler(command Command) bool {
+	switch command.(type) {
+	case *TracedTestCommand:
+		resp, err := t.GetHTTPFactory().NewHTTPClient(nil).Get(t.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return true
+	}
+	return false
+}
+
+func Test_CommandTracing(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(tracing.TRACEPARENT_HEADER)
+	}))
+	defer ts.Close()
+
+	e := &testExporter{}
+	tracing.Start("test-service", e)
+
+	factory := &config.HTTPClientFactory{
+		NewHTTPClient: func(overrideTimeoutS *uint) *http.Client { return ts.Client() },
+	}
+	cfg := getBasicConfig()
+	w := &TracingTestWorker{
+		BaseWorker: NewBaseWorker("tracingtest", cfg, NewExchangeContext("myorg/myid", "token", ts.URL, "", "", factory)),
+		URL:        ts.URL,
+	}
+
+	root := tracing.AgreementTraceContext("agreement1")
+	cmd := &TracedTestCommand{}
+	cmd.SetTraceContext(root)
+	w.internalCommandhandler(w, cmd)
+	if !strings.Contains(traceparent, root.TraceId) {
+		t.Errorf("exchange call should carry the agreement trace, traceparent was %v", traceparent)
+	}
+
+	// Commands without a trace context are not traced.
+	w.internalCommandhandler(w, &TracedTestCommand{})
+
+	tracing.Stop()
+
+	if len(e.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", e.spans)
+	}
+
+	httpSpan, cmdSpan := e.spans[0], e.spans[1]
+	if cmdSpan.Name != "tracingtest *worker.TracedTestCommand" || cmdSpan.Parent != root {
+		t.Errorf("unexpected command span %v", cmdSpan)
+	} else if httpSpan.Name != "HTTP GET" || httpSpan.Parent != cmdSpan.Context {
+		t.Errorf("exchange call span %v should be a child of the command span %v", httpSpan, cmdSpan)
+	} else if w.ActiveTraceContext().IsValid() {
+		t.Errorf("worker should not have an active span after the command is handled")
+	}
+}
@@ -0,0 +1,200 @@
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+)
+
+// The kinds of alert raised by the watchdog.
+const (
+	WATCHDOG_ALERT_STUCK     = "stuck"     // The worker has been handling the same command for too long
+	WATCHDOG_ALERT_BACKLOG   = "backlog"   // The worker's command queue is close to full
+	WATCHDOG_ALERT_RECOVERED = "recovered" // The worker is no longer stuck or backlogged
+)
+
+// An alert raised by the watchdog when a worker's state changes.
+type WatchdogAlert struct {
+	Worker        string        `json:"worker"`
+	Kind          string        `json:"kind"`
+	Command       string        `json:"command,omitempty"`
+	InFlight      time.Duration `json:"in_flight"`
+	QueueLength   int           `json:"queue_length"`
+	QueueCapacity int           `json:"queue_capacity"`
+}
+
+func (a WatchdogAlert) String() string {
+	return fmt.Sprintf("Worker: %v, Kind: %v, Command: %v, InFlight: %v, Queue: %v/%v", a.Worker, a.Kind, a.Command, a.InFlight, a.QueueLength, a.QueueCapacity)
+}
+
+// Alert handlers are called on the watchdog's go routine, not on the worker's, so they still run when the worker
+// they are about is stuck.
+type WatchdogAlertHandler func(alert WatchdogAlert)
+
+// The watchdog periodically checks the command processing activity that workers record in the worker status
+// manager, looking for workers that are blocked in a command handler or whose command queue is backing up. Either
+// will eventually block the event dispatcher, which stops every worker in the process.
+type Watchdog struct {
+	lock     sync.Mutex
+	handlers []WatchdogAlertHandler
+	stop     chan bool
+}
+
+var watchdog = &Watchdog{}
+
+func GetWatchdog() *Watchdog {
+	return watchdog
+}
+
+// Register a function to be called for each alert.
+func (wd *Watchdog) AddAlertHandler(handler WatchdogAlertHandler) {
+	wd.lock.Lock()
+	defer wd.lock.Unlock()
+	wd.handlers = append(wd.handlers, handler)
+}
+
+// Start checking the workers. This routine does not need to be a subworker because it does not belong to a worker,
+// and it will terminate on its own when the process terminates.
+func (wd *Watchdog) Start(cfg config.WatchdogConfig) {
+	if cfg.Disable {
+		glog.V(3).Infof(wdLogString("disabled"))
+		return
+	}
+
+	wd.lock.Lock()
+	if wd.stop != nil {
+		wd.lock.Unlock()
+		return
+	}
+	stop := make(chan bool)
+	wd.stop = stop
+	wd.lock.Unlock()
+
+	glog.V(3).Infof(wdLogString(fmt.Sprintf("started with %v", cfg)))
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.GetCheckIntervalS()) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				wd.Check(GetWorkerStatusManager(), cfg)
+			}
+		}
+	}()
+}
+
+func (wd *Watchdog) Stop() {
+	wd.lock.Lock()
+	defer wd.lock.Unlock()
+	if wd.stop != nil {
+		close(wd.stop)
+		wd.stop = nil
+	}
+}
+
+// Check every worker once and raise alerts for the workers whose state has changed. The alerts are returned.
+func (wd *Watchdog) Check(wsm *WorkerStatusManager, cfg config.WatchdogConfig) []WatchdogAlert {
+	stuckThreshold := time.Duration(cfg.GetStuckThresholdS()) * time.Second
+	queueThreshold := cfg.GetQueueThresholdPercent()
+
+	wsm.ManagerLock.Lock()
+	names := make([]string, 0, len(wsm.Workers))
+	for name := range wsm.Workers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	alerts := make([]WatchdogAlert, 0)
+	for _, name := range names {
+		ws := wsm.Workers[name]
+		ws.StatusLock.Lock()
+		if a := ws.Activity; a != nil {
+			alert := WatchdogAlert{
+				Worker:        name,
+				Command:       a.InFlightCommand,
+				QueueLength:   len(a.queue),
+				QueueCapacity: a.QueueCapacity,
+			}
+			if a.InFlightCommand != "" {
+				alert.InFlight = time.Since(a.inFlightStart)
+			}
+
+			stuck := a.InFlightCommand != "" && alert.InFlight >= stuckThreshold
+			backlogged := alert.QueueCapacity > 0 && alert.QueueLength*100 >= alert.QueueCapacity*queueThreshold
+
+			if stuck && !a.Stuck {
+				alert.Kind = WATCHDOG_ALERT_STUCK
+				alerts = append(alerts, alert)
+			} else if backlogged && !a.Backlogged && !stuck {
+				alert.Kind = WATCHDOG_ALERT_BACKLOG
+				alerts = append(alerts, alert)
+			} else if !stuck && !backlogged && (a.Stuck || a.Backlogged) {
+				alert.Kind = WATCHDOG_ALERT_RECOVERED
+				alerts = append(alerts, alert)
+			}
+
+			a.Stuck = stuck
+			a.Backlogged = backlogged
+			if stuck {
+				stuckGauge.Set(1, name)
+			} else {
+				stuckGauge.Set(0, name)
+			}
+		}
+		ws.StatusLock.Unlock()
+	}
+	wsm.ManagerLock.Unlock()
+
+	// Alert handlers are called without holding any locks, they might need the worker status.
+	for _, alert := range alerts {
+		wd.raise(alert, cfg.DumpStacks)
+	}
+	return alerts
+}
+
+func (wd *Watchdog) raise(alert WatchdogAlert, dumpStacks bool) {
+	watchdogAlerts.Inc(alert.Worker, alert.Kind)
+
+	switch alert.Kind {
+	case WATCHDOG_ALERT_STUCK:
+		glog.Errorf(wdLogString(fmt.Sprintf("worker %v has been handling %v for %v", alert.Worker, alert.Command, alert.InFlight)))
+		if dumpStacks {
+			glog.Errorf(wdLogString(fmt.Sprintf("go routine stacks:\n%v", goroutineStacks())))
+		}
+	case WATCHDOG_ALERT_BACKLOG:
+		glog.Errorf(wdLogString(fmt.Sprintf("worker %v command queue is backing up, %v of %v commands waiting", alert.Worker, alert.QueueLength, alert.QueueCapacity)))
+	case WATCHDOG_ALERT_RECOVERED:
+		glog.Infof(wdLogString(fmt.Sprintf("worker %v has recovered", alert.Worker)))
+	}
+
+	wd.lock.Lock()
+	handlers := make([]WatchdogAlertHandler, len(wd.handlers))
+	copy(handlers, wd.handlers)
+	wd.lock.Unlock()
+
+	for _, handler := range handlers {
+		handler(alert)
+	}
+}
+
+// Return the stacks of all go routines, in the same format as an unrecovered panic.
+func goroutineStacks() string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		return fmt.Sprintf("unable to collect go routine stacks, error: %v", err)
+	}
+	return buf.String()
+}
+
+var wdLogString = func(v interface{}) string {
+	return fmt.Sprintf("Worker Watchdog: %v", v)
+}
@@ -0,0 +1,92 @@
+//go:build unit
+// +build unit
+
+package worker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WorkerActivity(t *testing.T) {
+
+	wsm := NewWorkerStatusManager()
+	queue := make(chan Command, 10)
+
+	wsm.SetWorkerStatus("worker1", STATUS_INITIALIZED)
+	wsm.SetWorkerQueue("worker1", queue)
+	queue <- NewTerminateCommand("test")
+
+	wsm.CommandStarted("worker1", "*worker.TestCommand1")
+	a := wsm.Workers["worker1"].Activity
+	assert.Equal(t, "*worker.TestCommand1", a.InFlightCommand, "The in-flight command should be recorded.")
+	assert.NotZero(t, a.InFlightSince, "The in-flight command start time should be recorded.")
+
+	wsm.CommandEnded("worker1")
+	assert.Equal(t, "", a.InFlightCommand, "There should be no in-flight command.")
+	assert.Equal(t, uint64(1), a.CommandsHandled, "One command should have been handled.")
+
+	// The queue length is reported when the status is serialized.
+	serial, err := json.Marshal(wsm)
+	assert.Nil(t, err, "The worker status should serialize.")
+	assert.True(t, strings.Contains(string(serial), `"queue_length":1`), "The queue length should be reported: "+string(serial))
+	assert.True(t, strings.Contains(string(serial), `"queue_capacity":10`), "The queue capacity should be reported: "+string(serial))
+
+	// Workers without a command queue have no activity.
+	wsm.SetWorkerStatus("worker2", STATUS_INITIALIZED)
+	wsm.CommandStarted("worker2", "*worker.TestCommand1")
+	assert.Nil(t, wsm.Workers["worker2"].Activity, "worker2 should have no activity.")
+}
+
+func Test_Watchdog(t *testing.T) {
+
+	wsm := NewWorkerStatusManager()
+	wd := &Watchdog{}
+	raised := make([]WatchdogAlert, 0)
+	wd.AddAlertHandler(func(alert WatchdogAlert) { raised = append(raised, alert) })
+
+	cfg := config.WatchdogConfig{StuckThresholdS: 60, QueueThresholdPercent: 50}
+
+	stuckQueue := make(chan Command, 10)
+	wsm.SetWorkerQueue("stuck", stuckQueue)
+	busyQueue := make(chan Command, 4)
+	wsm.SetWorkerQueue("busy", busyQueue)
+	wsm.SetWorkerQueue("idle", make(chan Command, 10))
+
+	// The stuck worker started its command longer ago than the threshold.
+	wsm.CommandStarted("stuck", "*worker.TestCommand1")
+	wsm.Workers["stuck"].Activity.inFlightStart = time.Now().Add(-2 * time.Minute)
+
+	// The busy worker's queue is half full.
+	busyQueue <- NewTerminateCommand("1")
+	busyQueue <- NewTerminateCommand("2")
+
+	alerts := wd.Check(wsm, cfg)
+	assert.Equal(t, 2, len(alerts), "There should be 2 alerts.")
+	assert.Equal(t, 2, len(raised), "The alert handler should be called for each alert.")
+	assert.Equal(t, WATCHDOG_ALERT_BACKLOG, alerts[0].Kind, "The busy worker should be backlogged.")
+	assert.Equal(t, 2, alerts[0].QueueLength, "The busy worker's queue length should be reported.")
+	assert.Equal(t, WATCHDOG_ALERT_STUCK, alerts[1].Kind, "The stuck worker should be stuck.")
+	assert.Equal(t, "*worker.TestCommand1", alerts[1].Command, "The stuck command should be reported.")
+	assert.True(t, wsm.Workers["stuck"].Activity.Stuck, "The stuck worker's activity should say it is stuck.")
+	assert.Equal(t, float64(1), stuckGauge.Get("stuck"), "The stuck gauge should be set.")
+
+	// Alerts are only raised when a worker's state changes.
+	alerts = wd.Check(wsm, cfg)
+	assert.Equal(t, 0, len(alerts), "There should be no new alerts.")
+
+	// Both workers recover.
+	wsm.CommandEnded("stuck")
+	<-busyQueue
+	<-busyQueue
+	alerts = wd.Check(wsm, cfg)
+	assert.Equal(t, 2, len(alerts), "There should be 2 alerts.")
+	assert.Equal(t, WATCHDOG_ALERT_RECOVERED, alerts[0].Kind, "The busy worker should have recovered.")
+	assert.Equal(t, WATCHDOG_ALERT_RECOVERED, alerts[1].Kind, "The stuck worker should have recovered.")
+	assert.Equal(t, float64(0), stuckGauge.Get("stuck"), "The stuck gauge should be cleared.")
+}
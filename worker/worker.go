@@ -5,7 +5,9 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/tracing"
 	"runtime"
+	"sort"
 	"time"
 )
 
@@ -112,6 +114,8 @@ type Worker interface {
 	GetHTTPFactory() *config.HTTPClientFactory
 }
 
+// The URLs are the preferred management hub endpoints. When alternate endpoints are configured, the clients from the
+// HTTPFactory send the requests for these URLs to whichever endpoint is active.
 type BaseExchangeContext struct {
 	Id          string
 	Token       string
@@ -179,9 +183,9 @@ func (w *BaseWorker) GetAgbotURL() string {
 
 func (w *BaseWorker) GetHTTPFactory() *config.HTTPClientFactory {
 	if w.EC != nil {
-		return w.EC.HTTPFactory
+		return tracing.TracedFactory(w.EC.HTTPFactory, w.ActiveTraceContext)
 	} else {
-		return w.Config.Collaborators.HTTPClientFactory
+		return tracing.TracedFactory(w.Config.Collaborators.HTTPClientFactory, w.ActiveTraceContext)
 	}
 }
 
@@ -195,6 +199,7 @@ type BaseWorker struct {
 	ShuttingDown     bool
 	EC               *BaseExchangeContext // Holds the exchange context state
 	noWorkInterval   int
+	active           *tracing.ActiveSpan // The traced command being handled, if any
 }
 
 func NewBaseWorker(name string, cfg *config.HorizonConfig, ec *BaseExchangeContext) BaseWorker {
@@ -219,6 +224,7 @@ func NewBaseWorker(name string, cfg *config.HorizonConfig, ec *BaseExchangeConte
 		ShuttingDown:     false,
 		EC:               ec,
 		noWorkInterval:   0,
+		active:           &tracing.ActiveSpan{},
 	}
 }
 
@@ -318,8 +324,13 @@ func (w *BaseWorker) internalCommandhandler(worker Worker, command Command) bool
 		return false
 	}
 
-	// Handle domain specific commands
-	if handled := worker.CommandHandler(command); !handled {
+	// Handle domain specific commands, recording the worker's activity for the watchdog.
+	workerStatusManager.CommandStarted(w.GetName(), fmt.Sprintf("%T", command))
+	span := w.startCommandSpan(command)
+	handled := worker.CommandHandler(command)
+	w.endCommandSpan(span, handled)
+	workerStatusManager.CommandEnded(w.GetName())
+	if !handled {
 		glog.Errorf(cdLogString(fmt.Sprintf("%v received unknown command (%T): %v", w.GetName(), command, command)))
 	} else {
 		glog.V(2).Infof(cdLogString(fmt.Sprintf("%v handled command (%T)", w.GetName(), command)))
@@ -344,6 +355,7 @@ func (w *BaseWorker) Start(worker Worker, noWorkInterval int) {
 			return
 		} else {
 			workerStatusManager.SetWorkerStatus(w.GetName(), STATUS_INITIALIZED)
+			workerStatusManager.SetWorkerQueue(w.GetName(), w.Commands)
 		}
 
 		// Process commands in blocking or non-blocking fashion, depending on how we were called.
@@ -376,10 +388,12 @@ func (w *BaseWorker) Start(worker Worker, noWorkInterval int) {
 						return
 					}
 
-				case <-time.After(time.Duration(waitTime) * time.Second):
+				case <-GetClock().After(time.Duration(waitTime) * time.Second):
 					// Call the no work to do handler if it was requested.
 					if w.GetNoWorkInterval() != 0 {
+						workerStatusManager.CommandStarted(w.GetName(), "NoWorkHandler")
 						worker.NoWorkHandler()
+						workerStatusManager.CommandEnded(w.GetName())
 					}
 
 					// Requeue any deferred commands that have been accumulating.
@@ -475,7 +489,7 @@ func (w *BaseWorker) DispatchSubworker(name string, runSubWorker func() int, int
 				w.Commands <- NewSubWorkerTerminationCommand(name)
 				glog.V(3).Infof(cdLogString(fmt.Sprintf("exiting subworker %v", name)))
 				return
-			case <-time.After(time.Duration(nextWaitTime) * time.Second):
+			case <-GetClock().After(time.Duration(nextWaitTime) * time.Second):
 				if !logOptOut {
 					glog.V(3).Infof(cdLogString(fmt.Sprintf("Running subworker %v", name)))
 				}
@@ -500,20 +514,34 @@ type MessageHandler interface {
 }
 
 type MessageHandlerRegistry struct {
-	Handlers map[string]*MessageHandler
+	Handlers    map[string]*MessageHandler
+	queues      map[string]*eventQueue // The events waiting to be delivered to each worker.
+	queueConfig config.EventQueueConfig
 }
 
 func NewMessageHandlerRegistry() *MessageHandlerRegistry {
 	mhr := new(MessageHandlerRegistry)
 	mhr.Handlers = make(map[string]*MessageHandler)
+	mhr.queues = make(map[string]*eventQueue)
 	return mhr
 }
 
+// Set the capacity and overflow policy of the workers' event queues. The settings apply to the workers added afterwards.
+func (m *MessageHandlerRegistry) SetEventQueueConfig(cfg config.EventQueueConfig) {
+	m.queueConfig = cfg
+}
+
 func (m *MessageHandlerRegistry) Add(mh interface {
 	MessageHandler
 }) {
 	if y, ok := mh.(MessageHandler); ok {
+		m.Remove(y.GetName())
 		m.Handlers[y.GetName()] = &y
+
+		q := newEventQueue(y, m.queueConfig)
+		m.queues[y.GetName()] = q
+		workerStatusManager.SetWorkerEventQueue(y.GetName(), q)
+		go q.run()
 	}
 }
 
@@ -521,6 +549,10 @@ func (m *MessageHandlerRegistry) Remove(name string) {
 	if _, ok := m.Handlers[name]; ok {
 		delete(m.Handlers, name)
 	}
+	if q, ok := m.queues[name]; ok {
+		q.close()
+		delete(m.queues, name)
+	}
 }
 
 func (m *MessageHandlerRegistry) IsEmpty() bool {
@@ -535,7 +567,8 @@ func (m *MessageHandlerRegistry) Contains(name string) bool {
 // This is the Event Handler Main control flow area: it receives incoming Message messages and operates on them by pushing them
 // out to each worker. Workers then receive messages and, for messages they care about, the worker pushes them out as commands
 // onto their own channels to operate on them.
-func eventHandler(incoming events.Message, workers *MessageHandlerRegistry) (string, error) {
+// The source is the name of the worker that sent the message, it is passed on to the event tap.
+func eventHandler(incoming events.Message, source string, workers *MessageHandlerRegistry) (string, error) {
 	successMsg := "propagated event to all workers"
 
 	// If the message is the special worker stop message, then remove that worker from the dispatch pool.
@@ -544,27 +577,38 @@ func eventHandler(incoming events.Message, workers *MessageHandlerRegistry) (str
 		msg, _ := incoming.(*events.WorkerStopMessage)
 		workers.Remove(msg.Name())
 		workerStatusManager.SetWorkerStatus(msg.Name(), STATUS_TERMINATED)
+		tapEvent(incoming, source, nil)
 		return successMsg, nil
 	}
 
-	// Dispatch the message to all workers
-	for name, worker := range workers.Handlers {
-		glog.V(5).Infof(mdLogString(fmt.Sprintf("Delivering message to %v", name)))
-		(*worker).NewEvent(incoming)
-		glog.V(5).Infof(mdLogString(fmt.Sprintf("Delivered message to %v", name)))
+	// Queue the message for all workers. Each worker's messages are delivered by its own go routine.
+	queued := make([]string, 0, len(workers.queues))
+	for name, q := range workers.queues {
+		glog.V(5).Infof(mdLogString(fmt.Sprintf("Queueing message for %v", name)))
+		if q.push(incoming) {
+			queued = append(queued, name)
+		}
 	}
+	sort.Strings(queued)
+	tapEvent(incoming, source, queued)
 
 	return successMsg, nil
 }
 
+// A message sent by a worker, with the name of the worker.
+type muxedMessage struct {
+	source string
+	msg    events.Message
+}
+
 // This function combines all messages (events) from workers into a single global message queue. From this
 // global queue, each message will get delivered to each worker by the event handler function.
-func mux(workers *MessageHandlerRegistry, muxed chan events.Message) chan events.Message {
+func mux(workers *MessageHandlerRegistry, muxed chan muxedMessage) chan muxedMessage {
 
-	for _, w := range workers.Handlers {
+	for name, w := range workers.Handlers {
 		select {
 		case ev := <-(*w).Messages():
-			muxed <- ev
+			muxed <- muxedMessage{source: name, msg: ev}
 		default: // nothing
 		}
 	}
@@ -576,10 +620,20 @@ func (workers *MessageHandlerRegistry) ProcessEventMessages() {
 
 	// 200 messages should be plenty. We will never get more than 1 message from every worker each time
 	// we write into this stream.
-	messageStream := make(chan events.Message, 200)
+	messageStream := make(chan muxedMessage, 200)
 
 	last := int64(0)
 
+	// Dispatch the events that were in flight when the process last stopped, before any new event.
+	if j := journal; j != nil {
+		for _, msg := range j.replay(time.Now()) {
+			glog.V(3).Infof(mdLogString(fmt.Sprintf("Replaying journaled Message (%T): %v\n", msg, msg.ShortString())))
+			if _, err := eventHandler(msg, EVENT_TAP_SOURCE_JOURNAL, workers); err != nil {
+				glog.Errorf(mdLogString(fmt.Sprintf("Error occurred replaying message: %s, Error: %v\n", msg, err)))
+			}
+		}
+	}
+
 	for {
 		// Exit the event processing loop if all workers have deregistered.
 		if workers.IsEmpty() {
@@ -594,12 +648,18 @@ func (workers *MessageHandlerRegistry) ProcessEventMessages() {
 		done := false
 		for !done {
 			select {
-			case msg := <-messageStream:
+			case muxed := <-messageStream:
+				msg := muxed.msg
 				glog.V(3).Infof(mdLogString(fmt.Sprintf("Handling Message (%T): %v\n", msg, msg.ShortString())))
 				glog.V(5).Infof(mdLogString(fmt.Sprintf("Handling Message (%T): %v\n", msg, msg)))
 
+				// Journal the message before it is dispatched, so that it survives a restart.
+				if j := journal; j != nil {
+					j.record(msg)
+				}
+
 				// Push outbound messages into each worker.
-				if successMsg, err := eventHandler(msg, workers); err != nil {
+				if successMsg, err := eventHandler(msg, muxed.source, workers); err != nil {
 					// error! do some barfing and then continue
 					glog.Errorf(mdLogString(fmt.Sprintf("Error occurred handling message: %s, Error: %v\n", msg, err)))
 				} else {
@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -32,7 +33,67 @@ type WorkerStatus struct {
 	Name            string            `json:"name"`
 	Status          string            `json:"status"`
 	SubworkerStatus map[string]string `json:"subworker_status"`
-	StatusLock      sync.Mutex        `json:"-"` // The lock that protects modification from different threads at the same time
+	Activity        *WorkerActivity   `json:"activity,omitempty"` // Only workers with a command queue have activity
+	StatusLock      sync.Mutex        `json:"-"`                  // The lock that protects modification from different threads at the same time
+	readiness       map[string]ReadinessCheck
+	eventQueue      *eventQueue // The events waiting to be delivered to the worker, if it is registered for events
+}
+
+// The command processing activity of a worker, used by the watchdog to find workers that have stopped processing commands.
+// Times are in seconds since the epoch, latencies are in milliseconds.
+type WorkerActivity struct {
+	LastActivity    int64  `json:"last_activity"`
+	InFlightCommand string `json:"in_flight_command,omitempty"` // The type of the command being handled
+	InFlightSince   int64  `json:"in_flight_since,omitempty"`
+	QueueLength     int    `json:"queue_length"`
+	QueueCapacity   int    `json:"queue_capacity"`
+	CommandsHandled uint64 `json:"commands_handled"`
+	LastLatencyMs   int64  `json:"last_handler_latency_ms"`
+	MaxLatencyMs    int64  `json:"max_handler_latency_ms"`
+	AvgLatencyMs    int64  `json:"avg_handler_latency_ms"`
+	Stuck           bool   `json:"stuck"`      // Set by the watchdog
+	Backlogged      bool   `json:"backlogged"` // Set by the watchdog
+	queue           chan Command
+	inFlightStart   time.Time
+	totalLatency    time.Duration
+}
+
+// Lock the worker status while it is serialized, the worker may be updating it at the same time.
+func (w *WorkerStatus) MarshalJSON() ([]byte, error) {
+	w.StatusLock.Lock()
+	defer w.StatusLock.Unlock()
+
+	subworkerStatus := make(map[string]string, len(w.SubworkerStatus))
+	for name, status := range w.SubworkerStatus {
+		subworkerStatus[name] = status
+	}
+
+	var activity *WorkerActivity
+	if w.Activity != nil {
+		a := *w.Activity
+		a.QueueLength = len(a.queue)
+		activity = &a
+	}
+
+	var eventQueue *EventQueueStatus
+	if w.eventQueue != nil {
+		s := w.eventQueue.getStatus()
+		eventQueue = &s
+	}
+
+	return json.Marshal(struct {
+		Name            string            `json:"name"`
+		Status          string            `json:"status"`
+		SubworkerStatus map[string]string `json:"subworker_status"`
+		Activity        *WorkerActivity   `json:"activity,omitempty"`
+		EventQueue      *EventQueueStatus `json:"event_queue,omitempty"`
+	}{
+		Name:            w.Name,
+		Status:          w.Status,
+		SubworkerStatus: subworkerStatus,
+		Activity:        activity,
+		EventQueue:      eventQueue,
+	})
 }
 
 func (w *WorkerStatus) SetWorkerStatus(status string) {
@@ -62,6 +123,103 @@ func NewWorkerStatusManager() *WorkerStatusManager {
 	}
 }
 
+// Lock the manager while it is serialized, workers may be added at the same time.
+func (w *WorkerStatusManager) MarshalJSON() ([]byte, error) {
+	w.ManagerLock.Lock()
+	defer w.ManagerLock.Unlock()
+
+	return json.Marshal(struct {
+		Workers   map[string]*WorkerStatus `json:"workers"`
+		StatusLog []string                 `json:"worker_status_log"`
+	}{
+		Workers:   w.Workers,
+		StatusLog: w.StatusLog,
+	})
+}
+
+// Return the status of the named worker, creating it if necessary. The caller must hold the manager lock.
+func (w *WorkerStatusManager) getWorker(name string) *WorkerStatus {
+	if _, ok := w.Workers[name]; !ok {
+		w.Workers[name] = &WorkerStatus{
+			Name:            name,
+			Status:          STATUS_NONE,
+			SubworkerStatus: make(map[string]string),
+		}
+	}
+	return w.Workers[name]
+}
+
+// Start tracking the command processing activity of a worker, whose commands are queued on the input channel.
+func (w *WorkerStatusManager) SetWorkerQueue(name string, queue chan Command) {
+	w.ManagerLock.Lock()
+	defer w.ManagerLock.Unlock()
+
+	ws := w.getWorker(name)
+	ws.StatusLock.Lock()
+	defer ws.StatusLock.Unlock()
+	ws.Activity = &WorkerActivity{
+		LastActivity:  time.Now().Unix(),
+		QueueCapacity: cap(queue),
+		queue:         queue,
+	}
+}
+
+// Report the state of the queue of events waiting to be delivered to a worker.
+func (w *WorkerStatusManager) SetWorkerEventQueue(name string, q *eventQueue) {
+	w.ManagerLock.Lock()
+	defer w.ManagerLock.Unlock()
+
+	ws := w.getWorker(name)
+	ws.StatusLock.Lock()
+	defer ws.StatusLock.Unlock()
+	ws.eventQueue = q
+}
+
+// Record that the worker has started to handle a command.
+func (w *WorkerStatusManager) CommandStarted(name string, command string) {
+	w.ManagerLock.Lock()
+	ws := w.getWorker(name)
+	w.ManagerLock.Unlock()
+
+	ws.StatusLock.Lock()
+	defer ws.StatusLock.Unlock()
+	if ws.Activity == nil {
+		return
+	}
+	now := time.Now()
+	ws.Activity.LastActivity = now.Unix()
+	ws.Activity.InFlightCommand = command
+	ws.Activity.InFlightSince = now.Unix()
+	ws.Activity.inFlightStart = now
+}
+
+// Record that the worker has finished handling its in-flight command.
+func (w *WorkerStatusManager) CommandEnded(name string) {
+	w.ManagerLock.Lock()
+	ws := w.getWorker(name)
+	w.ManagerLock.Unlock()
+
+	ws.StatusLock.Lock()
+	defer ws.StatusLock.Unlock()
+	a := ws.Activity
+	if a == nil || a.InFlightCommand == "" {
+		return
+	}
+	latency := time.Since(a.inFlightStart)
+	handlerLatency.Observe(latency.Seconds(), name)
+
+	a.LastActivity = time.Now().Unix()
+	a.InFlightCommand = ""
+	a.InFlightSince = 0
+	a.CommandsHandled++
+	a.totalLatency += latency
+	a.LastLatencyMs = latency.Milliseconds()
+	if a.LastLatencyMs > a.MaxLatencyMs {
+		a.MaxLatencyMs = a.LastLatencyMs
+	}
+	a.AvgLatencyMs = (a.totalLatency / time.Duration(a.CommandsHandled)).Milliseconds()
+}
+
 func (w *WorkerStatusManager) SetWorkerStatus(name string, status string) {
 	w.ManagerLock.Lock()
 	defer w.ManagerLock.Unlock()
@@ -73,6 +231,9 @@ func (w *WorkerStatusManager) SetWorkerStatus(name string, status string) {
 			SubworkerStatus: make(map[string]string),
 		}
 	} else {
+		if status == STATUS_STARTED && w.Workers[name].Status != STATUS_NONE {
+			workerRestarts.Inc(name, "")
+		}
 		w.Workers[name].SetWorkerStatus(status)
 	}
 
@@ -91,6 +252,9 @@ func (w *WorkerStatusManager) SetSubworkerStatus(name string, subname string, st
 			SubworkerStatus: make(map[string]string),
 		}
 	}
+	if prev, ok := w.Workers[name].SubworkerStatus[subname]; ok && prev != STATUS_ADDED && status == STATUS_STARTED {
+		workerRestarts.Inc(name, subname)
+	}
 	w.Workers[name].SetSubworkerStatus(subname, status)
 
 	time_s := fmt.Sprintf("%s", time.Now().Format("2006-01-02 15:04:05"))
@@ -54,3 +54,31 @@ func Test_SubworkerStatus(t *testing.T) {
 	assert.Equal(t, STATUS_ADDED, workerStatusManager.GetSubworkerStatus("worker2", "sub2"), "The status for worker2 subworker sub2 should be "+STATUS_ADDED)
 	assert.Equal(t, STATUS_ADDED, workerStatusManager.GetSubworkerStatus("worker3", "sub1"), "The status for worker3 subworker sub2 should be "+STATUS_ADDED)
 }
+
+func Test_WorkerMetrics(t *testing.T) {
+
+	// reset the workerStatusManager for testing
+	workerStatusManager = NewWorkerStatusManager()
+
+	restarts := workerRestarts.Get("mworker1", "")
+	subRestarts := workerRestarts.Get("mworker1", "sub1")
+
+	workerStatusManager.SetWorkerStatus("mworker1", STATUS_STARTED)
+	workerStatusManager.SetWorkerStatus("mworker1", STATUS_INITIALIZED)
+	workerStatusManager.SetSubworkerStatus("mworker1", "sub1", STATUS_ADDED)
+	workerStatusManager.SetSubworkerStatus("mworker1", "sub1", STATUS_STARTED)
+	assert.Equal(t, restarts, workerRestarts.Get("mworker1", ""), "The first start of a worker is not a restart.")
+	assert.Equal(t, subRestarts, workerRestarts.Get("mworker1", "sub1"), "The first start of a subworker is not a restart.")
+
+	workerStatusManager.SetWorkerStatus("mworker1", STATUS_TERMINATED)
+	workerStatusManager.SetWorkerStatus("mworker1", STATUS_STARTED)
+	workerStatusManager.SetSubworkerStatus("mworker1", "sub1", STATUS_TERMINATED)
+	workerStatusManager.SetSubworkerStatus("mworker1", "sub1", STATUS_STARTED)
+	assert.Equal(t, restarts+1, workerRestarts.Get("mworker1", ""), "The worker should have been restarted once.")
+	assert.Equal(t, subRestarts+1, workerRestarts.Get("mworker1", "sub1"), "The subworker should have been restarted once.")
+
+	RefreshWorkerMetrics()
+	assert.Equal(t, float64(1), workerStatusGauge.Get("mworker1", STATUS_STARTED), "The worker status gauge should be set.")
+	assert.Equal(t, float64(0), workerStatusGauge.Get("mworker1", STATUS_TERMINATED), "The old worker status should not be reported.")
+	assert.Equal(t, float64(1), subworkerStatusGauge.Get("mworker1", "sub1", STATUS_STARTED), "The subworker status gauge should be set.")
+}